@@ -0,0 +1,404 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	dispatchPollInterval = 5 * time.Second
+	// deliveryLease keeps a claimed delivery from being picked up by another
+	// worker or replica while its webhook call is in flight
+	deliveryLease = 2 * time.Minute
+	// secretPrefix marks webhook signing secrets
+	secretPrefix = "whsec_"
+)
+
+// DispatcherConfig holds webhook delivery settings
+type DispatcherConfig struct {
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RequestTimeout time.Duration
+}
+
+// GetDispatcherConfig reads alert delivery settings from environment variables
+// with sensible defaults if not provided
+func GetDispatcherConfig() DispatcherConfig {
+	config := DispatcherConfig{
+		MaxAttempts:    6,
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  time.Hour,
+		RequestTimeout: 10 * time.Second,
+	}
+
+	if val := os.Getenv("ALERT_MAX_ATTEMPTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.MaxAttempts = n
+		}
+	}
+
+	if val := os.Getenv("ALERT_RETRY_BASE_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.RetryBaseDelay = time.Duration(n) * time.Second
+		}
+	}
+
+	if val := os.Getenv("ALERT_WEBHOOK_TIMEOUT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.RequestTimeout = time.Duration(n) * time.Second
+		}
+	}
+
+	return config
+}
+
+// Payload is the JSON body posted to a rule's webhook
+type Payload struct {
+	Event       string        `json:"event"`
+	TriggeredAt time.Time     `json:"triggered_at"`
+	Rule        PayloadRule   `json:"rule"`
+	Analysis    PayloadResult `json:"analysis"`
+}
+
+// PayloadRule identifies the rule that matched
+type PayloadRule struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// PayloadResult summarises the analysis that matched
+type PayloadResult struct {
+	ID            uint   `json:"id"`
+	Ticker        string `json:"ticker"`
+	FinalDecision string `json:"final_decision"`
+	// PreviousDecision is set for decision change alerts
+	PreviousDecision string    `json:"previous_decision,omitempty"`
+	Confidence       float64   `json:"confidence"`
+	LastClose        float64   `json:"last_close"`
+	Currency         string    `json:"currency"`
+	MaxVolumeZScore  float64   `json:"volume_zscore"`
+	SignalCount      int       `json:"signal_count"`
+	StartDate        time.Time `json:"start_date"`
+	EndDate          time.Time `json:"end_date"`
+}
+
+// Dispatcher matches completed analyses against users' alert rules and
+// delivers matches to their webhooks. Deliveries are persisted, so pending
+// retries survive restarts and replicas can share the work.
+type Dispatcher struct {
+	db      *gorm.DB
+	config  DispatcherConfig
+	wake    chan struct{}
+	running sync.WaitGroup
+}
+
+func NewDispatcher(db *gorm.DB, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{db: db, config: config, wake: make(chan struct{}, 1)}
+}
+
+// GenerateSecret returns a new webhook signing secret
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
+
+// Notify queues a delivery for every enabled rule of the analysis owner that
+// matches it and for every enabled integration of the owner. A nil
+// dispatcher does nothing.
+func (d *Dispatcher) Notify(ctx context.Context, analysis *models.TechnicalSignal) {
+	if d == nil || analysis == nil {
+		return
+	}
+
+	queued := d.queueRuleDeliveries(ctx, analysis) + d.queueIntegrationDeliveries(ctx, analysis)
+	if queued > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// queueRuleDeliveries queues a delivery for every enabled rule of the analysis
+// owner that matches it and returns how many were queued
+func (d *Dispatcher) queueRuleDeliveries(ctx context.Context, analysis *models.TechnicalSignal) int {
+	var rules []models.AlertRule
+	err := d.db.WithContext(ctx).
+		Where("user_id = ? AND enabled = ? AND (ticker = '' OR ticker = ?)", analysis.UserId, true, analysis.Ticker).
+		Find(&rules).Error
+	if err != nil {
+		fmt.Printf("[alerts] failed to load alert rules for analysis %d: %v\n", analysis.ID, err)
+		return 0
+	}
+
+	// The previous decision is only looked up if a decision change rule needs it
+	var previous *string
+	queued := 0
+	for _, rule := range rules {
+		if !Matches(rule, analysis) {
+			continue
+		}
+
+		payload := newPayload(rule, analysis)
+		if rule.Type == models.AlertTypeDecisionChange {
+			if previous == nil {
+				decision, err := d.previousDecision(ctx, analysis)
+				if err != nil {
+					fmt.Printf("[alerts] failed to load previous decision for analysis %d: %v\n", analysis.ID, err)
+					return queued
+				}
+				previous = &decision
+			}
+			// The first analysis of a ticker has nothing to change from
+			if *previous == "" || strings.EqualFold(*previous, analysis.FinalDecision) {
+				continue
+			}
+			payload.Event = "alert.decision_changed"
+			payload.Analysis.PreviousDecision = *previous
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			continue
+		}
+		delivery := models.AlertDelivery{
+			RuleID:        rule.ID,
+			UserId:        rule.UserId,
+			Payload:       body,
+			Status:        models.AlertDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+			fmt.Printf("[alerts] failed to queue delivery for rule %d: %v\n", rule.ID, err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// previousDecision returns the final decision of the owner's analysis of the
+// same ticker stored before this one, or "" if there is none
+func (d *Dispatcher) previousDecision(ctx context.Context, analysis *models.TechnicalSignal) (string, error) {
+	var previous []models.TechnicalSignal
+	err := d.db.WithContext(ctx).
+		Select("final_decision").
+		Where("user_id = ? AND ticker = ? AND id < ?", analysis.UserId, analysis.Ticker, analysis.ID).
+		Order("id desc").
+		Limit(1).
+		Find(&previous).Error
+	if err != nil || len(previous) == 0 {
+		return "", err
+	}
+	return previous[0].FinalDecision, nil
+}
+
+func newPayload(rule models.AlertRule, analysis *models.TechnicalSignal) Payload {
+	return Payload{
+		Event:       "alert.triggered",
+		TriggeredAt: time.Now().UTC(),
+		Rule:        PayloadRule{ID: rule.ID, Name: rule.Name},
+		Analysis: PayloadResult{
+			ID:              analysis.ID,
+			Ticker:          analysis.Ticker,
+			FinalDecision:   analysis.FinalDecision,
+			Confidence:      analysis.Confidence,
+			LastClose:       analysis.LastClose,
+			Currency:        analysis.Currency,
+			MaxVolumeZScore: analysis.MaxVolumeZScore,
+			SignalCount:     len(analysis.Signals),
+			StartDate:       analysis.StartDate,
+			EndDate:         analysis.EndDate,
+		},
+	}
+}
+
+// Start launches the delivery worker; it stops when ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.running.Add(1)
+	go func() {
+		defer d.running.Done()
+		d.worker(ctx)
+	}()
+}
+
+// Wait blocks until the delivery worker has stopped after Start's ctx is cancelled
+func (d *Dispatcher) Wait() {
+	d.running.Wait()
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	ticker := time.NewTicker(dispatchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			delivery, err := d.claim(ctx)
+			if err != nil {
+				fmt.Printf("[alerts] failed to claim delivery: %v\n", err)
+				break
+			}
+			if delivery == nil {
+				break
+			}
+			d.deliver(ctx, delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim takes the oldest due delivery and leases it to this worker. SQLite
+// runs one write at a time, so it needs no row lock.
+func (d *Dispatcher) claim(ctx context.Context) (*models.AlertDelivery, error) {
+	lock := "FOR UPDATE SKIP LOCKED"
+	if models.IsSQLite(d.db) {
+		lock = ""
+	}
+	now := time.Now()
+	var deliveries []models.AlertDelivery
+	err := d.db.WithContext(ctx).Raw(`
+		UPDATE alert_deliveries
+		SET next_attempt_at = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (
+			SELECT id FROM alert_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT 1
+			`+lock+`
+		)
+		RETURNING *`, now.Add(deliveryLease), now, models.AlertDeliveryPending, now).
+		Scan(&deliveries).Error
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// deliver posts one delivery and records the outcome, scheduling a retry with
+// exponential backoff on failure
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.AlertDelivery) {
+	if delivery.IntegrationID != nil {
+		d.deliverIntegration(ctx, delivery)
+		return
+	}
+
+	var rule models.AlertRule
+	err := d.db.WithContext(ctx).First(&rule, delivery.RuleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The rule was deleted; nothing left to deliver to
+		d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":     models.AlertDeliveryFailed,
+			"last_error": "alert rule no longer exists",
+		})
+		return
+	}
+	if err != nil {
+		// Leave the delivery leased; it is picked up again when the lease expires
+		fmt.Printf("[alerts] failed to load rule %d for delivery %d: %v\n", delivery.RuleID, delivery.ID, err)
+		return
+	}
+
+	notifier, err := d.notifierFor(ctx, rule)
+	if err != nil {
+		d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":     models.AlertDeliveryFailed,
+			"last_error": err.Error(),
+		})
+		return
+	}
+
+	d.send(ctx, delivery, notifier, fmt.Sprintf("rule %d", rule.ID))
+}
+
+// deliverIntegration posts an integration delivery, whose payload was mapped
+// when it was queued
+func (d *Dispatcher) deliverIntegration(ctx context.Context, delivery *models.AlertDelivery) {
+	var integration models.Integration
+	err := d.db.WithContext(ctx).First(&integration, *delivery.IntegrationID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":     models.AlertDeliveryFailed,
+			"last_error": "integration no longer exists",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("[alerts] failed to load integration %d for delivery %d: %v\n", *delivery.IntegrationID, delivery.ID, err)
+		return
+	}
+
+	notifier := webhookNotifier{url: integration.WebhookURL, secret: integration.Secret}
+	d.send(ctx, delivery, notifier, fmt.Sprintf("integration %d", integration.ID))
+}
+
+// send makes one delivery attempt and records the outcome, scheduling a retry
+// with exponential backoff on failure
+func (d *Dispatcher) send(ctx context.Context, delivery *models.AlertDelivery, notifier Notifier, target string) {
+	sendCtx, cancel := context.WithTimeout(ctx, d.config.RequestTimeout)
+	retryable, err := notifier.Send(sendCtx, delivery)
+	cancel()
+
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		now := time.Now()
+		updates["status"] = models.AlertDeliveryDelivered
+		updates["delivered_at"] = &now
+		updates["last_error"] = ""
+	case !retryable || delivery.Attempts >= d.config.MaxAttempts:
+		fmt.Printf("[alerts] delivery %d for %s failed permanently: %v\n", delivery.ID, target, err)
+		updates["status"] = models.AlertDeliveryFailed
+		updates["last_error"] = err.Error()
+	default:
+		updates["next_attempt_at"] = time.Now().Add(d.backoff(delivery.Attempts))
+		updates["last_error"] = err.Error()
+	}
+
+	if err := d.db.Model(delivery).Updates(updates).Error; err != nil {
+		fmt.Printf("[alerts] failed to record delivery %d: %v\n", delivery.ID, err)
+	}
+}
+
+// notifierFor returns the rule's notification channel, or its signed webhook
+func (d *Dispatcher) notifierFor(ctx context.Context, rule models.AlertRule) (Notifier, error) {
+	if rule.ChannelID == nil {
+		return webhookNotifier{url: rule.WebhookURL, secret: rule.Secret, template: rule.Template}, nil
+	}
+
+	var channel models.NotificationChannel
+	if err := d.db.WithContext(ctx).Where("user_id = ?", rule.UserId).First(&channel, *rule.ChannelID).Error; err != nil {
+		return nil, fmt.Errorf("notification channel %d is unavailable: %w", *rule.ChannelID, err)
+	}
+	return NewChannelNotifier(channel)
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.RetryBaseDelay << (attempts - 1)
+	if delay <= 0 || delay > d.config.RetryMaxDelay {
+		delay = d.config.RetryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,328 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// emailTimeout bounds one SMTP conversation, from dial to QUIT
+	emailTimeout = 30 * time.Second
+	// emailTopSignals is how many of the latest signals a summary lists
+	emailTopSignals = 10
+)
+
+// EmailConfig holds the SMTP settings for analysis summaries
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// To receives every summary, in addition to the analysis owner
+	To []string
+	// ImplicitTLS connects over TLS (port 465) instead of upgrading with STARTTLS
+	ImplicitTLS bool
+}
+
+// GetEmailConfig reads SMTP settings from environment variables with sensible
+// defaults if not provided. Emails are off unless SMTP_HOST and SMTP_FROM are set.
+func GetEmailConfig() EmailConfig {
+	config := EmailConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     587,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+
+	if val := os.Getenv("SMTP_PORT"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.Port = n
+		}
+	}
+	config.ImplicitTLS = config.Port == 465
+
+	for _, addr := range strings.Split(os.Getenv("ANALYSIS_EMAIL_TO"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			config.To = append(config.To, addr)
+		}
+	}
+
+	return config
+}
+
+// Configured reports whether summaries can be sent
+func (c EmailConfig) Configured() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// EmailNotifier mails a summary of each completed analysis to its owner and
+// to ANALYSIS_EMAIL_TO. A nil notifier does nothing.
+type EmailNotifier struct {
+	db     *gorm.DB
+	config EmailConfig
+}
+
+// NewEmailNotifier returns nil when SMTP is not configured
+func NewEmailNotifier(db *gorm.DB, config EmailConfig) *EmailNotifier {
+	if !config.Configured() {
+		return nil
+	}
+	return &EmailNotifier{db: db, config: config}
+}
+
+// Enabled reports whether summaries are sent, so callers can skip rendering the chart
+func (n *EmailNotifier) Enabled() bool {
+	return n != nil
+}
+
+// Email is one outgoing message. HTML replaces Text as the body when set.
+type Email struct {
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an Email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// NotifyCompleted sends the summary in the background; chart is an optional
+// PNG attached to the message. Failures are logged.
+func (n *EmailNotifier) NotifyCompleted(ctx context.Context, analysis *models.TechnicalSignal, chart []byte) {
+	if n == nil || analysis == nil {
+		return
+	}
+
+	recipients := n.recipients(ctx, analysis.UserId)
+	if len(recipients) == 0 {
+		return
+	}
+
+	email := Email{
+		To:      recipients,
+		Subject: fmt.Sprintf("%s analysis: %s (%.0f%% confidence)", analysis.Ticker, analysis.FinalDecision, analysis.Confidence*100),
+		Text:    summaryText(analysis),
+	}
+	if len(chart) > 0 {
+		email.Attachments = append(email.Attachments, Attachment{
+			Name:        fmt.Sprintf("%s-%d.png", analysis.Ticker, analysis.ID),
+			ContentType: "image/png",
+			Data:        chart,
+		})
+	}
+
+	go func() {
+		if err := n.Send(email); err != nil {
+			fmt.Printf("[alerts] failed to email analysis %d: %v\n", analysis.ID, err)
+		}
+	}()
+}
+
+// Send composes and delivers one message synchronously
+func (n *EmailNotifier) Send(email Email) error {
+	if n == nil {
+		return errors.New("email is not configured")
+	}
+	if len(email.To) == 0 {
+		return errors.New("email has no recipients")
+	}
+	message, err := n.compose(email)
+	if err != nil {
+		return err
+	}
+	return n.send(email.To, message)
+}
+
+// recipients returns ANALYSIS_EMAIL_TO plus the owner's account email, if the
+// owner is a registered user
+func (n *EmailNotifier) recipients(ctx context.Context, userID string) []string {
+	recipients := append([]string(nil), n.config.To...)
+
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return recipients
+	}
+	var user models.User
+	if err := n.db.WithContext(ctx).Select("email").First(&user, id).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Printf("[alerts] failed to load user %s for analysis email: %v\n", userID, err)
+		}
+		return recipients
+	}
+	for _, addr := range recipients {
+		if strings.EqualFold(addr, user.Email) {
+			return recipients
+		}
+	}
+	return append(recipients, user.Email)
+}
+
+// compose builds a multipart message: the body and any attachments
+func (n *EmailNotifier) compose(email Email) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	contentType, body := "text/plain; charset=utf-8", email.Text
+	if email.HTML != "" {
+		contentType, body = "text/html; charset=utf-8", email.HTML
+	}
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(body))
+
+	for _, attachment := range email.Attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Name)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, attachment.Data)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in 76-character lines
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// summaryText is the message body: the decision, the window and the latest signals
+func summaryText(analysis *models.TechnicalSignal) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s with %.0f%% confidence\r\n", analysis.Ticker, analysis.FinalDecision, analysis.Confidence*100)
+	fmt.Fprintf(&b, "Window: %s to %s (%s/%d bars, %d bars analysed)\r\n",
+		analysis.StartDate.Format("2006-01-02 15:04"), analysis.EndDate.Format("2006-01-02 15:04"),
+		analysis.PolyTimeSpan, analysis.PolyMultiplier, analysis.WindowSize)
+	fmt.Fprintf(&b, "Last close: %.2f %s, highest volume z-score: %.1f\r\n", analysis.LastClose, analysis.Currency, analysis.MaxVolumeZScore)
+	if analysis.TrailingStopSide != "" {
+		fmt.Fprintf(&b, "Trailing stop (%s): %.2f %s\r\n", analysis.TrailingStopSide, analysis.TrailingStop, analysis.Currency)
+	}
+	fmt.Fprintf(&b, "Analysis #%d, %d signals\r\n", analysis.ID, len(analysis.Signals))
+	if analysis.Explanation != nil {
+		fmt.Fprintf(&b, "Why: %s\r\n", analysis.Explanation.Summary)
+	}
+
+	signals := deepsearch.ParseSignals(analysis.Signals)
+	if len(signals) > emailTopSignals {
+		signals = signals[len(signals)-emailTopSignals:]
+	}
+	if len(signals) > 0 {
+		fmt.Fprintf(&b, "\r\nLatest signals:\r\n")
+		for _, s := range signals {
+			fmt.Fprintf(&b, "  %s %-8s %s\r\n", s.Time, s.Direction, s.Description)
+		}
+	}
+	return b.String()
+}
+
+// send delivers one message, upgrading to TLS with STARTTLS when the server offers it
+func (n *EmailNotifier) send(recipients []string, message []byte) error {
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	tlsConfig := &tls.Config{ServerName: n.config.Host}
+
+	var conn net.Conn
+	var err error
+	if n.config.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !n.config.ImplicitTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	from := n.config.From
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		from = parsed.Address
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
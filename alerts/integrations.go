@@ -0,0 +1,194 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+)
+
+// maxFieldMappings caps an integration's field mapping
+const maxFieldMappings = 100
+
+// EventAnalysisCompleted is the event of every integration delivery
+const EventAnalysisCompleted = "analysis.completed"
+
+// integrationFields builds each field of the flat integration schema. Lists
+// are joined into strings so no-code platforms can use every field as is.
+var integrationFields = map[string]func(*models.TechnicalSignal) interface{}{
+	"analysis_id":        func(a *models.TechnicalSignal) interface{} { return a.ID },
+	"created_at":         func(a *models.TechnicalSignal) interface{} { return a.CreatedAt.UTC().Format(time.RFC3339) },
+	"ticker":             func(a *models.TechnicalSignal) interface{} { return a.Ticker },
+	"decision":           func(a *models.TechnicalSignal) interface{} { return a.FinalDecision },
+	"confidence":         func(a *models.TechnicalSignal) interface{} { return a.Confidence },
+	"last_close":         func(a *models.TechnicalSignal) interface{} { return a.LastClose },
+	"currency":           func(a *models.TechnicalSignal) interface{} { return a.Currency },
+	"volume_zscore":      func(a *models.TechnicalSignal) interface{} { return a.MaxVolumeZScore },
+	"signal_count":       func(a *models.TechnicalSignal) interface{} { return len(a.Signals) },
+	"signals":            func(a *models.TechnicalSignal) interface{} { return strings.Join(a.Signals, "; ") },
+	"start_date":         func(a *models.TechnicalSignal) interface{} { return a.StartDate.UTC().Format(time.RFC3339) },
+	"end_date":           func(a *models.TechnicalSignal) interface{} { return a.EndDate.UTC().Format(time.RFC3339) },
+	"timespan":           func(a *models.TechnicalSignal) interface{} { return a.PolyTimeSpan },
+	"multiplier":         func(a *models.TechnicalSignal) interface{} { return a.PolyMultiplier },
+	"trailing_stop":      func(a *models.TechnicalSignal) interface{} { return a.TrailingStop },
+	"trailing_stop_side": func(a *models.TechnicalSignal) interface{} { return a.TrailingStopSide },
+	"tags":               func(a *models.TechnicalSignal) interface{} { return strings.Join(a.Tags, ", ") },
+	"vwap": func(a *models.TechnicalSignal) interface{} {
+		if a.Benchmarks == nil {
+			return 0.0
+		}
+		return a.Benchmarks.VWAP
+	},
+	"year_high": func(a *models.TechnicalSignal) interface{} {
+		if a.YearRange == nil {
+			return 0.0
+		}
+		return a.YearRange.High
+	},
+	"year_low": func(a *models.TechnicalSignal) interface{} {
+		if a.YearRange == nil {
+			return 0.0
+		}
+		return a.YearRange.Low
+	},
+}
+
+// IntegrationFields returns the names of the flat schema's fields, sorted,
+// plus "event"
+func IntegrationFields() []string {
+	names := make([]string, 0, len(integrationFields)+1)
+	names = append(names, "event")
+	for name := range integrationFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FlatPayload returns an analysis in the flat integration schema
+func FlatPayload(event string, analysis *models.TechnicalSignal) map[string]interface{} {
+	payload := make(map[string]interface{}, len(integrationFields)+1)
+	payload["event"] = event
+	for name, field := range integrationFields {
+		payload[name] = field(analysis)
+	}
+	return payload
+}
+
+// MapFields applies an integration's field mapping to a flat payload: each
+// mapped key gets the value of its schema field. An empty mapping keeps the
+// payload as is.
+func MapFields(mapping map[string]string, payload map[string]interface{}) map[string]interface{} {
+	if len(mapping) == 0 {
+		return payload
+	}
+	mapped := make(map[string]interface{}, len(mapping))
+	for key, field := range mapping {
+		mapped[key] = payload[field]
+	}
+	return mapped
+}
+
+// SampleIntegrationPayload is the flat payload integration tests and the
+// field listing show
+func SampleIntegrationPayload() map[string]interface{} {
+	now := time.Now().UTC()
+	return FlatPayload("analysis.test", &models.TechnicalSignal{
+		CreatedAt:        now,
+		Ticker:           "TEST",
+		FinalDecision:    "BUY",
+		Confidence:       0.72,
+		LastClose:        100,
+		Currency:         models.DefaultCurrency,
+		MaxVolumeZScore:  2.4,
+		Signals:          []string{"2026-01-02 15:30: Bollinger Breakout", "2026-01-02 15:45: CALL"},
+		StartDate:        now.AddDate(0, 0, -1),
+		EndDate:          now,
+		PolyTimeSpan:     "minute",
+		PolyMultiplier:   15,
+		TrailingStop:     97.5,
+		TrailingStopSide: "long",
+		Tags:             []string{"watchlist"},
+		Benchmarks:       &models.ExecutionBenchmarks{VWAP: 99.4},
+		YearRange:        &models.YearRange{High: 112, Low: 81},
+	})
+}
+
+// NormalizeIntegration validates an integration's name, webhook URL and
+// field mapping. Mapped keys must be non-empty and map to schema fields.
+func NormalizeIntegration(integration *models.Integration) error {
+	integration.Name = strings.TrimSpace(integration.Name)
+	integration.WebhookURL = strings.TrimSpace(integration.WebhookURL)
+
+	if integration.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := ValidateWebhookURL(integration.WebhookURL); err != nil {
+		return err
+	}
+
+	if len(integration.FieldMapping) > maxFieldMappings {
+		return fmt.Errorf("field_mapping cannot exceed %d entries", maxFieldMappings)
+	}
+	mapping := make(map[string]string, len(integration.FieldMapping))
+	for key, field := range integration.FieldMapping {
+		key, field = strings.TrimSpace(key), strings.TrimSpace(field)
+		if key == "" {
+			return errors.New("field_mapping keys cannot be empty")
+		}
+		if _, ok := integrationFields[field]; !ok && field != "event" {
+			return fmt.Errorf("field_mapping %q: unknown field %q", key, field)
+		}
+		mapping[key] = field
+	}
+	integration.FieldMapping = mapping
+	return nil
+}
+
+// queueIntegrationDeliveries queues the analysis for every enabled integration
+// of its owner and returns how many were queued
+func (d *Dispatcher) queueIntegrationDeliveries(ctx context.Context, analysis *models.TechnicalSignal) int {
+	var integrations []models.Integration
+	err := d.db.WithContext(ctx).
+		Where("user_id = ? AND enabled = ?", analysis.UserId, true).
+		Find(&integrations).Error
+	if err != nil {
+		fmt.Printf("[alerts] failed to load integrations for analysis %d: %v\n", analysis.ID, err)
+		return 0
+	}
+
+	queued := 0
+	payload := FlatPayload(EventAnalysisCompleted, analysis)
+	for _, integration := range integrations {
+		body, err := json.Marshal(MapFields(integration.FieldMapping, payload))
+		if err != nil {
+			continue
+		}
+		integrationID := integration.ID
+		delivery := models.AlertDelivery{
+			IntegrationID: &integrationID,
+			UserId:        integration.UserId,
+			Payload:       body,
+			Status:        models.AlertDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+			fmt.Printf("[alerts] failed to queue delivery for integration %d: %v\n", integration.ID, err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// SendIntegration posts body to an integration once, signed with its secret
+func SendIntegration(ctx context.Context, integration models.Integration, body []byte) error {
+	notifier := webhookNotifier{url: integration.WebhookURL, secret: integration.Secret}
+	_, err := notifier.Send(ctx, &models.AlertDelivery{Payload: body})
+	return err
+}
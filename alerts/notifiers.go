@@ -0,0 +1,305 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+)
+
+// Webhook request headers
+const (
+	SignatureHeader  = "X-Alert-Signature"
+	TimestampHeader  = "X-Alert-Timestamp"
+	DeliveryIDHeader = "X-Alert-Delivery"
+)
+
+// telegramAPIURL is the Bot API base; the bot token is appended per channel
+const telegramAPIURL = "https://api.telegram.org"
+
+// Notifier sends one delivery to its destination. It reports whether a
+// failure is worth retrying.
+type Notifier interface {
+	Send(ctx context.Context, delivery *models.AlertDelivery) (retryable bool, err error)
+}
+
+// NewChannelNotifier returns the notifier for a user's notification channel
+func NewChannelNotifier(channel models.NotificationChannel) (Notifier, error) {
+	switch channel.Type {
+	case models.ChannelSlack:
+		return slackNotifier{url: channel.WebhookURL, template: channel.Template}, nil
+	case models.ChannelDiscord:
+		return discordNotifier{url: channel.WebhookURL, template: channel.Template}, nil
+	case models.ChannelTelegram:
+		return telegramNotifier{token: channel.BotToken, chatID: channel.ChatID, template: channel.Template}, nil
+	case models.ChannelEmail:
+		email := NewEmailNotifier(nil, GetEmailConfig())
+		if email == nil {
+			return nil, errors.New("email channels need SMTP_HOST and SMTP_FROM")
+		}
+		return emailChannelNotifier{email: email, to: splitAddresses(channel.EmailTo), template: channel.Template}, nil
+	}
+	return nil, fmt.Errorf("unsupported notification channel type %q", channel.Type)
+}
+
+// NormalizeChannel validates a channel's type, credentials and template.
+// Slack and Discord webhooks must point at their official hosts.
+func NormalizeChannel(channel *models.NotificationChannel) error {
+	channel.Name = strings.TrimSpace(channel.Name)
+	channel.Type = strings.ToLower(strings.TrimSpace(channel.Type))
+	channel.WebhookURL = strings.TrimSpace(channel.WebhookURL)
+	channel.BotToken = strings.TrimSpace(channel.BotToken)
+	channel.ChatID = strings.TrimSpace(channel.ChatID)
+	channel.EmailTo = strings.Join(splitAddresses(channel.EmailTo), ", ")
+
+	if channel.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := ValidateTemplate(channel.Type, channel.Template); err != nil {
+		return err
+	}
+
+	switch channel.Type {
+	case models.ChannelSlack:
+		return requireWebhookHost(channel.WebhookURL, "hooks.slack.com")
+	case models.ChannelDiscord:
+		return requireWebhookHost(channel.WebhookURL, "discord.com", "discordapp.com")
+	case models.ChannelTelegram:
+		if channel.BotToken == "" || channel.ChatID == "" {
+			return errors.New("telegram channels need bot_token and chat_id")
+		}
+		return nil
+	case models.ChannelEmail:
+		if channel.EmailTo == "" {
+			return errors.New("email channels need email_to")
+		}
+		for _, addr := range splitAddresses(channel.EmailTo) {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("invalid email_to address %q", addr)
+			}
+		}
+		if !GetEmailConfig().Configured() {
+			return errors.New("email channels need SMTP_HOST and SMTP_FROM")
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid type %q (slack, discord, telegram or email)", channel.Type)
+}
+
+// splitAddresses splits a comma-separated recipient list
+func splitAddresses(raw string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(raw, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func requireWebhookHost(raw string, hosts ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" {
+		return errors.New("webhook_url must be an https URL")
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook_url must be on %s", strings.Join(hosts, " or "))
+}
+
+// Sign returns the signature header value for a delivery: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the rule's secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookNotifier posts the payload, or the rule's rendered template, to a
+// user's endpoint, signed with the rule's secret
+type webhookNotifier struct {
+	url      string
+	secret   string
+	template string
+}
+
+func (n webhookNotifier) Send(ctx context.Context, delivery *models.AlertDelivery) (bool, error) {
+	body := []byte(delivery.Payload)
+	if n.template != "" {
+		var err error
+		if body, err = renderDelivery(TemplateWebhook, n.template, delivery); err != nil {
+			return false, fmt.Errorf("failed to render template: %w", err)
+		}
+	}
+
+	timestamp := time.Now().Unix()
+	return postJSON(ctx, n.url, body, map[string]string{
+		TimestampHeader:  strconv.FormatInt(timestamp, 10),
+		SignatureHeader:  Sign(n.secret, timestamp, body),
+		DeliveryIDHeader: strconv.FormatUint(uint64(delivery.ID), 10),
+	})
+}
+
+type slackNotifier struct {
+	url      string
+	template string
+}
+
+func (n slackNotifier) Send(ctx context.Context, delivery *models.AlertDelivery) (bool, error) {
+	body, err := chatBody(models.ChannelSlack, n.template, delivery, "text", nil)
+	if err != nil {
+		return false, err
+	}
+	return postJSON(ctx, n.url, body, nil)
+}
+
+type discordNotifier struct {
+	url      string
+	template string
+}
+
+func (n discordNotifier) Send(ctx context.Context, delivery *models.AlertDelivery) (bool, error) {
+	body, err := chatBody(models.ChannelDiscord, n.template, delivery, "content", nil)
+	if err != nil {
+		return false, err
+	}
+	return postJSON(ctx, n.url, body, nil)
+}
+
+type telegramNotifier struct {
+	token    string
+	chatID   string
+	template string
+}
+
+func (n telegramNotifier) Send(ctx context.Context, delivery *models.AlertDelivery) (bool, error) {
+	body, err := chatBody(models.ChannelTelegram, n.template, delivery, "text", map[string]string{"chat_id": n.chatID})
+	if err != nil {
+		return false, err
+	}
+	retryable, err := postJSON(ctx, telegramAPIURL+"/bot"+n.token+"/sendMessage", body, nil)
+	if err != nil {
+		// The request URL embeds the bot token; keep it out of stored errors
+		err = errors.New(strings.ReplaceAll(err.Error(), n.token, "<token>"))
+	}
+	return retryable, err
+}
+
+// emailChannelNotifier mails alerts to an email channel's recipients, as the
+// rendered HTML template or the one-line message
+type emailChannelNotifier struct {
+	email    *EmailNotifier
+	to       []string
+	template string
+}
+
+func (n emailChannelNotifier) Send(ctx context.Context, delivery *models.AlertDelivery) (bool, error) {
+	var payload Payload
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+		return false, fmt.Errorf("invalid delivery payload: %w", err)
+	}
+
+	email := Email{
+		To:      n.to,
+		Subject: fmt.Sprintf("Alert %q: %s %s", payload.Rule.Name, payload.Analysis.Ticker, payload.Analysis.FinalDecision),
+	}
+	if n.template != "" {
+		html, err := RenderTemplate(models.ChannelEmail, n.template, payload)
+		if err != nil {
+			return false, fmt.Errorf("failed to render template: %w", err)
+		}
+		email.HTML = string(html)
+	} else {
+		text, err := formatMessage(delivery)
+		if err != nil {
+			return false, err
+		}
+		email.Text = text
+	}
+
+	// SMTP errors are mostly transient (greylisting, timeouts), so retry them
+	if err := n.email.Send(email); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// formatMessage renders a delivery's payload as a one-line chat message
+func formatMessage(delivery *models.AlertDelivery) (string, error) {
+	var payload Payload
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+		return "", fmt.Errorf("invalid delivery payload: %w", err)
+	}
+
+	a := payload.Analysis
+	// Deliveries queued before payloads carried a currency have none
+	lastClose := fmt.Sprintf("%.2f", a.LastClose)
+	if a.Currency != "" {
+		lastClose += " " + a.Currency
+	}
+	if a.PreviousDecision != "" {
+		return fmt.Sprintf("Alert %q: %s final decision changed %s -> %s (confidence %.0f%%), last close %s, analysis #%d",
+			payload.Rule.Name, a.Ticker, a.PreviousDecision, a.FinalDecision, a.Confidence*100, lastClose, a.ID), nil
+	}
+	return fmt.Sprintf("Alert %q: %s final decision %s (confidence %.0f%%), last close %s, volume z-score %.1f, %d signals, analysis #%d",
+		payload.Rule.Name, a.Ticker, a.FinalDecision, a.Confidence*100, lastClose, a.MaxVolumeZScore, a.SignalCount, a.ID), nil
+}
+
+// postJSON posts body through the outbound transport for user-supplied URLs,
+// which refuses private addresses at connect time unless
+// ALERT_WEBHOOK_ALLOW_PRIVATE is true. Network errors, 408, 429 and 5xx are
+// retryable; other non-2xx responses, including redirects, are not.
+func postJSON(ctx context.Context, target string, body []byte, headers map[string]string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "institution-analyser-alerts")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	// Redirects are not followed, so a webhook cannot bounce deliveries to another host
+	transport := service.PublicHTTPClient().Transport
+	if os.Getenv("ALERT_WEBHOOK_ALLOW_PRIVATE") == "true" {
+		transport = service.HTTPClient().Transport
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
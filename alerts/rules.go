@@ -0,0 +1,170 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"institutionanalyser/models"
+)
+
+// Metrics an alert rule can compare, read from the completed analysis
+var metrics = map[string]func(*models.TechnicalSignal) float64{
+	"confidence":    func(a *models.TechnicalSignal) float64 { return a.Confidence },
+	"volume_zscore": func(a *models.TechnicalSignal) float64 { return a.MaxVolumeZScore },
+	"last_close":    func(a *models.TechnicalSignal) float64 { return a.LastClose },
+	"signal_count":  func(a *models.TechnicalSignal) float64 { return float64(len(a.Signals)) },
+	// confirmed_moves counts signals backed by several independent sources,
+	// for a high-precision alert tier
+	"confirmed_moves": func(a *models.TechnicalSignal) float64 {
+		count := 0
+		for _, signal := range a.Signals {
+			if strings.Contains(signal, "Confirmed Institutional Move") {
+				count++
+			}
+		}
+		return float64(count)
+	},
+	// ftd_spikes counts fails-to-deliver spikes in the analysed window
+	"ftd_spikes": func(a *models.TechnicalSignal) float64 {
+		count := 0
+		for _, signal := range a.Signals {
+			if strings.Contains(signal, "FTD Spike") {
+				count++
+			}
+		}
+		return float64(count)
+	},
+}
+
+var operators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+}
+
+var decisions = map[string]bool{"BUY": true, "SELL": true, "HOLD": true, "STRADDLE": true}
+
+// Metrics returns the metric names rules may use
+func Metrics() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizeRule validates a rule's conditions and destination, upper-casing
+// ticker and decision. A rule with a channel drops its webhook URL.
+func NormalizeRule(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Ticker = strings.ToUpper(strings.TrimSpace(rule.Ticker))
+	rule.Decision = strings.ToUpper(strings.TrimSpace(rule.Decision))
+	rule.Metric = strings.ToLower(strings.TrimSpace(rule.Metric))
+	rule.Operator = strings.TrimSpace(rule.Operator)
+	rule.Type = strings.ToLower(strings.TrimSpace(rule.Type))
+	if rule.Type == "" {
+		rule.Type = models.AlertTypeMatch
+	}
+
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.Type != models.AlertTypeMatch && rule.Type != models.AlertTypeDecisionChange {
+		return fmt.Errorf("invalid type %q (match or decision_change)", rule.Type)
+	}
+	// A decision change is a condition in itself; match rules need one of their own
+	if rule.Type == models.AlertTypeMatch && rule.Decision == "" && rule.Metric == "" {
+		return errors.New("a rule needs a decision or a metric condition")
+	}
+	if rule.Decision != "" && !decisions[rule.Decision] {
+		return fmt.Errorf("invalid decision %q (BUY, SELL, HOLD or STRADDLE)", rule.Decision)
+	}
+	if rule.Metric != "" {
+		if _, ok := metrics[rule.Metric]; !ok {
+			return fmt.Errorf("invalid metric %q (one of %s)", rule.Metric, strings.Join(Metrics(), ", "))
+		}
+		if _, ok := operators[rule.Operator]; !ok {
+			return fmt.Errorf("invalid operator %q (>, >=, <, <= or ==)", rule.Operator)
+		}
+	}
+
+	// Channel deliveries are formatted by the channel's template
+	if rule.ChannelID != nil {
+		rule.WebhookURL = ""
+		rule.Template = ""
+		return nil
+	}
+	if strings.TrimSpace(rule.WebhookURL) == "" {
+		return errors.New("a rule needs a webhook_url or a channel_id")
+	}
+	if err := ValidateTemplate(TemplateWebhook, rule.Template); err != nil {
+		return err
+	}
+	return ValidateWebhookURL(rule.WebhookURL)
+}
+
+// Matches reports whether a completed analysis satisfies every condition of
+// the rule. For decision change rules the caller also checks the flip.
+func Matches(rule models.AlertRule, analysis *models.TechnicalSignal) bool {
+	if !rule.Enabled {
+		return false
+	}
+	if rule.Ticker != "" && !strings.EqualFold(rule.Ticker, analysis.Ticker) {
+		return false
+	}
+	if rule.Decision != "" && !strings.EqualFold(rule.Decision, analysis.FinalDecision) {
+		return false
+	}
+	if rule.Metric != "" {
+		metric, ok := metrics[rule.Metric]
+		compare, okOp := operators[rule.Operator]
+		if !ok || !okOp || !compare(metric(analysis), rule.Threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateWebhookURL requires an absolute https URL. Plain http and hosts that
+// resolve to loopback, private or link-local addresses are rejected unless
+// ALERT_WEBHOOK_ALLOW_HTTP / ALERT_WEBHOOK_ALLOW_PRIVATE are set to true. The
+// address check only gives early feedback; deliveries enforce it again when
+// they connect (see postJSON).
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return errors.New("webhook_url must be an absolute URL")
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if os.Getenv("ALERT_WEBHOOK_ALLOW_HTTP") != "true" {
+			return errors.New("webhook_url must use https")
+		}
+	default:
+		return errors.New("webhook_url must use https")
+	}
+
+	if os.Getenv("ALERT_WEBHOOK_ALLOW_PRIVATE") == "true" {
+		return nil
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook_url host cannot be resolved: %w", err)
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return errors.New("webhook_url must not point to a private address")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"institutionanalyser/models"
+)
+
+// maxTemplateSize bounds a stored notification template
+const maxTemplateSize = 16 * 1024
+
+// TemplateWebhook is the template kind of rules posting to a webhook; channel
+// templates use the channel type
+const TemplateWebhook = "webhook"
+
+// templateFuncs are available to every notification template
+var templateFuncs = map[string]interface{}{
+	// json encodes a value as JSON, e.g. {"text": {{json .Rule.Name}}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"percent": func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"date":    func(t time.Time, layout string) string { return t.Format(layout) },
+}
+
+// SamplePayload is the payload channel tests and template previews render
+func SamplePayload() Payload {
+	now := time.Now().UTC()
+	return Payload{
+		Event:       "alert.test",
+		TriggeredAt: now,
+		Rule:        PayloadRule{Name: "Test alert"},
+		Analysis: PayloadResult{
+			Ticker:          "TEST",
+			FinalDecision:   "HOLD",
+			Confidence:      0.5,
+			LastClose:       100,
+			Currency:        models.DefaultCurrency,
+			MaxVolumeZScore: 1.5,
+			SignalCount:     3,
+			StartDate:       now.AddDate(0, 0, -1),
+			EndDate:         now,
+		},
+	}
+}
+
+// RenderTemplate executes a notification template against a payload. Email
+// templates produce HTML and escape their values; webhook templates must
+// produce valid JSON.
+func RenderTemplate(kind, text string, payload Payload) ([]byte, error) {
+	var buf bytes.Buffer
+	if kind == models.ChannelEmail {
+		tmpl, err := htmltemplate.New(kind).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&buf, payload); err != nil {
+			return nil, err
+		}
+	} else {
+		tmpl, err := template.New(kind).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&buf, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if kind == TemplateWebhook && !json.Valid(buf.Bytes()) {
+		return nil, errors.New("webhook template must render valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// ValidateTemplate checks a template by rendering the sample payload; an
+// empty template keeps the built-in format
+func ValidateTemplate(kind, text string) error {
+	if text == "" {
+		return nil
+	}
+	if len(text) > maxTemplateSize {
+		return fmt.Errorf("template must be at most %d bytes", maxTemplateSize)
+	}
+	if _, err := RenderTemplate(kind, text, SamplePayload()); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+// renderDelivery renders a template against a stored delivery's payload
+func renderDelivery(kind, text string, delivery *models.AlertDelivery) ([]byte, error) {
+	var payload Payload
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid delivery payload: %w", err)
+	}
+	return RenderTemplate(kind, text, payload)
+}
+
+// chatBody is the request body of a chat message: the channel's rendered
+// template when it is a JSON object (Slack blocks, Discord embeds), otherwise
+// the message text under field. extra is merged into either form.
+func chatBody(kind, text string, delivery *models.AlertDelivery, field string, extra map[string]string) ([]byte, error) {
+	message := map[string]interface{}{}
+	if text == "" {
+		formatted, err := formatMessage(delivery)
+		if err != nil {
+			return nil, err
+		}
+		message[field] = formatted
+	} else {
+		rendered, err := renderDelivery(kind, text, delivery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		if trimmed := bytes.TrimSpace(rendered); len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &message) != nil {
+			message = map[string]interface{}{field: string(rendered)}
+		}
+	}
+
+	for key, value := range extra {
+		message[key] = value
+	}
+	return json.Marshal(message)
+}
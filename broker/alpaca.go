@@ -0,0 +1,209 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+)
+
+const (
+	defaultAlpacaLiveURL  = "https://api.alpaca.markets"
+	defaultAlpacaPaperURL = "https://paper-api.alpaca.markets"
+	// alpacaTimeout bounds one Alpaca API call
+	alpacaTimeout = 15 * time.Second
+)
+
+// AlpacaError is a non-2xx response from Alpaca
+type AlpacaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AlpacaError) Error() string {
+	return fmt.Sprintf("alpaca returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// AlpacaAccount is the part of Alpaca's account the service reads
+type AlpacaAccount struct {
+	ID             string `json:"id"`
+	AccountNumber  string `json:"account_number"`
+	Status         string `json:"status"`
+	Currency       string `json:"currency"`
+	TradingBlocked bool   `json:"trading_blocked"`
+}
+
+// AlpacaOrder is the part of an Alpaca order the service reads. Quantities
+// and prices are decimal strings.
+type AlpacaOrder struct {
+	ID             string     `json:"id"`
+	ClientOrderID  string     `json:"client_order_id"`
+	Status         string     `json:"status"`
+	FilledQty      string     `json:"filled_qty"`
+	FilledAvgPrice *string    `json:"filled_avg_price"`
+	SubmittedAt    *time.Time `json:"submitted_at"`
+	FilledAt       *time.Time `json:"filled_at"`
+}
+
+// alpacaOrderRequest is the body of a new order
+type alpacaOrderRequest struct {
+	Symbol        string `json:"symbol"`
+	Qty           string `json:"qty,omitempty"`
+	Notional      string `json:"notional,omitempty"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	TimeInForce   string `json:"time_in_force"`
+	ClientOrderID string `json:"client_order_id"`
+}
+
+// AlpacaClient calls the Alpaca trading API with one account's keys through
+// the shared outbound transport
+type AlpacaClient struct {
+	baseURL string
+	keyID   string
+	secret  string
+	client  *http.Client
+}
+
+// NewAlpacaClient returns a client for the paper or live environment.
+// ALPACA_PAPER_URL and ALPACA_LIVE_URL override their hosts.
+func NewAlpacaClient(paper bool, keyID, secret string) *AlpacaClient {
+	baseURL := os.Getenv("ALPACA_LIVE_URL")
+	if baseURL == "" {
+		baseURL = defaultAlpacaLiveURL
+	}
+	if paper {
+		baseURL = os.Getenv("ALPACA_PAPER_URL")
+		if baseURL == "" {
+			baseURL = defaultAlpacaPaperURL
+		}
+	}
+	return &AlpacaClient{
+		baseURL: baseURL,
+		keyID:   keyID,
+		secret:  secret,
+		client:  &http.Client{Transport: service.HTTPClient().Transport, Timeout: alpacaTimeout},
+	}
+}
+
+// clientFor opens an account's sealed credentials
+func clientFor(account *models.BrokerAccount) (*AlpacaClient, error) {
+	keyID, err := Open(account.SealedKeyID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := Open(account.SealedSecret)
+	if err != nil {
+		return nil, err
+	}
+	return NewAlpacaClient(account.Paper, keyID, secret), nil
+}
+
+// Account reads the account the keys belong to, which also checks the keys
+func (a *AlpacaClient) Account(ctx context.Context) (*AlpacaAccount, error) {
+	var account AlpacaAccount
+	if err := a.do(ctx, http.MethodGet, "/v2/account", nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// PlaceMarketOrder submits a market day order for quantity shares, or for
+// notional when quantity is 0
+func (a *AlpacaClient) PlaceMarketOrder(ctx context.Context, symbol, side string, quantity, notional float64, clientOrderID string) (*AlpacaOrder, error) {
+	body := alpacaOrderRequest{
+		Symbol:        symbol,
+		Side:          side,
+		Type:          "market",
+		TimeInForce:   "day",
+		ClientOrderID: clientOrderID,
+	}
+	if quantity > 0 {
+		body.Qty = strconv.FormatFloat(quantity, 'f', -1, 64)
+	} else {
+		body.Notional = strconv.FormatFloat(notional, 'f', 2, 64)
+	}
+	var order AlpacaOrder
+	if err := a.do(ctx, http.MethodPost, "/v2/orders", body, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Order reads an order by Alpaca's ID
+func (a *AlpacaClient) Order(ctx context.Context, id string) (*AlpacaOrder, error) {
+	var order AlpacaOrder
+	if err := a.do(ctx, http.MethodGet, "/v2/orders/"+url.PathEscape(id), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// OrderByClientID reads an order by the client order ID it was placed with
+func (a *AlpacaClient) OrderByClientID(ctx context.Context, clientOrderID string) (*AlpacaOrder, error) {
+	var order AlpacaOrder
+	path := "/v2/orders:by_client_order_id?client_order_id=" + url.QueryEscape(clientOrderID)
+	if err := a.do(ctx, http.MethodGet, path, nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder asks Alpaca to cancel an open order
+func (a *AlpacaClient) CancelOrder(ctx context.Context, id string) error {
+	return a.do(ctx, http.MethodDelete, "/v2/orders/"+url.PathEscape(id), nil, nil)
+}
+
+func (a *AlpacaClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("APCA-API-KEY-ID", a.keyID)
+	req.Header.Set("APCA-API-SECRET-KEY", a.secret)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return &AlpacaError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
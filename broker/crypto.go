@@ -0,0 +1,85 @@
+package broker
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// sealedPrefix versions sealed values, so the scheme can change without
+// misreading stored credentials
+const sealedPrefix = "v1:"
+
+// ErrNoEncryptionKey is returned when credentials cannot be sealed or opened
+var ErrNoEncryptionKey = errors.New("BROKER_ENCRYPTION_KEY is not configured")
+
+// encryptionKey returns BROKER_ENCRYPTION_KEY, 32 base64-encoded bytes
+func encryptionKey() ([]byte, error) {
+	val := os.Getenv("BROKER_ENCRYPTION_KEY")
+	if val == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	key, err := base64.StdEncoding.DecodeString(val)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("BROKER_ENCRYPTION_KEY must be 32 base64-encoded bytes")
+	}
+	return key, nil
+}
+
+// EncryptionConfigured reports whether credentials can be stored
+func EncryptionConfigured() bool {
+	_, err := encryptionKey()
+	return err == nil
+}
+
+// Seal encrypts plaintext with AES-256-GCM under BROKER_ENCRYPTION_KEY
+func Seal(plaintext string) (string, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed by Seal
+func Open(sealed string) (string, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", errors.New("unknown sealed value format")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credentials, was BROKER_ENCRYPTION_KEY changed? %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD() (cipher.AEAD, error) {
+	key, err := encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,325 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// syncBatch caps how many open orders one sync reads from the broker
+	syncBatch = 500
+	// unconfirmedAfter is how long an order sent without a confirmed
+	// response waits before it is looked up by client order ID
+	unconfirmedAfter = time.Minute
+)
+
+// Config holds order routing settings
+type Config struct {
+	Enabled bool
+	// MaxSignalAge is how old an analysis's last bar may be for its decision
+	// to place orders
+	MaxSignalAge time.Duration
+}
+
+// GetConfig reads order routing settings from environment variables with
+// sensible defaults if not provided. Routing is off unless
+// BROKER_ROUTING_ENABLED=true.
+func GetConfig() Config {
+	config := Config{
+		Enabled:      os.Getenv("BROKER_ROUTING_ENABLED") == "true",
+		MaxSignalAge: 15 * time.Minute,
+	}
+
+	if val := os.Getenv("BROKER_MAX_SIGNAL_AGE_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.MaxSignalAge = time.Duration(n) * time.Minute
+		}
+	}
+
+	return config
+}
+
+// Router places orders at users' brokers when analyses run with a strategy
+// decide BUY or SELL and the user has an execution rule for that strategy.
+// A nil router does nothing.
+type Router struct {
+	db     *gorm.DB
+	config Config
+}
+
+// NewRouter returns nil when routing is disabled, and an error when it is
+// enabled without an encryption key to open credentials with
+func NewRouter(db *gorm.DB, config Config) (*Router, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if _, err := encryptionKey(); err != nil {
+		return nil, err
+	}
+	return &Router{db: db, config: config}, nil
+}
+
+// OnAnalysis places an order for each of the owner's enabled execution rules
+// that the analysis matches. Nothing is placed while a kill switch is engaged,
+// or when the analysis's last bar is older than MaxSignalAge.
+func (r *Router) OnAnalysis(ctx context.Context, analysis *models.TechnicalSignal) error {
+	if r == nil || analysis == nil || analysis.StrategyID == 0 {
+		return nil
+	}
+	side := orderSide(analysis.FinalDecision)
+	if side == "" {
+		return nil
+	}
+
+	var rules []models.ExecutionRule
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND strategy_id = ? AND enabled = ?", analysis.UserId, analysis.StrategyID, true).
+		Where("ticker = '' OR ticker = ?", analysis.Ticker).
+		Order("id").Find(&rules).Error
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	if age := time.Since(analysis.EndDate); age > r.config.MaxSignalAge {
+		fmt.Printf("[broker] analysis %d ends %v ago, not placing orders\n", analysis.ID, age.Round(time.Minute))
+		return nil
+	}
+	halted, err := Halted(ctx, r.db, analysis.UserId)
+	if err != nil {
+		return err
+	}
+	if halted {
+		fmt.Printf("[broker] trading halted for user %s, not placing orders for analysis %d\n", analysis.UserId, analysis.ID)
+		return nil
+	}
+
+	var account models.BrokerAccount
+	err = r.db.WithContext(ctx).Where("user_id = ?", analysis.UserId).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("[broker] user %s has execution rules but no broker account\n", analysis.UserId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	client, err := clientFor(&account)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if analysis.Confidence < rule.MinConfidence || (side == "sell" && rule.BuyOnly) {
+			continue
+		}
+		if err := r.place(ctx, client, &account, rule, analysis, side); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// place records a rule's order, then sends it. The record comes first, so an
+// order already placed for this analysis and rule is never sent again.
+func (r *Router) place(ctx context.Context, client *AlpacaClient, account *models.BrokerAccount, rule models.ExecutionRule, analysis *models.TechnicalSignal, side string) error {
+	order := models.BrokerOrder{
+		UserId:        analysis.UserId,
+		AccountID:     account.ID,
+		RuleID:        rule.ID,
+		AnalysisID:    analysis.ID,
+		Paper:         account.Paper,
+		ClientOrderID: fmt.Sprintf("ia-%d-%d", analysis.ID, rule.ID),
+		Ticker:        analysis.Ticker,
+		Side:          side,
+		Quantity:      rule.Quantity,
+		Notional:      rule.Notional,
+		Status:        models.BrokerOrderPending,
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&order)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	placed, err := client.PlaceMarketOrder(ctx, order.Ticker, order.Side, order.Quantity, order.Notional, order.ClientOrderID)
+	var apiErr *AlpacaError
+	switch {
+	case errors.As(err, &apiErr):
+		order.Status = models.BrokerOrderFailed
+		order.Error = err.Error()
+	case err != nil:
+		// The order may have reached the broker; syncing looks it up by client order ID
+		order.Error = err.Error()
+	default:
+		applyOrder(&order, placed)
+		fmt.Printf("[broker] placed %s %s order %s for analysis %d\n", order.Ticker, order.Side, order.BrokerOrderID, analysis.ID)
+	}
+	return r.db.WithContext(ctx).Save(&order).Error
+}
+
+// SyncOrders reads every order that is not final from its broker and records
+// its status and fills. It returns how many orders it updated.
+func (r *Router) SyncOrders(ctx context.Context) (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+
+	var orders []models.BrokerOrder
+	if err := r.db.WithContext(ctx).Where("status NOT IN ?", models.BrokerOrderFinal).
+		Order("id").Limit(syncBatch).Find(&orders).Error; err != nil {
+		return 0, err
+	}
+
+	clients := map[uint]*AlpacaClient{}
+	synced := 0
+	for i := range orders {
+		order := &orders[i]
+		if order.BrokerOrderID == "" && time.Since(order.CreatedAt) < unconfirmedAfter {
+			continue
+		}
+		client, ok := clients[order.AccountID]
+		if !ok {
+			var account models.BrokerAccount
+			if err := r.db.WithContext(ctx).First(&account, order.AccountID).Error; err != nil {
+				fmt.Printf("[broker] order %d: account %d: %v\n", order.ID, order.AccountID, err)
+				continue
+			}
+			var err error
+			if client, err = clientFor(&account); err != nil {
+				return synced, err
+			}
+			clients[order.AccountID] = client
+		}
+
+		var placed *AlpacaOrder
+		var err error
+		if order.BrokerOrderID != "" {
+			placed, err = client.Order(ctx, order.BrokerOrderID)
+		} else {
+			placed, err = client.OrderByClientID(ctx, order.ClientOrderID)
+		}
+		var apiErr *AlpacaError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && order.BrokerOrderID == "":
+			order.Status = models.BrokerOrderFailed
+			order.Error = "order never reached the broker"
+		case err != nil:
+			fmt.Printf("[broker] failed to sync order %d: %v\n", order.ID, err)
+			continue
+		default:
+			applyOrder(order, placed)
+		}
+		now := time.Now()
+		order.SyncedAt = &now
+		if err := r.db.WithContext(ctx).Save(order).Error; err != nil {
+			return synced, err
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// Halted reports whether a kill switch stops orders for userID
+func Halted(ctx context.Context, db *gorm.DB, userID string) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.TradingHalt{}).
+		Where("user_id IN ?", []string{"", userID}).Count(&count).Error
+	return count > 0, err
+}
+
+// Halt engages the kill switch for userID, or for every user when userID is
+// empty, and cancels the open orders it covers. Orders that could not be
+// cancelled are reported in the error; the switch stays engaged regardless.
+func Halt(ctx context.Context, db *gorm.DB, userID, reason, haltedBy string) (*models.TradingHalt, int, error) {
+	halt := models.TradingHalt{UserId: userID, Reason: reason, HaltedBy: haltedBy}
+	err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "halted_by"}),
+	}).Create(&halt).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).First(&halt).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := db.WithContext(ctx).Where("status NOT IN ? AND broker_order_id <> ''", models.BrokerOrderFinal)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var orders []models.BrokerOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return &halt, 0, err
+	}
+
+	clients := map[uint]*AlpacaClient{}
+	cancelled := 0
+	var errs []error
+	for _, order := range orders {
+		client, ok := clients[order.AccountID]
+		if !ok {
+			var account models.BrokerAccount
+			if err := db.WithContext(ctx).First(&account, order.AccountID).Error; err != nil {
+				errs = append(errs, fmt.Errorf("order %d: %w", order.ID, err))
+				continue
+			}
+			var err error
+			if client, err = clientFor(&account); err != nil {
+				errs = append(errs, fmt.Errorf("order %d: %w", order.ID, err))
+				continue
+			}
+			clients[order.AccountID] = client
+		}
+		// 422 means the order filled or was cancelled in the meantime
+		var apiErr *AlpacaError
+		if err := client.CancelOrder(ctx, order.BrokerOrderID); err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity) {
+			errs = append(errs, fmt.Errorf("order %d: %w", order.ID, err))
+			continue
+		}
+		cancelled++
+	}
+	return &halt, cancelled, errors.Join(errs...)
+}
+
+// Resume releases the kill switch for userID, or the global one when userID
+// is empty, and reports whether it was engaged
+func Resume(ctx context.Context, db *gorm.DB, userID string) (bool, error) {
+	result := db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.TradingHalt{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// applyOrder records the broker's view of an order
+func applyOrder(order *models.BrokerOrder, placed *AlpacaOrder) {
+	order.BrokerOrderID = placed.ID
+	order.Status = placed.Status
+	order.Error = ""
+	if qty, err := strconv.ParseFloat(placed.FilledQty, 64); err == nil {
+		order.FilledQuantity = qty
+	}
+	if placed.FilledAvgPrice != nil {
+		if price, err := strconv.ParseFloat(*placed.FilledAvgPrice, 64); err == nil {
+			order.FilledAvgPrice = price
+		}
+	}
+	order.SubmittedAt = placed.SubmittedAt
+	order.FilledAt = placed.FilledAt
+}
+
+// orderSide is the order side a final decision places, or "" for HOLD and STRADDLE
+func orderSide(decision string) string {
+	switch decision {
+	case "BUY":
+		return "buy"
+	case "SELL":
+		return "sell"
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/handlers"
+	"institutionanalyser/models"
+	"institutionanalyser/usage"
+
+	"github.com/spf13/cobra"
+)
+
+// rootCommand runs the server, as the binary always has, unless a
+// subcommand is given
+func rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "institutionanalyser",
+		Short: "Institution Analyser API and command line tools",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			serve()
+		},
+		SilenceUsage: true,
+	}
+	root.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server (the default)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			serve()
+		},
+	})
+	root.AddCommand(analyseCommand())
+	return root
+}
+
+// analyseOptions are the analyse command's flags
+type analyseOptions struct {
+	ticker     string
+	from       string
+	to         string
+	timeSpan   string
+	multiplier int
+	vwapAnchor string
+	atrPeriod  string
+	strategyID uint
+	userID     string
+	output     string
+	chart      string
+}
+
+// analyseCommand runs one analysis in-process, without the server or its
+// queue, and stores it like a triggered analysis
+func analyseCommand() *cobra.Command {
+	var opts analyseOptions
+	cmd := &cobra.Command{
+		Use:   "analyse",
+		Short: "Run an analysis and store it, without the server",
+		Long: `Runs the deep search analysis of a ticker over a window in this process,
+stores it in DATABASE_URL like an analysis triggered through the API, and
+prints the result. Polygon is called with POLYGON_API_KEY.
+
+  institutionanalyser analyse --ticker SPY --from 2026-09-01 --to 2026-10-14`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAnalyse(cmd.Context(), opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.ticker, "ticker", "", "Ticker to analyse (required)")
+	flags.StringVar(&opts.from, "from", "", "Window start, YYYY-MM-DD (required)")
+	flags.StringVar(&opts.to, "to", "", "Window end, YYYY-MM-DD (default: today)")
+	flags.StringVar(&opts.timeSpan, "timespan", "minute", "Bar timespan: second, minute, hour or day")
+	flags.IntVar(&opts.multiplier, "multiplier", 5, "Bar multiplier")
+	flags.StringVar(&opts.vwapAnchor, "vwap-anchor", "", "Anchored VWAP anchor: session, earnings or an RFC3339 timestamp (default: none)")
+	flags.StringVar(&opts.atrPeriod, "atr-period", "", "Wilder ATR period, 2-100 (default: 14)")
+	flags.UintVar(&opts.strategyID, "strategy-id", 0, "Strategy whose rules produce the signals (default: the built-in signals)")
+	flags.StringVar(&opts.userID, "user", handlers.DefaultUserID, "User the analysis is stored for")
+	flags.StringVarP(&opts.output, "output", "o", "text", "Output format: text or json")
+	flags.StringVar(&opts.chart, "chart", "", "Also write the analysis chart as PNG to this file")
+	cmd.MarkFlagRequired("ticker")
+	cmd.MarkFlagRequired("from")
+	return cmd
+}
+
+func runAnalyse(ctx context.Context, opts analyseOptions) error {
+	ticker := strings.ToUpper(strings.TrimSpace(opts.ticker))
+	if ticker == "" {
+		return fmt.Errorf("--ticker is required")
+	}
+	from, err := time.Parse("2006-01-02", opts.from)
+	if err != nil {
+		return fmt.Errorf("invalid --from %q, use YYYY-MM-DD", opts.from)
+	}
+	to := time.Now()
+	if opts.to != "" {
+		if to, err = time.Parse("2006-01-02", opts.to); err != nil {
+			return fmt.Errorf("invalid --to %q, use YYYY-MM-DD", opts.to)
+		}
+	}
+	if to.Before(from) {
+		return fmt.Errorf("--to cannot be before --from")
+	}
+	switch opts.timeSpan {
+	case "second", "minute", "hour", "day":
+	default:
+		return fmt.Errorf("invalid --timespan %q (second, minute, hour or day)", opts.timeSpan)
+	}
+	if opts.multiplier < 1 {
+		return fmt.Errorf("--multiplier must be positive")
+	}
+	if opts.output != "text" && opts.output != "json" {
+		return fmt.Errorf("invalid --output %q (text or json)", opts.output)
+	}
+	vwapAnchor, err := deepsearch.ParseVWAPAnchor(opts.vwapAnchor)
+	if err != nil {
+		return err
+	}
+	atrPeriod, err := deepsearch.ParseATRPeriod(opts.atrPeriod)
+	if err != nil {
+		return err
+	}
+
+	// The analysis logs its progress to stdout, which is kept for the result
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	configureOutbound()
+
+	dbDSN := os.Getenv("DATABASE_URL")
+	if dbDSN == "" {
+		return fmt.Errorf("DATABASE_URL environment variable is required. Please set it in your .env file or as an environment variable.")
+	}
+	db, err := models.InitDatabase(dbDSN)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer models.CloseDatabase(db)
+
+	// Ctrl-C cancels the Polygon calls in flight
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = usage.WithUser(usage.WithFeature(ctx, usage.FeatureCLI), opts.userID)
+	// Calls are counted in memory, so write them before exiting
+	defer func() {
+		if err := usage.Flush(context.Background(), db); err != nil {
+			log.Printf("Failed to record Polygon usage: %v", err)
+		}
+	}()
+
+	svc := deepsearch.NewDeepSearchService(from.Format("2006-01-02"), to.Format("2006-01-02"), opts.timeSpan, opts.multiplier, ticker, opts.userID, db)
+	svc.SetVWAPAnchor(vwapAnchor)
+	svc.SetATRPeriod(atrPeriod)
+	if opts.strategyID != 0 {
+		strategy, err := deepsearch.LoadStrategy(ctx, db, opts.strategyID)
+		if err != nil {
+			return err
+		}
+		if err := svc.SetStrategy(strategy); err != nil {
+			return err
+		}
+	}
+
+	result, err := svc.AnalyseMain(ctx)
+	if err != nil {
+		return fmt.Errorf("analysis failed: %w", err)
+	}
+
+	if opts.chart != "" {
+		chart, err := svc.ChartPNG()
+		if err != nil {
+			return fmt.Errorf("failed to render chart: %w", err)
+		}
+		if err := os.WriteFile(opts.chart, chart, 0o644); err != nil {
+			return fmt.Errorf("failed to write chart: %w", err)
+		}
+	}
+
+	if opts.output == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	printAnalysis(stdout, result)
+	return nil
+}
+
+// printAnalysis writes a stored analysis as text
+func printAnalysis(w io.Writer, result *models.TechnicalSignal) {
+	fmt.Fprintf(w, "Analysis %d: %s %s to %s (%s/%d)\n", result.ID, result.Ticker, result.PolyStartDuration, result.PolyEndDuration, result.PolyTimeSpan, result.PolyMultiplier)
+	fmt.Fprintf(w, "Decision:   %s (confidence %.2f)\n", result.FinalDecision, result.Confidence)
+	fmt.Fprintf(w, "Last close: %.2f %s\n", result.LastClose, result.Currency)
+	if result.TrailingStop != 0 {
+		fmt.Fprintf(w, "Stop:       %.2f (%s)\n", result.TrailingStop, result.TrailingStopSide)
+	}
+	fmt.Fprintf(w, "Signals:    %d\n", len(result.Signals))
+	for _, signal := range result.Signals {
+		fmt.Fprintf(w, "  %s\n", signal)
+	}
+}
@@ -0,0 +1,47 @@
+// Command seed loads the bundled sample dataset (SPY/AAPL minute bars and
+// earnings fixtures) into DATABASE_URL, for demos and fresh deployments
+// without a Polygon key:
+//
+//	go run ./cmd/seed
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"institutionanalyser/models"
+	"institutionanalyser/seed"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		fmt.Println("Note: .env file not found, using environment variables only")
+	}
+
+	dbDSN := os.Getenv("DATABASE_URL")
+	if dbDSN == "" {
+		log.Fatal("DATABASE_URL environment variable is required. Please set it in your .env file or as an environment variable.")
+	}
+
+	// Migrations run on connect, so a fresh database can be seeded before the API first starts
+	db, err := models.InitDatabase(dbDSN)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		if sqlDB != nil {
+			sqlDB.Close()
+		}
+	}()
+
+	summary, err := seed.Load(context.Background(), db)
+	if err != nil {
+		log.Fatalf("Failed to load seed data: %v", err)
+	}
+	fmt.Printf("Seeded %d tickers, %d bars and %d earnings estimates\n", summary.Tickers, summary.Bars, summary.Earnings)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"crypto/tls"
+	"os"
+	"strconv"
+	"time"
+)
+
+// HTTPClientConfig holds settings for outbound HTTP calls (Polygon, earnings calendar)
+type HTTPClientConfig struct {
+	// ProxyURL routes all outbound traffic through an egress proxy. When empty
+	// the standard HTTPS_PROXY/HTTP_PROXY/NO_PROXY variables are honoured.
+	ProxyURL string
+	// CABundlePath is a PEM file of extra root CAs, e.g. a corporate TLS-inspecting proxy's CA
+	CABundlePath        string
+	InsecureSkipVerify  bool
+	MinTLSVersion       uint16
+	Timeout             time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// GetHTTPClientConfig reads outbound HTTP settings from environment variables
+// with sensible defaults if not provided
+func GetHTTPClientConfig() HTTPClientConfig {
+	config := HTTPClientConfig{
+		ProxyURL:            os.Getenv("EGRESS_PROXY_URL"),
+		CABundlePath:        os.Getenv("TLS_CA_BUNDLE"),
+		InsecureSkipVerify:  os.Getenv("TLS_INSECURE_SKIP_VERIFY") == "true",
+		MinTLSVersion:       tls.VersionTLS12,
+		Timeout:             30 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	if os.Getenv("TLS_MIN_VERSION") == "1.3" {
+		config.MinTLSVersion = tls.VersionTLS13
+	}
+
+	if val := os.Getenv("HTTP_CLIENT_TIMEOUT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.Timeout = time.Duration(n) * time.Second
+		}
+	}
+
+	if val := os.Getenv("HTTP_MAX_IDLE_CONNS_PER_HOST"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.MaxIdleConnsPerHost = n
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PolygonClientConfig holds the endpoint and the client-side rate limit and
+// retry policy shared by every Polygon call, so concurrent handlers stay within
+// the plan's quota
+type PolygonClientConfig struct {
+	// BaseURL is the Polygon REST endpoint, e.g. a regional or enterprise host
+	BaseURL string
+	// MirrorURL is a read-through cache or proxy in front of BaseURL. When set,
+	// requests go to it first and fall back to BaseURL if it fails.
+	MirrorURL string
+	// RequestsPerSecond and Burst size the token bucket; zero disables limiting
+	RequestsPerSecond float64
+	Burst             int
+	// MaxRetries is how often a 429, 5xx or network failure is retried
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// GetPolygonClientConfig reads Polygon rate limit settings from environment
+// variables with sensible defaults if not provided
+func GetPolygonClientConfig() PolygonClientConfig {
+	config := PolygonClientConfig{
+		BaseURL:           "https://api.polygon.io",
+		MirrorURL:         strings.TrimSuffix(os.Getenv("POLYGON_MIRROR_URL"), "/"),
+		RequestsPerSecond: 20,
+		Burst:             20,
+		MaxRetries:        4,
+		RetryBaseDelay:    500 * time.Millisecond,
+		RetryMaxDelay:     15 * time.Second,
+	}
+
+	if val := os.Getenv("POLYGON_BASE_URL"); val != "" {
+		config.BaseURL = strings.TrimSuffix(val, "/")
+	}
+
+	if val := os.Getenv("POLYGON_RATE_LIMIT_RPS"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n >= 0 {
+			config.RequestsPerSecond = n
+		}
+	}
+
+	if val := os.Getenv("POLYGON_RATE_LIMIT_BURST"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.Burst = n
+		}
+	}
+
+	if val := os.Getenv("POLYGON_MAX_RETRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			config.MaxRetries = n
+		}
+	}
+
+	if val := os.Getenv("POLYGON_RETRY_BASE_DELAY_MS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.RetryBaseDelay = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	if val := os.Getenv("POLYGON_RETRY_MAX_DELAY_MS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.RetryMaxDelay = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	return config
+}
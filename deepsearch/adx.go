@@ -0,0 +1,137 @@
+package deepsearch
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/models"
+)
+
+const (
+	adxPeriod = 14
+	// defaultADXTrendThreshold is the ADX below which the market is treated as choppy
+	defaultADXTrendThreshold = 20.0
+)
+
+// Market regimes tagged on signals once ADX is available
+const (
+	RegimeTrending = "TRENDING"
+	RegimeChoppy   = "CHOPPY"
+)
+
+// adxCalculator computes Wilder's ADX and directional indicators one bar at a time
+type adxCalculator struct {
+	period int
+	bars   int
+
+	prevHigh, prevLow, prevClose float64
+	// Wilder-smoothed true range and directional movement
+	tr, plusDM, minusDM float64
+	dxSum               float64
+	dxCount             int
+	adx                 float64
+}
+
+// next adds a bar and returns ADX, +DI and -DI. ADX is zero until 2*period
+// bars have been seen; the DIs from period+1 bars.
+func (a *adxCalculator) next(high, low, close float64) (float64, float64, float64) {
+	a.bars++
+	if a.bars == 1 {
+		a.prevHigh, a.prevLow, a.prevClose = high, low, close
+		return 0, 0, 0
+	}
+
+	tr := math.Max(high-low, math.Max(math.Abs(high-a.prevClose), math.Abs(low-a.prevClose)))
+	up, down := high-a.prevHigh, a.prevLow-low
+	plusDM, minusDM := 0.0, 0.0
+	if up > down && up > 0 {
+		plusDM = up
+	}
+	if down > up && down > 0 {
+		minusDM = down
+	}
+	a.prevHigh, a.prevLow, a.prevClose = high, low, close
+
+	period := float64(a.period)
+	if a.bars <= a.period+1 {
+		// Seed the smoothed sums with the first period of movements
+		a.tr += tr
+		a.plusDM += plusDM
+		a.minusDM += minusDM
+		if a.bars < a.period+1 {
+			return 0, 0, 0
+		}
+	} else {
+		a.tr = a.tr - a.tr/period + tr
+		a.plusDM = a.plusDM - a.plusDM/period + plusDM
+		a.minusDM = a.minusDM - a.minusDM/period + minusDM
+	}
+
+	if a.tr == 0 {
+		return a.adx, 0, 0
+	}
+	plusDI := 100 * a.plusDM / a.tr
+	minusDI := 100 * a.minusDM / a.tr
+	dx := 0.0
+	if plusDI+minusDI > 0 {
+		dx = 100 * math.Abs(plusDI-minusDI) / (plusDI + minusDI)
+	}
+
+	if a.dxCount < a.period {
+		a.dxSum += dx
+		a.dxCount++
+		if a.dxCount == a.period {
+			a.adx = a.dxSum / period
+		}
+	} else {
+		a.adx = (a.adx*(period-1) + dx) / period
+	}
+	return a.adx, plusDI, minusDI
+}
+
+// adxTrendThreshold returns the ADX below which CALL/PUT signals are
+// suppressed; ADX_TREND_THRESHOLD overrides the default and 0 disables gating
+func adxTrendThreshold() float64 {
+	if val := os.Getenv("ADX_TREND_THRESHOLD"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultADXTrendThreshold
+}
+
+// regime classifies a bar by its ADX, or "" before ADX is available
+func regime(bar EnhancedBar, threshold float64) string {
+	switch {
+	case bar.ADX == 0:
+		return ""
+	case bar.ADX < threshold:
+		return RegimeChoppy
+	default:
+		return RegimeTrending
+	}
+}
+
+// BarRegime classifies a bar by its ADX against the current trend
+// threshold, or "" before ADX is available
+func BarRegime(bar EnhancedBar) string {
+	return regime(bar, adxTrendThreshold())
+}
+
+// appendSignal tags a signal with the bar's regime and appends it, dropping
+// CALL/PUT signals in a choppy market. crossings are the thresholds behind it.
+func appendSignal(signals []Signal, bar EnhancedBar, text string, threshold float64, crossings ...models.ThresholdCrossing) []Signal {
+	r := regime(bar, threshold)
+	if r == RegimeChoppy && threshold > 0 {
+		if _, rest, ok := strings.Cut(text, " "); ok && (strings.HasPrefix(rest, "CALL:") || strings.HasPrefix(rest, "PUT:")) {
+			return signals
+		}
+	}
+	if r != "" {
+		text += fmt.Sprintf(" [%s ADX %.1f]", r, bar.ADX)
+	}
+	return append(signals, newSignal(bar, text, crossings))
+}
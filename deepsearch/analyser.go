@@ -1,23 +1,24 @@
 package deepsearch
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 
-	"os"
 	"sort"
 	"strings"
 	"time"
 
 	models "institutionanalyser/models"
 	"institutionanalyser/service"
+	"institutionanalyser/tracing"
 
-	"github.com/lib/pq"
 	"github.com/polygon-io/client-go/rest/iter"
 	polygonmodels "github.com/polygon-io/client-go/rest/models"
-	chart "github.com/wcharczuk/go-chart/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -37,8 +38,65 @@ type EnhancedBar struct {
 	InstitutionalFlow bool
 	ATR               float64
 	VWAP              float64
+	// Bollinger Bands over the last bollingerPeriod closes; zero until enough bars
+	BollingerMiddle float64
+	BollingerUpper  float64
+	BollingerLower  float64
+	// BollingerWidth is the band width relative to the middle band
+	BollingerWidth float64
+	// BollingerSqueeze is set while the width is the narrowest of the last bollingerSqueezeLookback bars
+	BollingerSqueeze bool
+	// ADX(14) trend strength with its directional indicators; zero until enough bars
+	ADX     float64
+	PlusDI  float64
+	MinusDI float64
+	// OBV is on-balance volume, cumulative from the first fetched bar
+	OBV float64
+	// AnchoredVWAP is the VWAP since AnchoredVWAPStart, the anchor in effect;
+	// zero without an anchor or before the first one
+	AnchoredVWAP      float64
+	AnchoredVWAPStart time.Time
+	// OBVDivergence is +1 when the close makes a new obvDivergenceLookback-bar
+	// low that OBV does not confirm (accumulation), -1 for an unconfirmed new
+	// high (distribution), else 0
+	OBVDivergence int
+	// SuperTrend is the ATR-based trailing line, below price while
+	// SuperTrendDirection is +1 and above it while -1; zero until ATR is available
+	SuperTrend          float64
+	SuperTrendDirection int
+	// YearHigh and YearLow are the 52-week high and low of the daily bars
+	// before the bar's day; zero when daily bars were unavailable
+	YearHigh float64
+	YearLow  float64
+	// ConfirmedMove is +1 (-1) when a stored confirmed institutional move
+	// upwards (downwards) fell in the bar, with the sources that agreed
+	ConfirmedMove    int
+	ConfirmedSources []string
+	// InsiderBuyers is how many insiders bought on the open market in the
+	// cluster known on the bar's day, 0 without a cluster; InsiderBuyValue is what they paid
+	InsiderBuyers   int
+	InsiderBuyValue float64
+	// FTDShares is the failed shares on the bar's day when it was a
+	// fails-to-deliver spike, 0 otherwise; FTDZScore is the spike's z-score
+	FTDShares int64
+	FTDZScore float64
 }
 
+const (
+	bollingerPeriod          = 20
+	bollingerStdDev          = 2.0
+	bollingerSqueezeLookback = 50
+	obvDivergenceLookback    = 20
+	// dojiBodyRatio is the largest body, relative to the bar's range, of a doji
+	dojiBodyRatio = 0.1
+	// volumeSpikeZScore is the volume z-score of a volume spike signal
+	volumeSpikeZScore = 2.0
+	// atrExpansionFactor is how far ATR must jump over the previous bar's
+	atrExpansionFactor = 1.5
+	// institutionalZScore is the volume z-score institutional flow signals need
+	institutionalZScore = 1.0
+)
+
 type DeepSearchService struct {
 	//polygonSvc    *service.StockTechnicalService
 	startDuration string
@@ -48,6 +106,21 @@ type DeepSearchService struct {
 	ticker        string
 	userId        string
 	db            *gorm.DB
+	// bars holds the last analysed window, for rendering its chart
+	bars []EnhancedBar
+	// vwapAnchor enables anchored VWAP (see SetVWAPAnchor)
+	vwapAnchor string
+	// atrPeriod is the Wilder ATR period (see SetATRPeriod)
+	atrPeriod int
+	// strategy and its compiled rules replace or extend the built-in signals (see SetStrategy)
+	strategy      *models.Strategy
+	strategyRules []strategyRule
+	// yearRange is the 52-week context at the last fetched bar
+	yearRange *models.YearRange
+	// ivRank is the implied volatility rank on the last fetched bar's day
+	ivRank *models.IVRank
+	// currency is the ticker's quote currency, read on first use (see quoteCurrency)
+	currency *models.QuoteCurrency
 }
 
 func NewDeepSearchService(startDuration, endDuration, timeSpan string, multiplier int, ticker string, userId string, db *gorm.DB) *DeepSearchService {
@@ -59,6 +132,7 @@ func NewDeepSearchService(startDuration, endDuration, timeSpan string, multiplie
 		ticker:        ticker,
 		userId:        userId,
 		db:            db,
+		atrPeriod:     defaultATRPeriod,
 	}
 }
 
@@ -82,111 +156,178 @@ func (s *DeepSearchService) UserId() string {
 	return s.userId
 }
 
-func (s *DeepSearchService) AnalyseWithTechnicals() error {
+// TechnicalDecision is the decision table's verdict on the latest bar and the
+// daily indicators, with the trace of the rules it evaluated
+type TechnicalDecision struct {
+	Ticker string    `json:"ticker"`
+	AsOf   time.Time `json:"as_of"`
+	*RuleEvaluation
+	Signals    []StructuredSignal          `json:"signals"`
+	Benchmarks *models.ExecutionBenchmarks `json:"benchmarks,omitempty"`
+	YearRange  *models.YearRange           `json:"year_range,omitempty"`
+	IVRank     *models.IVRank              `json:"iv_rank,omitempty"`
+	Warnings   []string                    `json:"warnings,omitempty"`
+}
+
+// AnalyseWithTechnicals decides from the latest bar of the window and the daily
+// SMA, RSI and MACD using the decision rule table. Indicators that cannot be
+// fetched are left out of the inputs, so conditions on them do not hold.
+func (s *DeepSearchService) AnalyseWithTechnicals(ctx context.Context, rules []models.DecisionRule) (*TechnicalDecision, error) {
 	// Minute-by-minute data
 	svc := service.NewStockTechnicalService(s.ticker)
-	bars, err := svc.GetPolygonAggregate(s.timeSpan, s.startDuration, s.endDuration, s.multiplier)
-
+	allBars, from, err := s.fetchEnhancedBars(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	enhancedBars := enhanceData(bars)
-
+	enhancedBars := allBars[from:]
 	if len(enhancedBars) == 0 {
-		return errors.New("no enhanced bars")
+		return nil, errors.New("no enhanced bars")
 	}
 
-	signals := generateSignals(enhancedBars)
+	signals := s.emitSignals(allBars, from, DefaultSignalParams())
 
 	// Store signals in the database if there are any
 	if len(signals) > 0 && len(enhancedBars) > 0 {
-		s.storeSignalsInDatabase(enhancedBars, signals, s.ticker)
+		s.storeSignalsInDatabase(ctx, enhancedBars, signals, s.ticker)
 	}
 
-	// Daily technicals
-
-	sma, _ := svc.FetchSMA(20)
-	rsi, _ := svc.FetchRSI(14)
-	macd, _ := svc.FetchMACD(12, 26, 9)
-
-	// Latest values
 	latestBar := enhancedBars[len(enhancedBars)-1]
-	latestSMA := sma.Results.Values[0].Value
-	latestRSI := rsi.Results.Values[0].Value
-	latestMACD := macd.Results.Values[0]
-
-	fmt.Printf("\nLatest %s Analysis:\n", s.ticker)
-	fmt.Printf("Price: %.2f | VWAP: %.2f | ATR: %.2f\n", latestBar.Close, latestBar.CumulativeVWAP, latestBar.ATR)
-	fmt.Printf("SMA(20): %.2f | RSI: %.2f | MACD: %.2f (Signal: %.2f)\n", latestSMA, latestRSI, latestMACD.Value, latestMACD.Signal)
-
-	// Decision logic
-	if latestBar.Close < latestBar.CumulativeVWAP && latestRSI < 30 && latestMACD.Value > latestMACD.Signal {
-		fmt.Println("Decision: BUY - Cheap price, oversold, bullish momentum.")
-	} else if latestBar.Close > latestBar.CumulativeVWAP && latestRSI > 70 && latestMACD.Value < latestMACD.Signal {
-		fmt.Println("Decision: SELL - Expensive price, overbought, bearish momentum.")
-	} else if len(enhancedBars) > 1 && latestBar.ATR > enhancedBars[len(enhancedBars)-2].ATR*1.5 {
-		fmt.Println("Decision: HOLD/STRADDLE - Volatility spiking, no clear trend.")
-	} else {
-		fmt.Println("Decision: HOLD - No strong signals.")
+	benchmarks := executionBenchmarks(enhancedBars)
+	inputs := map[string]float64{
+		"price": latestBar.Close,
+		"vwap":  latestBar.CumulativeVWAP,
+		"twap":  benchmarks.TWAP,
+		"atr":   latestBar.ATR,
+	}
+	if len(enhancedBars) > 1 {
+		inputs["prev_atr"] = enhancedBars[len(enhancedBars)-2].ATR
 	}
 
-	printSignals(signals)
-	// winRate := evaluateSignals(enhancedBars, signals)
-	// fmt.Printf("Signal Win Rate: %.2f%%\n", winRate*100)
+	// Daily technicals
+	var warnings []string
+	if sma, err := svc.FetchSMA(20); err != nil || len(sma.Results.Values) == 0 {
+		warnings = append(warnings, "SMA(20) unavailable")
+	} else {
+		inputs["sma"] = sma.Results.Values[0].Value
+	}
+	if rsi, err := svc.FetchRSI(14); err != nil || len(rsi.Results.Values) == 0 {
+		warnings = append(warnings, "RSI(14) unavailable")
+	} else {
+		inputs["rsi"] = rsi.Results.Values[0].Value
+	}
+	if macd, err := svc.FetchMACD(12, 26, 9); err != nil || len(macd.Results.Values) == 0 {
+		warnings = append(warnings, "MACD(12, 26, 9) unavailable")
+	} else {
+		inputs["macd"] = macd.Results.Values[0].Value
+		inputs["macd_signal"] = macd.Results.Values[0].Signal
+		inputs["macd_hist"] = macd.Results.Values[0].Histogram
+	}
 
-	return nil
+	evaluation := EvaluateDecisionRules(rules, inputs)
+	fmt.Printf("[deepsearch] %s technicals decision: %s (%s) by rule %q\n", s.ticker, evaluation.Decision, evaluation.Reason, evaluation.Rule)
+
+	texts := signalTexts(signals)
+	return &TechnicalDecision{
+		Ticker:         s.ticker,
+		AsOf:           latestBar.Timestamp,
+		RuleEvaluation: evaluation,
+		Signals:        ParseSignals(texts),
+		Benchmarks:     benchmarks,
+		YearRange:      s.yearRange,
+		IVRank:         s.ivRank,
+		Warnings:       warnings,
+	}, nil
 }
 
-func (s *DeepSearchService) AnalyseMain() error {
-	// Fetch data from Polygon
-	svc := service.NewStockTechnicalService(s.ticker)
-
-	bars, err := svc.GetPolygonAggregate(s.timeSpan, s.startDuration, s.endDuration, s.multiplier)
+// AnalyseMain fetches bars, generates signals and stores them, returning the stored record
+func (s *DeepSearchService) AnalyseMain(ctx context.Context) (*models.TechnicalSignal, error) {
+	// Fetch data from Polygon with a warm-up buffer and enhance it with technical indicators
+	fetchCtx, span := tracing.Tracer().Start(ctx, "deepsearch.fetch_aggregates",
+		trace.WithAttributes(attribute.String("ticker", s.ticker), attribute.String("timespan", s.timeSpan)))
+	allBars, from, err := s.fetchEnhancedBars(fetchCtx)
+	span.SetAttributes(attribute.Int("bars", len(allBars)), attribute.Int("warmup_bars", from))
+	endSpan(span, err)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	// Enhance data with technical indicators
-	enhancedBars := enhanceData(bars)
-
+	enhancedBars := allBars[from:]
 	if len(enhancedBars) == 0 {
-		return errors.New("no enhanced bars")
+		return nil, errors.New("no enhanced bars")
 	}
+	s.bars = enhancedBars
 
-	// Generate trading signals
-	signals := generateSignals(enhancedBars)
+	// Keep the bars so the analysis can be re-evaluated without Polygon
+	if storeAnalysedBars() {
+		if err := s.storeBars(ctx, allBars); err != nil {
+			fmt.Printf("[deepsearch] failed to store %d bars for %s: %v\n", len(allBars), s.ticker, err)
+		}
+	}
+	if models.GetTimescaleConfig().Enabled {
+		if err := s.storeEnhancedBars(ctx, enhancedBars); err != nil {
+			fmt.Printf("[deepsearch] failed to store %d enhanced bars for %s: %v\n", len(enhancedBars), s.ticker, err)
+		}
+	}
+
+	// Generate trading signals for the requested window only
+	_, span = tracing.Tracer().Start(ctx, "deepsearch.generate_signals")
+	signals := s.emitSignals(allBars, from, DefaultSignalParams())
+	span.SetAttributes(attribute.Int("signals", len(signals)))
+	span.End()
 
 	// Store signals in the database if there are any
-	if len(signals) > 0 && len(enhancedBars) > 0 {
-		err := s.storeSignalsInDatabase(enhancedBars, signals, s.ticker)
+	if len(signals) == 0 {
+		return nil, errors.New("no signals or enhanced bars")
+	}
 
-		if err != nil {
-			return err
-		}
+	storeCtx, span := tracing.Tracer().Start(ctx, "deepsearch.store_signals")
+	technicalSignal, err := s.storeSignalsInDatabase(storeCtx, enhancedBars, signals, s.ticker)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
 
-	} else {
-		return errors.New("no signals or enhanced bars")
+	// Keep the features the signals were computed from with the analysis
+	if storeAnalysisFeatures() {
+		if err := s.storeAnalysisBars(ctx, technicalSignal.ID, allBars, from); err != nil {
+			fmt.Printf("[deepsearch] failed to store %d bar features for analysis %d: %v\n", len(allBars), technicalSignal.ID, err)
+		}
 	}
 
 	// Print and visualize results
-	printSignals(signals)
+	printSignals(signalTexts(signals))
+
+	return technicalSignal, nil
+}
 
-	return nil
+// enhanceData computes indicators for each bar. Cumulative VWAP is anchored
+// at vwapAnchor so warm-up bars before the analysis window do not skew it.
+func enhanceData(bars *iter.Iter[polygonmodels.Agg], vwapAnchor time.Time, atrPeriod int) []EnhancedBar {
+	var aggs []polygonmodels.Agg
+	for bars.Next() {
+		aggs = append(aggs, bars.Item())
+	}
+	return enhanceAggs(aggs, vwapAnchor, atrPeriod)
 }
 
-func enhanceData(bars *iter.Iter[polygonmodels.Agg]) []EnhancedBar {
+// enhanceAggs is enhanceData over bars already in memory
+func enhanceAggs(aggs []polygonmodels.Agg, vwapAnchor time.Time, atrPeriod int) []EnhancedBar {
 	var enhanced []EnhancedBar
 	var (
 		cumulativeVolume float64
 		cumulativeVWAP   float64
 		volumes          []float64
-		ranges           []float64
 		volumePerTrade   []float64
+		closes           []float64
+		widths           []float64
+		atr              = atrCalculator{period: atrPeriod}
+		trend            = adxCalculator{period: adxPeriod}
+		superTrend       = superTrendCalculator{multiplier: superTrendMultiplier()}
+		obv              []float64
 	)
 
-	for bars.Next() {
-		agg := bars.Item()
+	for _, agg := range aggs {
 		millis := time.Time(agg.Timestamp).UnixMilli() // Convert Millis to int64
 		timestamp := time.UnixMilli(millis)
 		// Convert Agg to EnhancedBar
@@ -201,7 +342,10 @@ func enhanceData(bars *iter.Iter[polygonmodels.Agg]) []EnhancedBar {
 			VWAP:         agg.VWAP,
 		}
 
-		// Calculate cumulative VWAP
+		// Calculate cumulative VWAP, restarting at the anchor
+		if len(enhanced) > 0 && enhanced[len(enhanced)-1].Timestamp.Before(vwapAnchor) && !bar.Timestamp.Before(vwapAnchor) {
+			cumulativeVolume, cumulativeVWAP = 0, 0
+		}
 		cumulativeVolume += bar.Volume
 		cumulativeVWAP += bar.Volume * bar.VWAP
 		if cumulativeVolume > 0 {
@@ -210,16 +354,40 @@ func enhanceData(bars *iter.Iter[polygonmodels.Agg]) []EnhancedBar {
 
 		// Calculate volatility metrics
 		barRange := bar.High - bar.Low
-		ranges = append(ranges, barRange)
-		bar.ATR = calculateATR(ranges, 14)
+		bar.ATR = atr.next(bar.High, bar.Low, bar.Close)
+		bar.ADX, bar.PlusDI, bar.MinusDI = trend.next(bar.High, bar.Low, bar.Close)
+		bar.SuperTrend, bar.SuperTrendDirection = superTrend.next(bar.High, bar.Low, bar.Close, bar.ATR)
 
 		// Volume analysis
 		volumes = append(volumes, bar.Volume)
 		bar.VolumeZScore = volumeZScore(volumes, 14)
 
+		// Bollinger Bands and squeeze
+		closes = append(closes, bar.Close)
+		bar.BollingerMiddle, bar.BollingerUpper, bar.BollingerLower = bollingerBands(closes, bollingerPeriod, bollingerStdDev)
+		if bar.BollingerMiddle > 0 {
+			bar.BollingerWidth = (bar.BollingerUpper - bar.BollingerLower) / bar.BollingerMiddle
+			widths = append(widths, bar.BollingerWidth)
+			bar.BollingerSqueeze = isSqueeze(widths, bollingerSqueezeLookback)
+		}
+
+		// On-balance volume and its divergence from price
+		bar.OBV = bar.Volume
+		if len(enhanced) > 0 {
+			prevBar := enhanced[len(enhanced)-1]
+			bar.OBV = prevBar.OBV
+			if bar.Close > prevBar.Close {
+				bar.OBV += bar.Volume
+			} else if bar.Close < prevBar.Close {
+				bar.OBV -= bar.Volume
+			}
+		}
+		obv = append(obv, bar.OBV)
+		bar.OBVDivergence = obvDivergence(closes, obv, obvDivergenceLookback)
+
 		// Candlestick patterns
 		body := math.Abs(bar.Close - bar.Open)
-		bar.IsDoji = (body/barRange < 0.1) && barRange > 0
+		bar.IsDoji = (body/barRange < dojiBodyRatio) && barRange > 0
 
 		if len(enhanced) > 0 {
 			prevBar := enhanced[len(enhanced)-1]
@@ -245,102 +413,222 @@ func enhanceData(bars *iter.Iter[polygonmodels.Agg]) []EnhancedBar {
 	return enhanced
 }
 
-func generateSignals(bars []EnhancedBar) []string {
-	var signals []string
+// generateSignals emits signals for bars[from:]; earlier bars are warm-up
+// history that only feeds the indicators
+func generateSignals(bars []EnhancedBar, from int) []Signal {
+	return generateSignalsWith(bars, from, DefaultSignalParams())
+}
+
+// generateSignalsWith is generateSignals with the given thresholds,
+// consolidated by bar when params say so
+func generateSignalsWith(bars []EnhancedBar, from int, params SignalParams) []Signal {
+	signals := builtinSignals(bars, from, params)
+	if params.Consolidate {
+		signals = consolidateSignals(signals)
+	}
+	return signals
+}
+
+// builtinSignals emits every built-in signal for bars[from:], one per rule that fires
+func builtinSignals(bars []EnhancedBar, from int, params SignalParams) []Signal {
+	var signals []Signal
+	threshold := params.ADXTrendThreshold
+	// insiderDays and ftdDays are the days an insider cluster or
+	// fails-to-deliver spike signal already fired on
+	insiderDays := map[time.Time]bool{}
+	ftdDays := map[time.Time]bool{}
 	for i, bar := range bars {
-		if i < 3 {
-			continue // Skip first few bars to ensure enough data for indicators
+		if i < from || i < 3 {
+			continue // Skip warm-up and the first few bars to ensure enough data for indicators
 		}
 
 		// Doji pattern
-		if bar.IsDoji {
-			signals = append(signals, fmt.Sprintf("%s STRADDLE: Doji Pattern - Indecision Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Close))
+		if barRange := bar.High - bar.Low; barRange > 0 && math.Abs(bar.Close-bar.Open)/barRange < params.DojiBodyRatio {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s STRADDLE: Doji Pattern - Indecision Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Close), threshold,
+				crossed("body_to_range", "<", math.Abs(bar.Close-bar.Open)/barRange, params.DojiBodyRatio))
 		}
 
 		// Engulfing patterns
 		if bar.BearishEngulfing {
-			signals = append(signals, fmt.Sprintf("%s PUT: Bearish Engulfing - Reversal Likely Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Close))
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s PUT: Bearish Engulfing - Reversal Likely Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Close), threshold,
+				crossed("close_vs_prev_open", "<", bar.Close, bars[i-1].Open))
 		}
 		if bar.BullishEngulfing {
-			signals = append(signals, fmt.Sprintf("%s CALL: Bullish Engulfing - Reversal Likely Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Close))
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s CALL: Bullish Engulfing - Reversal Likely Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Close), threshold,
+				crossed("close_vs_prev_open", ">", bar.Close, bars[i-1].Open))
 		}
 
 		// Volume-based signals
-		if bar.VolumeZScore > 2 && bar.Close < bar.Open {
-			signals = append(signals, fmt.Sprintf("%s PUT: Volume Spike + Price Drop (%.2f) - Institutional Selling Likely Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close))
+		if bar.VolumeZScore > params.VolumeSpikeZScore && bar.Close < bar.Open {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s PUT: Volume Spike + Price Drop (%.2f) - Institutional Selling Likely Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close), threshold,
+				crossed("volume_zscore", ">", bar.VolumeZScore, params.VolumeSpikeZScore), crossed("close_vs_open", "<", bar.Close, bar.Open))
+		}
+		if bar.VolumeZScore > params.VolumeSpikeZScore && bar.Close > bar.Open {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s CALL: Volume Spike + Institutional Flow (%.2f) - Institutional Buying Likely Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close), threshold,
+				crossed("volume_zscore", ">", bar.VolumeZScore, params.VolumeSpikeZScore), crossed("close_vs_open", ">", bar.Close, bar.Open))
+		}
+		if i > 0 && bar.ATR > bars[i-1].ATR*params.ATRExpansionFactor {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s STRADDLE: Volatility Expansion (ATR %.2f) - Institutional Activity Likely Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.ATR, bar.Close), threshold,
+				crossed("atr", ">", bar.ATR, bars[i-1].ATR*params.ATRExpansionFactor))
+		}
+
+		// Bollinger squeeze start and band breakouts
+		if i > 0 {
+			prev := bars[i-1]
+			if bar.BollingerSqueeze && !prev.BollingerSqueeze {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s STRADDLE: Bollinger Squeeze - Band Width %.2f%% at %d-bar Low (Bands %.2f-%.2f) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), bar.BollingerWidth*100, bollingerSqueezeLookback, bar.BollingerLower, bar.BollingerUpper, bar.Close), threshold)
+			}
+			if bar.BollingerUpper > 0 && prev.BollingerUpper > 0 {
+				if bar.Close > bar.BollingerUpper && prev.Close <= prev.BollingerUpper {
+					signals = appendSignal(signals, bar, fmt.Sprintf("%s CALL: Bollinger Breakout - Close Above Upper Band (%.2f) - Closing price (%.2f)",
+						bar.Timestamp.Format("15:04"), bar.BollingerUpper, bar.Close), threshold,
+						crossed("close_vs_upper_band", ">", bar.Close, bar.BollingerUpper))
+				}
+				if bar.Close < bar.BollingerLower && prev.Close >= prev.BollingerLower {
+					signals = appendSignal(signals, bar, fmt.Sprintf("%s PUT: Bollinger Breakdown - Close Below Lower Band (%.2f) - Closing price (%.2f)",
+						bar.Timestamp.Format("15:04"), bar.BollingerLower, bar.Close), threshold,
+						crossed("close_vs_lower_band", "<", bar.Close, bar.BollingerLower))
+				}
+			}
+		}
+
+		// Intraday breakout through a new 52-week level: the close crosses the
+		// prior 52-week high (low) while breaking out of the Bollinger Bands
+		if i > 0 && bar.YearHigh > 0 && bar.BollingerUpper > 0 {
+			prev := bars[i-1]
+			if bar.Close > bar.YearHigh && prev.Close <= bar.YearHigh && bar.Close > bar.BollingerUpper {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s CALL: 52-Week High Breakout - Close Above 52-Week High (%.2f) and Upper Band (%.2f) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), bar.YearHigh, bar.BollingerUpper, bar.Close), threshold,
+					crossed("close_vs_year_high", ">", bar.Close, bar.YearHigh), crossed("close_vs_upper_band", ">", bar.Close, bar.BollingerUpper))
+			}
+			if bar.Close < bar.YearLow && prev.Close >= bar.YearLow && bar.Close < bar.BollingerLower {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s PUT: 52-Week Low Breakdown - Close Below 52-Week Low (%.2f) and Lower Band (%.2f) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), bar.YearLow, bar.BollingerLower, bar.Close), threshold,
+					crossed("close_vs_year_low", "<", bar.Close, bar.YearLow), crossed("close_vs_lower_band", "<", bar.Close, bar.BollingerLower))
+			}
+		}
+
+		// OBV divergence, once per run of unconfirmed highs or lows
+		if i > 0 && bar.OBVDivergence != 0 && bars[i-1].OBVDivergence != bar.OBVDivergence {
+			if bar.OBVDivergence > 0 {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s UP: OBV Bullish Divergence - New %d-bar Low Without OBV Confirmation (Accumulation) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), obvDivergenceLookback, bar.Close), threshold)
+			} else {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s DOWN: OBV Bearish Divergence - New %d-bar High Without OBV Confirmation (Distribution) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), obvDivergenceLookback, bar.Close), threshold)
+			}
+		}
+
+		// Anchored VWAP reclaimed or lost, within the same anchor
+		if i > 0 && bar.AnchoredVWAP > 0 && bars[i-1].AnchoredVWAP > 0 && bar.AnchoredVWAPStart.Equal(bars[i-1].AnchoredVWAPStart) {
+			prev := bars[i-1]
+			anchored := bar.AnchoredVWAPStart.In(marketTimezone).Format("01-02 15:04")
+			if bar.Close > bar.AnchoredVWAP && prev.Close <= prev.AnchoredVWAP {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s CALL: Anchored VWAP Reclaim - Close Above AVWAP (%.2f) Anchored %s - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), bar.AnchoredVWAP, anchored, bar.Close), threshold,
+					crossed("close_vs_anchored_vwap", ">", bar.Close, bar.AnchoredVWAP))
+			} else if bar.Close < bar.AnchoredVWAP && prev.Close >= prev.AnchoredVWAP {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s PUT: Anchored VWAP Lost - Close Below AVWAP (%.2f) Anchored %s - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), bar.AnchoredVWAP, anchored, bar.Close), threshold,
+					crossed("close_vs_anchored_vwap", "<", bar.Close, bar.AnchoredVWAP))
+			}
 		}
-		if bar.VolumeZScore > 2 && bar.Close > bar.Open {
-			signals = append(signals, fmt.Sprintf("%s CALL: Volume Spike + Institutional Flow (%.2f) - Institutional Buying Likely Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close))
+
+		// Independent sources agreed on the direction around this bar
+		if bar.ConfirmedMove > 0 {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s UP: Confirmed Institutional Move - %d Sources Agree (%s) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), len(bar.ConfirmedSources), strings.Join(bar.ConfirmedSources, ", "), bar.Close), threshold)
+		} else if bar.ConfirmedMove < 0 {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s DOWN: Confirmed Institutional Move - %d Sources Agree (%s) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), len(bar.ConfirmedSources), strings.Join(bar.ConfirmedSources, ", "), bar.Close), threshold)
+		}
+
+		// Clustered insider buying backed by institutional buying, once a day
+		if bar.InsiderBuyers > 0 && bar.InstitutionalFlow && bar.Close > bar.Open && !insiderDays[marketDay(bar.Timestamp)] {
+			insiderDays[marketDay(bar.Timestamp)] = true
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s UP: Insider Cluster + Institutional Flow - %d Insiders Bought (%.0f) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.InsiderBuyers, bar.InsiderBuyValue, bar.Close), threshold)
 		}
-		if i > 0 && bar.ATR > bars[i-1].ATR*1.5 {
-			signals = append(signals, fmt.Sprintf("%s STRADDLE: Volatility Expansion (ATR %.2f) - Institutional Activity Likely Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.ATR, bar.Close))
+
+		// Fails-to-deliver spike on the bar's settlement date, once a day
+		if bar.FTDShares > 0 && !ftdDays[marketDay(bar.Timestamp)] {
+			ftdDays[marketDay(bar.Timestamp)] = true
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s STRADDLE: FTD Spike - %d Shares Failed to Deliver (z %.1f) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.FTDShares, bar.FTDZScore, bar.Close), threshold)
 		}
 
 		// New directional flow check
-		if bar.InstitutionalFlow && bar.Close > bar.Open && bar.VolumeZScore > 1 {
-			signals = append(signals, fmt.Sprintf("%s UP: Institutional Buying Detected (Volume %.0f) - Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close))
-		} else if bar.InstitutionalFlow && bar.Close < bar.Open && bar.VolumeZScore > 1 {
-			signals = append(signals, fmt.Sprintf("%s DOWN: Institutional Selling Detected (Volume %.0f) - Closing price (%.2f)",
-				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close))
+		if bar.InstitutionalFlow && bar.Close > bar.Open && bar.VolumeZScore > params.InstitutionalZScore {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s UP: Institutional Buying Detected (Volume %.0f) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close), threshold,
+				crossed("volume_zscore", ">", bar.VolumeZScore, params.InstitutionalZScore))
+		} else if bar.InstitutionalFlow && bar.Close < bar.Open && bar.VolumeZScore > params.InstitutionalZScore {
+			signals = appendSignal(signals, bar, fmt.Sprintf("%s DOWN: Institutional Selling Detected (Volume %.0f) - Closing price (%.2f)",
+				bar.Timestamp.Format("15:04"), bar.Volume, bar.Close), threshold,
+				crossed("volume_zscore", ">", bar.VolumeZScore, params.InstitutionalZScore))
 		}
 	}
 
 	return signals
 }
 
-func getFinalDecisionFromSignals(signals []string) string {
-	counts := map[string]int{
-		"BUY":      0,
-		"SELL":     0,
-		"STRADDLE": 0,
-		"HOLD":     0,
-	}
-
-	for _, signal := range signals {
-		s := strings.ToUpper(signal)
-		switch {
-		case strings.Contains(s, "CALL") || strings.Contains(s, "UP") || strings.Contains(s, "BUY"):
-			counts["BUY"]++
-		case strings.Contains(s, "PUT") || strings.Contains(s, "DOWN") || strings.Contains(s, "SELL"):
-			counts["SELL"]++
-		case strings.Contains(s, "STRADDLE"):
-			counts["STRADDLE"]++
-		default:
-			counts["HOLD"]++
-		}
+// signalVote is the decision a signal string counts towards. The direction
+// prefix decides, so words in a strategy rule's name cannot sway the vote;
+// text without one falls back to keywords.
+func signalVote(signal string) string {
+	switch ParseSignal(signal).Direction {
+	case "CALL", "UP":
+		return "BUY"
+	case "PUT", "DOWN":
+		return "SELL"
+	case "STRADDLE":
+		return "STRADDLE"
 	}
 
-	// Find the decision with the highest count
-	final := "HOLD"
-	maxCount := counts["HOLD"]
-	for k, v := range counts {
-		if v > maxCount {
-			final = k
-			maxCount = v
-		}
+	s := strings.ToUpper(signal)
+	switch {
+	case strings.Contains(s, "CALL") || strings.Contains(s, "UP") || strings.Contains(s, "BUY"):
+		return "BUY"
+	case strings.Contains(s, "PUT") || strings.Contains(s, "DOWN") || strings.Contains(s, "SELL"):
+		return "SELL"
+	case strings.Contains(s, "STRADDLE"):
+		return "STRADDLE"
+	default:
+		return "HOLD"
 	}
+}
 
-	return final
+// getFinalDecisionFromSignals weighs each signal by its kind's weight and
+// confidence and returns the direction with the highest score, with its share
+// of the total score as confidence
+func getFinalDecisionFromSignals(signals []string) (string, float64) {
+	return scoredDecision(decisionScores(signals, nil))
 }
 
 // storeSignalsInDatabase stores the technical signals in the PostgreSQL database
-func (s *DeepSearchService) storeSignalsInDatabase(bars []EnhancedBar, signals []string, ticker string) error {
+func (s *DeepSearchService) storeSignalsInDatabase(ctx context.Context, bars []EnhancedBar, signals []Signal, ticker string) (*models.TechnicalSignal, error) {
 	if len(bars) == 0 || len(signals) == 0 {
-		return errors.New("no bars or signals")
+		return nil, errors.New("no bars or signals")
 	}
 
 	// Get the first and last bar to determine the time range
 	firstBar := bars[0]
 	lastBar := bars[len(bars)-1]
 
-	finalDecision := getFinalDecisionFromSignals(signals)
+	finalDecision, confidence := getFinalDecisionFromSignals(signalTexts(signals))
+
+	maxVolumeZScore := 0.0
+	for _, bar := range bars {
+		maxVolumeZScore = math.Max(maxVolumeZScore, bar.VolumeZScore)
+	}
+	stop, stopSide := trailingStop(bars)
 
 	// Create a new TechnicalSignal record
 	technicalSignal := models.TechnicalSignal{
@@ -350,14 +638,31 @@ func (s *DeepSearchService) storeSignalsInDatabase(bars []EnhancedBar, signals [
 		WindowSize:   len(bars),
 		Ticker:       ticker,
 		AnalysisType: "technical",
-		Signals:      pq.StringArray(signals),
+		Signals:      models.StringArray(signalTexts(signals)),
+
+		SignalTimestamps: models.Int64Array(signalTimestamps(signals)),
 
 		PolyStartDuration: s.StartDuration(),
 		PolyEndDuration:   s.EndDuration(),
 		PolyTimeSpan:      s.TimeSpan(),
 		PolyMultiplier:    s.Multiplier(),
+		VWAPAnchor:        s.vwapAnchor,
+		ATRPeriod:         s.atrPeriod,
+		StrategyID:        s.strategyID(),
+		AlgoVersion:       AlgoVersion,
 		FinalDecision:     finalDecision,
+		Confidence:        confidence,
 		UserId:            s.UserId(),
+		LastClose:         lastBar.Close,
+		MaxVolumeZScore:   maxVolumeZScore,
+		TrailingStop:      stop,
+		TrailingStopSide:  stopSide,
+		ATR:               lastBar.ATR,
+		Currency:          s.Currency(),
+		Benchmarks:        executionBenchmarks(bars),
+		YearRange:         s.yearRange,
+		IVRank:            s.ivRank,
+		Explanation:       explainDecision(signals, finalDecision, confidence, nil),
 	}
 
 	fmt.Println("--------------------------------")
@@ -366,62 +671,90 @@ func (s *DeepSearchService) storeSignalsInDatabase(bars []EnhancedBar, signals [
 	fmt.Println("--------------------------------")
 
 	// Store in the database
-	result := s.db.Create(&technicalSignal)
+	result := s.db.WithContext(ctx).Create(&technicalSignal)
 	if result.Error != nil {
-		return result.Error
+		return nil, result.Error
 	}
 
-	return nil
+	if err := storeSignalPerformance(ctx, s.db, &technicalSignal, evaluateSignals(bars, signals)); err != nil {
+		fmt.Printf("[deepsearch] failed to record signal performance for analysis %d: %v\n", technicalSignal.ID, err)
+	}
+	if err := trackSignalReturns(ctx, s.db, &technicalSignal); err != nil {
+		fmt.Printf("[deepsearch] failed to track signal returns for analysis %d: %v\n", technicalSignal.ID, err)
+	}
+
+	return &technicalSignal, nil
 }
 
-// evaluateSignals calculates the win rate of CALL and PUT signals based on the next bar's price movement
-func evaluateSignals(bars []EnhancedBar, signals []string) float64 {
-	if len(bars) < 2 || len(signals) == 0 {
-		return 0.0
+// Helper functions
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	span.End()
+}
 
-	var wins, total int
-	signalIndex := 0
-
-	// Iterate through bars, aligning signals with their corresponding bar
-	for i := 0; i < len(bars)-1 && signalIndex < len(signals); i++ {
-		bar := bars[i]
-		nextBar := bars[i+1]
-		// Check if this bar has a signal
-		if signalIndex < len(signals) && bar.Timestamp.Format("15:04") == strings.Split(signals[signalIndex], " ")[0] {
-			signal := signals[signalIndex]
-			if strings.Contains(signal, "CALL") {
-				total++
-				if nextBar.Close > bar.Close {
-					wins++
-				}
-			} else if strings.Contains(signal, "PUT") {
-				total++
-				if nextBar.Close < bar.Close {
-					wins++
-				}
-			}
-			// Note: STRADDLE isn't directional, so we skip it for win rate
-			signalIndex++
-		}
+// bollingerBands returns the middle, upper and lower bands over the last
+// period closes, or zeros until there are enough
+func bollingerBands(closes []float64, period int, k float64) (float64, float64, float64) {
+	if len(closes) < period || period == 0 {
+		return 0, 0, 0
 	}
+	window := closes[len(closes)-period:]
 
-	if total == 0 {
-		return 0.0
+	mean := 0.0
+	for _, c := range window {
+		mean += c
+	}
+	mean /= float64(period)
+
+	variance := 0.0
+	for _, c := range window {
+		variance += math.Pow(c-mean, 2)
 	}
-	return float64(wins) / float64(total)
+	stdDev := math.Sqrt(variance / float64(period))
+
+	return mean, mean + k*stdDev, mean - k*stdDev
 }
 
-// Helper functions
-func calculateATR(ranges []float64, period int) float64 {
-	if len(ranges) < period {
-		return 0.0
+// obvDivergence compares the latest close and OBV against the previous
+// lookback bars: +1 for a new closing low with OBV above its low, -1 for a
+// new closing high with OBV below its high
+func obvDivergence(closes, obv []float64, lookback int) int {
+	n := len(closes)
+	if n <= lookback || len(obv) != n {
+		return 0
+	}
+
+	highClose, lowClose := math.Inf(-1), math.Inf(1)
+	highOBV, lowOBV := math.Inf(-1), math.Inf(1)
+	for i := n - 1 - lookback; i < n-1; i++ {
+		highClose, lowClose = math.Max(highClose, closes[i]), math.Min(lowClose, closes[i])
+		highOBV, lowOBV = math.Max(highOBV, obv[i]), math.Min(lowOBV, obv[i])
+	}
+
+	switch {
+	case closes[n-1] > highClose && obv[n-1] < highOBV:
+		return -1
+	case closes[n-1] < lowClose && obv[n-1] > lowOBV:
+		return 1
 	}
-	sum := 0.0
-	for _, r := range ranges[len(ranges)-period:] {
-		sum += r
+	return 0
+}
+
+// isSqueeze reports whether the latest band width is the narrowest of the last lookback widths
+func isSqueeze(widths []float64, lookback int) bool {
+	if len(widths) < lookback {
+		return false
 	}
-	return sum / float64(period)
+	latest := widths[len(widths)-1]
+	for _, w := range widths[len(widths)-lookback : len(widths)-1] {
+		if w < latest {
+			return false
+		}
+	}
+	return latest > 0
 }
 
 func volumeZScore(volumes []float64, lookback int) float64 {
@@ -470,45 +803,8 @@ func printSignals(signals []string) {
 	}
 }
 
-func plotChart(bars []EnhancedBar) {
-	var timeSeries []time.Time
-	var prices, vwap []float64
-
-	for _, bar := range bars {
-		timeSeries = append(timeSeries, bar.Timestamp)
-		prices = append(prices, bar.Close)
-		vwap = append(vwap, bar.CumulativeVWAP)
-	}
-
-	graph := chart.Chart{
-		Title: "SPY Intraday Analysis",
-		XAxis: chart.XAxis{
-			Name:           "Time",
-			ValueFormatter: chart.TimeHourValueFormatter,
-		},
-		YAxis: chart.YAxis{
-			Name: "Price",
-		},
-		Series: []chart.Series{
-			chart.TimeSeries{
-				Name:    "Price",
-				XValues: timeSeries,
-				YValues: prices,
-			},
-			chart.TimeSeries{
-				Name:    "VWAP",
-				XValues: timeSeries,
-				YValues: vwap,
-				Style: chart.Style{
-					StrokeColor:     chart.ColorBlue,
-					StrokeDashArray: []float64{5.0, 5.0},
-				},
-			},
-		},
-	}
-
-	f, _ := os.Create("intraday_chart.png")
-	defer f.Close()
-	graph.Render(chart.PNG, f)
-	fmt.Println("\nChart saved as intraday_chart.png")
+// ChartPNG renders price and cumulative VWAP over the window analysed by the
+// last AnalyseMain call
+func (s *DeepSearchService) ChartPNG() ([]byte, error) {
+	return chartPNG(fmt.Sprintf("%s %s-%s", s.ticker, s.startDuration, s.endDuration), s.bars, nil, ChartOptions{})
 }
@@ -0,0 +1,161 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+)
+
+// Anchored VWAP anchors, besides an explicit RFC3339 timestamp
+const (
+	// AnchorSession restarts the anchored VWAP at every regular session open
+	AnchorSession = "session"
+	// AnchorEarnings anchors at the first session that traded on the last
+	// earnings report on or before the window end
+	AnchorEarnings = "earnings"
+)
+
+// maxAnchorLookback bounds how far before the window an anchor may be, since
+// every bar from the anchor on has to be fetched
+const maxAnchorLookback = 180 * 24 * time.Hour
+
+// ParseVWAPAnchor validates an anchor: "", "session", "earnings" or an RFC3339
+// timestamp, which is returned normalised to UTC
+func ParseVWAPAnchor(raw string) (string, error) {
+	anchor := strings.ToLower(strings.TrimSpace(raw))
+	switch anchor {
+	case "", AnchorSession, AnchorEarnings:
+		return anchor, nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+	if err != nil {
+		return "", errors.New("vwap_anchor must be session, earnings or an RFC3339 timestamp")
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// SetVWAPAnchor enables anchored VWAP and its reclaim/lose signals for the
+// next analysis; anchor is a value accepted by ParseVWAPAnchor
+func (s *DeepSearchService) SetVWAPAnchor(anchor string) {
+	s.vwapAnchor = anchor
+}
+
+// fixedAnchor resolves a timestamp or earnings anchor; session anchors depend
+// on the bars fetched and are resolved by sessionAnchors
+func (s *DeepSearchService) fixedAnchor(ctx context.Context) (time.Time, error) {
+	switch s.vwapAnchor {
+	case "", AnchorSession:
+		return time.Time{}, nil
+	case AnchorEarnings:
+		return s.earningsAnchor(ctx)
+	}
+	return time.Parse(time.RFC3339, s.vwapAnchor)
+}
+
+// earningsAnchor finds the last stored earnings report on or before the window
+// end. Reports after the close trade from the next session's open.
+func (s *DeepSearchService) earningsAnchor(ctx context.Context) (time.Time, error) {
+	var estimates []models.EarningsEstimate
+	err := s.db.WithContext(ctx).
+		Where("ticker = ? AND report_date <= ?", s.ticker, s.endDuration).
+		Order("report_date desc, created_at desc").
+		Limit(1).
+		Find(&estimates).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(estimates) == 0 {
+		return time.Time{}, fmt.Errorf("no earnings date recorded for %s on or before %s", s.ticker, s.endDuration)
+	}
+
+	reportDate, err := time.ParseInLocation("2006-01-02", estimates[0].ReportDate, marketTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid earnings date %q: %w", estimates[0].ReportDate, err)
+	}
+
+	calendar := service.DefaultTradingCalendar()
+	session := calendar.OnOrBeforeTradingDay(ctx, reportDate)
+	if reportedAfterClose(estimates[0].ReportTime) || !session.Equal(reportDate) {
+		session = reportDate
+		for i := 0; i < 10; i++ {
+			session = session.AddDate(0, 0, 1)
+			if calendar.IsTradingDay(ctx, session) {
+				break
+			}
+		}
+	}
+	return sessionOpen(session), nil
+}
+
+// reportedAfterClose reports whether a Benzinga time ("HH:MM:SS" or AMC) is at or after 16:00
+func reportedAfterClose(reportTime string) bool {
+	reportTime = strings.ToUpper(strings.TrimSpace(reportTime))
+	if reportTime == "AMC" {
+		return true
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, reportTime); err == nil {
+			return t.Hour() >= 16
+		}
+	}
+	return false
+}
+
+func sessionOpen(day time.Time) time.Time {
+	day = day.In(marketTimezone)
+	return time.Date(day.Year(), day.Month(), day.Day(), 9, 30, 0, 0, marketTimezone)
+}
+
+// sessionAnchors returns the open of every day the bars cover
+func sessionAnchors(bars []EnhancedBar) []time.Time {
+	var anchors []time.Time
+	for _, bar := range bars {
+		open := sessionOpen(bar.Timestamp)
+		if len(anchors) == 0 || !anchors[len(anchors)-1].Equal(open) {
+			anchors = append(anchors, open)
+		}
+	}
+	return anchors
+}
+
+// applyAnchoredVWAP fills AnchoredVWAP from the first bar at or after each
+// anchor (sorted ascending) until the next one; bars before the first anchor are left at zero
+func applyAnchoredVWAP(bars []EnhancedBar, anchors []time.Time) {
+	var cumulativeVolume, cumulativeValue float64
+	next := 0
+	var current time.Time
+	for i := range bars {
+		for next < len(anchors) && !bars[i].Timestamp.Before(anchors[next]) {
+			current = anchors[next]
+			cumulativeVolume, cumulativeValue = 0, 0
+			next++
+		}
+		if current.IsZero() {
+			continue
+		}
+
+		cumulativeVolume += bars[i].Volume
+		cumulativeValue += bars[i].Volume * bars[i].VWAP
+		if cumulativeVolume > 0 {
+			bars[i].AnchoredVWAP = cumulativeValue / cumulativeVolume
+		}
+		bars[i].AnchoredVWAPStart = current
+	}
+}
+
+// anchoredVWAPFetchStart moves the fetch start back to a fixed anchor before it
+func anchoredVWAPFetchStart(fetchStart, windowStart, anchor time.Time) (time.Time, error) {
+	if anchor.IsZero() || !anchor.Before(fetchStart) {
+		return fetchStart, nil
+	}
+	if windowStart.Sub(anchor) > maxAnchorLookback {
+		return fetchStart, fmt.Errorf("vwap anchor %s is more than %d days before the window", anchor.Format(time.RFC3339), int(maxAnchorLookback.Hours()/24))
+	}
+	a := anchor.In(marketTimezone)
+	return time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, marketTimezone), nil
+}
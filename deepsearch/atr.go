@@ -0,0 +1,67 @@
+package deepsearch
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultATRPeriod = 14
+	// maxATRPeriod bounds the period so the warm-up fetch stays reasonable
+	maxATRPeriod = 100
+)
+
+// ParseATRPeriod validates an ATR period; "" is the default of 14
+func ParseATRPeriod(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultATRPeriod, nil
+	}
+	period, err := strconv.Atoi(raw)
+	if err != nil || period < 2 || period > maxATRPeriod {
+		return 0, fmt.Errorf("atr_period must be an integer between 2 and %d", maxATRPeriod)
+	}
+	return period, nil
+}
+
+// SetATRPeriod sets the ATR period of the next analysis; 0 restores the default
+func (s *DeepSearchService) SetATRPeriod(period int) {
+	if period <= 0 {
+		period = defaultATRPeriod
+	}
+	s.atrPeriod = period
+}
+
+// atrCalculator computes Wilder's ATR one bar at a time
+type atrCalculator struct {
+	period int
+	bars   int
+
+	prevClose float64
+	sum, atr  float64
+}
+
+// next adds a bar and returns the ATR, zero until period bars have been seen.
+// True range is the largest of the bar's range and its gap from the previous
+// close; the first ATR is their mean, later ones Wilder-smoothed.
+func (a *atrCalculator) next(high, low, close float64) float64 {
+	tr := high - low
+	if a.bars > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(high-a.prevClose), math.Abs(low-a.prevClose)))
+	}
+	a.bars++
+	a.prevClose = close
+
+	period := float64(a.period)
+	switch {
+	case a.bars < a.period:
+		a.sum += tr
+	case a.bars == a.period:
+		a.atr = (a.sum + tr) / period
+	default:
+		a.atr = (a.atr*(period-1) + tr) / period
+	}
+	return a.atr
+}
@@ -0,0 +1,162 @@
+package deepsearch
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/parquet"
+
+	"gorm.io/gorm"
+)
+
+// AnalysisBars returns the enhanced bars of a stored analysis as the analysis
+// saw them: the bars stored with it, or recomputed from the bar store for
+// analyses stored without them. With warmup, the warm-up bars before the
+// window are included; from is the index of the first bar in the window.
+// Recomputing the 52-week levels needs Polygon, so recomputed bars have zero
+// YearHigh and YearLow.
+func AnalysisBars(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal, warmup bool) (bars []EnhancedBar, from int, err error) {
+	_, bars, from, err = reloadAnalysis(ctx, db, analysis)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !warmup {
+		return bars[from:], 0, nil
+	}
+	return bars, from, nil
+}
+
+// barColumn is an exported bar field: build makes its Parquet column
+type barColumn struct {
+	name  string
+	build func(name string, bars []EnhancedBar) parquet.Column
+}
+
+func floatBarColumn(value func(EnhancedBar) float64) func(string, []EnhancedBar) parquet.Column {
+	return func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]float64, len(bars))
+		for i, bar := range bars {
+			values[i] = value(bar)
+		}
+		return parquet.DoubleColumn(name, values)
+	}
+}
+
+func intBarColumn(value func(EnhancedBar) int) func(string, []EnhancedBar) parquet.Column {
+	return func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]int32, len(bars))
+		for i, bar := range bars {
+			values[i] = int32(value(bar))
+		}
+		return parquet.Int32Column(name, values)
+	}
+}
+
+func boolBarColumn(value func(EnhancedBar) bool) func(string, []EnhancedBar) parquet.Column {
+	return func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]bool, len(bars))
+		for i, bar := range bars {
+			values[i] = value(bar)
+		}
+		return parquet.BoolColumn(name, values)
+	}
+}
+
+func timeBarColumn(value func(EnhancedBar) time.Time) func(string, []EnhancedBar) parquet.Column {
+	return func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]time.Time, len(bars))
+		for i, bar := range bars {
+			values[i] = value(bar)
+		}
+		return parquet.TimestampColumn(name, values)
+	}
+}
+
+// barColumns are the exported columns after timestamp and warmup
+var barColumns = []barColumn{
+	{"open", floatBarColumn(func(b EnhancedBar) float64 { return b.Open })},
+	{"high", floatBarColumn(func(b EnhancedBar) float64 { return b.High })},
+	{"low", floatBarColumn(func(b EnhancedBar) float64 { return b.Low })},
+	{"close", floatBarColumn(func(b EnhancedBar) float64 { return b.Close })},
+	{"volume", floatBarColumn(func(b EnhancedBar) float64 { return b.Volume })},
+	{"transactions", floatBarColumn(func(b EnhancedBar) float64 { return b.Transactions })},
+	{"vwap", floatBarColumn(func(b EnhancedBar) float64 { return b.VWAP })},
+	{"cumulative_vwap", floatBarColumn(func(b EnhancedBar) float64 { return b.CumulativeVWAP })},
+	{"anchored_vwap", floatBarColumn(func(b EnhancedBar) float64 { return b.AnchoredVWAP })},
+	{"anchored_vwap_start", timeBarColumn(func(b EnhancedBar) time.Time { return b.AnchoredVWAPStart })},
+	{"volume_z_score", floatBarColumn(func(b EnhancedBar) float64 { return b.VolumeZScore })},
+	{"atr", floatBarColumn(func(b EnhancedBar) float64 { return b.ATR })},
+	{"bollinger_middle", floatBarColumn(func(b EnhancedBar) float64 { return b.BollingerMiddle })},
+	{"bollinger_upper", floatBarColumn(func(b EnhancedBar) float64 { return b.BollingerUpper })},
+	{"bollinger_lower", floatBarColumn(func(b EnhancedBar) float64 { return b.BollingerLower })},
+	{"bollinger_width", floatBarColumn(func(b EnhancedBar) float64 { return b.BollingerWidth })},
+	{"bollinger_squeeze", boolBarColumn(func(b EnhancedBar) bool { return b.BollingerSqueeze })},
+	{"adx", floatBarColumn(func(b EnhancedBar) float64 { return b.ADX })},
+	{"plus_di", floatBarColumn(func(b EnhancedBar) float64 { return b.PlusDI })},
+	{"minus_di", floatBarColumn(func(b EnhancedBar) float64 { return b.MinusDI })},
+	{"obv", floatBarColumn(func(b EnhancedBar) float64 { return b.OBV })},
+	{"obv_divergence", intBarColumn(func(b EnhancedBar) int { return b.OBVDivergence })},
+	{"super_trend", floatBarColumn(func(b EnhancedBar) float64 { return b.SuperTrend })},
+	{"super_trend_direction", intBarColumn(func(b EnhancedBar) int { return b.SuperTrendDirection })},
+	{"doji", boolBarColumn(func(b EnhancedBar) bool { return b.IsDoji })},
+	{"bearish_engulfing", boolBarColumn(func(b EnhancedBar) bool { return b.BearishEngulfing })},
+	{"bullish_engulfing", boolBarColumn(func(b EnhancedBar) bool { return b.BullishEngulfing })},
+	{"institutional_flow", boolBarColumn(func(b EnhancedBar) bool { return b.InstitutionalFlow })},
+	{"confirmed_move", intBarColumn(func(b EnhancedBar) int { return b.ConfirmedMove })},
+	{"confirmed_sources", func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]string, len(bars))
+		for i, bar := range bars {
+			values[i] = strings.Join(bar.ConfirmedSources, ",")
+		}
+		return parquet.StringColumn(name, values)
+	}},
+	{"insider_buyers", intBarColumn(func(b EnhancedBar) int { return b.InsiderBuyers })},
+	{"insider_buy_value", floatBarColumn(func(b EnhancedBar) float64 { return b.InsiderBuyValue })},
+	{"ftd_shares", func(name string, bars []EnhancedBar) parquet.Column {
+		values := make([]int64, len(bars))
+		for i, bar := range bars {
+			values[i] = bar.FTDShares
+		}
+		return parquet.Int64Column(name, values)
+	}},
+	{"ftd_z_score", floatBarColumn(func(b EnhancedBar) float64 { return b.FTDZScore })},
+}
+
+// WriteBarsParquet writes an analysis's bars as a Parquet table, one row per
+// bar, with the analysis settings as file metadata. Bars before from are
+// flagged as warm-up.
+func WriteBarsParquet(w io.Writer, analysis *models.TechnicalSignal, bars []EnhancedBar, from int) error {
+	warmup := make([]bool, len(bars))
+	for i := 0; i < from && i < len(bars); i++ {
+		warmup[i] = true
+	}
+	columns := []parquet.Column{
+		timeBarColumn(func(b EnhancedBar) time.Time { return b.Timestamp })("timestamp", bars),
+		parquet.BoolColumn("warmup", warmup),
+	}
+	for _, c := range barColumns {
+		columns = append(columns, c.build(c.name, bars))
+	}
+
+	file := parquet.File{
+		Columns: columns,
+		Metadata: map[string]string{
+			"analysis_id":    strconv.FormatUint(uint64(analysis.ID), 10),
+			"ticker":         analysis.Ticker,
+			"start_duration": analysis.PolyStartDuration,
+			"end_duration":   analysis.PolyEndDuration,
+			"timespan":       analysis.PolyTimeSpan,
+			"multiplier":     strconv.Itoa(analysis.PolyMultiplier),
+			"vwap_anchor":    analysis.VWAPAnchor,
+			"atr_period":     strconv.Itoa(analysis.ATRPeriod),
+			"algo_version":   strconv.Itoa(analysis.AlgoVersion),
+			"currency":       analysis.Currency,
+		},
+	}
+	_, err := file.WriteTo(w)
+	return err
+}
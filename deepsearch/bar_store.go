@@ -0,0 +1,229 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"institutionanalyser/models"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+	"gorm.io/gorm"
+)
+
+// analysisBarBatchSize is how many analysis bars are inserted per statement
+const analysisBarBatchSize = 1000
+
+// storeAnalysedBars reports whether analyses write their bars to the bar
+// store, so they can be re-evaluated later without Polygon; ANALYSIS_STORE_BARS=false turns it off
+func storeAnalysedBars() bool {
+	return os.Getenv("ANALYSIS_STORE_BARS") != "false"
+}
+
+// storeAnalysisFeatures reports whether analyses keep their bars' features
+// with them, for auditing and re-evaluation; ANALYSIS_STORE_FEATURES=false turns it off
+func storeAnalysisFeatures() bool {
+	return os.Getenv("ANALYSIS_STORE_FEATURES") != "false"
+}
+
+// storeBars writes analysed bars, warm-up included, to the bar store. The
+// store keeps prices as quoted, so normalized prices are turned back.
+func (s *DeepSearchService) storeBars(ctx context.Context, bars []EnhancedBar) error {
+	scale := s.quoteCurrency().Scale
+	stored := make([]models.Bar, 0, len(bars))
+	for _, bar := range bars {
+		stored = append(stored, models.Bar{
+			Ticker:       s.ticker,
+			Timestamp:    bar.Timestamp.UTC(),
+			TimeSpan:     s.timeSpan,
+			Multiplier:   s.multiplier,
+			Open:         bar.Open / scale,
+			High:         bar.High / scale,
+			Low:          bar.Low / scale,
+			Close:        bar.Close / scale,
+			Volume:       bar.Volume,
+			VWAP:         bar.VWAP / scale,
+			Transactions: int64(bar.Transactions),
+		})
+	}
+	return models.UpsertBars(s.db.WithContext(ctx), stored)
+}
+
+// storeEnhancedBars writes the analysis window's bars, with their indicators,
+// to the Timescale enhanced bar store. Warm-up bars are left out, since their
+// indicators are still warming up and would overwrite complete ones.
+func (s *DeepSearchService) storeEnhancedBars(ctx context.Context, bars []EnhancedBar) error {
+	currency := s.Currency()
+	stored := make([]models.EnhancedBar, 0, len(bars))
+	for _, bar := range bars {
+		stored = append(stored, models.EnhancedBar{
+			Ticker:      s.ticker,
+			TimeSpan:    s.timeSpan,
+			Multiplier:  s.multiplier,
+			Timestamp:   bar.Timestamp.UTC(),
+			Currency:    currency,
+			BarFeatures: barFeatures(bar),
+		})
+	}
+	return models.UpsertEnhancedBars(s.db.WithContext(ctx), stored)
+}
+
+// storeAnalysisBars writes every bar of a stored analysis with its features,
+// flagging the bars before from as warm-up
+func (s *DeepSearchService) storeAnalysisBars(ctx context.Context, analysisID uint, bars []EnhancedBar, from int) error {
+	stored := make([]models.AnalysisBar, 0, len(bars))
+	for i, bar := range bars {
+		stored = append(stored, models.AnalysisBar{
+			AnalysisID:  analysisID,
+			Timestamp:   bar.Timestamp.UTC(),
+			Warmup:      i < from,
+			BarFeatures: barFeatures(bar),
+		})
+	}
+	return s.db.WithContext(ctx).CreateInBatches(stored, analysisBarBatchSize).Error
+}
+
+// storedAnalysisBars reads the bars stored with an analysis, returning the
+// index of the first bar in the window; there are none for analyses stored
+// before bars were kept with them
+func storedAnalysisBars(ctx context.Context, db *gorm.DB, analysisID uint) ([]EnhancedBar, int, error) {
+	stored, err := models.FindAnalysisBars(db.WithContext(ctx), analysisID)
+	if err != nil {
+		return nil, 0, err
+	}
+	bars := make([]EnhancedBar, 0, len(stored))
+	from := 0
+	for _, bar := range stored {
+		if bar.Warmup {
+			from++
+		}
+		bars = append(bars, enhancedBar(bar.Timestamp, bar.BarFeatures))
+	}
+	return bars, from, nil
+}
+
+// barFeatures is the stored form of a bar's features
+func barFeatures(bar EnhancedBar) models.BarFeatures {
+	features := models.BarFeatures{
+		Open:                bar.Open,
+		High:                bar.High,
+		Low:                 bar.Low,
+		Close:               bar.Close,
+		Volume:              bar.Volume,
+		Transactions:        int64(bar.Transactions),
+		VWAP:                bar.VWAP,
+		CumulativeVWAP:      bar.CumulativeVWAP,
+		AnchoredVWAP:        bar.AnchoredVWAP,
+		VolumeZScore:        bar.VolumeZScore,
+		ATR:                 bar.ATR,
+		BollingerMiddle:     bar.BollingerMiddle,
+		BollingerUpper:      bar.BollingerUpper,
+		BollingerLower:      bar.BollingerLower,
+		BollingerWidth:      bar.BollingerWidth,
+		BollingerSqueeze:    bar.BollingerSqueeze,
+		ADX:                 bar.ADX,
+		PlusDI:              bar.PlusDI,
+		MinusDI:             bar.MinusDI,
+		OBV:                 bar.OBV,
+		OBVDivergence:       bar.OBVDivergence,
+		SuperTrend:          bar.SuperTrend,
+		SuperTrendDirection: bar.SuperTrendDirection,
+		YearHigh:            bar.YearHigh,
+		YearLow:             bar.YearLow,
+		Doji:                bar.IsDoji,
+		BearishEngulfing:    bar.BearishEngulfing,
+		BullishEngulfing:    bar.BullishEngulfing,
+		InstitutionalFlow:   bar.InstitutionalFlow,
+		ConfirmedMove:       bar.ConfirmedMove,
+		ConfirmedSources:    models.StringArray(bar.ConfirmedSources),
+		InsiderBuyers:       bar.InsiderBuyers,
+		InsiderBuyValue:     bar.InsiderBuyValue,
+		FTDShares:           bar.FTDShares,
+		FTDZScore:           bar.FTDZScore,
+	}
+	if !bar.AnchoredVWAPStart.IsZero() {
+		start := bar.AnchoredVWAPStart
+		features.AnchoredVWAPStart = &start
+	}
+	return features
+}
+
+// enhancedBar turns stored features back into the bar they were taken from
+func enhancedBar(timestamp time.Time, f models.BarFeatures) EnhancedBar {
+	bar := EnhancedBar{
+		Timestamp:           timestamp,
+		Open:                f.Open,
+		High:                f.High,
+		Low:                 f.Low,
+		Close:               f.Close,
+		Volume:              f.Volume,
+		Transactions:        float64(f.Transactions),
+		VWAP:                f.VWAP,
+		CumulativeVWAP:      f.CumulativeVWAP,
+		AnchoredVWAP:        f.AnchoredVWAP,
+		VolumeZScore:        f.VolumeZScore,
+		ATR:                 f.ATR,
+		BollingerMiddle:     f.BollingerMiddle,
+		BollingerUpper:      f.BollingerUpper,
+		BollingerLower:      f.BollingerLower,
+		BollingerWidth:      f.BollingerWidth,
+		BollingerSqueeze:    f.BollingerSqueeze,
+		ADX:                 f.ADX,
+		PlusDI:              f.PlusDI,
+		MinusDI:             f.MinusDI,
+		OBV:                 f.OBV,
+		OBVDivergence:       f.OBVDivergence,
+		SuperTrend:          f.SuperTrend,
+		SuperTrendDirection: f.SuperTrendDirection,
+		YearHigh:            f.YearHigh,
+		YearLow:             f.YearLow,
+		IsDoji:              f.Doji,
+		BearishEngulfing:    f.BearishEngulfing,
+		BullishEngulfing:    f.BullishEngulfing,
+		InstitutionalFlow:   f.InstitutionalFlow,
+		ConfirmedMove:       f.ConfirmedMove,
+		ConfirmedSources:    []string(f.ConfirmedSources),
+		InsiderBuyers:       f.InsiderBuyers,
+		InsiderBuyValue:     f.InsiderBuyValue,
+		FTDShares:           f.FTDShares,
+		FTDZScore:           f.FTDZScore,
+	}
+	if f.AnchoredVWAPStart != nil {
+		bar.AnchoredVWAPStart = *f.AnchoredVWAPStart
+	}
+	return bar
+}
+
+// storedAggs reads the analysis aggregation from the bar store, up to the end of the window
+func (s *DeepSearchService) storedAggs(ctx context.Context, from time.Time) ([]polygonmodels.Agg, error) {
+	end, err := time.ParseInLocation("2006-01-02", s.endDuration, marketTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end_duration: %w", err)
+	}
+
+	stored, err := models.FindBars(s.db.WithContext(ctx), s.ticker, s.timeSpan, s.multiplier, from, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return barsToAggs(stored), nil
+}
+
+// barsToAggs converts stored bars to the Polygon form the indicators take
+func barsToAggs(stored []models.Bar) []polygonmodels.Agg {
+	aggs := make([]polygonmodels.Agg, 0, len(stored))
+	for _, bar := range stored {
+		aggs = append(aggs, polygonmodels.Agg{
+			Ticker:       bar.Ticker,
+			Open:         bar.Open,
+			High:         bar.High,
+			Low:          bar.Low,
+			Close:        bar.Close,
+			Volume:       bar.Volume,
+			VWAP:         bar.VWAP,
+			Transactions: bar.Transactions,
+			Timestamp:    polygonmodels.Millis(bar.Timestamp),
+		})
+	}
+	return aggs
+}
@@ -0,0 +1,68 @@
+package deepsearch
+
+import (
+	"institutionanalyser/models"
+)
+
+// executionBenchmarks measures the window's close and large prints against
+// its VWAP and TWAP. Large prints are volume spike bars; without trade-level
+// data each counts at its bar VWAP. Returns nil without bars.
+func executionBenchmarks(bars []EnhancedBar) *models.ExecutionBenchmarks {
+	if len(bars) == 0 {
+		return nil
+	}
+
+	var notional, volume, ohlc4Sum float64
+	var largeNotional, largeVolume float64
+	largePrints := 0
+	for _, bar := range bars {
+		price := barPrice(bar)
+		notional += price * bar.Volume
+		volume += bar.Volume
+		ohlc4Sum += (bar.Open + bar.High + bar.Low + bar.Close) / 4
+
+		if bar.VolumeZScore >= volumeSpikeZScore {
+			largePrints++
+			largeNotional += price * bar.Volume
+			largeVolume += bar.Volume
+		}
+	}
+
+	last := bars[len(bars)-1]
+	b := &models.ExecutionBenchmarks{
+		TWAP:        ohlc4Sum / float64(len(bars)),
+		Close:       last.Close,
+		LargePrints: largePrints,
+	}
+	if volume > 0 {
+		b.VWAP = notional / volume
+	}
+	b.CloseVsVWAPBps = differenceBps(b.Close, b.VWAP)
+	b.CloseVsTWAPBps = differenceBps(b.Close, b.TWAP)
+
+	if largeVolume > 0 {
+		b.LargePrintVolume = largeVolume
+		b.LargePrintVolumeShare = largeVolume / volume
+		b.LargePrintVWAP = largeNotional / largeVolume
+		b.LargePrintVsVWAPBps = differenceBps(b.LargePrintVWAP, b.VWAP)
+		b.LargePrintVsTWAPBps = differenceBps(b.LargePrintVWAP, b.TWAP)
+	}
+	return b
+}
+
+// barPrice is the bar's VWAP, or its typical price when Polygon sent none
+func barPrice(bar EnhancedBar) float64 {
+	if bar.VWAP > 0 {
+		return bar.VWAP
+	}
+	return (bar.High + bar.Low + bar.Close) / 3
+}
+
+// differenceBps is how far price is above benchmark in basis points, 0
+// without a benchmark
+func differenceBps(price, benchmark float64) float64 {
+	if benchmark == 0 {
+		return 0
+	}
+	return (price/benchmark - 1) * 10000
+}
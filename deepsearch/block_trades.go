@@ -0,0 +1,154 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// defaultBlockTradeMinNotional matches the notional half of the block rule
+// SessionPrints uses for footprints
+const defaultBlockTradeMinNotional = 200000
+
+// BlockTradeMinNotional returns the dollar value a print needs to count as a
+// block, BLOCK_TRADE_MIN_NOTIONAL overriding the default
+func BlockTradeMinNotional() float64 {
+	if val := os.Getenv("BLOCK_TRADE_MIN_NOTIONAL"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultBlockTradeMinNotional
+}
+
+// LastCompletedSession returns the latest trading day whose regular session
+// had closed at now
+func LastCompletedSession(ctx context.Context, now time.Time) time.Time {
+	calendar := service.DefaultTradingCalendar()
+	now = now.In(marketTimezone)
+	day := calendar.OnOrBeforeTradingDay(ctx, now)
+	if marketDay(day).Equal(marketDay(now)) && now.Hour() < 16 {
+		day = calendar.PreviousTradingDay(ctx, day)
+	}
+	return marketDay(day)
+}
+
+// DetectBlockTrades reads a ticker's tape over its regular session on date,
+// keeps the prints of at least minNotional as blocks and totals them by side.
+// The session's timeSpan/multiplier bars are then loaded like a replay to
+// check the InstitutionalFlow flags against the bars the blocks printed in;
+// when they cannot be loaded the session is returned without a verification.
+func DetectBlockTrades(ctx context.Context, db *gorm.DB, ticker string, date time.Time, minNotional float64, timeSpan string, multiplier int) (*models.BlockTradeSession, []models.BlockTrade, error) {
+	if !service.DefaultTradingCalendar().IsTradingDay(ctx, date) {
+		return nil, nil, fmt.Errorf("%s is not a trading day", date.Format("2006-01-02"))
+	}
+
+	tape, err := service.NewTradeFlowService().SessionBlocks(ctx, ticker, date, minNotional)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &models.BlockTradeSession{
+		Ticker:      ticker,
+		Day:         tape.Date,
+		MinNotional: minNotional,
+		TotalTrades: tape.TotalTrades,
+		TotalVolume: tape.TotalVolume,
+		BlockTrades: len(tape.Blocks),
+	}
+	blocks := make([]models.BlockTrade, 0, len(tape.Blocks))
+	for _, trade := range tape.Blocks {
+		block := models.BlockTrade{
+			Ticker:      ticker,
+			Day:         tape.Date,
+			Timestamp:   trade.Timestamp,
+			Price:       trade.Price,
+			Size:        trade.Size,
+			Notional:    trade.Price * trade.Size,
+			OffExchange: trade.OffExchange,
+		}
+		session.BlockVolume += block.Size
+		session.BlockNotional += block.Notional
+		if block.OffExchange {
+			session.OffExchangeBlockVolume += block.Size
+		}
+		switch trade.Side {
+		case 1:
+			block.Side = models.BlockSideBuy
+			session.BuyBlocks++
+			session.BuyVolume += block.Size
+			session.BuyNotional += block.Notional
+		case -1:
+			block.Side = models.BlockSideSell
+			session.SellBlocks++
+			session.SellVolume += block.Size
+			session.SellNotional += block.Notional
+		}
+		blocks = append(blocks, block)
+	}
+	session.NetNotional = session.BuyNotional - session.SellNotional
+
+	// Bars stay as quoted, like the tape they are checked against
+	allBars, from, _, err := loadSessionBars(ctx, db, ticker, date, timeSpan, multiplier, 1)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		fmt.Printf("[deepsearch] failed to load %s bars to verify institutional flow, storing blocks only: %v\n", ticker, err)
+		return session, blocks, nil
+	}
+	session.Verification = verifyInstitutionalFlow(allBars[from:], blocks, BarDuration(timeSpan, multiplier))
+	session.Verification.TimeSpan = timeSpan
+	session.Verification.Multiplier = multiplier
+	return session, blocks, nil
+}
+
+// verifyInstitutionalFlow places each block (oldest first) in the bar it
+// printed in and compares those bars with the ones flagged as institutional flow
+func verifyInstitutionalFlow(bars []EnhancedBar, blocks []models.BlockTrade, duration time.Duration) *models.FlowVerification {
+	verification := &models.FlowVerification{Bars: len(bars)}
+	withBlock := make([]bool, len(bars))
+	var flaggedNotional, totalNotional float64
+	for _, block := range blocks {
+		// The bar opened last at or before the print, if the print is within it
+		i := sort.Search(len(bars), func(i int) bool { return bars[i].Timestamp.After(block.Timestamp) }) - 1
+		if i < 0 || !block.Timestamp.Before(bars[i].Timestamp.Add(duration)) {
+			continue
+		}
+		withBlock[i] = true
+		totalNotional += block.Notional
+		if bars[i].InstitutionalFlow {
+			flaggedNotional += block.Notional
+		}
+	}
+
+	for i, bar := range bars {
+		if bar.InstitutionalFlow {
+			verification.FlowBars++
+		}
+		if withBlock[i] {
+			verification.BlockBars++
+			if bar.InstitutionalFlow {
+				verification.MatchedBars++
+			}
+		}
+	}
+	if verification.FlowBars > 0 {
+		verification.Precision = float64(verification.MatchedBars) / float64(verification.FlowBars)
+	}
+	if verification.BlockBars > 0 {
+		verification.Recall = float64(verification.MatchedBars) / float64(verification.BlockBars)
+	}
+	if totalNotional > 0 {
+		verification.NotionalCaptured = flaggedNotional / totalNotional
+	}
+	return verification
+}
@@ -0,0 +1,180 @@
+package deepsearch
+
+import (
+	"context"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// breadthSMADays is the daily SMA period tickers are measured against
+	breadthSMADays = 20
+	// breadthRiskOn and breadthRiskOff are the shares of advancers and of
+	// tickers above VWAP that mark a risk-on or risk-off tape
+	breadthRiskOn  = 0.6
+	breadthRiskOff = 0.4
+)
+
+// Market regimes from breadth
+const (
+	RegimeRiskOn  = "risk_on"
+	RegimeRiskOff = "risk_off"
+	RegimeMixed   = "mixed"
+)
+
+// BreadthRatio counts how many measured tickers meet a condition
+type BreadthRatio struct {
+	Count    int `json:"count"`
+	Measured int `json:"measured"`
+	// Pct is Count as a percentage of Measured, 0 when nothing was measured
+	Pct float64 `json:"pct"`
+}
+
+func (r *BreadthRatio) add(met bool) {
+	r.Measured++
+	if met {
+		r.Count++
+	}
+	r.Pct = float64(r.Count) / float64(r.Measured) * 100
+}
+
+// share is Count over Measured, 0 when nothing was measured
+func (r BreadthRatio) share() float64 {
+	if r.Measured == 0 {
+		return 0
+	}
+	return float64(r.Count) / float64(r.Measured)
+}
+
+// MarketBreadth summarises the tickers analysed on one market day
+type MarketBreadth struct {
+	Date    string `json:"date"`
+	Tickers int    `json:"tickers"`
+	// Advancers and Decliners compare each ticker's last analysed close that
+	// day with its previous daily close in the bar store
+	Advancers int `json:"advancers"`
+	Decliners int `json:"decliners"`
+	Unchanged int `json:"unchanged"`
+	// AdvanceDecline is advancers over decliners, 0 without decliners
+	AdvanceDecline float64      `json:"advance_decline_ratio"`
+	AboveVWAP      BreadthRatio `json:"above_vwap"`
+	AboveSMA20     BreadthRatio `json:"above_sma20"`
+	NewHighs       int          `json:"new_highs"`
+	NewLows        int          `json:"new_lows"`
+	// Regime is risk_on when both the advancers' share and the share above
+	// VWAP reach 60%, risk_off when both are at 40% or below, else mixed
+	Regime string `json:"regime"`
+}
+
+// dailyClose is one stored daily close
+type dailyClose struct {
+	Ticker string
+	Close  float64
+}
+
+// ComputeMarketBreadth measures breadth across every ticker with an analysis
+// of the market day containing date, each through the analysis reaching
+// furthest into the day: its last close against the previous daily close and
+// the 20-day SMA from the bar store, against its window VWAP, and whether it
+// set a new 52-week level.
+func ComputeMarketBreadth(ctx context.Context, db *gorm.DB, date time.Time) (*MarketBreadth, error) {
+	day := marketDay(date)
+	breadth := &MarketBreadth{Date: day.Format("2006-01-02"), Regime: RegimeMixed}
+
+	var latest []models.TechnicalSignal
+	query := db.WithContext(ctx).Model(&models.TechnicalSignal{}).
+		Where("end_date >= ? AND end_date < ?", day, day.AddDate(0, 0, 1))
+	err := models.LatestPer(query, "ticker", "end_date DESC, created_at DESC").
+		Select("id, ticker, last_close, benchmarks, year_range, end_date").
+		Order("ticker").
+		Find(&latest).Error
+	if err != nil || len(latest) == 0 {
+		return breadth, err
+	}
+
+	tickers := make([]string, 0, len(latest))
+	for _, analysis := range latest {
+		tickers = append(tickers, analysis.Ticker)
+	}
+
+	// The 20 daily closes before the day, newest first; weekends and holidays
+	// make 20 sessions span about 28 calendar days
+	var closes []dailyClose
+	err = db.WithContext(ctx).Raw(`
+		SELECT ticker, close FROM (
+			SELECT ticker, close, timestamp,
+				ROW_NUMBER() OVER (PARTITION BY ticker ORDER BY timestamp DESC) AS n
+			FROM bars
+			WHERE time_span = 'day' AND multiplier = 1 AND ticker IN ? AND timestamp >= ? AND timestamp < ?
+		) recent
+		WHERE n <= ?
+		ORDER BY ticker, timestamp DESC`, tickers, day.AddDate(0, 0, -2*breadthSMADays), day, breadthSMADays).
+		Scan(&closes).Error
+	if err != nil {
+		return nil, err
+	}
+	// Stored closes are as quoted; analyses' closes are normalized to the major currency
+	currencies, err := models.TickerCurrencies(db.WithContext(ctx), tickers)
+	if err != nil {
+		return nil, err
+	}
+	history := make(map[string][]float64, len(tickers))
+	for _, c := range closes {
+		history[c.Ticker] = append(history[c.Ticker], c.Close*currencies[c.Ticker].Scale)
+	}
+
+	for _, analysis := range latest {
+		if analysis.LastClose <= 0 {
+			continue
+		}
+		breadth.Tickers++
+		price := analysis.LastClose
+
+		if prior := history[analysis.Ticker]; len(prior) > 0 {
+			switch {
+			case price > prior[0]:
+				breadth.Advancers++
+			case price < prior[0]:
+				breadth.Decliners++
+			default:
+				breadth.Unchanged++
+			}
+			if len(prior) == breadthSMADays {
+				sum := 0.0
+				for _, c := range prior {
+					sum += c
+				}
+				breadth.AboveSMA20.add(price > sum/breadthSMADays)
+			}
+		}
+
+		if b := analysis.Benchmarks; b != nil && b.VWAP > 0 {
+			breadth.AboveVWAP.add(price > b.VWAP)
+		}
+		if r := analysis.YearRange; r != nil {
+			if r.DaysSinceHigh == 0 {
+				breadth.NewHighs++
+			}
+			if r.DaysSinceLow == 0 {
+				breadth.NewLows++
+			}
+		}
+	}
+
+	if breadth.Decliners > 0 {
+		breadth.AdvanceDecline = float64(breadth.Advancers) / float64(breadth.Decliners)
+	}
+
+	moved := BreadthRatio{Count: breadth.Advancers, Measured: breadth.Advancers + breadth.Decliners + breadth.Unchanged}
+	switch {
+	case moved.Measured == 0 || breadth.AboveVWAP.Measured == 0:
+	case moved.share() >= breadthRiskOn && breadth.AboveVWAP.share() >= breadthRiskOn:
+		breadth.Regime = RegimeRiskOn
+	case moved.share() <= breadthRiskOff && breadth.AboveVWAP.share() <= breadthRiskOff:
+		breadth.Regime = RegimeRiskOff
+	}
+	return breadth, nil
+}
@@ -0,0 +1,227 @@
+package deepsearch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// Chart themes
+const (
+	ChartThemeLight = "light"
+	ChartThemeDark  = "dark"
+)
+
+// Chart sizes, in pixels
+const (
+	defaultChartWidth  = 1024
+	defaultChartHeight = 400
+	minChartWidth      = 320
+	maxChartWidth      = 4096
+	minChartHeight     = 200
+	maxChartHeight     = 2048
+)
+
+// ChartOptions sizes and themes a chart; zero values take the defaults
+type ChartOptions struct {
+	Width  int
+	Height int
+	Theme  string
+}
+
+// ParseChartOptions parses chart width, height and theme query values;
+// empty values take the defaults
+func ParseChartOptions(width, height, theme string) (ChartOptions, error) {
+	opts := ChartOptions{Width: defaultChartWidth, Height: defaultChartHeight, Theme: ChartThemeLight}
+	if width != "" {
+		n, err := strconv.Atoi(width)
+		if err != nil || n < minChartWidth || n > maxChartWidth {
+			return opts, fmt.Errorf("width must be between %d and %d", minChartWidth, maxChartWidth)
+		}
+		opts.Width = n
+	}
+	if height != "" {
+		n, err := strconv.Atoi(height)
+		if err != nil || n < minChartHeight || n > maxChartHeight {
+			return opts, fmt.Errorf("height must be between %d and %d", minChartHeight, maxChartHeight)
+		}
+		opts.Height = n
+	}
+	if theme != "" {
+		if _, ok := chartThemes[theme]; !ok {
+			return opts, fmt.Errorf("theme must be %s or %s", ChartThemeLight, ChartThemeDark)
+		}
+		opts.Theme = theme
+	}
+	return opts, nil
+}
+
+// chartTheme colours a chart
+type chartTheme struct {
+	background, text, price, vwap drawing.Color
+}
+
+var chartThemes = map[string]chartTheme{
+	ChartThemeLight: {background: chart.ColorWhite, text: chart.ColorBlack, price: chart.ColorBlue, vwap: chart.ColorBlue},
+	ChartThemeDark: {
+		background: drawing.ColorFromHex("1e1e1e"),
+		text:       drawing.ColorFromHex("d4d4d4"),
+		price:      chart.ColorCyan,
+		vwap:       chart.ColorAlternateYellow,
+	},
+}
+
+// chartMarkers are the signal markers by vote, in legend order. position
+// and shape are TradingView Lightweight Charts marker options.
+var chartMarkers = []struct {
+	vote, name      string
+	color           drawing.Color
+	position, shape string
+}{
+	{"BUY", "Bullish signal", chart.ColorGreen, "belowBar", "arrowUp"},
+	{"SELL", "Bearish signal", chart.ColorRed, "aboveBar", "arrowDown"},
+	{"STRADDLE", "Straddle signal", chart.ColorOrange, "inBar", "circle"},
+}
+
+// chartSignal is a signal marked at its bar's close
+type chartSignal struct {
+	time        time.Time
+	close       float64
+	vote        string
+	description string
+}
+
+// analysisChartSignals returns the analysis's signals to mark on a chart of
+// bars. Signals stored without a timestamp, or outside bars, are left out.
+func analysisChartSignals(analysis *models.TechnicalSignal, bars []EnhancedBar) []chartSignal {
+	closes := make(map[int64]float64, len(bars))
+	for _, bar := range bars {
+		closes[bar.Timestamp.UnixMilli()] = bar.Close
+	}
+	var signals []chartSignal
+	for i, text := range analysis.Signals {
+		if i >= len(analysis.SignalTimestamps) {
+			break
+		}
+		ms := analysis.SignalTimestamps[i]
+		if close, ok := closes[ms]; ok {
+			signals = append(signals, chartSignal{
+				time:        time.UnixMilli(ms),
+				close:       close,
+				vote:        signalVote(text),
+				description: ParseSignal(text).Description,
+			})
+		}
+	}
+	return signals
+}
+
+// AnalysisChartPNG renders price and cumulative VWAP over an analysis's bars,
+// with its signals marked at their bar's close. Signals stored without a
+// timestamp, or outside bars, are not marked.
+func AnalysisChartPNG(analysis *models.TechnicalSignal, bars []EnhancedBar, opts ChartOptions) ([]byte, error) {
+	title := fmt.Sprintf("%s %s-%s", analysis.Ticker, analysis.PolyStartDuration, analysis.PolyEndDuration)
+	return chartPNG(title, bars, analysisChartSignals(analysis, bars), opts)
+}
+
+func chartPNG(title string, bars []EnhancedBar, signals []chartSignal, opts ChartOptions) ([]byte, error) {
+	if len(bars) < 2 {
+		return nil, errors.New("not enough bars to chart")
+	}
+
+	var timeSeries []time.Time
+	var prices, vwap []float64
+	for _, bar := range bars {
+		timeSeries = append(timeSeries, bar.Timestamp)
+		prices = append(prices, bar.Close)
+		vwap = append(vwap, bar.CumulativeVWAP)
+	}
+
+	theme, ok := chartThemes[opts.Theme]
+	if !ok {
+		theme = chartThemes[ChartThemeLight]
+	}
+	text := chart.Style{FontColor: theme.text, StrokeColor: theme.text}
+	graph := chart.Chart{
+		Title:      title,
+		TitleStyle: chart.Style{FontColor: theme.text},
+		Width:      opts.Width,
+		Height:     opts.Height,
+		Background: chart.Style{FillColor: theme.background, StrokeColor: theme.background},
+		Canvas:     chart.Style{FillColor: theme.background, StrokeColor: theme.background},
+		XAxis: chart.XAxis{
+			Name:           "Time",
+			NameStyle:      text,
+			Style:          text,
+			ValueFormatter: chart.TimeHourValueFormatter,
+		},
+		YAxis: chart.YAxis{
+			Name:      "Price",
+			NameStyle: text,
+			Style:     text,
+		},
+		Series: []chart.Series{
+			chart.TimeSeries{
+				Name:    "Price",
+				XValues: timeSeries,
+				YValues: prices,
+				Style:   chart.Style{StrokeColor: theme.price},
+			},
+			chart.TimeSeries{
+				Name:    "VWAP",
+				XValues: timeSeries,
+				YValues: vwap,
+				Style: chart.Style{
+					StrokeColor:     theme.vwap,
+					StrokeDashArray: []float64{5.0, 5.0},
+				},
+			},
+		},
+	}
+
+	for _, marker := range chartMarkers {
+		var times []time.Time
+		var closes []float64
+		for _, signal := range signals {
+			if signal.vote == marker.vote {
+				times = append(times, signal.time)
+				closes = append(closes, signal.close)
+			}
+		}
+		if len(times) == 0 {
+			continue
+		}
+		graph.Series = append(graph.Series, chart.TimeSeries{
+			Name:    marker.name,
+			XValues: times,
+			YValues: closes,
+			// The stroke colour is for the legend; the line itself is not drawn
+			Style: chart.Style{
+				StrokeColor: marker.color,
+				StrokeWidth: chart.Disabled,
+				DotWidth:    4,
+				DotColor:    marker.color,
+			},
+		})
+	}
+	if len(signals) > 0 {
+		graph.Elements = []chart.Renderable{chart.Legend(&graph, chart.Style{
+			FillColor:   theme.background,
+			FontColor:   theme.text,
+			StrokeColor: theme.text,
+		})}
+	}
+
+	var buf bytes.Buffer
+	if err := graph.Render(chart.PNG, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package deepsearch
+
+import (
+	"fmt"
+
+	"institutionanalyser/models"
+)
+
+// ChartSeries is an analysis's bars, indicators and signals as time series
+// for client-side charting. Each point is an object with a time in Unix
+// seconds, the shape TradingView Lightweight Charts takes; for ECharts, map
+// a series to [time*1000, value] pairs.
+type ChartSeries struct {
+	AnalysisID uint   `json:"analysis_id"`
+	Ticker     string `json:"ticker"`
+	Currency   string `json:"currency"`
+	// Candles and Volume have a point per bar
+	Candles []ChartCandle `json:"candles"`
+	Volume  []ChartPoint  `json:"volume"`
+	// Indicators are keyed by name; bars before an indicator is available
+	// have no point
+	Indicators map[string][]ChartPoint `json:"indicators"`
+	Markers    []ChartMarker           `json:"markers"`
+}
+
+// ChartCandle is one bar's OHLC
+type ChartCandle struct {
+	Time  int64   `json:"time"`
+	Open  float64 `json:"open"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Close float64 `json:"close"`
+}
+
+// ChartPoint is one value of a line or histogram series
+type ChartPoint struct {
+	Time  int64   `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// ChartMarker marks a signal on its bar. Position, Shape and Color are
+// TradingView Lightweight Charts marker options; Price is the bar's close,
+// for libraries that place markers by value.
+type ChartMarker struct {
+	Time     int64   `json:"time"`
+	Price    float64 `json:"price"`
+	Vote     string  `json:"vote"`
+	Text     string  `json:"text"`
+	Position string  `json:"position"`
+	Shape    string  `json:"shape"`
+	Color    string  `json:"color"`
+}
+
+// chartIndicators are the indicator series; optional ones are zero until
+// enough bars have been seen, and those bars are left out
+var chartIndicators = []struct {
+	name     string
+	value    func(EnhancedBar) float64
+	optional bool
+}{
+	{"vwap", func(b EnhancedBar) float64 { return b.VWAP }, true},
+	{"cumulative_vwap", func(b EnhancedBar) float64 { return b.CumulativeVWAP }, true},
+	{"anchored_vwap", func(b EnhancedBar) float64 { return b.AnchoredVWAP }, true},
+	{"atr", func(b EnhancedBar) float64 { return b.ATR }, true},
+	{"bollinger_upper", func(b EnhancedBar) float64 { return b.BollingerUpper }, true},
+	{"bollinger_middle", func(b EnhancedBar) float64 { return b.BollingerMiddle }, true},
+	{"bollinger_lower", func(b EnhancedBar) float64 { return b.BollingerLower }, true},
+	{"super_trend", func(b EnhancedBar) float64 { return b.SuperTrend }, true},
+	{"adx", func(b EnhancedBar) float64 { return b.ADX }, true},
+	{"plus_di", func(b EnhancedBar) float64 { return b.PlusDI }, true},
+	{"minus_di", func(b EnhancedBar) float64 { return b.MinusDI }, true},
+	{"obv", func(b EnhancedBar) float64 { return b.OBV }, false},
+	{"volume_z_score", func(b EnhancedBar) float64 { return b.VolumeZScore }, false},
+	{"ftd_z_score", func(b EnhancedBar) float64 { return b.FTDZScore }, false},
+}
+
+// AnalysisChartSeries lays out an analysis's bars as chart series, with its
+// signals as markers as on the PNG chart
+func AnalysisChartSeries(analysis *models.TechnicalSignal, bars []EnhancedBar) *ChartSeries {
+	series := &ChartSeries{
+		AnalysisID: analysis.ID,
+		Ticker:     analysis.Ticker,
+		Currency:   analysis.Currency,
+		Candles:    make([]ChartCandle, 0, len(bars)),
+		Volume:     make([]ChartPoint, 0, len(bars)),
+		Indicators: make(map[string][]ChartPoint, len(chartIndicators)),
+		Markers:    []ChartMarker{},
+	}
+	for _, indicator := range chartIndicators {
+		series.Indicators[indicator.name] = []ChartPoint{}
+	}
+
+	for _, bar := range bars {
+		t := bar.Timestamp.Unix()
+		series.Candles = append(series.Candles, ChartCandle{Time: t, Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close})
+		series.Volume = append(series.Volume, ChartPoint{Time: t, Value: bar.Volume})
+		for _, indicator := range chartIndicators {
+			value := indicator.value(bar)
+			if indicator.optional && value == 0 {
+				continue
+			}
+			series.Indicators[indicator.name] = append(series.Indicators[indicator.name], ChartPoint{Time: t, Value: value})
+		}
+	}
+
+	for _, signal := range analysisChartSignals(analysis, bars) {
+		for _, marker := range chartMarkers {
+			if marker.vote != signal.vote {
+				continue
+			}
+			series.Markers = append(series.Markers, ChartMarker{
+				Time:     signal.time.Unix(),
+				Price:    signal.close,
+				Vote:     signal.vote,
+				Text:     signal.description,
+				Position: marker.position,
+				Shape:    marker.shape,
+				Color:    fmt.Sprintf("#%02x%02x%02x", marker.color.R, marker.color.G, marker.color.B),
+			})
+		}
+	}
+	return series
+}
@@ -0,0 +1,188 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"institutionanalyser/models"
+)
+
+// compareConcurrency bounds how many windows are fetched from Polygon at once
+const compareConcurrency = 4
+
+// ComparisonWindow is one date range to run the pipeline over
+type ComparisonWindow struct {
+	Label string `json:"label"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// WindowMetrics is the pipeline's outcome for one window. Nothing is stored.
+type WindowMetrics struct {
+	ComparisonWindow
+	Error         string  `json:"error,omitempty"`
+	Bars          int     `json:"bars"`
+	Signals       int     `json:"signals"`
+	BuySignals    int     `json:"buy_signals"`
+	SellSignals   int     `json:"sell_signals"`
+	StraddleCount int     `json:"straddle_signals"`
+	Decision      string  `json:"decision"`
+	Confidence    float64 `json:"confidence"`
+	// PriceChangePct is the move from the window's first open to its last close
+	PriceChangePct  float64 `json:"price_change_pct"`
+	MaxVolumeZScore float64 `json:"max_volume_zscore"`
+	// InstitutionalBars counts bars flagged as institutional flow
+	InstitutionalBars int `json:"institutional_bars"`
+	// CloseVsVWAPPct is the last close against the window's cumulative VWAP
+	CloseVsVWAPPct float64 `json:"close_vs_vwap_pct"`
+	// ATRPct is the last bar's ATR as a percentage of its close
+	ATRPct      float64                     `json:"atr_pct"`
+	Explanation *models.DecisionExplanation `json:"explanation,omitempty"`
+}
+
+// metrics returns the numeric metrics by name, for aligning windows side by side
+func (m WindowMetrics) metrics() map[string]float64 {
+	return map[string]float64{
+		"bars":               float64(m.Bars),
+		"signals":            float64(m.Signals),
+		"buy_signals":        float64(m.BuySignals),
+		"sell_signals":       float64(m.SellSignals),
+		"straddle_signals":   float64(m.StraddleCount),
+		"confidence":         m.Confidence,
+		"price_change_pct":   m.PriceChangePct,
+		"max_volume_zscore":  m.MaxVolumeZScore,
+		"institutional_bars": float64(m.InstitutionalBars),
+		"close_vs_vwap_pct":  m.CloseVsVWAPPct,
+		"atr_pct":            m.ATRPct,
+	}
+}
+
+// Comparison holds each window's metrics in request order, and every metric
+// as a series aligned to that order (null where a window failed)
+type Comparison struct {
+	Ticker     string                `json:"ticker"`
+	TimeSpan   string                `json:"timespan"`
+	Multiplier int                   `json:"multiplier"`
+	Windows    []WindowMetrics       `json:"windows"`
+	Series     map[string][]*float64 `json:"series"`
+}
+
+// CompareWindows runs the signal pipeline over each window of one ticker and
+// lines the results up. A window that fails carries its error; the others are
+// still returned.
+func CompareWindows(ctx context.Context, ticker, timeSpan string, multiplier int, windows []ComparisonWindow) *Comparison {
+	comparison := &Comparison{
+		Ticker:     ticker,
+		TimeSpan:   timeSpan,
+		Multiplier: multiplier,
+		Windows:    make([]WindowMetrics, len(windows)),
+		Series:     map[string][]*float64{},
+	}
+
+	sem := make(chan struct{}, compareConcurrency)
+	var wg sync.WaitGroup
+	for i, window := range windows {
+		wg.Add(1)
+		go func(i int, window ComparisonWindow) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			metrics, err := analyseWindow(ctx, ticker, timeSpan, multiplier, window)
+			if err != nil {
+				metrics = &WindowMetrics{ComparisonWindow: window, Error: err.Error()}
+			}
+			comparison.Windows[i] = *metrics
+		}(i, window)
+	}
+	wg.Wait()
+
+	for i, window := range comparison.Windows {
+		for name, value := range window.metrics() {
+			if comparison.Series[name] == nil {
+				comparison.Series[name] = make([]*float64, len(windows))
+			}
+			if window.Error == "" {
+				v := value
+				comparison.Series[name][i] = &v
+			}
+		}
+	}
+
+	return comparison
+}
+
+// analyseWindow fetches and scores one window the same way AnalyseMain does,
+// without storing the result
+func analyseWindow(ctx context.Context, ticker, timeSpan string, multiplier int, window ComparisonWindow) (*WindowMetrics, error) {
+	s := NewDeepSearchService(window.Start, window.End, timeSpan, multiplier, ticker, "", nil)
+	allBars, from, err := s.fetchEnhancedBars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bars := allBars[from:]
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars between %s and %s", window.Start, window.End)
+	}
+
+	signals := generateSignals(allBars, from)
+	texts := signalTexts(signals)
+	decision, confidence := getFinalDecisionFromSignals(texts)
+
+	metrics := &WindowMetrics{
+		ComparisonWindow: window,
+		Bars:             len(bars),
+		Signals:          len(signals),
+		Decision:         decision,
+		Confidence:       confidence,
+		Explanation:      explainDecision(signals, decision, confidence, nil),
+	}
+	for _, signal := range ParseSignals(texts) {
+		switch signal.Direction {
+		case "CALL", "UP":
+			metrics.BuySignals++
+		case "PUT", "DOWN":
+			metrics.SellSignals++
+		case "STRADDLE":
+			metrics.StraddleCount++
+		}
+	}
+	for _, bar := range bars {
+		metrics.MaxVolumeZScore = math.Max(metrics.MaxVolumeZScore, bar.VolumeZScore)
+		if bar.InstitutionalFlow {
+			metrics.InstitutionalBars++
+		}
+	}
+
+	first, last := bars[0], bars[len(bars)-1]
+	if first.Open != 0 {
+		metrics.PriceChangePct = (last.Close - first.Open) / first.Open * 100
+	}
+	if last.CumulativeVWAP != 0 {
+		metrics.CloseVsVWAPPct = (last.Close - last.CumulativeVWAP) / last.CumulativeVWAP * 100
+	}
+	if last.Close != 0 {
+		metrics.ATRPct = last.ATR / last.Close * 100
+	}
+
+	return metrics, nil
+}
+
+// ValidateWindow checks a window's dates: YYYY-MM-DD, start not after end
+func ValidateWindow(window ComparisonWindow) error {
+	start, err := time.Parse("2006-01-02", window.Start)
+	if err != nil {
+		return fmt.Errorf("window %q: invalid start, use YYYY-MM-DD", window.Label)
+	}
+	end, err := time.Parse("2006-01-02", window.End)
+	if err != nil {
+		return fmt.Errorf("window %q: invalid end, use YYYY-MM-DD", window.Label)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("window %q: end is before start", window.Label)
+	}
+	return nil
+}
@@ -0,0 +1,433 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultConfirmationRequired is how many sources must agree by default
+	defaultConfirmationRequired = 3
+	// defaultConfirmationWindowMinutes is how close together agreeing sources must fire
+	defaultConfirmationWindowMinutes = 15
+	// maxConfirmationWindowMinutes is a regular session
+	maxConfirmationWindowMinutes = 390
+	// defaultSweepMinPremium is the premium an option sweep needs to count
+	defaultSweepMinPremium = 100000
+	// sweepChainContracts caps the chain read to pick the contracts scanned for sweeps
+	sweepChainContracts = 5000
+	// sweepContracts is how many of the most active contracts are scanned for sweeps
+	sweepContracts = 10
+	// darkPoolLookback is how many printed minutes a minute's off-exchange volume is scored against
+	darkPoolLookback = 30
+	// darkPoolSpikeZScore is the off-exchange volume z-score of a dark-pool spike
+	darkPoolSpikeZScore = 2.0
+)
+
+// ConfirmationConfig sets which sources a confirmed institutional move is
+// built from (N), how many of them must agree (K) and within how many minutes
+type ConfirmationConfig struct {
+	Sources       []string `json:"sources"`
+	Required      int      `json:"required"`
+	WindowMinutes int      `json:"window_minutes"`
+}
+
+// DefaultConfirmationConfig returns every source, 3 required within 15
+// minutes; CONFIRMATION_SOURCES, CONFIRMATION_REQUIRED and
+// CONFIRMATION_WINDOW_MINUTES override them
+func DefaultConfirmationConfig() ConfirmationConfig {
+	config := ConfirmationConfig{
+		Sources:       append([]string{}, models.ConfirmationSources...),
+		Required:      defaultConfirmationRequired,
+		WindowMinutes: defaultConfirmationWindowMinutes,
+	}
+	if val := os.Getenv("CONFIRMATION_SOURCES"); val != "" {
+		config.Sources = strings.Split(val, ",")
+	}
+	if val := os.Getenv("CONFIRMATION_REQUIRED"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.Required = n
+		}
+	}
+	if val := os.Getenv("CONFIRMATION_WINDOW_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.WindowMinutes = n
+		}
+	}
+	return config
+}
+
+// Normalize validates the config, ordering its sources like
+// models.ConfirmationSources without repeats
+func (c *ConfirmationConfig) Normalize() error {
+	selected := map[string]bool{}
+	for _, source := range c.Sources {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if source == "" {
+			continue
+		}
+		known := false
+		for _, s := range models.ConfirmationSources {
+			known = known || s == source
+		}
+		if !known {
+			return fmt.Errorf("invalid source %q (one of %s)", source, strings.Join(models.ConfirmationSources, ", "))
+		}
+		selected[source] = true
+	}
+	c.Sources = c.Sources[:0]
+	for _, source := range models.ConfirmationSources {
+		if selected[source] {
+			c.Sources = append(c.Sources, source)
+		}
+	}
+
+	if len(c.Sources) < 2 {
+		return errors.New("a confirmation needs at least two sources")
+	}
+	if c.Required < 2 || c.Required > len(c.Sources) {
+		return fmt.Errorf("required must be between 2 and the %d sources", len(c.Sources))
+	}
+	if c.WindowMinutes <= 0 || c.WindowMinutes > maxConfirmationWindowMinutes {
+		return fmt.Errorf("window_minutes must be between 1 and %d", maxConfirmationWindowMinutes)
+	}
+	return nil
+}
+
+// sweepMinPremium returns the premium an option sweep needs,
+// CONFIRMATION_SWEEP_MIN_PREMIUM overriding the default
+func sweepMinPremium() float64 {
+	if val := os.Getenv("CONFIRMATION_SWEEP_MIN_PREMIUM"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultSweepMinPremium
+}
+
+// Confirmation is a session's confirmed moves with what each source saw
+type Confirmation struct {
+	Ticker string             `json:"ticker"`
+	Day    string             `json:"day"`
+	Config ConfirmationConfig `json:"config"`
+	// Events counts each source's directed events; a source that could not be
+	// read has an error instead and cannot agree with the others
+	Events map[string]int         `json:"events"`
+	Errors map[string]string      `json:"errors,omitempty"`
+	Moves  []models.ConfirmedMove `json:"moves"`
+}
+
+// confirmationEvent is one source firing; direction is +1 (buying) or -1 (selling)
+type confirmationEvent struct {
+	source    string
+	timestamp time.Time
+	direction int
+	detail    string
+}
+
+// ConfirmInstitutionalMoves reads each configured source over a ticker's
+// regular session on date and returns the moments at least config.Required
+// of them agreed on a direction within config.WindowMinutes. A source that
+// fails is reported and left out rather than failing the session. The config
+// must be normalized.
+func ConfirmInstitutionalMoves(ctx context.Context, db *gorm.DB, ticker string, date time.Time, config ConfirmationConfig) (*Confirmation, error) {
+	if !service.DefaultTradingCalendar().IsTradingDay(ctx, date) {
+		return nil, fmt.Errorf("%s is not a trading day", date.Format("2006-01-02"))
+	}
+
+	confirmation := &Confirmation{
+		Ticker: ticker,
+		Day:    marketDay(date).Format("2006-01-02"),
+		Config: config,
+		Events: map[string]int{},
+		Errors: map[string]string{},
+		Moves:  []models.ConfirmedMove{},
+	}
+	enabled := map[string]bool{}
+	for _, source := range config.Sources {
+		enabled[source] = true
+	}
+
+	var events []confirmationEvent
+	collect := func(source string, found []confirmationEvent, err error) {
+		if err != nil {
+			confirmation.Errors[source] = err.Error()
+			return
+		}
+		confirmation.Events[source] = len(found)
+		events = append(events, found...)
+	}
+
+	if enabled[models.ConfirmVolume] {
+		found, err := volumeEvents(ctx, db, ticker, date)
+		collect(models.ConfirmVolume, found, err)
+	}
+	// Blocks and dark-pool spikes come from one read of the tape
+	if enabled[models.ConfirmBlocks] || enabled[models.ConfirmDarkPool] {
+		tape, err := service.NewTradeFlowService().SessionBlocks(ctx, ticker, date, BlockTradeMinNotional())
+		if enabled[models.ConfirmBlocks] {
+			collect(models.ConfirmBlocks, blockEvents(tape), err)
+		}
+		if enabled[models.ConfirmDarkPool] {
+			collect(models.ConfirmDarkPool, darkPoolEvents(tape), err)
+		}
+	}
+	if enabled[models.ConfirmSweeps] {
+		found, err := sweepEvents(ctx, ticker, date)
+		collect(models.ConfirmSweeps, found, err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for _, move := range confirmMoves(events, config.Required, time.Duration(config.WindowMinutes)*time.Minute) {
+		move.Ticker = ticker
+		move.Day = confirmation.Day
+		move.OutOf = len(config.Sources)
+		move.WindowMinutes = config.WindowMinutes
+		confirmation.Moves = append(confirmation.Moves, move)
+	}
+	return confirmation, nil
+}
+
+// volumeEvents are the regular-session minute bars whose volume z-score
+// spiked, directed by the bar's body
+func volumeEvents(ctx context.Context, db *gorm.DB, ticker string, date time.Time) ([]confirmationEvent, error) {
+	allBars, from, _, err := loadSessionBars(ctx, db, ticker, date, "minute", 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	var events []confirmationEvent
+	for _, bar := range allBars[from:] {
+		at := bar.Timestamp.In(marketTimezone)
+		if minutes := at.Hour()*60 + at.Minute(); minutes < 9*60+30 || minutes >= 16*60 {
+			continue
+		}
+		if bar.VolumeZScore <= volumeSpikeZScore || bar.Close == bar.Open {
+			continue
+		}
+		direction := 1
+		if bar.Close < bar.Open {
+			direction = -1
+		}
+		events = append(events, confirmationEvent{
+			source:    models.ConfirmVolume,
+			timestamp: bar.Timestamp,
+			direction: direction,
+			detail:    fmt.Sprintf("volume %.0f, z-score %.2f", bar.Volume, bar.VolumeZScore),
+		})
+	}
+	return events, nil
+}
+
+// blockEvents are the blocks the tick rule could classify
+func blockEvents(tape *service.SessionBlocks) []confirmationEvent {
+	if tape == nil {
+		return nil
+	}
+	var events []confirmationEvent
+	for _, block := range tape.Blocks {
+		if block.Side == 0 {
+			continue
+		}
+		events = append(events, confirmationEvent{
+			source:    models.ConfirmBlocks,
+			timestamp: block.Timestamp,
+			direction: block.Side,
+			detail:    fmt.Sprintf("%.0f shares at %.2f ($%.0f)", block.Size, block.Price, block.Size*block.Price),
+		})
+	}
+	return events
+}
+
+// darkPoolEvents are the minutes whose off-exchange volume spiked against
+// the printed minutes before, directed by their net off-exchange volume
+func darkPoolEvents(tape *service.SessionBlocks) []confirmationEvent {
+	if tape == nil {
+		return nil
+	}
+	var events []confirmationEvent
+	volumes := make([]float64, 0, len(tape.Minutes))
+	for _, minute := range tape.Minutes {
+		volumes = append(volumes, minute.OffExchangeVolume)
+		z := volumeZScore(volumes, darkPoolLookback)
+		if z <= darkPoolSpikeZScore || minute.OffExchangeNet == 0 {
+			continue
+		}
+		direction := 1
+		if minute.OffExchangeNet < 0 {
+			direction = -1
+		}
+		events = append(events, confirmationEvent{
+			source:    models.ConfirmDarkPool,
+			timestamp: minute.Start,
+			direction: direction,
+			detail:    fmt.Sprintf("off-exchange volume %.0f of %.0f, z-score %.2f", minute.OffExchangeVolume, minute.Volume, z),
+		})
+	}
+	return events
+}
+
+// sweepEvents are the sweeps of the ticker's most active unexpired contracts,
+// calls bullish and puts bearish. Contracts are picked from the current chain
+// snapshot, so for past sessions contracts expired since are not scanned.
+func sweepEvents(ctx context.Context, ticker string, date time.Time) ([]confirmationEvent, error) {
+	chain, err := FetchOptionsChain(ctx, ticker, service.OptionsChainFilter{ExpirationFrom: marketDay(date).Format("2006-01-02")}, sweepChainContracts)
+	if err != nil {
+		return nil, err
+	}
+	contracts := append([]OptionContract{}, chain.Contracts...)
+	sort.SliceStable(contracts, func(i, j int) bool { return contracts[i].Volume > contracts[j].Volume })
+	if len(contracts) > sweepContracts {
+		contracts = contracts[:sweepContracts]
+	}
+
+	flow := service.NewTradeFlowService()
+	minPremium := sweepMinPremium()
+	var events []confirmationEvent
+	for _, contract := range contracts {
+		if contract.Volume == 0 {
+			break
+		}
+		direction := 1
+		if contract.Type == "put" {
+			direction = -1
+		}
+		sweeps, err := flow.ContractSweeps(ctx, contract.Ticker, date, minPremium)
+		if err != nil {
+			return nil, err
+		}
+		for _, sweep := range sweeps {
+			events = append(events, confirmationEvent{
+				source:    models.ConfirmSweeps,
+				timestamp: sweep.Timestamp,
+				direction: direction,
+				detail: fmt.Sprintf("%s %.0f contracts across %d exchanges ($%.0f)",
+					contract.Ticker, sweep.Size, sweep.Exchanges, sweep.Premium),
+			})
+		}
+	}
+	return events, nil
+}
+
+// confirmMoves walks the events in time order and confirms a move whenever
+// at least required sources fired in the same direction within window up to
+// an event. A direction is not confirmed again until window has passed since
+// its last confirmation, so one sustained move is reported once.
+func confirmMoves(events []confirmationEvent, required int, window time.Duration) []models.ConfirmedMove {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].timestamp.Before(events[j].timestamp) })
+
+	var moves []models.ConfirmedMove
+	lastConfirmed := map[int]time.Time{}
+	start := 0
+	for i, event := range events {
+		for events[start].timestamp.Before(event.timestamp.Add(-window)) {
+			start++
+		}
+		if last, ok := lastConfirmed[event.direction]; ok && event.timestamp.Sub(last) <= window {
+			continue
+		}
+
+		// The latest event of each source agreeing with this one
+		latest := map[string]confirmationEvent{}
+		for _, e := range events[start : i+1] {
+			if e.direction == event.direction {
+				latest[e.source] = e
+			}
+		}
+		if len(latest) < required {
+			continue
+		}
+
+		move := models.ConfirmedMove{
+			Timestamp: event.timestamp,
+			Direction: "UP",
+			Required:  required,
+		}
+		if event.direction < 0 {
+			move.Direction = "DOWN"
+		}
+		for _, source := range models.ConfirmationSources {
+			e, ok := latest[source]
+			if !ok {
+				continue
+			}
+			move.Sources = append(move.Sources, source)
+			move.Evidence = append(move.Evidence, models.ConfirmationEvidence{Source: source, Timestamp: e.timestamp, Detail: e.detail})
+		}
+		moves = append(moves, move)
+		lastConfirmed[event.direction] = event.timestamp
+	}
+	return moves
+}
+
+// applyConfirmedMoves marks each bar a stored confirmed move of the ticker
+// fell in; Polygon is not called. When a bar holds moves both ways, neither counts.
+func (s *DeepSearchService) applyConfirmedMoves(ctx context.Context, bars []EnhancedBar) {
+	if len(bars) == 0 || s.db == nil {
+		return
+	}
+	duration := BarDuration(s.timeSpan, s.multiplier)
+	var moves []models.ConfirmedMove
+	err := s.db.WithContext(ctx).
+		Where("ticker = ? AND timestamp >= ? AND timestamp < ?", s.ticker, bars[0].Timestamp, bars[len(bars)-1].Timestamp.Add(duration)).
+		Order("timestamp").
+		Find(&moves).Error
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to load confirmed moves of %s: %v\n", s.ticker, err)
+		return
+	}
+	placeConfirmedMoves(bars, moves, duration)
+}
+
+// placeConfirmedMoves sets ConfirmedMove and ConfirmedSources on the bar each
+// move (oldest first) fell in
+func placeConfirmedMoves(bars []EnhancedBar, moves []models.ConfirmedMove, duration time.Duration) {
+	for _, move := range moves {
+		i := sort.Search(len(bars), func(i int) bool { return bars[i].Timestamp.After(move.Timestamp) }) - 1
+		if i < 0 || !move.Timestamp.Before(bars[i].Timestamp.Add(duration)) {
+			continue
+		}
+		direction := 1
+		if move.Direction == "DOWN" {
+			direction = -1
+		}
+		bar := &bars[i]
+		switch bar.ConfirmedMove {
+		case 0:
+			bar.ConfirmedMove = direction
+			bar.ConfirmedSources = move.Sources
+		case direction:
+			bar.ConfirmedSources = mergeSources(bar.ConfirmedSources, move.Sources)
+		default:
+			bar.ConfirmedMove = 0
+			bar.ConfirmedSources = nil
+		}
+	}
+}
+
+// mergeSources returns the sources in either list, in models.ConfirmationSources order
+func mergeSources(a, b []string) []string {
+	in := map[string]bool{}
+	for _, source := range append(append([]string{}, a...), b...) {
+		in[source] = true
+	}
+	var merged []string
+	for _, source := range models.ConfirmationSources {
+		if in[source] {
+			merged = append(merged, source)
+		}
+	}
+	return merged
+}
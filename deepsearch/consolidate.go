@@ -0,0 +1,99 @@
+package deepsearch
+
+import (
+	"os"
+	"strings"
+
+	"institutionanalyser/models"
+)
+
+// signalConsolidation reports whether signals of the same bar and vote are
+// merged into one; SIGNAL_CONSOLIDATION=false keeps every signal
+func signalConsolidation() bool {
+	return os.Getenv("SIGNAL_CONSOLIDATION") != "false"
+}
+
+// consolidateSignals merges the signals of each bar that count towards the
+// same vote into one record, so a single bar cannot outvote the rest of the
+// window. The merged record keeps the text of its highest-scoring signal,
+// lists the other kinds in an "[Also: ...]" tag and carries every signal's
+// crossings. Signals must be in bar order.
+func consolidateSignals(signals []Signal) []Signal {
+	consolidated := make([]Signal, 0, len(signals))
+	for start := 0; start < len(signals); {
+		end := start + 1
+		for end < len(signals) && signals[end].Timestamp.Equal(signals[start].Timestamp) {
+			end++
+		}
+		consolidated = append(consolidated, consolidateBar(signals[start:end])...)
+		start = end
+	}
+	return consolidated
+}
+
+// consolidateBar merges one bar's signals by vote, in the order each vote
+// first appears
+func consolidateBar(signals []Signal) []Signal {
+	if len(signals) < 2 {
+		return signals
+	}
+
+	var votes []string
+	byVote := map[string][]Signal{}
+	for _, signal := range signals {
+		vote := signalVote(signal.Text)
+		if _, ok := byVote[vote]; !ok {
+			votes = append(votes, vote)
+		}
+		byVote[vote] = append(byVote[vote], signal)
+	}
+
+	merged := make([]Signal, 0, len(votes))
+	for _, vote := range votes {
+		merged = append(merged, mergeSignals(byVote[vote]))
+	}
+	return merged
+}
+
+// mergeSignals merges signals of one bar and vote. Ties for the highest
+// score go to the signal emitted first.
+func mergeSignals(signals []Signal) Signal {
+	if len(signals) == 1 {
+		return signals[0]
+	}
+
+	primary, best := 0, -1.0
+	kinds := make([]string, len(signals))
+	for i, signal := range signals {
+		kinds[i] = signalKind(ParseSignal(signal.Text).Description)
+		if score := weightOf(kinds[i]).Score(); score > best {
+			primary, best = i, score
+		}
+	}
+
+	var also []string
+	crossings := append([]models.ThresholdCrossing{}, signals[primary].Crossings...)
+	for i, signal := range signals {
+		if i == primary {
+			continue
+		}
+		also = append(also, kinds[i])
+		crossings = append(crossings, signal.Crossings...)
+	}
+
+	return Signal{
+		Timestamp: signals[primary].Timestamp,
+		Text:      withAlso(signals[primary].Text, also),
+		Crossings: crossings,
+	}
+}
+
+// withAlso adds the "[Also: ...]" tag to a signal's text, ahead of its
+// regime tag
+func withAlso(text string, kinds []string) string {
+	tag := " [Also: " + strings.Join(kinds, "; ") + "]"
+	if loc := regimeRe.FindStringIndex(text); loc != nil {
+		return text[:loc[0]] + tag + text[loc[0]:]
+	}
+	return text + tag
+}
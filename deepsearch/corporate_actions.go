@@ -0,0 +1,119 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"institutionanalyser/service"
+)
+
+// Corporate action types
+const (
+	CorporateActionSplit    = "split"
+	CorporateActionDividend = "dividend"
+)
+
+// CorporateAction is a split or cash dividend that changes the price scale
+// of a ticker on its effective (execution/ex) date
+type CorporateAction struct {
+	Type          string    `json:"type"`
+	EffectiveDate time.Time `json:"effective_date"`
+	// SplitFrom/SplitTo describe a split, e.g. 1 -> 4 for a 4-for-1 split
+	SplitFrom  float64 `json:"split_from,omitempty"`
+	SplitTo    float64 `json:"split_to,omitempty"`
+	CashAmount float64 `json:"cash_amount,omitempty"`
+}
+
+// largeDividendThreshold returns the dividend yield (fraction of price) above
+// which a dividend is treated as a price-moving corporate action
+func largeDividendThreshold() float64 {
+	threshold := 0.02
+	if val := os.Getenv("LARGE_DIVIDEND_PCT"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n >= 0 {
+			threshold = n / 100
+		}
+	}
+	return threshold
+}
+
+// LookupCorporateActions returns the splits and dividends for a ticker between from and to, oldest first
+func LookupCorporateActions(ctx context.Context, ticker string, from, to time.Time) ([]CorporateAction, error) {
+	svc := service.NewStockTechnicalService(ticker)
+
+	splits, err := svc.GetSplits(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch splits: %w", err)
+	}
+	dividends, err := svc.GetDividends(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dividends: %w", err)
+	}
+
+	var actions []CorporateAction
+	for _, split := range splits {
+		if split.SplitFrom <= 0 || split.SplitTo <= 0 {
+			continue
+		}
+		actions = append(actions, CorporateAction{
+			Type:          CorporateActionSplit,
+			EffectiveDate: time.Time(split.ExecutionDate),
+			SplitFrom:     split.SplitFrom,
+			SplitTo:       split.SplitTo,
+		})
+	}
+	for _, dividend := range dividends {
+		exDate, err := time.Parse("2006-01-02", dividend.ExDividendDate)
+		if err != nil || dividend.CashAmount <= 0 {
+			continue
+		}
+		actions = append(actions, CorporateAction{
+			Type:          CorporateActionDividend,
+			EffectiveDate: exDate,
+			CashAmount:    dividend.CashAmount,
+		})
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].EffectiveDate.Before(actions[j].EffectiveDate)
+	})
+	return actions, nil
+}
+
+// AdjustPriceForCorporateActions restates a price recorded at entry into the
+// price scale in effect at exit, applying every split and large dividend that
+// took effect after entry and on or before exit. It returns the adjusted price
+// and the actions that were applied.
+func AdjustPriceForCorporateActions(price float64, entry, exit time.Time, actions []CorporateAction) (float64, []CorporateAction) {
+	var applied []CorporateAction
+	threshold := largeDividendThreshold()
+	adjusted := price
+
+	for _, action := range actions {
+		// Actions are effective from the session open of their date
+		if !action.EffectiveDate.After(truncateToDate(entry)) || action.EffectiveDate.After(exit) {
+			continue
+		}
+
+		switch action.Type {
+		case CorporateActionSplit:
+			adjusted *= action.SplitFrom / action.SplitTo
+			applied = append(applied, action)
+		case CorporateActionDividend:
+			if adjusted > 0 && action.CashAmount/adjusted >= threshold {
+				adjusted -= action.CashAmount
+				applied = append(applied, action)
+			}
+		}
+	}
+
+	return adjusted, applied
+}
+
+func truncateToDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
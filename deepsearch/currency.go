@@ -0,0 +1,39 @@
+package deepsearch
+
+import (
+	"institutionanalyser/models"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+)
+
+// quoteCurrency returns the analysed ticker's quote currency, read from the
+// reference data once per service. Services without a database assume
+// models.DefaultCurrency.
+func (s *DeepSearchService) quoteCurrency() models.QuoteCurrency {
+	if s.currency == nil {
+		currency := models.TickerCurrency(s.db, s.ticker)
+		s.currency = &currency
+	}
+	return *s.currency
+}
+
+// Currency is the ISO code of the currency the analysis's prices are in
+func (s *DeepSearchService) Currency() string {
+	return s.quoteCurrency().Currency
+}
+
+// scaleAggs turns quoted prices into the major currency in place, e.g.
+// pence into pounds. Volumes are share counts and stay as they are, so
+// notional values (price x volume) come out in the major currency too.
+func scaleAggs(aggs []polygonmodels.Agg, scale float64) {
+	if scale == 1 || scale == 0 {
+		return
+	}
+	for i := range aggs {
+		aggs[i].Open *= scale
+		aggs[i].High *= scale
+		aggs[i].Low *= scale
+		aggs[i].Close *= scale
+		aggs[i].VWAP *= scale
+	}
+}
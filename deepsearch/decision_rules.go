@@ -0,0 +1,211 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// Inputs a decision rule condition can reference, taken from the latest bar
+// and the daily indicators
+var decisionInputs = map[string]string{
+	"price":       "close of the latest bar",
+	"vwap":        "cumulative VWAP of the latest bar",
+	"twap":        "TWAP of the window",
+	"atr":         "ATR of the latest bar",
+	"prev_atr":    "ATR of the bar before",
+	"sma":         "daily SMA(20)",
+	"rsi":         "daily RSI(14)",
+	"macd":        "daily MACD(12, 26, 9) value",
+	"macd_signal": "daily MACD signal line",
+	"macd_hist":   "daily MACD histogram",
+}
+
+var decisionOperators = map[string]func(left, right float64) bool{
+	">":  func(l, r float64) bool { return l > r },
+	">=": func(l, r float64) bool { return l >= r },
+	"<":  func(l, r float64) bool { return l < r },
+	"<=": func(l, r float64) bool { return l <= r },
+	"==": func(l, r float64) bool { return l == r },
+	"!=": func(l, r float64) bool { return l != r },
+}
+
+var ruleDecisions = map[string]bool{"BUY": true, "SELL": true, "HOLD": true, "STRADDLE": true}
+
+// DefaultDecisionRules is the built-in decision table, used while no rules are stored
+func DefaultDecisionRules() []models.DecisionRule {
+	return []models.DecisionRule{
+		{
+			Name: "oversold_reversal", Priority: 10, Decision: "BUY", Enabled: true,
+			Reason: "Cheap price, oversold, bullish momentum",
+			Conditions: []models.DecisionCondition{
+				{Input: "price", Operator: "<", Ref: "vwap"},
+				{Input: "rsi", Operator: "<", Value: 30},
+				{Input: "macd", Operator: ">", Ref: "macd_signal"},
+			},
+		},
+		{
+			Name: "overbought_reversal", Priority: 20, Decision: "SELL", Enabled: true,
+			Reason: "Expensive price, overbought, bearish momentum",
+			Conditions: []models.DecisionCondition{
+				{Input: "price", Operator: ">", Ref: "vwap"},
+				{Input: "rsi", Operator: ">", Value: 70},
+				{Input: "macd", Operator: "<", Ref: "macd_signal"},
+			},
+		},
+		{
+			Name: "volatility_spike", Priority: 30, Decision: "STRADDLE", Enabled: true,
+			Reason: "Volatility spiking, no clear trend",
+			Conditions: []models.DecisionCondition{
+				{Input: "atr", Operator: ">", Ref: "prev_atr", Factor: 1.5},
+			},
+		},
+	}
+}
+
+// DecisionInputs returns the input names conditions may use with their descriptions
+func DecisionInputs() map[string]string {
+	return decisionInputs
+}
+
+// NormalizeDecisionRule validates a rule, upper-casing its decision
+func NormalizeDecisionRule(rule *models.DecisionRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Decision = strings.ToUpper(strings.TrimSpace(rule.Decision))
+
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if !ruleDecisions[rule.Decision] {
+		return fmt.Errorf("invalid decision %q (BUY, SELL, HOLD or STRADDLE)", rule.Decision)
+	}
+	if len(rule.Conditions) == 0 {
+		return errors.New("a rule needs at least one condition")
+	}
+	for i := range rule.Conditions {
+		cond := &rule.Conditions[i]
+		cond.Input = strings.ToLower(strings.TrimSpace(cond.Input))
+		cond.Ref = strings.ToLower(strings.TrimSpace(cond.Ref))
+		cond.Operator = strings.TrimSpace(cond.Operator)
+		if _, ok := decisionInputs[cond.Input]; !ok {
+			return fmt.Errorf("condition %d: invalid input %q (one of %s)", i+1, cond.Input, strings.Join(inputNames(), ", "))
+		}
+		if _, ok := decisionInputs[cond.Ref]; cond.Ref != "" && !ok {
+			return fmt.Errorf("condition %d: invalid ref %q (one of %s)", i+1, cond.Ref, strings.Join(inputNames(), ", "))
+		}
+		if _, ok := decisionOperators[cond.Operator]; !ok {
+			return fmt.Errorf("condition %d: invalid operator %q (>, >=, <, <=, == or !=)", i+1, cond.Operator)
+		}
+	}
+	return nil
+}
+
+func inputNames() []string {
+	names := make([]string, 0, len(decisionInputs))
+	for name := range decisionInputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDecisionRules returns the stored decision table, or the defaults if none is stored
+func LoadDecisionRules(ctx context.Context, db *gorm.DB) ([]models.DecisionRule, error) {
+	var rules []models.DecisionRule
+	if err := db.WithContext(ctx).Order("priority, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return DefaultDecisionRules(), nil
+	}
+	return rules, nil
+}
+
+// ConditionTrace is one evaluated condition with the values it compared
+type ConditionTrace struct {
+	Condition string  `json:"condition"`
+	Left      float64 `json:"left"`
+	Right     float64 `json:"right"`
+	Passed    bool    `json:"passed"`
+}
+
+// RuleTrace records how one rule was evaluated. Rules after the deciding one
+// are not evaluated and have no trace.
+type RuleTrace struct {
+	Rule       string           `json:"rule"`
+	Priority   int              `json:"priority"`
+	Decision   string           `json:"decision"`
+	Matched    bool             `json:"matched"`
+	Conditions []ConditionTrace `json:"conditions"`
+}
+
+// RuleEvaluation is the decision of a rule table over a set of inputs
+type RuleEvaluation struct {
+	Decision string             `json:"decision"`
+	Reason   string             `json:"reason"`
+	Rule     string             `json:"rule,omitempty"`
+	Inputs   map[string]float64 `json:"inputs"`
+	Trace    []RuleTrace        `json:"trace"`
+}
+
+// EvaluateDecisionRules applies the enabled rules in priority order; the first
+// whose conditions all hold decides
+func EvaluateDecisionRules(rules []models.DecisionRule, inputs map[string]float64) *RuleEvaluation {
+	sorted := append([]models.DecisionRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	result := &RuleEvaluation{Decision: "HOLD", Reason: "No strong signals", Inputs: inputs, Trace: []RuleTrace{}}
+	for _, rule := range sorted {
+		if !rule.Enabled {
+			continue
+		}
+
+		trace := RuleTrace{Rule: rule.Name, Priority: rule.Priority, Decision: rule.Decision, Matched: true}
+		for _, cond := range rule.Conditions {
+			ct := evaluateCondition(cond, inputs)
+			trace.Conditions = append(trace.Conditions, ct)
+			if !ct.Passed {
+				// Later conditions are still traced, so the whole rule can be inspected
+				trace.Matched = false
+			}
+		}
+		result.Trace = append(result.Trace, trace)
+
+		if trace.Matched {
+			result.Decision = rule.Decision
+			result.Reason = rule.Reason
+			result.Rule = rule.Name
+			break
+		}
+	}
+	return result
+}
+
+func evaluateCondition(cond models.DecisionCondition, inputs map[string]float64) ConditionTrace {
+	left, okLeft := inputs[cond.Input]
+	right, okRight := cond.Value, true
+	text := fmt.Sprintf("%s %s %g", cond.Input, cond.Operator, cond.Value)
+	if cond.Ref != "" {
+		factor := cond.Factor
+		if factor == 0 {
+			factor = 1
+		}
+		right, okRight = inputs[cond.Ref]
+		right *= factor
+		text = fmt.Sprintf("%s %s %s", cond.Input, cond.Operator, cond.Ref)
+		if factor != 1 {
+			text = fmt.Sprintf("%s %s %s * %g", cond.Input, cond.Operator, cond.Ref, factor)
+		}
+	}
+
+	compare, okOp := decisionOperators[cond.Operator]
+	// A missing input (e.g. no previous bar) never satisfies a condition
+	passed := okLeft && okRight && okOp && compare(left, right)
+	return ConditionTrace{Condition: text, Left: left, Right: right, Passed: passed}
+}
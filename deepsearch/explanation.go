@@ -0,0 +1,146 @@
+package deepsearch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"institutionanalyser/models"
+)
+
+// explainTopReasons is how many supporting signals the summary names
+const explainTopReasons = 3
+
+// signalValuesRe matches the values in a signal description, e.g. "(1234.00)",
+// so signals of the same kind group together
+var signalValuesRe = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// explainDecision breaks a scored decision down into each signal's
+// contribution: its kind's weight and confidence, scaled by its direction's
+// weight when weights are given, and its share of the total score.
+func explainDecision(signals []Signal, decision string, confidence float64, weights map[string]float64) *models.DecisionExplanation {
+	method := "signal_score"
+	if weights != nil {
+		method = "weighted_signal_score"
+	}
+	explanation := &models.DecisionExplanation{
+		Method:        method,
+		Decision:      decision,
+		Confidence:    confidence,
+		Votes:         map[string]int{"BUY": 0, "SELL": 0, "STRADDLE": 0, "HOLD": 0},
+		Scores:        decisionScores(signalTexts(signals), weights),
+		Contributions: make([]models.SignalContribution, 0, len(signals)),
+	}
+
+	total := scoreTotal(explanation.Scores)
+	for _, signal := range signals {
+		parsed := ParseSignal(signal.Text)
+		kind := signalKind(parsed.Description)
+		kindWeight := weightOf(kind)
+		score := signalScore(signal.Text, weights)
+		weight := 0.0
+		if total > 0 {
+			weight = score / total
+		}
+		vote := signalVote(signal.Text)
+		explanation.Votes[vote]++
+		explanation.Contributions = append(explanation.Contributions, models.SignalContribution{
+			Time:           signal.Timestamp,
+			Signal:         parsed.Description,
+			Direction:      parsed.Direction,
+			Kind:           kind,
+			KindWeight:     kindWeight.Weight,
+			KindConfidence: kindWeight.Confidence,
+			Score:          score,
+			Vote:           vote,
+			Weight:         weight,
+			Supports:       vote == decision,
+			Crossings:      signal.Crossings,
+		})
+	}
+
+	explanation.Summary = explanationSummary(explanation)
+	return explanation
+}
+
+// explanationSummary justifies the decision in one line: the vote, and the
+// supporting signal kinds that fired most often with their widest margin
+func explanationSummary(e *models.DecisionExplanation) string {
+	total := len(e.Contributions)
+	if total == 0 {
+		return fmt.Sprintf("%s: no signals fired", e.Decision)
+	}
+
+	type reason struct {
+		signal string
+		count  int
+		margin *models.ThresholdCrossing
+	}
+	byKind := map[string]*reason{}
+	var kinds []*reason
+	for _, c := range e.Contributions {
+		if !c.Supports {
+			continue
+		}
+		kind := c.Kind
+		if kind == "" {
+			kind = signalKind(c.Signal)
+		}
+		r, ok := byKind[kind]
+		if !ok {
+			r = &reason{signal: kind}
+			byKind[kind] = r
+			kinds = append(kinds, r)
+		}
+		r.count++
+		if len(c.Crossings) > 0 {
+			crossing := c.Crossings[0]
+			if r.margin == nil || relativeMargin(crossing) > relativeMargin(*r.margin) {
+				r.margin = &crossing
+			}
+		}
+	}
+	sort.SliceStable(kinds, func(i, j int) bool { return kinds[i].count > kinds[j].count })
+
+	summary := fmt.Sprintf("%s: %d of %d signals (%.0f%%) voted %s", e.Decision, e.Votes[e.Decision], total, e.Confidence*100, e.Decision)
+	if e.Scores != nil {
+		summary = fmt.Sprintf("%s: score %.2f of %.2f (%.0f%%) from %d of %d signals", e.Decision, e.Scores[e.Decision], scoreTotal(e.Scores), e.Confidence*100, e.Votes[e.Decision], total)
+	}
+	var reasons []string
+	for i, r := range kinds {
+		if i == explainTopReasons {
+			break
+		}
+		text := fmt.Sprintf("%s x%d", r.signal, r.count)
+		if r.margin != nil {
+			text += fmt.Sprintf(" (%s %s %.2f by %.2f)", r.margin.Metric, r.margin.Comparison, r.margin.Threshold, r.margin.Margin)
+		}
+		reasons = append(reasons, text)
+	}
+	if len(reasons) > 0 {
+		summary += "; led by " + strings.Join(reasons, ", ")
+	}
+	return summary
+}
+
+// scoreTotal sums the scores of all votes
+func scoreTotal(scores map[string]float64) float64 {
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+	return total
+}
+
+// relativeMargin is how far past its threshold a crossing went, as a share of the threshold
+func relativeMargin(c models.ThresholdCrossing) float64 {
+	if c.Threshold == 0 {
+		return 0
+	}
+	m := c.Margin / c.Threshold
+	if m < 0 {
+		m = -m
+	}
+	return m
+}
@@ -0,0 +1,196 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// footprintBiasThreshold is the score beyond which a week counts as accumulation or distribution
+const footprintBiasThreshold = 0.1
+
+// FootprintWeek returns the Monday (market time) of the week containing date
+func FootprintWeek(date time.Time) time.Time {
+	date = date.In(marketTimezone)
+	offset := (int(date.Weekday()) + 6) % 7
+	return time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, marketTimezone)
+}
+
+// LastCompletedWeek returns the Monday of the latest week whose sessions are
+// all over at now: the current week at the weekend, the previous one otherwise
+func LastCompletedWeek(now time.Time) time.Time {
+	week := FootprintWeek(now)
+	if day := now.In(marketTimezone).Weekday(); day != time.Saturday && day != time.Sunday {
+		week = week.AddDate(0, 0, -7)
+	}
+	return week
+}
+
+// BuildFootprint combines a ticker's institutional activity over the week
+// containing week: the institutional flow signals of every stored analysis,
+// and the block trades and off-exchange share of each session's prints. Sessions
+// still to come are left out, and a session whose trades cannot be loaded is
+// kept with its error so the rest of the week is still reported.
+func BuildFootprint(ctx context.Context, db *gorm.DB, ticker string, week time.Time) (*models.InstitutionalFootprint, error) {
+	monday := FootprintWeek(week)
+	saturday := monday.AddDate(0, 0, 5)
+	footprint := &models.InstitutionalFootprint{
+		Ticker:    ticker,
+		WeekStart: monday.Format("2006-01-02"),
+		WeekEnd:   saturday.AddDate(0, 0, -1).Format("2006-01-02"),
+		Bias:      models.FootprintNeutral,
+	}
+
+	calendar := service.DefaultTradingCalendar()
+	now := time.Now().In(marketTimezone)
+	days := map[string]*models.FootprintDay{}
+	for day := monday; day.Before(saturday) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		if !calendar.IsTradingDay(ctx, day) {
+			continue
+		}
+		footprint.Days = append(footprint.Days, models.FootprintDay{Date: day.Format("2006-01-02")})
+	}
+	for i := range footprint.Days {
+		days[footprint.Days[i].Date] = &footprint.Days[i]
+	}
+	footprint.Sessions = len(footprint.Days)
+
+	if err := countInstitutionalSignals(ctx, db, footprint, days, monday, saturday); err != nil {
+		return nil, err
+	}
+
+	flow := service.NewTradeFlowService()
+	for i := range footprint.Days {
+		day := &footprint.Days[i]
+		date, _ := time.ParseInLocation("2006-01-02", day.Date, marketTimezone)
+		prints, err := flow.SessionPrints(ctx, ticker, date)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			day.Error = err.Error()
+			continue
+		}
+
+		day.TotalVolume = prints.TotalVolume
+		day.BlockTrades = prints.BlockTrades
+		day.BlockVolume = prints.BlockVolume
+		day.NetBlockFlow = prints.NetBlockFlow
+		day.OffExchangeVolume = prints.OffExchangeVolume
+		if prints.TotalVolume > 0 {
+			day.DarkPoolShare = prints.OffExchangeVolume / prints.TotalVolume
+		}
+
+		footprint.TotalVolume += prints.TotalVolume
+		footprint.BlockTrades += prints.BlockTrades
+		footprint.BlockVolume += prints.BlockVolume
+		footprint.BlockBuyVolume += prints.BlockBuyVolume
+		footprint.BlockSellVolume += prints.BlockSellVolume
+		footprint.NetBlockFlow += prints.NetBlockFlow
+		footprint.OffExchangeVolume += prints.OffExchangeVolume
+	}
+	if footprint.TotalVolume > 0 {
+		footprint.DarkPoolShare = footprint.OffExchangeVolume / footprint.TotalVolume
+	}
+
+	footprint.Score, footprint.Bias = footprintBias(footprint)
+	return footprint, nil
+}
+
+// countInstitutionalSignals tallies the institutional flow signals of the
+// week's analyses by session. Analyses of overlapping windows repeat signals,
+// so each bar's signal is only counted once.
+func countInstitutionalSignals(ctx context.Context, db *gorm.DB, footprint *models.InstitutionalFootprint, days map[string]*models.FootprintDay, from, to time.Time) error {
+	var analyses []models.TechnicalSignal
+	err := db.WithContext(ctx).
+		Select("signals", "signal_timestamps").
+		Where("ticker = ? AND end_date >= ? AND start_date < ?", footprint.Ticker, from, to).
+		Find(&analyses).Error
+	if err != nil {
+		return fmt.Errorf("failed to load analyses: %w", err)
+	}
+	footprint.Analyses = len(analyses)
+
+	seen := map[string]bool{}
+	for _, analysis := range analyses {
+		// Signals stored without timestamps cannot be placed in a session
+		if len(analysis.SignalTimestamps) != len(analysis.Signals) {
+			continue
+		}
+		for i, text := range analysis.Signals {
+			at := time.UnixMilli(analysis.SignalTimestamps[i]).In(marketTimezone)
+			day := days[at.Format("2006-01-02")]
+			if day == nil {
+				continue
+			}
+			kind := institutionalKind(text)
+			key := fmt.Sprintf("%d %s", analysis.SignalTimestamps[i], kind)
+			if kind == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			switch kind {
+			case "buying":
+				day.BuyingSignals++
+				footprint.BuyingSignals++
+			case "selling":
+				day.SellingSignals++
+				footprint.SellingSignals++
+			default:
+				footprint.ActivitySignals++
+			}
+		}
+	}
+	return nil
+}
+
+// institutionalKind classifies an institutional flow signal as buying,
+// selling or activity (volatility expansion), or "" for other signals
+func institutionalKind(text string) string {
+	switch {
+	case strings.Contains(text, "Institutional Buying"):
+		return "buying"
+	case strings.Contains(text, "Institutional Selling"):
+		return "selling"
+	case strings.Contains(text, "Institutional Activity"):
+		return "activity"
+	}
+	return ""
+}
+
+// footprintBias scores a week from -1 (distribution) to 1 (accumulation) as
+// the mean of its buying/selling signal imbalance and block volume imbalance
+func footprintBias(footprint *models.InstitutionalFootprint) (float64, string) {
+	var imbalances []float64
+	if n := footprint.BuyingSignals + footprint.SellingSignals; n > 0 {
+		imbalances = append(imbalances, float64(footprint.BuyingSignals-footprint.SellingSignals)/float64(n))
+	}
+	if v := footprint.BlockBuyVolume + footprint.BlockSellVolume; v > 0 {
+		imbalances = append(imbalances, (footprint.BlockBuyVolume-footprint.BlockSellVolume)/v)
+	}
+	if len(imbalances) == 0 {
+		return 0, models.FootprintNeutral
+	}
+
+	score := 0.0
+	for _, imbalance := range imbalances {
+		score += imbalance
+	}
+	score /= float64(len(imbalances))
+
+	switch {
+	case score > footprintBiasThreshold:
+		return score, models.FootprintAccumulation
+	case score < -footprintBiasThreshold:
+		return score, models.FootprintDistribution
+	default:
+		return score, models.FootprintNeutral
+	}
+}
@@ -0,0 +1,260 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ftdLookback is how many earlier settlement dates a ticker's fails are compared with
+	ftdLookback = 20
+	// ftdMinHistory is how many earlier settlement dates a spike needs
+	ftdMinHistory = 5
+	// defaultFTDSpikeZScore is how far above its recent fails a spike must be
+	defaultFTDSpikeZScore = 2.5
+	// defaultFTDSpikeMinShares keeps thinly traded names' small fails from spiking
+	defaultFTDSpikeMinShares = 100000
+)
+
+// ftdSpikeZScore returns the spike threshold, FTD_SPIKE_ZSCORE overriding the default
+func ftdSpikeZScore() float64 {
+	if val := os.Getenv("FTD_SPIKE_ZSCORE"); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	return defaultFTDSpikeZScore
+}
+
+// ftdSpikeMinShares returns the fewest failed shares of a spike,
+// FTD_SPIKE_MIN_SHARES overriding the default
+func ftdSpikeMinShares() int64 {
+	if val := os.Getenv("FTD_SPIKE_MIN_SHARES"); val != "" {
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultFTDSpikeMinShares
+}
+
+// FTDPeriod names the half-month fails-to-deliver file covering day, e.g.
+// 202505a for May 1-15 and 202505b for May 16-31
+func FTDPeriod(day time.Time) string {
+	half := "a"
+	if day.Day() > 15 {
+		half = "b"
+	}
+	return day.Format("200601") + half
+}
+
+// RecentFTDPeriods returns the n half-month periods before the one covering
+// now, newest first. The SEC publishes each about two weeks after it ends,
+// so the current period is never available.
+func RecentFTDPeriods(now time.Time, n int) []string {
+	periods := make([]string, 0, n)
+	start := ftdPeriodStart(now)
+	for len(periods) < n {
+		start = ftdPeriodStart(start.AddDate(0, 0, -1))
+		periods = append(periods, FTDPeriod(start))
+	}
+	return periods
+}
+
+// ftdPeriodStart returns the first day of the half month covering day
+func ftdPeriodStart(day time.Time) time.Time {
+	first := 1
+	if day.Day() > 15 {
+		first = 16
+	}
+	return time.Date(day.Year(), day.Month(), first, 0, 0, 0, 0, day.Location())
+}
+
+// ValidFTDPeriod reports whether period names a half month, e.g. 202505a
+func ValidFTDPeriod(period string) bool {
+	if len(period) != 7 || (period[6] != 'a' && period[6] != 'b') {
+		return false
+	}
+	_, err := time.Parse("200601", period[:6])
+	return err == nil
+}
+
+// FTDIngest counts what loading one fails-to-deliver file stored
+type FTDIngest struct {
+	Period  string `json:"period"`
+	Rows    int    `json:"rows"`
+	Tickers int    `json:"tickers"`
+	// Published is false when the SEC has not released the period yet
+	Published bool `json:"published"`
+}
+
+// IngestFailsToDeliver loads one half-month fails-to-deliver file, storing
+// every ticker's fails by settlement date. Loading a period again replaces
+// its rows.
+func IngestFailsToDeliver(ctx context.Context, db *gorm.DB, period string) (*FTDIngest, error) {
+	sec := service.NewSECService()
+	if !sec.Configured() {
+		return nil, errors.New("SEC_USER_AGENT must be set to read fails-to-deliver data")
+	}
+
+	ingest := &FTDIngest{Period: period}
+	rows, err := sec.FailsToDeliver(ctx, period)
+	if errors.Is(err, service.ErrSECNotFound) {
+		return ingest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ingest.Published = true
+
+	// A symbol can be listed under more than one CUSIP on a date; their fails add up
+	type key struct{ ticker, date string }
+	index := map[key]int{}
+	tickers := map[string]bool{}
+	var fails []models.FailToDeliver
+	for _, row := range rows {
+		if row.Symbol == "" {
+			continue
+		}
+		k := key{row.Symbol, row.SettlementDate}
+		if i, ok := index[k]; ok {
+			fails[i].Quantity += row.Quantity
+			fails[i].Value += float64(row.Quantity) * row.Price
+			continue
+		}
+		index[k] = len(fails)
+		tickers[row.Symbol] = true
+		fails = append(fails, models.FailToDeliver{
+			Ticker:         row.Symbol,
+			SettlementDate: row.SettlementDate,
+			CUSIP:          row.CUSIP,
+			Quantity:       row.Quantity,
+			Price:          row.Price,
+			Value:          float64(row.Quantity) * row.Price,
+		})
+	}
+
+	if err := models.StoreFailsToDeliver(db.WithContext(ctx), period, fails); err != nil {
+		return nil, fmt.Errorf("failed to store fails-to-deliver %s: %w", period, err)
+	}
+	ingest.Rows, ingest.Tickers = len(fails), len(tickers)
+	return ingest, nil
+}
+
+// FTDSpike is a settlement date on which a ticker's failed shares jumped
+// well above its recent fails
+type FTDSpike struct {
+	SettlementDate string  `json:"settlement_date"`
+	Quantity       int64   `json:"quantity"`
+	Value          float64 `json:"value"`
+	// ZScore compares Quantity with the ticker's earlier settlement dates
+	ZScore float64 `json:"zscore"`
+}
+
+// loadFailsToDeliver returns a ticker's stored fails settled from from to to,
+// after the ftdLookback settlement dates before from, oldest first
+func loadFailsToDeliver(ctx context.Context, db *gorm.DB, ticker string, from, to time.Time) ([]models.FailToDeliver, error) {
+	var prior, fails []models.FailToDeliver
+	err := db.WithContext(ctx).
+		Where("ticker = ? AND settlement_date < ?", ticker, from.Format("2006-01-02")).
+		Order("settlement_date DESC").
+		Limit(ftdLookback).
+		Find(&prior).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.WithContext(ctx).
+		Where("ticker = ? AND settlement_date >= ? AND settlement_date <= ?", ticker, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("settlement_date").
+		Find(&fails).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(prior)-1; i < j; i, j = i+1, j-1 {
+		prior[i], prior[j] = prior[j], prior[i]
+	}
+	return append(prior, fails...), nil
+}
+
+// ftdSpikes finds the spikes among fails (oldest first) settled on or after
+// from. Settlement dates the SEC did not list are not counted as zero, so a
+// ticker's history is the dates it had fails on.
+func ftdSpikes(fails []models.FailToDeliver, from string, minZScore float64, minShares int64) []FTDSpike {
+	spikes := []FTDSpike{}
+	for i, fail := range fails {
+		if fail.SettlementDate < from || fail.Quantity < minShares || i < ftdMinHistory {
+			continue
+		}
+		prior := fails[max(0, i-ftdLookback):i]
+		mean := 0.0
+		for _, p := range prior {
+			mean += float64(p.Quantity)
+		}
+		mean /= float64(len(prior))
+		stdDev := 0.0
+		for _, p := range prior {
+			stdDev += math.Pow(float64(p.Quantity)-mean, 2)
+		}
+		stdDev = math.Sqrt(stdDev / float64(len(prior)))
+		if stdDev == 0 {
+			continue
+		}
+		if z := (float64(fail.Quantity) - mean) / stdDev; z >= minZScore {
+			spikes = append(spikes, FTDSpike{SettlementDate: fail.SettlementDate, Quantity: fail.Quantity, Value: fail.Value, ZScore: z})
+		}
+	}
+	return spikes
+}
+
+// FTDSpikes returns the ticker's fails-to-deliver spikes settled between from
+// and to (market days, inclusive)
+func FTDSpikes(ctx context.Context, db *gorm.DB, ticker string, from, to time.Time) ([]FTDSpike, error) {
+	from, to = marketDay(from), marketDay(to)
+	fails, err := loadFailsToDeliver(ctx, db, ticker, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return ftdSpikes(fails, from.Format("2006-01-02"), ftdSpikeZScore(), ftdSpikeMinShares()), nil
+}
+
+// applyFTDSpikes sets FTDShares and FTDZScore on the bars of settlement dates
+// with a fails-to-deliver spike, from the stored data; the SEC is not called.
+// Services without a database have none.
+func (s *DeepSearchService) applyFTDSpikes(ctx context.Context, bars []EnhancedBar) {
+	if len(bars) == 0 || s.db == nil {
+		return
+	}
+	spikes, err := FTDSpikes(ctx, s.db, s.ticker, bars[0].Timestamp, bars[len(bars)-1].Timestamp)
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to load fails-to-deliver of %s: %v\n", s.ticker, err)
+		return
+	}
+	if len(spikes) == 0 {
+		return
+	}
+	setFTDSpikes(bars, spikes)
+}
+
+// setFTDSpikes marks the bars of each spike's settlement date
+func setFTDSpikes(bars []EnhancedBar, spikes []FTDSpike) {
+	byDay := make(map[string]FTDSpike, len(spikes))
+	for _, spike := range spikes {
+		byDay[spike.SettlementDate] = spike
+	}
+	for i := range bars {
+		spike, ok := byDay[marketDay(bars[i].Timestamp).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		bars[i].FTDShares, bars[i].FTDZScore = spike.Quantity, spike.ZScore
+	}
+}
@@ -0,0 +1,203 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"institutionanalyser/models"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxHeatmapDays and MaxHeatmapTickers cap the heatmap's columns and rows
+	MaxHeatmapDays    = 366
+	MaxHeatmapTickers = 100
+
+	// Layout of the rendered PNG in pixels; heatmapWidth is its minimum width
+	heatmapCell   = 18
+	heatmapLeft   = 70
+	heatmapTop    = 40
+	heatmapBottom = 60
+	heatmapRight  = 20
+	heatmapWidth  = 320
+)
+
+// HeatmapCell is a ticker's last analysis of a market day
+type HeatmapCell struct {
+	Decision   string  `json:"decision"`
+	Confidence float64 `json:"confidence"`
+	// Score is the confidence signed by direction: positive for BUY,
+	// negative for SELL, 0 for HOLD and STRADDLE
+	Score      float64 `json:"score"`
+	AnalysisID uint    `json:"analysis_id"`
+}
+
+// SignalHeatmap is a ticker by market day matrix of stored decisions
+type SignalHeatmap struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Dates   []string `json:"dates"`
+	Tickers []string `json:"tickers"`
+	// Cells[i][j] is Tickers[i] on Dates[j], null where it was not analysed
+	Cells [][]*HeatmapCell `json:"cells"`
+	// Truncated is set when more tickers were analysed than the heatmap holds
+	Truncated bool `json:"truncated"`
+}
+
+// heatmapRow is one ticker's last analysis of a day
+type heatmapRow struct {
+	ID            uint
+	Ticker        string
+	Day           time.Time
+	FinalDecision string
+	Confidence    float64
+}
+
+// ComputeSignalHeatmap lays out the analyses matching filter between two
+// market days, inclusive, as a matrix of weekdays by ticker. Each cell holds
+// the ticker's analysis reaching furthest into that day. Without tickers in
+// the filter, the analysed tickers are listed alphabetically, at most
+// MaxHeatmapTickers of them. Bucketing by market day needs Postgres.
+func ComputeSignalHeatmap(ctx context.Context, db *gorm.DB, from, to time.Time, filter SignalFilter) (*SignalHeatmap, error) {
+	from, to = marketDay(from), marketDay(to)
+	heatmap := &SignalHeatmap{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Dates: []string{}, Tickers: []string{}}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			heatmap.Dates = append(heatmap.Dates, day.Format("2006-01-02"))
+		}
+	}
+
+	dayExpr := fmt.Sprintf("(end_date AT TIME ZONE '%s')::date", marketTimezone.String())
+	var rows []heatmapRow
+	err := filter.Apply(db.WithContext(ctx).Model(&models.TechnicalSignal{})).
+		Select(fmt.Sprintf("DISTINCT ON (ticker, %[1]s) id, ticker, %[1]s AS day, final_decision, confidence", dayExpr)).
+		Where("end_date >= ? AND end_date < ?", from, to.AddDate(0, 0, 1)).
+		Order(fmt.Sprintf("ticker, %s, end_date DESC, created_at DESC", dayExpr)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tickers := filter.Tickers
+	if len(tickers) == 0 {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			if !seen[row.Ticker] {
+				seen[row.Ticker] = true
+				tickers = append(tickers, row.Ticker)
+			}
+		}
+		sort.Strings(tickers)
+		if len(tickers) > MaxHeatmapTickers {
+			tickers, heatmap.Truncated = tickers[:MaxHeatmapTickers], true
+		}
+	}
+	heatmap.Tickers = append(heatmap.Tickers, tickers...)
+
+	rowOf := make(map[string]int, len(tickers))
+	for i, ticker := range tickers {
+		rowOf[ticker] = i
+	}
+	colOf := make(map[string]int, len(heatmap.Dates))
+	for j, date := range heatmap.Dates {
+		colOf[date] = j
+	}
+	heatmap.Cells = make([][]*HeatmapCell, len(tickers))
+	for i := range heatmap.Cells {
+		heatmap.Cells[i] = make([]*HeatmapCell, len(heatmap.Dates))
+	}
+	for _, row := range rows {
+		i, ok := rowOf[row.Ticker]
+		j, inRange := colOf[row.Day.Format("2006-01-02")]
+		if !ok || !inRange {
+			continue
+		}
+		cell := &HeatmapCell{Decision: row.FinalDecision, Confidence: row.Confidence, AnalysisID: row.ID}
+		switch row.FinalDecision {
+		case "BUY":
+			cell.Score = row.Confidence
+		case "SELL":
+			cell.Score = -row.Confidence
+		}
+		heatmap.Cells[i][j] = cell
+	}
+	return heatmap, nil
+}
+
+var (
+	heatmapBuy      = drawing.Color{R: 22, G: 163, B: 74, A: 255}
+	heatmapSell     = drawing.Color{R: 220, G: 38, B: 38, A: 255}
+	heatmapStraddle = drawing.Color{R: 217, G: 119, B: 6, A: 255}
+	heatmapHold     = drawing.Color{R: 203, G: 213, B: 225, A: 255}
+	heatmapEmpty    = drawing.Color{R: 248, G: 250, B: 252, A: 255}
+)
+
+// heatmapColor shades a cell by decision, deeper with confidence
+func heatmapColor(cell *HeatmapCell) drawing.Color {
+	if cell == nil {
+		return heatmapEmpty
+	}
+	base := heatmapHold
+	switch cell.Decision {
+	case "BUY":
+		base = heatmapBuy
+	case "SELL":
+		base = heatmapSell
+	case "STRADDLE":
+		base = heatmapStraddle
+	default:
+		return base
+	}
+	// Blend from white: 25% at no confidence up to the full colour at 100%
+	weight := 0.25 + 0.75*math.Max(0, math.Min(1, cell.Confidence))
+	blend := func(c uint8) uint8 { return uint8(255 - (255-float64(c))*weight) }
+	return drawing.Color{R: blend(base.R), G: blend(base.G), B: blend(base.B), A: 255}
+}
+
+// RenderHeatmapPNG draws the heatmap as a PNG: tickers down the side, dates
+// along the bottom, green for BUY, red for SELL and amber for STRADDLE,
+// deeper with confidence
+func RenderHeatmapPNG(heatmap *SignalHeatmap, w io.Writer) error {
+	width := max(heatmapLeft+heatmapRight+heatmapCell*len(heatmap.Dates), heatmapWidth)
+	height := heatmapTop + heatmapBottom + heatmapCell*max(len(heatmap.Tickers), 1)
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return err
+	}
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return err
+	}
+
+	chart.Draw.Box(r, chart.Box{Right: width, Bottom: height}, chart.Style{FillColor: drawing.ColorWhite, StrokeColor: drawing.ColorWhite})
+	label := chart.Style{Font: font, FontSize: 8, FontColor: drawing.ColorBlack}
+	chart.Draw.Text(r, fmt.Sprintf("Decisions %s to %s", heatmap.From, heatmap.To), heatmapLeft, heatmapTop/2, chart.Style{Font: font, FontSize: 12, FontColor: drawing.ColorBlack})
+
+	for i, ticker := range heatmap.Tickers {
+		y := heatmapTop + i*heatmapCell
+		chart.Draw.Text(r, ticker, 8, y+heatmapCell-5, label)
+		for j := range heatmap.Dates {
+			x := heatmapLeft + j*heatmapCell
+			box := chart.Box{Left: x, Top: y, Right: x + heatmapCell - 1, Bottom: y + heatmapCell - 1}
+			color := heatmapColor(heatmap.Cells[i][j])
+			chart.Draw.Box(r, box, chart.Style{FillColor: color, StrokeColor: color})
+		}
+	}
+
+	// Dates run down from under their column as MM-DD
+	rotated := label
+	rotated.TextRotationDegrees = 90
+	bottom := heatmapTop + max(len(heatmap.Tickers), 1)*heatmapCell
+	for j, date := range heatmap.Dates {
+		chart.Draw.Text(r, date[5:], heatmapLeft+j*heatmapCell+heatmapCell/2-4, bottom+6, rotated)
+	}
+
+	return r.Save(w)
+}
@@ -0,0 +1,287 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultInsiderClusterDays is the window insiders' buys are clustered over, in calendar days
+	defaultInsiderClusterDays = 14
+	// defaultInsiderClusterBuyers is how many different insiders make a cluster
+	defaultInsiderClusterBuyers = 3
+)
+
+// insiderClusterDays returns the cluster window, INSIDER_CLUSTER_DAYS overriding the default
+func insiderClusterDays() int {
+	if val := os.Getenv("INSIDER_CLUSTER_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultInsiderClusterDays
+}
+
+// insiderClusterBuyers returns the insiders a cluster needs,
+// INSIDER_CLUSTER_MIN_BUYERS overriding the default
+func insiderClusterBuyers() int {
+	if val := os.Getenv("INSIDER_CLUSTER_MIN_BUYERS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 1 {
+			return n
+		}
+	}
+	return defaultInsiderClusterBuyers
+}
+
+// InsiderIngest counts what one ingest of a ticker's Form 4 filings stored
+type InsiderIngest struct {
+	Ticker       string `json:"ticker"`
+	CIK          string `json:"cik"`
+	Since        string `json:"since"`
+	Filings      int    `json:"filings"`
+	Transactions int    `json:"transactions"`
+	// Skipped are filings already read before
+	Skipped int `json:"skipped"`
+}
+
+// IngestInsiderFilings reads the ticker's Form 4 filings filed since the
+// given day that were not read before, storing their open market buys and
+// sells. The CIK comes from the synced ticker reference data, or EDGAR's
+// ticker map when it has none.
+func IngestInsiderFilings(ctx context.Context, db *gorm.DB, ticker string, since time.Time) (*InsiderIngest, error) {
+	sec := service.NewSECService()
+	if !sec.Configured() {
+		return nil, errors.New("SEC_USER_AGENT must be set to read insider filings")
+	}
+
+	var reference models.Ticker
+	if err := db.WithContext(ctx).Select("cik").Where("ticker = ?", ticker).Limit(1).Find(&reference).Error; err != nil {
+		return nil, err
+	}
+	cik := reference.CIK
+	if cik == "" {
+		var err error
+		if cik, err = sec.TickerCIK(ctx, ticker); err != nil {
+			return nil, err
+		}
+	}
+
+	ingest := &InsiderIngest{Ticker: ticker, CIK: cik, Since: marketDay(since).Format("2006-01-02")}
+	filings, err := sec.RecentForm4Filings(ctx, cik, ingest.Since)
+	if err != nil {
+		return nil, err
+	}
+	if len(filings) == 0 {
+		return ingest, nil
+	}
+
+	accessions := make([]string, 0, len(filings))
+	for _, filing := range filings {
+		accessions = append(accessions, filing.Accession)
+	}
+	var read []string
+	if err := db.WithContext(ctx).Model(&models.InsiderFiling{}).Where("accession IN ?", accessions).Pluck("accession", &read).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(read))
+	for _, accession := range read {
+		seen[accession] = true
+	}
+
+	for _, filing := range filings {
+		if seen[filing.Accession] {
+			ingest.Skipped++
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		reported, err := sec.Form4Transactions(ctx, cik, filing)
+		if err != nil {
+			return nil, err
+		}
+
+		var transactions []models.InsiderTransaction
+		for seq, t := range reported {
+			side := ""
+			switch {
+			case t.Code == "P" && t.Acquired:
+				side = models.InsiderBuy
+			case t.Code == "S" && !t.Acquired:
+				side = models.InsiderSell
+			default:
+				continue // grants, exercises, gifts and tax withholding say little about conviction
+			}
+			transactions = append(transactions, models.InsiderTransaction{
+				Ticker:           ticker,
+				Accession:        filing.Accession,
+				Seq:              seq,
+				FiledAt:          filing.FiledAt,
+				TransactionDate:  t.Date,
+				OwnerCIK:         t.OwnerCIK,
+				OwnerName:        t.OwnerName,
+				Relationship:     t.Relationship,
+				Code:             t.Code,
+				Side:             side,
+				Shares:           t.Shares,
+				Price:            t.Price,
+				Value:            t.Shares * t.Price,
+				SharesOwnedAfter: t.SharesOwnedAfter,
+			})
+		}
+
+		record := &models.InsiderFiling{Accession: filing.Accession, Ticker: ticker, FiledAt: filing.FiledAt, Transactions: len(transactions)}
+		if err := models.StoreInsiderFiling(db.WithContext(ctx), record, transactions); err != nil {
+			return nil, fmt.Errorf("failed to store Form 4 %s: %w", filing.Accession, err)
+		}
+		ingest.Filings++
+		ingest.Transactions += len(transactions)
+	}
+	return ingest, nil
+}
+
+// InsiderCluster is a period in which at least the cluster minimum of
+// different insiders bought on the open market, each buy counting from the
+// day it was filed for the cluster window after it was made
+type InsiderCluster struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Buyers and Owners are the different insiders whose buys counted, Value what they paid
+	Buyers int      `json:"buyers"`
+	Owners []string `json:"owners"`
+	Value  float64  `json:"value"`
+}
+
+// insiderBuyers returns the insiders and their buys counting on day: filed
+// by then, and made within the last days calendar days
+func insiderBuyers(buys []models.InsiderTransaction, day time.Time, days int) (map[string]bool, float64) {
+	today := day.Format("2006-01-02")
+	earliest := day.AddDate(0, 0, -days).Format("2006-01-02")
+	owners := map[string]bool{}
+	value := 0.0
+	for _, buy := range buys {
+		if buy.FiledAt > today || buy.TransactionDate <= earliest || buy.TransactionDate > today {
+			continue
+		}
+		owner := buy.OwnerCIK
+		if owner == "" {
+			owner = buy.OwnerName
+		}
+		owners[owner] = true
+		value += buy.Value
+	}
+	return owners, value
+}
+
+// loadInsiderBuys returns a ticker's stored open market buys that can count
+// on days from from to to
+func loadInsiderBuys(ctx context.Context, db *gorm.DB, ticker string, from, to time.Time, days int) ([]models.InsiderTransaction, error) {
+	var buys []models.InsiderTransaction
+	err := db.WithContext(ctx).
+		Where("ticker = ? AND side = ? AND transaction_date > ? AND filed_at <= ?", ticker, models.InsiderBuy,
+			from.AddDate(0, 0, -days).Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("transaction_date").
+		Find(&buys).Error
+	return buys, err
+}
+
+// InsiderClusters returns the periods between from and to (market days, inclusive) in
+// which clustered insider buying of the ticker was known
+func InsiderClusters(ctx context.Context, db *gorm.DB, ticker string, from, to time.Time) ([]InsiderCluster, error) {
+	days, minBuyers := insiderClusterDays(), insiderClusterBuyers()
+	from, to = marketDay(from), marketDay(to)
+	buys, err := loadInsiderBuys(ctx, db, ticker, from, to, days)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []InsiderCluster{}
+	var current *InsiderCluster
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if buyers, _ := insiderBuyers(buys, day, days); len(buyers) < minBuyers {
+			current = nil
+			continue
+		}
+		if current == nil {
+			clusters = append(clusters, InsiderCluster{Start: day.Format("2006-01-02")})
+			current = &clusters[len(clusters)-1]
+		}
+		current.End = day.Format("2006-01-02")
+	}
+
+	// Name each cluster's insiders and total their buys within it
+	for i := range clusters {
+		cluster := &clusters[i]
+		start, _ := time.ParseInLocation("2006-01-02", cluster.Start, marketTimezone)
+		earliest := start.AddDate(0, 0, -days).Format("2006-01-02")
+		counted := map[string]bool{}
+		for _, buy := range buys {
+			if buy.TransactionDate <= earliest || buy.TransactionDate > cluster.End || buy.FiledAt > cluster.End {
+				continue
+			}
+			owner := buy.OwnerCIK
+			if owner == "" {
+				owner = buy.OwnerName
+			}
+			if !counted[owner] {
+				counted[owner] = true
+				cluster.Owners = append(cluster.Owners, buy.OwnerName)
+			}
+			cluster.Value += buy.Value
+		}
+		sort.Strings(cluster.Owners)
+		cluster.Buyers = len(cluster.Owners)
+	}
+	return clusters, nil
+}
+
+// applyInsiderClusters sets InsiderBuyers and InsiderBuyValue on the bars of
+// days with clustered insider buying, from the stored filings; EDGAR is not
+// called. Services without a database have none.
+func (s *DeepSearchService) applyInsiderClusters(ctx context.Context, bars []EnhancedBar) {
+	if len(bars) == 0 || s.db == nil {
+		return
+	}
+	days, minBuyers := insiderClusterDays(), insiderClusterBuyers()
+	buys, err := loadInsiderBuys(ctx, s.db, s.ticker, marketDay(bars[0].Timestamp), marketDay(bars[len(bars)-1].Timestamp), days)
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to load insider buys of %s: %v\n", s.ticker, err)
+		return
+	}
+	if len(buys) == 0 {
+		return
+	}
+	setInsiderClusters(bars, buys, days, minBuyers)
+}
+
+// setInsiderClusters marks the bars of each day on which at least minBuyers
+// insiders' buys counted
+func setInsiderClusters(bars []EnhancedBar, buys []models.InsiderTransaction, days, minBuyers int) {
+	type cluster struct {
+		buyers int
+		value  float64
+	}
+	byDay := map[time.Time]cluster{}
+	for i := range bars {
+		day := marketDay(bars[i].Timestamp)
+		c, ok := byDay[day]
+		if !ok {
+			owners, value := insiderBuyers(buys, day, days)
+			if len(owners) >= minBuyers {
+				c = cluster{buyers: len(owners), value: value}
+			}
+			byDay[day] = c
+		}
+		bars[i].InsiderBuyers, bars[i].InsiderBuyValue = c.buyers, c.value
+	}
+}
@@ -0,0 +1,207 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ivTargetDays is the time to expiry at-the-money IV is read at; the
+	// expiry nearest it between ivMinDays and ivMaxDays out is used
+	ivTargetDays = 30
+	ivMinDays    = 14
+	ivMaxDays    = 60
+	// ivContracts caps the contracts read for one IV reading
+	ivContracts = 2500
+	// ivRankDays is the trailing window IV rank is measured over, in calendar days
+	ivRankDays = 365
+	// ivRankMinReadings is how many daily readings a rank needs
+	ivRankMinReadings = 20
+	// ivRankMaxAgeDays is how old the latest reading may be to rank a day
+	ivRankMaxAgeDays = 7
+	// defaultStraddleMaxIVRank is the IV rank above which STRADDLE signals are dropped
+	defaultStraddleMaxIVRank = 30
+)
+
+// straddleMaxIVRank returns the IV rank STRADDLE signals need to be at or
+// below, STRADDLE_MAX_IV_RANK overriding the default; 100 keeps them all
+func straddleMaxIVRank() float64 {
+	if val := os.Getenv("STRADDLE_MAX_IV_RANK"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n >= 0 && n <= 100 {
+			return n
+		}
+	}
+	return defaultStraddleMaxIVRank
+}
+
+// ReadATMImpliedVolatility reads a ticker's at-the-money implied volatility
+// at at: the mean IV of the call and put at the strike nearest the
+// underlying price, on the expiry nearest 30 days out
+func ReadATMImpliedVolatility(ctx context.Context, ticker string, at time.Time) (*models.ImpliedVolatility, error) {
+	day := marketDay(at)
+	filter := service.OptionsChainFilter{
+		ExpirationFrom: day.AddDate(0, 0, ivMinDays).Format("2006-01-02"),
+		ExpirationTo:   day.AddDate(0, 0, ivMaxDays).Format("2006-01-02"),
+	}
+	chain, err := FetchOptionsChain(ctx, ticker, filter, ivContracts)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain.Expirations) == 0 || chain.UnderlyingPrice == 0 {
+		return nil, fmt.Errorf("no options on %s expire %d to %d days out", ticker, ivMinDays, ivMaxDays)
+	}
+
+	expiration, nearest := "", math.Inf(1)
+	for _, e := range chain.Expirations {
+		date, err := time.ParseInLocation("2006-01-02", e, marketTimezone)
+		if err != nil {
+			continue
+		}
+		if d := math.Abs(float64(daysBetween(day, date) - ivTargetDays)); d < nearest {
+			expiration, nearest = e, d
+		}
+	}
+
+	strike, distance := 0.0, math.Inf(1)
+	for _, contract := range chain.Contracts {
+		if contract.Expiration == expiration && contract.ImpliedVolatility > 0 {
+			if d := math.Abs(contract.Strike - chain.UnderlyingPrice); d < distance {
+				strike, distance = contract.Strike, d
+			}
+		}
+	}
+
+	sum, count := 0.0, 0
+	for _, contract := range chain.Contracts {
+		if contract.Expiration == expiration && contract.Strike == strike && contract.ImpliedVolatility > 0 {
+			sum += contract.ImpliedVolatility
+			count++
+		}
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no implied volatility for %s options expiring %s", ticker, expiration)
+	}
+
+	return &models.ImpliedVolatility{
+		Ticker:          ticker,
+		Day:             day.Format("2006-01-02"),
+		IV:              sum / float64(count),
+		Expiration:      expiration,
+		Strike:          strike,
+		UnderlyingPrice: chain.UnderlyingPrice,
+	}, nil
+}
+
+// RefreshImpliedVolatility reads and stores a ticker's at-the-money IV for
+// today, returning it with its rank
+func RefreshImpliedVolatility(ctx context.Context, db *gorm.DB, ticker string) (*models.ImpliedVolatility, *models.IVRank, error) {
+	iv, err := ReadATMImpliedVolatility(ctx, ticker, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := models.UpsertImpliedVolatility(db.WithContext(ctx), iv); err != nil {
+		return nil, nil, fmt.Errorf("failed to store implied volatility: %w", err)
+	}
+	rank, err := IVRankOn(ctx, db, ticker, iv.Day)
+	if err != nil {
+		return nil, nil, err
+	}
+	return iv, rank, nil
+}
+
+// IVRankOn ranks a ticker's latest stored IV reading on or before day (YYYY-MM-DD)
+// within the year of readings up to it. It returns nil when the reading is
+// over a week old or the year holds fewer than 20 readings.
+func IVRankOn(ctx context.Context, db *gorm.DB, ticker, day string) (*models.IVRank, error) {
+	end, err := time.ParseInLocation("2006-01-02", day, marketTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest models.ImpliedVolatility
+	err = db.WithContext(ctx).
+		Where("ticker = ? AND day <= ? AND day > ?", ticker, day, end.AddDate(0, 0, -ivRankMaxAgeDays).Format("2006-01-02")).
+		Order("day DESC").
+		First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	latestDay, _ := time.ParseInLocation("2006-01-02", latest.Day, marketTimezone)
+	var history []float64
+	err = db.WithContext(ctx).Model(&models.ImpliedVolatility{}).
+		Where("ticker = ? AND day < ? AND day > ?", ticker, latest.Day, latestDay.AddDate(0, 0, -ivRankDays).Format("2006-01-02")).
+		Pluck("iv", &history).Error
+	if err != nil {
+		return nil, err
+	}
+	return ivRank(latest, history), nil
+}
+
+// ivRank ranks a reading within the earlier readings of its year, nil when
+// there are too few of them
+func ivRank(latest models.ImpliedVolatility, history []float64) *models.IVRank {
+	if len(history)+1 < ivRankMinReadings {
+		return nil
+	}
+
+	rank := &models.IVRank{Day: latest.Day, IV: latest.IV, High: latest.IV, Low: latest.IV, Days: len(history) + 1}
+	below := 0
+	for _, iv := range history {
+		rank.High = math.Max(rank.High, iv)
+		rank.Low = math.Min(rank.Low, iv)
+		if iv < latest.IV {
+			below++
+		}
+	}
+	if rank.High > rank.Low {
+		rank.Rank = (latest.IV - rank.Low) / (rank.High - rank.Low) * 100
+	}
+	rank.Percentile = float64(below) / float64(len(history)) * 100
+	return rank
+}
+
+// applyIVRank records the ticker's IV rank on the last bar's day from the
+// stored readings; Polygon is not called. Services without a database have none.
+func (s *DeepSearchService) applyIVRank(ctx context.Context, bars []EnhancedBar) {
+	s.ivRank = nil
+	if len(bars) == 0 || s.db == nil {
+		return
+	}
+	day := marketDay(bars[len(bars)-1].Timestamp).Format("2006-01-02")
+	rank, err := IVRankOn(ctx, s.db, s.ticker, day)
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to load IV rank of %s, keeping straddle signals: %v\n", s.ticker, err)
+		return
+	}
+	s.ivRank = rank
+}
+
+// qualifyStraddles drops STRADDLE signals when the analysis's IV rank is
+// above maxRank, as options are then too expensive to buy both sides.
+// Without an IV rank every signal is kept.
+func qualifyStraddles(signals []Signal, rank *models.IVRank, maxRank float64) []Signal {
+	if rank == nil || rank.Rank <= maxRank {
+		return signals
+	}
+	qualified := make([]Signal, 0, len(signals))
+	for _, signal := range signals {
+		if ParseSignal(signal.Text).Direction != "STRADDLE" {
+			qualified = append(qualified, signal)
+		}
+	}
+	return qualified
+}
@@ -0,0 +1,109 @@
+package deepsearch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+)
+
+const (
+	// maxPainContracts caps the contracts read for max pain; the nearest
+	// expiries of even the busiest underlyings fit well within it
+	maxPainContracts = 5000
+	// contractMultiplier is the shares one equity option contract covers
+	contractMultiplier = 100
+)
+
+// ComputeMaxPain reads the options chain of a ticker and returns the max pain
+// and open interest by strike of its nearest expiries, at most expiries of
+// them, nearest first. Expiries without open interest are skipped.
+func ComputeMaxPain(ctx context.Context, ticker string, expiries int) ([]models.MaxPainSnapshot, error) {
+	today := time.Now().In(marketTimezone).Format("2006-01-02")
+	filter := service.OptionsChainFilter{ExpirationFrom: today}
+	chain, err := FetchOptionsChain(ctx, ticker, filter, maxPainContracts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Contracts come ordered by expiration; when the chain was cut short, its
+	// last expiry may be missing strikes and is left out
+	byExpiration := map[string][]OptionContract{}
+	for _, contract := range chain.Contracts {
+		byExpiration[contract.Expiration] = append(byExpiration[contract.Expiration], contract)
+	}
+	expirations := chain.Expirations
+	if chain.Truncated && len(expirations) > 1 {
+		expirations = expirations[:len(expirations)-1]
+	}
+
+	var snapshots []models.MaxPainSnapshot
+	for _, expiration := range expirations {
+		if len(snapshots) == expiries {
+			break
+		}
+		snapshot, ok := maxPainSnapshot(byExpiration[expiration])
+		if !ok {
+			continue
+		}
+		snapshot.Ticker = ticker
+		snapshot.Expiration = expiration
+		snapshot.Day = today
+		snapshot.UnderlyingPrice = chain.UnderlyingPrice
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// maxPainSnapshot sums one expiry's open interest by strike and finds the
+// strike whose settlement pays option holders the least. Ties go to the
+// lower strike. It reports false when the expiry has no open interest.
+func maxPainSnapshot(contracts []OptionContract) (models.MaxPainSnapshot, bool) {
+	var snapshot models.MaxPainSnapshot
+	index := map[float64]int{}
+	for _, contract := range contracts {
+		i, ok := index[contract.Strike]
+		if !ok {
+			i = len(snapshot.Strikes)
+			index[contract.Strike] = i
+			snapshot.Strikes = append(snapshot.Strikes, models.StrikeOpenInterest{Strike: contract.Strike})
+		}
+		switch contract.Type {
+		case "call":
+			snapshot.Strikes[i].CallOpenInterest += contract.OpenInterest
+			snapshot.CallOpenInterest += contract.OpenInterest
+		case "put":
+			snapshot.Strikes[i].PutOpenInterest += contract.OpenInterest
+			snapshot.PutOpenInterest += contract.OpenInterest
+		}
+	}
+	if snapshot.CallOpenInterest+snapshot.PutOpenInterest == 0 {
+		return snapshot, false
+	}
+	sort.Slice(snapshot.Strikes, func(i, j int) bool { return snapshot.Strikes[i].Strike < snapshot.Strikes[j].Strike })
+
+	best := 0
+	for i := range snapshot.Strikes {
+		settle := snapshot.Strikes[i].Strike
+		payout := 0.0
+		for _, s := range snapshot.Strikes {
+			if settle > s.Strike {
+				payout += s.CallOpenInterest * (settle - s.Strike)
+			} else {
+				payout += s.PutOpenInterest * (s.Strike - settle)
+			}
+		}
+		snapshot.Strikes[i].Payout = payout * contractMultiplier
+		if snapshot.Strikes[i].Payout < snapshot.Strikes[best].Payout {
+			best = i
+		}
+	}
+	snapshot.MaxPain = snapshot.Strikes[best].Strike
+
+	if snapshot.CallOpenInterest > 0 {
+		snapshot.PutCallOIRatio = snapshot.PutOpenInterest / snapshot.CallOpenInterest
+	}
+	return snapshot, true
+}
@@ -0,0 +1,143 @@
+package deepsearch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"institutionanalyser/service"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+)
+
+// OptionContract is one contract of an options chain
+type OptionContract struct {
+	Ticker            string  `json:"ticker"`
+	Type              string  `json:"type"` // "call" or "put"
+	Expiration        string  `json:"expiration"`
+	Strike            float64 `json:"strike"`
+	OpenInterest      float64 `json:"open_interest"`
+	Volume            float64 `json:"volume"`
+	ImpliedVolatility float64 `json:"implied_volatility"`
+	Delta             float64 `json:"delta"`
+	Gamma             float64 `json:"gamma"`
+	Theta             float64 `json:"theta"`
+	Vega              float64 `json:"vega"`
+	Bid               float64 `json:"bid"`
+	Ask               float64 `json:"ask"`
+	Last              float64 `json:"last"`
+	BreakEven         float64 `json:"break_even"`
+}
+
+// OptionsTotals sums open interest and volume by side
+type OptionsTotals struct {
+	CallOpenInterest float64 `json:"call_open_interest"`
+	PutOpenInterest  float64 `json:"put_open_interest"`
+	CallVolume       float64 `json:"call_volume"`
+	PutVolume        float64 `json:"put_volume"`
+	// PutCallOIRatio and PutCallVolumeRatio are puts over calls, 0 without calls
+	PutCallOIRatio     float64 `json:"put_call_oi_ratio"`
+	PutCallVolumeRatio float64 `json:"put_call_volume_ratio"`
+}
+
+// OptionsChain is a snapshot of a ticker's option contracts
+type OptionsChain struct {
+	Ticker          string           `json:"ticker"`
+	AsOf            time.Time        `json:"as_of"`
+	UnderlyingPrice float64          `json:"underlying_price"`
+	Expirations     []string         `json:"expirations"`
+	Strikes         []float64        `json:"strikes"`
+	Totals          OptionsTotals    `json:"totals"`
+	Contracts       []OptionContract `json:"contracts"`
+	Count           int              `json:"count"`
+	// Truncated is set when more contracts matched than were returned
+	Truncated bool `json:"truncated"`
+}
+
+// FetchOptionsChain loads the options chain snapshot of a ticker from
+// Polygon, at most max contracts ordered by expiration, strike and type
+func FetchOptionsChain(ctx context.Context, ticker string, filter service.OptionsChainFilter, max int) (*OptionsChain, error) {
+	svc := service.NewStockTechnicalService(ticker)
+	snapshots, truncated, err := svc.GetOptionsChain(ctx, filter, max)
+	if err != nil {
+		return nil, err
+	}
+	return buildOptionsChain(ticker, snapshots, truncated), nil
+}
+
+func buildOptionsChain(ticker string, snapshots []polygonmodels.OptionContractSnapshot, truncated bool) *OptionsChain {
+	chain := &OptionsChain{
+		Ticker:      ticker,
+		AsOf:        time.Now().UTC(),
+		Expirations: []string{},
+		Strikes:     []float64{},
+		Contracts:   make([]OptionContract, 0, len(snapshots)),
+		Truncated:   truncated,
+	}
+
+	expirations := map[string]bool{}
+	strikes := map[float64]bool{}
+	for _, s := range snapshots {
+		contract := OptionContract{
+			Ticker:            s.Details.Ticker,
+			Type:              s.Details.ContractType,
+			Expiration:        time.Time(s.Details.ExpirationDate).Format("2006-01-02"),
+			Strike:            s.Details.StrikePrice,
+			OpenInterest:      s.OpenInterest,
+			Volume:            s.Day.Volume,
+			ImpliedVolatility: s.ImpliedVolatility,
+			Delta:             s.Greeks.Delta,
+			Gamma:             s.Greeks.Gamma,
+			Theta:             s.Greeks.Theta,
+			Vega:              s.Greeks.Vega,
+			Bid:               s.LastQuote.Bid,
+			Ask:               s.LastQuote.Ask,
+			Last:              s.LastTrade.Price,
+			BreakEven:         s.BreakEvenPrice,
+		}
+		chain.Contracts = append(chain.Contracts, contract)
+
+		if chain.UnderlyingPrice == 0 {
+			chain.UnderlyingPrice = s.UnderlyingAsset.Price
+		}
+		if !expirations[contract.Expiration] {
+			expirations[contract.Expiration] = true
+			chain.Expirations = append(chain.Expirations, contract.Expiration)
+		}
+		if !strikes[contract.Strike] {
+			strikes[contract.Strike] = true
+			chain.Strikes = append(chain.Strikes, contract.Strike)
+		}
+
+		switch contract.Type {
+		case "call":
+			chain.Totals.CallOpenInterest += contract.OpenInterest
+			chain.Totals.CallVolume += contract.Volume
+		case "put":
+			chain.Totals.PutOpenInterest += contract.OpenInterest
+			chain.Totals.PutVolume += contract.Volume
+		}
+	}
+
+	sort.SliceStable(chain.Contracts, func(i, j int) bool {
+		a, b := chain.Contracts[i], chain.Contracts[j]
+		if a.Expiration != b.Expiration {
+			return a.Expiration < b.Expiration
+		}
+		if a.Strike != b.Strike {
+			return a.Strike < b.Strike
+		}
+		return a.Type < b.Type
+	})
+	sort.Strings(chain.Expirations)
+	sort.Float64s(chain.Strikes)
+
+	if chain.Totals.CallOpenInterest > 0 {
+		chain.Totals.PutCallOIRatio = chain.Totals.PutOpenInterest / chain.Totals.CallOpenInterest
+	}
+	if chain.Totals.CallVolume > 0 {
+		chain.Totals.PutCallVolumeRatio = chain.Totals.PutVolume / chain.Totals.CallVolume
+	}
+	chain.Count = len(chain.Contracts)
+	return chain
+}
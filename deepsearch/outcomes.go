@@ -0,0 +1,184 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	models "institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// SignalOutcome is the result of one directional signal measured at a horizon
+type SignalOutcome struct {
+	// Index is the signal's position in the analysis's Signals
+	Index              int               `json:"-"`
+	Signal             string            `json:"signal"`
+	Direction          string            `json:"direction"`
+	EntryTime          time.Time         `json:"entry_time"`
+	EntryPrice         float64           `json:"entry_price"`
+	AdjustedEntryPrice float64           `json:"adjusted_entry_price"`
+	ExitTime           *time.Time        `json:"exit_time,omitempty"`
+	ExitPrice          float64           `json:"exit_price,omitempty"`
+	Return             float64           `json:"return"`
+	Win                bool              `json:"win"`
+	Pending            bool              `json:"pending"`
+	Adjustments        []CorporateAction `json:"adjustments,omitempty"`
+}
+
+// OutcomeSummary aggregates signal outcomes for one analysis
+type OutcomeSummary struct {
+	Horizon  string          `json:"horizon"`
+	Scored   int             `json:"scored"`
+	Wins     int             `json:"wins"`
+	Pending  int             `json:"pending"`
+	WinRate  float64         `json:"win_rate"`
+	Outcomes []SignalOutcome `json:"outcomes"`
+}
+
+// ParseHorizon parses durations like "5m", "30m", "2h" and "1d"
+func ParseHorizon(horizon string) (time.Duration, error) {
+	if strings.HasSuffix(horizon, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(horizon, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid horizon %q", horizon)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(horizon)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid horizon %q", horizon)
+	}
+	return d, nil
+}
+
+// signalSide returns +1 for bullish, -1 for bearish and 0 for non-directional signals
+func signalSide(direction string) int {
+	switch direction {
+	case "CALL", "UP":
+		return 1
+	case "PUT", "DOWN":
+		return -1
+	default:
+		return 0
+	}
+}
+
+// LabelOutcomes scores each directional signal of a stored analysis by the
+// price move over horizon. Entry and exit prices are both closes of the same
+// unadjusted bars: the signal's own text is priced from bars adjusted as of
+// when the analysis ran, which differ after a later split. The entry price is
+// then restated for any split or large dividend between the signal and the
+// horizon so corporate actions are not mistaken for price moves. Prices are
+// normalized to the analysis's currency like its signals'.
+func LabelOutcomes(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal, horizon time.Duration) ([]SignalOutcome, error) {
+	if len(analysis.SignalTimestamps) != len(analysis.Signals) {
+		return nil, errors.New("analysis has no signal timestamps; re-run it to label outcomes")
+	}
+
+	var outcomes []SignalOutcome
+	var first, last time.Time
+	for i, text := range analysis.Signals {
+		parsed := ParseSignal(text)
+		if signalSide(parsed.Direction) == 0 {
+			continue
+		}
+		entry := time.UnixMilli(analysis.SignalTimestamps[i]).UTC()
+		if first.IsZero() || entry.Before(first) {
+			first = entry
+		}
+		if entry.After(last) {
+			last = entry
+		}
+		outcomes = append(outcomes, SignalOutcome{
+			Index:     i,
+			Signal:    text,
+			Direction: parsed.Direction,
+			EntryTime: entry,
+		})
+	}
+	if len(outcomes) == 0 {
+		return outcomes, nil
+	}
+
+	// Fetch enough bars to cover the horizon past the last signal, plus a
+	// buffer for weekends and holidays
+	to := last.Add(horizon).AddDate(0, 0, 4)
+	if now := time.Now().UTC(); to.After(now) {
+		to = now
+	}
+	timeSpan, multiplier := analysis.PolyTimeSpan, analysis.PolyMultiplier
+	if timeSpan == "" || multiplier == 0 {
+		timeSpan, multiplier = "minute", 5
+	}
+
+	svc := service.NewStockTechnicalService(analysis.Ticker)
+	it, err := svc.GetPolygonAggregateAdjusted(ctx, timeSpan, first.Format("2006-01-02"), to.Format("2006-01-02"), multiplier, false)
+	if err != nil {
+		return nil, err
+	}
+	scale := models.TickerCurrency(db, analysis.Ticker).Scale
+	var bars []EnhancedBar
+	for it.Next() {
+		agg := it.Item()
+		bars = append(bars, EnhancedBar{Timestamp: time.Time(agg.Timestamp).UTC(), Close: agg.Close * scale})
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch aggregates: %w", err)
+	}
+
+	actions, err := LookupCorporateActions(ctx, analysis.Ticker, first, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range outcomes {
+		o := &outcomes[i]
+		// The signal's bar, or the last one before it if that bar is missing
+		if idx := sort.Search(len(bars), func(j int) bool { return bars[j].Timestamp.After(o.EntryTime) }); idx > 0 {
+			o.EntryPrice = bars[idx-1].Close
+		}
+		target := o.EntryTime.Add(horizon)
+		idx := sort.Search(len(bars), func(j int) bool { return !bars[j].Timestamp.Before(target) })
+		if idx == len(bars) || o.EntryPrice == 0 {
+			o.Pending = true
+			continue
+		}
+
+		exit := bars[idx]
+		o.ExitTime = &exit.Timestamp
+		o.ExitPrice = exit.Close
+		o.AdjustedEntryPrice, o.Adjustments = AdjustPriceForCorporateActions(o.EntryPrice, o.EntryTime, exit.Timestamp, actions)
+		if o.AdjustedEntryPrice > 0 {
+			o.Return = float64(signalSide(o.Direction)) * (o.ExitPrice - o.AdjustedEntryPrice) / o.AdjustedEntryPrice
+		}
+		o.Win = o.Return > 0
+	}
+
+	return outcomes, nil
+}
+
+// SummariseOutcomes computes the win rate across scored (non-pending) outcomes
+func SummariseOutcomes(horizon string, outcomes []SignalOutcome) OutcomeSummary {
+	summary := OutcomeSummary{Horizon: horizon, Outcomes: outcomes}
+	for _, o := range outcomes {
+		if o.Pending {
+			summary.Pending++
+			continue
+		}
+		summary.Scored++
+		if o.Win {
+			summary.Wins++
+		}
+	}
+	if summary.Scored > 0 {
+		summary.WinRate = float64(summary.Wins) / float64(summary.Scored)
+	}
+	return summary
+}
@@ -0,0 +1,107 @@
+package deepsearch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// evaluateSignals scores each directional signal by the close of the bar
+// after it: CALL and UP signals hit when it closed higher, PUT and DOWN when
+// it closed lower. Results are grouped by signal kind and direction. Signals
+// on the last bar have no next bar yet and are left out.
+func evaluateSignals(bars []EnhancedBar, signals []Signal) []models.SignalPerformance {
+	var performance []models.SignalPerformance
+	index := map[[2]string]int{}
+	for _, signal := range signals {
+		parsed := ParseSignal(signal.Text)
+		side := signalSide(parsed.Direction)
+		if side == 0 {
+			continue
+		}
+		i := sort.Search(len(bars), func(j int) bool { return !bars[j].Timestamp.Before(signal.Timestamp) })
+		if i+1 >= len(bars) || !bars[i].Timestamp.Equal(signal.Timestamp) || bars[i].Close == 0 {
+			continue
+		}
+
+		key := [2]string{signalKind(parsed.Description), parsed.Direction}
+		n, ok := index[key]
+		if !ok {
+			n = len(performance)
+			index[key] = n
+			performance = append(performance, models.SignalPerformance{Kind: key[0], Direction: key[1]})
+		}
+		ret := float64(side) * (bars[i+1].Close - bars[i].Close) / bars[i].Close
+		performance[n].Signals++
+		performance[n].ReturnSum += ret
+		if ret > 0 {
+			performance[n].Wins++
+		}
+	}
+	return performance
+}
+
+// storeSignalPerformance replaces an analysis's signal performance rows and
+// marks it scored
+func storeSignalPerformance(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal, performance []models.SignalPerformance) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("analysis_id = ?", analysis.ID).Delete(&models.SignalPerformance{}).Error; err != nil {
+			return err
+		}
+		for i := range performance {
+			performance[i].AnalysisID = analysis.ID
+			performance[i].Ticker = analysis.Ticker
+		}
+		if len(performance) > 0 {
+			if err := tx.Create(&performance).Error; err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		analysis.PerformanceScoredAt = &now
+		return tx.Model(analysis).UpdateColumn("performance_scored_at", now).Error
+	})
+}
+
+// ScoreStoredAnalysis records the signal performance of an analysis stored
+// before its run scored it. Bars come from the bar store, or from Polygon
+// when the store does not cover the window. Analyses without signal
+// timestamps are marked scored with nothing to record.
+func ScoreStoredAnalysis(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal) error {
+	if len(analysis.SignalTimestamps) != len(analysis.Signals) {
+		return storeSignalPerformance(ctx, db, analysis, nil)
+	}
+
+	s := NewDeepSearchService(analysis.PolyStartDuration, analysis.PolyEndDuration, analysis.PolyTimeSpan,
+		analysis.PolyMultiplier, analysis.Ticker, analysis.UserId, db)
+	aggs, err := s.storedAggs(ctx, analysis.StartDate)
+	if err != nil {
+		return err
+	}
+	if len(aggs) == 0 || time.Time(aggs[len(aggs)-1].Timestamp).Before(analysis.EndDate) {
+		if aggs, err = s.polygonAggs(ctx, analysis.StartDate); err != nil {
+			return err
+		}
+	}
+	scaleAggs(aggs, s.quoteCurrency().Scale)
+
+	// Score the window the analysis covered, as its run would have
+	var bars []EnhancedBar
+	for _, agg := range aggs {
+		timestamp := time.UnixMilli(time.Time(agg.Timestamp).UnixMilli())
+		if timestamp.Before(analysis.StartDate) || timestamp.After(analysis.EndDate) {
+			continue
+		}
+		bars = append(bars, EnhancedBar{Timestamp: timestamp, Close: agg.Close})
+	}
+
+	signals := make([]Signal, 0, len(analysis.Signals))
+	for i, text := range analysis.Signals {
+		signals = append(signals, Signal{Timestamp: time.UnixMilli(analysis.SignalTimestamps[i]), Text: text})
+	}
+	return storeSignalPerformance(ctx, db, analysis, evaluateSignals(bars, signals))
+}
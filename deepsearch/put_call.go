@@ -0,0 +1,129 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// putCallContracts caps the contracts read per ticker; a cut-short chain
+	// marks its reading partial
+	putCallContracts = 10000
+	// putCallLookbackDays is how many previous daily ratios a reading is scored against
+	putCallLookbackDays = 20
+	// putCallMinHistory is how many previous daily ratios scoring needs
+	putCallMinHistory = 10
+	// defaultPutCallExtremeZScore is the z-score an extreme reading passes
+	defaultPutCallExtremeZScore = 2.0
+)
+
+// putCallExtremeZScore returns the z-score marking an extreme reading,
+// PUT_CALL_EXTREME_ZSCORE overriding the default
+func putCallExtremeZScore() float64 {
+	if val := os.Getenv("PUT_CALL_EXTREME_ZSCORE"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultPutCallExtremeZScore
+}
+
+// ReadPutCallRatio reads the day's option volume and the open interest by
+// side across a ticker's unexpired contracts at at
+func ReadPutCallRatio(ctx context.Context, ticker string, at time.Time) (models.PutCallRatio, error) {
+	day := marketDay(at)
+	filter := service.OptionsChainFilter{ExpirationFrom: day.Format("2006-01-02")}
+	chain, err := FetchOptionsChain(ctx, ticker, filter, putCallContracts)
+	if err != nil {
+		return models.PutCallRatio{}, err
+	}
+
+	ratio := models.PutCallRatio{
+		Ticker:           ticker,
+		Day:              day.Format("2006-01-02"),
+		CallVolume:       chain.Totals.CallVolume,
+		PutVolume:        chain.Totals.PutVolume,
+		CallOpenInterest: chain.Totals.CallOpenInterest,
+		PutOpenInterest:  chain.Totals.PutOpenInterest,
+		VolumeRatio:      chain.Totals.PutCallVolumeRatio,
+		OIRatio:          chain.Totals.PutCallOIRatio,
+		Partial:          chain.Truncated,
+	}
+	return ratio, nil
+}
+
+// CombinePutCallRatios sums readings of the same day into one reading of ticker
+func CombinePutCallRatios(ticker string, ratios []models.PutCallRatio) models.PutCallRatio {
+	combined := models.PutCallRatio{Ticker: ticker}
+	for _, r := range ratios {
+		combined.Day = r.Day
+		combined.CallVolume += r.CallVolume
+		combined.PutVolume += r.PutVolume
+		combined.CallOpenInterest += r.CallOpenInterest
+		combined.PutOpenInterest += r.PutOpenInterest
+		combined.Partial = combined.Partial || r.Partial
+	}
+	if combined.CallVolume > 0 {
+		combined.VolumeRatio = combined.PutVolume / combined.CallVolume
+	}
+	if combined.CallOpenInterest > 0 {
+		combined.OIRatio = combined.PutOpenInterest / combined.CallOpenInterest
+	}
+	return combined
+}
+
+// ScorePutCallRatio scores a reading's volume ratio against the ticker's
+// previous daily ratios and flags it when it is extreme. High put/call
+// readings mark crowded hedging and read as contrarian bullish; low ones mark
+// crowded call buying and read as contrarian bearish. Readings without call
+// volume or enough history are left unscored.
+func ScorePutCallRatio(ctx context.Context, db *gorm.DB, ratio *models.PutCallRatio) error {
+	if ratio.CallVolume == 0 {
+		return nil
+	}
+
+	var prior []float64
+	err := db.WithContext(ctx).Model(&models.PutCallRatio{}).
+		Where("ticker = ? AND sample_interval = ? AND day < ? AND call_volume > 0", ratio.Ticker, models.PutCallDaily, ratio.Day).
+		Order("day DESC").
+		Limit(putCallLookbackDays).
+		Pluck("volume_ratio", &prior).Error
+	if err != nil || len(prior) < putCallMinHistory {
+		return err
+	}
+
+	mean := 0.0
+	for _, v := range prior {
+		mean += v
+	}
+	mean /= float64(len(prior))
+	stdDev := 0.0
+	for _, v := range prior {
+		stdDev += math.Pow(v-mean, 2)
+	}
+	stdDev = math.Sqrt(stdDev / float64(len(prior)))
+	if stdDev == 0 {
+		return nil
+	}
+
+	ratio.ZScore = (ratio.VolumeRatio - mean) / stdDev
+	threshold := putCallExtremeZScore()
+	switch {
+	case ratio.ZScore >= threshold:
+		ratio.Extreme = models.PutCallExtremeHigh
+		ratio.Signal = fmt.Sprintf("Put/Call Extreme High (%.2f, z %.1f) - Heavy Put Buying, Contrarian Bullish", ratio.VolumeRatio, ratio.ZScore)
+	case ratio.ZScore <= -threshold:
+		ratio.Extreme = models.PutCallExtremeLow
+		ratio.Signal = fmt.Sprintf("Put/Call Extreme Low (%.2f, z %.1f) - Heavy Call Buying, Contrarian Bearish", ratio.VolumeRatio, ratio.ZScore)
+	}
+	return nil
+}
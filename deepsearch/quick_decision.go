@@ -0,0 +1,217 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+)
+
+// Weights of the quick decision inputs. The stored analysis weight also decays
+// with its age, halving every storedAnalysisHalfLife.
+const (
+	intradayWeight         = 0.5
+	storedAnalysisWeight   = 0.3
+	snapshotWeight         = 0.2
+	storedAnalysisHalfLife = 24 * time.Hour
+	// quickDecisionThreshold is the weighted score needed for BUY or SELL
+	quickDecisionThreshold = 0.15
+)
+
+// QuickDecision is an on-the-spot decision from the live snapshot, today's
+// partial intraday bars and the latest stored analysis. Nothing is stored.
+type QuickDecision struct {
+	Ticker     string    `json:"ticker"`
+	AsOf       time.Time `json:"as_of"`
+	Decision   string    `json:"decision"`
+	Confidence float64   `json:"confidence"`
+	// Score is the weighted vote in [-1, 1]; positive is bullish
+	Score    float64              `json:"score"`
+	Snapshot *QuickSnapshot       `json:"snapshot,omitempty"`
+	Intraday *QuickIntraday       `json:"intraday,omitempty"`
+	Stored   *QuickStoredAnalysis `json:"stored_analysis,omitempty"`
+	Warnings []string             `json:"warnings,omitempty"`
+	health.Degradation
+}
+
+// QuickSnapshot is the live snapshot input: last price against the day's VWAP
+type QuickSnapshot struct {
+	Price     float64 `json:"price"`
+	DayVWAP   float64 `json:"day_vwap"`
+	ChangePct float64 `json:"change_pct"`
+	Volume    float64 `json:"volume"`
+	Bias      string  `json:"bias"` // "BUY" above VWAP, "SELL" below
+}
+
+// QuickIntraday is the signal vote over today's bars so far
+type QuickIntraday struct {
+	SessionDate string                      `json:"session_date"`
+	Bars        int                         `json:"bars"`
+	Decision    string                      `json:"decision"`
+	Confidence  float64                     `json:"confidence"`
+	Signals     []StructuredSignal          `json:"signals"`
+	Explanation *models.DecisionExplanation `json:"explanation"`
+	// TrailingStop is the session's SuperTrend stop, for the side in TrailingStopSide
+	TrailingStop     float64 `json:"trailing_stop"`
+	TrailingStopSide string  `json:"trailing_stop_side,omitempty"`
+}
+
+// QuickStoredAnalysis is the latest full analysis and how much it still counts
+type QuickStoredAnalysis struct {
+	ID         uint      `json:"id"`
+	Decision   string    `json:"decision"`
+	Confidence float64   `json:"confidence"`
+	CreatedAt  time.Time `json:"created_at"`
+	Weight     float64   `json:"weight"`
+}
+
+// decisionSign maps a decision onto a vote direction
+func decisionSign(decision string) float64 {
+	switch decision {
+	case "BUY":
+		return 1
+	case "SELL":
+		return -1
+	}
+	return 0
+}
+
+// QuickDecide combines the three inputs into a decision. latest may be nil.
+// Inputs that cannot be fetched are reported as warnings and left out of the
+// weighting; it fails only if none are available. While Polygon is degraded
+// the live inputs are skipped and the decision rests on the stored analysis.
+func QuickDecide(ctx context.Context, ticker string, latest *models.TechnicalSignal) (*QuickDecision, error) {
+	now := time.Now()
+	result := &QuickDecision{Ticker: ticker, AsOf: now, Decision: "HOLD"}
+	svc := service.NewStockTechnicalService(ticker)
+
+	var score, weight float64
+
+	if health.Degraded(health.Polygon) {
+		result.Add(health.Polygon, "live snapshot and intraday bars skipped")
+	} else {
+		snapshot, err := svc.GetTickerSnapshot(ctx)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("snapshot unavailable: %v", err))
+		} else if quick := quickSnapshot(snapshot); quick != nil {
+			result.Snapshot = quick
+			score += snapshotWeight * decisionSign(quick.Bias)
+			weight += snapshotWeight
+		}
+
+		intraday, err := quickIntraday(ctx, svc, now)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("intraday bars unavailable: %v", err))
+		} else {
+			result.Intraday = intraday
+			score += intradayWeight * intraday.Confidence * decisionSign(intraday.Decision)
+			weight += intradayWeight
+		}
+
+		if result.Snapshot == nil && result.Intraday == nil && health.Degraded(health.Polygon) {
+			result.Add(health.Polygon, "live snapshot and intraday bars unavailable")
+		}
+	}
+
+	if latest != nil {
+		age := now.Sub(latest.CreatedAt)
+		decay := math.Pow(0.5, age.Hours()/storedAnalysisHalfLife.Hours())
+		w := storedAnalysisWeight * decay
+		result.Stored = &QuickStoredAnalysis{
+			ID:         latest.ID,
+			Decision:   latest.FinalDecision,
+			Confidence: latest.Confidence,
+			CreatedAt:  latest.CreatedAt,
+			Weight:     w,
+		}
+		score += w * latest.Confidence * decisionSign(latest.FinalDecision)
+		weight += w
+	}
+
+	if weight == 0 {
+		return nil, errors.New("no snapshot, intraday bars or stored analysis available")
+	}
+
+	result.Score = score / weight
+	result.Confidence = math.Abs(result.Score)
+	switch {
+	case result.Score >= quickDecisionThreshold:
+		result.Decision = "BUY"
+	case result.Score <= -quickDecisionThreshold:
+		result.Decision = "SELL"
+	}
+
+	return result, nil
+}
+
+func quickSnapshot(snapshot *polygonmodels.TickerSnapshot) *QuickSnapshot {
+	price := snapshot.LastTrade.Price
+	if price == 0 {
+		price = snapshot.Day.Close
+	}
+	if price == 0 || snapshot.Day.VolumeWeightedAverage == 0 {
+		return nil
+	}
+
+	bias := "BUY"
+	if price < snapshot.Day.VolumeWeightedAverage {
+		bias = "SELL"
+	}
+	return &QuickSnapshot{
+		Price:     price,
+		DayVWAP:   snapshot.Day.VolumeWeightedAverage,
+		ChangePct: snapshot.TodaysChangePerc,
+		Volume:    snapshot.Day.Volume,
+		Bias:      bias,
+	}
+}
+
+// quickIntraday votes over signals from the current (or most recent) session's
+// minute bars, with the previous session as indicator warm-up
+func quickIntraday(ctx context.Context, svc *service.StockTechnicalService, now time.Time) (*QuickIntraday, error) {
+	calendar := service.DefaultTradingCalendar()
+	today := now.In(marketTimezone)
+	session := calendar.OnOrBeforeTradingDay(ctx, time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, marketTimezone))
+	warmup := calendar.PreviousTradingDay(ctx, session)
+	sessionStart := time.Date(session.Year(), session.Month(), session.Day(), 0, 0, 0, 0, marketTimezone)
+	warmupStart := time.Date(warmup.Year(), warmup.Month(), warmup.Day(), 0, 0, 0, 0, marketTimezone)
+
+	bars := svc.GetPolygonAggregateBetween(ctx, "minute", 1, warmupStart, now)
+	allBars := enhanceData(bars, sessionStart, defaultATRPeriod)
+	if err := bars.Err(); err != nil {
+		return nil, err
+	}
+
+	from := len(allBars)
+	for i, bar := range allBars {
+		if !bar.Timestamp.Before(sessionStart) {
+			from = i
+			break
+		}
+	}
+	if from == len(allBars) {
+		return nil, errors.New("no bars in the current session yet")
+	}
+
+	signals := generateSignals(allBars, from)
+	texts := signalTexts(signals)
+	decision, confidence := getFinalDecisionFromSignals(texts)
+
+	intraday := &QuickIntraday{
+		SessionDate: session.Format("2006-01-02"),
+		Bars:        len(allBars) - from,
+		Decision:    decision,
+		Confidence:  confidence,
+		Signals:     ParseSignals(texts),
+		Explanation: explainDecision(signals, decision, confidence, nil),
+	}
+	intraday.TrailingStop, intraday.TrailingStopSide = trailingStop(allBars)
+	return intraday, nil
+}
@@ -0,0 +1,159 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+	"gorm.io/gorm"
+)
+
+// Replay sources
+const (
+	ReplaySourceStore   = "bar_store"
+	ReplaySourcePolygon = "polygon"
+)
+
+// ReplayFrame is one bar of a session replay and the analyser's state after it
+type ReplayFrame struct {
+	Index     int       `json:"index"`
+	Timestamp time.Time `json:"timestamp"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    float64   `json:"volume"`
+	VWAP      float64   `json:"vwap"`
+	ATR       float64   `json:"atr"`
+	// Signals are those emitted on this bar
+	Signals []StructuredSignal `json:"signals"`
+	// Decision and Confidence are the vote over every signal so far in the
+	// session or simulated window
+	Decision   string  `json:"decision"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Replay is a session's bars with the signals the analyser would have emitted
+// at each one. Indicators only use bars up to the frame, so each frame shows
+// exactly what was knowable at that time.
+type Replay struct {
+	Ticker     string `json:"ticker"`
+	Date       string `json:"date"`
+	TimeSpan   string `json:"timespan"`
+	Multiplier int    `json:"multiplier"`
+	Source     string `json:"source"`
+	// Currency is the ISO code the frames' prices are in
+	Currency string        `json:"currency"`
+	Frames   []ReplayFrame `json:"-"`
+}
+
+// BuildReplay prepares the replay of one trading session. Bars come from the
+// bar store when it holds the session at this aggregation, otherwise from
+// Polygon. The previous trading day is loaded as indicator warm-up.
+func BuildReplay(ctx context.Context, db *gorm.DB, ticker string, date time.Time, timeSpan string, multiplier int) (*Replay, error) {
+	if !service.DefaultTradingCalendar().IsTradingDay(ctx, date) {
+		return nil, fmt.Errorf("%s is not a trading day", date.Format("2006-01-02"))
+	}
+	sim, err := NewSimulation(ctx, db, ticker, date, date, timeSpan, multiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := &Replay{
+		Ticker:     ticker,
+		Date:       date.Format("2006-01-02"),
+		TimeSpan:   timeSpan,
+		Multiplier: multiplier,
+		Source:     sim.Source,
+		Currency:   sim.Currency,
+	}
+	for frame, ok := sim.Next(); ok; frame, ok = sim.Next() {
+		replay.Frames = append(replay.Frames, frame)
+	}
+	return replay, nil
+}
+
+// loadSessionBars enhances a trading session's bars, prices multiplied by
+// scale, with the previous trading day as warm-up, returning them with the
+// index of the session's first bar
+func loadSessionBars(ctx context.Context, db *gorm.DB, ticker string, date time.Time, timeSpan string, multiplier int, scale float64) ([]EnhancedBar, int, string, error) {
+	if !service.DefaultTradingCalendar().IsTradingDay(ctx, date) {
+		return nil, 0, "", fmt.Errorf("%s is not a trading day", date.Format("2006-01-02"))
+	}
+	return loadWindowBars(ctx, db, ticker, date, date, timeSpan, multiplier, scale)
+}
+
+// loadWindowBars enhances the bars of the sessions from one date to another,
+// prices multiplied by scale, with the trading day before the first as
+// warm-up, returning them with the index of the window's first bar
+func loadWindowBars(ctx context.Context, db *gorm.DB, ticker string, fromDate, toDate time.Time, timeSpan string, multiplier int, scale float64) ([]EnhancedBar, int, string, error) {
+	calendar := service.DefaultTradingCalendar()
+	windowStart := time.Date(fromDate.Year(), fromDate.Month(), fromDate.Day(), 0, 0, 0, 0, marketTimezone)
+	windowEnd := time.Date(toDate.Year(), toDate.Month(), toDate.Day(), 0, 0, 0, 0, marketTimezone).AddDate(0, 0, 1)
+	lastSession := calendar.OnOrBeforeTradingDay(ctx, windowEnd.AddDate(0, 0, -1))
+	if lastSession.Before(windowStart) {
+		return nil, 0, "", fmt.Errorf("no trading days from %s to %s", fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+	}
+	warmup := calendar.PreviousTradingDay(ctx, windowStart)
+	warmupStart := time.Date(warmup.Year(), warmup.Month(), warmup.Day(), 0, 0, 0, 0, marketTimezone)
+
+	aggs, source, err := loadReplayAggs(ctx, db, ticker, timeSpan, multiplier, warmupStart, lastSession, windowEnd)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	scaleAggs(aggs, scale)
+	allBars := enhanceAggs(aggs, windowStart, defaultATRPeriod)
+	from := len(allBars)
+	for i, bar := range allBars {
+		if !bar.Timestamp.Before(windowStart) {
+			from = i
+			break
+		}
+	}
+	if from == len(allBars) {
+		if fromDate.Equal(toDate) {
+			return nil, 0, "", fmt.Errorf("no %s/%d bars for %s on %s", timeSpan, multiplier, ticker, fromDate.Format("2006-01-02"))
+		}
+		return nil, 0, "", fmt.Errorf("no %s/%d bars for %s from %s to %s", timeSpan, multiplier, ticker, fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+	}
+	return allBars, from, source, nil
+}
+
+// loadReplayAggs reads warm-up and window bars from the bar store, falling
+// back to Polygon when the store has no bars for the window's last session
+func loadReplayAggs(ctx context.Context, db *gorm.DB, ticker, timeSpan string, multiplier int, from, lastSession, to time.Time) ([]polygonmodels.Agg, string, error) {
+	stored, err := models.FindBars(db.WithContext(ctx), ticker, timeSpan, multiplier, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(stored) > 0 && !stored[len(stored)-1].Timestamp.Before(lastSession) {
+		return barsToAggs(stored), ReplaySourceStore, nil
+	}
+
+	svc := service.NewStockTechnicalService(ticker)
+	bars := svc.GetPolygonAggregateBetween(ctx, timeSpan, multiplier, from, to.Add(-time.Millisecond))
+	var aggs []polygonmodels.Agg
+	for bars.Next() {
+		aggs = append(aggs, bars.Item())
+	}
+	if err := bars.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch aggregates: %w", err)
+	}
+	return aggs, ReplaySourcePolygon, nil
+}
+
+// BarDuration is the wall-clock length of one bar at this aggregation
+func BarDuration(timeSpan string, multiplier int) time.Duration {
+	unit := map[string]time.Duration{
+		"second": time.Second,
+		"minute": time.Minute,
+		"hour":   time.Hour,
+		"day":    24 * time.Hour,
+	}[timeSpan]
+	return unit * time.Duration(multiplier)
+}
@@ -0,0 +1,225 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// SignalParams are the signal thresholds a sandbox evaluation can override
+type SignalParams struct {
+	DojiBodyRatio       float64 `json:"doji_body_ratio"`
+	VolumeSpikeZScore   float64 `json:"volume_spike_zscore"`
+	ATRExpansionFactor  float64 `json:"atr_expansion_factor"`
+	InstitutionalZScore float64 `json:"institutional_zscore"`
+	// ADXTrendThreshold gates CALL/PUT signals; 0 disables gating
+	ADXTrendThreshold float64 `json:"adx_trend_threshold"`
+	// Consolidate merges signals of the same bar and vote into one
+	Consolidate bool `json:"consolidate"`
+	// StraddleMaxIVRank drops STRADDLE signals when the IV rank is above it; 100 keeps them
+	StraddleMaxIVRank float64 `json:"straddle_max_iv_rank"`
+}
+
+// DefaultSignalParams returns the thresholds analyses run with
+func DefaultSignalParams() SignalParams {
+	return SignalParams{
+		DojiBodyRatio:       dojiBodyRatio,
+		VolumeSpikeZScore:   volumeSpikeZScore,
+		ATRExpansionFactor:  atrExpansionFactor,
+		InstitutionalZScore: institutionalZScore,
+		ADXTrendThreshold:   adxTrendThreshold(),
+		Consolidate:         signalConsolidation(),
+		StraddleMaxIVRank:   straddleMaxIVRank(),
+	}
+}
+
+// SignalOverrides replaces the thresholds that are set
+type SignalOverrides struct {
+	DojiBodyRatio       *float64 `json:"doji_body_ratio"`
+	VolumeSpikeZScore   *float64 `json:"volume_spike_zscore"`
+	ATRExpansionFactor  *float64 `json:"atr_expansion_factor"`
+	InstitutionalZScore *float64 `json:"institutional_zscore"`
+	ADXTrendThreshold   *float64 `json:"adx_trend_threshold"`
+	Consolidate         *bool    `json:"consolidate"`
+	StraddleMaxIVRank   *float64 `json:"straddle_max_iv_rank"`
+}
+
+// apply returns params with the overrides set, rejecting negative thresholds
+func (o SignalOverrides) apply(params SignalParams) (SignalParams, error) {
+	for name, value := range map[string]*float64{
+		"doji_body_ratio":      o.DojiBodyRatio,
+		"volume_spike_zscore":  o.VolumeSpikeZScore,
+		"atr_expansion_factor": o.ATRExpansionFactor,
+		"institutional_zscore": o.InstitutionalZScore,
+		"adx_trend_threshold":  o.ADXTrendThreshold,
+		"straddle_max_iv_rank": o.StraddleMaxIVRank,
+	} {
+		if value != nil && *value < 0 {
+			return params, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	if o.DojiBodyRatio != nil {
+		params.DojiBodyRatio = *o.DojiBodyRatio
+	}
+	if o.VolumeSpikeZScore != nil {
+		params.VolumeSpikeZScore = *o.VolumeSpikeZScore
+	}
+	if o.ATRExpansionFactor != nil {
+		params.ATRExpansionFactor = *o.ATRExpansionFactor
+	}
+	if o.InstitutionalZScore != nil {
+		params.InstitutionalZScore = *o.InstitutionalZScore
+	}
+	if o.ADXTrendThreshold != nil {
+		params.ADXTrendThreshold = *o.ADXTrendThreshold
+	}
+	if o.StraddleMaxIVRank != nil {
+		if *o.StraddleMaxIVRank > 100 {
+			return params, errors.New("straddle_max_iv_rank must not exceed 100")
+		}
+		params.StraddleMaxIVRank = *o.StraddleMaxIVRank
+	}
+	if o.Consolidate != nil {
+		params.Consolidate = *o.Consolidate
+	}
+	return params, nil
+}
+
+// signalDirections are the directions vote weights can be set for
+var signalDirections = map[string]bool{"CALL": true, "PUT": true, "UP": true, "DOWN": true, "STRADDLE": true}
+
+// ErrBarsNotStored is returned when the bar store does not hold an analysis's window
+var ErrBarsNotStored = errors.New("the bars of this analysis are not stored; run it again to store them")
+
+// SandboxOutcome is a decision with the signals behind it
+type SandboxOutcome struct {
+	Decision    string                      `json:"decision"`
+	Confidence  float64                     `json:"confidence"`
+	SignalCount int                         `json:"signal_count"`
+	Signals     []StructuredSignal          `json:"signals,omitempty"`
+	Explanation *models.DecisionExplanation `json:"explanation,omitempty"`
+}
+
+// SandboxResult compares a stored analysis with its re-evaluation under other thresholds and weights
+type SandboxResult struct {
+	AnalysisID uint               `json:"analysis_id"`
+	Ticker     string             `json:"ticker"`
+	Bars       int                `json:"bars"`
+	Params     SignalParams       `json:"params"`
+	Weights    map[string]float64 `json:"weights"`
+	Stored     SandboxOutcome     `json:"stored"`
+	Result     SandboxOutcome     `json:"result"`
+	Changed    bool               `json:"changed"`
+}
+
+// EvaluateSandbox recomputes an analysis's signals and decision from the bar
+// store with the overrides applied. Weights scale each direction's votes
+// (default 1). Polygon is never called, and nothing is stored.
+func EvaluateSandbox(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal, overrides SignalOverrides, weights map[string]float64) (*SandboxResult, error) {
+	params, err := overrides.apply(DefaultSignalParams())
+	if err != nil {
+		return nil, err
+	}
+	effective := map[string]float64{}
+	for direction := range signalDirections {
+		effective[direction] = 1
+	}
+	for direction, weight := range weights {
+		if !signalDirections[direction] {
+			return nil, fmt.Errorf("invalid weight direction %q (CALL, PUT, UP, DOWN or STRADDLE)", direction)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight for %s must not be negative", direction)
+		}
+		effective[direction] = weight
+	}
+
+	s, allBars, from, err := reloadAnalysis(ctx, db, analysis)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.StrategyID != 0 {
+		strategy, err := LoadStrategy(ctx, db, analysis.StrategyID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.SetStrategy(strategy); err != nil {
+			return nil, err
+		}
+	}
+
+	signals := s.emitSignals(allBars, from, params)
+	decision, confidence := weightedDecision(signals, effective)
+
+	result := &SandboxResult{
+		AnalysisID: analysis.ID,
+		Ticker:     analysis.Ticker,
+		Bars:       len(allBars) - from,
+		Params:     params,
+		Weights:    effective,
+		Stored: SandboxOutcome{
+			Decision:    analysis.FinalDecision,
+			Confidence:  analysis.Confidence,
+			SignalCount: len(analysis.Signals),
+		},
+		Result: SandboxOutcome{
+			Decision:    decision,
+			Confidence:  confidence,
+			SignalCount: len(signals),
+			Signals:     ParseSignals(signalTexts(signals)),
+			Explanation: explainDecision(signals, decision, confidence, effective),
+		},
+	}
+	result.Changed = result.Result.Decision != result.Stored.Decision
+	return result, nil
+}
+
+// reloadAnalysis returns a stored analysis's bars as it saw them: the bars
+// stored with it, or for analyses stored without them, bars recomputed from
+// the bar store with the settings it ran with, up to the end of its window.
+// It returns the analysis's service, every bar and the index of the first bar
+// in the window.
+func reloadAnalysis(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal) (*DeepSearchService, []EnhancedBar, int, error) {
+	s := NewDeepSearchService(analysis.PolyStartDuration, analysis.PolyEndDuration, analysis.PolyTimeSpan,
+		analysis.PolyMultiplier, analysis.Ticker, analysis.UserId, db)
+	s.SetVWAPAnchor(analysis.VWAPAnchor)
+	s.SetATRPeriod(analysis.ATRPeriod)
+	s.ivRank = analysis.IVRank
+
+	allBars, from, err := storedAnalysisBars(ctx, db, analysis.ID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if from < len(allBars) {
+		return s, allBars, from, nil
+	}
+
+	allBars, from, err = s.loadEnhancedBars(ctx, s.storedAggs)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	s.applyConfirmedMoves(ctx, allBars)
+	s.applyInsiderClusters(ctx, allBars)
+	s.applyFTDSpikes(ctx, allBars)
+	// The store must reach into the analysed window, or there is nothing to evaluate
+	if from == len(allBars) || allBars[len(allBars)-1].Timestamp.Before(analysis.EndDate) {
+		return nil, nil, 0, ErrBarsNotStored
+	}
+	// Keep the window the analysis covered, not bars stored since
+	end := len(allBars)
+	for end > from && allBars[end-1].Timestamp.After(analysis.EndDate) {
+		end--
+	}
+	return s, allBars[:end], from, nil
+}
+
+// weightedDecision is getFinalDecisionFromSignals with each signal's score
+// also scaled by its direction's weight
+func weightedDecision(signals []Signal, weights map[string]float64) (string, float64) {
+	return scoredDecision(decisionScores(signalTexts(signals), weights))
+}
@@ -0,0 +1,193 @@
+package deepsearch
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// sectorVerdictThreshold is the sector score a BUY or SELL verdict needs
+const sectorVerdictThreshold = 0.2
+
+// SectorETF is a sector ETF with its largest holdings
+type SectorETF struct {
+	ETF          string   `json:"etf"`
+	Sector       string   `json:"sector"`
+	Constituents []string `json:"constituents"`
+}
+
+// sectorETFs are the SPDR sector ETFs with their top ten holdings by weight.
+// Requests can pass their own constituents when holdings change.
+var sectorETFs = map[string]SectorETF{
+	"XLB":  {ETF: "XLB", Sector: "Materials", Constituents: []string{"LIN", "SHW", "APD", "ECL", "FCX", "NEM", "CTVA", "DD", "NUE", "MLM"}},
+	"XLC":  {ETF: "XLC", Sector: "Communication Services", Constituents: []string{"META", "GOOGL", "GOOG", "NFLX", "TMUS", "DIS", "T", "VZ", "CMCSA", "EA"}},
+	"XLE":  {ETF: "XLE", Sector: "Energy", Constituents: []string{"XOM", "CVX", "COP", "EOG", "WMB", "SLB", "PSX", "MPC", "OKE", "KMI"}},
+	"XLF":  {ETF: "XLF", Sector: "Financials", Constituents: []string{"BRK.B", "JPM", "V", "MA", "BAC", "WFC", "GS", "MS", "SPGI", "AXP"}},
+	"XLI":  {ETF: "XLI", Sector: "Industrials", Constituents: []string{"GE", "CAT", "RTX", "UBER", "HON", "UNP", "ETN", "BA", "DE", "LMT"}},
+	"XLK":  {ETF: "XLK", Sector: "Technology", Constituents: []string{"AAPL", "MSFT", "NVDA", "AVGO", "ORCL", "CRM", "AMD", "ADBE", "CSCO", "ACN"}},
+	"XLP":  {ETF: "XLP", Sector: "Consumer Staples", Constituents: []string{"COST", "WMT", "PG", "KO", "PM", "PEP", "MDLZ", "MO", "CL", "TGT"}},
+	"XLRE": {ETF: "XLRE", Sector: "Real Estate", Constituents: []string{"PLD", "AMT", "EQIX", "WELL", "SPG", "PSA", "O", "DLR", "CCI", "CBRE"}},
+	"XLU":  {ETF: "XLU", Sector: "Utilities", Constituents: []string{"NEE", "SO", "DUK", "CEG", "SRE", "AEP", "VST", "D", "EXC", "PCG"}},
+	"XLV":  {ETF: "XLV", Sector: "Health Care", Constituents: []string{"LLY", "UNH", "JNJ", "ABBV", "MRK", "TMO", "ABT", "ISRG", "AMGN", "DHR"}},
+	"XLY":  {ETF: "XLY", Sector: "Consumer Discretionary", Constituents: []string{"AMZN", "TSLA", "HD", "MCD", "BKNG", "LOW", "TJX", "SBUX", "NKE", "ORLY"}},
+}
+
+// SectorETFs returns the built-in sector ETFs sorted by symbol
+func SectorETFs() []SectorETF {
+	etfs := make([]SectorETF, 0, len(sectorETFs))
+	for _, etf := range sectorETFs {
+		etfs = append(etfs, etf)
+	}
+	sort.Slice(etfs, func(i, j int) bool { return etfs[i].ETF < etfs[j].ETF })
+	return etfs
+}
+
+// LookupSectorETF returns a built-in sector ETF
+func LookupSectorETF(etf string) (SectorETF, bool) {
+	sector, ok := sectorETFs[etf]
+	return sector, ok
+}
+
+// SectorMember is the pipeline's outcome for the ETF or one constituent
+type SectorMember struct {
+	Ticker string `json:"ticker"`
+	WindowMetrics
+}
+
+// SectorBreadth counts constituent decisions
+type SectorBreadth struct {
+	Buy      int `json:"buy"`
+	Sell     int `json:"sell"`
+	Hold     int `json:"hold"`
+	Straddle int `json:"straddle"`
+	Failed   int `json:"failed"`
+	// Net is buys minus sells as a share of the constituents analysed
+	Net float64 `json:"net"`
+}
+
+// SectorAnalysis is a sector-level verdict from its ETF and constituents.
+// Nothing is stored.
+type SectorAnalysis struct {
+	ETF        string           `json:"etf"`
+	Sector     string           `json:"sector"`
+	Window     ComparisonWindow `json:"window"`
+	TimeSpan   string           `json:"timespan"`
+	Multiplier int              `json:"multiplier"`
+	// Verdict is BUY or SELL when Score reaches sectorVerdictThreshold either
+	// way, else HOLD; Confidence is the score's size, at most 1
+	Verdict    string  `json:"verdict"`
+	Confidence float64 `json:"confidence"`
+	// Score averages the ETF's conviction (direction x confidence) with the
+	// constituents' mean conviction, from -1 to 1
+	Score float64 `json:"score"`
+	// ETFConviction and ConstituentConviction are the two halves of Score
+	ETFConviction         float64       `json:"etf_conviction"`
+	ConstituentConviction float64       `json:"constituent_conviction"`
+	Breadth               SectorBreadth `json:"breadth"`
+	// InstitutionalBars totals the institutional flow bars of the ETF and its
+	// constituents
+	InstitutionalBars int            `json:"institutional_bars"`
+	ETFResult         SectorMember   `json:"etf_result"`
+	Constituents      []SectorMember `json:"constituents"`
+}
+
+// AnalyseSector runs the signal pipeline over a sector ETF and its
+// constituents for one window and combines them into a sector verdict. A
+// member that fails carries its error and is left out of the verdict.
+func AnalyseSector(ctx context.Context, sector SectorETF, timeSpan string, multiplier int, window ComparisonWindow) *SectorAnalysis {
+	tickers := append([]string{sector.ETF}, sector.Constituents...)
+	members := make([]SectorMember, len(tickers))
+
+	sem := make(chan struct{}, compareConcurrency)
+	var wg sync.WaitGroup
+	for i, ticker := range tickers {
+		wg.Add(1)
+		go func(i int, ticker string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			memberWindow := ComparisonWindow{Label: ticker, Start: window.Start, End: window.End}
+			metrics, err := analyseWindow(ctx, ticker, timeSpan, multiplier, memberWindow)
+			if err != nil {
+				metrics = &WindowMetrics{ComparisonWindow: memberWindow, Error: err.Error()}
+			}
+			members[i] = SectorMember{Ticker: ticker, WindowMetrics: *metrics}
+		}(i, ticker)
+	}
+	wg.Wait()
+
+	analysis := &SectorAnalysis{
+		ETF:          sector.ETF,
+		Sector:       sector.Sector,
+		Window:       window,
+		TimeSpan:     timeSpan,
+		Multiplier:   multiplier,
+		ETFResult:    members[0],
+		Constituents: members[1:],
+	}
+
+	if members[0].Error == "" {
+		analysis.ETFConviction = conviction(members[0].WindowMetrics)
+		analysis.InstitutionalBars += members[0].InstitutionalBars
+	}
+
+	analysed := 0
+	for _, member := range analysis.Constituents {
+		if member.Error != "" {
+			analysis.Breadth.Failed++
+			continue
+		}
+		analysed++
+		analysis.ConstituentConviction += conviction(member.WindowMetrics)
+		analysis.InstitutionalBars += member.InstitutionalBars
+		switch member.Decision {
+		case "BUY":
+			analysis.Breadth.Buy++
+		case "SELL":
+			analysis.Breadth.Sell++
+		case "STRADDLE":
+			analysis.Breadth.Straddle++
+		default:
+			analysis.Breadth.Hold++
+		}
+	}
+	if analysed > 0 {
+		analysis.ConstituentConviction /= float64(analysed)
+		analysis.Breadth.Net = float64(analysis.Breadth.Buy-analysis.Breadth.Sell) / float64(analysed)
+	}
+
+	// Without the ETF or without any constituent, the other half decides alone
+	analysis.Verdict = "HOLD"
+	switch {
+	case members[0].Error != "" && analysed == 0:
+		return analysis
+	case members[0].Error != "":
+		analysis.Score = analysis.ConstituentConviction
+	case analysed == 0:
+		analysis.Score = analysis.ETFConviction
+	default:
+		analysis.Score = (analysis.ETFConviction + analysis.ConstituentConviction) / 2
+	}
+
+	if analysis.Score >= sectorVerdictThreshold {
+		analysis.Verdict = "BUY"
+	} else if analysis.Score <= -sectorVerdictThreshold {
+		analysis.Verdict = "SELL"
+	}
+	analysis.Confidence = math.Min(math.Abs(analysis.Score), 1)
+	return analysis
+}
+
+// conviction is a decision's direction weighted by its confidence: positive
+// for BUY, negative for SELL and 0 otherwise
+func conviction(m WindowMetrics) float64 {
+	switch m.Decision {
+	case "BUY":
+		return m.Confidence
+	case "SELL":
+		return -m.Confidence
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package deepsearch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+)
+
+// Signal is a generated signal and the bar that produced it
+type Signal struct {
+	Timestamp time.Time
+	Text      string
+	// Crossings are the thresholds that made the signal fire, for explanations
+	Crossings []models.ThresholdCrossing
+}
+
+func newSignal(bar EnhancedBar, text string, crossings []models.ThresholdCrossing) Signal {
+	return Signal{Timestamp: bar.Timestamp, Text: text, Crossings: crossings}
+}
+
+// crossed records that value passed threshold in the given comparison
+func crossed(metric, comparison string, value, threshold float64) models.ThresholdCrossing {
+	return models.ThresholdCrossing{
+		Metric:     metric,
+		Comparison: comparison,
+		Value:      value,
+		Threshold:  threshold,
+		Margin:     value - threshold,
+	}
+}
+
+// signalTexts returns the stored string form of each signal
+func signalTexts(signals []Signal) []string {
+	texts := make([]string, 0, len(signals))
+	for _, s := range signals {
+		texts = append(texts, s.Text)
+	}
+	return texts
+}
+
+// signalTimestamps returns each signal's bar time in Unix milliseconds
+func signalTimestamps(signals []Signal) []int64 {
+	timestamps := make([]int64, 0, len(signals))
+	for _, s := range signals {
+		timestamps = append(timestamps, s.Timestamp.UnixMilli())
+	}
+	return timestamps
+}
+
+// StructuredSignal is the parsed form of a stored signal string
+type StructuredSignal struct {
+	Time        string  `json:"time"`
+	Direction   string  `json:"direction"` // CALL, PUT, UP, DOWN, STRADDLE
+	Description string  `json:"description"`
+	Close       float64 `json:"close,omitempty"`
+	// Regime is TRENDING or CHOPPY by the bar's ADX, empty for signals stored before ADX
+	Regime string  `json:"regime,omitempty"`
+	ADX    float64 `json:"adx,omitempty"`
+	// Reasons are the kinds merged into a consolidated signal, its own kind
+	// first; empty for signals that were not merged
+	Reasons []string `json:"reasons,omitempty"`
+	Raw     string   `json:"raw"`
+}
+
+var (
+	closingPriceRe = regexp.MustCompile(`Closing price \((-?[0-9.]+)\)`)
+	regimeRe       = regexp.MustCompile(`\s*\[(TRENDING|CHOPPY) ADX ([0-9.]+)\]`)
+	alsoRe         = regexp.MustCompile(`\s*\[Also: ([^\]]+)\]`)
+)
+
+// ParseSignal splits a signal string produced by generateSignals
+// ("15:04 CALL: Bullish Engulfing - ... Closing price (123.45) [Also: Doji Pattern] [TRENDING ADX 27.3]")
+// into its parts
+func ParseSignal(signal string) StructuredSignal {
+	parsed := StructuredSignal{Raw: signal}
+
+	timePart, rest, found := strings.Cut(signal, " ")
+	if !found {
+		parsed.Description = signal
+		return parsed
+	}
+	parsed.Time = timePart
+
+	direction, description, found := strings.Cut(rest, ":")
+	if !found {
+		parsed.Description = rest
+		return parsed
+	}
+	parsed.Direction = strings.TrimSpace(direction)
+	if m := regimeRe.FindStringSubmatch(description); len(m) == 3 {
+		parsed.Regime = m[1]
+		parsed.ADX, _ = strconv.ParseFloat(m[2], 64)
+		description = regimeRe.ReplaceAllString(description, "")
+	}
+	if m := alsoRe.FindStringSubmatch(description); len(m) == 2 {
+		description = alsoRe.ReplaceAllString(description, "")
+		parsed.Reasons = append([]string{signalKind(description)}, strings.Split(m[1], "; ")...)
+	}
+	parsed.Description = strings.TrimSpace(closingPriceRe.ReplaceAllString(description, ""))
+	parsed.Description = strings.TrimSuffix(strings.TrimSpace(parsed.Description), "-")
+	parsed.Description = strings.TrimSpace(parsed.Description)
+
+	if m := closingPriceRe.FindStringSubmatch(description); len(m) == 2 {
+		parsed.Close, _ = strconv.ParseFloat(m[1], 64)
+	}
+
+	return parsed
+}
+
+// ParseSignals parses every signal string in order
+func ParseSignals(signals []string) []StructuredSignal {
+	parsed := make([]StructuredSignal, 0, len(signals))
+	for _, s := range signals {
+		parsed = append(parsed, ParseSignal(s))
+	}
+	return parsed
+}
@@ -0,0 +1,120 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultOutcomeHorizons are the horizons each signal's return is tracked over
+var defaultOutcomeHorizons = []string{"5m", "30m", "1d"}
+
+// OutcomeHorizons returns the horizons signal returns are tracked over;
+// SIGNAL_OUTCOME_HORIZONS overrides the defaults with a comma-separated list
+// such as "5m,30m,1d". Invalid entries are skipped.
+func OutcomeHorizons() []string {
+	val := os.Getenv("SIGNAL_OUTCOME_HORIZONS")
+	if val == "" {
+		return defaultOutcomeHorizons
+	}
+	var horizons []string
+	for _, horizon := range strings.Split(val, ",") {
+		horizon = strings.TrimSpace(horizon)
+		if _, err := ParseHorizon(horizon); err != nil {
+			fmt.Printf("[deepsearch] SIGNAL_OUTCOME_HORIZONS: %v\n", err)
+			continue
+		}
+		horizons = append(horizons, horizon)
+	}
+	return horizons
+}
+
+// trackSignalReturns records a pending return per directional signal of a
+// stored analysis and horizon, for ScoreSignalReturns to price later
+func trackSignalReturns(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal) error {
+	if len(analysis.SignalTimestamps) != len(analysis.Signals) {
+		return nil
+	}
+
+	var rows []models.SignalReturn
+	for _, horizon := range OutcomeHorizons() {
+		d, _ := ParseHorizon(horizon)
+		for i, text := range analysis.Signals {
+			parsed := ParseSignal(text)
+			if signalSide(parsed.Direction) == 0 || parsed.Close == 0 {
+				continue
+			}
+			entry := time.UnixMilli(analysis.SignalTimestamps[i]).UTC()
+			rows = append(rows, models.SignalReturn{
+				AnalysisID:  analysis.ID,
+				SignalIndex: i,
+				Horizon:     horizon,
+				UserId:      analysis.UserId,
+				Ticker:      analysis.Ticker,
+				StrategyID:  analysis.StrategyID,
+				Kind:        signalKind(parsed.Description),
+				Direction:   parsed.Direction,
+				EntryTime:   entry,
+				EntryPrice:  parsed.Close,
+				DueAt:       entry.Add(d),
+				Status:      models.SignalReturnPending,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rows, 500).Error
+}
+
+// ScoreSignalReturns prices an analysis's pending returns at one horizon from
+// bars past it (see LabelOutcomes), and returns how many it scored. Returns
+// still unpriced a week after they were due are marked unavailable.
+func ScoreSignalReturns(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal, horizon string, rows []models.SignalReturn) (int, error) {
+	d, err := ParseHorizon(horizon)
+	if err != nil {
+		return 0, err
+	}
+	outcomes, err := LabelOutcomes(ctx, db, analysis, d)
+	if err != nil {
+		return 0, err
+	}
+	byIndex := make(map[int]*SignalOutcome, len(outcomes))
+	for i := range outcomes {
+		byIndex[outcomes[i].Index] = &outcomes[i]
+	}
+
+	scored := 0
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			row := &rows[i]
+			o, ok := byIndex[row.SignalIndex]
+			switch {
+			case ok && !o.Pending:
+				row.Status = models.SignalReturnScored
+				row.AdjustedEntryPrice = o.AdjustedEntryPrice
+				row.ExitTime = o.ExitTime
+				row.ExitPrice = o.ExitPrice
+				row.SignedReturn = o.Return
+				row.Win = o.Win
+				scored++
+			case time.Since(row.DueAt) > 7*24*time.Hour:
+				row.Status = models.SignalReturnUnavailable
+			default:
+				continue
+			}
+			if err := tx.Save(row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return scored, err
+}
@@ -0,0 +1,195 @@
+package deepsearch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AlgoVersion is the version of the signal and decision logic stored with
+// each analysis. Bump it when signals or decisions change so stored analyses
+// from before and after can be told apart.
+const AlgoVersion = 6
+
+const (
+	maxFilterTickers     = 500
+	maxFilterSignalTypes = 20
+	maxFilterTags        = 20
+)
+
+// SignalFilter selects stored analyses. Every field is optional and set
+// fields are ANDed; values within a list field are ORed, except Tags, which
+// must all be present.
+type SignalFilter struct {
+	Tickers []string `json:"tickers"`
+	// StartDuration is the window start the analysis was triggered with (YYYY-MM-DD)
+	StartDuration string `json:"start_duration"`
+	// From and To (YYYY-MM-DD, market time) keep analyses whose window overlaps the range
+	From string `json:"from"`
+	To   string `json:"to"`
+	// SignalTypes keeps analyses with at least one signal of these kinds,
+	// e.g. "Bollinger Breakout", including kinds merged into a consolidated
+	// signal; a kind also matches kinds it prefixes
+	SignalTypes   []string `json:"signal_types"`
+	MinConfidence *float64 `json:"min_confidence"`
+	// Decisions are final decisions: BUY, SELL, HOLD or STRADDLE
+	Decisions    []string `json:"decisions"`
+	AlgoVersions []int    `json:"algo_versions"`
+	Tags         []string `json:"tags"`
+
+	from, to time.Time
+}
+
+// Normalize trims and validates the filter in place. Apply expects a
+// normalized filter.
+func (f *SignalFilter) Normalize() error {
+	f.Tickers = normalizeList(f.Tickers, strings.ToUpper)
+	if len(f.Tickers) > maxFilterTickers {
+		return fmt.Errorf("tickers cannot exceed %d entries", maxFilterTickers)
+	}
+
+	f.StartDuration = strings.TrimSpace(f.StartDuration)
+	if f.StartDuration != "" {
+		if _, err := time.Parse("2006-01-02", f.StartDuration); err != nil {
+			return errors.New("invalid start_duration format, use YYYY-MM-DD")
+		}
+	}
+
+	var err error
+	if f.from, err = parseFilterDate("from", f.From); err != nil {
+		return err
+	}
+	if f.to, err = parseFilterDate("to", f.To); err != nil {
+		return err
+	}
+	if !f.from.IsZero() && !f.to.IsZero() && f.to.Before(f.from) {
+		return errors.New("to cannot be before from")
+	}
+
+	f.SignalTypes = normalizeList(f.SignalTypes, func(s string) string { return s })
+	if len(f.SignalTypes) > maxFilterSignalTypes {
+		return fmt.Errorf("signal_types cannot exceed %d entries", maxFilterSignalTypes)
+	}
+
+	if f.MinConfidence != nil && (*f.MinConfidence < 0 || *f.MinConfidence > 1) {
+		return errors.New("min_confidence must be between 0 and 1")
+	}
+
+	f.Decisions = normalizeList(f.Decisions, strings.ToUpper)
+	for _, decision := range f.Decisions {
+		switch decision {
+		case "BUY", "SELL", "HOLD", "STRADDLE":
+		default:
+			return fmt.Errorf("invalid decision %q (BUY, SELL, HOLD or STRADDLE)", decision)
+		}
+	}
+
+	for _, version := range f.AlgoVersions {
+		if version < 0 {
+			return errors.New("algo_versions cannot be negative")
+		}
+	}
+
+	f.Tags = normalizeList(f.Tags, strings.ToLower)
+	if len(f.Tags) > maxFilterTags {
+		return fmt.Errorf("tags cannot exceed %d entries", maxFilterTags)
+	}
+	return nil
+}
+
+// Apply adds the filter's conditions to a technical_signals query. Values are
+// always bound as parameters. Tickers use the (ticker, created_at) index and,
+// on Postgres, tags the GIN index.
+func (f *SignalFilter) Apply(query *gorm.DB) *gorm.DB {
+	switch len(f.Tickers) {
+	case 0:
+	case 1:
+		query = query.Where("ticker = ?", f.Tickers[0])
+	default:
+		query = query.Where("ticker IN ?", f.Tickers)
+	}
+	if f.StartDuration != "" {
+		query = query.Where("poly_start_duration = ?", f.StartDuration)
+	}
+	if !f.from.IsZero() {
+		query = query.Where("end_date >= ?", f.from)
+	}
+	if !f.to.IsZero() {
+		query = query.Where("start_date < ?", f.to.AddDate(0, 0, 1))
+	}
+	if len(f.SignalTypes) > 0 {
+		patterns := make(pq.StringArray, 0, len(f.SignalTypes))
+		for _, kind := range f.SignalTypes {
+			patterns = append(patterns, "%: "+escapeLike(kind)+"%", "%[Also: %"+escapeLike(kind)+"%")
+		}
+		query = query.Where(signalsLike(query, patterns))
+	}
+	if f.MinConfidence != nil {
+		query = query.Where("confidence >= ?", *f.MinConfidence)
+	}
+	if len(f.Decisions) > 0 {
+		query = query.Where("final_decision IN ?", f.Decisions)
+	}
+	if len(f.AlgoVersions) > 0 {
+		query = query.Where("algo_version IN ?", f.AlgoVersions)
+	}
+	if len(f.Tags) > 0 {
+		query = query.Where(models.ArrayContains(query, "tags", f.Tags))
+	}
+	return query
+}
+
+// signalsLike matches analyses with a signal matching one of the LIKE patterns
+func signalsLike(db *gorm.DB, patterns pq.StringArray) clause.Expr {
+	if !models.IsSQLite(db) {
+		return clause.Expr{SQL: "EXISTS (SELECT 1 FROM unnest(signals) AS signal WHERE signal LIKE ANY (?::text[]))", Vars: []interface{}{patterns}}
+	}
+	// SQLite's LIKE has no default escape character
+	conditions := make([]string, 0, len(patterns))
+	vars := make([]interface{}, 0, len(patterns))
+	for _, pattern := range patterns {
+		conditions = append(conditions, `value LIKE ? ESCAPE '\'`)
+		vars = append(vars, pattern)
+	}
+	return clause.Expr{SQL: "EXISTS (SELECT 1 FROM json_each(signals) WHERE " + strings.Join(conditions, " OR ") + ")", Vars: vars}
+}
+
+// normalizeList trims, transforms and de-duplicates values, dropping empty ones
+func normalizeList(values []string, transform func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		value = transform(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		normalized = append(normalized, value)
+	}
+	return normalized
+}
+
+// parseFilterDate parses a YYYY-MM-DD date as midnight market time
+func parseFilterDate(field, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, marketTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s format, use YYYY-MM-DD", field)
+	}
+	return date, nil
+}
+
+// escapeLike escapes LIKE wildcards so a signal kind matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,113 @@
+package deepsearch
+
+import (
+	"strings"
+)
+
+// SignalWeight is how much one kind of signal counts towards a decision.
+// Weight is its importance and Confidence (0-1) how reliably it has called
+// direction; a signal scores Weight x Confidence for its vote.
+type SignalWeight struct {
+	Weight     float64 `json:"weight"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Score is what one signal of this kind adds to its vote
+func (w SignalWeight) Score() float64 {
+	return w.Weight * w.Confidence
+}
+
+// defaultSignalWeight applies to signal kinds without an entry, such as
+// strategy rules
+var defaultSignalWeight = SignalWeight{Weight: 1, Confidence: 0.5}
+
+// signalWeights by signal kind. Flow confirmed by several independent
+// sources counts most; candle patterns on their own count least.
+var signalWeights = map[string]SignalWeight{
+	"Doji Pattern":                         {Weight: 0.5, Confidence: 0.4},
+	"Bearish Engulfing":                    {Weight: 1, Confidence: 0.6},
+	"Bullish Engulfing":                    {Weight: 1, Confidence: 0.6},
+	"Volume Spike + Price Drop":            {Weight: 1.5, Confidence: 0.7},
+	"Volume Spike + Institutional Flow":    {Weight: 1.5, Confidence: 0.7},
+	"Volatility Expansion":                 {Weight: 1, Confidence: 0.5},
+	"Bollinger Squeeze":                    {Weight: 0.75, Confidence: 0.5},
+	"Bollinger Breakout":                   {Weight: 1.25, Confidence: 0.6},
+	"Bollinger Breakdown":                  {Weight: 1.25, Confidence: 0.6},
+	"OBV Bullish Divergence":               {Weight: 1, Confidence: 0.55},
+	"OBV Bearish Divergence":               {Weight: 1, Confidence: 0.55},
+	"Anchored VWAP Reclaim":                {Weight: 1.25, Confidence: 0.6},
+	"Anchored VWAP Lost":                   {Weight: 1.25, Confidence: 0.6},
+	"Institutional Buying Detected":        {Weight: 1.5, Confidence: 0.65},
+	"Institutional Selling Detected":       {Weight: 1.5, Confidence: 0.65},
+	"52-Week High Breakout":                {Weight: 1.5, Confidence: 0.6},
+	"52-Week Low Breakdown":                {Weight: 1.5, Confidence: 0.6},
+	"Confirmed Institutional Move":         {Weight: 2, Confidence: 0.8},
+	"Insider Cluster + Institutional Flow": {Weight: 1.75, Confidence: 0.7},
+	"FTD Spike":                            {Weight: 0.75, Confidence: 0.45},
+}
+
+// SignalWeights returns a copy of the weight table by signal kind
+func SignalWeights() map[string]SignalWeight {
+	weights := make(map[string]SignalWeight, len(signalWeights))
+	for kind, w := range signalWeights {
+		weights[kind] = w
+	}
+	return weights
+}
+
+// DefaultSignalWeight is the weight of signal kinds not in the table
+func DefaultSignalWeight() SignalWeight {
+	return defaultSignalWeight
+}
+
+// signalKind names the kind of a parsed signal description: the part before
+// " - " without values, e.g. "Volume Spike + Price Drop"
+func signalKind(description string) string {
+	kind, _, _ := strings.Cut(description, " - ")
+	return strings.TrimSpace(signalValuesRe.ReplaceAllString(kind, ""))
+}
+
+// weightOf returns the weight of a signal kind
+func weightOf(kind string) SignalWeight {
+	if w, ok := signalWeights[kind]; ok {
+		return w
+	}
+	return defaultSignalWeight
+}
+
+// signalScore is a signal's score towards its vote: its kind's score, scaled
+// by its direction's weight when direction weights are given
+func signalScore(text string, directionWeights map[string]float64) float64 {
+	parsed := ParseSignal(text)
+	score := weightOf(signalKind(parsed.Description)).Score()
+	if w, ok := directionWeights[parsed.Direction]; ok {
+		score *= w
+	}
+	return score
+}
+
+// decisionScores totals signal scores by vote
+func decisionScores(texts []string, directionWeights map[string]float64) map[string]float64 {
+	scores := map[string]float64{"BUY": 0, "SELL": 0, "STRADDLE": 0, "HOLD": 0}
+	for _, text := range texts {
+		scores[signalVote(text)] += signalScore(text, directionWeights)
+	}
+	return scores
+}
+
+// scoredDecision picks the vote with the highest score, preferring HOLD on a
+// tie, and returns it with its share of the total score as confidence
+func scoredDecision(scores map[string]float64) (string, float64) {
+	total := scoreTotal(scores)
+
+	final, best := "HOLD", scores["HOLD"]
+	for _, vote := range []string{"BUY", "SELL", "STRADDLE"} {
+		if scores[vote] > best {
+			final, best = vote, scores[vote]
+		}
+	}
+	if total == 0 {
+		return final, 0
+	}
+	return final, best / total
+}
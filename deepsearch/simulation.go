@@ -0,0 +1,122 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// MaxSimulationDays bounds a simulation's window, whose bars are loaded at once
+const MaxSimulationDays = 31
+
+// Simulation feeds a window's bars to the signal generator one at a time, as
+// if they were arriving live. Each step hands the generator only the bars up
+// to and including the new one, so no signal can depend on a later bar, and
+// the running decision is the vote over every signal so far in the window.
+// Bars come from the bar store when it holds the window at this aggregation,
+// otherwise from Polygon; the trading day before the window warms up the
+// indicators.
+type Simulation struct {
+	Ticker     string `json:"ticker"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TimeSpan   string `json:"timespan"`
+	Multiplier int    `json:"multiplier"`
+	Source     string `json:"source"`
+	// Currency is the ISO code the frames' prices are in
+	Currency string `json:"currency"`
+	// Bars is how many bars the window has, warm-up excluded
+	Bars int `json:"bars"`
+
+	bars []EnhancedBar
+	// start is the index of the window's first bar
+	start int
+	// next is the index of the next bar to feed
+	next int
+	// dayStart is the index of the first bar of the next bar's market day
+	dayStart int
+	signals  []string
+}
+
+// ValidateSimulationWindow checks a simulation's dates: from no later than
+// to, at most MaxSimulationDays apart, and not in the future
+func ValidateSimulationWindow(from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf("to cannot be before from")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > MaxSimulationDays {
+		return fmt.Errorf("window is %d days, at most %d can be simulated", days, MaxSimulationDays)
+	}
+	if from.After(time.Now()) {
+		return fmt.Errorf("from cannot be in the future")
+	}
+	return nil
+}
+
+// NewSimulation loads the bars of the sessions from one date to another and
+// returns a simulation positioned before the first
+func NewSimulation(ctx context.Context, db *gorm.DB, ticker string, from, to time.Time, timeSpan string, multiplier int) (*Simulation, error) {
+	currency := models.TickerCurrency(db, ticker)
+	allBars, start, source, err := loadWindowBars(ctx, db, ticker, from, to, timeSpan, multiplier, currency.Scale)
+	if err != nil {
+		return nil, err
+	}
+	return &Simulation{
+		Ticker:     ticker,
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		TimeSpan:   timeSpan,
+		Multiplier: multiplier,
+		Source:     source,
+		Currency:   currency.Currency,
+		Bars:       len(allBars) - start,
+		bars:       allBars,
+		start:      start,
+		next:       start,
+		dayStart:   start,
+	}, nil
+}
+
+// Next feeds the next bar and returns the frame it produced, or false once
+// every bar has been fed
+func (s *Simulation) Next() (ReplayFrame, bool) {
+	if s.next >= len(s.bars) {
+		return ReplayFrame{}, false
+	}
+	i := s.next
+	s.next++
+	bar := s.bars[i]
+	if i > s.start && !marketDay(bar.Timestamp).Equal(marketDay(s.bars[i-1].Timestamp)) {
+		s.dayStart = i
+	}
+
+	// Rules that fire once a day look back to the day's first bar, so the
+	// generator sees the day so far and the signals of this bar are kept
+	var texts []string
+	for _, signal := range generateSignals(s.bars[:i+1], s.dayStart) {
+		if signal.Timestamp.Equal(bar.Timestamp) {
+			texts = append(texts, signal.Text)
+		}
+	}
+	s.signals = append(s.signals, texts...)
+	decision, confidence := getFinalDecisionFromSignals(s.signals)
+
+	return ReplayFrame{
+		Index:      i - s.start,
+		Timestamp:  bar.Timestamp,
+		Open:       bar.Open,
+		High:       bar.High,
+		Low:        bar.Low,
+		Close:      bar.Close,
+		Volume:     bar.Volume,
+		VWAP:       bar.CumulativeVWAP,
+		ATR:        bar.ATR,
+		Signals:    ParseSignals(texts),
+		Decision:   decision,
+		Confidence: confidence,
+	}, true
+}
@@ -0,0 +1,340 @@
+package deepsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// maxStrategyRules bounds the rules of one strategy
+const maxStrategyRules = 50
+
+// strategyField is a bar field strategy rules can reference
+type strategyField struct {
+	Description string
+	value       func(bar EnhancedBar) float64
+}
+
+func boolField(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// strategyFields are the bar fields strategy rules can reference by their
+// snake_case name; prefixed with prev_ they read the bar before
+var strategyFields = map[string]strategyField{
+	"open":                 {"bar open", func(b EnhancedBar) float64 { return b.Open }},
+	"high":                 {"bar high", func(b EnhancedBar) float64 { return b.High }},
+	"low":                  {"bar low", func(b EnhancedBar) float64 { return b.Low }},
+	"close":                {"bar close", func(b EnhancedBar) float64 { return b.Close }},
+	"volume":               {"bar volume", func(b EnhancedBar) float64 { return b.Volume }},
+	"transactions":         {"trades in the bar", func(b EnhancedBar) float64 { return b.Transactions }},
+	"vwap":                 {"VWAP of the bar", func(b EnhancedBar) float64 { return b.VWAP }},
+	"cumulative_vwap":      {"VWAP since the window start", func(b EnhancedBar) float64 { return b.CumulativeVWAP }},
+	"anchored_vwap":        {"anchored VWAP, 0 without an anchor", func(b EnhancedBar) float64 { return b.AnchoredVWAP }},
+	"volume_zscore":        {"volume z-score over 14 bars", func(b EnhancedBar) float64 { return b.VolumeZScore }},
+	"atr":                  {"Wilder ATR", func(b EnhancedBar) float64 { return b.ATR }},
+	"adx":                  {"ADX(14)", func(b EnhancedBar) float64 { return b.ADX }},
+	"plus_di":              {"+DI(14)", func(b EnhancedBar) float64 { return b.PlusDI }},
+	"minus_di":             {"-DI(14)", func(b EnhancedBar) float64 { return b.MinusDI }},
+	"bollinger_middle":     {"Bollinger middle band", func(b EnhancedBar) float64 { return b.BollingerMiddle }},
+	"bollinger_upper":      {"Bollinger upper band", func(b EnhancedBar) float64 { return b.BollingerUpper }},
+	"bollinger_lower":      {"Bollinger lower band", func(b EnhancedBar) float64 { return b.BollingerLower }},
+	"bollinger_width":      {"Bollinger band width relative to the middle band", func(b EnhancedBar) float64 { return b.BollingerWidth }},
+	"bollinger_squeeze":    {"1 during a Bollinger squeeze", func(b EnhancedBar) float64 { return boolField(b.BollingerSqueeze) }},
+	"obv":                  {"on-balance volume", func(b EnhancedBar) float64 { return b.OBV }},
+	"obv_divergence":       {"1 bullish, -1 bearish OBV divergence, else 0", func(b EnhancedBar) float64 { return float64(b.OBVDivergence) }},
+	"supertrend":           {"SuperTrend line", func(b EnhancedBar) float64 { return b.SuperTrend }},
+	"supertrend_direction": {"1 in a SuperTrend uptrend, -1 in a downtrend", func(b EnhancedBar) float64 { return float64(b.SuperTrendDirection) }},
+	"year_high":            {"52-week high before the bar's day, 0 if unavailable", func(b EnhancedBar) float64 { return b.YearHigh }},
+	"year_low":             {"52-week low before the bar's day, 0 if unavailable", func(b EnhancedBar) float64 { return b.YearLow }},
+	"insider_buyers":       {"insiders in the clustered insider buying known on the bar's day, 0 without a cluster", func(b EnhancedBar) float64 { return float64(b.InsiderBuyers) }},
+	"ftd_shares":           {"failed shares when the bar's day was a fails-to-deliver spike, else 0", func(b EnhancedBar) float64 { return float64(b.FTDShares) }},
+	"ftd_zscore":           {"z-score of the fails-to-deliver spike on the bar's day, else 0", func(b EnhancedBar) float64 { return b.FTDZScore }},
+	"confirmed_move":       {"1 (-1) when a confirmed institutional move up (down) fell in the bar, else 0", func(b EnhancedBar) float64 { return float64(b.ConfirmedMove) }},
+	"is_doji":              {"1 on a doji", func(b EnhancedBar) float64 { return boolField(b.IsDoji) }},
+	"bullish_engulfing":    {"1 on a bullish engulfing bar", func(b EnhancedBar) float64 { return boolField(b.BullishEngulfing) }},
+	"bearish_engulfing":    {"1 on a bearish engulfing bar", func(b EnhancedBar) float64 { return boolField(b.BearishEngulfing) }},
+	"institutional_flow":   {"1 when volume is in the window's top decile", func(b EnhancedBar) float64 { return boolField(b.InstitutionalFlow) }},
+}
+
+// strategyFieldNames maps a field name with case and underscores removed to
+// its snake_case form, so VolumeZScore and volume_zscore both resolve
+var strategyFieldNames = func() map[string]string {
+	names := make(map[string]string, len(strategyFields))
+	for name := range strategyFields {
+		names[strings.ReplaceAll(name, "_", "")] = name
+	}
+	return names
+}()
+
+// StrategyFields returns the field names strategy rules may use with their descriptions
+func StrategyFields() map[string]string {
+	fields := make(map[string]string, len(strategyFields))
+	for name, field := range strategyFields {
+		fields[name] = field.Description
+	}
+	return fields
+}
+
+// resolveStrategyField returns the snake_case input name of a field reference,
+// "prev_" prefixed for the previous bar
+func resolveStrategyField(ref string) (string, bool) {
+	key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(ref), "_", ""))
+	if name, ok := strategyFieldNames[key]; ok {
+		return name, true
+	}
+	if rest, ok := strings.CutPrefix(key, "prev"); ok {
+		if name, ok := strategyFieldNames[rest]; ok {
+			return "prev_" + name, true
+		}
+	}
+	return "", false
+}
+
+var (
+	strategyAndRe   = regexp.MustCompile(`(?i)\s+AND\s+|\s*&&\s*`)
+	strategyArrowRe = regexp.MustCompile(`\s*(->|→)\s*`)
+	// Two-character operators first, so ">=" is not read as ">"
+	strategyOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+)
+
+// strategyRule is a rule with its expression parsed into conditions
+type strategyRule struct {
+	models.StrategyRule
+	conditions []models.DecisionCondition
+	inputs     []string
+}
+
+// parseStrategyCondition parses one comparison: a field against a number
+// ("volume_zscore > 2"), against another field optionally scaled
+// ("atr > prev_atr * 1.5"), or a bare field that holds while it is non-zero
+// ("institutional_flow")
+func parseStrategyCondition(text string) (models.DecisionCondition, error) {
+	var cond models.DecisionCondition
+	left, right := text, ""
+	for _, op := range strategyOperators {
+		if l, r, ok := strings.Cut(text, op); ok {
+			left, right, cond.Operator = l, r, op
+			break
+		}
+	}
+
+	input, ok := resolveStrategyField(left)
+	if !ok {
+		return cond, fmt.Errorf("unknown field %q", strings.TrimSpace(left))
+	}
+	cond.Input = input
+	if cond.Operator == "" {
+		cond.Operator = "!="
+		return cond, nil
+	}
+
+	right = strings.TrimSpace(right)
+	if value, err := strconv.ParseFloat(right, 64); err == nil {
+		cond.Value = value
+		return cond, nil
+	}
+	ref, factor, scaled := strings.Cut(right, "*")
+	if cond.Ref, ok = resolveStrategyField(ref); !ok {
+		return cond, fmt.Errorf("%q is neither a number nor a field", right)
+	}
+	if scaled {
+		value, err := strconv.ParseFloat(strings.TrimSpace(factor), 64)
+		if err != nil || value == 0 {
+			return cond, fmt.Errorf("invalid factor %q", strings.TrimSpace(factor))
+		}
+		cond.Factor = value
+	}
+	return cond, nil
+}
+
+// compileStrategyRule parses a rule's expression into conditions that all have to hold
+func compileStrategyRule(rule models.StrategyRule) (strategyRule, error) {
+	compiled := strategyRule{StrategyRule: rule}
+	seen := map[string]bool{}
+	for _, part := range strategyAndRe.Split(rule.When, -1) {
+		cond, err := parseStrategyCondition(part)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.conditions = append(compiled.conditions, cond)
+		for _, input := range []string{cond.Input, cond.Ref} {
+			if input != "" && !seen[input] {
+				seen[input] = true
+				compiled.inputs = append(compiled.inputs, input)
+			}
+		}
+	}
+	return compiled, nil
+}
+
+// NormalizeStrategy validates a strategy, upper-casing rule signals and
+// splitting "when -> SIGNAL" shorthand into its parts
+func NormalizeStrategy(strategy *models.Strategy) error {
+	strategy.Name = strings.TrimSpace(strategy.Name)
+	if strategy.Name == "" {
+		return errors.New("strategy name is required")
+	}
+	if len(strategy.Rules) == 0 {
+		return errors.New("a strategy needs at least one rule")
+	}
+	if len(strategy.Rules) > maxStrategyRules {
+		return fmt.Errorf("a strategy can have at most %d rules", maxStrategyRules)
+	}
+
+	names := map[string]bool{}
+	for i := range strategy.Rules {
+		rule := &strategy.Rules[i]
+		if when, signal, ok := strings.Cut(strategyArrowRe.ReplaceAllString(rule.When, " -> "), " -> "); ok && rule.Signal == "" {
+			rule.When, rule.Signal = when, signal
+		}
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.When = strings.TrimSpace(rule.When)
+		rule.Signal = strings.ToUpper(strings.TrimSpace(rule.Signal))
+
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule_%d", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %d: duplicate name %q", i+1, rule.Name)
+		}
+		names[rule.Name] = true
+		if !signalDirections[rule.Signal] {
+			return fmt.Errorf("rule %q: invalid signal %q (CALL, PUT, UP, DOWN or STRADDLE)", rule.Name, rule.Signal)
+		}
+		if rule.When == "" {
+			return fmt.Errorf("rule %q: when is required", rule.Name)
+		}
+		if _, err := compileStrategyRule(*rule); err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// ErrStrategyNotFound is returned when an analysis's strategy has been deleted
+var ErrStrategyNotFound = errors.New("strategy not found")
+
+// LoadStrategy returns a stored strategy by ID
+func LoadStrategy(ctx context.Context, db *gorm.DB, id uint) (*models.Strategy, error) {
+	var strategy models.Strategy
+	err := db.WithContext(ctx).First(&strategy, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrStrategyNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &strategy, nil
+}
+
+// SetStrategy makes the next analysis emit the strategy's signals, replacing
+// the built-in ones unless the strategy includes them; nil restores the built-in signals
+func (s *DeepSearchService) SetStrategy(strategy *models.Strategy) error {
+	s.strategy, s.strategyRules = nil, nil
+	if strategy == nil {
+		return nil
+	}
+	rules := make([]strategyRule, 0, len(strategy.Rules))
+	for _, rule := range strategy.Rules {
+		compiled, err := compileStrategyRule(rule)
+		if err != nil {
+			return fmt.Errorf("strategy %s rule %q: %w", strategy.Name, rule.Name, err)
+		}
+		rules = append(rules, compiled)
+	}
+	s.strategy, s.strategyRules = strategy, rules
+	return nil
+}
+
+// strategyID is the ID of the analysis's strategy, 0 for the built-in signals
+func (s *DeepSearchService) strategyID() uint {
+	if s.strategy == nil {
+		return 0
+	}
+	return s.strategy.ID
+}
+
+// emitSignals emits the analysis's signals for bars[from:]: the built-in
+// ones with params, the strategy's, or both in bar order. With
+// params.Consolidate, built-in and strategy signals of one bar merge together.
+// STRADDLE signals are dropped when the IV rank is above params.StraddleMaxIVRank.
+func (s *DeepSearchService) emitSignals(bars []EnhancedBar, from int, params SignalParams) []Signal {
+	if s.strategy == nil {
+		return qualifyStraddles(generateSignalsWith(bars, from, params), s.ivRank, params.StraddleMaxIVRank)
+	}
+
+	var signals []Signal
+	if s.strategy.IncludeBuiltin {
+		signals = builtinSignals(bars, from, params)
+	}
+	signals = append(signals, strategySignals(s.strategy.Name, s.strategyRules, bars, from, params.ADXTrendThreshold)...)
+	sort.SliceStable(signals, func(i, j int) bool { return signals[i].Timestamp.Before(signals[j].Timestamp) })
+	if params.Consolidate {
+		signals = consolidateSignals(signals)
+	}
+	return qualifyStraddles(signals, s.ivRank, params.StraddleMaxIVRank)
+}
+
+// strategySignals evaluates each rule on bars[from:]. A rule fires on the bar
+// its conditions start to hold and not again until they have failed, so a
+// state such as "close > cumulative_vwap" signals once per crossing.
+func strategySignals(name string, rules []strategyRule, bars []EnhancedBar, from int, threshold float64) []Signal {
+	var signals []Signal
+	held := make([]bool, len(rules))
+	start := from - 1
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(bars); i++ {
+		bar := bars[i]
+		for r, rule := range rules {
+			inputs := strategyInputs(bars, i, rule.inputs)
+			matched := true
+			crossings := make([]models.ThresholdCrossing, 0, len(rule.conditions))
+			for _, cond := range rule.conditions {
+				ct := evaluateCondition(cond, inputs)
+				if !ct.Passed {
+					matched = false
+					break
+				}
+				crossings = append(crossings, crossed(cond.Input, cond.Operator, ct.Left, ct.Right))
+			}
+
+			// The bar before the window only primes the state
+			if matched && !held[r] && i >= from {
+				signals = appendSignal(signals, bar, fmt.Sprintf("%s %s: Strategy %s/%s (%s) - Closing price (%.2f)",
+					bar.Timestamp.Format("15:04"), rule.Signal, name, rule.Name, rule.When, bar.Close), threshold, crossings...)
+			}
+			held[r] = matched
+		}
+	}
+	return signals
+}
+
+// strategyInputs reads the named inputs from bars[i]; prev_ inputs are
+// missing on the first bar, so conditions on them do not hold there
+func strategyInputs(bars []EnhancedBar, i int, names []string) map[string]float64 {
+	inputs := make(map[string]float64, len(names))
+	for _, name := range names {
+		if field, ok := strings.CutPrefix(name, "prev_"); ok {
+			if i > 0 {
+				inputs[name] = strategyFields[field].value(bars[i-1])
+			}
+			continue
+		}
+		inputs[name] = strategyFields[name].value(bars[i])
+	}
+	return inputs
+}
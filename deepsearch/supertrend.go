@@ -0,0 +1,91 @@
+package deepsearch
+
+import (
+	"os"
+	"strconv"
+)
+
+// defaultSuperTrendMultiplier is how many ATRs the SuperTrend bands sit from the bar's midpoint
+const defaultSuperTrendMultiplier = 3.0
+
+// Trailing stop sides: a long stop sits below price in an uptrend, a short stop above it in a downtrend
+const (
+	StopSideLong  = "long"
+	StopSideShort = "short"
+)
+
+// superTrendMultiplier returns the band width in ATRs; SUPERTREND_MULTIPLIER overrides the default
+func superTrendMultiplier() float64 {
+	if val := os.Getenv("SUPERTREND_MULTIPLIER"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultSuperTrendMultiplier
+}
+
+// superTrendCalculator computes SuperTrend one bar at a time from each bar's ATR
+type superTrendCalculator struct {
+	multiplier float64
+
+	upper, lower float64
+	prevClose    float64
+	direction    int
+}
+
+// next adds a bar and returns the SuperTrend line and direction (+1 up, -1
+// down). The line is the lower band in an uptrend and the upper band in a
+// downtrend, so it trails price as a stop. Both are zero until ATR is available.
+func (st *superTrendCalculator) next(high, low, close, atr float64) (float64, int) {
+	if atr == 0 {
+		st.prevClose = close
+		return 0, 0
+	}
+
+	mid := (high + low) / 2
+	upper, lower := mid+st.multiplier*atr, mid-st.multiplier*atr
+	if st.direction == 0 {
+		// Start in the direction of the bar's close relative to its midpoint
+		st.upper, st.lower, st.direction = upper, lower, 1
+		if close < mid {
+			st.direction = -1
+		}
+	} else {
+		// Bands only tighten while price stays inside them
+		if upper < st.upper || st.prevClose > st.upper {
+			st.upper = upper
+		}
+		if lower > st.lower || st.prevClose < st.lower {
+			st.lower = lower
+		}
+		switch {
+		case st.direction < 0 && close > st.upper:
+			st.direction = 1
+		case st.direction > 0 && close < st.lower:
+			st.direction = -1
+		}
+	}
+	st.prevClose = close
+
+	if st.direction > 0 {
+		return st.lower, st.direction
+	}
+	return st.upper, st.direction
+}
+
+// trailingStop returns the last bar's SuperTrend as a stop level and the side
+// it protects, or zero and "" before SuperTrend is available
+func trailingStop(bars []EnhancedBar) (float64, string) {
+	if len(bars) == 0 {
+		return 0, ""
+	}
+	last := bars[len(bars)-1]
+	switch last.SuperTrendDirection {
+	case 1:
+		return last.SuperTrend, StopSideLong
+	case -1:
+		return last.SuperTrend, StopSideShort
+	default:
+		return 0, ""
+	}
+}
@@ -0,0 +1,161 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/service"
+
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+)
+
+// defaultWarmupBars is enough history for ATR(14), ADX(14), the 14-bar volume
+// z-score and the Bollinger squeeze (20-bar bands over a 50-bar width lookback) to be
+// populated, with headroom for the institutional flow quantile
+const defaultWarmupBars = 70
+
+var marketTimezone = loadMarketTimezone()
+
+func loadMarketTimezone() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// warmupBars returns how many bars to fetch before the requested window; ANALYSIS_WARMUP_BARS overrides the default
+func warmupBars() int {
+	if val := os.Getenv("ANALYSIS_WARMUP_BARS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultWarmupBars
+}
+
+// warmupBars is the warm-up of this analysis, extended so a long ATR period
+// has settled by the window start; a disabled warm-up stays disabled
+func (s *DeepSearchService) warmupBars() int {
+	bars := warmupBars()
+	if bars > 0 && bars < 3*s.atrPeriod {
+		bars = 3 * s.atrPeriod
+	}
+	return bars
+}
+
+// warmupStart returns a fetch start far enough before start to cover bars
+// bars of the given aggregation, allowing for nights, weekends and holidays
+func warmupStart(start time.Time, timeSpan string, multiplier, bars int) time.Time {
+	if bars == 0 {
+		return start
+	}
+
+	span := float64(bars * multiplier)
+	var tradingDays float64
+	switch timeSpan {
+	case "second":
+		tradingDays = span / (6.5 * 3600)
+	case "minute":
+		tradingDays = span / (6.5 * 60)
+	case "hour":
+		tradingDays = span / 6.5
+	case "day":
+		tradingDays = span
+	case "week":
+		tradingDays = span * 5
+	case "month":
+		tradingDays = span * 21
+	case "quarter":
+		tradingDays = span * 63
+	case "year":
+		tradingDays = span * 252
+	default:
+		tradingDays = span
+	}
+
+	calendarDays := int(math.Ceil(tradingDays*7/5)) + 4
+	return start.AddDate(0, 0, -calendarDays)
+}
+
+// fetchEnhancedBars fetches the requested window plus a warm-up buffer and
+// computes indicators across both. It returns every bar and the index of the
+// first bar inside the requested window; signals should only be emitted from there.
+func (s *DeepSearchService) fetchEnhancedBars(ctx context.Context) ([]EnhancedBar, int, error) {
+	bars, from, err := s.loadEnhancedBars(ctx, s.polygonAggs)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.applyYearRange(ctx, bars)
+	s.applyIVRank(ctx, bars)
+	s.applyConfirmedMoves(ctx, bars)
+	s.applyInsiderClusters(ctx, bars)
+	s.applyFTDSpikes(ctx, bars)
+	return bars, from, nil
+}
+
+// aggLoader returns the bars of the analysis aggregation from a time up to the window end
+type aggLoader func(ctx context.Context, from time.Time) ([]polygonmodels.Agg, error)
+
+// loadEnhancedBars is fetchEnhancedBars with the bars read by load
+func (s *DeepSearchService) loadEnhancedBars(ctx context.Context, load aggLoader) ([]EnhancedBar, int, error) {
+	windowStart, err := time.ParseInLocation("2006-01-02", s.startDuration, marketTimezone)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid start_duration: %w", err)
+	}
+	fetchStart := warmupStart(windowStart, s.timeSpan, s.multiplier, s.warmupBars())
+
+	anchor, err := s.fixedAnchor(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to resolve vwap anchor: %w", err)
+	}
+	if fetchStart, err = anchoredVWAPFetchStart(fetchStart, windowStart, anchor); err != nil {
+		return nil, 0, err
+	}
+
+	aggs, err := load(ctx, fetchStart)
+	if err != nil {
+		return nil, 0, err
+	}
+	scaleAggs(aggs, s.quoteCurrency().Scale)
+	enhancedBars := enhanceAggs(aggs, windowStart, s.atrPeriod)
+
+	switch {
+	case s.vwapAnchor == AnchorSession:
+		applyAnchoredVWAP(enhancedBars, sessionAnchors(enhancedBars))
+	case !anchor.IsZero():
+		applyAnchoredVWAP(enhancedBars, []time.Time{anchor})
+	}
+
+	from := len(enhancedBars)
+	for i, bar := range enhancedBars {
+		if !bar.Timestamp.Before(windowStart) {
+			from = i
+			break
+		}
+	}
+
+	return enhancedBars, from, nil
+}
+
+// polygonAggs fetches the analysis aggregation from Polygon
+func (s *DeepSearchService) polygonAggs(ctx context.Context, from time.Time) ([]polygonmodels.Agg, error) {
+	svc := service.NewStockTechnicalService(s.ticker)
+	bars, err := svc.GetPolygonAggregate(ctx, s.timeSpan, from.Format("2006-01-02"), s.endDuration, s.multiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggs []polygonmodels.Agg
+	for bars.Next() {
+		aggs = append(aggs, bars.Item())
+	}
+	if err := bars.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch aggregates: %w", err)
+	}
+	return aggs, nil
+}
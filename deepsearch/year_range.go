@@ -0,0 +1,137 @@
+package deepsearch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+)
+
+// yearRangeDays is the 52-week lookback in calendar days
+const yearRangeDays = 52 * 7
+
+// dailyLevel is one daily bar's range
+type dailyLevel struct {
+	day       time.Time
+	high, low float64
+}
+
+// applyYearRange sets each bar's 52-week high and low from Polygon daily bars
+// and records the 52-week context at the last bar. Without daily bars the
+// levels stay zero, so 52-week signals do not fire.
+func (s *DeepSearchService) applyYearRange(ctx context.Context, bars []EnhancedBar) {
+	s.yearRange = nil
+	if len(bars) == 0 {
+		return
+	}
+
+	first, last := bars[0], bars[len(bars)-1]
+	daily, err := s.dailyLevels(ctx, marketDay(first.Timestamp).AddDate(0, 0, -yearRangeDays), last.Timestamp)
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to fetch daily bars for %s, skipping 52-week context: %v\n", s.ticker, err)
+		return
+	}
+
+	setYearLevels(bars, daily)
+	s.yearRange = yearRangeAt(daily, last)
+}
+
+// dailyLevels fetches adjusted daily bars between two instants, oldest first
+func (s *DeepSearchService) dailyLevels(ctx context.Context, from, to time.Time) ([]dailyLevel, error) {
+	svc := service.NewStockTechnicalService(s.ticker)
+	it := svc.GetPolygonAggregateBetween(ctx, "day", 1, from, to)
+
+	scale := s.quoteCurrency().Scale
+	var levels []dailyLevel
+	for it.Next() {
+		agg := it.Item()
+		levels = append(levels, dailyLevel{day: marketDay(time.Time(agg.Timestamp)), high: agg.High * scale, low: agg.Low * scale})
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// setYearLevels sets YearHigh and YearLow on each bar from the daily bars of
+// the 52 weeks before its day, so a bar never counts towards its own level
+func setYearLevels(bars []EnhancedBar, daily []dailyLevel) {
+	type levels struct{ high, low float64 }
+	byDay := make(map[time.Time]levels)
+
+	for i := range bars {
+		day := marketDay(bars[i].Timestamp)
+		l, ok := byDay[day]
+		if !ok {
+			start := day.AddDate(0, 0, -yearRangeDays)
+			for _, d := range daily {
+				if d.day.Before(start) || !d.day.Before(day) {
+					continue
+				}
+				if d.high > l.high {
+					l.high = d.high
+				}
+				if d.low > 0 && (l.low == 0 || d.low < l.low) {
+					l.low = d.low
+				}
+			}
+			byDay[day] = l
+		}
+		bars[i].YearHigh, bars[i].YearLow = l.high, l.low
+	}
+}
+
+// yearRangeAt places the last bar's close within the 52 weeks up to and
+// including its day. When a level was hit more than once, the latest counts.
+func yearRangeAt(daily []dailyLevel, last EnhancedBar) *models.YearRange {
+	lastDay := marketDay(last.Timestamp)
+	start := lastDay.AddDate(0, 0, -yearRangeDays)
+
+	high, highDay := last.High, lastDay
+	low, lowDay := last.Low, lastDay
+	for _, d := range daily {
+		if d.day.Before(start) || d.day.After(lastDay) {
+			continue
+		}
+		if d.high > high || (d.high == high && d.day.After(highDay)) {
+			high, highDay = d.high, d.day
+		}
+		if d.low > 0 && (d.low < low || (d.low == low && d.day.After(lowDay))) {
+			low, lowDay = d.low, d.day
+		}
+	}
+
+	r := &models.YearRange{
+		High:          high,
+		HighDate:      highDay.Format("2006-01-02"),
+		Low:           low,
+		LowDate:       lowDay.Format("2006-01-02"),
+		Close:         last.Close,
+		DaysSinceHigh: daysBetween(highDay, lastDay),
+		DaysSinceLow:  daysBetween(lowDay, lastDay),
+	}
+	if high > 0 {
+		r.DistanceFromHighPct = (last.Close/high - 1) * 100
+	}
+	if low > 0 {
+		r.DistanceFromLowPct = (last.Close/low - 1) * 100
+	}
+	if high > low {
+		r.Position = (last.Close - low) / (high - low)
+	}
+	return r
+}
+
+// marketDay is midnight market time of t's trading day
+func marketDay(t time.Time) time.Time {
+	y, m, d := t.In(marketTimezone).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, marketTimezone)
+}
+
+// daysBetween counts calendar days from one market day to a later one
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"strings"
+	"sync"
+)
+
+// Event types
+const (
+	TypeSignal = "signal"
+	TypeJob    = "job"
+	// Replay events are sent only to the connection that started the replay
+	TypeReplay    = "replay"
+	TypeReplayBar = "replay_bar"
+	TypeReplayEnd = "replay_end"
+)
+
+// subscriberBuffer is how many events a slow subscriber can fall behind
+// before further events are dropped for it
+const subscriberBuffer = 64
+
+// Event is pushed to subscribers of its ticker that belong to the same user
+type Event struct {
+	Type   string      `json:"type"`
+	Ticker string      `json:"ticker"`
+	UserID string      `json:"-"`
+	Data   interface{} `json:"data"`
+}
+
+// Subscriber receives events for the tickers it subscribed to
+type Subscriber struct {
+	C      chan Event
+	userID string
+
+	mu      sync.Mutex
+	all     bool
+	tickers map[string]bool
+}
+
+// Subscribe adds tickers; "*" subscribes to every ticker
+func (s *Subscriber) Subscribe(tickers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tickers {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "*" {
+			s.all = true
+		} else if t != "" {
+			s.tickers[t] = true
+		}
+	}
+}
+
+// Unsubscribe removes tickers; "*" removes the wildcard subscription
+func (s *Subscriber) Unsubscribe(tickers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tickers {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "*" {
+			s.all = false
+		} else {
+			delete(s.tickers, t)
+		}
+	}
+}
+
+// Tickers returns the current subscriptions
+func (s *Subscriber) Tickers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tickers := make([]string, 0, len(s.tickers)+1)
+	if s.all {
+		tickers = append(tickers, "*")
+	}
+	for t := range s.tickers {
+		tickers = append(tickers, t)
+	}
+	return tickers
+}
+
+func (s *Subscriber) wants(e Event) bool {
+	if e.UserID != s.userID {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.all || s.tickers[strings.ToUpper(e.Ticker)]
+}
+
+// Hub fans events out to in-process subscribers (e.g. WebSocket connections).
+// Events are not shared across replicas.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscriber]struct{})}
+}
+
+// Subscribe registers a subscriber for userID's events; it starts with no tickers
+func (h *Hub) Subscribe(userID string) *Subscriber {
+	s := &Subscriber{
+		C:       make(chan Event, subscriberBuffer),
+		userID:  userID,
+		tickers: make(map[string]bool),
+	}
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.C)
+	}
+	h.mu.Unlock()
+}
+
+// Publish delivers e to matching subscribers without blocking; subscribers
+// whose buffer is full miss the event
+func (h *Hub) Publish(e Event) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subscribers {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.C <- e:
+		default:
+		}
+	}
+}
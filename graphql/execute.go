@@ -0,0 +1,868 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Request is a GraphQL request as posted over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is a GraphQL response. Data is absent when the request failed
+// before execution and null when a non-null root field failed.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a request or field error
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errNull marks a value nulled by an error that was already recorded, so a
+// non-null parent is nulled too
+var errNull = errors.New("null propagated")
+
+// Execute parses, validates and runs the request's query operation
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &Response{Errors: []*Error{{Message: syntaxErr.Error(), Locations: []Location{syntaxErr.Location}}}}
+		}
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if errs := s.validate(doc, op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	e := &executor{schema: s, doc: doc, batched: map[string]batchResult{}}
+	if errs := e.coerceVariables(op.variables, req.Variables); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	if s.MaxCost > 0 || s.MaxNodes > 0 {
+		cost := e.cost(s.Query, op.selections, 1)
+		if e.tooLarge {
+			return &Response{Errors: []*Error{{Message: errTooLarge(s).Error()}}}
+		}
+		if s.MaxCost > 0 && cost > s.MaxCost {
+			return &Response{Errors: []*Error{{
+				Message: fmt.Sprintf("Query would resolve more than the maximum of %d objects. Lower the limit arguments or select fewer nested lists.", s.MaxCost),
+			}}}
+		}
+	}
+
+	data, err := e.selectionSet(ctx, s.Query, nil, op.selections, nil)
+	response := &Response{Data: data, Errors: e.errors}
+	if err != nil {
+		response.Data = json.RawMessage("null")
+	}
+	return response
+}
+
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("Must provide operation name if query contains multiple operations.")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown operation named \"%s\".", name)
+}
+
+type executor struct {
+	schema *Schema
+	doc    *document
+	// variables are the request's variables as decoded from JSON, with
+	// defaults applied; values are coerced where they are used
+	variables map[string]interface{}
+	errors    []*Error
+	// batched holds the values of batch fields resolved ahead for a list's
+	// items, by field path
+	batched map[string]batchResult
+	// costNodes counts the fields cost walked, against Schema.MaxNodes
+	costNodes int
+	tooLarge  bool
+}
+
+func errTooLarge(s *Schema) error {
+	return fmt.Errorf("Query selects more than the maximum of %d fields once fragments are expanded.", s.MaxNodes)
+}
+
+type batchResult struct {
+	value interface{}
+	err   error
+}
+
+// cost estimates how many objects resolving selections on times values of
+// obj produces, stopping once it exceeds Schema.MaxCost or walks more than
+// Schema.MaxNodes fields, which sets tooLarge
+func (e *executor) cost(obj *Object, selections []selection, times int) int {
+	total := 0
+	groups, err := e.collectFields(obj, selections)
+	if err != nil {
+		e.tooLarge = true
+		return total
+	}
+	for _, group := range groups {
+		e.costNodes += len(group.fields)
+		if e.schema.MaxNodes > 0 && e.costNodes > e.schema.MaxNodes {
+			e.tooLarge = true
+			return total
+		}
+		f := group.fields[0]
+		if f.name == "__typename" {
+			continue
+		}
+		def := obj.field(f.name)
+		if isLeaf(def.Type) {
+			continue
+		}
+
+		count := times
+		if isList(def.Type) {
+			size := DefaultListSize
+			if def.Size != nil {
+				// Invalid arguments fail the field when it resolves
+				if args, err := e.arguments(def.Args, f.args); err == nil {
+					size = def.Size(args)
+				}
+			}
+			count *= max(size, 0)
+		}
+		total += count
+		if e.schema.MaxCost > 0 && total > e.schema.MaxCost {
+			return total
+		}
+
+		var selections []selection
+		for _, f := range group.fields {
+			selections = append(selections, f.selections...)
+		}
+		total += e.cost(namedType(def.Type).(*Object), selections, count)
+		if e.tooLarge || e.schema.MaxCost > 0 && total > e.schema.MaxCost {
+			return total
+		}
+	}
+	return total
+}
+
+func (e *executor) addError(err error, loc Location, path []interface{}) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Locations: []Location{loc}, Path: path})
+}
+
+// coerceVariables checks the given variables against their definitions and
+// applies defaults
+func (e *executor) coerceVariables(defs []*variableDef, given map[string]interface{}) []*Error {
+	types := e.schema.types()
+	e.variables = map[string]interface{}{}
+	var errs []*Error
+	for _, def := range defs {
+		t, err := inputType(types, def.typ)
+		if err != nil {
+			errs = append(errs, &Error{Message: err.Error(), Locations: []Location{def.loc}})
+			continue
+		}
+
+		value, ok := given[def.name]
+		if !ok && def.defaultValue != nil {
+			value, ok = e.raw(def.defaultValue), true
+		}
+		if !ok {
+			if def.typ.nonNull {
+				errs = append(errs, &Error{
+					Message:   fmt.Sprintf("Variable \"$%s\" of required type \"%s\" was not provided.", def.name, def.typ),
+					Locations: []Location{def.loc},
+				})
+			}
+			continue
+		}
+		if _, err := coerceValue(t, value); err != nil {
+			errs = append(errs, &Error{
+				Message:   fmt.Sprintf("Variable \"$%s\" got invalid value: %v", def.name, err),
+				Locations: []Location{def.loc},
+			})
+			continue
+		}
+		e.variables[def.name] = value
+	}
+	return errs
+}
+
+// inputType resolves a variable's declared type
+func inputType(types map[string]Type, ref *typeRef) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := inputType(types, ref.elem)
+		if err != nil {
+			return nil, err
+		}
+		t = ListOf(elem)
+	} else {
+		named, ok := types[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown type \"%s\".", ref.name)
+		}
+		if _, isObject := named.(*Object); isObject {
+			return nil, fmt.Errorf("Variable type \"%s\" is not an input type.", ref.name)
+		}
+		t = named
+	}
+	if ref.nonNull {
+		t = NonNullOf(t)
+	}
+	return t, nil
+}
+
+// fieldGroup is the fields selected under one response key
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collection is the state of flattening one selection set
+type collection struct {
+	// visited holds the fragments already spread
+	visited map[string]bool
+	// nodes counts the fields collected, against Schema.MaxNodes
+	nodes int
+}
+
+// collectFields flattens fragments and drops skipped selections, grouping
+// fields by response key in query order. It fails once more than
+// Schema.MaxNodes fields are collected.
+func (e *executor) collectFields(obj *Object, selections []selection) ([]*fieldGroup, error) {
+	c := &collection{visited: map[string]bool{}}
+	groups := e.collect(obj, selections, nil, c)
+	if e.schema.MaxNodes > 0 && c.nodes > e.schema.MaxNodes {
+		return nil, errTooLarge(e.schema)
+	}
+	return groups, nil
+}
+
+func (e *executor) collect(obj *Object, selections []selection, groups []*fieldGroup, c *collection) []*fieldGroup {
+	for _, sel := range selections {
+		if e.schema.MaxNodes > 0 && c.nodes > e.schema.MaxNodes {
+			return groups
+		}
+		switch s := sel.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			c.nodes++
+			var group *fieldGroup
+			for _, g := range groups {
+				if g.key == s.responseKey() {
+					group = g
+					break
+				}
+			}
+			if group == nil {
+				group = &fieldGroup{key: s.responseKey()}
+				groups = append(groups, group)
+			}
+			group.fields = append(group.fields, s)
+		case *fragmentSpread:
+			if c.visited[s.name] || !e.included(s.directives) {
+				continue
+			}
+			c.visited[s.name] = true
+			frag := e.doc.fragments[s.name]
+			if frag.typeCondition != obj.Name || !e.included(frag.directives) {
+				continue
+			}
+			groups = e.collect(obj, frag.selections, groups, c)
+		case *inlineFragment:
+			if !e.included(s.directives) || (s.typeCondition != "" && s.typeCondition != obj.Name) {
+				continue
+			}
+			groups = e.collect(obj, s.selections, groups, c)
+		}
+	}
+	return groups
+}
+
+// included applies @skip and @include
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		value, _ := coerceValue(Boolean, e.raw(d.args[0].value))
+		condition, _ := value.(bool)
+		if d.name == "skip" && condition || d.name == "include" && !condition {
+			return false
+		}
+	}
+	return true
+}
+
+// selectionSet resolves the selections on source, an object of type obj. It
+// returns errNull when a non-null field failed.
+func (e *executor) selectionSet(ctx context.Context, obj *Object, source interface{}, selections []selection, path []interface{}) (*orderedMap, error) {
+	result := &orderedMap{values: map[string]interface{}{}}
+	groups, err := e.collectFields(obj, selections)
+	if err != nil {
+		e.addError(err, selectionsLoc(selections), path)
+		return nil, errNull
+	}
+	for _, group := range groups {
+		f := group.fields[0]
+		fieldPath := appendPath(path, group.key)
+		if f.name == "__typename" {
+			result.set(group.key, obj.Name)
+			continue
+		}
+
+		def := obj.field(f.name)
+		var value interface{}
+		var err error
+		if result, ok := e.batched[pathKey(fieldPath)]; ok {
+			delete(e.batched, pathKey(fieldPath))
+			value, err = result.value, result.err
+		} else {
+			value, err = e.resolve(ctx, def, source, f)
+		}
+		if err == nil {
+			value, err = e.complete(ctx, def.Type, group.fields, value, fieldPath)
+		} else {
+			e.addError(err, f.loc, fieldPath)
+			if _, nonNull := def.Type.(*NonNull); nonNull {
+				err = errNull
+			} else {
+				value, err = nil, nil
+			}
+		}
+		if err != nil {
+			return nil, errNull
+		}
+		result.set(group.key, value)
+	}
+	return result, nil
+}
+
+// resolve coerces a field's arguments and calls its resolver, turning a
+// panic into an error
+func (e *executor) resolve(ctx context.Context, def *Field, source interface{}, f *field) (value interface{}, err error) {
+	args, err := e.arguments(def.Args, f.args)
+	if err != nil {
+		return nil, err
+	}
+	if def.BatchResolve != nil {
+		values, err := callBatch(ctx, def, []interface{}{source}, args)
+		if err != nil {
+			return nil, err
+		}
+		return values[0], nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error resolving %s: %v", def.Name, r)
+		}
+	}()
+	if def.Resolve == nil {
+		return defaultResolve(source, def.Name), nil
+	}
+	return def.Resolve(ctx, source, args)
+}
+
+// callBatch calls a field's batch resolver, turning a panic or a result of
+// the wrong length into an error
+func callBatch(ctx context.Context, def *Field, sources []interface{}, args map[string]interface{}) (values []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			values, err = nil, fmt.Errorf("internal error resolving %s: %v", def.Name, r)
+		}
+	}()
+	values, err = def.BatchResolve(ctx, sources, args)
+	if err == nil && len(values) != len(sources) {
+		return nil, fmt.Errorf("internal error resolving %s: %d values for %d parents", def.Name, len(values), len(sources))
+	}
+	return values, err
+}
+
+// batchSource is an object whose batch fields prefetch resolves, with its
+// response path
+type batchSource struct {
+	value interface{}
+	path  []interface{}
+}
+
+// prefetch resolves the batch fields selected on a list of objects with one
+// call each, leaving every item's value in e.batched for selectionSet. The
+// values' own batch fields are prefetched the same way across all of them,
+// so each level of nested lists takes one call per field.
+func (e *executor) prefetch(ctx context.Context, obj *Object, fields []*field, sources []batchSource) {
+	var selections []selection
+	for _, f := range fields {
+		selections = append(selections, f.selections...)
+	}
+
+	// selectionSet reports a selection too large to collect
+	groups, _ := e.collectFields(obj, selections)
+	for _, group := range groups {
+		f := group.fields[0]
+		if f.name == "__typename" || obj.field(f.name).BatchResolve == nil {
+			continue
+		}
+		def := obj.field(f.name)
+
+		var pending []batchSource
+		var values []interface{}
+		for _, source := range sources {
+			if _, done := e.batched[pathKey(appendPath(source.path, group.key))]; !done {
+				pending = append(pending, source)
+				values = append(values, source.value)
+			}
+		}
+		if len(pending) < 2 {
+			continue
+		}
+
+		args, err := e.arguments(def.Args, f.args)
+		var results []interface{}
+		if err == nil {
+			results, err = callBatch(ctx, def, values, args)
+		}
+		var children []batchSource
+		for j, source := range pending {
+			fieldPath := appendPath(source.path, group.key)
+			result := batchResult{err: err}
+			if err == nil {
+				result.value = results[j]
+				children = appendObjects(children, def.Type, result.value, fieldPath)
+			}
+			e.batched[pathKey(fieldPath)] = result
+		}
+		if child, ok := namedType(def.Type).(*Object); ok {
+			e.prefetch(ctx, child, group.fields, children)
+		}
+	}
+}
+
+// appendObjects appends the objects a field of type t resolved to, with
+// their paths: the value itself, or each item of a list of objects
+func appendObjects(sources []batchSource, t Type, value interface{}, path []interface{}) []batchSource {
+	if isNull(value) {
+		return sources
+	}
+	if !isList(t) {
+		return append(sources, batchSource{value: value, path: path})
+	}
+	list := t
+	if nonNull, ok := list.(*NonNull); ok {
+		list = nonNull.Of
+	}
+	if isList(list.(*List).Of) {
+		return sources
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return sources
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if item := rv.Index(i).Interface(); !isNull(item) {
+			sources = append(sources, batchSource{value: item, path: appendPath(path, i)})
+		}
+	}
+	return sources
+}
+
+// arguments coerces a field's arguments, applying defaults
+func (e *executor) arguments(defs []*InputField, args []*argument) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		var node *valueNode
+		for _, arg := range args {
+			if arg.name == def.Name {
+				node = arg.value
+			}
+		}
+		if node != nil && node.kind == variableValue {
+			if _, given := e.variables[node.raw]; !given {
+				node = nil
+			}
+		}
+
+		if node == nil {
+			if def.Default != nil {
+				coerced[def.Name] = def.Default
+			} else if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, fmt.Errorf("Argument \"%s\" of type \"%s\" is required, but it was not provided.", def.Name, def.Type)
+			}
+			continue
+		}
+
+		value, err := coerceValue(def.Type, e.raw(node))
+		if err != nil {
+			return nil, fmt.Errorf("Argument \"%s\" has invalid value: %v", def.Name, err)
+		}
+		coerced[def.Name] = value
+	}
+	return coerced, nil
+}
+
+// complete converts a resolved value to its response form. A null in a
+// non-null position is recorded and returns errNull; a nullable position
+// absorbs errNull from below as null.
+func (e *executor) complete(ctx context.Context, t Type, fields []*field, value interface{}, path []interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		completed, err := e.completeNullable(ctx, nonNull.Of, fields, value, path)
+		if err != nil {
+			return nil, err
+		}
+		if completed == nil {
+			e.addError(fmt.Errorf("Cannot return null for non-nullable field %s.", fields[0].name), fields[0].loc, path)
+			return nil, errNull
+		}
+		return completed, nil
+	}
+
+	completed, err := e.completeNullable(ctx, t, fields, value, path)
+	if err != nil {
+		return nil, nil
+	}
+	return completed, nil
+}
+
+func (e *executor) completeNullable(ctx context.Context, t Type, fields []*field, value interface{}, path []interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if rv.Kind() == reflect.Map && rv.IsNil() {
+		return nil, nil
+	}
+
+	switch typed := t.(type) {
+	case *List:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.addError(fmt.Errorf("Expected a list for field %s, got %s.", fields[0].name, rv.Type()), fields[0].loc, path)
+			return nil, errNull
+		}
+		if obj, ok := namedType(typed.Of).(*Object); ok && !isList(typed.Of) {
+			e.prefetch(ctx, obj, fields, appendObjects(nil, t, rv.Interface(), path))
+		}
+		items := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := e.complete(ctx, typed.Of, fields, rv.Index(i).Interface(), appendPath(path, i))
+			if err != nil {
+				return nil, errNull
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case *Scalar:
+		serialized, err := typed.Serialize(rv.Interface())
+		if err != nil {
+			e.addError(err, fields[0].loc, path)
+			return nil, errNull
+		}
+		return serialized, nil
+	case *Enum:
+		if rv.Kind() != reflect.String || !typed.has(rv.String()) {
+			e.addError(fmt.Errorf("Enum \"%s\" cannot represent value: %v", typed.Name, rv.Interface()), fields[0].loc, path)
+			return nil, errNull
+		}
+		return rv.String(), nil
+	case *Object:
+		var selections []selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		return e.selectionSet(ctx, typed, rv.Interface(), selections, path)
+	}
+	return nil, fmt.Errorf("unsupported output type %s", t)
+}
+
+// pathKey identifies a response path in e.batched
+func pathKey(path []interface{}) string {
+	return fmt.Sprint(path)
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	extended := make([]interface{}, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, key)
+}
+
+// raw converts a value node to the form JSON variables decode to,
+// substituting variables. Object fields set to an undefined variable are
+// left out, as if omitted.
+func (e *executor) raw(node *valueNode) interface{} {
+	switch node.kind {
+	case variableValue:
+		return e.variables[node.raw]
+	case intValue:
+		n, err := strconv.ParseInt(node.raw, 10, 64)
+		if err != nil {
+			f, _ := strconv.ParseFloat(node.raw, 64)
+			return f
+		}
+		return n
+	case floatValue:
+		f, _ := strconv.ParseFloat(node.raw, 64)
+		return f
+	case stringValue, enumValue:
+		return node.raw
+	case booleanValue:
+		return node.raw == "true"
+	case listValue:
+		items := make([]interface{}, 0, len(node.list))
+		for _, item := range node.list {
+			items = append(items, e.raw(item))
+		}
+		return items
+	case objectValue:
+		fields := make(map[string]interface{}, len(node.fields))
+		for _, f := range node.fields {
+			if f.value.kind == variableValue {
+				if _, given := e.variables[f.value.raw]; !given {
+					continue
+				}
+			}
+			fields[f.name] = e.raw(f.value)
+		}
+		return fields
+	}
+	return nil
+}
+
+// coerceValue converts a raw argument or variable value to type t
+func coerceValue(t Type, value interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if value == nil {
+			return nil, fmt.Errorf("expected non-null value of type %s", t)
+		}
+		return coerceValue(nonNull.Of, value)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch typed := t.(type) {
+	case *List:
+		values, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one
+			values = []interface{}{value}
+		}
+		coerced := make([]interface{}, 0, len(values))
+		for i, item := range values {
+			c, err := coerceValue(typed.Of, item)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			coerced = append(coerced, c)
+		}
+		return coerced, nil
+	case *Scalar:
+		return typed.Parse(value)
+	case *Enum:
+		s, ok := value.(string)
+		if !ok || !typed.has(s) {
+			return nil, fmt.Errorf("value %v does not exist in \"%s\" enum (%s)", value, typed.Name, strings.Join(typed.Values, ", "))
+		}
+		return s, nil
+	case *InputObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %s", typed.Name)
+		}
+		for name := range fields {
+			if findInput(typed.Fields, name) == nil {
+				return nil, fmt.Errorf("field \"%s\" is not defined by type \"%s\"", name, typed.Name)
+			}
+		}
+		coerced := make(map[string]interface{}, len(fields))
+		for _, def := range typed.Fields {
+			fieldValue, given := fields[def.Name]
+			if !given {
+				if def.Default != nil {
+					coerced[def.Name] = def.Default
+				} else if _, nonNull := def.Type.(*NonNull); nonNull {
+					return nil, fmt.Errorf("field \"%s.%s\" of required type \"%s\" was not provided", typed.Name, def.Name, def.Type)
+				}
+				continue
+			}
+			c, err := coerceValue(def.Type, fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("in field \"%s\": %w", def.Name, err)
+			}
+			coerced[def.Name] = c
+		}
+		return coerced, nil
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// types returns the schema's named types by name
+func (s *Schema) types() map[string]Type {
+	s.typesOnce.Do(func() {
+		s.named = map[string]Type{}
+		for _, builtin := range []*Scalar{Int, Float, String, Boolean, ID} {
+			s.named[builtin.Name] = builtin
+		}
+		var collect func(t Type)
+		collect = func(t Type) {
+			t = namedType(t)
+			if _, seen := s.named[t.String()]; seen {
+				return
+			}
+			s.named[t.String()] = t
+			switch typed := t.(type) {
+			case *Object:
+				for _, f := range typed.Fields {
+					collect(f.Type)
+					for _, arg := range f.Args {
+						collect(arg.Type)
+					}
+				}
+			case *InputObject:
+				for _, f := range typed.Fields {
+					collect(f.Type)
+				}
+			}
+		}
+		collect(s.Query)
+	})
+	return s.named
+}
+
+// defaultResolve reads the source's map entry named name, or its struct
+// field whose json name is name or whose Go name matches it ignoring case
+func defaultResolve(source interface{}, name string) interface{} {
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !value.IsValid() {
+			return nil
+		}
+		return value.Interface()
+	case reflect.Struct:
+		index, ok := structField(rv.Type(), name)
+		if !ok {
+			return nil
+		}
+		value, err := rv.FieldByIndexErr(index)
+		if err != nil {
+			return nil
+		}
+		return value.Interface()
+	}
+	return nil
+}
+
+var structFields sync.Map // structFieldKey -> []int, nil when absent
+
+type structFieldKey struct {
+	t    reflect.Type
+	name string
+}
+
+func structField(t reflect.Type, name string) ([]int, bool) {
+	key := structFieldKey{t, name}
+	if cached, ok := structFields.Load(key); ok {
+		index := cached.([]int)
+		return index, index != nil
+	}
+
+	var index []int
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == name {
+			index = f.Index
+			break
+		}
+		if index == nil && strings.EqualFold(f.Name, name) {
+			index = f.Index
+		}
+	}
+	structFields.Store(key, index)
+	return index, index != nil
+}
+
+// orderedMap is a response object, keeping fields in query order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// item is the value of the test schema's Item type
+type item struct {
+	Name string
+}
+
+func testSchema() *Schema {
+	itemType := &Object{Name: "Item", Fields: []*Field{{Name: "name", Type: NonNullOf(String)}}}
+	itemType.Fields = append(itemType.Fields,
+		&Field{Name: "child", Type: itemType, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return item{Name: source.(item).Name + ".child"}, nil
+		}},
+		&Field{Name: "children", Type: ListOf(itemType),
+			Args: []*InputField{{Name: "limit", Type: Int, Default: 2}},
+			Size: func(args map[string]interface{}) int { return args["limit"].(int) },
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				var children []item
+				for i := 0; i < args["limit"].(int); i++ {
+					children = append(children, item{Name: fmt.Sprintf("%s.%d", source.(item).Name, i)})
+				}
+				return children, nil
+			}},
+	)
+	query := &Object{Name: "Query", Fields: []*Field{
+		{Name: "hello", Type: String,
+			Args: []*InputField{{Name: "name", Type: NonNullOf(String)}},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return "hello " + args["name"].(string), nil
+			}},
+		{Name: "root", Type: itemType, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return item{Name: "root"}, nil
+		}},
+	}}
+	return &Schema{Query: query, MaxDepth: 4, MaxAliases: 3, MaxCost: 100, MaxNodes: 50}
+}
+
+func execute(t *testing.T, s *Schema, query string, variables map[string]interface{}) (string, []string) {
+	t.Helper()
+	resp := s.Execute(context.Background(), Request{Query: query, Variables: variables})
+	var messages []string
+	for _, err := range resp.Errors {
+		messages = append(messages, err.Message)
+	}
+	if resp.Data == nil {
+		return "", messages
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshal data: %v", err)
+	}
+	return string(data), messages
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ hello(name: "x") `, "Syntax Error: expected a name, found end of query (line 1, column 20)"},
+		{`{ hello(name: "x) }`, "Syntax Error: unterminated string (line 1, column 15)"},
+		{`fragment F on Query { hello }`, "the document has no operation"},
+		{`mutation { hello }`, "Syntax Error"},
+	}
+	for _, tt := range tests {
+		_, err := parse(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parse(%q) = %v, want %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	doc, err := parse(`
+		# comment
+		query Named($name: String! = "a") { ...F hello(name: $name) @skip(if: false) }
+		fragment F on Query { root { name } }`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(doc.operations) != 1 || doc.operations[0].name != "Named" || len(doc.operations[0].variables) != 1 {
+		t.Fatalf("operations = %+v, want Named with one variable", doc.operations)
+	}
+	if frag := doc.fragments["F"]; frag == nil || frag.typeCondition != "Query" || len(frag.selections) != 1 {
+		t.Fatalf("fragments = %+v, want F on Query", doc.fragments)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"fields", `{ hello(name: "a") root { name } }`, nil, `{"hello":"hello a","root":{"name":"root"}}`},
+		{"alias", `{ a: hello(name: "a") b: hello(name: "b") }`, nil, `{"a":"hello a","b":"hello b"}`},
+		{"variables", `query($n: String!) { hello(name: $n) }`, map[string]interface{}{"n": "v"}, `{"hello":"hello v"}`},
+		{"list argument", `{ root { children(limit: 1) { name } } }`, nil, `{"root":{"children":[{"name":"root.0"}]}}`},
+		{"default argument", `{ root { children { name } } }`, nil, `{"root":{"children":[{"name":"root.0"},{"name":"root.1"}]}}`},
+		{"fragment", `{ root { ...Names } } fragment Names on Item { name child { name } }`, nil, `{"root":{"name":"root","child":{"name":"root.child"}}}`},
+		{"inline fragment", `{ root { ... on Item { name } } }`, nil, `{"root":{"name":"root"}}`},
+		{"merged fields", `{ root { child { name } child { __typename } } }`, nil, `{"root":{"child":{"name":"root.child","__typename":"Item"}}}`},
+		{"skip", `{ root { name @skip(if: true) child @include(if: true) { name } } }`, nil, `{"root":{"child":{"name":"root.child"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := execute(t, testSchema(), tt.query, tt.variables)
+			if len(errs) > 0 {
+				t.Fatalf("errors: %v", errs)
+			}
+			if data != tt.want {
+				t.Errorf("data = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"unknown field", `{ nope }`, `Cannot query field "nope" on type "Query".`},
+		{"missing argument", `{ hello }`, `Argument "name" of type "String!" is required on field "Query.hello", but it was not provided.`},
+		{"unknown argument", `{ hello(name: "a", x: 1) }`, `Unknown argument "x" on field "Query.hello".`},
+		{"leaf selection", `{ hello(name: "a") { x } }`, `Field "hello" must not have a selection since type "String" has no subfields.`},
+		{"missing selection", `{ root }`, `Field "root" of type "Item" must have a selection of subfields. Did you mean "root { ... }"?`},
+		{"undefined variable", `{ hello(name: $n) }`, `Variable "$n" is not defined.`},
+		{"unknown directive", `{ root @cached { name } }`, `Unknown directive "@cached".`},
+		{"unknown fragment", `{ ...Missing }`, `Unknown fragment "Missing".`},
+		{"fragment cycle", `{ root { ...A } } fragment A on Item { child { ...B } } fragment B on Item { ...A }`, `Cannot spread fragment "A" within itself.`},
+		{"fragment type", `{ ...F } fragment F on Item { name }`, `Fragment "F" cannot be spread here as objects of type "Query" can never be of type "Item".`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := execute(t, testSchema(), tt.query, nil)
+			if len(errs) == 0 || errs[0] != tt.want {
+				t.Errorf("errors = %q, want %q", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"depth", `{ root { child { child { child { name } } } } }`, "Query is nested deeper than the maximum depth of 4."},
+		{"aliases", `{ a: root { name } b: root { name } c: root { name } d: root { name } }`, "Query uses more than the maximum of 3 aliases."},
+		{"aliases in fragments", `{ ...F ...G } fragment F on Query { a: root { name } b: root { name } } fragment G on Query { c: root { ...H } d: root { ...H } } fragment H on Item { name }`,
+			"Query uses more than the maximum of 3 aliases."},
+		{"cost", `{ root { children(limit: 10) { children(limit: 10) { name } } } }`, "Query would resolve more than the maximum of 100 objects. Lower the limit arguments or select fewer nested lists."},
+		{"nodes", `{ root { ...F child { ...F } } } fragment F on Item { ` + strings.Repeat("name ", 30) + `}`, "Query selects more than the maximum of 50 fields once fragments are expanded."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := execute(t, testSchema(), tt.query, nil)
+			if data != "" || len(errs) == 0 || errs[0] != tt.want {
+				t.Errorf("data = %s, errors = %q, want %q", data, errs, tt.want)
+			}
+		})
+	}
+}
+
+// nestedSpreads returns a query whose fragments each spread the next one
+// twice, so it expands to 2^levels fields
+func nestedSpreads(levels int) string {
+	var b strings.Builder
+	b.WriteString("{ ...F0 }\n")
+	for i := 0; i < levels; i++ {
+		fmt.Fprintf(&b, "fragment F%d on Query { ...F%d ...F%d root { ...I%d } }\n", i, i+1, i+1, i)
+		fmt.Fprintf(&b, "fragment I%d on Item { name ...I%d ...I%d }\n", i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, "fragment F%d on Query { hello(name: \"x\") }\n", levels)
+	fmt.Fprintf(&b, "fragment I%d on Item { name }\n", levels)
+	return b.String()
+}
+
+func TestFragmentExpansion(t *testing.T) {
+	query := nestedSpreads(60)
+
+	start := time.Now()
+	_, errs := execute(t, testSchema(), query, nil)
+	if len(errs) != 1 || errs[0] != "Query selects more than the maximum of 50 fields once fragments are expanded." {
+		t.Errorf("errors = %q, want the field limit", errs)
+	}
+
+	// Without a field limit, each fragment is still validated, costed and
+	// collected once per selection set
+	unlimited := testSchema()
+	unlimited.MaxNodes = 0
+	unlimited.MaxCost = 0
+	data, errs := execute(t, unlimited, query, nil)
+	if len(errs) > 0 || data != `{"hello":"hello x","root":{"name":"root"}}` {
+		t.Errorf("data = %s, errors = %q", data, errs)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+}
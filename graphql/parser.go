@@ -0,0 +1,819 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Location is a line and column in the query, both from 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	name       string
+	variables  []*variableDef
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type variableDef struct {
+	name         string
+	typ          *typeRef
+	defaultValue *valueNode
+	loc          Location
+}
+
+// typeRef is a type written in a variable definition
+type typeRef struct {
+	name    string
+	elem    *typeRef // set for lists
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+type argument struct {
+	name  string
+	value *valueNode
+	loc   Location
+}
+
+type valueKind int
+
+const (
+	variableValue valueKind = iota
+	intValue
+	floatValue
+	stringValue
+	booleanValue
+	nullValue
+	enumValue
+	listValue
+	objectValue
+)
+
+type valueNode struct {
+	kind   valueKind
+	raw    string // the variable name, or the literal of a scalar or enum value
+	list   []*valueNode
+	fields []*argument
+	loc    Location
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+// SyntaxError is a query that cannot be parsed
+type SyntaxError struct {
+	Message  string
+	Location Location
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error: %s (line %d, column %d)", e.Message, e.Location.Line, e.Location.Column)
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func (l *lexer) loc() Location {
+	return Location{Line: l.line, Column: l.pos - l.lineStart + 1}
+}
+
+func (l *lexer) errorf(loc Location, format string, args ...interface{}) error {
+	return &SyntaxError{Message: fmt.Sprintf(format, args...), Location: loc}
+}
+
+// skipIgnored skips whitespace, commas, comments and the byte order mark
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.lineStart = l.pos
+		case c == '\r':
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.line++
+			l.lineStart = l.pos
+		case c == ' ' || c == '\t' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	loc := l.loc()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, loc: loc}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", loc: loc}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString(loc)
+		}
+		return l.string(loc)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(loc, "unexpected character %q", r)
+}
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos == digits {
+		return token{}, l.errorf(loc, "invalid number")
+	}
+	if l.pos-digits > 1 && l.src[digits] == '0' {
+		return token{}, l.errorf(loc, "invalid number, unexpected digit after 0")
+	}
+
+	kind := tokenInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		fraction := l.pos
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		if l.pos == fraction {
+			return token{}, l.errorf(loc, "invalid number, expected digit after '.'")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		exponent := l.pos
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		if l.pos == exponent {
+			return token{}, l.errorf(loc, "invalid number, expected digit in exponent")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, l.errorf(loc, "invalid number, unexpected %q", l.src[l.pos])
+	}
+	return token{kind: kind, value: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) string(loc Location) (token, error) {
+	l.pos++ // opening quote
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: b.String(), loc: loc}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf(loc, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(loc, "unterminated string")
+			}
+			escape := l.src[l.pos+1]
+			l.pos += 2
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, l.errorf(loc, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(loc, "invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, l.errorf(loc, "invalid escape \\%c", escape)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(loc, "unterminated string")
+}
+
+// blockString reads a """-delimited string, removing its common indentation
+func (l *lexer) blockString(loc Location) (token, error) {
+	l.pos += 3
+	start := l.pos
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			l.pos += 4
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			raw := strings.ReplaceAll(l.src[start:l.pos], `\"""`, `"""`)
+			l.pos += 3
+			return token{kind: tokenString, value: dedentBlockString(raw), loc: loc}, nil
+		case l.src[l.pos] == '\n':
+			l.pos++
+			l.line++
+			l.lineStart = l.pos
+		default:
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(loc, "unterminated block string")
+}
+
+func dedentBlockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+// parse parses an executable document
+func parse(query string) (*document, error) {
+	p := &parser{lexer: &lexer{src: query, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{selections: selections, loc: selectionsLoc(selections)})
+		case p.tok.kind == tokenName && p.tok.value == "query":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.tok.kind == tokenName && (p.tok.value == "mutation" || p.tok.value == "subscription"):
+			return nil, p.lexer.errorf(p.tok.loc, "%ss are not supported", p.tok.value)
+		case p.tok.kind == tokenName && p.tok.value == "fragment":
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.fragments[frag.name]; exists {
+				return nil, p.lexer.errorf(frag.loc, "there can be only one fragment named %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, p.lexer.errorf(p.tok.loc, "the document has no operation")
+	}
+	return doc, nil
+}
+
+func selectionsLoc(selections []selection) Location {
+	switch s := selections[0].(type) {
+	case *field:
+		return s.loc
+	case *fragmentSpread:
+		return s.loc
+	case *inlineFragment:
+		return s.loc
+	}
+	return Location{}
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.lexer.errorf(p.tok.loc, "unexpected end of query")
+	}
+	return p.lexer.errorf(p.tok.loc, "unexpected %q", p.tok.value)
+}
+
+// expect consumes the punctuator or fails
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		if p.tok.kind == tokenEOF {
+			return p.lexer.errorf(p.tok.loc, "expected %q, found end of query", punct)
+		}
+		return p.lexer.errorf(p.tok.loc, "expected %q, found %q", punct, p.tok.value)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		if p.tok.kind == tokenEOF {
+			return "", p.lexer.errorf(p.tok.loc, "expected a name, found end of query")
+		}
+		return "", p.lexer.errorf(p.tok.loc, "expected a name, found %q", p.tok.value)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) keyword(word string) error {
+	if p.tok.kind != tokenName || p.tok.value != word {
+		return p.lexer.errorf(p.tok.loc, "expected %q", word)
+	}
+	return p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{loc: p.tok.loc}
+	if err := p.advance(); err != nil { // "query"
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokenName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if op.variables, err = p.variableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*variableDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []*variableDef
+	for !p.peek(")") {
+		def := &variableDef{loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if def.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if p.peek("=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if def.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		elem, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		t.elem = elem
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t.name = name
+	}
+	if p.peek("!") {
+		t.nonNull = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	frag := &fragment{loc: p.tok.loc}
+	if err := p.advance(); err != nil { // "fragment"
+		return nil, err
+	}
+	var err error
+	if frag.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.name == "on" {
+		return nil, p.lexer.errorf(frag.loc, `a fragment cannot be named "on"`)
+	}
+	if err := p.keyword("on"); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.peek("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, p.lexer.errorf(p.tok.loc, "a selection set cannot be empty")
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (selection, error) {
+	loc := p.tok.loc
+	if !p.peek("...") {
+		return p.field()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &fragmentSpread{loc: loc}
+		var err error
+		if spread.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if spread.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+
+	inline := &inlineFragment{loc: loc}
+	var err error
+	if p.tok.kind == tokenName {
+		if err := p.advance(); err != nil { // "on"
+			return nil, err
+		}
+		if inline.typeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) field() (*field, error) {
+	f := &field{loc: p.tok.loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.name = name
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if f.args, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments() ([]*argument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []*argument
+	for !p.peek(")") {
+		arg := &argument{loc: p.tok.loc}
+		var err error
+		if arg.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.value, err = p.value(false); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, p.lexer.errorf(p.tok.loc, "an argument list cannot be empty")
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if p.peek("(") {
+			if d.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// value parses a value; constant values (variable defaults) cannot hold variables
+func (p *parser) value(constant bool) (*valueNode, error) {
+	v := &valueNode{loc: p.tok.loc}
+	switch p.tok.kind {
+	case tokenInt:
+		v.kind, v.raw = intValue, p.tok.value
+	case tokenFloat:
+		v.kind, v.raw = floatValue, p.tok.value
+	case tokenString:
+		v.kind, v.raw = stringValue, p.tok.value
+	case tokenName:
+		switch p.tok.value {
+		case "true", "false":
+			v.kind, v.raw = booleanValue, p.tok.value
+		case "null":
+			v.kind = nullValue
+		default:
+			v.kind, v.raw = enumValue, p.tok.value
+		}
+	case tokenPunct:
+		switch p.tok.value {
+		case "$":
+			if constant {
+				return nil, p.lexer.errorf(v.loc, "unexpected variable in a constant value")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			v.kind, v.raw = variableValue, name
+			return v, nil
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			v.kind = listValue
+			for !p.peek("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.list = append(v.list, item)
+			}
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			v.kind = objectValue
+			for !p.peek("}") {
+				f := &argument{loc: p.tok.loc}
+				var err error
+				if f.name, err = p.name(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if f.value, err = p.value(constant); err != nil {
+					return nil, err
+				}
+				v.fields = append(v.fields, f)
+			}
+		default:
+			return nil, p.unexpected()
+		}
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
@@ -0,0 +1,139 @@
+package graphql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Built-in scalars
+var (
+	Int = &Scalar{
+		Name:      "Int",
+		Serialize: serializeInt,
+		Parse: func(v interface{}) (interface{}, error) {
+			n, ok := wholeNumber(v)
+			if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name:      "Float",
+		Serialize: serializeFloat,
+		Parse: func(v interface{}) (interface{}, error) {
+			f, err := serializeFloat(v)
+			if err != nil {
+				return nil, fmt.Errorf("Float cannot represent %v", v)
+			}
+			return f, nil
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.String {
+				return nil, fmt.Errorf("String cannot represent %T", v)
+			}
+			return rv.String(), nil
+		},
+		Parse: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %v", v)
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Bool {
+				return nil, fmt.Errorf("Boolean cannot represent %T", v)
+			}
+			return rv.Bool(), nil
+		},
+		Parse: func(v interface{}) (interface{}, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %v", v)
+		},
+	}
+	// ID serializes strings and integers as strings
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.String {
+				return rv.String(), nil
+			}
+			n, err := serializeInt(v)
+			if err != nil {
+				return nil, fmt.Errorf("ID cannot represent %T", v)
+			}
+			return strconv.FormatInt(n.(int64), 10), nil
+		},
+		Parse: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := wholeNumber(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+	}
+)
+
+// builtinScalars are predefined by GraphQL and left out of the SDL
+var builtinScalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+
+func serializeInt(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("Int cannot represent %v", v)
+		}
+		return int64(rv.Uint()), nil
+	}
+	return nil, fmt.Errorf("Int cannot represent %T", v)
+}
+
+func serializeFloat(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("Float cannot represent %v", f)
+		}
+		return f, nil
+	}
+	n, err := serializeInt(v)
+	if err != nil {
+		return nil, fmt.Errorf("Float cannot represent %T", v)
+	}
+	return float64(n.(int64)), nil
+}
+
+// wholeNumber returns an integer, or a JSON number without a fraction
+func wholeNumber(v interface{}) (int64, bool) {
+	if n, ok := v.(float64); ok {
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+		return 0, false
+	}
+	n, err := serializeInt(v)
+	if err != nil {
+		return 0, false
+	}
+	return n.(int64), true
+}
@@ -0,0 +1,308 @@
+// Package graphql executes GraphQL queries against a schema of Go resolvers.
+// It implements the query subset dashboards use: fields, aliases, arguments,
+// variables, fragments, @include/@skip and __typename. Mutations,
+// subscriptions, interfaces, unions and introspection are not supported;
+// Schema.SDL prints the schema for client code generators instead.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Type is a GraphQL type: a *Scalar, *Enum, *Object, *InputObject, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type. Serialize converts a resolved Go value for the
+// response; Parse converts an argument or variable value, already decoded
+// from the query or JSON, to its Go value.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(v interface{}) (interface{}, error)
+	Parse       func(v interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Enum is a leaf type whose values are resolved and parsed as strings
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+func (e *Enum) String() string { return e.Name }
+
+func (e *Enum) has(value string) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Object is an output type with fields. Fields may be appended after the
+// object is created, so types can refer to each other.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Resolver returns a field's value. source is the parent object's resolved
+// value and args the field's arguments, coerced with defaults applied.
+type Resolver func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// BatchResolver returns a field's values for several parents at once, one
+// per source in order, so a field under a list resolves in one call instead
+// of one per item
+type BatchResolver func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error)
+
+// Field is a field of an object. A nil Resolve reads the source's struct
+// field or map entry of the same name: a struct field matches by json name
+// or by Go name ignoring case. BatchResolve, if set, is used instead of
+// Resolve.
+type Field struct {
+	Name         string
+	Description  string
+	Args         []*InputField
+	Type         Type
+	Resolve      Resolver
+	BatchResolve BatchResolver
+	// Size estimates how many items a list field returns given its
+	// arguments, for Schema.MaxCost; nil counts DefaultListSize
+	Size func(args map[string]interface{}) int
+}
+
+// DefaultListSize is the estimated length of a list field without a Size
+const DefaultListSize = 10
+
+// InputObject is an argument type with fields. Fields may be appended after
+// the object is created, so a filter can nest itself.
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*InputField
+}
+
+func (o *InputObject) String() string { return o.Name }
+
+// InputField is an argument or a field of an input object. Default is used
+// when the value is omitted and must already be coerced.
+type InputField struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     interface{}
+}
+
+func findInput(fields []*InputField, name string) *InputField {
+	for _, f := range fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// List is a list of another type
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a type whose values cannot be null
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// ListOf returns a list of t
+func ListOf(t Type) *List { return &List{Of: t} }
+
+// NonNullOf returns t, not null
+func NonNullOf(t Type) *NonNull { return &NonNull{Of: t} }
+
+// namedType strips lists and non-null wrappers
+func namedType(t Type) Type {
+	for {
+		switch wrapped := t.(type) {
+		case *List:
+			t = wrapped.Of
+		case *NonNull:
+			t = wrapped.Of
+		default:
+			return t
+		}
+	}
+}
+
+// isList reports whether t is a list, nullable or not
+func isList(t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.Of
+	}
+	_, ok := t.(*List)
+	return ok
+}
+
+// isNull reports whether a resolved value is nil, directly or behind pointers
+func isNull(value interface{}) bool {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return true
+		}
+		rv = rv.Elem()
+	}
+	return !rv.IsValid()
+}
+
+func isLeaf(t Type) bool {
+	switch namedType(t).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+	return false
+}
+
+// Schema is the types reachable from the query root
+type Schema struct {
+	Query *Object
+	// MaxDepth caps how deeply selections may nest, 0 for no limit
+	MaxDepth int
+	// MaxAliases caps the aliased fields in an operation, 0 for no limit
+	MaxAliases int
+	// MaxCost caps the estimated number of objects a query resolves, 0 for
+	// no limit. An object field counts once per parent, and a list field
+	// multiplies the count of everything below it by its Size.
+	MaxCost int
+	// MaxNodes caps the fields an operation selects once its fragments are
+	// expanded in place, 0 for no limit. It bounds the work of validating,
+	// costing and collecting a query whose fragments spread each other.
+	MaxNodes int
+
+	typesOnce sync.Once
+	named     map[string]Type
+}
+
+// SDL prints the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	types := s.types()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("schema {\n  query: " + s.Query.Name + "\n}\n")
+	for _, name := range names {
+		if builtinScalars[name] {
+			continue
+		}
+		b.WriteString("\n")
+		switch t := types[name].(type) {
+		case *Scalar:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("scalar " + name + "\n")
+		case *Enum:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("enum " + name + " {\n")
+			for _, v := range t.Values {
+				b.WriteString("  " + v + "\n")
+			}
+			b.WriteString("}\n")
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("type " + name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, 0, len(f.Args))
+					for _, arg := range f.Args {
+						args = append(args, inputFieldSDL(arg))
+					}
+					b.WriteString("(" + strings.Join(args, ", ") + ")")
+				}
+				b.WriteString(": " + f.Type.String() + "\n")
+			}
+			b.WriteString("}\n")
+		case *InputObject:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("input " + name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + inputFieldSDL(f) + "\n")
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+func inputFieldSDL(f *InputField) string {
+	sdl := f.Name + ": " + f.Type.String()
+	if f.Default != nil {
+		sdl += " = " + literal(f.Type, f.Default)
+	}
+	return sdl
+}
+
+// literal prints a coerced value of type t as a GraphQL literal
+func literal(t Type, v interface{}) string {
+	switch typed := t.(type) {
+	case *NonNull:
+		return literal(typed.Of, v)
+	case *List:
+		values, _ := v.([]interface{})
+		items := make([]string, 0, len(values))
+		for _, item := range values {
+			items = append(items, literal(typed.Of, item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *Enum:
+		return fmt.Sprint(v)
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		b.WriteString(indent + fmt.Sprintf("%q", description) + "\n")
+		return
+	}
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(description, "\n") {
+		b.WriteString(indent + line + "\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+}
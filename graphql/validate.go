@@ -0,0 +1,208 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// validator checks an operation against the schema before it is executed
+type validator struct {
+	schema    *Schema
+	doc       *document
+	variables map[string]bool
+	errors    []*Error
+	tooDeep   bool
+	aliases   int
+	// nodes counts the fields selected with fragments expanded in place
+	nodes    int
+	tooLarge bool
+	// spread holds the expanded counts of each fragment already checked on
+	// a type at a depth, so a fragment spread many times is walked once
+	spread map[spreadKey]spreadCounts
+}
+
+type spreadKey struct {
+	fragment string
+	object   *Object
+	depth    int
+}
+
+type spreadCounts struct {
+	nodes   int
+	aliases int
+}
+
+func (s *Schema) validate(doc *document, op *operation) []*Error {
+	v := &validator{schema: s, doc: doc, variables: map[string]bool{}, spread: map[spreadKey]spreadCounts{}}
+	for _, def := range op.variables {
+		if v.variables[def.name] {
+			v.errorf(def.loc, "There can be only one variable named \"$%s\".", def.name)
+		}
+		v.variables[def.name] = true
+	}
+	v.directives(op.directives)
+	v.selections(s.Query, op.selections, 1, map[string]bool{})
+	return v.errors
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// selections checks a selection set on obj, nested depth objects deep;
+// spreading holds the fragments being spread, to catch cycles
+func (v *validator) selections(obj *Object, selections []selection, depth int, spreading map[string]bool) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			v.field(obj, s, depth, spreading)
+		case *fragmentSpread:
+			v.directives(s.directives)
+			frag, ok := v.doc.fragments[s.name]
+			if !ok {
+				v.errorf(s.loc, "Unknown fragment \"%s\".", s.name)
+				continue
+			}
+			if spreading[s.name] {
+				v.errorf(s.loc, "Cannot spread fragment \"%s\" within itself.", s.name)
+				continue
+			}
+			if frag.typeCondition != obj.Name {
+				v.errorf(s.loc, "Fragment \"%s\" cannot be spread here as objects of type \"%s\" can never be of type \"%s\".", s.name, obj.Name, frag.typeCondition)
+				continue
+			}
+			key := spreadKey{fragment: s.name, object: obj, depth: depth}
+			if counts, ok := v.spread[key]; ok {
+				v.count(s.loc, counts.nodes)
+				v.countAliases(s.loc, counts.aliases)
+				continue
+			}
+			v.directives(frag.directives)
+			nodes, aliases := v.nodes, v.aliases
+			spreading[s.name] = true
+			v.selections(obj, frag.selections, depth, spreading)
+			delete(spreading, s.name)
+			v.spread[key] = spreadCounts{nodes: v.nodes - nodes, aliases: v.aliases - aliases}
+		case *inlineFragment:
+			v.directives(s.directives)
+			if s.typeCondition != "" && s.typeCondition != obj.Name {
+				v.errorf(s.loc, "Fragment cannot be spread here as objects of type \"%s\" can never be of type \"%s\".", obj.Name, s.typeCondition)
+				continue
+			}
+			v.selections(obj, s.selections, depth, spreading)
+		}
+	}
+}
+
+// count adds n expanded fields, failing the query once it exceeds Schema.MaxNodes
+func (v *validator) count(loc Location, n int) {
+	v.nodes += n
+	if v.schema.MaxNodes > 0 && v.nodes > v.schema.MaxNodes && !v.tooLarge {
+		v.errorf(loc, "Query selects more than the maximum of %d fields once fragments are expanded.", v.schema.MaxNodes)
+		v.tooLarge = true
+	}
+}
+
+func (v *validator) countAliases(loc Location, n int) {
+	exceeded := v.schema.MaxAliases > 0 && v.aliases > v.schema.MaxAliases
+	v.aliases += n
+	if v.schema.MaxAliases > 0 && v.aliases > v.schema.MaxAliases && !exceeded {
+		v.errorf(loc, "Query uses more than the maximum of %d aliases.", v.schema.MaxAliases)
+	}
+}
+
+func (v *validator) field(obj *Object, f *field, depth int, spreading map[string]bool) {
+	if v.tooLarge {
+		return
+	}
+	v.count(f.loc, 1)
+	v.directives(f.directives)
+	if f.alias != "" {
+		v.countAliases(f.loc, 1)
+	}
+	if f.name == "__typename" {
+		if len(f.args) > 0 {
+			v.errorf(f.args[0].loc, "Unknown argument \"%s\" on field \"%s.__typename\".", f.args[0].name, obj.Name)
+		}
+		if len(f.selections) > 0 {
+			v.errorf(f.loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		return
+	}
+
+	def := obj.field(f.name)
+	if def == nil {
+		v.errorf(f.loc, "Cannot query field \"%s\" on type \"%s\".", f.name, obj.Name)
+		return
+	}
+	v.arguments(def.Args, f.args, fmt.Sprintf("field \"%s.%s\"", obj.Name, f.name), f.loc)
+
+	if isLeaf(def.Type) {
+		if len(f.selections) > 0 {
+			v.errorf(f.loc, "Field \"%s\" must not have a selection since type \"%s\" has no subfields.", f.name, def.Type)
+		}
+		return
+	}
+	if len(f.selections) == 0 {
+		v.errorf(f.loc, "Field \"%s\" of type \"%s\" must have a selection of subfields. Did you mean \"%s { ... }\"?", f.name, def.Type, f.name)
+		return
+	}
+	if v.schema.MaxDepth > 0 && depth >= v.schema.MaxDepth {
+		if !v.tooDeep {
+			v.errorf(f.loc, "Query is nested deeper than the maximum depth of %d.", v.schema.MaxDepth)
+			v.tooDeep = true
+		}
+		return
+	}
+	v.selections(namedType(def.Type).(*Object), f.selections, depth+1, spreading)
+}
+
+// arguments checks that args are known and required ones are given
+func (v *validator) arguments(defs []*InputField, args []*argument, on string, loc Location) {
+	given := map[string]bool{}
+	for _, arg := range args {
+		if given[arg.name] {
+			v.errorf(arg.loc, "There can be only one argument named \"%s\".", arg.name)
+		}
+		given[arg.name] = true
+		if findInput(defs, arg.name) == nil {
+			v.errorf(arg.loc, "Unknown argument \"%s\" on %s.", arg.name, on)
+		}
+		v.value(arg.value)
+	}
+	for _, def := range defs {
+		if _, nonNull := def.Type.(*NonNull); nonNull && def.Default == nil && !given[def.Name] {
+			v.errorf(loc, "Argument \"%s\" of type \"%s\" is required on %s, but it was not provided.", def.Name, def.Type, on)
+		}
+	}
+}
+
+func (v *validator) directives(directives []*directive) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.errorf(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		v.arguments(directiveArgs, d.args, "directive \"@"+d.name+"\"", d.loc)
+	}
+}
+
+// value checks that the variables a value uses are defined
+func (v *validator) value(node *valueNode) {
+	switch node.kind {
+	case variableValue:
+		if !v.variables[node.raw] {
+			v.errorf(node.loc, "Variable \"$%s\" is not defined.", node.raw)
+		}
+	case listValue:
+		for _, item := range node.list {
+			v.value(item)
+		}
+	case objectValue:
+		for _, f := range node.fields {
+			v.value(f.value)
+		}
+	}
+}
+
+// directiveArgs are the arguments of @include and @skip
+var directiveArgs = []*InputField{{Name: "if", Type: NonNullOf(Boolean)}}
@@ -0,0 +1,206 @@
+package grpcapi
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/events"
+	"institutionanalyser/grpcapi/analyserv1"
+	"institutionanalyser/handlers"
+	"institutionanalyser/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchJobPoll is how often WatchJob re-reads the job, catching changes whose
+// events were dropped or published by another replica
+const watchJobPoll = 15 * time.Second
+
+type deepSearchServer struct {
+	analyserv1.UnimplementedDeepSearchServer
+
+	deepSearch *handlers.DeepSearchHandler
+	hub        *events.Hub
+}
+
+func (s *deepSearchServer) TriggerAnalysis(ctx context.Context, req *analyserv1.TriggerAnalysisRequest) (*analyserv1.TriggerAnalysisResponse, error) {
+	triggerReq := handlers.TriggerRequest{
+		Ticker:        req.GetTicker(),
+		StartDuration: req.GetStartDuration(),
+		Preset:        req.GetPreset(),
+		VWAPAnchor:    req.GetVwapAnchor(),
+		Strategy:      req.GetStrategy(),
+	}
+	if req.GetAtrPeriod() != 0 {
+		triggerReq.ATRPeriod = strconv.Itoa(int(req.GetAtrPeriod()))
+	}
+
+	job, created, err := s.deepSearch.Trigger(ctx, currentUserID(ctx), triggerReq)
+	if err != nil {
+		return nil, err
+	}
+	return &analyserv1.TriggerAnalysisResponse{Job: jobMessage(job), Duplicate: !created}, nil
+}
+
+func (s *deepSearchServer) GetJob(ctx context.Context, req *analyserv1.GetJobRequest) (*analyserv1.Job, error) {
+	job, err := s.deepSearch.Job(ctx, ownerID(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, err
+	}
+	return jobMessage(job), nil
+}
+
+// WatchJob sends the job, then again whenever a job event or poll changes it,
+// until it completes or fails or the caller cancels
+func (s *deepSearchServer) WatchJob(req *analyserv1.GetJobRequest, stream grpc.ServerStreamingServer[analyserv1.Job]) error {
+	ctx := stream.Context()
+	userID := currentUserID(ctx)
+
+	// Subscribe before the first read so no change in between is missed
+	sub := s.hub.Subscribe(userID)
+	defer s.hub.Unsubscribe(sub)
+
+	job, err := s.deepSearch.Job(ctx, ownerID(ctx), uint(req.GetId()))
+	if err != nil {
+		return err
+	}
+	sub.Subscribe([]string{job.Ticker})
+
+	poll := time.NewTicker(watchJobPoll)
+	defer poll.Stop()
+
+	var sent *analyserv1.Job
+	for {
+		if msg := jobMessage(job); !proto.Equal(msg, sent) {
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			sent = msg
+		}
+		if job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "job events closed")
+			}
+			if updated, isJob := event.Data.(*models.AnalysisJob); isJob && updated.ID == job.ID {
+				job = updated
+			}
+		case <-poll.C:
+			if job, err = s.deepSearch.Job(ctx, ownerID(ctx), job.ID); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *deepSearchServer) GetAnalysis(ctx context.Context, req *analyserv1.GetAnalysisRequest) (*analyserv1.Analysis, error) {
+	signal, err := s.deepSearch.LatestAnalysis(ctx, ownerID(ctx), req.GetTicker(), req.GetStartDuration())
+	if err != nil {
+		return nil, err
+	}
+	if signal == nil {
+		return nil, status.Errorf(codes.NotFound, "no analysis of %s from %s", req.GetTicker(), req.GetStartDuration())
+	}
+	return analysisMessage(signal), nil
+}
+
+func (s *deepSearchServer) ListAnalyses(ctx context.Context, req *analyserv1.ListAnalysesRequest) (*analyserv1.ListAnalysesResponse, error) {
+	userID := req.GetUserId()
+	if owner := ownerID(ctx); owner != "" {
+		userID = owner
+	} else if userID == "me" {
+		userID = currentUserID(ctx)
+	}
+
+	signals, total, err := s.deepSearch.History(ctx, handlers.HistoryQuery{
+		Tickers:   req.GetTickers(),
+		Decisions: req.GetDecisions(),
+		UserID:    userID,
+		From:      req.GetFrom(),
+		To:        req.GetTo(),
+		Sort:      req.GetSort(),
+		Order:     req.GetOrder(),
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analyses := make([]*analyserv1.Analysis, 0, len(signals))
+	for i := range signals {
+		analyses = append(analyses, analysisMessage(&signals[i]))
+	}
+	return &analyserv1.ListAnalysesResponse{Analyses: analyses, Total: total}, nil
+}
+
+func jobMessage(job *models.AnalysisJob) *analyserv1.Job {
+	msg := &analyserv1.Job{
+		Id:            uint64(job.ID),
+		Status:        job.Status,
+		Ticker:        job.Ticker,
+		StartDuration: job.StartDuration,
+		EndDuration:   job.EndDuration,
+		Timespan:      job.TimeSpan,
+		Multiplier:    int32(job.Multiplier),
+		Attempts:      int32(job.Attempts),
+		Error:         job.Error,
+		CreatedAt:     timestamppb.New(job.CreatedAt),
+	}
+	if job.ResultID != nil {
+		msg.ResultId = uint64(*job.ResultID)
+	}
+	if job.StartedAt != nil {
+		msg.StartedAt = timestamppb.New(*job.StartedAt)
+	}
+	if job.FinishedAt != nil {
+		msg.FinishedAt = timestamppb.New(*job.FinishedAt)
+	}
+	return msg
+}
+
+func analysisMessage(signal *models.TechnicalSignal) *analyserv1.Analysis {
+	msg := &analyserv1.Analysis{
+		Id:               uint64(signal.ID),
+		Ticker:           signal.Ticker,
+		CreatedAt:        timestamppb.New(signal.CreatedAt),
+		StartDate:        timestamppb.New(signal.StartDate),
+		EndDate:          timestamppb.New(signal.EndDate),
+		Timespan:         signal.PolyTimeSpan,
+		Multiplier:       int32(signal.PolyMultiplier),
+		FinalDecision:    signal.FinalDecision,
+		Confidence:       signal.Confidence,
+		LastClose:        signal.LastClose,
+		Currency:         signal.Currency,
+		TrailingStop:     signal.TrailingStop,
+		TrailingStopSide: signal.TrailingStopSide,
+		AlgoVersion:      int32(signal.AlgoVersion),
+		Tags:             signal.Tags,
+		UserId:           signal.UserId,
+	}
+	for _, parsed := range deepsearch.ParseSignals(signal.Signals) {
+		msg.Signals = append(msg.Signals, &analyserv1.Signal{
+			Time:        parsed.Time,
+			Direction:   parsed.Direction,
+			Description: parsed.Description,
+			Close:       parsed.Close,
+			Regime:      parsed.Regime,
+			Adx:         parsed.ADX,
+			Reasons:     parsed.Reasons,
+			Raw:         parsed.Raw,
+		})
+	}
+	return msg
+}
@@ -0,0 +1,76 @@
+package grpcapi
+
+import (
+	"context"
+
+	"institutionanalyser/grpcapi/analyserv1"
+	"institutionanalyser/handlers"
+)
+
+type earningsServer struct {
+	analyserv1.UnimplementedEarningsServer
+
+	bigMoney *handlers.EarningsBigMoneyHandler
+}
+
+func (s *earningsServer) GetBigMoney(ctx context.Context, req *analyserv1.GetBigMoneyRequest) (*analyserv1.GetBigMoneyResponse, error) {
+	response, err := s.bigMoney.BigMoney(ctx, handlers.BigMoneyRequest{
+		Date:                req.GetDate(),
+		AnalysisDate:        req.GetAnalysisDate(),
+		LargeTradeThreshold: req.GetLargeTradeThreshold(),
+		Limit:               int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &analyserv1.GetBigMoneyResponse{
+		Date:          response.Date,
+		AnalysisDates: response.AnalysisDates,
+		Summary: &analyserv1.BigMoneySummary{
+			BullishCount:     int32(response.Summary.BullishCount),
+			BearishCount:     int32(response.Summary.BearishCount),
+			NeutralCount:     int32(response.Summary.NeutralCount),
+			ErrorCount:       int32(response.Summary.ErrorCount),
+			UnavailableCount: int32(response.Summary.UnavailableCount),
+			TotalAnalyzed:    int32(response.Summary.TotalAnalyzed),
+		},
+		Degraded:        response.Degraded,
+		DegradedReasons: response.Reasons,
+	}
+	for _, result := range response.Results {
+		msg.Results = append(msg.Results, bigMoneyResultMessage(result))
+	}
+	return msg, nil
+}
+
+func bigMoneyResultMessage(result handlers.EarningsBigMoneyResult) *analyserv1.BigMoneyResult {
+	msg := &analyserv1.BigMoneyResult{
+		Ticker:                result.Ticker,
+		Date:                  result.Date,
+		Time:                  result.Time,
+		Session:               result.Session,
+		EstimatedEps:          result.EstimatedEPS,
+		ActualEps:             result.ActualEPS,
+		EstimatedRevenue:      result.EstimatedRevenue,
+		ActualRevenue:         result.ActualRevenue,
+		Importance:            int32(result.Importance),
+		BigMoneyDirection:     result.BigMoneyDirection,
+		NetBigMoneyFlow:       result.NetBigMoneyFlow,
+		BuyerInitiatedVolume:  result.BuyerInitiatedVol,
+		SellerInitiatedVolume: result.SellerInitiatedVol,
+		LateRevisionPct:       result.LateRevisionPct,
+		SharpLateRevision:     result.SharpLateRevision,
+	}
+	if result.LargeTradesCount != nil {
+		count := int32(*result.LargeTradesCount)
+		msg.LargeTradesCount = &count
+	}
+	if result.AnalysisDate != nil {
+		msg.AnalysisDate = *result.AnalysisDate
+	}
+	if result.Error != nil {
+		msg.Error = *result.Error
+	}
+	return msg
+}
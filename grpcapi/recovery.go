@@ -0,0 +1,38 @@
+package grpcapi
+
+import (
+	"context"
+
+	"institutionanalyser/monitoring"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recoverUnary converts a handler panic into an Internal error and reports it,
+// as middleware.Recovery does for REST; grpc-go would otherwise let the panic
+// take down the whole process
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoveredError(ctx, info.FullMethod, recovered)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoverStream is recoverUnary for streaming methods
+func recoverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoveredError(stream.Context(), info.FullMethod, recovered)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+func recoveredError(ctx context.Context, method string, recovered interface{}) error {
+	monitoring.CapturePanic(recovered, currentUserID(ctx), map[string]string{"grpc_method": method})
+	return status.Error(codes.Internal, "Internal server error")
+}
@@ -0,0 +1,197 @@
+// Package grpcapi serves the deep search and earnings big money operations
+// over gRPC for internal orchestrators. The services are defined in
+// proto/analyser/v1/analyser.proto and share the REST handlers' operations, so
+// validation, defaults and errors match the REST endpoints.
+package grpcapi
+
+//go:generate protoc -I ../proto --go_out=analyserv1 --go_opt=paths=source_relative --go-grpc_out=analyserv1 --go-grpc_opt=paths=source_relative analyser/v1/analyser.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/events"
+	"institutionanalyser/grpcapi/analyserv1"
+	"institutionanalyser/handlers"
+	"institutionanalyser/jobs"
+	"institutionanalyser/middleware"
+	"institutionanalyser/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// methodScopes are the API key scopes each method requires, as on the
+// matching REST routes; methods not listed are not limited by scopes
+var methodScopes = map[string]string{
+	analyserv1.DeepSearch_TriggerAnalysis_FullMethodName: models.ScopeDeepsearchTrigger,
+	analyserv1.DeepSearch_GetJob_FullMethodName:          models.ScopeDeepsearchRead,
+	analyserv1.DeepSearch_WatchJob_FullMethodName:        models.ScopeDeepsearchRead,
+	analyserv1.DeepSearch_GetAnalysis_FullMethodName:     models.ScopeDeepsearchRead,
+	analyserv1.DeepSearch_ListAnalyses_FullMethodName:    models.ScopeDeepsearchRead,
+}
+
+// Server is the gRPC API server
+type Server struct {
+	grpc *grpc.Server
+	auth *middleware.Authenticator
+}
+
+// NewServer creates a server with the DeepSearch and Earnings services
+// registered. Callers authenticate with the REST API's credentials, sent as
+// "authorization: Bearer <token>" or "x-api-key" metadata.
+func NewServer(db *gorm.DB, queue *jobs.AnalysisQueue, hub *events.Hub) *Server {
+	s := &Server{auth: middleware.NewAuthenticator(db)}
+	// Recovery runs inside authorization so panics are reported with the caller
+	s.grpc = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor, recoverUnary),
+		grpc.ChainStreamInterceptor(s.streamInterceptor, recoverStream),
+	)
+
+	analyserv1.RegisterDeepSearchServer(s.grpc, &deepSearchServer{
+		deepSearch: handlers.NewDeepSearchHandler(db, queue, nil),
+		hub:        hub,
+	})
+	analyserv1.RegisterEarningsServer(s.grpc, &earningsServer{
+		bigMoney: handlers.NewEarningsBigMoneyHandler(db),
+	})
+	// Lets grpcurl and similar tools list and call the services
+	reflection.Register(s.grpc)
+
+	return s
+}
+
+// Serve listens on addr and serves until ctx is done, then stops gracefully
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.grpc.GracefulStop()
+	}()
+
+	return s.grpc.Serve(listener)
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	var resp interface{}
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err == nil {
+		resp, err = handler(ctx, req)
+		err = toStatus(err)
+	}
+	logCall(info.FullMethod, err, start)
+	return resp, err
+}
+
+func (s *Server) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, err := s.authorize(stream.Context(), info.FullMethod)
+	if err == nil {
+		err = toStatus(handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx}))
+	}
+	logCall(info.FullMethod, err, start)
+	return err
+}
+
+// authorizedStream carries the caller's identity in its context
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+type identityKey struct{}
+
+// authorize resolves the call's credentials and checks the method's scope,
+// returning a context carrying the caller's identity
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	bearer, _ := strings.CutPrefix(firstValue(md, "authorization"), "Bearer ")
+
+	identity, err := s.auth.Resolve(bearer, firstValue(md, middleware.APIKeyHeader))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if scope := methodScopes[method]; scope != "" && !identity.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "API key is missing scope "+scope)
+	}
+
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// currentUserID returns the caller's user, or the orchestrator account for
+// anonymous and shared-token calls
+func currentUserID(ctx context.Context) string {
+	if identity, ok := ctx.Value(identityKey{}).(middleware.Identity); ok && identity.UserID != "" {
+		return identity.UserID
+	}
+	return handlers.DefaultUserID
+}
+
+// ownerID returns the user whose data the caller may access, or "" for
+// admins, who may access every user's data
+func ownerID(ctx context.Context) string {
+	if identity, ok := ctx.Value(identityKey{}).(middleware.Identity); ok && identity.Admin {
+		return ""
+	}
+	return currentUserID(ctx)
+}
+
+// toStatus converts an operation error to a gRPC status: a RequestError by its
+// HTTP status, anything else that is not already a status as Internal
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var requestErr *handlers.RequestError
+	if !errors.As(err, &requestErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Internal
+	switch requestErr.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, requestErr.Error())
+}
+
+func logCall(method string, err error, start time.Time) {
+	fmt.Printf("[gRPC] %-16s | %13v | %s\n", status.Code(err), time.Since(start), method)
+}
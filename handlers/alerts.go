@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/alerts"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AlertsHandler manages a user's alert rules and notification channels and
+// shows their deliveries
+type AlertsHandler struct {
+	db *gorm.DB
+}
+
+// NewAlertsHandler creates a new alerts handler
+func NewAlertsHandler(db *gorm.DB) *AlertsHandler {
+	return &AlertsHandler{db: db}
+}
+
+// AlertRuleRequest is the body of POST /api/v1/alerts. On PATCH every field is optional.
+type AlertRuleRequest struct {
+	Name       *string  `json:"name"`
+	Type       *string  `json:"type"`
+	Ticker     *string  `json:"ticker"`
+	Decision   *string  `json:"decision"`
+	Metric     *string  `json:"metric"`
+	Operator   *string  `json:"operator"`
+	Threshold  *float64 `json:"threshold"`
+	WebhookURL *string  `json:"webhook_url"`
+	// ChannelID sends the rule's alerts to a notification channel; 0 clears it
+	ChannelID *uint `json:"channel_id"`
+	Enabled   *bool `json:"enabled"`
+	// Template renders the webhook body; "" restores the standard payload
+	Template *string `json:"template"`
+}
+
+// apply copies the fields set in the request onto rule
+func (r AlertRuleRequest) apply(rule *models.AlertRule) {
+	if r.Name != nil {
+		rule.Name = *r.Name
+	}
+	if r.Type != nil {
+		rule.Type = *r.Type
+	}
+	if r.Ticker != nil {
+		rule.Ticker = *r.Ticker
+	}
+	if r.Decision != nil {
+		rule.Decision = *r.Decision
+	}
+	if r.Metric != nil {
+		rule.Metric = *r.Metric
+	}
+	if r.Operator != nil {
+		rule.Operator = *r.Operator
+	}
+	if r.Threshold != nil {
+		rule.Threshold = *r.Threshold
+	}
+	if r.WebhookURL != nil {
+		rule.WebhookURL = *r.WebhookURL
+		if *r.WebhookURL != "" {
+			rule.ChannelID = nil
+		}
+	}
+	if r.ChannelID != nil {
+		rule.ChannelID = nil
+		if *r.ChannelID != 0 {
+			id := *r.ChannelID
+			rule.ChannelID = &id
+		}
+	}
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+	if r.Template != nil {
+		rule.Template = *r.Template
+	}
+}
+
+// findRule loads one of the current user's rules, writing the error response if it fails
+func (h *AlertsHandler) findRule(c *gin.Context) (*models.AlertRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule id"})
+		return nil, false
+	}
+
+	var rule models.AlertRule
+	if err := h.db.Where("user_id = ?", currentUserID(c)).First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// checkChannel verifies a rule's channel belongs to the current user, writing the error response if not
+func (h *AlertsHandler) checkChannel(c *gin.Context, rule *models.AlertRule) bool {
+	if rule.ChannelID == nil {
+		return true
+	}
+
+	var count int64
+	if err := h.db.Model(&models.NotificationChannel{}).Where("id = ? AND user_id = ?", *rule.ChannelID, currentUserID(c)).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notification channel not found"})
+		return false
+	}
+	return true
+}
+
+// HandleListAlertRules returns the current user's alert rules
+func (h *AlertsHandler) HandleListAlertRules(c *gin.Context) {
+	var rules []models.AlertRule
+	if err := h.db.Where("user_id = ?", currentUserID(c)).Order("created_at").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alert_rules": rules})
+}
+
+// HandleCreateAlertRule registers a rule; its signing secret is only returned in this response
+func (h *AlertsHandler) HandleCreateAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	rule := models.AlertRule{UserId: currentUserID(c), Enabled: true}
+	req.apply(&rule)
+	if err := alerts.NormalizeRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkChannel(c, &rule) {
+		return
+	}
+
+	secret, err := alerts.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rule.Secret = secret
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"alert_rule": rule, "secret": secret})
+}
+
+// HandleUpdateAlertRule changes a rule's conditions, destination or enabled flag
+func (h *AlertsHandler) HandleUpdateAlertRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	req.apply(rule)
+	if err := alerts.NormalizeRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkChannel(c, rule) {
+		return
+	}
+
+	// Select all columns so false/zero values (enabled, threshold) are written
+	if err := h.db.Model(rule).Select("*").Omit("id", "created_at", "user_id", "secret").Updates(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alert_rule": rule})
+}
+
+// HandleDeleteAlertRule removes a rule; its queued deliveries fail on their next attempt
+func (h *AlertsHandler) HandleDeleteAlertRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted"})
+}
+
+// HandleListAlertDeliveries lists a rule's deliveries, newest first
+// Query parameters:
+//   - status: pending, delivered or failed (optional)
+//   - limit/offset: Pagination (default 50, max 500)
+func (h *AlertsHandler) HandleListAlertDeliveries(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.AlertDelivery{}).Where("rule_id = ?", rule.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 50, 500)
+	var deliveries []models.AlertDelivery
+	if err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(deliveries),
+		},
+	})
+}
+
+// NotificationChannelRequest is the body of POST /api/v1/alerts/channels
+type NotificationChannelRequest struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	WebhookURL string `json:"webhook_url"`
+	BotToken   string `json:"bot_token"`
+	ChatID     string `json:"chat_id"`
+	EmailTo    string `json:"email_to"`
+	Template   string `json:"template"`
+}
+
+// NotificationChannelUpdate is the body of PATCH /api/v1/alerts/channels/:id;
+// only the fields present are changed
+type NotificationChannelUpdate struct {
+	Name     *string `json:"name"`
+	EmailTo  *string `json:"email_to"`
+	Template *string `json:"template"`
+}
+
+// TemplatePreviewRequest is the body of POST /api/v1/alerts/templates/preview
+type TemplatePreviewRequest struct {
+	// Kind is "webhook" or a channel type
+	Kind     string `json:"kind"`
+	Template string `json:"template"`
+}
+
+// findChannel loads one of the current user's channels, writing the error response if it fails
+func (h *AlertsHandler) findChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification channel id"})
+		return nil, false
+	}
+
+	var channel models.NotificationChannel
+	if err := h.db.Where("user_id = ?", currentUserID(c)).First(&channel, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &channel, true
+}
+
+// HandleListNotificationChannels returns the current user's notification channels
+func (h *AlertsHandler) HandleListNotificationChannels(c *gin.Context) {
+	var channels []models.NotificationChannel
+	if err := h.db.Where("user_id = ?", currentUserID(c)).Order("created_at").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels})
+}
+
+// HandleCreateNotificationChannel registers a Slack, Discord or Telegram channel
+func (h *AlertsHandler) HandleCreateNotificationChannel(c *gin.Context) {
+	var req NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	channel := models.NotificationChannel{
+		UserId:     currentUserID(c),
+		Name:       req.Name,
+		Type:       req.Type,
+		WebhookURL: req.WebhookURL,
+		BotToken:   req.BotToken,
+		ChatID:     req.ChatID,
+		EmailTo:    req.EmailTo,
+		Template:   req.Template,
+	}
+	if err := alerts.NormalizeChannel(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"channel": channel})
+}
+
+// HandleUpdateNotificationChannel changes a channel's name, email recipients
+// or template. Credentials cannot be changed; create a new channel instead.
+func (h *AlertsHandler) HandleUpdateNotificationChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	var req NotificationChannelUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		channel.Name = *req.Name
+	}
+	if req.EmailTo != nil {
+		channel.EmailTo = *req.EmailTo
+	}
+	if req.Template != nil {
+		channel.Template = *req.Template
+	}
+	if err := alerts.NormalizeChannel(channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(channel).Select("name", "email_to", "template").Updates(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channel": channel})
+}
+
+// HandlePreviewTemplate renders a notification template against a sample
+// alert without sending anything, to try out formatting
+func (h *AlertsHandler) HandlePreviewTemplate(c *gin.Context) {
+	var req TemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	switch kind {
+	case alerts.TemplateWebhook, models.ChannelSlack, models.ChannelDiscord, models.ChannelTelegram, models.ChannelEmail:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be webhook, slack, discord, telegram or email"})
+		return
+	}
+
+	rendered, err := alerts.RenderTemplate(kind, req.Template, alerts.SamplePayload())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid template", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"kind": kind, "rendered": string(rendered), "payload": alerts.SamplePayload()})
+}
+
+// HandleDeleteNotificationChannel removes a channel. Rules still pointing at it
+// are rejected with 409 so they are not left without a destination.
+func (h *AlertsHandler) HandleDeleteNotificationChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	var rules int64
+	if err := h.db.Model(&models.AlertRule{}).Where("channel_id = ?", channel.ID).Count(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rules > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Notification channel is used by alert rules", "details": fmt.Sprintf("%d rule(s) still send to this channel", rules)})
+		return
+	}
+
+	if err := h.db.Delete(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted"})
+}
+
+// HandleTestNotificationChannel sends a sample alert to a channel right away,
+// without queueing or retrying it
+func (h *AlertsHandler) HandleTestNotificationChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	notifier, err := alerts.NewChannelNotifier(*channel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, _ := json.Marshal(alerts.SamplePayload())
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), alerts.GetDispatcherConfig().RequestTimeout)
+	defer cancel()
+	if _, err := notifier.Send(ctx, &models.AlertDelivery{Payload: body}); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Test notification failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent"})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AnalyticsHandler serves views across stored analyses
+type AnalyticsHandler struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(db *gorm.DB) *AnalyticsHandler {
+	return &AnalyticsHandler{db: db}
+}
+
+// HandleGetHeatmap returns a ticker by market day matrix of stored decisions
+// and confidence, as JSON or a rendered PNG
+// Query parameters:
+//   - from, to: Market days, YYYY-MM-DD (default: the 30 days up to today, at most 366 days)
+//   - tickers: Comma-separated tickers, in row order (default: every analysed ticker, at most 100)
+//   - tag: Only analyses carrying this tag (optional)
+//   - format: json (default) or png
+func (h *AnalyticsHandler) HandleGetHeatmap(c *gin.Context) {
+	to := time.Now().In(jobs.MarketTimezone)
+	if val := c.Query("to"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to format, use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if val := c.Query("from"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from format, use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to cannot be before from"})
+		return
+	}
+	if to.Sub(from) >= deepsearch.MaxHeatmapDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The heatmap cannot span more than %d days", deepsearch.MaxHeatmapDays)})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "png" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or png"})
+		return
+	}
+
+	var filter deepsearch.SignalFilter
+	if val := c.Query("tickers"); val != "" {
+		filter.Tickers = strings.Split(val, ",")
+	}
+	if tag := c.Query("tag"); tag != "" {
+		filter.Tags = []string{tag}
+	}
+	if err := filter.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(filter.Tickers) > deepsearch.MaxHeatmapTickers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tickers cannot exceed %d entries", deepsearch.MaxHeatmapTickers)})
+		return
+	}
+
+	// Grouping by market day needs Postgres's time zone conversion
+	if models.IsSQLite(h.db) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "The signal heatmap needs a Postgres database"})
+		return
+	}
+
+	heatmap, err := deepsearch.ComputeSignalHeatmap(c.Request.Context(), ownedBy(h.db, ownerID(c)), from, to, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute heatmap", "details": err.Error()})
+		return
+	}
+
+	if format == "png" {
+		var buf bytes.Buffer
+		if err := deepsearch.RenderHeatmapPNG(heatmap, &buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render heatmap", "details": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "image/png", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, heatmap)
+}
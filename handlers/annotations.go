@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTagsPerAnalysis = 20
+	maxTagLength       = 50
+	maxNotesLength     = 10000
+)
+
+// AnnotationsHandler manages analyst tags and notes on stored analyses
+type AnnotationsHandler struct {
+	db *gorm.DB
+}
+
+// NewAnnotationsHandler creates a new annotations handler
+func NewAnnotationsHandler(db *gorm.DB) *AnnotationsHandler {
+	return &AnnotationsHandler{db: db}
+}
+
+// UpdateAnnotationsRequest is a partial update; omitted fields are left unchanged
+type UpdateAnnotationsRequest struct {
+	Tags  *[]string `json:"tags"`
+	Notes *string   `json:"notes"`
+}
+
+// HandleUpdateAnnotations sets tags and/or notes on one of the caller's analyses
+// Body: {"tags": ["earnings-play"], "notes": "..."}
+func (h *AnnotationsHandler) HandleUpdateAnnotations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return
+	}
+
+	var req UpdateAnnotationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Tags == nil && req.Notes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags or notes is required"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["tags"] = models.StringArray(tags)
+	}
+	if req.Notes != nil {
+		if len(*req.Notes) > maxNotesLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notes cannot exceed 10000 characters"})
+			return
+		}
+		updates["notes"] = *req.Notes
+	}
+
+	var signal models.TechnicalSignal
+	if err := ownedBy(h.db, ownerID(c)).First(&signal, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&signal).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.db.First(&signal, id)
+
+	c.JSON(http.StatusOK, gin.H{"analysis": signal})
+}
+
+// HandleListByTag returns the caller's analyses carrying a tag
+// Query parameters:
+//   - tag: Tag to filter by (required)
+//   - ticker: Optional ticker filter
+//   - archived: List archived analyses instead (default: false)
+//   - limit: Maximum number of results (default: 100, max: 1000)
+func (h *AnnotationsHandler) HandleListByTag(c *gin.Context) {
+	tag := strings.ToLower(strings.TrimSpace(c.Query("tag")))
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag is required"})
+		return
+	}
+
+	limit := 100
+	if parsed, err := strconv.Atoi(c.DefaultQuery("limit", "100")); err == nil && parsed > 0 {
+		limit = parsed
+		if limit > 1000 {
+			limit = 1000
+		}
+	}
+
+	filter := deepsearch.SignalFilter{Tags: []string{tag}}
+	if ticker := c.Query("ticker"); ticker != "" {
+		filter.Tickers = []string{strings.ToUpper(ticker)}
+	}
+
+	var signals []models.TechnicalSignal
+	query := withArchived(filter.Apply(ownedBy(models.ReadReplica(h.db), ownerID(c))), c.Query("archived") == "true")
+	if err := query.Order("created_at desc").Limit(limit).Find(&signals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"analyses": signals, "count": len(signals), "tag": tag})
+}
+
+// TagCount is a tag and the number of analyses carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// HandleListTags returns every tag on the caller's analyses with its usage count
+func (h *AnnotationsHandler) HandleListTags(c *gin.Context) {
+	tags := []TagCount{}
+	db := models.ReadReplica(h.db)
+	err := ownedBy(db.Table("technical_signals, "+models.ArrayElements(db, "tags")), ownerID(c)).
+		Where("deleted_at IS NULL").
+		Select("value AS tag, COUNT(*) AS count").
+		Group("value").
+		Order("count DESC, tag").
+		Scan(&tags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags and enforces limits
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if len(t) > maxTagLength {
+			return nil, errors.New("tags cannot exceed 50 characters")
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	if len(normalized) > maxTagsPerAnalysis {
+		return nil, errors.New("an analysis cannot have more than 20 tags")
+	}
+	return normalized, nil
+}
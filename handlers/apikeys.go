@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/middleware"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeysHandler lets users create, list and revoke API keys for machine clients
+type APIKeysHandler struct {
+	db *gorm.DB
+}
+
+// NewAPIKeysHandler creates a new API keys handler
+func NewAPIKeysHandler(db *gorm.DB) *APIKeysHandler {
+	return &APIKeysHandler{db: db}
+}
+
+// CreateAPIKeyRequest is the body of POST /api/v1/apikeys
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// requireUserSession ensures key management is done by a logged-in user, not by another key
+func requireUserSession(c *gin.Context) (string, bool) {
+	if c.GetString(middleware.AuthMethodKey) != middleware.AuthMethodJWT {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys can only be managed with a user token"})
+		return "", false
+	}
+	return c.GetString(middleware.UserIDKey), true
+}
+
+// HandleCreateAPIKey generates a key; the plaintext is only returned in this response
+func (h *APIKeysHandler) HandleCreateAPIKey(c *gin.Context) {
+	userID, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// An admin key grants access to every user's data, so only admins may create one
+	for _, s := range scopes {
+		if s == models.ScopeAdmin && !c.GetBool(middleware.AdminKey) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create keys with the admin scope"})
+			return
+		}
+	}
+
+	key, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	apiKey := models.APIKey{
+		UserId:  userID,
+		Name:    strings.TrimSpace(req.Name),
+		Prefix:  key[:len(middleware.APIKeyPrefix)+8],
+		KeyHash: hash,
+		Scopes:  models.StringArray(scopes),
+	}
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"api_key": apiKey, "key": key})
+}
+
+// HandleListAPIKeys returns the current user's keys, including revoked ones
+func (h *APIKeysHandler) HandleListAPIKeys(c *gin.Context) {
+	userID, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	if err := h.db.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// HandleRevokeAPIKey revokes one of the current user's keys
+func (h *APIKeysHandler) HandleRevokeAPIKey(c *gin.Context) {
+	userID, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key id"})
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := h.db.Where("user_id = ?", userID).First(&apiKey, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		if err := h.db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_key": apiKey})
+}
+
+// normalizeScopes validates requested scopes and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	valid := make(map[string]bool, len(models.APIKeyScopes))
+	for _, s := range models.APIKeyScopes {
+		valid[s] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if !valid[s] {
+			return nil, errors.New("unknown scope: " + s + " (valid: " + strings.Join(models.APIKeyScopes, ", ") + ")")
+		}
+		if !seen[s] {
+			seen[s] = true
+			normalized = append(normalized, s)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ArchiveHandler archives, restores and soft-deletes stored analyses, so users
+// can clean up their history while the rows are kept for audit
+type ArchiveHandler struct {
+	db *gorm.DB
+}
+
+// NewArchiveHandler creates a new archive handler
+func NewArchiveHandler(db *gorm.DB) *ArchiveHandler {
+	return &ArchiveHandler{db: db}
+}
+
+// withArchived keeps only archived analyses when archived is set, else only
+// unarchived ones
+func withArchived(query *gorm.DB, archived bool) *gorm.DB {
+	if archived {
+		return query.Where("archived_at IS NOT NULL")
+	}
+	return query.Where("archived_at IS NULL")
+}
+
+// HandleArchiveAnalysis archives one of the caller's analyses, leaving it out
+// of history listings
+func (h *ArchiveHandler) HandleArchiveAnalysis(c *gin.Context) {
+	analysis, ok := h.analysis(c, h.db)
+	if !ok {
+		return
+	}
+	if analysis.ArchivedAt == nil {
+		now := time.Now()
+		if err := h.db.Model(analysis).Update("archived_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive analysis", "details": err.Error()})
+			return
+		}
+		analysis.ArchivedAt = &now
+	}
+
+	c.JSON(http.StatusOK, gin.H{"analysis": analysis})
+}
+
+// HandleRestoreAnalysis brings one of the caller's archived or deleted
+// analyses back into history
+func (h *ArchiveHandler) HandleRestoreAnalysis(c *gin.Context) {
+	analysis, ok := h.analysis(c, h.db.Unscoped())
+	if !ok {
+		return
+	}
+	err := h.db.Unscoped().Model(analysis).Updates(map[string]interface{}{"archived_at": nil, "deleted_at": nil}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore analysis", "details": err.Error()})
+		return
+	}
+	analysis.ArchivedAt = nil
+	analysis.DeletedAt = gorm.DeletedAt{}
+
+	c.JSON(http.StatusOK, gin.H{"analysis": analysis})
+}
+
+// HandleDeleteAnalysis soft-deletes one of the caller's analyses: it
+// disappears from every endpoint, but the row is kept and can be restored
+func (h *ArchiveHandler) HandleDeleteAnalysis(c *gin.Context) {
+	analysis, ok := h.analysis(c, h.db)
+	if !ok {
+		return
+	}
+	if err := h.db.Delete(analysis).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete analysis", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Analysis deleted"})
+}
+
+// HandleArchiveHistory archives the caller's analyses run before a date in one go
+// Query parameters:
+//   - before: Archive analyses run before this date, YYYY-MM-DD (required)
+//   - ticker: Comma-separated tickers (optional)
+func (h *ArchiveHandler) HandleArchiveHistory(c *gin.Context) {
+	before, err := time.ParseInLocation("2006-01-02", c.Query("before"), jobs.MarketTimezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before is required, use YYYY-MM-DD"})
+		return
+	}
+
+	query := ownedBy(h.db.Model(&models.TechnicalSignal{}), ownerID(c)).
+		Where("archived_at IS NULL AND created_at < ?", before)
+	if val := c.Query("ticker"); val != "" {
+		query = query.Where("ticker IN ?", normalizeTickers(strings.Split(val, ",")))
+	}
+	result := query.Update("archived_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive analyses", "details": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"archived": result.RowsAffected})
+}
+
+// analysis loads the caller's analysis named by the id path parameter through
+// db, writing the error response and returning false when it cannot
+func (h *ArchiveHandler) analysis(c *gin.Context, db *gorm.DB) (*models.TechnicalSignal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return nil, false
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(db, ownerID(c)).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &analysis, true
+}
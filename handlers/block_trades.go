@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// largestBlocks is how many blocks a detection returns, largest first
+const largestBlocks = 10
+
+// BlockTradesHandler serves block trades detected on the trade tape
+type BlockTradesHandler struct {
+	db *gorm.DB
+}
+
+// NewBlockTradesHandler creates a new block trades handler
+func NewBlockTradesHandler(db *gorm.DB) *BlockTradesHandler {
+	return &BlockTradesHandler{db: db}
+}
+
+// HandleGetBlockTrades returns a ticker's stored block trade sessions, most
+// recent first, or one session with its blocks
+// Query parameters:
+//   - date: Session date, YYYY-MM-DD; returns that session and its blocks
+//   - side: With date, only BUY or SELL blocks (optional)
+//   - sort: With date, blocks by time (default) or notional, largest first
+//   - limit: Sessions or blocks per page (default: 20, max 500)
+//   - offset: Number of sessions or blocks to skip (default: 0)
+func (h *BlockTradesHandler) HandleGetBlockTrades(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	limit, offset := parsePagination(c, 20, 500)
+
+	if val := c.Query("date"); val != "" {
+		date, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		day := date.Format("2006-01-02")
+
+		side := strings.ToUpper(c.Query("side"))
+		if side != "" && side != models.BlockSideBuy && side != models.BlockSideSell {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "side must be BUY or SELL"})
+			return
+		}
+		order := "timestamp"
+		switch c.DefaultQuery("sort", "time") {
+		case "time":
+		case "notional":
+			order = "notional DESC"
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be time or notional"})
+			return
+		}
+
+		var session models.BlockTradeSession
+		err = h.db.Where("ticker = ? AND day = ?", ticker, day).First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No block trades stored for this session"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch block trade session", "details": err.Error()})
+			return
+		}
+
+		query := h.db.Model(&models.BlockTrade{}).Where("ticker = ? AND day = ?", ticker, day)
+		if side != "" {
+			query = query.Where("side = ?", side)
+		}
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count block trades", "details": err.Error()})
+			return
+		}
+		var blocks []models.BlockTrade
+		if err := query.Order(order).Limit(limit).Offset(offset).Find(&blocks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch block trades", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"session": session,
+			"blocks":  blocks,
+			"pagination": gin.H{
+				"total":  total,
+				"limit":  limit,
+				"offset": offset,
+				"count":  len(blocks),
+			},
+		})
+		return
+	}
+
+	query := h.db.Model(&models.BlockTradeSession{}).Where("ticker = ?", ticker)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count block trade sessions", "details": err.Error()})
+		return
+	}
+	var sessions []models.BlockTradeSession
+	if err := query.Order("day DESC").Limit(limit).Offset(offset).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch block trade sessions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sessions,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(sessions),
+		},
+	})
+}
+
+// HandleDetectBlockTrades reads a session's trade tape, stores its block
+// trades, replacing those stored for the session, and returns the session
+// with its largest blocks
+// Query parameters:
+//   - date: Session date, YYYY-MM-DD (default: the last completed session)
+//   - min_notional: Dollar value a print needs to count as a block (default: BLOCK_TRADE_MIN_NOTIONAL or 200000)
+//   - timespan: Bar size unit the institutional flow flags are verified at, second, minute or hour (default: minute)
+//   - multiplier: Bar size multiplier (default: 1)
+func (h *BlockTradesHandler) HandleDetectBlockTrades(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	date := deepsearch.LastCompletedSession(c.Request.Context(), time.Now())
+	if val := c.Query("date"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		if !service.DefaultTradingCalendar().IsTradingDay(c.Request.Context(), parsed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date is not a trading day"})
+			return
+		}
+		date = parsed
+	}
+
+	minNotional := deepsearch.BlockTradeMinNotional()
+	if val := c.Query("min_notional"); val != "" {
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_notional must be a positive number"})
+			return
+		}
+		minNotional = n
+	}
+
+	timeSpan := c.DefaultQuery("timespan", "minute")
+	if timeSpan != "second" && timeSpan != "minute" && timeSpan != "hour" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timespan must be second, minute or hour"})
+		return
+	}
+	multiplier, err := strconv.Atoi(c.DefaultQuery("multiplier", "1"))
+	if err != nil || multiplier <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multiplier must be a positive integer"})
+		return
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, blocks, err := jobs.RefreshBlockTrades(c.Request.Context(), h.db, ticker, date, minNotional, timeSpan, multiplier)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to detect block trades", "details": err.Error()})
+		return
+	}
+
+	largest := append([]models.BlockTrade{}, blocks...)
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Notional > largest[j].Notional })
+	if len(largest) > largestBlocks {
+		largest = largest[:largestBlocks]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":        session,
+		"largest_blocks": largest,
+	})
+}
@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/broker"
+	"institutionanalyser/middleware"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BrokerHandler manages a user's broker account, execution rules, orders and
+// kill switch
+type BrokerHandler struct {
+	db *gorm.DB
+}
+
+// NewBrokerHandler creates a new broker handler
+func NewBrokerHandler(db *gorm.DB) *BrokerHandler {
+	return &BrokerHandler{db: db}
+}
+
+// BrokerAccountRequest is the body of PUT /api/v1/broker/account
+type BrokerAccountRequest struct {
+	// Broker is the broker the keys are for; only alpaca is supported
+	Broker    string `json:"broker"`
+	KeyID     string `json:"key_id" binding:"required"`
+	SecretKey string `json:"secret_key" binding:"required"`
+	// Paper routes orders to the paper trading environment (default: true)
+	Paper *bool `json:"paper"`
+}
+
+// ExecutionRuleRequest is the body of POST /api/v1/broker/rules. On PATCH
+// every field is optional and the strategy cannot change.
+type ExecutionRuleRequest struct {
+	StrategyID    *uint    `json:"strategy_id"`
+	Ticker        *string  `json:"ticker"`
+	Quantity      *float64 `json:"quantity"`
+	Notional      *float64 `json:"notional"`
+	MinConfidence *float64 `json:"min_confidence"`
+	BuyOnly       *bool    `json:"buy_only"`
+	Enabled       *bool    `json:"enabled"`
+}
+
+// apply copies the fields set in the request onto rule
+func (r ExecutionRuleRequest) apply(rule *models.ExecutionRule) {
+	if r.Ticker != nil {
+		rule.Ticker = strings.ToUpper(strings.TrimSpace(*r.Ticker))
+	}
+	if r.Quantity != nil {
+		rule.Quantity = *r.Quantity
+	}
+	if r.Notional != nil {
+		rule.Notional = *r.Notional
+	}
+	if r.MinConfidence != nil {
+		rule.MinConfidence = *r.MinConfidence
+	}
+	if r.BuyOnly != nil {
+		rule.BuyOnly = *r.BuyOnly
+	}
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+}
+
+// validateExecutionRule checks a rule sizes its orders one way
+func validateExecutionRule(rule *models.ExecutionRule) error {
+	if rule.Quantity < 0 || rule.Notional < 0 {
+		return errors.New("quantity and notional cannot be negative")
+	}
+	if (rule.Quantity > 0) == (rule.Notional > 0) {
+		return errors.New("set exactly one of quantity or notional")
+	}
+	if rule.MinConfidence < 0 || rule.MinConfidence > 1 {
+		return errors.New("min_confidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// requireBrokerUser returns the signed-in user, writing a 401 unless the
+// request carries a user token. Broker keys and execution rules place real
+// orders, so unlike other routes these never fall back to the default user
+// when auth is off, and API keys cannot manage them.
+func requireBrokerUser(c *gin.Context) (string, bool) {
+	if c.GetString(middleware.AuthMethodKey) != middleware.AuthMethodJWT {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "The broker integration can only be managed with a user token"})
+		return "", false
+	}
+	return c.GetString(middleware.UserIDKey), true
+}
+
+// KillSwitchRequest is the optional body of POST /api/v1/broker/kill-switch
+type KillSwitchRequest struct {
+	Reason string `json:"reason"`
+}
+
+// HandleGetBrokerAccount returns the current user's broker account and
+// whether a kill switch stops its orders
+func (h *BrokerHandler) HandleGetBrokerAccount(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	var account models.BrokerAccount
+	err := h.db.Where("user_id = ?", userID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No broker account"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	halted, err := broker.Halted(c.Request.Context(), h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"account":         account,
+		"halted":          halted,
+		"routing_enabled": broker.GetConfig().Enabled,
+	})
+}
+
+// HandleSaveBrokerAccount stores the current user's broker keys, sealed,
+// replacing any saved before. The keys are checked against the broker first.
+func (h *BrokerHandler) HandleSaveBrokerAccount(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	if !broker.EncryptionConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Broker credentials cannot be stored", "details": broker.ErrNoEncryptionKey.Error()})
+		return
+	}
+
+	var req BrokerAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Broker != "" && req.Broker != models.BrokerAlpaca {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "broker must be alpaca"})
+		return
+	}
+	paper := req.Paper == nil || *req.Paper
+	keyID, secret := strings.TrimSpace(req.KeyID), strings.TrimSpace(req.SecretKey)
+
+	remote, err := broker.NewAlpacaClient(paper, keyID, secret).Account(c.Request.Context())
+	var apiErr *broker.AlpacaError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Broker rejected the keys", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reach the broker", "details": err.Error()})
+		return
+	}
+
+	account := models.BrokerAccount{
+		UserId:        userID,
+		Broker:        models.BrokerAlpaca,
+		Paper:         paper,
+		KeyIDHint:     keyIDHint(keyID),
+		AccountNumber: remote.AccountNumber,
+	}
+	if account.SealedKeyID, err = broker.Seal(keyID); err == nil {
+		account.SealedSecret, err = broker.Seal(secret)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"broker", "paper", "key_id_hint", "sealed_key_id", "sealed_secret", "account_number", "updated_at"}),
+	}).Create(&account).Error
+	if err == nil {
+		err = h.db.Where("user_id = ?", account.UserId).First(&account).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"account": account, "trading_blocked": remote.TradingBlocked})
+}
+
+// HandleDeleteBrokerAccount removes the current user's broker keys. Orders
+// already placed stay at the broker and are no longer synced.
+func (h *BrokerHandler) HandleDeleteBrokerAccount(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	result := h.db.Where("user_id = ?", userID).Delete(&models.BrokerAccount{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No broker account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Broker account deleted"})
+}
+
+// HandleListExecutionRules returns the current user's execution rules
+func (h *BrokerHandler) HandleListExecutionRules(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	rules := []models.ExecutionRule{}
+	if err := h.db.Where("user_id = ?", userID).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// HandleCreateExecutionRule creates an execution rule for one of the current
+// user's strategies
+func (h *BrokerHandler) HandleCreateExecutionRule(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	var req ExecutionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.StrategyID == nil || *req.StrategyID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy_id is required"})
+		return
+	}
+
+	var count int64
+	if err := h.db.Model(&models.Strategy{}).Where("id = ? AND user_id = ?", *req.StrategyID, userID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Strategy not found"})
+		return
+	}
+
+	rule := models.ExecutionRule{UserId: userID, StrategyID: *req.StrategyID, Enabled: true}
+	req.apply(&rule)
+	if err := validateExecutionRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+// HandleUpdateExecutionRule changes the fields of an execution rule present in the body
+func (h *BrokerHandler) HandleUpdateExecutionRule(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	rule, found := h.findRule(c, userID)
+	if !found {
+		return
+	}
+
+	var req ExecutionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.StrategyID != nil && *req.StrategyID != rule.StrategyID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy_id cannot be changed"})
+		return
+	}
+	req.apply(rule)
+	if err := validateExecutionRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Select all columns so false/zero values (enabled, quantity) are written
+	if err := h.db.Model(rule).Select("*").Omit("id", "created_at", "user_id", "strategy_id").Updates(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+// HandleDeleteExecutionRule removes an execution rule
+func (h *BrokerHandler) HandleDeleteExecutionRule(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	rule, found := h.findRule(c, userID)
+	if !found {
+		return
+	}
+
+	if err := h.db.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Execution rule deleted"})
+}
+
+// findRule loads one of userID's execution rules, writing the error response if it fails
+func (h *BrokerHandler) findRule(c *gin.Context, userID string) (*models.ExecutionRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution rule id"})
+		return nil, false
+	}
+
+	var rule models.ExecutionRule
+	if err := h.db.Where("user_id = ?", userID).First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution rule not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return &rule, true
+}
+
+// HandleListBrokerOrders lists the current user's broker orders, newest first
+// Query parameters:
+//   - status: pending, failed or a broker status such as filled (optional)
+//   - ticker: Only orders for this ticker (optional)
+//   - limit/offset: Pagination (default 100, max 1000)
+func (h *BrokerHandler) HandleListBrokerOrders(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.BrokerOrder{}).Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if ticker := c.Query("ticker"); ticker != "" {
+		query = query.Where("ticker = ?", strings.ToUpper(ticker))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	orders := []models.BrokerOrder{}
+	if err := query.Order("id desc").Limit(limit).Offset(offset).Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": orders,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(orders),
+		},
+	})
+}
+
+// HandleEngageKillSwitch stops order routing for the current user and
+// cancels their open orders
+func (h *BrokerHandler) HandleEngageKillSwitch(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+	h.engage(c, userID)
+}
+
+// HandleReleaseKillSwitch resumes order routing for the current user
+func (h *BrokerHandler) HandleReleaseKillSwitch(c *gin.Context) {
+	userID, ok := requireBrokerUser(c)
+	if !ok {
+		return
+	}
+	h.release(c, userID)
+}
+
+// HandleEngageGlobalKillSwitch stops order routing for every user and
+// cancels every open order
+func (h *BrokerHandler) HandleEngageGlobalKillSwitch(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	h.engage(c, "")
+}
+
+// HandleReleaseGlobalKillSwitch releases the global kill switch; users' own
+// kill switches stay engaged
+func (h *BrokerHandler) HandleReleaseGlobalKillSwitch(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	h.release(c, "")
+}
+
+func (h *BrokerHandler) engage(c *gin.Context, userID string) {
+	var req KillSwitchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+	}
+
+	halt, cancelled, err := broker.Halt(c.Request.Context(), h.db, userID, req.Reason, currentUserID(c))
+	if halt == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to engage kill switch", "details": err.Error()})
+		return
+	}
+	response := gin.H{"halt": halt, "cancelled_orders": cancelled}
+	if err != nil {
+		response["cancel_errors"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *BrokerHandler) release(c *gin.Context, userID string) {
+	released, err := broker.Resume(c.Request.Context(), h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !released {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kill switch is not engaged"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Kill switch released"})
+}
+
+// keyIDHint keeps the last four characters of a key ID
+func keyIDHint(keyID string) string {
+	if len(keyID) <= 4 {
+		return keyID
+	}
+	return "..." + keyID[len(keyID)-4:]
+}
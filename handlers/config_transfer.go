@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/alerts"
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfigBundleVersion is bumped whenever the bundle layout changes incompatibly.
+// Version 2 added strategies, notification channels and alert rules; version 1
+// documents (presets only) are still accepted.
+const ConfigBundleVersion = 2
+
+// ConfigBundle is the portable JSON document holding a user's configuration.
+// A section missing from an imported document is left untouched, even in replace mode.
+type ConfigBundle struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Presets    []PresetConfig    `json:"presets"`
+	Strategies []StrategyConfig  `json:"strategies"`
+	Channels   []ChannelConfig   `json:"channels"`
+	AlertRules []AlertRuleConfig `json:"alert_rules"`
+}
+
+// StrategyConfig is the portable form of a Strategy
+type StrategyConfig struct {
+	Name           string                `json:"name"`
+	Description    string                `json:"description,omitempty"`
+	IncludeBuiltin bool                  `json:"include_builtin"`
+	Rules          []models.StrategyRule `json:"rules"`
+}
+
+// ChannelConfig is the portable form of a NotificationChannel. Credentials are
+// not exported; an imported channel without them keeps those of the existing
+// channel with the same name and type.
+type ChannelConfig struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+	BotToken   string `json:"bot_token,omitempty"`
+	ChatID     string `json:"chat_id,omitempty"`
+	EmailTo    string `json:"email_to,omitempty"`
+	Template   string `json:"template,omitempty"`
+}
+
+// AlertRuleConfig is the portable form of an AlertRule. Channel names the
+// notification channel instead of its ID; signing secrets are not exported and
+// new rules get a fresh one.
+type AlertRuleConfig struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type,omitempty"`
+	Ticker     string  `json:"ticker,omitempty"`
+	Decision   string  `json:"decision,omitempty"`
+	Metric     string  `json:"metric,omitempty"`
+	Operator   string  `json:"operator,omitempty"`
+	Threshold  float64 `json:"threshold"`
+	WebhookURL string  `json:"webhook_url,omitempty"`
+	Channel    string  `json:"channel,omitempty"`
+	Enabled    *bool   `json:"enabled,omitempty"`
+	Template   string  `json:"template,omitempty"`
+}
+
+// ConfigTransferHandler exports and imports user configuration between accounts/environments
+type ConfigTransferHandler struct {
+	db *gorm.DB
+}
+
+// NewConfigTransferHandler creates a new config transfer handler
+func NewConfigTransferHandler(db *gorm.DB) *ConfigTransferHandler {
+	return &ConfigTransferHandler{db: db}
+}
+
+// HandleExport returns the current user's configuration as a single JSON document
+func (h *ConfigTransferHandler) HandleExport(c *gin.Context) {
+	userID := currentUserID(c)
+
+	var presets []models.AnalysisPreset
+	if err := h.db.Where("user_id = ?", userID).Order("name").Find(&presets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var strategies []models.Strategy
+	if err := h.db.Where("user_id = ?", userID).Order("name").Find(&strategies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var channels []models.NotificationChannel
+	if err := h.db.Where("user_id = ?", userID).Order("name").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var rules []models.AlertRule
+	if err := h.db.Where("user_id = ?", userID).Order("name").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle := ConfigBundle{
+		Version:    ConfigBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Presets:    make([]PresetConfig, 0, len(presets)),
+		Strategies: make([]StrategyConfig, 0, len(strategies)),
+		Channels:   make([]ChannelConfig, 0, len(channels)),
+		AlertRules: make([]AlertRuleConfig, 0, len(rules)),
+	}
+	for _, p := range presets {
+		bundle.Presets = append(bundle.Presets, presetConfigFromModel(p))
+	}
+	for _, s := range strategies {
+		bundle.Strategies = append(bundle.Strategies, StrategyConfig{
+			Name:           s.Name,
+			Description:    s.Description,
+			IncludeBuiltin: s.IncludeBuiltin,
+			Rules:          s.Rules,
+		})
+	}
+	channelNames := make(map[uint]string, len(channels))
+	for _, ch := range channels {
+		channelNames[ch.ID] = ch.Name
+		bundle.Channels = append(bundle.Channels, ChannelConfig{
+			Name:     ch.Name,
+			Type:     ch.Type,
+			ChatID:   ch.ChatID,
+			EmailTo:  ch.EmailTo,
+			Template: ch.Template,
+		})
+	}
+	for _, r := range rules {
+		enabled := r.Enabled
+		cfg := AlertRuleConfig{
+			Name:       r.Name,
+			Type:       r.Type,
+			Ticker:     r.Ticker,
+			Decision:   r.Decision,
+			Metric:     r.Metric,
+			Operator:   r.Operator,
+			Threshold:  r.Threshold,
+			WebhookURL: r.WebhookURL,
+			Enabled:    &enabled,
+			Template:   r.Template,
+		}
+		if r.ChannelID != nil {
+			cfg.Channel = channelNames[*r.ChannelID]
+		}
+		bundle.AlertRules = append(bundle.AlertRules, cfg)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"institutionanalyser-config-%s.json\"", bundle.ExportedAt.Format("20060102")))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// HandleImport loads a configuration document into the current user's account.
+// The import runs in one transaction, so a rejected document changes nothing.
+// Query parameters:
+//   - mode: "merge" (default) upserts by name; "replace" also removes entries of
+//     each section in the document that the document does not list
+func (h *ConfigTransferHandler) HandleImport(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "merge")
+	if mode != "merge" && mode != "replace" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be merge or replace"})
+		return
+	}
+
+	var bundle ConfigBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid config document", "details": err.Error()})
+		return
+	}
+	if bundle.Version < 1 || bundle.Version > ConfigBundleVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported config version %d", bundle.Version)})
+		return
+	}
+
+	if err := bundle.validate(); err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	userID := currentUserID(c)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if bundle.Presets != nil && mode == "replace" {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.AnalysisPreset{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, p := range bundle.Presets {
+			if _, err := upsertPreset(tx, userID, p); err != nil {
+				return err
+			}
+		}
+
+		if bundle.Strategies != nil && mode == "replace" {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.Strategy{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range bundle.Strategies {
+			if err := importStrategy(tx, userID, s); err != nil {
+				return err
+			}
+		}
+
+		// Channels and rules are upserted before anything is pruned so rules
+		// keep pointing at channels that survive the import
+		channelIDs, err := importChannels(tx, userID, bundle.Channels)
+		if err != nil {
+			return err
+		}
+		for _, r := range bundle.AlertRules {
+			if err := importAlertRule(tx, userID, r, channelIDs); err != nil {
+				return err
+			}
+		}
+		if mode != "replace" {
+			return nil
+		}
+		if bundle.AlertRules != nil {
+			if err := tx.Where("user_id = ? AND name NOT IN ?", userID, alertRuleNames(bundle.AlertRules)).Delete(&models.AlertRule{}).Error; err != nil {
+				return err
+			}
+		}
+		if bundle.Channels != nil {
+			return pruneChannels(tx, userID, bundle.Channels)
+		}
+		return nil
+	})
+	if err != nil {
+		var requestErr *RequestError
+		if errors.As(err, &requestErr) {
+			writeOperationError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Configuration imported",
+		"mode":    mode,
+		"imported": gin.H{
+			"presets":     len(bundle.Presets),
+			"strategies":  len(bundle.Strategies),
+			"channels":    len(bundle.Channels),
+			"alert_rules": len(bundle.AlertRules),
+		},
+	})
+}
+
+// validate checks every entry that can be checked without the database and
+// rejects duplicate names within a section
+func (b *ConfigBundle) validate() error {
+	seen := make(map[string]bool)
+	unique := func(section, name string) error {
+		key := section + "\x00" + name
+		if seen[key] {
+			return badRequest(fmt.Sprintf("duplicate %s %q", section, name))
+		}
+		seen[key] = true
+		return nil
+	}
+
+	for i := range b.Presets {
+		if err := b.Presets[i].Validate(); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := unique("preset", b.Presets[i].Name); err != nil {
+			return err
+		}
+	}
+	for i := range b.Strategies {
+		strategy := b.Strategies[i].model()
+		if err := deepsearch.NormalizeStrategy(&strategy); err != nil {
+			return badRequest(fmt.Sprintf("strategy %q: %s", b.Strategies[i].Name, err))
+		}
+		b.Strategies[i] = StrategyConfig{
+			Name:           strategy.Name,
+			Description:    strategy.Description,
+			IncludeBuiltin: strategy.IncludeBuiltin,
+			Rules:          strategy.Rules,
+		}
+		if err := unique("strategy", strategy.Name); err != nil {
+			return err
+		}
+	}
+	for i := range b.Channels {
+		b.Channels[i].Name = strings.TrimSpace(b.Channels[i].Name)
+		if b.Channels[i].Name == "" {
+			return badRequest("channel name is required")
+		}
+		if err := unique("channel", b.Channels[i].Name); err != nil {
+			return err
+		}
+	}
+	for i := range b.AlertRules {
+		b.AlertRules[i].Name = strings.TrimSpace(b.AlertRules[i].Name)
+		b.AlertRules[i].Channel = strings.TrimSpace(b.AlertRules[i].Channel)
+		if b.AlertRules[i].Name == "" {
+			return badRequest("alert rule name is required")
+		}
+		if err := unique("alert rule", b.AlertRules[i].Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s StrategyConfig) model() models.Strategy {
+	return models.Strategy{
+		Name:           s.Name,
+		Description:    s.Description,
+		IncludeBuiltin: s.IncludeBuiltin,
+		Rules:          s.Rules,
+	}
+}
+
+// importStrategy upserts a validated strategy keyed on (user_id, name)
+func importStrategy(tx *gorm.DB, userID string, cfg StrategyConfig) error {
+	strategy := cfg.model()
+	strategy.UserId = userID
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "include_builtin", "rules", "updated_at"}),
+	}).Create(&strategy).Error
+}
+
+// importChannels upserts channels by name and returns the IDs of all the user's
+// channels keyed by name, including ones the document does not mention
+func importChannels(tx *gorm.DB, userID string, configs []ChannelConfig) (map[string]uint, error) {
+	var existing []models.NotificationChannel
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.NotificationChannel, len(existing))
+	for _, ch := range existing {
+		if _, ok := byName[ch.Name]; !ok {
+			byName[ch.Name] = ch
+		}
+	}
+
+	for _, cfg := range configs {
+		channel, found := byName[cfg.Name]
+		if !found || !strings.EqualFold(strings.TrimSpace(cfg.Type), channel.Type) {
+			// Credentials only carry over to a channel of the same type
+			channel.WebhookURL = ""
+			channel.BotToken = ""
+		}
+		channel.UserId = userID
+		channel.Name = cfg.Name
+		channel.Type = cfg.Type
+		if cfg.WebhookURL != "" {
+			channel.WebhookURL = cfg.WebhookURL
+		}
+		if cfg.BotToken != "" {
+			channel.BotToken = cfg.BotToken
+		}
+		channel.ChatID = cfg.ChatID
+		channel.EmailTo = cfg.EmailTo
+		channel.Template = cfg.Template
+		if err := alerts.NormalizeChannel(&channel); err != nil {
+			return nil, badRequest(fmt.Sprintf("channel %q: %s", cfg.Name, err))
+		}
+		if err := tx.Save(&channel).Error; err != nil {
+			return nil, err
+		}
+		byName[channel.Name] = channel
+	}
+
+	ids := make(map[string]uint, len(byName))
+	for name, ch := range byName {
+		ids[name] = ch.ID
+	}
+	return ids, nil
+}
+
+// importAlertRule upserts a rule by name, resolving its channel by name.
+// Existing rules keep their signing secret; new ones get a fresh one.
+func importAlertRule(tx *gorm.DB, userID string, cfg AlertRuleConfig, channelIDs map[string]uint) error {
+	var rule models.AlertRule
+	err := tx.Where("user_id = ? AND name = ?", userID, cfg.Name).Order("id").First(&rule).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	rule.UserId = userID
+	rule.Name = cfg.Name
+	rule.Type = cfg.Type
+	rule.Ticker = cfg.Ticker
+	rule.Decision = cfg.Decision
+	rule.Metric = cfg.Metric
+	rule.Operator = cfg.Operator
+	rule.Threshold = cfg.Threshold
+	rule.WebhookURL = cfg.WebhookURL
+	rule.Template = cfg.Template
+	rule.Enabled = cfg.Enabled == nil || *cfg.Enabled
+	rule.ChannelID = nil
+	if cfg.Channel != "" {
+		id, ok := channelIDs[cfg.Channel]
+		if !ok {
+			return badRequest(fmt.Sprintf("alert rule %q: unknown channel %q", cfg.Name, cfg.Channel))
+		}
+		rule.ChannelID = &id
+	}
+	if err := alerts.NormalizeRule(&rule); err != nil {
+		return badRequest(fmt.Sprintf("alert rule %q: %s", cfg.Name, err))
+	}
+
+	if rule.Secret == "" {
+		secret, err := alerts.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		rule.Secret = secret
+	}
+	if rule.ID != 0 || rule.Enabled {
+		return tx.Save(&rule).Error
+	}
+	// Create would swap a false Enabled for the column default
+	if err := tx.Create(&rule).Error; err != nil {
+		return err
+	}
+	return tx.Model(&rule).Update("enabled", false).Error
+}
+
+func alertRuleNames(configs []AlertRuleConfig) []string {
+	names := make([]string, 0, len(configs)+1)
+	for _, r := range configs {
+		names = append(names, r.Name)
+	}
+	// NOT IN () is invalid SQL; an empty name never matches a rule
+	return append(names, "")
+}
+
+// pruneChannels deletes the user's channels missing from the document. A channel
+// still used by a rule the import kept is a 409, like deleting it directly.
+func pruneChannels(tx *gorm.DB, userID string, configs []ChannelConfig) error {
+	names := make([]string, 0, len(configs)+1)
+	for _, ch := range configs {
+		names = append(names, ch.Name)
+	}
+	names = append(names, "")
+
+	stale := tx.Model(&models.NotificationChannel{}).Select("id").Where("user_id = ? AND name NOT IN ?", userID, names)
+	var rules int64
+	if err := tx.Model(&models.AlertRule{}).Where("user_id = ? AND channel_id IN (?)", userID, stale).Count(&rules).Error; err != nil {
+		return err
+	}
+	if rules > 0 {
+		return &RequestError{
+			Status:  http.StatusConflict,
+			Message: "Notification channel is used by alert rules",
+			Err:     fmt.Errorf("%d rule(s) still send to a channel missing from the document", rules),
+		}
+	}
+	return tx.Where("user_id = ? AND name NOT IN ?", userID, names).Delete(&models.NotificationChannel{}).Error
+}
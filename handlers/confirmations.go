@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ConfirmationsHandler serves confirmed institutional moves, where several
+// independent sources agreed on a direction
+type ConfirmationsHandler struct {
+	db *gorm.DB
+}
+
+// NewConfirmationsHandler creates a new confirmations handler
+func NewConfirmationsHandler(db *gorm.DB) *ConfirmationsHandler {
+	return &ConfirmationsHandler{db: db}
+}
+
+// HandleGetConfirmedMoves returns a ticker's stored confirmed moves, most recent first
+// Query parameters:
+//   - date: Session date, YYYY-MM-DD (optional)
+//   - direction: UP or DOWN (optional)
+//   - limit: Moves per page (default: 50, max 500)
+//   - offset: Number of moves to skip (default: 0)
+func (h *ConfirmationsHandler) HandleGetConfirmedMoves(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	limit, offset := parsePagination(c, 50, 500)
+
+	query := h.db.Model(&models.ConfirmedMove{}).Where("ticker = ?", ticker)
+	if val := c.Query("date"); val != "" {
+		date, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("day = ?", date.Format("2006-01-02"))
+	}
+	if direction := strings.ToUpper(c.Query("direction")); direction != "" {
+		if direction != "UP" && direction != "DOWN" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be UP or DOWN"})
+			return
+		}
+		query = query.Where("direction = ?", direction)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count confirmed moves", "details": err.Error()})
+		return
+	}
+	var moves []models.ConfirmedMove
+	if err := query.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&moves).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch confirmed moves", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": moves,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(moves),
+		},
+	})
+}
+
+// HandleConfirmMoves reads a session's sources, stores its confirmed moves,
+// replacing those stored for the session, and returns them with each
+// source's event count
+// Query parameters:
+//   - date: Session date, YYYY-MM-DD (default: the last completed session)
+//   - sources: Comma-separated sources, N (default: CONFIRMATION_SOURCES or volume,blocks,sweeps,dark_pool)
+//   - required: Sources that must agree, K (default: CONFIRMATION_REQUIRED or 3)
+//   - window_minutes: How close together they must fire (default: CONFIRMATION_WINDOW_MINUTES or 15)
+func (h *ConfirmationsHandler) HandleConfirmMoves(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	date := deepsearch.LastCompletedSession(c.Request.Context(), time.Now())
+	if val := c.Query("date"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		if !service.DefaultTradingCalendar().IsTradingDay(c.Request.Context(), parsed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date is not a trading day"})
+			return
+		}
+		date = parsed
+	}
+
+	config := deepsearch.DefaultConfirmationConfig()
+	if val := c.Query("sources"); val != "" {
+		config.Sources = strings.Split(val, ",")
+	}
+	for param, target := range map[string]*int{"required": &config.Required, "window_minutes": &config.WindowMinutes} {
+		if val := c.Query(param); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an integer"})
+				return
+			}
+			*target = n
+		}
+	}
+	if err := config.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	confirmation, err := jobs.RefreshConfirmedMoves(c.Request.Context(), h.db, ticker, date, config)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to confirm institutional moves", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, confirmation)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DecisionRulesHandler edits the technicals decision table
+type DecisionRulesHandler struct {
+	db *gorm.DB
+}
+
+// NewDecisionRulesHandler creates a new decision rules handler
+func NewDecisionRulesHandler(db *gorm.DB) *DecisionRulesHandler {
+	return &DecisionRulesHandler{db: db}
+}
+
+// DecisionRuleRequest is the body of decision rule create and update requests.
+// On update, only the fields present are changed.
+type DecisionRuleRequest struct {
+	Name       *string                     `json:"name"`
+	Priority   *int                        `json:"priority"`
+	Decision   *string                     `json:"decision"`
+	Reason     *string                     `json:"reason"`
+	Conditions *[]models.DecisionCondition `json:"conditions"`
+	Enabled    *bool                       `json:"enabled"`
+}
+
+// apply copies the fields set in the request onto rule
+func (r DecisionRuleRequest) apply(rule *models.DecisionRule) {
+	if r.Name != nil {
+		rule.Name = *r.Name
+	}
+	if r.Priority != nil {
+		rule.Priority = *r.Priority
+	}
+	if r.Decision != nil {
+		rule.Decision = *r.Decision
+	}
+	if r.Reason != nil {
+		rule.Reason = *r.Reason
+	}
+	if r.Conditions != nil {
+		rule.Conditions = *r.Conditions
+	}
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+}
+
+// HandleListDecisionRules returns the decision table in evaluation order. While
+// no rules are stored the built-in defaults are returned with "default": true.
+func (h *DecisionRulesHandler) HandleListDecisionRules(c *gin.Context) {
+	var rules []models.DecisionRule
+	if err := h.db.Order("priority, id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"data": rules, "default": false, "inputs": deepsearch.DecisionInputs()}
+	if len(rules) == 0 {
+		response["data"] = deepsearch.DefaultDecisionRules()
+		response["default"] = true
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleCreateDecisionRule adds a rule to the table. The first stored rule
+// replaces the built-in defaults, which can be imported with ?seed=true.
+func (h *DecisionRulesHandler) HandleCreateDecisionRule(c *gin.Context) {
+	var req DecisionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	rule := models.DecisionRule{Enabled: true}
+	req.apply(&rule)
+	if err := deepsearch.NormalizeDecisionRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if c.Query("seed") == "true" {
+			var count int64
+			if err := tx.Model(&models.DecisionRule{}).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				defaults := deepsearch.DefaultDecisionRules()
+				if err := tx.Create(&defaults).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Create(&rule).Error
+	})
+	if err != nil {
+		h.writeSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"decision_rule": rule})
+}
+
+// HandleUpdateDecisionRule changes the fields present in the body
+func (h *DecisionRulesHandler) HandleUpdateDecisionRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	var req DecisionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	req.apply(rule)
+	if err := deepsearch.NormalizeDecisionRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Select all columns so false/zero values (enabled, priority) are written
+	if err := h.db.Model(rule).Select("*").Omit("id", "created_at").Updates(rule).Error; err != nil {
+		h.writeSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"decision_rule": rule})
+}
+
+// HandleDeleteDecisionRule removes a rule. Deleting the last one restores the defaults.
+func (h *DecisionRulesHandler) HandleDeleteDecisionRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Decision rule deleted"})
+}
+
+// HandleEvaluateDecisionRules evaluates the table against inputs supplied in the
+// body, without fetching any data, to test rule edits
+func (h *DecisionRulesHandler) HandleEvaluateDecisionRules(c *gin.Context) {
+	var inputs map[string]float64
+	if err := c.ShouldBindJSON(&inputs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	rules, err := deepsearch.LoadDecisionRules(c.Request.Context(), h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deepsearch.EvaluateDecisionRules(rules, inputs))
+}
+
+// findRule loads the rule named by the :id parameter, writing the error response if missing
+func (h *DecisionRulesHandler) findRule(c *gin.Context) (*models.DecisionRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid decision rule id"})
+		return nil, false
+	}
+
+	var rule models.DecisionRule
+	if err := h.db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Decision rule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &rule, true
+}
+
+func (h *DecisionRulesHandler) writeSaveError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+		c.JSON(http.StatusConflict, gin.H{"error": "A decision rule with this name already exists"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
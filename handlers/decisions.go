@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MaxBulkDecisionTickers caps the number of tickers accepted by the bulk endpoint
+const MaxBulkDecisionTickers = 500
+
+// DecisionsHandler serves final decisions across tickers
+type DecisionsHandler struct {
+	db *gorm.DB
+}
+
+// NewDecisionsHandler creates a new decisions handler
+func NewDecisionsHandler(db *gorm.DB) *DecisionsHandler {
+	return &DecisionsHandler{db: db}
+}
+
+// LatestDecisionsRequest is the body accepted by HandleLatestDecisions
+type LatestDecisionsRequest struct {
+	Tickers []string `json:"tickers" binding:"required"`
+	// Tag optionally restricts the lookup to analyses carrying this tag
+	Tag string `json:"tag"`
+}
+
+// LatestDecision is the most recent final decision stored for a ticker
+type LatestDecision struct {
+	Ticker        string    `json:"ticker"`
+	FinalDecision string    `json:"final_decision"`
+	Confidence    float64   `json:"confidence"`
+	AnalysisID    uint      `json:"analysis_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// HandleLatestDecisions returns the caller's latest FinalDecision for each requested ticker in one query
+// Body: {"tickers": ["AAPL", "MSFT", ...], "tag": "earnings-play"} (max 500 tickers, tag optional)
+func (h *DecisionsHandler) HandleLatestDecisions(c *gin.Context) {
+	var req LatestDecisionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be JSON with a tickers array"})
+		return
+	}
+
+	tickers := normalizeTickers(req.Tickers)
+	if len(tickers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one ticker is required"})
+		return
+	}
+	if len(tickers) > MaxBulkDecisionTickers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many tickers, maximum is 500"})
+		return
+	}
+
+	decisions, err := latestDecisions(ownedBy(h.db, ownerID(c)), tickers, strings.ToLower(strings.TrimSpace(req.Tag)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	found := make(map[string]bool, len(decisions))
+	for _, d := range decisions {
+		found[d.Ticker] = true
+	}
+	missing := []string{}
+	for _, t := range tickers {
+		if !found[t] {
+			missing = append(missing, t)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": decisions,
+		"count":     len(decisions),
+		"missing":   missing,
+	})
+}
+
+// latestDecisions fetches the newest technical_signals row per ticker. The
+// tickers are looked up by the (ticker, created_at) index, or (user_id, ticker,
+// created_at) when db is narrowed to a user. An empty tag matches all rows.
+func latestDecisions(db *gorm.DB, tickers []string, tag string) ([]LatestDecision, error) {
+	filter := deepsearch.SignalFilter{Tickers: tickers}
+	if tag != "" {
+		filter.Tags = []string{tag}
+	}
+
+	decisions := []LatestDecision{}
+	err := models.LatestPer(filter.Apply(db.Model(&models.TechnicalSignal{})), "ticker", "created_at DESC, id DESC").
+		Select("ticker, final_decision, confidence, id AS analysis_id, created_at").
+		Order("ticker").
+		Scan(&decisions).Error
+	return decisions, err
+}
+
+// normalizeTickers upper-cases, trims and de-duplicates tickers, preserving order
+func normalizeTickers(tickers []string) []string {
+	seen := make(map[string]bool, len(tickers))
+	normalized := make([]string, 0, len(tickers))
+	for _, t := range tickers {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	return normalized
+}
+
+// HandleQuickDecision returns an on-the-spot decision for a ticker from its live
+// snapshot, today's partial intraday bars and the caller's latest stored analysis,
+// without running or storing a deep search. If the stored analysis cannot be
+// read, the decision is built from the live inputs and marked degraded.
+func (h *DecisionsHandler) HandleQuickDecision(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker is required"})
+		return
+	}
+
+	var latest *models.TechnicalSignal
+	var analysis models.TechnicalSignal
+	filter := deepsearch.SignalFilter{Tickers: []string{ticker}}
+	dbErr := filter.Apply(ownedBy(h.db, ownerID(c))).Order("created_at DESC").First(&analysis).Error
+	switch {
+	case dbErr == nil:
+		latest = &analysis
+	case errors.Is(dbErr, gorm.ErrRecordNotFound):
+		dbErr = nil
+	}
+
+	decision, err := deepsearch.QuickDecide(c.Request.Context(), ticker, latest)
+	if err != nil {
+		if dbErr != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to build a decision", "details": errors.Join(err, dbErr).Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to build a decision", "details": err.Error()})
+		return
+	}
+	if dbErr != nil {
+		decision.Add(health.Database, "latest stored analysis unavailable")
+	}
+
+	respondDegradable(c, http.StatusOK, decision, &decision.Degradation)
+}
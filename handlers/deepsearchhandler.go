@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
 	"institutionanalyser/models"
+	"institutionanalyser/portfolio"
+	"institutionanalyser/reports"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type DeepSearchHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	queue *jobs.AnalysisQueue
+	// store keeps uploaded charts and reports; nil when there is none
+	store reports.Store
 }
 
-func NewDeepSearchHandler(db *gorm.DB) *DeepSearchHandler {
-	return &DeepSearchHandler{db: db}
+func NewDeepSearchHandler(db *gorm.DB, queue *jobs.AnalysisQueue, store reports.Store) *DeepSearchHandler {
+	return &DeepSearchHandler{db: db, queue: queue, store: store}
 }
 
-// HandleGetAnalysis returns the latest technical analysis signals for a ticker
+// HandleGetAnalysis returns the latest technical analysis signals for a ticker.
+// A stale result is still returned while a refresh is queued (see freshness).
 func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysis(c *gin.Context) {
 	ticker := c.Query("ticker")
 	if ticker == "" {
@@ -35,69 +47,797 @@ func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysis(c *gin.Context) {
 		return
 	}
 
+	filter := deepsearch.SignalFilter{Tickers: []string{ticker}, StartDuration: end_duration}
 	var signals []models.TechnicalSignal
-	result := deepSearchHandler.db.Where("ticker = ? and poly_start_duration = ?", ticker, end_duration).Order("created_at desc").Limit(1).Find(&signals)
+	result := filter.Apply(ownedBy(deepSearchHandler.db, ownerID(c))).Order("created_at desc").Limit(1).Find(&signals)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"signals": signals})
+	response := gin.H{"signals": signals}
+	if len(signals) > 0 {
+		response["freshness"] = deepSearchHandler.queue.RefreshIfStale(c.Request.Context(), &signals[0])
+		if dc := deepSearchHandler.portfolioContext(c, &signals[0]); dc != nil {
+			response["portfolio"] = dc
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-func (deepSearchHandler *DeepSearchHandler) HandleTriggerAnalysis(c *gin.Context) {
-	ticker := c.Query("ticker")
-	if ticker == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+// AnalysisV2Response is a stored analysis with its signals parsed into structured form
+type AnalysisV2Response struct {
+	models.TechnicalSignal
+	StructuredSignals []deepsearch.StructuredSignal `json:"structured_signals"`
+}
+
+// HandleGetAnalysisV2 returns the latest analysis for a ticker with structured signals.
+// Unlike v1 it filters on start_duration, which is the field the trigger stores.
+// A stale result is still returned while a refresh is queued (see freshness).
+func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysisV2(c *gin.Context) {
+	signal, err := deepSearchHandler.LatestAnalysis(c.Request.Context(), ownerID(c), c.Query("ticker"), c.Query("start_duration"))
+	if err != nil {
+		writeOperationError(c, err)
 		return
 	}
 
-	startDuration := c.Query("start_duration")
+	analyses := []AnalysisV2Response{}
+	response := gin.H{}
+	if signal != nil {
+		analyses = append(analyses, AnalysisV2Response{
+			TechnicalSignal:   *signal,
+			StructuredSignals: deepsearch.ParseSignals(signal.Signals),
+		})
+		response["freshness"] = deepSearchHandler.queue.RefreshIfStale(c.Request.Context(), signal)
+		if dc := deepSearchHandler.portfolioContext(c, signal); dc != nil {
+			response["portfolio"] = dc
+		}
+	}
+	response["analyses"] = analyses
+
+	c.JSON(http.StatusOK, response)
+}
+
+// portfolioContext places analysis in the caller's portfolio; nil when they
+// hold nothing or it cannot be read, which does not fail the response
+func (deepSearchHandler *DeepSearchHandler) portfolioContext(c *gin.Context, analysis *models.TechnicalSignal) *portfolio.DecisionContext {
+	dc, err := portfolio.Context(c.Request.Context(), deepSearchHandler.db, currentUserID(c), analysis)
+	if err != nil {
+		fmt.Printf("[deepsearch] failed to read the portfolio of user %s: %v\n", currentUserID(c), err)
+	}
+	return dc
+}
+
+// LatestAnalysis returns userID's latest analysis of ticker triggered with
+// startDuration, nil if there is none. An empty userID matches every user's.
+func (deepSearchHandler *DeepSearchHandler) LatestAnalysis(ctx context.Context, userID, ticker, startDuration string) (*models.TechnicalSignal, error) {
+	if ticker == "" {
+		return nil, badRequest("Ticker is required")
+	}
 	if startDuration == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_duration is required"})
+		return nil, badRequest("start_duration is required")
+	}
+
+	filter := deepsearch.SignalFilter{Tickers: []string{ticker}, StartDuration: startDuration}
+	var signals []models.TechnicalSignal
+	if err := filter.Apply(ownedBy(deepSearchHandler.db.WithContext(ctx), userID)).Order("created_at desc").Limit(1).Find(&signals).Error; err != nil {
+		return nil, err
+	}
+	if len(signals) == 0 {
+		return nil, nil
+	}
+	return &signals[0], nil
+}
+
+// historySorts maps the history sort options to their columns
+var historySorts = map[string]string{
+	"created_at": "created_at",
+	"confidence": "confidence",
+	"ticker":     "ticker",
+	"start_date": "start_date",
+}
+
+// HistoryQuery selects and orders stored analyses. Empty fields do not filter.
+type HistoryQuery struct {
+	Tickers   []string
+	Decisions []string
+	// UserID keeps analyses run by this user
+	UserID string
+	// From and To (YYYY-MM-DD, market time) bound the day the analysis was run
+	From, To string
+	// Archived lists archived analyses instead of the unarchived ones
+	Archived bool
+	// Sort is a historySorts key (default: created_at) and Order asc or desc (default)
+	Sort, Order   string
+	Limit, Offset int
+}
+
+// HandleGetHistory lists stored analyses, newest first by default
+// Query parameters:
+//   - ticker: Comma-separated tickers (optional)
+//   - user: Only analyses run by this user ID, or "me" for the caller (optional;
+//     admins only, everyone else only sees their own analyses)
+//   - decision: Comma-separated final decisions: BUY, SELL, HOLD or STRADDLE (optional)
+//   - from: Analyses run on or after this date, YYYY-MM-DD (optional)
+//   - to: Analyses run on or before this date, YYYY-MM-DD (optional)
+//   - archived: List archived analyses instead (default: false)
+//   - sort: created_at (default), confidence, ticker or start_date
+//   - order: desc (default) or asc
+//   - limit: Maximum number of results (default: 50, max: 500)
+//   - offset: Number of results to skip (default: 0)
+func (deepSearchHandler *DeepSearchHandler) HandleGetHistory(c *gin.Context) {
+	limit, offset := parsePagination(c, 50, 500)
+	query := HistoryQuery{
+		UserID:   c.Query("user"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Archived: c.Query("archived") == "true",
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if val := c.Query("ticker"); val != "" {
+		query.Tickers = strings.Split(val, ",")
+	}
+	if val := c.Query("decision"); val != "" {
+		query.Decisions = strings.Split(val, ",")
+	}
+	if owner := ownerID(c); owner != "" {
+		query.UserID = owner
+	} else if query.UserID == "me" {
+		query.UserID = currentUserID(c)
+	}
+
+	signals, total, err := deepSearchHandler.History(c.Request.Context(), query)
+	if err != nil {
+		writeOperationError(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"data": signals,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(signals),
+		},
+	})
+}
+
+// History returns a page of the stored analyses matching q and how many match in total
+func (deepSearchHandler *DeepSearchHandler) History(ctx context.Context, q HistoryQuery) ([]models.TechnicalSignal, int64, error) {
+	filter := deepsearch.SignalFilter{Tickers: q.Tickers, Decisions: q.Decisions}
+	if err := filter.Normalize(); err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	if q.Sort == "" {
+		q.Sort = "created_at"
+	}
+	column, ok := historySorts[q.Sort]
+	if !ok {
+		return nil, 0, badRequest("sort must be created_at, confidence, ticker or start_date")
+	}
+	direction := strings.ToUpper(q.Order)
+	if direction == "" {
+		direction = "DESC"
+	}
+	if direction != "ASC" && direction != "DESC" {
+		return nil, 0, badRequest("order must be asc or desc")
+	}
+
+	query := withArchived(filter.Apply(models.ReadReplica(deepSearchHandler.db).WithContext(ctx).Model(&models.TechnicalSignal{})), q.Archived)
+	if q.UserID != "" {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if q.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", q.From, jobs.MarketTimezone)
+		if err != nil {
+			return nil, 0, badRequest("Invalid from format, use YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if q.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", q.To, jobs.MarketTimezone)
+		if err != nil {
+			return nil, 0, badRequest("Invalid to format, use YYYY-MM-DD")
+		}
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count analyses: %w", err)
+	}
+	// id breaks ties so pages do not overlap
+	signals := []models.TechnicalSignal{}
+	order := fmt.Sprintf("%s %s, id %s", column, direction, direction)
+	if err := query.Order(order).Limit(q.Limit).Offset(q.Offset).Find(&signals).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch analyses: %w", err)
+	}
+	return signals, total, nil
+}
+
+// TriggerRequest is an analysis to queue; the window runs from StartDuration
+// to today. Optional fields are empty for their defaults.
+type TriggerRequest struct {
+	Ticker        string
+	StartDuration string
+	// Preset names one of the user's aggregation presets (default: minute/5)
+	Preset     string
+	VWAPAnchor string
+	ATRPeriod  string
+	// Strategy names one of the user's strategies (default: the built-in signals)
+	Strategy string
+}
+
+// HandleTriggerAnalysis validates the request and queues the analysis, returning a job ID
+// that can be polled at /api/v1/deepsearch/jobs/:id
+func (deepSearchHandler *DeepSearchHandler) HandleTriggerAnalysis(c *gin.Context) {
+	job, created, err := deepSearchHandler.Trigger(c.Request.Context(), currentUserID(c), TriggerRequest{
+		Ticker:        c.Query("ticker"),
+		StartDuration: c.Query("start_duration"),
+		Preset:        c.Query("preset"),
+		VWAPAnchor:    c.Query("vwap_anchor"),
+		ATRPeriod:     c.Query("atr_period"),
+		Strategy:      c.Query("strategy"),
+	})
+	if err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	message := "Analysis queued"
+	if !created {
+		message = "Analysis already in progress"
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    message,
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"duplicate":  !created,
+		"status_url": fmt.Sprintf("/api/v1/deepsearch/jobs/%d", job.ID),
+	})
+}
+
+// Trigger validates req and queues the analysis for userID. It returns the
+// job and false when the same analysis was already queued or running.
+func (deepSearchHandler *DeepSearchHandler) Trigger(ctx context.Context, userID string, req TriggerRequest) (*models.AnalysisJob, bool, error) {
+	ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+	if ticker == "" {
+		return nil, false, badRequest("Ticker is required")
+	}
+
+	startDuration := req.StartDuration
+	if startDuration == "" {
+		return nil, false, badRequest("start_duration is required")
+	}
+
+	if err := validateTicker(deepSearchHandler.db, ticker); err != nil {
+		return nil, false, badRequest(err.Error())
+	}
+
 	fmt.Printf("Start Duration: %s\n", startDuration)
 	fmt.Printf("Ticker: %s\n", ticker)
 
 	// Parse end_date
 	_, err := time.Parse("2006-01-02", startDuration)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_duration format, use YYYY-MM-DD"})
-		return
+		return nil, false, badRequest("Invalid start_duration format, use YYYY-MM-DD")
 	}
 
-	// Get user_id from context (set by auth middleware) or query parameter (for system/orchestrator calls)
-
-	// Fallback to query parameter if not in context
+	timeSpan, multiplier, err := deepSearchHandler.resolveAggregation(userID, req.Preset)
+	if err != nil {
+		return nil, false, err
+	}
 
-	// Add one day for start_date
-	//endDate := end.AddDate(0, 0, 1)
-	//endDuration := endDate.Format("2006-01-02")
+	vwapAnchor, err := deepsearch.ParseVWAPAnchor(req.VWAPAnchor)
+	if err != nil {
+		return nil, false, badRequest(err.Error())
+	}
+	atrPeriod, err := deepsearch.ParseATRPeriod(req.ATRPeriod)
+	if err != nil {
+		return nil, false, badRequest(err.Error())
+	}
+	strategyID, err := deepSearchHandler.resolveStrategy(userID, req.Strategy)
+	if err != nil {
+		return nil, false, err
+	}
 
 	endDuration := time.Now().Format("2006-01-02")
 
-	// Trigger analysis
-
 	fmt.Printf("Trigger search params: %s - %s\n", startDuration, endDuration)
 
-	//store the deepsearch request in the database
+	// The deepsearch request is stored with the job, and only for a new job
 	deepSearchRequest := models.DeepSearchRequest{
 		StartDate: startDuration,
 		EndDate:   endDuration,
 		Ticker:    ticker,
-		UserId:    "orchestrator",
+		UserId:    userID,
 	}
-	deepSearchHandler.db.Create(&deepSearchRequest)
 
-	svc := deepsearch.NewDeepSearchService(startDuration, endDuration, "minute", 5, ticker, "orchestrator", deepSearchHandler.db)
-	err = svc.AnalyseMain()
+	job := models.AnalysisJob{
+		Ticker:        ticker,
+		StartDuration: startDuration,
+		EndDuration:   endDuration,
+		TimeSpan:      timeSpan,
+		Multiplier:    multiplier,
+		VWAPAnchor:    vwapAnchor,
+		ATRPeriod:     atrPeriod,
+		StrategyID:    strategyID,
+		UserId:        userID,
+	}
+	created, err := deepSearchHandler.queue.Enqueue(ctx, &job, &deepSearchRequest)
+	if err != nil {
+		return nil, false, err
+	}
+	return &job, created, nil
+}
 
+// HandleTechnicalDecision runs the decision rule table over the latest bar of
+// the window and the daily indicators, returning the decision with the trace
+// of the rules evaluated. Like a trigger, the window's signals are stored.
+// Query parameters:
+//   - ticker, start_duration (YYYY-MM-DD): the window, ending today
+//   - preset: Named aggregation preset (optional, default minute/5)
+//   - atr_period: Wilder ATR period, 2-100 (optional, default 14)
+func (deepSearchHandler *DeepSearchHandler) HandleTechnicalDecision(c *gin.Context) {
+	ticker := strings.ToUpper(c.Query("ticker"))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+
+	startDuration := c.Query("start_duration")
+	if _, err := time.Parse("2006-01-02", startDuration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_duration format, use YYYY-MM-DD"})
+		return
+	}
+
+	if err := validateTicker(deepSearchHandler.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := currentUserID(c)
+	timeSpan, multiplier, ok := deepSearchHandler.aggregation(c, userID, c.Query("preset"))
+	if !ok {
+		return
+	}
+	atrPeriod, err := deepsearch.ParseATRPeriod(c.Query("atr_period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rules, err := deepsearch.LoadDecisionRules(c.Request.Context(), deepSearchHandler.db)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Analysis triggered successfully"})
+	svc := deepsearch.NewDeepSearchService(startDuration, time.Now().Format("2006-01-02"), timeSpan, multiplier, ticker, userID, deepSearchHandler.db)
+	svc.SetATRPeriod(atrPeriod)
+	decision, err := svc.AnalyseWithTechnicals(c.Request.Context(), rules)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to build a decision", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// aggregation resolves the bar size like resolveAggregation and writes the
+// error response for an unknown preset
+func (deepSearchHandler *DeepSearchHandler) aggregation(c *gin.Context, userID, presetName string) (string, int, bool) {
+	timeSpan, multiplier, err := deepSearchHandler.resolveAggregation(userID, presetName)
+	if err != nil {
+		writeOperationError(c, err)
+		return "", 0, false
+	}
+	return timeSpan, multiplier, true
+}
+
+// resolveAggregation resolves the bar size for an analysis: the user's named
+// preset, or the default minute/5
+func (deepSearchHandler *DeepSearchHandler) resolveAggregation(userID, presetName string) (string, int, error) {
+	if presetName == "" {
+		return "minute", 5, nil
+	}
+
+	var preset models.AnalysisPreset
+	if err := deepSearchHandler.db.Where("user_id = ? AND name = ?", userID, presetName).First(&preset).Error; err != nil {
+		return "", 0, badRequest("Unknown preset: " + presetName)
+	}
+	return preset.TimeSpan, preset.Multiplier, nil
+}
+
+// resolveStrategy resolves the user's named strategy to its ID, 0 for the
+// built-in signals
+func (deepSearchHandler *DeepSearchHandler) resolveStrategy(userID, name string) (uint, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	var strategy models.Strategy
+	if err := deepSearchHandler.db.Where("user_id = ? AND name = ?", userID, name).First(&strategy).Error; err != nil {
+		return 0, badRequest("Unknown strategy: " + name)
+	}
+	return strategy.ID, nil
+}
+
+// HandleGetJob reports the status of a queued analysis and, once completed, its result
+func (deepSearchHandler *DeepSearchHandler) HandleGetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := deepSearchHandler.Job(c.Request.Context(), ownerID(c), uint(id))
+	if err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	response := gin.H{"job": job}
+	if job.ResultID != nil {
+		response["result"] = gin.H{
+			"analysis_id": *job.ResultID,
+			"url":         fmt.Sprintf("/api/v2/deepsearch/analysis?ticker=%s&start_duration=%s", job.Ticker, job.StartDuration),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Job returns one of userID's analysis jobs; an empty userID matches any user's
+func (deepSearchHandler *DeepSearchHandler) Job(ctx context.Context, userID string, id uint) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	if err := ownedBy(deepSearchHandler.db.WithContext(ctx), userID).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RequestError{Status: http.StatusNotFound, Message: "Job not found"}
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// HandleGetOutcomes scores a stored analysis's directional signals at a horizon,
+// adjusting for splits and large dividends in between
+// Query parameters:
+//   - horizon: How far after each signal to measure, e.g. 5m, 30m, 1d (default: 30m)
+func (deepSearchHandler *DeepSearchHandler) HandleGetOutcomes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return
+	}
+
+	horizonStr := c.DefaultQuery("horizon", "30m")
+	horizon, err := deepsearch.ParseHorizon(horizonStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(deepSearchHandler.db, ownerID(c)).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	outcomes, err := deepsearch.LabelOutcomes(c.Request.Context(), deepSearchHandler.db, &analysis, horizon)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deepsearch.SummariseOutcomes(horizonStr, outcomes))
+}
+
+// HandleGetAnalysisBars downloads the enhanced bars behind a stored analysis
+// as a Parquet file, one row per bar with its indicators. The bars are those
+// stored with the analysis, or recomputed from the bar store with its settings.
+// Query parameters:
+//   - warmup: Include the warm-up bars before the window, flagged in the warmup column (default: false)
+func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysisBars(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(deepSearchHandler.db, ownerID(c)).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bars, from, err := deepsearch.AnalysisBars(c.Request.Context(), deepSearchHandler.db, &analysis, c.Query("warmup") == "true")
+	if errors.Is(err, deepsearch.ErrBarsNotStored) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bars not stored", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bars", "details": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := deepsearch.WriteBarsParquet(&buf, &analysis, bars, from); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write Parquet", "details": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%d-bars.parquet\"", analysis.Ticker, analysis.ID))
+	c.Data(http.StatusOK, "application/vnd.apache.parquet", buf.Bytes())
+}
+
+// HandleGetAnalysisFeatures returns the bars stored with an analysis, each
+// with the features its signals were computed from, for auditing. Unlike the
+// Parquet export, nothing is recomputed.
+// Query parameters:
+//   - warmup: Include the warm-up bars before the window, flagged by warmup (default: false)
+func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysisFeatures(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(deepSearchHandler.db, ownerID(c)).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored, err := models.FindAnalysisBars(deepSearchHandler.db.WithContext(c.Request.Context()), analysis.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bar features", "details": err.Error()})
+		return
+	}
+	if len(stored) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bar features not stored", "details": "the features of this analysis were not stored; run it again to store them"})
+		return
+	}
+	bars := stored
+	if c.Query("warmup") != "true" {
+		bars = make([]models.AnalysisBar, 0, len(stored))
+		for _, bar := range stored {
+			if !bar.Warmup {
+				bars = append(bars, bar)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"analysis_id":  analysis.ID,
+		"ticker":       analysis.Ticker,
+		"timespan":     analysis.PolyTimeSpan,
+		"multiplier":   analysis.PolyMultiplier,
+		"currency":     analysis.Currency,
+		"algo_version": analysis.AlgoVersion,
+		"count":        len(bars),
+		"bars":         bars,
+	})
+}
+
+// HandleGetAnalysisReport downloads a stored analysis as a PDF report for
+// sharing with people who do not use the API: the decision and its rationale,
+// a price/VWAP chart and the indicators at the last bar, and the signals. The
+// chart and indicators come from the analysis's stored bars (see
+// deepsearch.AnalysisBars) and are left out when the bars are not stored.
+// Query parameters:
+//   - upload: Write the PDF to report storage and return a signed URL to it instead (default: false)
+func (deepSearchHandler *DeepSearchHandler) HandleGetAnalysisReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis id"})
+		return
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(deepSearchHandler.db, ownerID(c)).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := reports.BuildTickerReport(c.Request.Context(), deepSearchHandler.db, &analysis)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report", "details": err.Error()})
+		return
+	}
+	body, contentType, err := reports.Render(report, reports.FormatPDF)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render report", "details": err.Error()})
+		return
+	}
+	name := fmt.Sprintf("%s-%d-report.pdf", analysis.Ticker, analysis.ID)
+	if c.Query("upload") == "true" {
+		deepSearchHandler.upload(c, path.Join("analyses", analysis.Ticker, renderedKey(name)), contentType, body)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// HandleGetChart renders the latest analysis of a ticker as a PNG chart of
+// price and cumulative VWAP with its signals marked. The bars are the
+// analysis's stored bars (see deepsearch.AnalysisBars).
+// Query parameters:
+//   - ticker: Ticker symbol (required)
+//   - start_duration: Window the analysis was triggered with (default: the latest analysis of any window)
+//   - width: Width in pixels, 320 to 4096 (default: 1024)
+//   - height: Height in pixels, 200 to 2048 (default: 400)
+//   - theme: light or dark (default: light)
+//   - upload: Write the PNG to report storage and return a signed URL to it instead (default: false)
+func (deepSearchHandler *DeepSearchHandler) HandleGetChart(c *gin.Context) {
+	opts, err := deepsearch.ParseChartOptions(c.Query("width"), c.Query("height"), c.Query("theme"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	analysis, bars, ok := deepSearchHandler.chartAnalysis(c)
+	if !ok {
+		return
+	}
+
+	png, err := deepsearch.AnalysisChartPNG(analysis, bars, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render chart", "details": err.Error()})
+		return
+	}
+	if c.Query("upload") == "true" {
+		name := fmt.Sprintf("%s-%d-%dx%d-%s.png", analysis.Ticker, analysis.ID, opts.Width, opts.Height, opts.Theme)
+		deepSearchHandler.upload(c, path.Join("charts", analysis.Ticker, renderedKey(name)), "image/png", png)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// renderedKey stamps the file name of a rendered chart or report so each
+// upload is a new object
+func renderedKey(name string) string {
+	return time.Now().UTC().Format("20060102T150405Z") + "_" + name
+}
+
+// upload writes a rendered chart or report to report storage and returns
+// where it is, with a signed download URL for bucket storage
+func (deepSearchHandler *DeepSearchHandler) upload(c *gin.Context, key, contentType string, body []byte) {
+	if deepSearchHandler.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Report storage is not configured"})
+		return
+	}
+	object, err := reports.Upload(c.Request.Context(), deepSearchHandler.store, key, contentType, body)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to upload", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, object)
+}
+
+// HandleGetChartSeries returns the latest analysis of a ticker as JSON series
+// for client-side charts: candles, volume, indicators and signal markers, each
+// point with a time in Unix seconds as TradingView Lightweight Charts takes
+// them. The bars are the analysis's stored bars (see deepsearch.AnalysisBars).
+// Query parameters:
+//   - ticker: Ticker symbol (required)
+//   - start_duration: Window the analysis was triggered with (default: the latest analysis of any window)
+func (deepSearchHandler *DeepSearchHandler) HandleGetChartSeries(c *gin.Context) {
+	analysis, bars, ok := deepSearchHandler.chartAnalysis(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, deepsearch.AnalysisChartSeries(analysis, bars))
+}
+
+// chartAnalysis loads the caller's latest analysis of the ticker query parameter, of
+// start_duration if given, and its stored bars. It writes the
+// error response and returns false when it cannot.
+func (deepSearchHandler *DeepSearchHandler) chartAnalysis(c *gin.Context) (*models.TechnicalSignal, []deepsearch.EnhancedBar, bool) {
+	ticker := c.Query("ticker")
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return nil, nil, false
+	}
+
+	filter := deepsearch.SignalFilter{Tickers: []string{ticker}, StartDuration: c.Query("start_duration")}
+	var analyses []models.TechnicalSignal
+	if err := filter.Apply(ownedBy(deepSearchHandler.db, ownerID(c))).Order("created_at desc").Limit(1).Find(&analyses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	if len(analyses) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+		return nil, nil, false
+	}
+
+	bars, _, err := deepsearch.AnalysisBars(c.Request.Context(), deepSearchHandler.db, &analyses[0], false)
+	if errors.Is(err, deepsearch.ErrBarsNotStored) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bars not stored", "details": err.Error()})
+		return nil, nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bars", "details": err.Error()})
+		return nil, nil, false
+	}
+	return &analyses[0], bars, true
+}
+
+// HandleGetSignalWeights returns the weight and confidence of each signal kind
+// that final decisions are scored with
+func (deepSearchHandler *DeepSearchHandler) HandleGetSignalWeights(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"weights": deepsearch.SignalWeights(), "default": deepsearch.DefaultSignalWeight()})
+}
+
+// maxComparisonWindows caps how many windows one comparison may run
+const maxComparisonWindows = 8
+
+// CompareRequest is the body of POST /api/v1/deepsearch/compare
+type CompareRequest struct {
+	Ticker  string                        `json:"ticker"`
+	Preset  string                        `json:"preset"`
+	Windows []deepsearch.ComparisonWindow `json:"windows"`
+}
+
+// HandleCompareWindows runs the pipeline over several historical windows of one
+// ticker (e.g. the week before each of the last earnings) and returns their
+// metrics side by side. Results are computed on the fly and not stored.
+func (deepSearchHandler *DeepSearchHandler) HandleCompareWindows(c *gin.Context) {
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+	if len(req.Windows) == 0 || len(req.Windows) > maxComparisonWindows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Between 1 and %d windows are required", maxComparisonWindows)})
+		return
+	}
+	for i := range req.Windows {
+		if req.Windows[i].Label == "" {
+			req.Windows[i].Label = fmt.Sprintf("window %d", i+1)
+		}
+		if err := deepsearch.ValidateWindow(req.Windows[i]); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := validateTicker(deepSearchHandler.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timeSpan, multiplier, ok := deepSearchHandler.aggregation(c, currentUserID(c), req.Preset)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, deepsearch.CompareWindows(c.Request.Context(), ticker, timeSpan, multiplier, req.Windows))
 }
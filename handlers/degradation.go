@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"institutionanalyser/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondDegradable writes body as JSON and, when it is degraded, names the
+// dependencies it did without in the degraded header. gin.H bodies get the
+// degraded fields added; typed bodies embed health.Degradation.
+func respondDegradable(c *gin.Context, status int, body interface{}, degradation *health.Degradation) {
+	if fields, ok := body.(gin.H); ok {
+		fields["degraded"] = degradation.Degraded
+		if degradation.Degraded {
+			fields["degraded_reasons"] = degradation.Reasons
+		}
+	}
+	if degradation.Degraded {
+		c.Header(health.Header, degradation.HeaderValue())
+	}
+	c.JSON(status, body)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+
+	"institutionanalyser/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSwaggerUICDN serves the Swagger UI assets the docs page loads
+const defaultSwaggerUICDN = "https://unpkg.com/swagger-ui-dist@5"
+
+// DocsHandler serves the OpenAPI document and Swagger UI
+type DocsHandler struct {
+	router *gin.Engine
+
+	once sync.Once
+	spec []byte
+	err  error
+}
+
+// NewDocsHandler creates a docs handler documenting the router's routes
+func NewDocsHandler(router *gin.Engine) *DocsHandler {
+	return &DocsHandler{router: router}
+}
+
+// HandleSpec returns the OpenAPI 3 document. It is built on the first request,
+// once every route has been registered.
+func (h *DocsHandler) HandleSpec(c *gin.Context) {
+	h.once.Do(func() {
+		doc := openapi.Build(openapi.Info{
+			Title:       "Institution Analyser API",
+			Version:     "1.0.0",
+			Description: "Institutional flow analysis, deep search signals and decisions. See API_CALL_DOCUMENTATION.md for worked examples.",
+		}, h.router.Routes(), apiRoutes)
+		h.spec, h.err = json.Marshal(doc)
+	})
+	if h.err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build OpenAPI document", "details": h.err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// HandleUI serves Swagger UI for the OpenAPI document. SWAGGER_UI_CDN
+// overrides where its assets are loaded from, e.g. an internal mirror.
+func (h *DocsHandler) HandleUI(c *gin.Context) {
+	cdn := os.Getenv("SWAGGER_UI_CDN")
+	if cdn == "" {
+		cdn = defaultSwaggerUICDN
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.UIPage("Institution Analyser API", "/openapi.json", cdn))
+}
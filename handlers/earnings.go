@@ -1,61 +1,48 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	"institutionanalyser/health"
+	"institutionanalyser/jobs"
+	"institutionanalyser/service"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // EarningsHandler handles earnings-related API endpoints
 type EarningsHandler struct {
 	PolygonAPIKey string
 	PolygonBaseURL string
+	db *gorm.DB
+	earnings *service.EarningsService
 }
 
 // NewEarningsHandler creates a new earnings handler
-func NewEarningsHandler() *EarningsHandler {
+func NewEarningsHandler(db *gorm.DB) *EarningsHandler {
 	apiKey := os.Getenv("POLYGON_API_KEY")
 	if apiKey == "" {
 		apiKey = os.Getenv("POLYGON_API_KEY") // Fallback, but will error if not set
 	}
 	
-	baseURL := os.Getenv("POLYGON_BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://api.polygon.io"
-	}
+	baseURL := service.PolygonBaseURL()
 	
 	return &EarningsHandler{
 		PolygonAPIKey: apiKey,
 		PolygonBaseURL: baseURL,
+		db: db,
+		earnings: service.NewEarningsService(),
 	}
 }
 
-// PolygonEarningsResponse represents the response from Polygon API
-type PolygonEarningsResponse struct {
-	Status    string    `json:"status"`
-	RequestID string    `json:"request_id"`
-	Count     int       `json:"count"`
-	Results   []EarningsResult `json:"results"`
-}
-
 // EarningsResult represents a single earnings announcement
-type EarningsResult struct {
-	Ticker         string  `json:"ticker"`
-	Date           string  `json:"date"`
-	ActualEPS      *float64 `json:"actual_eps,omitempty"`
-	ActualRevenue  *float64 `json:"actual_revenue,omitempty"`
-	EstimatedEPS   *float64 `json:"estimated_eps,omitempty"`
-	EstimatedRevenue *float64 `json:"estimated_revenue,omitempty"`
-	Importance     int     `json:"importance"`
-	Time           string  `json:"time,omitempty"`
-	Updated        string  `json:"updated,omitempty"`
-}
+type EarningsResult = service.EarningsResult
 
 // GetEarnings retrieves earnings announcements within a given time frame
 // Query parameters:
@@ -142,15 +129,17 @@ func (h *EarningsHandler) GetEarnings(c *gin.Context) {
 
 	// Collect earnings from all dates in the range
 	var allEarnings []EarningsResult
+	var degradation health.Degradation
 	currentDate := startDate
 	
 	for !currentDate.After(endDate) {
 		dateStr := currentDate.Format("2006-01-02")
 		
-		earnings, err := h.fetchEarningsFromPolygon(dateStr, ticker, importance, limit)
+		earnings, err := h.fetchEarnings(c.Request.Context(), dateStr, ticker, importance, limit, &degradation)
 		if err != nil {
 			// Log error but continue with other dates
 			fmt.Printf("Error fetching earnings for %s: %v\n", dateStr, err)
+			degradation.Add(health.Polygon, fmt.Sprintf("earnings for %s unavailable", dateStr))
 		} else {
 			allEarnings = append(allEarnings, earnings...)
 		}
@@ -161,57 +150,30 @@ func (h *EarningsHandler) GetEarnings(c *gin.Context) {
 	// Remove duplicates based on ticker and date combination
 	uniqueEarnings := removeDuplicateEarnings(allEarnings)
 
-	c.JSON(http.StatusOK, gin.H{
+	respondDegradable(c, http.StatusOK, gin.H{
 		"data": uniqueEarnings,
 		"count": len(uniqueEarnings),
 		"start_date": startDateStr,
 		"end_date": endDateStr,
 		"date_range_days": daysDiff + 1,
-	})
+	}, &degradation)
 }
 
-// fetchEarningsFromPolygon makes a request to Polygon API for a specific date
-func (h *EarningsHandler) fetchEarningsFromPolygon(date, ticker string, importance *int, limit int) ([]EarningsResult, error) {
-	// Build URL
-	url := fmt.Sprintf("%s/benzinga/v1/earnings?date=%s&limit=%d&apiKey=%s", 
-		h.PolygonBaseURL, date, limit, h.PolygonAPIKey)
-	
-	if ticker != "" {
-		url += fmt.Sprintf("&ticker=%s", ticker)
-	}
-	
-	if importance != nil {
-		url += fmt.Sprintf("&importance=%d", *importance)
-	}
-
-	// Make HTTP request
-	resp, err := http.Get(url)
+// fetchEarningsFromPolygon makes a request to Polygon API for a specific date.
+// Estimates are snapshotted on every fetch so revisions can be tracked.
+func (h *EarningsHandler) fetchEarningsFromPolygon(ctx context.Context, date, ticker string, importance *int, limit int) ([]EarningsResult, error) {
+	earnings, err := h.earnings.FetchEarnings(ctx, date, ticker, importance, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to Polygon API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Polygon API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
-	// Parse response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var polygonResp PolygonEarningsResponse
-	if err := json.Unmarshal(body, &polygonResp); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	if polygonResp.Status != "OK" {
-		return nil, fmt.Errorf("Polygon API returned non-OK status: %s", polygonResp.Status)
+	if h.db != nil {
+		if err := jobs.RecordEarningsEstimates(h.db, earnings); err != nil {
+			fmt.Printf("[API] failed to record earnings estimates for %s: %v\n", date, err)
+		}
 	}
 
-	return polygonResp.Results, nil
+	return earnings, nil
 }
 
 // removeDuplicateEarnings removes duplicate earnings entries based on ticker and date
@@ -1,46 +1,44 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"sync"
 	"time"
 
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // EarningsBigMoneyHandler handles earnings calendar with big money flow analysis
 type EarningsBigMoneyHandler struct {
-	PolygonAPIKey     string
-	PolygonBaseURL    string
-	TradeAnalysisURL  string
+	PolygonAPIKey  string
+	PolygonBaseURL string
+	tradeFlow      *service.TradeFlowService
+	db             *gorm.DB
 }
 
 // NewEarningsBigMoneyHandler creates a new earnings big money handler
-func NewEarningsBigMoneyHandler() *EarningsBigMoneyHandler {
+func NewEarningsBigMoneyHandler(db *gorm.DB) *EarningsBigMoneyHandler {
 	apiKey := os.Getenv("POLYGON_API_KEY")
 	if apiKey == "" {
 		apiKey = os.Getenv("POLYGON_API_KEY")
 	}
 
-	baseURL := os.Getenv("POLYGON_BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://api.polygon.io"
-	}
-
-	tradeAnalysisURL := os.Getenv("TRADE_ANALYSIS_API_URL")
-	if tradeAnalysisURL == "" {
-		tradeAnalysisURL = "http://localhost:8082"
-	}
+	baseURL := service.PolygonBaseURL()
 
 	return &EarningsBigMoneyHandler{
-		PolygonAPIKey:    apiKey,
-		PolygonBaseURL:   baseURL,
-		TradeAnalysisURL: tradeAnalysisURL,
+		PolygonAPIKey:  apiKey,
+		PolygonBaseURL: baseURL,
+		tradeFlow:      service.NewTradeFlowService(),
+		db:             db,
 	}
 }
 
@@ -48,8 +46,15 @@ func NewEarningsBigMoneyHandler() *EarningsBigMoneyHandler {
 type EarningsBigMoneyResponse struct {
 	Date           string                      `json:"date"`
 	TotalTickers   int                         `json:"total_tickers"`
+	// AnalysisDates is the resolved trading day analysed for each report session
+	AnalysisDates  map[string]string           `json:"analysis_dates,omitempty"`
 	Results        []EarningsBigMoneyResult    `json:"results"`
+	// Results split by report time; Other holds intraday and unknown times
+	PreMarket      []EarningsBigMoneyResult    `json:"pre_market"`
+	AfterHours     []EarningsBigMoneyResult    `json:"after_hours"`
+	Other          []EarningsBigMoneyResult    `json:"other"`
 	Summary        EarningsBigMoneySummary     `json:"summary"`
+	health.Degradation
 }
 
 // EarningsBigMoneyResult represents a single ticker's earnings + big money analysis
@@ -57,15 +62,21 @@ type EarningsBigMoneyResult struct {
 	Ticker              string  `json:"ticker"`
 	Date                string  `json:"date"`
 	Time                string  `json:"time,omitempty"`
+	Session             string  `json:"session"` // "PRE_MARKET", "DURING_MARKET", "AFTER_HOURS", "UNKNOWN"
 	EstimatedEPS        *float64 `json:"estimated_eps,omitempty"`
 	ActualEPS           *float64 `json:"actual_eps,omitempty"`
+	EstimatedRevenue    *float64 `json:"estimated_revenue,omitempty"`
+	ActualRevenue       *float64 `json:"actual_revenue,omitempty"`
 	Importance          int     `json:"importance"`
-	BigMoneyDirection   string  `json:"big_money_direction"` // "BUYING_PRESSURE", "SELLING_PRESSURE", "NEUTRAL", "ERROR", "NO_DATA"
+	BigMoneyDirection   string  `json:"big_money_direction"` // "BUYING_PRESSURE", "SELLING_PRESSURE", "NEUTRAL", "ERROR", "NO_DATA", "UNAVAILABLE"
 	NetBigMoneyFlow     *float64 `json:"net_big_money_flow,omitempty"`
 	LargeTradesCount    *int    `json:"large_trades_count,omitempty"`
 	BuyerInitiatedVol   *float64 `json:"buyer_initiated_volume,omitempty"`
 	SellerInitiatedVol  *float64 `json:"seller_initiated_volume,omitempty"`
 	AnalysisDate        *string  `json:"analysis_date,omitempty"`
+	// EPS estimate revision over the late window before the report, from stored snapshots
+	LateRevisionPct     *float64 `json:"late_revision_pct,omitempty"`
+	SharpLateRevision   bool     `json:"sharp_late_revision"`
 	Error               *string  `json:"error,omitempty"`
 }
 
@@ -75,125 +86,133 @@ type EarningsBigMoneySummary struct {
 	BearishCount    int `json:"bearish_count"`    // SELLING_PRESSURE
 	NeutralCount    int `json:"neutral_count"`    // NEUTRAL
 	ErrorCount      int `json:"error_count"`      // ERROR or NO_DATA
+	UnavailableCount int `json:"unavailable_count"` // UNAVAILABLE: skipped while Polygon was degraded
 	TotalAnalyzed   int `json:"total_analyzed"`
 }
 
-// TradeAnalysisResponse represents the response from tradeanalysis API
-type TradeAnalysisResponse struct {
-	Ticker              string         `json:"ticker"`
-	StartTime           time.Time      `json:"start_time"`
-	EndTime             time.Time      `json:"end_time"`
-	AnalysisDate        time.Time      `json:"analysis_date"`
-	LargeTradeThreshold float64        `json:"large_trade_threshold"`
-	Result              TradeAnalysisResult `json:"result"`
-}
-
-// TradeAnalysisResult holds the results from tradeanalysis API
-type TradeAnalysisResult struct {
-	TotalTrades           int          `json:"total_trades"`
-	AvgTradeSize          float64      `json:"avg_trade_size"`
-	LargeTradesCount      int          `json:"large_trades_count"`
-	NetBigMoneyFlow       float64      `json:"net_big_money_flow"`
-	BuyerInitiatedVolume  float64      `json:"buyer_initiated_volume"`
-	SellerInitiatedVolume float64      `json:"seller_initiated_volume"`
-	Direction             string       `json:"direction"` // "BUYING_PRESSURE", "SELLING_PRESSURE", "NEUTRAL"
+// BigMoneyRequest selects the earnings date and flow analysis of a big money
+// lookup. REST and gRPC callers share it; zero values use the defaults.
+type BigMoneyRequest struct {
+	// Date is the earnings date, YYYY-MM-DD
+	Date string
+	// AnalysisDate fixes the session analysed for every ticker (default: per report session)
+	AnalysisDate string
+	// LargeTradeThreshold is the large trade multiplier (default: 10.0)
+	LargeTradeThreshold float64
+	// Limit caps the earnings results (default: 100, max: 50000)
+	Limit int
 }
 
 // GetEarningsWithBigMoney analyzes earnings calendar and big money flow for each ticker
 // Query parameters:
 //   - date: Date in YYYY-MM-DD format (required) - earnings date
-//   - analysis_date: Date to analyze big money flow (default: one trading day before earnings date)
+//   - analysis_date: Date to analyze big money flow for every ticker (default: the prior session
+//     for pre-market reports, the report date's session for after-hours reports)
 //   - large_trade_threshold: Threshold multiplier for large trades (default: 10.0)
 //   - limit: Maximum number of earnings results per date (default: 100, max: 50000)
 func (h *EarningsBigMoneyHandler) GetEarningsWithBigMoney(c *gin.Context) {
-	if h.PolygonAPIKey == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Polygon API key not configured. Please set POLYGON_API_KEY environment variable.",
-		})
+	req := BigMoneyRequest{Date: c.Query("date"), AnalysisDate: c.DefaultQuery("analysis_date", "")}
+
+	// Get large_trade_threshold
+	thresholdStr := c.DefaultQuery("large_trade_threshold", "10.0")
+	if thresholdStr != "" {
+		threshold, err := strconv.ParseFloat(thresholdStr, 64)
+		if err == nil && threshold > 0 {
+			req.LargeTradeThreshold = threshold
+		}
+	}
+
+	// Get limit
+	limitStr := c.DefaultQuery("limit", "100")
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			req.Limit = parsedLimit
+		}
+	}
+
+	response, err := h.BigMoney(c.Request.Context(), req)
+	if err != nil {
+		writeOperationError(c, err)
 		return
 	}
 
+	respondDegradable(c, http.StatusOK, response, &response.Degradation)
+}
+
+// BigMoney fetches the earnings calendar of a date and analyzes each reporter's big money flow
+func (h *EarningsBigMoneyHandler) BigMoney(ctx context.Context, req BigMoneyRequest) (*EarningsBigMoneyResponse, error) {
+	if h.PolygonAPIKey == "" {
+		return nil, &RequestError{
+			Status:  http.StatusServiceUnavailable,
+			Message: "Polygon API key not configured. Please set POLYGON_API_KEY environment variable.",
+		}
+	}
+
 	// Parse query parameters
-	dateStr := c.Query("date")
+	dateStr := req.Date
 	if dateStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "date query parameter is required (format: YYYY-MM-DD)",
-		})
-		return
+		return nil, badRequest("date query parameter is required (format: YYYY-MM-DD)")
 	}
 
 	// Validate date format
 	earningsDate, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid date format. Use YYYY-MM-DD",
-		})
-		return
+		return nil, badRequest("Invalid date format. Use YYYY-MM-DD")
 	}
 
-	// Get analysis_date (default: one trading day before earnings date)
-	analysisDateStr := c.DefaultQuery("analysis_date", "")
-	var analysisDate time.Time
-	if analysisDateStr != "" {
-		analysisDate, err = time.Parse("2006-01-02", analysisDateStr)
+	// Get analysis_date (default: chosen per ticker from its report time)
+	var fixedAnalysisDate *time.Time
+	if req.AnalysisDate != "" {
+		analysisDate, err := time.Parse("2006-01-02", req.AnalysisDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid analysis_date format. Use YYYY-MM-DD",
-			})
-			return
-		}
-	} else {
-		// Default: one trading day before earnings date
-		analysisDate = earningsDate.AddDate(0, 0, -1)
-		// If earnings is on Monday, go back to Friday
-		if analysisDate.Weekday() == time.Sunday {
-			analysisDate = analysisDate.AddDate(0, 0, -2)
-		} else if analysisDate.Weekday() == time.Saturday {
-			analysisDate = analysisDate.AddDate(0, 0, -1)
+			return nil, badRequest("Invalid analysis_date format. Use YYYY-MM-DD")
 		}
+		fixedAnalysisDate = &analysisDate
 	}
 
-	// Get large_trade_threshold
 	largeThreshold := 10.0
-	thresholdStr := c.DefaultQuery("large_trade_threshold", "10.0")
-	if thresholdStr != "" {
-		threshold, err := strconv.ParseFloat(thresholdStr, 64)
-		if err == nil && threshold > 0 {
-			largeThreshold = threshold
-		}
+	if req.LargeTradeThreshold > 0 {
+		largeThreshold = req.LargeTradeThreshold
 	}
 
-	// Get limit
-	limitStr := c.DefaultQuery("limit", "100")
 	limit := 100
-	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-			if limit > 50000 {
-				limit = 50000
-			}
+	if req.Limit > 0 {
+		limit = req.Limit
+		if limit > 50000 {
+			limit = 50000
 		}
 	}
 
-	// Fetch earnings calendar for the date
-	earningsHandler := NewEarningsHandler()
-	earnings, err := earningsHandler.fetchEarningsFromPolygon(dateStr, "", nil, limit)
+	// Fetch earnings calendar for the date, from stored estimates if Polygon is down
+	var degradation health.Degradation
+	earningsHandler := NewEarningsHandler(h.db)
+	earnings, err := earningsHandler.fetchEarnings(ctx, dateStr, "", nil, limit, &degradation)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch earnings calendar",
-			"details": err.Error(),
-		})
-		return
+		return nil, &RequestError{Status: http.StatusBadGateway, Message: "Failed to fetch earnings calendar", Err: err}
 	}
 
 	if len(earnings) == 0 {
-		c.JSON(http.StatusOK, EarningsBigMoneyResponse{
+		return &EarningsBigMoneyResponse{
 			Date:         dateStr,
 			TotalTickers: 0,
 			Results:      []EarningsBigMoneyResult{},
+			PreMarket:    []EarningsBigMoneyResult{},
+			AfterHours:   []EarningsBigMoneyResult{},
+			Other:        []EarningsBigMoneyResult{},
 			Summary: EarningsBigMoneySummary{},
-		})
-		return
+		}, nil
+	}
+
+	// Resolve the analysis date of each session once, on the trading calendar
+	calendar := service.DefaultTradingCalendar()
+	analysisDates := make(map[string]string)
+	resolved := make(map[string]time.Time)
+	for _, session := range []string{SessionPreMarket, SessionDuringMarket, SessionAfterHours, SessionUnknown} {
+		resolved[session] = analysisDateForSession(ctx, calendar, earningsDate, session)
+		if fixedAnalysisDate != nil {
+			resolved[session] = *fixedAnalysisDate
+		}
+		analysisDates[session] = resolved[session].Format("2006-01-02")
 	}
 
 	// Analyze big money flow for each ticker concurrently
@@ -213,7 +232,9 @@ func (h *EarningsBigMoneyHandler) GetEarningsWithBigMoney(c *gin.Context) {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			result := h.analyzeTickerBigMoney(e, analysisDate, largeThreshold)
+			session := reportSession(e.Time)
+			result := h.analyzeTickerBigMoney(ctx, e, resolved[session], largeThreshold)
+			result.Session = session
 			
 			mu.Lock()
 			results = append(results, result)
@@ -223,6 +244,9 @@ func (h *EarningsBigMoneyHandler) GetEarningsWithBigMoney(c *gin.Context) {
 
 	wg.Wait()
 
+	h.flagLateRevisions(dateStr, results, &degradation)
+	sortBySession(results)
+
 	// Calculate summary
 	summary := EarningsBigMoneySummary{
 		TotalAnalyzed: len(results),
@@ -235,86 +259,122 @@ func (h *EarningsBigMoneyHandler) GetEarningsWithBigMoney(c *gin.Context) {
 			summary.BearishCount++
 		case "NEUTRAL":
 			summary.NeutralCount++
+		case "UNAVAILABLE":
+			summary.UnavailableCount++
 		default:
 			summary.ErrorCount++
 		}
 	}
 
 	response := EarningsBigMoneyResponse{
-		Date:         dateStr,
-		TotalTickers: len(results),
-		Results:      results,
-		Summary:      summary,
+		Date:          dateStr,
+		TotalTickers:  len(results),
+		AnalysisDates: analysisDates,
+		Results:       results,
+		PreMarket:     []EarningsBigMoneyResult{},
+		AfterHours:    []EarningsBigMoneyResult{},
+		Other:         []EarningsBigMoneyResult{},
+		Summary:       summary,
+		Degradation:   degradation,
+	}
+	if summary.UnavailableCount > 0 {
+		response.Add(health.Polygon, fmt.Sprintf("big money flow skipped for %d tickers", summary.UnavailableCount))
+	}
+	for _, r := range results {
+		switch r.Session {
+		case SessionPreMarket:
+			response.PreMarket = append(response.PreMarket, r)
+		case SessionAfterHours:
+			response.AfterHours = append(response.AfterHours, r)
+		default:
+			response.Other = append(response.Other, r)
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	return &response, nil
 }
 
 // analyzeTickerBigMoney analyzes big money flow for a single ticker
-func (h *EarningsBigMoneyHandler) analyzeTickerBigMoney(earning EarningsResult, analysisDate time.Time, largeThreshold float64) EarningsBigMoneyResult {
+func (h *EarningsBigMoneyHandler) analyzeTickerBigMoney(ctx context.Context, earning EarningsResult, analysisDate time.Time, largeThreshold float64) EarningsBigMoneyResult {
 	result := EarningsBigMoneyResult{
-		Ticker:     earning.Ticker,
-		Date:       earning.Date,
-		Time:       earning.Time,
-		EstimatedEPS: earning.EstimatedEPS,
-		ActualEPS:  earning.ActualEPS,
-		Importance: earning.Importance,
+		Ticker:           earning.Ticker,
+		Date:             earning.Date,
+		Time:             earning.Time,
+		EstimatedEPS:     earning.EstimatedEPS,
+		ActualEPS:        earning.ActualEPS,
+		EstimatedRevenue: earning.EstimatedRevenue,
+		ActualRevenue:    earning.ActualRevenue,
+		Importance:       earning.Importance,
 	}
 
-	// Call tradeanalysis API
-	analysisDateStr := analysisDate.Format("2006-01-02")
-	url := fmt.Sprintf("%s/api/v1/trade-analysis/%s?start_date=%s&large_trade_threshold=%.2f",
-		h.TradeAnalysisURL, earning.Ticker, analysisDateStr, largeThreshold)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to call tradeanalysis API: %v", err)
-		result.BigMoneyDirection = "ERROR"
-		result.Error = &errorMsg
-		return result
-	}
-	defer resp.Body.Close()
+	analysisDateFormatted := analysisDate.Format("2006-01-02")
+	result.AnalysisDate = &analysisDateFormatted
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		errorMsg := fmt.Sprintf("Tradeanalysis API returned status %d: %s", resp.StatusCode, string(bodyBytes))
-		result.BigMoneyDirection = "ERROR"
-		result.Error = &errorMsg
+	// Trades are not requested while Polygon is degraded, so one outage does
+	// not turn every row into a slow ERROR
+	if health.Degraded(health.Polygon) {
+		result.BigMoneyDirection = "UNAVAILABLE"
 		return result
 	}
 
-	// Parse response
-	body, err := io.ReadAll(resp.Body)
+	flow, err := h.tradeFlow.AnalyzeSession(ctx, earning.Ticker, analysisDate, largeThreshold)
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to read tradeanalysis response: %v", err)
-		result.BigMoneyDirection = "ERROR"
-		result.Error = &errorMsg
-		return result
-	}
-
-	var tradeAnalysis TradeAnalysisResponse
-	if err := json.Unmarshal(body, &tradeAnalysis); err != nil {
-		errorMsg := fmt.Sprintf("Failed to parse tradeanalysis response: %v", err)
+		errorMsg := fmt.Sprintf("Failed to analyze trades: %v", err)
 		result.BigMoneyDirection = "ERROR"
 		result.Error = &errorMsg
 		return result
 	}
 
 	// Populate result
-	result.BigMoneyDirection = tradeAnalysis.Result.Direction
-	result.NetBigMoneyFlow = &tradeAnalysis.Result.NetBigMoneyFlow
-	result.LargeTradesCount = &tradeAnalysis.Result.LargeTradesCount
-	result.BuyerInitiatedVol = &tradeAnalysis.Result.BuyerInitiatedVolume
-	result.SellerInitiatedVol = &tradeAnalysis.Result.SellerInitiatedVolume
-	
-	analysisDateFormatted := tradeAnalysis.AnalysisDate.Format("2006-01-02")
-	result.AnalysisDate = &analysisDateFormatted
+	result.BigMoneyDirection = flow.Direction
+	result.NetBigMoneyFlow = &flow.NetBigMoneyFlow
+	result.LargeTradesCount = &flow.LargeTradesCount
+	result.BuyerInitiatedVol = &flow.BuyerInitiatedVolume
+	result.SellerInitiatedVol = &flow.SellerInitiatedVolume
 
 	// Handle case where no trades were found
-	if tradeAnalysis.Result.TotalTrades == 0 {
+	if flow.TotalTrades == 0 {
 		result.BigMoneyDirection = "NO_DATA"
 	}
 
 	return result
 }
 
+// flagLateRevisions marks tickers whose EPS estimate moved sharply in the days before the report.
+// It is skipped, and the response marked degraded, while the database is unavailable.
+func (h *EarningsBigMoneyHandler) flagLateRevisions(reportDate string, results []EarningsBigMoneyResult, degradation *health.Degradation) {
+	if h.db == nil || len(results) == 0 {
+		return
+	}
+	if health.Degraded(health.Database) {
+		degradation.Add(health.Database, "late estimate revisions skipped")
+		return
+	}
+
+	tickers := make([]string, 0, len(results))
+	for _, r := range results {
+		tickers = append(tickers, r.Ticker)
+	}
+
+	var snapshots []models.EarningsEstimate
+	err := h.db.Where("report_date = ? AND ticker IN ?", reportDate, tickers).
+		Order("created_at ASC").
+		Find(&snapshots).Error
+	if err != nil {
+		fmt.Printf("[API] failed to load earnings estimates for %s: %v\n", reportDate, err)
+		degradation.Add(health.Database, "late estimate revisions unavailable")
+		return
+	}
+
+	byTicker := make(map[string][]models.EarningsEstimate)
+	for _, snapshot := range snapshots {
+		byTicker[snapshot.Ticker] = append(byTicker[snapshot.Ticker], snapshot)
+	}
+
+	config := GetEstimateRevisionConfig()
+	for i := range results {
+		summary := summariseEstimateRevisions(results[i].Ticker, reportDate, byTicker[results[i].Ticker], config)
+		results[i].LateRevisionPct = summary.LateRevisionPct
+		results[i].SharpLateRevision = summary.SharpLateRevision
+	}
+}
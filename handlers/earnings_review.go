@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetEarningsReview scores the reports of one date on EPS surprise,
+// revenue surprise and guidance
+// Query parameters:
+//   - date: Report date in YYYY-MM-DD format (required)
+//   - ticker: Optional filter by ticker symbol
+//   - limit: Maximum number of earnings results (default: 100, max: 50000)
+func (h *EarningsHandler) HandleGetEarningsReview(c *gin.Context) {
+	if h.PolygonAPIKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Polygon API key not configured. Please set POLYGON_API_KEY environment variable.",
+		})
+		return
+	}
+
+	date := c.Query("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date query parameter is required (format: YYYY-MM-DD)"})
+		return
+	}
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+
+	limit := 100
+	if parsedLimit, err := strconv.Atoi(c.DefaultQuery("limit", "100")); err == nil && parsedLimit > 0 {
+		limit = min(parsedLimit, 50000)
+	}
+
+	var degradation health.Degradation
+	earnings, err := h.fetchEarnings(c.Request.Context(), date, ticker, nil, limit, &degradation)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to fetch earnings calendar",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Guidance is optional input to the score, so a failed lookup only drops
+	// it, and it is not looked up while Polygon is degraded
+	guidanceByTicker := make(map[string]*service.GuidanceResult)
+	if health.Degraded(health.Polygon) {
+		degradation.Add(health.Polygon, "guidance skipped")
+	} else {
+		guidance, err := h.earnings.FetchGuidance(c.Request.Context(), date, ticker, limit)
+		if err != nil {
+			fmt.Printf("[API] failed to fetch guidance for %s: %v\n", date, err)
+			degradation.Add(health.Polygon, "guidance unavailable")
+		}
+		for i := range guidance {
+			guidanceByTicker[guidance[i].Ticker] = &guidance[i]
+		}
+	}
+
+	reviews := make([]service.EarningsReview, 0, len(earnings))
+	for _, e := range removeDuplicateEarnings(earnings) {
+		review := service.ReviewEarnings(e, guidanceByTicker[e.Ticker])
+		if review.Verdict == "NOT_REPORTED" {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+
+	sort.Slice(reviews, func(i, j int) bool {
+		return reviews[i].Score > reviews[j].Score
+	})
+
+	respondDegradable(c, http.StatusOK, gin.H{
+		"date":  date,
+		"data":  reviews,
+		"count": len(reviews),
+	}, &degradation)
+}
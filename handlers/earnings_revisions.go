@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// EstimateRevisionConfig decides when a revision close to the report counts as sharp
+type EstimateRevisionConfig struct {
+	// LateWindowDays is how many calendar days before the report count as "late"
+	LateWindowDays int
+	// SharpRevisionPct is the absolute EPS estimate change (percent) within the late window that gets flagged
+	SharpRevisionPct float64
+}
+
+// GetEstimateRevisionConfig reads late revision settings from environment variables
+func GetEstimateRevisionConfig() EstimateRevisionConfig {
+	config := EstimateRevisionConfig{
+		LateWindowDays:   7,
+		SharpRevisionPct: 5,
+	}
+
+	if val := os.Getenv("EARNINGS_LATE_REVISION_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.LateWindowDays = n
+		}
+	}
+
+	if val := os.Getenv("EARNINGS_SHARP_REVISION_PCT"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			config.SharpRevisionPct = n
+		}
+	}
+
+	return config
+}
+
+// EPSRevision is a single change of the EPS estimate
+type EPSRevision struct {
+	RevisedAt time.Time `json:"revised_at"`
+	From      float64   `json:"from"`
+	To        float64   `json:"to"`
+	ChangePct *float64  `json:"change_pct,omitempty"`
+}
+
+// EstimateRevisionSummary is the EPS estimate history leading into one report
+type EstimateRevisionSummary struct {
+	Ticker            string                    `json:"ticker"`
+	ReportDate        string                    `json:"report_date"`
+	InitialEPS        *float64                  `json:"initial_estimated_eps,omitempty"`
+	LatestEPS         *float64                  `json:"latest_estimated_eps,omitempty"`
+	TotalRevisionPct  *float64                  `json:"total_revision_pct,omitempty"`
+	LateRevisionPct   *float64                  `json:"late_revision_pct,omitempty"`
+	SharpLateRevision bool                      `json:"sharp_late_revision"`
+	LateWindowDays    int                       `json:"late_window_days"`
+	SharpRevisionPct  float64                   `json:"sharp_revision_pct"`
+	Revisions         []EPSRevision             `json:"revisions"`
+	Snapshots         []models.EarningsEstimate `json:"snapshots"`
+}
+
+// revisionTime is when the provider changed a snapshot, falling back to when it was first seen
+func revisionTime(snapshot models.EarningsEstimate) time.Time {
+	if snapshot.ProviderUpdated != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, snapshot.ProviderUpdated); err == nil {
+				return t
+			}
+		}
+	}
+	return snapshot.CreatedAt
+}
+
+// revisionPct is the percentage change from one estimate to another, nil when from is zero
+func revisionPct(from, to float64) *float64 {
+	if from == 0 {
+		return nil
+	}
+	pct := (to - from) / math.Abs(from) * 100
+	return &pct
+}
+
+// summariseEstimateRevisions builds the revision history from snapshots ordered oldest first
+func summariseEstimateRevisions(ticker, reportDate string, snapshots []models.EarningsEstimate, config EstimateRevisionConfig) EstimateRevisionSummary {
+	summary := EstimateRevisionSummary{
+		Ticker:           ticker,
+		ReportDate:       reportDate,
+		LateWindowDays:   config.LateWindowDays,
+		SharpRevisionPct: config.SharpRevisionPct,
+		Revisions:        []EPSRevision{},
+		Snapshots:        snapshots,
+	}
+
+	report, err := time.Parse("2006-01-02", reportDate)
+	if err != nil {
+		return summary
+	}
+	lateStart := report.AddDate(0, 0, -config.LateWindowDays)
+
+	// baseline is the last estimate in force when the late window opened
+	var baseline *float64
+	for _, snapshot := range snapshots {
+		eps := snapshot.EstimatedEPS
+		if eps == nil {
+			continue
+		}
+		revisedAt := revisionTime(snapshot)
+
+		if summary.InitialEPS == nil {
+			summary.InitialEPS = eps
+		} else if *summary.LatestEPS != *eps {
+			summary.Revisions = append(summary.Revisions, EPSRevision{
+				RevisedAt: revisedAt,
+				From:      *summary.LatestEPS,
+				To:        *eps,
+				ChangePct: revisionPct(*summary.LatestEPS, *eps),
+			})
+		}
+		if revisedAt.Before(lateStart) || baseline == nil {
+			baseline = eps
+		}
+		summary.LatestEPS = eps
+	}
+
+	if summary.InitialEPS != nil {
+		summary.TotalRevisionPct = revisionPct(*summary.InitialEPS, *summary.LatestEPS)
+		summary.LateRevisionPct = revisionPct(*baseline, *summary.LatestEPS)
+		if summary.LateRevisionPct != nil && math.Abs(*summary.LateRevisionPct) >= config.SharpRevisionPct {
+			summary.SharpLateRevision = true
+		}
+	}
+
+	return summary
+}
+
+// loadEstimateRevisions returns the stored revision history of one ticker's report
+func loadEstimateRevisions(db *gorm.DB, ticker, reportDate string, config EstimateRevisionConfig) (EstimateRevisionSummary, error) {
+	var snapshots []models.EarningsEstimate
+	err := db.Where("ticker = ? AND report_date = ?", ticker, reportDate).
+		Order("created_at ASC").
+		Find(&snapshots).Error
+	if err != nil {
+		return EstimateRevisionSummary{}, err
+	}
+	return summariseEstimateRevisions(ticker, reportDate, snapshots, config), nil
+}
+
+// HandleGetEstimateRevisions returns the EPS estimate revisions leading into a report
+// Query parameters:
+//   - ticker: Ticker symbol (required)
+//   - date: Report date in YYYY-MM-DD format (default: the ticker's latest stored report)
+func (h *EarningsHandler) HandleGetEstimateRevisions(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker query parameter is required"})
+		return
+	}
+
+	reportDate := c.Query("date")
+	if reportDate != "" {
+		if _, err := time.Parse("2006-01-02", reportDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	} else {
+		var latest models.EarningsEstimate
+		err := h.db.Where("ticker = ?", ticker).Order("report_date DESC").First(&latest).Error
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No earnings estimates recorded for ticker"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch earnings estimates", "details": err.Error()})
+			return
+		}
+		reportDate = latest.ReportDate
+	}
+
+	summary, err := loadEstimateRevisions(h.db, ticker, reportDate, GetEstimateRevisionConfig())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch earnings estimates", "details": err.Error()})
+		return
+	}
+	if len(summary.Snapshots) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No earnings estimates recorded for ticker and date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"institutionanalyser/service"
+)
+
+// Report sessions derived from the Benzinga earnings time (market time)
+const (
+	SessionPreMarket    = "PRE_MARKET"    // before the open (BMO)
+	SessionDuringMarket = "DURING_MARKET" // between 09:30 and 16:00
+	SessionAfterHours   = "AFTER_HOURS"   // at or after the close (AMC)
+	SessionUnknown      = "UNKNOWN"
+)
+
+// sessionOrder is the order sections appear in within a report date
+var sessionOrder = map[string]int{
+	SessionPreMarket:    0,
+	SessionDuringMarket: 1,
+	SessionAfterHours:   2,
+	SessionUnknown:      3,
+}
+
+// reportSession classifies a Benzinga time ("HH:MM:SS", or a BMO/AMC label)
+func reportSession(reportTime string) string {
+	switch strings.ToUpper(strings.TrimSpace(reportTime)) {
+	case "":
+		return SessionUnknown
+	case "BMO":
+		return SessionPreMarket
+	case "AMC":
+		return SessionAfterHours
+	}
+
+	var t time.Time
+	var err error
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err = time.Parse(layout, reportTime); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return SessionUnknown
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+	switch {
+	case minutes < 9*60+30:
+		return SessionPreMarket
+	case minutes >= 16*60:
+		return SessionAfterHours
+	default:
+		return SessionDuringMarket
+	}
+}
+
+// analysisDateForSession picks the last full session before the report: the
+// prior trading session for pre-market (and intraday or unknown) reports, and
+// the report date's own session for after-hours reports. Weekends and exchange
+// holidays are skipped.
+func analysisDateForSession(ctx context.Context, calendar *service.TradingCalendar, earningsDate time.Time, session string) time.Time {
+	if session == SessionAfterHours {
+		return calendar.OnOrBeforeTradingDay(ctx, earningsDate)
+	}
+	return calendar.PreviousTradingDay(ctx, earningsDate)
+}
+
+// sortBySession orders results by session, then report time, importance (highest first) and ticker
+func sortBySession(results []EarningsBigMoneyResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if sessionOrder[a.Session] != sessionOrder[b.Session] {
+			return sessionOrder[a.Session] < sessionOrder[b.Session]
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		if a.Importance != b.Importance {
+			return a.Importance > b.Importance
+		}
+		return a.Ticker < b.Ticker
+	})
+}
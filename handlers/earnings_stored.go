@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// fetchEarnings returns one date's announcements from Polygon, or from the
+// estimates stored by earlier fetches when Polygon fails or is degraded. A
+// stored fallback is noted on degradation.
+func (h *EarningsHandler) fetchEarnings(ctx context.Context, date, ticker string, importance *int, limit int, degradation *health.Degradation) ([]EarningsResult, error) {
+	fetchErr := errors.New("polygon is unavailable")
+	if !health.Degraded(health.Polygon) {
+		earnings, err := h.fetchEarningsFromPolygon(ctx, date, ticker, importance, limit)
+		if err == nil {
+			return earnings, nil
+		}
+		fetchErr = err
+	}
+	if h.db == nil {
+		return nil, fetchErr
+	}
+
+	stored, err := storedEarnings(ctx, h.db, date, ticker, importance, limit)
+	if err != nil {
+		return nil, errors.Join(fetchErr, err)
+	}
+	if len(stored) == 0 {
+		return nil, fetchErr
+	}
+	degradation.Add(health.Polygon, fmt.Sprintf("earnings for %s served from stored estimates", date))
+	return stored, nil
+}
+
+// storedEarnings returns the latest stored estimate of each announcement on a
+// date. Stored estimates lack surprise percentages and fiscal periods.
+func storedEarnings(ctx context.Context, db *gorm.DB, date, ticker string, importance *int, limit int) ([]EarningsResult, error) {
+	query := db.WithContext(ctx).Model(&models.EarningsEstimate{}).Where("report_date = ?", date)
+	if ticker != "" {
+		query = query.Where("ticker = ?", ticker)
+	}
+	if importance != nil {
+		query = query.Where("importance = ?", *importance)
+	}
+
+	var snapshots []models.EarningsEstimate
+	if err := models.LatestPer(query, "ticker", "created_at DESC, id DESC").Order("ticker").Limit(limit).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	earnings := make([]EarningsResult, 0, len(snapshots))
+	for _, s := range snapshots {
+		earnings = append(earnings, EarningsResult{
+			Ticker:           s.Ticker,
+			Date:             s.ReportDate,
+			ActualEPS:        s.ActualEPS,
+			ActualRevenue:    s.ActualRevenue,
+			EstimatedEPS:     s.EstimatedEPS,
+			EstimatedRevenue: s.EstimatedRevenue,
+			Importance:       s.Importance,
+			Time:             s.ReportTime,
+			Updated:          s.ProviderUpdated,
+		})
+	}
+	return earnings, nil
+}
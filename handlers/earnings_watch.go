@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bigMoneySeries is one reporter's intraday big-money flow on its report date
+type bigMoneySeries struct {
+	Ticker     string                  `json:"ticker"`
+	ReportTime string                  `json:"report_time,omitempty"`
+	Session    string                  `json:"session"`
+	Direction  string                  `json:"direction"`
+	Latest     float64                 `json:"latest_net_big_money_flow"`
+	Samples    []models.BigMoneySample `json:"samples"`
+}
+
+// HandleGetBigMoneyWatch returns the big-money flow the earnings watch sampled
+// through a report date, as a time series per reporter
+// Query parameters:
+//   - date: Report date, YYYY-MM-DD (default: today)
+//   - ticker: Only this reporter (optional)
+func (h *EarningsBigMoneyHandler) HandleGetBigMoneyWatch(c *gin.Context) {
+	date := time.Now().In(jobs.MarketTimezone)
+	if val := c.Query("date"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+	day := date.Format("2006-01-02")
+
+	query := h.db.Where("report_date = ?", day)
+	if ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker"))); ticker != "" {
+		query = query.Where("ticker = ?", ticker)
+	}
+	var samples []models.BigMoneySample
+	if err := query.Order("ticker, timestamp").Find(&samples).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch big money samples", "details": err.Error()})
+		return
+	}
+
+	var series []*bigMoneySeries
+	byTicker := make(map[string]*bigMoneySeries)
+	for _, sample := range samples {
+		s, ok := byTicker[sample.Ticker]
+		if !ok {
+			s = &bigMoneySeries{Ticker: sample.Ticker}
+			byTicker[sample.Ticker] = s
+			series = append(series, s)
+		}
+		s.ReportTime = sample.ReportTime
+		s.Session = reportSession(sample.ReportTime)
+		s.Direction = sample.Direction
+		s.Latest = sample.NetBigMoneyFlow
+		s.Samples = append(s.Samples, sample)
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		if sessionOrder[series[i].Session] != sessionOrder[series[j].Session] {
+			return sessionOrder[series[i].Session] < sessionOrder[series[j].Session]
+		}
+		return series[i].Ticker < series[j].Ticker
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"report_date": day,
+		"interval":    jobs.EarningsWatchInterval().String(),
+		"count":       len(series),
+		"data":        series,
+	})
+}
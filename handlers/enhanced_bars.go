@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// EnhancedBarsHandler serves bars with their indicators from the Timescale
+// enhanced bar store
+type EnhancedBarsHandler struct {
+	db *gorm.DB
+}
+
+// NewEnhancedBarsHandler creates a new enhanced bars handler
+func NewEnhancedBarsHandler(db *gorm.DB) *EnhancedBarsHandler {
+	return &EnhancedBarsHandler{db: db}
+}
+
+// HandleGetEnhancedBars returns a ticker's stored enhanced bars, oldest first
+// Query parameters:
+//   - from: First market date, YYYY-MM-DD (required)
+//   - to: Last market date, YYYY-MM-DD (default: today)
+//   - timespan: Bar size unit (default: minute)
+//   - multiplier: Bar size multiplier (default: 5)
+func (h *EnhancedBarsHandler) HandleGetEnhancedBars(c *gin.Context) {
+	if !models.GetTimescaleConfig().Enabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The enhanced bar store is not enabled (set TIMESCALE_ENABLED=true)"})
+		return
+	}
+
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	from, err := time.ParseInLocation("2006-01-02", c.Query("from"), jobs.MarketTimezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required, use YYYY-MM-DD"})
+		return
+	}
+	to := time.Now().In(jobs.MarketTimezone)
+	if val := c.Query("to"); val != "" {
+		if to, err = time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, use YYYY-MM-DD"})
+			return
+		}
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, jobs.MarketTimezone).AddDate(0, 0, 1)
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	timeSpan := c.DefaultQuery("timespan", "minute")
+	multiplier, err := strconv.Atoi(c.DefaultQuery("multiplier", "5"))
+	if err != nil || multiplier <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multiplier must be a positive integer"})
+		return
+	}
+
+	bars, err := models.FindEnhancedBars(h.db.WithContext(c.Request.Context()), ticker, timeSpan, multiplier, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enhanced bars", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticker":     ticker,
+		"timespan":   timeSpan,
+		"multiplier": multiplier,
+		"count":      len(bars),
+		"bars":       bars,
+	})
+}
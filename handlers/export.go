@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Export pagination headers
+const (
+	NextCursorHeader = "X-Next-Cursor"
+	HasMoreHeader    = "X-Has-More"
+)
+
+// exportFlushEvery is how many lines are written between flushes
+const exportFlushEvery = 500
+
+// ExportHandler serves bulk exports for external pipelines
+type ExportHandler struct {
+	db *gorm.DB
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(db *gorm.DB) *ExportHandler {
+	return &ExportHandler{db: db}
+}
+
+// ExportedDecision is one NDJSON line of the decision export: a stored
+// analysis's decision with its key metrics
+type ExportedDecision struct {
+	AnalysisID       uint               `json:"analysis_id"`
+	CreatedAt        time.Time          `json:"created_at"`
+	Ticker           string             `json:"ticker"`
+	StartDate        time.Time          `json:"start_date"`
+	EndDate          time.Time          `json:"end_date"`
+	TimeSpan         string             `json:"timespan"`
+	Multiplier       int                `json:"multiplier"`
+	FinalDecision    string             `json:"final_decision"`
+	Confidence       float64            `json:"confidence"`
+	LastClose        float64            `json:"last_close"`
+	Currency         string             `json:"currency"`
+	MaxVolumeZScore  float64            `json:"max_volume_zscore"`
+	SignalCount      int                `json:"signal_count"`
+	TrailingStop     float64            `json:"trailing_stop"`
+	TrailingStopSide string             `json:"trailing_stop_side"`
+	StrategyID       uint               `json:"strategy_id"`
+	AlgoVersion      int                `json:"algo_version"`
+	Tags             models.StringArray `json:"tags"`
+}
+
+// exportCursor is the position after the last exported row, ordered by
+// created_at then id
+type exportCursor struct {
+	createdAt time.Time
+	id        uint
+}
+
+// encode returns the cursor as an opaque URL-safe token
+func (c exportCursor) encode() string {
+	raw := fmt.Sprintf("%d:%d", c.createdAt.UnixMicro(), c.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeExportCursor(token string) (exportCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return exportCursor{}, errors.New("invalid cursor")
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return exportCursor{}, errors.New("invalid cursor")
+	}
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return exportCursor{}, errors.New("invalid cursor")
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return exportCursor{}, errors.New("invalid cursor")
+	}
+	return exportCursor{createdAt: time.UnixMicro(us), id: uint(n)}, nil
+}
+
+// parseSince reads an RFC3339 timestamp or a YYYY-MM-DD date (midnight UTC)
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("invalid since, use RFC3339 or YYYY-MM-DD")
+}
+
+// HandleExportDecisions streams the caller's stored decisions created since a timestamp as
+// NDJSON, oldest first. Pages are keyset-paginated on (created_at, id), so
+// rows stored during a sync are neither skipped nor repeated; pass the
+// X-Next-Cursor header of one page as cursor to fetch the next.
+// Query parameters:
+//   - since: RFC3339 timestamp or YYYY-MM-DD (required without cursor)
+//   - cursor: Cursor from the previous page; takes precedence over since
+//   - limit: Rows per page (default: 1000, max: 10000)
+func (h *ExportHandler) HandleExportDecisions(c *gin.Context) {
+	var after exportCursor
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := decodeExportCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		after = cursor
+	} else {
+		since := c.Query("since")
+		if since == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since or cursor is required"})
+			return
+		}
+		t, err := parseSince(since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// id 0 sorts before every row created at exactly since
+		after = exportCursor{createdAt: t}
+	}
+
+	limit, _ := parsePagination(c, 1000, 10000)
+
+	// One row past the page tells whether another page follows
+	var rows []ExportedDecision
+	err := ownedBy(h.db.Model(&models.TechnicalSignal{}), ownerID(c)).
+		Select(`id AS analysis_id, created_at, ticker, start_date, end_date,
+			poly_time_span AS time_span, poly_multiplier AS multiplier, final_decision, confidence,
+			last_close, currency, max_volume_z_score, `+models.ArrayLength(h.db, "signals")+` AS signal_count,
+			trailing_stop, trailing_stop_side, strategy_id, algo_version, tags`).
+		Where("(created_at, id) > (?, ?)", after.createdAt, after.id).
+		Order("created_at, id").
+		Limit(limit + 1).
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export decisions", "details": err.Error()})
+		return
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		c.Header(NextCursorHeader, exportCursor{createdAt: last.CreatedAt, id: last.AnalysisID}.encode())
+	} else {
+		c.Header(NextCursorHeader, after.encode())
+	}
+	c.Header(HasMoreHeader, strconv.FormatBool(hasMore))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for i, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			// The client went away; the next sync resumes from its last cursor
+			fmt.Printf("[API] decision export aborted after %d rows: %v\n", i, err)
+			return
+		}
+		if (i+1)%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	c.Writer.Flush()
+}
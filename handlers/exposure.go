@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unknownSector groups tickers without synced reference data
+const unknownSector = "Unknown"
+
+// ExposureRequest is the body of POST /api/v1/decisions/exposure. Positions
+// carry relative weights; tickers listed without a weight count as 1.
+type ExposureRequest struct {
+	Positions []ExposurePosition `json:"positions"`
+	Tickers   []string           `json:"tickers"`
+	// Tag optionally restricts the lookup to analyses carrying this tag
+	Tag string `json:"tag"`
+}
+
+// ExposurePosition is one watchlist or portfolio entry
+type ExposurePosition struct {
+	Ticker string  `json:"ticker"`
+	Weight float64 `json:"weight"`
+}
+
+// BookEntry is one ticker's contribution to the book
+type BookEntry struct {
+	Ticker        string    `json:"ticker"`
+	Sector        string    `json:"sector"`
+	FinalDecision string    `json:"final_decision"`
+	Confidence    float64   `json:"confidence"`
+	AnalysisID    uint      `json:"analysis_id"`
+	AnalysedAt    time.Time `json:"analysed_at"`
+	// Weight is the position's share of the book's total weight
+	Weight float64 `json:"weight"`
+	// Direction is +1 for BUY, -1 for SELL and 0 otherwise
+	Direction int `json:"direction"`
+	// ConvictionExposure is weight x direction x confidence
+	ConvictionExposure float64 `json:"conviction_exposure"`
+}
+
+// ExposureSummary aggregates directional exposure over a set of book entries
+type ExposureSummary struct {
+	Weight float64 `json:"weight"`
+	// NetExposure is long minus short weight, as a share of this group's weight
+	NetExposure float64 `json:"net_exposure"`
+	// GrossExposure is long plus short weight, as a share of this group's weight
+	GrossExposure float64 `json:"gross_exposure"`
+	// ConvictionNet weights each direction by the decision's confidence
+	ConvictionNet float64 `json:"conviction_net"`
+	Long          int     `json:"long"`
+	Short         int     `json:"short"`
+	Neutral       int     `json:"neutral"`
+}
+
+// SectorTilt is the exposure summary of one sector
+type SectorTilt struct {
+	Sector string `json:"sector"`
+	ExposureSummary
+	Tickers []string `json:"tickers"`
+}
+
+// HandleExposure aggregates the latest decisions of a watchlist or portfolio
+// into net directional exposure, sector tilts and a conviction-weighted book
+// Body: {"positions": [{"ticker": "NVDA", "weight": 2}], "tickers": ["AAPL"], "tag": "core"}
+func (h *DecisionsHandler) HandleExposure(c *gin.Context) {
+	var req ExposureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	weights := map[string]float64{}
+	var tickers []string
+	for _, p := range req.Positions {
+		if p.Weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Position weights must not be negative"})
+			return
+		}
+		tickers = append(tickers, p.Ticker)
+		weights[strings.ToUpper(strings.TrimSpace(p.Ticker))] += p.Weight
+	}
+	for _, t := range req.Tickers {
+		tickers = append(tickers, t)
+		if t = strings.ToUpper(strings.TrimSpace(t)); weights[t] == 0 {
+			weights[t] = 1
+		}
+	}
+
+	tickers = normalizeTickers(tickers)
+	if len(tickers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one ticker is required"})
+		return
+	}
+	if len(tickers) > MaxBulkDecisionTickers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many tickers, maximum is 500"})
+		return
+	}
+
+	decisions, err := latestDecisions(ownedBy(h.db, ownerID(c)), tickers, strings.ToLower(strings.TrimSpace(req.Tag)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var refs []models.Ticker
+	if err := h.db.Select("ticker", "sector").Where("ticker IN ?", tickers).Find(&refs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sectors := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		sectors[ref.Ticker] = ref.Sector
+	}
+
+	found := make(map[string]bool, len(decisions))
+	for _, d := range decisions {
+		found[d.Ticker] = true
+	}
+	missing := []string{}
+	for _, t := range tickers {
+		if !found[t] {
+			missing = append(missing, t)
+		}
+	}
+
+	book, sectorTilts, portfolio := buildExposure(decisions, weights, sectors)
+	c.JSON(http.StatusOK, gin.H{
+		"as_of":     time.Now().UTC(),
+		"portfolio": portfolio,
+		"sectors":   sectorTilts,
+		"book":      book,
+		"missing":   missing,
+	})
+}
+
+// buildExposure weights each decision, normalising over tickers that have one,
+// and returns the book ordered by conviction, sector tilts ordered by
+// conviction and the portfolio summary
+func buildExposure(decisions []LatestDecision, weights map[string]float64, sectors map[string]string) ([]BookEntry, []SectorTilt, ExposureSummary) {
+	total := 0.0
+	for _, d := range decisions {
+		total += weights[d.Ticker]
+	}
+
+	book := make([]BookEntry, 0, len(decisions))
+	for _, d := range decisions {
+		entry := BookEntry{
+			Ticker:        d.Ticker,
+			Sector:        sectors[d.Ticker],
+			FinalDecision: d.FinalDecision,
+			Confidence:    d.Confidence,
+			AnalysisID:    d.AnalysisID,
+			AnalysedAt:    d.CreatedAt,
+		}
+		if entry.Sector == "" {
+			entry.Sector = unknownSector
+		}
+		if total > 0 {
+			entry.Weight = weights[d.Ticker] / total
+		}
+		switch d.FinalDecision {
+		case "BUY":
+			entry.Direction = 1
+		case "SELL":
+			entry.Direction = -1
+		}
+		entry.ConvictionExposure = entry.Weight * float64(entry.Direction) * entry.Confidence
+		book = append(book, entry)
+	}
+	sort.SliceStable(book, func(i, j int) bool {
+		return math.Abs(book[i].ConvictionExposure) > math.Abs(book[j].ConvictionExposure)
+	})
+
+	bySector := map[string]*SectorTilt{}
+	var order []string
+	for _, entry := range book {
+		tilt, ok := bySector[entry.Sector]
+		if !ok {
+			tilt = &SectorTilt{Sector: entry.Sector}
+			bySector[entry.Sector] = tilt
+			order = append(order, entry.Sector)
+		}
+		tilt.add(entry)
+		tilt.Tickers = append(tilt.Tickers, entry.Ticker)
+	}
+	tilts := make([]SectorTilt, 0, len(order))
+	for _, sector := range order {
+		tilt := bySector[sector]
+		tilt.finish()
+		tilts = append(tilts, *tilt)
+	}
+	sort.SliceStable(tilts, func(i, j int) bool {
+		return math.Abs(tilts[i].ConvictionNet*tilts[i].Weight) > math.Abs(tilts[j].ConvictionNet*tilts[j].Weight)
+	})
+
+	var portfolio ExposureSummary
+	for _, entry := range book {
+		portfolio.add(entry)
+	}
+	portfolio.finish()
+
+	return book, tilts, portfolio
+}
+
+// add accumulates an entry's absolute exposures; finish turns them into shares
+func (s *ExposureSummary) add(entry BookEntry) {
+	s.Weight += entry.Weight
+	s.NetExposure += entry.Weight * float64(entry.Direction)
+	s.GrossExposure += entry.Weight * math.Abs(float64(entry.Direction))
+	s.ConvictionNet += entry.ConvictionExposure
+	switch entry.Direction {
+	case 1:
+		s.Long++
+	case -1:
+		s.Short++
+	default:
+		s.Neutral++
+	}
+}
+
+func (s *ExposureSummary) finish() {
+	if s.Weight == 0 {
+		return
+	}
+	s.NetExposure /= s.Weight
+	s.GrossExposure /= s.Weight
+	s.ConvictionNet /= s.Weight
+}
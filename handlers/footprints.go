@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FootprintsHandler serves weekly institutional footprints
+type FootprintsHandler struct {
+	db *gorm.DB
+}
+
+// NewFootprintsHandler creates a new footprints handler
+func NewFootprintsHandler(db *gorm.DB) *FootprintsHandler {
+	return &FootprintsHandler{db: db}
+}
+
+// HandleGetFootprints returns a ticker's stored footprints, most recent week first
+// Query parameters:
+//   - week: Any date in the week, YYYY-MM-DD; returns that week's footprint only
+//   - limit: Weeks per page when listing (default: 12, max 104)
+//   - offset: Number of weeks to skip (default: 0)
+func (h *FootprintsHandler) HandleGetFootprints(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	if val := c.Query("week"); val != "" {
+		week, err := parseFootprintWeek(val)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var footprint models.InstitutionalFootprint
+		err = h.db.Where("ticker = ? AND week_start = ?", ticker, week.Format("2006-01-02")).First(&footprint).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No footprint stored for this week"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch footprint", "details": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, footprint)
+		return
+	}
+
+	limit, offset := parsePagination(c, 12, 104)
+	query := h.db.Model(&models.InstitutionalFootprint{}).Where("ticker = ?", ticker)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count footprints", "details": err.Error()})
+		return
+	}
+
+	var footprints []models.InstitutionalFootprint
+	if err := query.Order("week_start DESC").Limit(limit).Offset(offset).Find(&footprints).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch footprints", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": footprints,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(footprints),
+		},
+	})
+}
+
+// HandleBuildFootprint builds and stores a ticker's footprint now, replacing a stored one
+// Query parameters:
+//   - week: Any date in the week, YYYY-MM-DD (default: the last completed week)
+func (h *FootprintsHandler) HandleBuildFootprint(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	week := deepsearch.LastCompletedWeek(time.Now())
+	if val := c.Query("week"); val != "" {
+		var err error
+		if week, err = parseFootprintWeek(val); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	footprint, err := jobs.RefreshFootprint(c.Request.Context(), h.db, ticker, week)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to build footprint", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, footprint)
+}
+
+// parseFootprintWeek returns the Monday of the week containing a YYYY-MM-DD date
+func parseFootprintWeek(val string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", val)
+	if err != nil {
+		return time.Time{}, errors.New("week must be a date, use YYYY-MM-DD")
+	}
+	return deepsearch.FootprintWeek(time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)), nil
+}
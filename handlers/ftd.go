@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultFTDDays is the span fails-to-deliver are returned for without from.
+// The SEC publishes them weeks late, so it reaches further back than most.
+const defaultFTDDays = 180
+
+// FTDHandler serves the SEC fails-to-deliver data
+type FTDHandler struct {
+	db *gorm.DB
+}
+
+// NewFTDHandler creates a new fails-to-deliver handler
+func NewFTDHandler(db *gorm.DB) *FTDHandler {
+	return &FTDHandler{db: db}
+}
+
+// HandleGetFailsToDeliver returns a ticker's stored fails-to-deliver by
+// settlement date, most recent first, and its spikes between from and to
+// Query parameters:
+//   - from: Earliest settlement date, YYYY-MM-DD (default: 180 days before to)
+//   - to: Latest settlement date, YYYY-MM-DD (default: today)
+//   - limit: Settlement dates per page (default: 50, max 500)
+//   - offset: Number of settlement dates to skip (default: 0)
+func (h *FTDHandler) HandleGetFailsToDeliver(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	limit, offset := parsePagination(c, 50, 500)
+
+	to := time.Now().In(jobs.MarketTimezone)
+	if val := c.Query("to"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -defaultFTDDays)
+	if val := c.Query("from"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	if to.Sub(from) > 3*365*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be at most 3 years apart"})
+		return
+	}
+
+	query := h.db.Model(&models.FailToDeliver{}).
+		Where("ticker = ? AND settlement_date >= ? AND settlement_date <= ?", ticker, from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count fails-to-deliver", "details": err.Error()})
+		return
+	}
+	var fails []models.FailToDeliver
+	if err := query.Order("settlement_date DESC").Limit(limit).Offset(offset).Find(&fails).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fails-to-deliver", "details": err.Error()})
+		return
+	}
+
+	spikes, err := deepsearch.FTDSpikes(c.Request.Context(), h.db, ticker, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find fails-to-deliver spikes", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticker": ticker,
+		"from":   from.Format("2006-01-02"),
+		"to":     to.Format("2006-01-02"),
+		"data":   fails,
+		"spikes": spikes,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(fails),
+		},
+	})
+}
+
+// HandleIngestFailsToDeliver loads one half-month fails-to-deliver file now,
+// replacing its stored rows
+// Query parameters:
+//   - period: Month and half, e.g. 202505a for May 1-15 or 202505b for May 16-31
+//     (default: the last period before the current one)
+func (h *FTDHandler) HandleIngestFailsToDeliver(c *gin.Context) {
+	period := strings.ToLower(strings.TrimSpace(c.Query("period")))
+	if period == "" {
+		period = deepsearch.RecentFTDPeriods(time.Now().In(jobs.MarketTimezone), 1)[0]
+	}
+	if !deepsearch.ValidFTDPeriod(period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be a month and half, e.g. 202505a or 202505b"})
+		return
+	}
+
+	ingest, err := deepsearch.IngestFailsToDeliver(c.Request.Context(), h.db, period)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load fails-to-deliver", "details": err.Error()})
+		return
+	}
+	if !ingest.Published {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The SEC has not published this period yet", "period": period})
+		return
+	}
+	c.JSON(http.StatusOK, ingest)
+}
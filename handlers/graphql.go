@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"institutionanalyser/graphql"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GraphQLHandler serves GraphQL queries over stored analyses, deep search
+// requests and earnings, so dashboards fetch the fields they need in one
+// round trip
+type GraphQLHandler struct {
+	db     *gorm.DB
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(db *gorm.DB) *GraphQLHandler {
+	h := &GraphQLHandler{db: db}
+	h.schema = newGraphQLSchema(h)
+	return h
+}
+
+// HandleQuery executes a GraphQL query. POST takes a JSON body with query,
+// operationName and variables; GET takes them as query parameters, with
+// variables JSON-encoded. Field errors are returned alongside the data with
+// status 200; a query that fails to parse or validate returns 400.
+func (h *GraphQLHandler) HandleQuery(c *gin.Context) {
+	var req graphql.Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if val := c.Query("variables"); val != "" {
+			if err := json.Unmarshal([]byte(val), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variables", "details": err.Error()})
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphQLUserKey{}, currentUserID(c))
+	ctx = context.WithValue(ctx, graphQLOwnerKey{}, ownerID(c))
+	response := h.schema.Execute(ctx, req)
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, response)
+}
+
+// HandleSchema returns the schema in the GraphQL schema definition language,
+// for client code generators
+func (h *GraphQLHandler) HandleSchema(c *gin.Context) {
+	c.String(http.StatusOK, h.schema.SDL())
+}
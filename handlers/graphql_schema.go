@@ -0,0 +1,901 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/graphql"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// graphQLMaxLimit caps the limit argument of every list field
+const graphQLMaxLimit = 500
+
+// graphQLMaxDepth caps selection nesting, so one query cannot fan out
+// analyses -> earnings -> analyses indefinitely
+const graphQLMaxDepth = 5
+
+// graphQLMaxCost caps the objects one query may resolve: limits multiply
+// down nested lists, so 50 analyses x 4 earnings x 50 revisions is 10,250
+const graphQLMaxCost = 20000
+
+// graphQLMaxAliases caps aliased fields, which otherwise repeat a list
+// field any number of times in one query
+const graphQLMaxAliases = 20
+
+// graphQLMaxNodes caps the fields a query selects with its fragments spread
+// in place, since fragments spreading each other grow it exponentially
+const graphQLMaxNodes = 1000
+
+var (
+	timeScalar = &graphql.Scalar{
+		Name:        "Time",
+		Description: "An RFC 3339 timestamp",
+		Serialize: func(v interface{}) (interface{}, error) {
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("Time cannot represent %T", v)
+			}
+			return t.Format(time.RFC3339), nil
+		},
+		Parse: func(v interface{}) (interface{}, error) {
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("Time cannot represent %v, use RFC 3339", v)
+			}
+			return t, nil
+		},
+	}
+	jsonScalar = &graphql.Scalar{
+		Name:        "JSON",
+		Description: "A JSON value, shaped as in the REST responses",
+		Serialize:   func(v interface{}) (interface{}, error) { return v, nil },
+		Parse:       func(v interface{}) (interface{}, error) { return v, nil },
+	}
+
+	decisionEnum = &graphql.Enum{
+		Name:   "Decision",
+		Values: []string{"BUY", "SELL", "HOLD", "STRADDLE"},
+	}
+	directionEnum = &graphql.Enum{
+		Name:   "SignalDirection",
+		Values: []string{"CALL", "PUT", "UP", "DOWN", "STRADDLE"},
+	}
+	regimeEnum = &graphql.Enum{
+		Name:        "Regime",
+		Description: "The market regime by the signal bar's ADX",
+		Values:      []string{"TRENDING", "CHOPPY"},
+	}
+	analysisSortEnum = &graphql.Enum{
+		Name:   "AnalysisSort",
+		Values: []string{"CREATED_AT", "CONFIDENCE", "TICKER", "START_DATE"},
+	}
+	sortOrderEnum = &graphql.Enum{
+		Name:   "SortOrder",
+		Values: []string{"ASC", "DESC"},
+	}
+)
+
+// graphQLUserKey holds the caller's user ID in a GraphQL request's context
+type graphQLUserKey struct{}
+
+func graphQLUser(ctx context.Context) string {
+	if userID, ok := ctx.Value(graphQLUserKey{}).(string); ok && userID != "" {
+		return userID
+	}
+	return DefaultUserID
+}
+
+// graphQLOwnerKey holds the user whose analyses and requests a GraphQL
+// request may read, "" for admins (see ownerID)
+type graphQLOwnerKey struct{}
+
+func graphQLOwner(ctx context.Context) string {
+	userID, _ := ctx.Value(graphQLOwnerKey{}).(string)
+	return userID
+}
+
+// newGraphQLSchema builds the schema served at /api/v1/graphql: stored
+// analyses, deep search requests and earnings, each linked to the others
+// by ticker
+func newGraphQLSchema(h *GraphQLHandler) *graphql.Schema {
+	signalFilter := &graphql.InputObject{
+		Name:        "TechnicalSignalFilter",
+		Description: "Selects analyses. Fields are combined with AND; or adds filters of which at least one must also match.",
+		Fields: []*graphql.InputField{
+			{Name: "tickers", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+			{Name: "startDuration", Description: "The window start the analysis was triggered with, YYYY-MM-DD", Type: graphql.String},
+			{Name: "from", Description: "Keep analyses whose window ends on or after this date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "to", Description: "Keep analyses whose window starts on or before this date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "signalTypes", Description: "Keep analyses with at least one signal of these kinds, e.g. \"Bollinger Breakout\"", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+			{Name: "minConfidence", Description: "Minimum confidence, 0-1", Type: graphql.Float},
+			{Name: "decisions", Type: graphql.ListOf(graphql.NonNullOf(decisionEnum))},
+			{Name: "algoVersions", Type: graphql.ListOf(graphql.NonNullOf(graphql.Int))},
+			{Name: "tags", Description: "Keep analyses carrying all of these tags", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+			{Name: "userId", Description: "Only analyses run by this user ID, or \"me\" for the caller", Type: graphql.String},
+		},
+	}
+	signalFilter.Fields = append(signalFilter.Fields, &graphql.InputField{Name: "or", Type: graphql.ListOf(graphql.NonNullOf(signalFilter))})
+
+	structuredSignalFilter := &graphql.InputObject{
+		Name:        "StructuredSignalFilter",
+		Description: "Selects an analysis's signals. Fields are combined with AND.",
+		Fields: []*graphql.InputField{
+			{Name: "directions", Type: graphql.ListOf(graphql.NonNullOf(directionEnum))},
+			{Name: "regimes", Type: graphql.ListOf(graphql.NonNullOf(regimeEnum))},
+			{Name: "minAdx", Type: graphql.Float},
+			{Name: "signalTypes", Description: "Keep signals of these kinds, including kinds merged into a consolidated signal", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+		},
+	}
+	earningsFilter := &graphql.InputObject{
+		Name: "EarningsFilter",
+		Fields: []*graphql.InputField{
+			{Name: "tickers", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+			{Name: "date", Description: "Report date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "from", Description: "Earliest report date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "to", Description: "Latest report date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "minImportance", Description: "Minimum Benzinga importance, 0-5", Type: graphql.Int},
+		},
+	}
+	requestFilter := &graphql.InputObject{
+		Name: "DeepSearchRequestFilter",
+		Fields: []*graphql.InputField{
+			{Name: "tickers", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+			{Name: "userId", Description: "Only requests made by this user ID, or \"me\" for the caller", Type: graphql.String},
+			{Name: "from", Description: "Requests made on or after this date, YYYY-MM-DD", Type: graphql.String},
+			{Name: "to", Description: "Requests made on or before this date, YYYY-MM-DD", Type: graphql.String},
+		},
+	}
+
+	signalsArgs := func(defaultLimit int) []*graphql.InputField {
+		return []*graphql.InputField{
+			{Name: "filter", Type: signalFilter},
+			{Name: "sort", Type: analysisSortEnum, Default: "CREATED_AT"},
+			{Name: "order", Type: sortOrderEnum, Default: "DESC"},
+			{Name: "limit", Type: graphql.Int, Default: defaultLimit},
+			{Name: "offset", Type: graphql.Int, Default: 0},
+		}
+	}
+	earningsArgs := func(defaultLimit int, defaultOrder string) []*graphql.InputField {
+		return []*graphql.InputField{
+			{Name: "filter", Type: earningsFilter},
+			{Name: "order", Description: "Report date order", Type: sortOrderEnum, Default: defaultOrder},
+			{Name: "limit", Type: graphql.Int, Default: defaultLimit},
+			{Name: "offset", Type: graphql.Int, Default: 0},
+		}
+	}
+	pageArgs := func(defaultLimit int) []*graphql.InputField {
+		return []*graphql.InputField{
+			{Name: "limit", Type: graphql.Int, Default: defaultLimit},
+			{Name: "offset", Type: graphql.Int, Default: 0},
+		}
+	}
+
+	structuredSignal := &graphql.Object{
+		Name:        "StructuredSignal",
+		Description: "The parsed form of a stored signal",
+		Fields: []*graphql.Field{
+			{Name: "time", Description: "Bar time, HH:MM market time", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "direction", Description: "CALL, PUT, UP, DOWN or STRADDLE", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "description", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "close", Type: graphql.NonNullOf(graphql.Float)},
+			{Name: "regime", Description: "TRENDING or CHOPPY, empty for signals stored before ADX", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "adx", Type: graphql.NonNullOf(graphql.Float)},
+			{Name: "reasons", Description: "The kinds merged into a consolidated signal, its own kind first", Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(graphql.String)))},
+			{Name: "raw", Description: "The signal as stored", Type: graphql.NonNullOf(graphql.String)},
+		},
+	}
+	bigMoneySample := &graphql.Object{
+		Name:        "BigMoneySample",
+		Description: "An intraday reading of large-trade flow on a report date, from the open up to timestamp",
+		Fields: []*graphql.Field{
+			{Name: "timestamp", Type: graphql.NonNullOf(timeScalar)},
+			{Name: "reportTime", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "direction", Type: graphql.NonNullOf(graphql.String)},
+			{Name: "netBigMoneyFlow", Type: graphql.NonNullOf(graphql.Float)},
+			{Name: "buyerInitiatedVolume", Type: graphql.NonNullOf(graphql.Float)},
+			{Name: "sellerInitiatedVolume", Type: graphql.NonNullOf(graphql.Float)},
+			{Name: "largeTradesCount", Type: graphql.NonNullOf(graphql.Int)},
+			{Name: "totalTrades", Type: graphql.NonNullOf(graphql.Int)},
+		},
+	}
+	technicalSignal := &graphql.Object{Name: "TechnicalSignal", Description: "A stored deep search analysis"}
+	earnings := &graphql.Object{Name: "Earnings", Description: "An earnings announcement, as last estimated"}
+	deepSearchRequest := &graphql.Object{Name: "DeepSearchRequest", Description: "A deep search trigger"}
+
+	technicalSignal.Fields = []*graphql.Field{
+		{Name: "id", Type: graphql.NonNullOf(graphql.ID)},
+		{Name: "createdAt", Type: graphql.NonNullOf(timeScalar)},
+		{Name: "ticker", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "userId", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "startDuration", Description: "The window start the analysis was triggered with, YYYY-MM-DD", Type: graphql.NonNullOf(graphql.String),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(models.TechnicalSignal).PolyStartDuration, nil
+			}},
+		{Name: "endDuration", Type: graphql.NonNullOf(graphql.String),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(models.TechnicalSignal).PolyEndDuration, nil
+			}},
+		{Name: "timespan", Description: "Aggregate timespan, e.g. minute", Type: graphql.NonNullOf(graphql.String),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(models.TechnicalSignal).PolyTimeSpan, nil
+			}},
+		{Name: "multiplier", Type: graphql.NonNullOf(graphql.Int),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(models.TechnicalSignal).PolyMultiplier, nil
+			}},
+		{Name: "startDate", Type: graphql.NonNullOf(timeScalar)},
+		{Name: "endDate", Type: graphql.NonNullOf(timeScalar)},
+		{Name: "interval", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "windowSize", Type: graphql.NonNullOf(graphql.Int)},
+		{Name: "analysisType", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "vwapAnchor", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "atrPeriod", Type: graphql.NonNullOf(graphql.Int)},
+		{Name: "strategyId", Description: "The user strategy that produced the signals, 0 for the built-in signals", Type: graphql.NonNullOf(graphql.Int)},
+		{Name: "algoVersion", Type: graphql.NonNullOf(graphql.Int)},
+		{Name: "finalDecision", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "confidence", Type: graphql.NonNullOf(graphql.Float)},
+		{Name: "lastClose", Type: graphql.NonNullOf(graphql.Float)},
+		{Name: "maxVolumeZScore", Type: graphql.NonNullOf(graphql.Float)},
+		{Name: "trailingStop", Type: graphql.NonNullOf(graphql.Float)},
+		{Name: "trailingStopSide", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "currency", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "benchmarks", Type: jsonScalar},
+		{Name: "yearRange", Type: jsonScalar},
+		{Name: "ivRank", Type: jsonScalar},
+		{Name: "explanation", Type: jsonScalar},
+		{Name: "tags", Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(graphql.String)))},
+		{Name: "notes", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "signals", Description: "The signals as stored", Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(graphql.String)))},
+		{
+			Name: "structuredSignals",
+			Args: []*graphql.InputField{{Name: "filter", Type: structuredSignalFilter}},
+			Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(structuredSignal))),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				signals := deepsearch.ParseSignals(source.(models.TechnicalSignal).Signals)
+				return filterStructuredSignals(signals, inputObject(args, "filter")), nil
+			},
+		},
+		{
+			Name:        "earnings",
+			Description: "The ticker's earnings announcements, latest first",
+			Args:        earningsArgs(4, "DESC"),
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(earnings))),
+			Size:        graphQLLimit,
+			BatchResolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				return h.earningsByTicker(ctx, sources, args, func(source interface{}) string {
+					return source.(models.TechnicalSignal).Ticker
+				})
+			},
+		},
+	}
+
+	earnings.Fields = []*graphql.Field{
+		{Name: "ticker", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "reportDate", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "reportTime", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "importance", Type: graphql.NonNullOf(graphql.Int)},
+		{Name: "estimatedEps", Type: graphql.Float},
+		{Name: "estimatedRevenue", Type: graphql.Float},
+		{Name: "actualEps", Type: graphql.Float},
+		{Name: "actualRevenue", Type: graphql.Float},
+		{Name: "providerUpdated", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "observedAt", Description: "When this estimate was stored", Type: graphql.NonNullOf(timeScalar),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return source.(models.EarningsEstimate).CreatedAt, nil
+			}},
+		{
+			Name:        "revisions",
+			Description: "The stored estimates of the announcement, oldest first",
+			Args:        pageArgs(50),
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(earnings))),
+			Size:        graphQLLimit,
+			BatchResolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				return h.revisions(ctx, sources, args)
+			},
+		},
+		{
+			Name:        "bigMoneySamples",
+			Description: "Large-trade flow samples taken on the report date, in time order",
+			Args:        pageArgs(100),
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(bigMoneySample))),
+			Size:        graphQLLimit,
+			BatchResolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				return h.bigMoneySamples(ctx, sources, args)
+			},
+		},
+		{
+			Name:        "technicalSignals",
+			Description: "Analyses of the ticker",
+			Args:        signalsArgs(10),
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(technicalSignal))),
+			Size:        graphQLLimit,
+			BatchResolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				return h.technicalSignalsByParent(ctx, sources, args, []string{"ticker"},
+					func(source interface{}) []interface{} {
+						return []interface{}{source.(models.EarningsEstimate).Ticker}
+					},
+					func(signal models.TechnicalSignal) []interface{} {
+						return []interface{}{signal.Ticker}
+					})
+			},
+		},
+	}
+
+	deepSearchRequest.Fields = []*graphql.Field{
+		{Name: "id", Type: graphql.NonNullOf(graphql.ID)},
+		{Name: "createdAt", Type: graphql.NonNullOf(timeScalar)},
+		{Name: "ticker", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "userId", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "startDate", Description: "Window start, YYYY-MM-DD", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "endDate", Description: "Window end, YYYY-MM-DD", Type: graphql.NonNullOf(graphql.String)},
+		{
+			Name:        "technicalSignals",
+			Description: "The requester's analyses of the ticker over the same window start",
+			Args:        signalsArgs(10),
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(technicalSignal))),
+			Size:        graphQLLimit,
+			BatchResolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+				columns := []string{"ticker", "user_id", "poly_start_duration"}
+				return h.technicalSignalsByParent(ctx, sources, args, columns,
+					func(source interface{}) []interface{} {
+						request := source.(models.DeepSearchRequest)
+						return []interface{}{request.Ticker, request.UserId, request.StartDate}
+					},
+					func(signal models.TechnicalSignal) []interface{} {
+						return []interface{}{signal.Ticker, signal.UserId, signal.PolyStartDuration}
+					})
+			},
+		},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.Field{
+			{
+				Name:        "technicalSignals",
+				Description: "The caller's stored analyses (every user's for admins), newest first by default",
+				Args:        signalsArgs(50),
+				Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(technicalSignal))),
+				Size:        graphQLLimit,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return h.technicalSignals(ctx, args)
+				},
+			},
+			{
+				Name: "technicalSignal",
+				Args: []*graphql.InputField{{Name: "id", Type: graphql.NonNullOf(graphql.ID)}},
+				Type: technicalSignal,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					id, err := strconv.ParseUint(args["id"].(string), 10, 64)
+					if err != nil {
+						return nil, nil
+					}
+					var signal models.TechnicalSignal
+					err = ownedBy(h.db.WithContext(ctx), graphQLOwner(ctx)).Where("id = ?", id).Take(&signal).Error
+					if err == gorm.ErrRecordNotFound {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, fmt.Errorf("failed to fetch analysis: %w", err)
+					}
+					return signal, nil
+				},
+			},
+			{
+				Name:        "deepSearchRequests",
+				Description: "The caller's deep search triggers (every user's for admins), newest first",
+				Args: []*graphql.InputField{
+					{Name: "filter", Type: requestFilter},
+					{Name: "limit", Type: graphql.Int, Default: 50},
+					{Name: "offset", Type: graphql.Int, Default: 0},
+				},
+				Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(deepSearchRequest))),
+				Size: graphQLLimit,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return h.deepSearchRequests(ctx, args)
+				},
+			},
+			{
+				Name:        "earnings",
+				Description: "Earnings announcements, each as last estimated, in report date order",
+				Args:        earningsArgs(100, "ASC"),
+				Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(earnings))),
+				Size:        graphQLLimit,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return h.earnings(ctx, args)
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{
+		Query:      query,
+		MaxDepth:   graphQLMaxDepth,
+		MaxAliases: graphQLMaxAliases,
+		MaxCost:    graphQLMaxCost,
+		MaxNodes:   graphQLMaxNodes,
+	}
+}
+
+// technicalSignals resolves the technicalSignals query
+func (h *GraphQLHandler) technicalSignals(ctx context.Context, args map[string]interface{}) ([]models.TechnicalSignal, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	query, order, err := h.technicalSignalsQuery(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	signals := []models.TechnicalSignal{}
+	if err := query.Order(order).Limit(limit).Offset(offset).Find(&signals).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch analyses: %w", err)
+	}
+	return signals, nil
+}
+
+// technicalSignalsByParent resolves a technicalSignals field for a list of
+// parents in one query. columns are the analysis columns a parent matches on;
+// parentKey returns a parent's values for them and signalKey an analysis's.
+func (h *GraphQLHandler) technicalSignalsByParent(ctx context.Context, sources []interface{}, args map[string]interface{}, columns []string, parentKey func(interface{}) []interface{}, signalKey func(models.TechnicalSignal) []interface{}) ([]interface{}, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	query, order, err := h.technicalSignalsQuery(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	partition := strings.Join(columns, ", ")
+	query = query.Where(fmt.Sprintf("(%s) IN ?", partition), parentKeys(sources, parentKey))
+	signals := []models.TechnicalSignal{}
+	if err := pagePerParent(h.db.WithContext(ctx), query, partition, order, limit, offset).Find(&signals).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch analyses: %w", err)
+	}
+
+	byParent := map[string][]models.TechnicalSignal{}
+	for _, signal := range signals {
+		k := fmt.Sprint(signalKey(signal))
+		byParent[k] = append(byParent[k], signal)
+	}
+	results := make([]interface{}, len(sources))
+	for i, source := range sources {
+		results[i] = nonNilSignals(byParent[fmt.Sprint(parentKey(source))])
+	}
+	return results, nil
+}
+
+// technicalSignalsQuery builds a technicalSignals field's query over the
+// caller's unarchived analyses, and its ORDER BY
+func (h *GraphQLHandler) technicalSignalsQuery(ctx context.Context, args map[string]interface{}) (*gorm.DB, string, error) {
+	query := withArchived(ownedBy(h.db.WithContext(ctx).Model(&models.TechnicalSignal{}), graphQLOwner(ctx)), false)
+	if filter := inputObject(args, "filter"); filter != nil {
+		condition, err := h.signalCondition(ctx, filter)
+		if err != nil {
+			return nil, "", err
+		}
+		if condition != nil {
+			query = query.Where(condition)
+		}
+	}
+
+	// id breaks ties so pages do not overlap
+	column := historySorts[strings.ToLower(inputString(args, "sort"))]
+	direction := inputString(args, "order")
+	return query, fmt.Sprintf("%s %s, id %s", column, direction, direction), nil
+}
+
+func nonNilSignals(signals []models.TechnicalSignal) []models.TechnicalSignal {
+	if signals == nil {
+		return []models.TechnicalSignal{}
+	}
+	return signals
+}
+
+// parentKeys returns the distinct keys of a list's parents, for a
+// "(columns) IN ?" condition
+func parentKeys(sources []interface{}, key func(interface{}) []interface{}) [][]interface{} {
+	seen := map[string]bool{}
+	var keys [][]interface{}
+	for _, source := range sources {
+		k := key(source)
+		if !seen[fmt.Sprint(k)] {
+			seen[fmt.Sprint(k)] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// pagePerParent pages query separately within each partition of its rows,
+// so one query returns a child list for every parent in a list. Rows come
+// back in order within each partition.
+func pagePerParent(db, query *gorm.DB, partition, order string, limit, offset int) *gorm.DB {
+	ranked := db.Table("(?) AS q", query).
+		Select(fmt.Sprintf("q.*, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS parent_row", partition, order))
+	// The inner query already skipped soft-deleted rows
+	return db.Unscoped().Table("(?) AS ranked", ranked).
+		Where("parent_row > ? AND parent_row <= ?", offset, offset+limit).
+		Order("parent_row")
+}
+
+// signalCondition returns a TechnicalSignalFilter's conditions as a group:
+// its own fields, and any one of its or filters. It returns nil for a filter
+// that matches every analysis.
+func (h *GraphQLHandler) signalCondition(ctx context.Context, filter map[string]interface{}) (*gorm.DB, error) {
+	signalFilter := deepsearch.SignalFilter{
+		Tickers:       inputStrings(filter, "tickers"),
+		StartDuration: inputString(filter, "startDuration"),
+		From:          inputString(filter, "from"),
+		To:            inputString(filter, "to"),
+		SignalTypes:   inputStrings(filter, "signalTypes"),
+		MinConfidence: inputFloat(filter, "minConfidence"),
+		Decisions:     inputStrings(filter, "decisions"),
+		Tags:          inputStrings(filter, "tags"),
+	}
+	for _, version := range inputList(filter, "algoVersions") {
+		signalFilter.AlgoVersions = append(signalFilter.AlgoVersions, version.(int))
+	}
+	if err := signalFilter.Normalize(); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	condition := signalFilter.Apply(h.db.Session(&gorm.Session{NewDB: true}))
+	if userID := inputString(filter, "userId"); userID != "" {
+		if userID == "me" {
+			userID = graphQLUser(ctx)
+		}
+		condition = condition.Where("user_id = ?", userID)
+	}
+
+	alternatives := h.db.Session(&gorm.Session{NewDB: true})
+	for _, item := range inputList(filter, "or") {
+		alternative, err := h.signalCondition(ctx, item.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if alternative == nil {
+			// One alternative matches everything, so the or does not filter
+			alternatives = nil
+			break
+		}
+		alternatives = alternatives.Or(alternative)
+	}
+	if alternatives != nil && hasConditions(alternatives) {
+		condition = condition.Where(alternatives)
+	}
+
+	if !hasConditions(condition) {
+		return nil, nil
+	}
+	return condition, nil
+}
+
+func hasConditions(query *gorm.DB) bool {
+	_, ok := query.Statement.Clauses["WHERE"]
+	return ok
+}
+
+// filterStructuredSignals keeps the signals matching a StructuredSignalFilter
+func filterStructuredSignals(signals []deepsearch.StructuredSignal, filter map[string]interface{}) []deepsearch.StructuredSignal {
+	if filter == nil {
+		return signals
+	}
+	directions := inputStrings(filter, "directions")
+	regimes := inputStrings(filter, "regimes")
+	minADX := inputFloat(filter, "minAdx")
+	kinds := inputStrings(filter, "signalTypes")
+
+	kept := make([]deepsearch.StructuredSignal, 0, len(signals))
+	for _, s := range signals {
+		if len(directions) > 0 && !containsString(directions, s.Direction) {
+			continue
+		}
+		if len(regimes) > 0 && !containsString(regimes, s.Regime) {
+			continue
+		}
+		if minADX != nil && s.ADX < *minADX {
+			continue
+		}
+		if len(kinds) > 0 && !signalOfKind(s, kinds) {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// signalOfKind reports whether the signal, or a kind merged into it, starts
+// with one of kinds, as SignalFilter.SignalTypes matches
+func signalOfKind(s deepsearch.StructuredSignal, kinds []string) bool {
+	for _, kind := range kinds {
+		if strings.HasPrefix(s.Description, kind) {
+			return true
+		}
+		for _, reason := range s.Reasons {
+			if strings.HasPrefix(reason, kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// earnings resolves the earnings query
+func (h *GraphQLHandler) earnings(ctx context.Context, args map[string]interface{}) ([]models.EarningsEstimate, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	query, err := h.earningsQuery(ctx, args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := []models.EarningsEstimate{}
+	order := fmt.Sprintf("report_date %s, ticker", inputString(args, "order"))
+	if err := query.Order(order).Limit(limit).Offset(offset).Find(&estimates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch earnings: %w", err)
+	}
+	return estimates, nil
+}
+
+// earningsByTicker resolves an earnings field for a list of parents in one
+// query; ticker returns a parent's ticker
+func (h *GraphQLHandler) earningsByTicker(ctx context.Context, sources []interface{}, args map[string]interface{}, ticker func(interface{}) string) ([]interface{}, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	var tickers []string
+	for _, source := range sources {
+		tickers = append(tickers, ticker(source))
+	}
+	query, err := h.earningsQuery(ctx, args, tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := []models.EarningsEstimate{}
+	order := "report_date " + inputString(args, "order")
+	if err := pagePerParent(h.db.WithContext(ctx), query, "ticker", order, limit, offset).Find(&estimates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch earnings: %w", err)
+	}
+
+	byTicker := map[string][]models.EarningsEstimate{}
+	for _, estimate := range estimates {
+		byTicker[estimate.Ticker] = append(byTicker[estimate.Ticker], estimate)
+	}
+	results := make([]interface{}, len(sources))
+	for i, source := range sources {
+		results[i] = nonNilEstimates(byTicker[ticker(source)])
+	}
+	return results, nil
+}
+
+// earningsQuery builds an earnings field's query: the latest estimate of each
+// announcement matching the filter, limited to tickers if set
+func (h *GraphQLHandler) earningsQuery(ctx context.Context, args map[string]interface{}, tickers []string) (*gorm.DB, error) {
+	query := h.db.WithContext(ctx).Model(&models.EarningsEstimate{})
+	if tickers != nil {
+		query = query.Where("ticker IN ?", tickers)
+	}
+
+	filter := inputObject(args, "filter")
+	if tickers := inputStrings(filter, "tickers"); len(tickers) > 0 {
+		for i := range tickers {
+			tickers[i] = strings.ToUpper(strings.TrimSpace(tickers[i]))
+		}
+		query = query.Where("ticker IN ?", tickers)
+	}
+	for _, bound := range []struct{ name, condition string }{
+		{"date", "report_date = ?"},
+		{"from", "report_date >= ?"},
+		{"to", "report_date <= ?"},
+	} {
+		date := inputString(filter, bound.name)
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, badRequest(fmt.Sprintf("Invalid %s format, use YYYY-MM-DD", bound.name))
+		}
+		query = query.Where(bound.condition, date)
+	}
+	if importance, ok := filter["minImportance"].(int); ok {
+		query = query.Where("importance >= ?", importance)
+	}
+	return models.LatestPer(query, "report_date, ticker", "created_at DESC, id DESC"), nil
+}
+
+// revisions resolves Earnings.revisions for a list of announcements in one query
+func (h *GraphQLHandler) revisions(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	key := func(source interface{}) []interface{} {
+		estimate := source.(models.EarningsEstimate)
+		return []interface{}{estimate.Ticker, estimate.ReportDate}
+	}
+	query := h.db.WithContext(ctx).Model(&models.EarningsEstimate{}).
+		Where("(ticker, report_date) IN ?", parentKeys(sources, key))
+
+	revisions := []models.EarningsEstimate{}
+	if err := pagePerParent(h.db.WithContext(ctx), query, "ticker, report_date", "created_at, id", limit, offset).Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch estimate revisions: %w", err)
+	}
+
+	byAnnouncement := map[string][]models.EarningsEstimate{}
+	for _, revision := range revisions {
+		k := fmt.Sprint(key(revision))
+		byAnnouncement[k] = append(byAnnouncement[k], revision)
+	}
+	results := make([]interface{}, len(sources))
+	for i, source := range sources {
+		results[i] = nonNilEstimates(byAnnouncement[fmt.Sprint(key(source))])
+	}
+	return results, nil
+}
+
+// bigMoneySamples resolves Earnings.bigMoneySamples for a list of
+// announcements in one query
+func (h *GraphQLHandler) bigMoneySamples(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	key := func(source interface{}) []interface{} {
+		estimate := source.(models.EarningsEstimate)
+		return []interface{}{estimate.Ticker, estimate.ReportDate}
+	}
+	query := h.db.WithContext(ctx).Model(&models.BigMoneySample{}).
+		Where("(ticker, report_date) IN ?", parentKeys(sources, key))
+
+	samples := []models.BigMoneySample{}
+	if err := pagePerParent(h.db.WithContext(ctx), query, "ticker, report_date", "timestamp", limit, offset).Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch big money samples: %w", err)
+	}
+
+	byAnnouncement := map[string][]models.BigMoneySample{}
+	for _, sample := range samples {
+		k := fmt.Sprint([]interface{}{sample.Ticker, sample.ReportDate})
+		byAnnouncement[k] = append(byAnnouncement[k], sample)
+	}
+	results := make([]interface{}, len(sources))
+	for i, source := range sources {
+		samples := byAnnouncement[fmt.Sprint(key(source))]
+		if samples == nil {
+			samples = []models.BigMoneySample{}
+		}
+		results[i] = samples
+	}
+	return results, nil
+}
+
+func nonNilEstimates(estimates []models.EarningsEstimate) []models.EarningsEstimate {
+	if estimates == nil {
+		return []models.EarningsEstimate{}
+	}
+	return estimates
+}
+
+// deepSearchRequests resolves the deepSearchRequests query
+func (h *GraphQLHandler) deepSearchRequests(ctx context.Context, args map[string]interface{}) ([]models.DeepSearchRequest, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+	query := ownedBy(h.db.WithContext(ctx).Model(&models.DeepSearchRequest{}), graphQLOwner(ctx))
+
+	filter := inputObject(args, "filter")
+	if tickers := inputStrings(filter, "tickers"); len(tickers) > 0 {
+		for i := range tickers {
+			tickers[i] = strings.ToUpper(strings.TrimSpace(tickers[i]))
+		}
+		query = query.Where("ticker IN ?", tickers)
+	}
+	if userID := inputString(filter, "userId"); userID != "" {
+		if userID == "me" {
+			userID = graphQLUser(ctx)
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if val := inputString(filter, "from"); val != "" {
+		from, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			return nil, badRequest("Invalid from format, use YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if val := inputString(filter, "to"); val != "" {
+		to, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			return nil, badRequest("Invalid to format, use YYYY-MM-DD")
+		}
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	requests := []models.DeepSearchRequest{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch deep search requests: %w", err)
+	}
+	return requests, nil
+}
+
+// graphQLLimit is the Size of a list field with a limit argument
+func graphQLLimit(args map[string]interface{}) int {
+	limit, _ := args["limit"].(int)
+	return limit
+}
+
+// graphQLPage reads a list field's limit and offset arguments
+func graphQLPage(args map[string]interface{}) (int, int, error) {
+	limit, _ := args["limit"].(int)
+	offset, _ := args["offset"].(int)
+	if limit < 1 || limit > graphQLMaxLimit {
+		return 0, 0, badRequest(fmt.Sprintf("limit must be between 1 and %d", graphQLMaxLimit))
+	}
+	if offset < 0 {
+		return 0, 0, badRequest("offset cannot be negative")
+	}
+	return limit, offset, nil
+}
+
+// Accessors for coerced arguments and input object fields; a nil map reads
+// as empty
+
+func inputObject(values map[string]interface{}, name string) map[string]interface{} {
+	object, _ := values[name].(map[string]interface{})
+	return object
+}
+
+func inputList(values map[string]interface{}, name string) []interface{} {
+	list, _ := values[name].([]interface{})
+	return list
+}
+
+func inputString(values map[string]interface{}, name string) string {
+	s, _ := values[name].(string)
+	return s
+}
+
+func inputStrings(values map[string]interface{}, name string) []string {
+	list := inputList(values, name)
+	if len(list) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		strs = append(strs, item.(string))
+	}
+	return strs
+}
+
+func inputFloat(values map[string]interface{}, name string) *float64 {
+	f, ok := values[name].(float64)
+	if !ok {
+		return nil
+	}
+	return &f
+}
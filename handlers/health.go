@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+	"institutionanalyser/usage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// deepCheckTimeout bounds each dependency probe of a deep health check
+	deepCheckTimeout = 5 * time.Second
+	// readinessTimeout bounds the database ping of a readiness probe, which
+	// orchestrators call every few seconds
+	readinessTimeout = 2 * time.Second
+)
+
+// HealthHandler reports the health of the service and its dependencies
+type HealthHandler struct {
+	db *gorm.DB
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db *gorm.DB) *HealthHandler {
+	return &HealthHandler{db: db}
+}
+
+// HandleHealth reports the service's health from the outcomes of recent
+// database and Polygon calls. The server stays up while a dependency is
+// unavailable, so it reports "degraded" rather than failing.
+// Query parameters:
+//   - deep: Also probe the database, the read replica (if configured) and Polygon now, reporting each one's status and latency (default: false)
+func (h *HealthHandler) HandleHealth(c *gin.Context) {
+	status := "healthy"
+	dependencies := health.Statuses()
+	for _, dep := range dependencies {
+		if !dep.Healthy {
+			status = "degraded"
+		}
+	}
+	response := gin.H{
+		"service":      "institution-analyser-api",
+		"dependencies": dependencies,
+	}
+
+	if c.Query("deep") == "true" {
+		checks := h.deepChecks(c.Request.Context())
+		status = "healthy"
+		for _, check := range checks {
+			if !check.Healthy {
+				status = "degraded"
+			}
+		}
+		response["checks"] = checks
+	}
+
+	response["status"] = status
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleLivez reports that the process is up and serving. It checks no
+// dependencies, so an orchestrator does not restart instances over a
+// database or Polygon outage.
+func (h *HealthHandler) HandleLivez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// HandleReadyz reports whether the instance should receive traffic: startup
+// warmup is complete and the database pool reaches the database. It returns
+// 503 with the reason otherwise. Polygon outages do not affect readiness, as
+// endpoints degrade without it.
+func (h *HealthHandler) HandleReadyz(c *gin.Context) {
+	if pending := health.WarmupPending(); len(pending) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "starting", "pending": pending})
+		return
+	}
+
+	sqlDB, err := h.db.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		err = sqlDB.PingContext(ctx)
+		cancel()
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "Database unreachable", "details": err.Error()})
+		return
+	}
+	// Statements have failed to reach the database even if this ping did not
+	if health.Degraded(health.Database) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "Database degraded"})
+		return
+	}
+
+	stats := sqlDB.Stats()
+	c.JSON(http.StatusOK, gin.H{
+		"status":           "ready",
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"wait_count":       stats.WaitCount,
+	})
+}
+
+// deepChecks probes every dependency concurrently, each within
+// deepCheckTimeout
+func (h *HealthHandler) deepChecks(ctx context.Context) []health.Check {
+	probes := []func(context.Context) (health.Check, bool){
+		h.checkDatabase,
+		h.checkReadReplica,
+		h.checkPolygon,
+	}
+
+	results := make([]*health.Check, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe func(context.Context) (health.Check, bool)) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, deepCheckTimeout)
+			defer cancel()
+			if check, ok := probe(probeCtx); ok {
+				results[i] = &check
+			}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	checks := make([]health.Check, 0, len(results))
+	for _, check := range results {
+		if check != nil {
+			checks = append(checks, *check)
+		}
+	}
+	return checks
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) (health.Check, bool) {
+	return health.Probe(health.Database, func() (map[string]interface{}, error) {
+		sqlDB, err := h.db.DB()
+		if err != nil {
+			return nil, err
+		}
+		err = sqlDB.PingContext(ctx)
+		if err != nil {
+			health.RecordFailure(health.Database, err)
+		} else {
+			health.RecordSuccess(health.Database)
+		}
+		stats := sqlDB.Stats()
+		return map[string]interface{}{
+			"driver":           h.db.Dialector.Name(),
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		}, err
+	}), true
+}
+
+// checkReadReplica is skipped without DATABASE_REPLICA_URL
+func (h *HealthHandler) checkReadReplica(ctx context.Context) (health.Check, bool) {
+	configured := false
+	check := health.Probe(health.ReadReplica, func() (map[string]interface{}, error) {
+		var err error
+		configured, err = models.PingReadReplica(ctx, h.db)
+		return nil, err
+	})
+	return check, configured
+}
+
+// checkPolygon makes one market status call and reports today's metered
+// Polygon calls alongside it. Polygon does not report remaining quota, so a
+// 429 is how an exhausted plan shows up.
+func (h *HealthHandler) checkPolygon(ctx context.Context) (health.Check, bool) {
+	return health.Probe(health.Polygon, func() (map[string]interface{}, error) {
+		statusCode, err := service.PingPolygon(usage.WithFeature(ctx, usage.FeatureHealth))
+		details := map[string]interface{}{}
+		if statusCode != 0 {
+			details["status_code"] = statusCode
+		}
+		if calls, callsErr := h.polygonCallsToday(ctx); callsErr == nil {
+			details["calls_today"] = calls
+		} else {
+			fmt.Printf("[API] failed to count today's Polygon calls: %v\n", callsErr)
+		}
+		return details, err
+	}), true
+}
+
+// polygonCallsToday sums the Polygon calls metered today (UTC), flushing
+// counted calls first so the figure is current
+func (h *HealthHandler) polygonCallsToday(ctx context.Context) (int64, error) {
+	if err := usage.Flush(ctx, h.db); err != nil {
+		return 0, err
+	}
+	var calls int64
+	err := h.db.WithContext(ctx).Model(&models.PolygonUsage{}).
+		Where("day = ?", time.Now().UTC().Truncate(24*time.Hour)).
+		Select("COALESCE(SUM(calls), 0)").
+		Scan(&calls).Error
+	return calls, err
+}
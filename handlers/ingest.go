@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"institutionanalyser/jobs"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IngestHandler exposes manual triggers for data ingestion jobs
+type IngestHandler struct {
+	db *gorm.DB
+}
+
+// NewIngestHandler creates a new ingest handler
+func NewIngestHandler(db *gorm.DB) *IngestHandler {
+	return &IngestHandler{db: db}
+}
+
+// HandleIngestGroupedDaily loads grouped daily bars for one date, e.g. to backfill missed evenings
+// Query parameters:
+//   - date: Market date in YYYY-MM-DD format (required)
+func (h *IngestHandler) HandleIngestGroupedDaily(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date is required, use YYYY-MM-DD"})
+		return
+	}
+
+	n, err := jobs.IngestGroupedDaily(c.Request.Context(), h.db, date)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date": date.Format("2006-01-02"), "bars_stored": n})
+}
+
+// HandleCompactBars runs the bar store compaction now
+// Query parameters:
+//   - retention_days: Delete intraday bars older than this many days; 0 keeps
+//     them (default: BAR_RETENTION_DAYS)
+func (h *IngestHandler) HandleCompactBars(c *gin.Context) {
+	retention := jobs.BarRetention()
+	if val := c.Query("retention_days"); val != "" {
+		days, err := strconv.Atoi(val)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be a non-negative integer"})
+			return
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	result, err := jobs.CompactBars(c.Request.Context(), h.db, retention)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compact bars", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetRetention returns the analysis retention policy and the rows this
+// process has archived or purged under it
+func (h *IngestHandler) HandleGetRetention(c *gin.Context) {
+	config, err := jobs.GetRetentionConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid retention configuration", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"config": config, "stats": jobs.GetRetentionStats()})
+}
+
+// HandleRunRetention runs the analysis retention job now
+// Query parameters:
+//   - mode: archive or purge (default: ANALYSIS_RETENTION_MODE)
+func (h *IngestHandler) HandleRunRetention(c *gin.Context) {
+	config, err := jobs.GetRetentionConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid retention configuration", "details": err.Error()})
+		return
+	}
+	if val := c.Query("mode"); val != "" {
+		if val != jobs.RetentionArchive && val != jobs.RetentionPurge {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be archive or purge"})
+			return
+		}
+		config.Mode = val
+	}
+
+	result, err := jobs.ApplyRetention(c.Request.Context(), h.db, config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply retention", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultInsiderDays is the span insider activity is returned for without from
+const defaultInsiderDays = 90
+
+// InsidersHandler serves insider buys and sells from Form 4 filings
+type InsidersHandler struct {
+	db *gorm.DB
+}
+
+// NewInsidersHandler creates a new insiders handler
+func NewInsidersHandler(db *gorm.DB) *InsidersHandler {
+	return &InsidersHandler{db: db}
+}
+
+// HandleGetInsiders returns a ticker's stored insider transactions, most
+// recent first, and the periods of clustered insider buying between from and to
+// Query parameters:
+//   - from: Earliest transaction date, YYYY-MM-DD (default: 90 days before to)
+//   - to: Latest transaction date, YYYY-MM-DD (default: today)
+//   - side: BUY or SELL (optional)
+//   - limit: Transactions per page (default: 50, max 500)
+//   - offset: Number of transactions to skip (default: 0)
+func (h *InsidersHandler) HandleGetInsiders(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	limit, offset := parsePagination(c, 50, 500)
+
+	to := time.Now().In(jobs.MarketTimezone)
+	if val := c.Query("to"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format, use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -defaultInsiderDays)
+	if val := c.Query("from"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format, use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	if to.Sub(from) > 3*365*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be at most 3 years apart"})
+		return
+	}
+
+	query := h.db.Model(&models.InsiderTransaction{}).
+		Where("ticker = ? AND transaction_date >= ? AND transaction_date <= ?", ticker, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if side := strings.ToUpper(c.Query("side")); side != "" {
+		if side != models.InsiderBuy && side != models.InsiderSell {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "side must be BUY or SELL"})
+			return
+		}
+		query = query.Where("side = ?", side)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count insider transactions", "details": err.Error()})
+		return
+	}
+	var transactions []models.InsiderTransaction
+	if err := query.Order("transaction_date DESC, id DESC").Limit(limit).Offset(offset).Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch insider transactions", "details": err.Error()})
+		return
+	}
+
+	clusters, err := deepsearch.InsiderClusters(c.Request.Context(), h.db, ticker, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find insider clusters", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticker":   ticker,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"data":     transactions,
+		"clusters": clusters,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(transactions),
+		},
+	})
+}
+
+// HandleIngestInsiders reads a ticker's new Form 4 filings now
+// Query parameters:
+//   - lookback_days: How many days of filings to read (default: INSIDER_LOOKBACK_DAYS or 90, max 1000)
+func (h *InsidersHandler) HandleIngestInsiders(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	days := jobs.InsiderLookbackDays()
+	if val := c.Query("lookback_days"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "lookback_days must be between 1 and 1000"})
+			return
+		}
+		days = n
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	since := time.Now().In(jobs.MarketTimezone).AddDate(0, 0, -days)
+	ingest, err := deepsearch.IngestInsiderFilings(c.Request.Context(), h.db, ticker, since)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read insider filings", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ingest)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"institutionanalyser/alerts"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IntegrationsHandler manages a user's integrations with automation platforms
+// and shows their deliveries
+type IntegrationsHandler struct {
+	db *gorm.DB
+}
+
+// NewIntegrationsHandler creates a new integrations handler
+func NewIntegrationsHandler(db *gorm.DB) *IntegrationsHandler {
+	return &IntegrationsHandler{db: db}
+}
+
+// IntegrationRequest is the body of POST /api/v1/integrations. On PATCH every
+// field is optional.
+type IntegrationRequest struct {
+	Name       *string `json:"name"`
+	WebhookURL *string `json:"webhook_url"`
+	// FieldMapping replaces the whole mapping; {} restores every field
+	FieldMapping *map[string]string `json:"field_mapping"`
+	Enabled      *bool              `json:"enabled"`
+}
+
+// apply copies the fields set in the request onto integration
+func (r IntegrationRequest) apply(integration *models.Integration) {
+	if r.Name != nil {
+		integration.Name = *r.Name
+	}
+	if r.WebhookURL != nil {
+		integration.WebhookURL = *r.WebhookURL
+	}
+	if r.FieldMapping != nil {
+		integration.FieldMapping = *r.FieldMapping
+	}
+	if r.Enabled != nil {
+		integration.Enabled = *r.Enabled
+	}
+}
+
+// findIntegration loads one of the current user's integrations, writing the error response if it fails
+func (h *IntegrationsHandler) findIntegration(c *gin.Context) (*models.Integration, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid integration id"})
+		return nil, false
+	}
+
+	var integration models.Integration
+	if err := h.db.Where("user_id = ?", currentUserID(c)).First(&integration, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &integration, true
+}
+
+// HandleListIntegrationFields returns the flat schema's fields and a sample
+// payload, to build field mappings against
+func (h *IntegrationsHandler) HandleListIntegrationFields(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"fields": alerts.IntegrationFields(), "sample": alerts.SampleIntegrationPayload()})
+}
+
+// HandleListIntegrations returns the current user's integrations
+func (h *IntegrationsHandler) HandleListIntegrations(c *gin.Context) {
+	var integrations []models.Integration
+	if err := h.db.Where("user_id = ?", currentUserID(c)).Order("created_at").Find(&integrations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"integrations": integrations})
+}
+
+// HandleCreateIntegration registers an integration; its signing secret is only returned in this response
+func (h *IntegrationsHandler) HandleCreateIntegration(c *gin.Context) {
+	var req IntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	integration := models.Integration{UserId: currentUserID(c), Enabled: true}
+	req.apply(&integration)
+	if err := alerts.NormalizeIntegration(&integration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := alerts.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	integration.Secret = secret
+
+	if err := h.db.Create(&integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"integration": integration, "secret": secret})
+}
+
+// HandleUpdateIntegration changes an integration's name, URL, field mapping or enabled flag
+func (h *IntegrationsHandler) HandleUpdateIntegration(c *gin.Context) {
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+
+	var req IntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	req.apply(integration)
+	if err := alerts.NormalizeIntegration(integration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(integration).Select("name", "webhook_url", "field_mapping", "enabled").Updates(integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"integration": integration})
+}
+
+// HandleDeleteIntegration removes an integration; its queued deliveries fail on their next attempt
+func (h *IntegrationsHandler) HandleDeleteIntegration(c *gin.Context) {
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Integration deleted"})
+}
+
+// HandleTestIntegration posts the sample payload, mapped and signed, to an
+// integration right away, without queueing or retrying it
+func (h *IntegrationsHandler) HandleTestIntegration(c *gin.Context) {
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+
+	payload := alerts.MapFields(integration.FieldMapping, alerts.SampleIntegrationPayload())
+	body, _ := json.Marshal(payload)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), alerts.GetDispatcherConfig().RequestTimeout)
+	defer cancel()
+	if err := alerts.SendIntegration(ctx, *integration, body); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Test delivery failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test delivery sent", "payload": payload})
+}
+
+// HandleListIntegrationDeliveries lists an integration's deliveries, newest first
+// Query parameters:
+//   - status: pending, delivered or failed (optional)
+//   - limit/offset: Pagination (default 50, max 500)
+func (h *IntegrationsHandler) HandleListIntegrationDeliveries(c *gin.Context) {
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.AlertDelivery{}).Where("integration_id = ?", integration.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 50, 500)
+	var deliveries []models.AlertDelivery
+	if err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(deliveries),
+		},
+	})
+}
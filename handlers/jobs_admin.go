@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// JobsAdminHandler lets operators inspect and replay failed analysis jobs
+type JobsAdminHandler struct {
+	db    *gorm.DB
+	queue *jobs.AnalysisQueue
+}
+
+// NewJobsAdminHandler creates a new jobs admin handler
+func NewJobsAdminHandler(db *gorm.DB, queue *jobs.AnalysisQueue) *JobsAdminHandler {
+	return &JobsAdminHandler{db: db, queue: queue}
+}
+
+// HandleListFailedJobs lists failed jobs with their error and parameters, newest first
+// Query parameters:
+//   - ticker: Only jobs for this ticker (optional)
+//   - limit/offset: Pagination (default 100, max 1000)
+func (h *JobsAdminHandler) HandleListFailedJobs(c *gin.Context) {
+	query := h.db.Model(&models.AnalysisJob{}).Where("status = ?", models.JobStatusFailed)
+	if ticker := c.Query("ticker"); ticker != "" {
+		query = query.Where("ticker = ?", strings.ToUpper(ticker))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	var failed []models.AnalysisJob
+	if err := query.Order("finished_at desc").Limit(limit).Offset(offset).Find(&failed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": failed,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(failed),
+		},
+	})
+}
+
+// HandleRetryFailedJob requeues one failed job
+func (h *JobsAdminHandler) HandleRetryFailedJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	retried, err := h.queue.RetryFailed(c.Request.Context(), jobs.RetryFilter{IDs: []uint{uint(id)}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(retried) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is not failed, does not exist, or its analysis is already queued again"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"job": retried[0]})
+}
+
+// HandleRetryAllFailedJobs requeues every failed job, e.g. after a Polygon outage
+// Query parameters:
+//   - ticker: Only retry jobs for this ticker (optional)
+//   - since: Only retry jobs that failed at or after this time, RFC3339 or YYYY-MM-DD (optional)
+func (h *JobsAdminHandler) HandleRetryAllFailedJobs(c *gin.Context) {
+	filter := jobs.RetryFilter{Ticker: strings.ToUpper(c.Query("ticker"))}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", since)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, use RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		filter.Since = t
+	}
+
+	retried, err := h.queue.RetryFailed(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]uint, 0, len(retried))
+	for _, job := range retried {
+		ids = append(ids, job.ID)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"retried": len(retried), "job_ids": ids})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MarketHandler serves market-wide context
+type MarketHandler struct {
+	db *gorm.DB
+}
+
+// NewMarketHandler creates a new market handler
+func NewMarketHandler(db *gorm.DB) *MarketHandler {
+	return &MarketHandler{db: db}
+}
+
+// HandleGetBreadth returns breadth across the tickers analysed on a market day,
+// as regime context for individual signals
+// Query parameters:
+//   - date: Market date in YYYY-MM-DD format (default: today)
+func (h *MarketHandler) HandleGetBreadth(c *gin.Context) {
+	date := time.Now()
+	if val := c.Query("date"); val != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	breadth, err := deepsearch.ComputeMarketBreadth(c.Request.Context(), h.db, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute market breadth", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breadth)
+}
@@ -0,0 +1,852 @@
+package handlers
+
+import (
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/graphql"
+	"institutionanalyser/models"
+	"institutionanalyser/openapi"
+)
+
+// Shared query parameters
+var (
+	limitParam  = openapi.Query("limit", "Maximum number of results").Int()
+	offsetParam = openapi.Query("offset", "Number of results to skip (default: 0)").Int()
+	dateParam   = openapi.Query("date", "Market date, YYYY-MM-DD")
+	fromParam   = openapi.Query("from", "Earliest date, YYYY-MM-DD")
+	toParam     = openapi.Query("to", "Latest date, YYYY-MM-DD")
+	presetParam = openapi.Query("preset", "Named aggregation preset (default: minute/5)")
+	atrParam    = openapi.Query("atr_period", "Wilder ATR period, 2-100 (default: 14)").Int()
+	statusParam = openapi.Query("status", "Delivery status").OneOf("pending", "delivered", "failed")
+	sharesParam = openapi.Query("expires_in_hours", "How long the link works (default: 168, max 720)").Int()
+)
+
+// apiRoutes describes the routes served, keyed by "METHOD /path" as
+// registered on the router, for the OpenAPI document. Routes missing here are
+// still listed, without parameters.
+var apiRoutes = map[string]openapi.Route{
+	// System
+	"GET /health": {
+		ID: "getHealth", Tag: "System", Public: true,
+		Summary: "Service health, including Polygon and database status",
+		Query:   []openapi.Param{openapi.Query("deep", "Also probe each dependency now, with its status and latency (default: false)").Bool()},
+	},
+	"GET /livez": {
+		ID: "getLivez", Tag: "System", Public: true,
+		Summary: "Liveness probe: the process is up",
+	},
+	"GET /readyz": {
+		ID: "getReadyz", Tag: "System", Public: true,
+		Summary: "Readiness probe: startup is complete and the database is reachable (503 otherwise)",
+	},
+	"GET /openapi.json": {
+		ID: "getOpenAPI", Tag: "System", Public: true,
+		Summary: "This OpenAPI document",
+	},
+	"GET /docs": {
+		ID: "getDocs", Tag: "System", Public: true, Produces: "text/html",
+		Summary: "Swagger UI for this document",
+	},
+
+	// Authentication and API keys
+	"POST /api/v1/auth/signup": {
+		Tag: "Auth", Public: true, Body: SignupRequest{},
+		Summary: "Create a user and return an access token",
+	},
+	"POST /api/v1/auth/login": {
+		Tag: "Auth", Public: true, Body: LoginRequest{},
+		Summary: "Verify credentials and return an access token",
+	},
+	"GET /api/v1/auth/me": {
+		Tag: "Auth", Summary: "The authenticated user",
+	},
+	"GET /api/v1/apikeys": {
+		Tag: "API Keys", Summary: "The current user's API keys, including revoked ones",
+	},
+	"POST /api/v1/apikeys": {
+		Tag: "API Keys", Body: CreateAPIKeyRequest{},
+		Summary:     "Create an API key",
+		Description: "The plaintext key is only returned in this response. Requires a login token, not another key. Only admins may request the admin scope.",
+	},
+	"DELETE /api/v1/apikeys/:id": {
+		Tag: "API Keys", Summary: "Revoke one of the current user's API keys",
+	},
+
+	// Deep search
+	"GET /api/v1/deepsearch/analysis": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Deprecated: true,
+		Summary:     "Latest analysis for a ticker",
+		Description: "Superseded by GET /api/v2/deepsearch/analysis.",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("end_duration", "Window start the analysis was triggered with, YYYY-MM-DD").Require(),
+		},
+	},
+	"GET /api/v2/deepsearch/analysis": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "Latest analysis for a ticker with structured signals",
+		Description: "A stale result is still returned while a refresh is queued (see freshness).",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("start_duration", "Window start the analysis was triggered with, YYYY-MM-DD").Require(),
+		},
+	},
+	"GET /api/v1/deepsearch/history": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored analyses, paginated, filtered and sorted",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Comma-separated tickers"),
+			openapi.Query("user", `Analyses run by this user ID, or "me" for the caller; admins only, others always get their own`),
+			openapi.Query("decision", "Comma-separated final decisions: BUY, SELL, HOLD or STRADDLE"),
+			openapi.Query("from", "Analyses run on or after this date, YYYY-MM-DD"),
+			openapi.Query("to", "Analyses run on or before this date, YYYY-MM-DD"),
+			openapi.Query("archived", "List archived analyses instead").Bool(),
+			openapi.Query("sort", "Sort column (default: created_at)").OneOf("created_at", "confidence", "ticker", "start_date"),
+			openapi.Query("order", "Sort direction (default: desc)").OneOf("asc", "desc"),
+			limitParam, offsetParam,
+		},
+	},
+	"POST /api/v1/deepsearch/trigger": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchTrigger,
+		Summary:     "Queue an analysis",
+		Description: "Returns a job ID to poll at /api/v1/deepsearch/jobs/{id}. The window runs from start_duration to today.",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("start_duration", "Window start, YYYY-MM-DD").Require(),
+			presetParam,
+			openapi.Query("vwap_anchor", "Anchored VWAP anchor: session, earnings or an RFC3339 timestamp"),
+			atrParam,
+			openapi.Query("strategy", "Name of one of the caller's strategies to run instead of the built-in signals"),
+		},
+	},
+	"POST /api/v1/deepsearch/compare": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchTrigger, Body: CompareRequest{},
+		Summary: "Run the pipeline over several windows of one ticker and compare their metrics",
+	},
+	"POST /api/v1/deepsearch/sector": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchTrigger, Body: SectorRequest{},
+		Summary: "Analyse a sector ETF and its top constituents for one window",
+	},
+	"GET /api/v1/deepsearch/sectors": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Built-in sector ETFs and their constituents",
+	},
+	"POST /api/v1/deepsearch/technical-decision": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchTrigger,
+		Summary: "Run the decision rule table over a window and return the decision with its trace",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("start_duration", "Window start, YYYY-MM-DD").Require(),
+			presetParam, atrParam,
+		},
+	},
+	"GET /api/v1/deepsearch/jobs/:id": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Status of a queued analysis and, once completed, its result",
+	},
+	"GET /api/v1/deepsearch/signal-weights": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Weight and confidence of each signal kind",
+	},
+	"GET /api/v1/deepsearch/analysis/:id/bars": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Produces: "application/vnd.apache.parquet",
+		Summary:     "Download the enhanced bars behind a stored analysis as Parquet",
+		Description: "The bars stored with the analysis, or recomputed from the bar store; 409 when neither holds them.",
+		Query:       []openapi.Param{openapi.Query("warmup", "Include the warm-up bars before the window (default: false)").Bool()},
+	},
+	"GET /api/v1/deepsearch/analysis/:id/features": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "The bars stored with an analysis, with the features its signals were computed from",
+		Description: "409 for analyses stored without their features.",
+		Query:       []openapi.Param{openapi.Query("warmup", "Include the warm-up bars before the window (default: false)").Bool()},
+	},
+	"GET /api/v1/deepsearch/chart": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Produces: "image/png",
+		Summary:     "Render a ticker's latest analysis as a price/VWAP chart with its signals marked",
+		Description: "The bars stored with the analysis, or recomputed from the bar store; 409 when neither holds them.",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("start_duration", "Window the analysis was triggered with (default: the latest analysis of any window)"),
+			openapi.Query("width", "Width in pixels, 320 to 4096 (default: 1024)").Int(),
+			openapi.Query("height", "Height in pixels, 200 to 2048 (default: 400)").Int(),
+			openapi.Query("theme", "Colour theme (default: light)").OneOf("light", "dark"),
+			openapi.Query("upload", "Write the PNG to report storage and return a signed URL to it instead (default: false)").Bool(),
+		},
+	},
+	"GET /api/v1/deepsearch/chart/series": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "Return a ticker's latest analysis as JSON chart series",
+		Description: "Candles, volume, indicators and signal markers with times in Unix seconds, for TradingView Lightweight Charts or ECharts. 409 when the bars are not stored.",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("start_duration", "Window the analysis was triggered with (default: the latest analysis of any window)"),
+		},
+	},
+	"GET /api/v1/deepsearch/analysis/:id/report": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Produces: "application/pdf",
+		Summary:     "Download a stored analysis as a PDF report",
+		Description: "Decision rationale, price/VWAP chart, indicators at the last bar and signals. The chart and indicators are left out when the bars are not stored.",
+		Query:       []openapi.Param{openapi.Query("upload", "Write the PDF to report storage and return a signed URL to it instead (default: false)").Bool()},
+	},
+	"GET /api/v1/deepsearch/analysis/:id/outcomes": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Score a stored analysis's directional signals at a horizon",
+		Query: []openapi.Param{
+			openapi.Query("horizon", "How far after each signal to measure, e.g. 5m, 30m, 1d (default: 30m)"),
+		},
+	},
+	"POST /api/v1/signals/search": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Body: deepsearch.SignalFilter{},
+		Summary: "Stored analyses matching a structured filter, newest first",
+		Query:   []openapi.Param{limitParam, offsetParam, openapi.Query("archived", "Search archived analyses instead").Bool()},
+	},
+	"POST /api/v1/graphql": {
+		ID: "postGraphQL", Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Body: graphql.Request{},
+		Summary:     "Run a GraphQL query over stored analyses, deep search requests and earnings",
+		Description: "The schema is served at /api/v1/graphql/schema. Field errors are returned alongside the data with status 200.",
+	},
+	"GET /api/v1/graphql": {
+		ID: "getGraphQL", Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Run a GraphQL query passed as query parameters",
+		Query: []openapi.Param{
+			openapi.Query("query", "GraphQL query document").Require(),
+			openapi.Query("operationName", "Operation to run when the document has several"),
+			openapi.Query("variables", "JSON-encoded variables"),
+		},
+	},
+	"GET /api/v1/graphql/schema": {
+		ID: "getGraphQLSchema", Tag: "Deep Search", Produces: "text/plain",
+		Summary: "The GraphQL schema in schema definition language",
+	},
+	"POST /api/v1/sandbox/evaluate": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Body: SandboxRequest{},
+		Summary: "Recompute a stored analysis with threshold overrides and weights, without storing it",
+	},
+	"GET /api/v1/performance": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "A ticker's signal hit rates by signal type",
+		Query:   []openapi.Param{openapi.Query("ticker", "Ticker symbol").Require()},
+	},
+	"GET /api/v1/performance/attribution": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "Hypothetical P&L of the caller's signals at a horizon by signal type, ticker or strategy",
+		Query: []openapi.Param{
+			openapi.Query("horizon", "A tracked horizon, e.g. 5m, 30m or 1d (default: 30m)"),
+			openapi.Query("group_by", "What to attribute P&L to (default: kind)").OneOf("kind", "ticker", "strategy"),
+			openapi.Query("ticker", "Only signals in this ticker"),
+			openapi.Query("strategy_id", "Only signals of this strategy, 0 for the built-in signals").Int(),
+			fromParam, toParam,
+			openapi.Query("notional", "The position taken per signal (default: 10000)"),
+		},
+	},
+	"GET /api/v1/paper/trades": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "The caller's paper trades opened by analysis decisions, newest first",
+		Query: []openapi.Param{
+			openapi.Query("status", "Only open or closed trades (default: both)").OneOf("open", "closed"),
+			openapi.Query("ticker", "Only trades in this ticker"),
+			openapi.Query("limit", "Page size (default: 100, max 1000)").Int(),
+			openapi.Query("offset", "Rows to skip").Int(),
+		},
+	},
+	"GET /api/v1/paper/pnl": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary: "The caller's realized and unrealized paper trading P&L by currency",
+	},
+	"GET /api/v1/replay/:ticker": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead, Produces: "text/event-stream",
+		Summary: "Replay a session's bars, signals and running decision over Server-Sent Events",
+		Query: []openapi.Param{
+			openapi.Query("date", "Session date, YYYY-MM-DD").Require(),
+			openapi.Query("timespan", "Bar size unit (default: minute)").OneOf("second", "minute", "hour"),
+			openapi.Query("multiplier", "Bar size multiplier (default: 5)").Int(),
+			openapi.Query("speed", `Multiple of real time, or "max" (default: 60, max 3600)`),
+		},
+	},
+	"GET /api/v1/replay/:ticker/simulation": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "Feed several sessions' bars through the signal generator one at a time, without lookahead",
+		Description: "The WebSocket stream's replay action sends the same frames paced like a live session.",
+		Query: []openapi.Param{
+			openapi.Query("from", "First session, YYYY-MM-DD").Require(),
+			openapi.Query("to", "Last session, YYYY-MM-DD (at most 31 days after from)").Require(),
+			openapi.Query("timespan", "Bar size unit (default: minute)").OneOf("second", "minute", "hour"),
+			openapi.Query("multiplier", "Bar size multiplier (default: 5)").Int(),
+		},
+	},
+	"GET /api/v1/bars/enhanced/:ticker": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "Stored bars with the indicators analyses computed for them",
+		Description: "Served from the Timescale enhanced bar store; 503 unless TIMESCALE_ENABLED=true.",
+		Query: []openapi.Param{
+			openapi.Query("from", "First market date, YYYY-MM-DD").Require(),
+			openapi.Query("to", "Last market date, YYYY-MM-DD (default: today)"),
+			openapi.Query("timespan", "Bar size unit (default: minute)"),
+			openapi.Query("multiplier", "Bar size multiplier (default: 5)").Int(),
+		},
+	},
+	"GET /api/v1/ws": {
+		Tag: "Deep Search", Scope: models.ScopeDeepsearchRead,
+		Summary:     "WebSocket stream of the caller's new analyses and job progress",
+		Description: `Send {"action": "subscribe", "tickers": ["AAPL"]} ("*" for all tickers) to change the subscription.`,
+		Query:       []openapi.Param{openapi.Query("tickers", "Comma-separated tickers to subscribe to on connect")},
+	},
+
+	// Annotations
+	"PATCH /api/v1/deepsearch/analysis/:id/annotations": {
+		Tag: "Annotations", Body: UpdateAnnotationsRequest{},
+		Summary: "Set tags and/or notes on an analysis",
+	},
+	"GET /api/v1/deepsearch/analyses/tagged": {
+		Tag: "Annotations", Summary: "Analyses carrying a tag",
+		Query: []openapi.Param{
+			openapi.Query("tag", "Tag to filter by").Require(),
+			openapi.Query("ticker", "Ticker symbol"),
+			openapi.Query("archived", "List archived analyses instead").Bool(),
+			limitParam,
+		},
+	},
+	"GET /api/v1/tags": {
+		Tag: "Annotations", Summary: "Every tag in use with its usage count",
+	},
+
+	// Archive
+	"POST /api/v1/deepsearch/analysis/:id/archive": {
+		Tag: "Archive", Summary: "Archive an analysis, leaving it out of history listings",
+	},
+	"POST /api/v1/deepsearch/analysis/:id/restore": {
+		Tag: "Archive", Summary: "Restore an archived or deleted analysis",
+	},
+	"DELETE /api/v1/deepsearch/analysis/:id": {
+		Tag: "Archive", Summary: "Soft-delete an analysis",
+		Description: "The analysis disappears from every endpoint, but the row is kept for audit and can be restored.",
+	},
+	"POST /api/v1/deepsearch/history/archive": {
+		Tag: "Archive", Summary: "Archive every analysis run before a date",
+		Query: []openapi.Param{
+			openapi.Query("before", "Archive analyses run before this date, YYYY-MM-DD").Require(),
+			openapi.Query("ticker", "Comma-separated tickers"),
+		},
+	},
+
+	// Decisions
+	"POST /api/v1/decisions/latest": {
+		Tag: "Decisions", Body: LatestDecisionsRequest{},
+		Summary: "Each requested ticker's latest final decision",
+	},
+	"POST /api/v1/decisions/exposure": {
+		Tag: "Decisions", Body: ExposureRequest{},
+		Summary: "Net directional exposure, sector tilts and book of a watchlist or portfolio",
+	},
+	"GET /api/v1/decide/:ticker": {
+		Tag: "Decisions", Scope: models.ScopeDeepsearchRead,
+		Summary: "On-the-spot decision from the live snapshot, today's bars and the latest analysis",
+	},
+	"GET /api/v1/export/decisions": {
+		Tag: "Decisions", Scope: models.ScopeDeepsearchRead, Produces: "application/x-ndjson",
+		Summary:     "Stream stored decisions as NDJSON, oldest first",
+		Description: "Pass the X-Next-Cursor header of one page as cursor to fetch the next.",
+		Query: []openapi.Param{
+			openapi.Query("since", "RFC3339 timestamp or YYYY-MM-DD (required without cursor)"),
+			openapi.Query("cursor", "Cursor from the previous page"),
+			limitParam,
+		},
+	},
+
+	// Earnings
+	"GET /api/v1/earnings/bigmoney": {
+		Tag: "Earnings", Summary: "Earnings reporters of a date with their big money flow",
+		Query: []openapi.Param{
+			openapi.Query("date", "Earnings date, YYYY-MM-DD").Require(),
+			openapi.Query("analysis_date", "Date to analyse flow on (default: the session before pre-market reports, the report date's for after-hours)"),
+			openapi.Query("large_trade_threshold", "Threshold multiplier for large trades (default: 10.0)").Number(),
+			limitParam,
+		},
+	},
+	"GET /api/v1/earnings/bigmoney/watch": {
+		Tag: "Earnings", Summary: "Big money flow sampled through a report date, per reporter",
+		Query: []openapi.Param{
+			openapi.Query("date", "Report date, YYYY-MM-DD (default: today)"),
+			openapi.Query("ticker", "Only this reporter"),
+		},
+	},
+	"GET /api/v1/earnings/revisions": {
+		Tag: "Earnings", Summary: "EPS estimate revisions leading into a report",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("date", "Report date, YYYY-MM-DD (default: the latest stored report)"),
+		},
+	},
+	"GET /api/v1/earnings/review": {
+		Tag: "Earnings", Summary: "A date's reports scored on EPS surprise, revenue surprise and guidance",
+		Query: []openapi.Param{
+			openapi.Query("date", "Report date, YYYY-MM-DD").Require(),
+			openapi.Query("ticker", "Ticker symbol"),
+			limitParam,
+		},
+	},
+
+	// Configuration
+	"GET /api/v1/presets": {
+		Tag: "Configuration", Summary: "The current user's aggregation presets",
+	},
+	"POST /api/v1/presets": {
+		Tag: "Configuration", Body: PresetConfig{},
+		Summary: "Create or update a preset",
+	},
+	"DELETE /api/v1/presets/:name": {
+		Tag: "Configuration", Summary: "Delete a preset",
+	},
+	"GET /api/v1/strategies": {
+		Tag: "Configuration", Summary: "The current user's strategies and the fields rules can use",
+	},
+	"GET /api/v1/strategies/:name": {
+		Tag: "Configuration", Summary: "One strategy",
+		Query: []openapi.Param{openapi.Query("format", "Response format (default: json)").OneOf("json", "yaml")},
+	},
+	"POST /api/v1/strategies": {
+		Tag: "Configuration", Body: models.Strategy{},
+		Summary:     "Create or replace a strategy",
+		Description: "The body is JSON, or YAML with a YAML content type.",
+	},
+	"DELETE /api/v1/strategies/:name": {
+		Tag: "Configuration", Summary: "Delete a strategy",
+	},
+	"GET /api/v1/config/export": {
+		Tag: "Configuration", Summary: "The current user's configuration as one document",
+		Description: "Presets, strategies, notification channels and alert rules. Channel credentials and rule signing secrets are not exported.",
+	},
+	"POST /api/v1/config/import": {
+		Tag: "Configuration", Body: ConfigBundle{},
+		Summary: "Load a configuration document into the current user's account",
+		Query: []openapi.Param{
+			openapi.Query("mode", "merge upserts by name; replace also removes entries of each section in the document that it does not list (default: merge)").OneOf("merge", "replace"),
+		},
+	},
+
+	// Reference data and market data
+	"GET /api/v1/tickers": {
+		Tag: "Reference Data", Summary: "Screen the ticker table",
+		Query: []openapi.Param{
+			openapi.Query("sector", "Substring of the SIC sector description"),
+			openapi.Query("exchange", "Primary exchange MIC, e.g. XNAS"),
+			openapi.Query("type", "Security type, e.g. CS or ETF"),
+			openapi.Query("locale", "Polygon locale, e.g. us or global"),
+			openapi.Query("currency", "ISO code prices are normalized to, e.g. USD"),
+			openapi.Query("min_market_cap", "Lowest market cap, in currency").Number(),
+			openapi.Query("max_market_cap", "Highest market cap, in currency").Number(),
+			openapi.Query("search", "Substring of ticker or name"),
+			openapi.Query("active", "Active tickers (default: true)").Bool(),
+			limitParam, offsetParam,
+		},
+	},
+	"GET /api/v1/tickers/:ticker": {
+		Tag: "Reference Data", Summary: "Reference data for one ticker",
+	},
+	"GET /api/v1/technicals/summary": {
+		Tag: "Reference Data", Summary: "Latest daily SMA, EMA, RSI and MACD values for a ticker",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Ticker symbol").Require(),
+			openapi.Query("max_latency_ms", "Latency budget; late indicators can be collected from remainder_url").Int(),
+		},
+	},
+	"GET /api/v1/technicals/summary/:id": {
+		Tag: "Reference Data", Summary: "A previously requested summary, with indicators completed since",
+	},
+	"GET /api/v1/quote/:ticker": {
+		Tag: "Reference Data", Scope: models.ScopeDeepsearchRead, Summary: "One ticker's quote",
+	},
+	"GET /api/v1/quotes": {
+		Tag: "Reference Data", Scope: models.ScopeDeepsearchRead,
+		Summary: "Quotes of up to 100 tickers in request order",
+		Query:   []openapi.Param{openapi.Query("tickers", "Comma-separated tickers").Require()},
+	},
+	"GET /api/v1/market/breadth": {
+		Tag: "Reference Data", Scope: models.ScopeDeepsearchRead,
+		Summary: "Breadth across the tickers analysed on a market day",
+		Query:   []openapi.Param{openapi.Query("date", "Market date, YYYY-MM-DD (default: today)")},
+	},
+	"GET /api/v1/analytics/heatmap": {
+		Tag: "Reference Data", Scope: models.ScopeDeepsearchRead,
+		Summary:     "Ticker by market day matrix of stored decisions and confidence",
+		Description: "Returns image/png with format=png.",
+		Query: []openapi.Param{
+			openapi.Query("from", "First market day, YYYY-MM-DD (default: 30 days before to, at most 366 days)"),
+			openapi.Query("to", "Last market day, YYYY-MM-DD (default: today)"),
+			openapi.Query("tickers", "Comma-separated tickers in row order (default: every analysed ticker, at most 100)"),
+			openapi.Query("tag", "Only analyses carrying this tag"),
+			openapi.Query("format", "Response format (default: json)").OneOf("json", "png"),
+		},
+	},
+
+	// Institutional flow
+	"GET /api/v1/footprints/:ticker": {
+		Tag: "Institutional Flow", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored weekly footprints, most recent first",
+		Query: []openapi.Param{
+			openapi.Query("week", "Any date in the week, YYYY-MM-DD; returns that week only"),
+			limitParam, offsetParam,
+		},
+	},
+	"GET /api/v1/blocks/:ticker": {
+		Tag: "Institutional Flow", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored block trade sessions, or one session with its blocks",
+		Query: []openapi.Param{
+			openapi.Query("date", "Session date, YYYY-MM-DD; returns that session and its blocks"),
+			openapi.Query("side", "With date, only BUY or SELL blocks").OneOf("BUY", "SELL"),
+			openapi.Query("sort", "With date, blocks by time or notional (default: time)").OneOf("time", "notional"),
+			limitParam, offsetParam,
+		},
+	},
+	"GET /api/v1/confirmations/:ticker": {
+		Tag: "Institutional Flow", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored confirmed institutional moves, most recent first",
+		Query: []openapi.Param{
+			openapi.Query("date", "Session date, YYYY-MM-DD"),
+			openapi.Query("direction", "Move direction").OneOf("UP", "DOWN"),
+			limitParam, offsetParam,
+		},
+	},
+	"GET /api/v1/insiders/:ticker": {
+		Tag: "Institutional Flow", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored Form 4 insider transactions and clustered buying",
+		Query: []openapi.Param{
+			openapi.Query("from", "Earliest transaction date, YYYY-MM-DD (default: 90 days before to)"),
+			openapi.Query("to", "Latest transaction date, YYYY-MM-DD (default: today)"),
+			openapi.Query("side", "Transaction side").OneOf("BUY", "SELL"),
+			limitParam, offsetParam,
+		},
+	},
+	"GET /api/v1/ftd/:ticker": {
+		Tag: "Institutional Flow", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored fails-to-deliver by settlement date and their spikes",
+		Query: []openapi.Param{
+			openapi.Query("from", "Earliest settlement date, YYYY-MM-DD (default: 180 days before to)"),
+			openapi.Query("to", "Latest settlement date, YYYY-MM-DD (default: today)"),
+			limitParam, offsetParam,
+		},
+	},
+
+	// Options
+	"GET /api/v1/options/chain/:ticker": {
+		Tag: "Options", Scope: models.ScopeDeepsearchRead,
+		Summary: "Options chain with open interest, volume and greeks per contract",
+		Query: []openapi.Param{
+			openapi.Query("type", "Contract type (default: both)").OneOf("call", "put"),
+			openapi.Query("expiration", "Only this expiry, YYYY-MM-DD"),
+			openapi.Query("expiration_from", "Earliest expiry, YYYY-MM-DD (default: today)"),
+			openapi.Query("expiration_to", "Latest expiry, YYYY-MM-DD"),
+			openapi.Query("strike_min", "Lowest strike").Number(),
+			openapi.Query("strike_max", "Highest strike").Number(),
+			limitParam,
+		},
+	},
+	"GET /api/v1/options/max-pain/:ticker": {
+		Tag: "Options", Scope: models.ScopeDeepsearchRead,
+		Summary: "Max pain of the nearest expiries, stored as today's snapshot",
+		Query:   []openapi.Param{openapi.Query("expiries", "Number of nearest expiries (default: 4, max: 12)").Int()},
+	},
+	"GET /api/v1/options/max-pain/:ticker/history": {
+		Tag: "Options", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored daily max pain snapshots by expiration",
+		Query: []openapi.Param{
+			openapi.Query("expiration", "Only this expiry, YYYY-MM-DD"),
+			fromParam, toParam,
+			openapi.Query("strikes", "Include open interest by strike (default: false)").Bool(),
+		},
+	},
+	"GET /api/v1/options/iv-rank/:ticker": {
+		Tag: "Options", Scope: models.ScopeDeepsearchRead,
+		Summary: "At-the-money implied volatility with its IV rank and percentile",
+		Query:   []openapi.Param{openapi.Query("history", "Include the year's daily readings (default: false)").Bool()},
+	},
+	"GET /api/v1/options/put-call/:ticker": {
+		Tag: "Options", Scope: models.ScopeDeepsearchRead,
+		Summary: "Stored put/call ratios, oldest first; MARKET is the market-wide ratio",
+		Query: []openapi.Param{
+			openapi.Query("interval", "Reading interval (default: daily)").OneOf("daily", "intraday"),
+			fromParam, toParam,
+			openapi.Query("extreme", "Only extreme readings (default: false)").Bool(),
+		},
+	},
+
+	// Alerts
+	"GET /api/v1/alerts": {
+		Tag: "Alerts", Summary: "The current user's alert rules",
+	},
+	"POST /api/v1/alerts": {
+		Tag: "Alerts", Body: AlertRuleRequest{},
+		Summary: "Create an alert rule; its signing secret is only returned in this response",
+	},
+	"PATCH /api/v1/alerts/:id": {
+		Tag: "Alerts", Body: AlertRuleRequest{},
+		Summary: "Change an alert rule; only the fields present are changed",
+	},
+	"DELETE /api/v1/alerts/:id": {
+		Tag: "Alerts", Summary: "Delete an alert rule",
+	},
+	"GET /api/v1/alerts/:id/deliveries": {
+		Tag: "Alerts", Summary: "An alert rule's deliveries, newest first",
+		Query: []openapi.Param{statusParam, limitParam, offsetParam},
+	},
+	"GET /api/v1/alerts/channels": {
+		Tag: "Alerts", Summary: "The current user's notification channels",
+	},
+	"POST /api/v1/alerts/channels": {
+		Tag: "Alerts", Body: NotificationChannelRequest{},
+		Summary: "Create a Slack, Discord, Telegram or email channel",
+	},
+	"PATCH /api/v1/alerts/channels/:id": {
+		Tag: "Alerts", Body: NotificationChannelUpdate{},
+		Summary: "Change a channel's name, email recipients or template",
+	},
+	"DELETE /api/v1/alerts/channels/:id": {
+		Tag: "Alerts", Summary: "Delete a notification channel",
+	},
+	"POST /api/v1/alerts/templates/preview": {
+		Tag: "Alerts", Body: TemplatePreviewRequest{},
+		Summary: "Render a notification template against a sample alert",
+	},
+	"POST /api/v1/alerts/channels/:id/test": {
+		Tag: "Alerts", Summary: "Send a sample alert to a channel now",
+	},
+
+	// Integrations
+	"GET /api/v1/integrations": {
+		Tag: "Integrations", Summary: "The current user's integrations",
+	},
+	"POST /api/v1/integrations": {
+		Tag: "Integrations", Body: IntegrationRequest{},
+		Summary: "Create an integration; its signing secret is only returned in this response",
+	},
+	"GET /api/v1/integrations/fields": {
+		Tag: "Integrations", Summary: "The flat schema's fields and a sample payload",
+	},
+	"PATCH /api/v1/integrations/:id": {
+		Tag: "Integrations", Body: IntegrationRequest{},
+		Summary: "Change an integration; only the fields present are changed",
+	},
+	"DELETE /api/v1/integrations/:id": {
+		Tag: "Integrations", Summary: "Delete an integration",
+	},
+	"POST /api/v1/integrations/:id/test": {
+		Tag: "Integrations", Summary: "Post the sample payload to an integration now",
+	},
+	"GET /api/v1/integrations/:id/deliveries": {
+		Tag: "Integrations", Summary: "An integration's deliveries, newest first",
+		Query: []openapi.Param{statusParam, limitParam, offsetParam},
+	},
+
+	// Portfolio
+	"GET /api/v1/portfolio/positions": {
+		Tag: "Portfolio", Summary: "The caller's open positions valued at their last trades, with totals per currency",
+		Query: []openapi.Param{openapi.Query("include_closed", "Also list closed positions with their realized P&L (default: false)").Bool()},
+	},
+	"GET /api/v1/portfolio/transactions": {
+		Tag: "Portfolio", Summary: "The caller's transactions, latest executed first",
+		Query: []openapi.Param{openapi.Query("ticker", "Only transactions in this ticker"), limitParam, offsetParam},
+	},
+	"POST /api/v1/portfolio/transactions": {
+		Tag: "Portfolio", Body: TransactionRequest{},
+		Summary: "Record a buy or sell and rebuild the ticker's position",
+	},
+	"DELETE /api/v1/portfolio/transactions/:id": {
+		Tag: "Portfolio", Summary: "Delete a transaction and rebuild the ticker's position",
+	},
+
+	// Broker
+	"GET /api/v1/broker/account": {
+		Tag:     "Broker",
+		Summary: "The caller's broker account and whether a kill switch stops its orders",
+	},
+	"PUT /api/v1/broker/account": {
+		Tag: "Broker", Body: BrokerAccountRequest{},
+		Summary:     "Check Alpaca keys against the broker and store them encrypted",
+		Description: "Replaces any keys saved before. Returns 503 when BROKER_ENCRYPTION_KEY is not configured. Like every broker route, requires a login token, not an API key, even when auth is optional.",
+	},
+	"DELETE /api/v1/broker/account": {
+		Tag:     "Broker",
+		Summary: "Delete the caller's broker keys",
+	},
+	"GET /api/v1/broker/rules": {
+		Tag:     "Broker",
+		Summary: "The caller's execution rules",
+	},
+	"POST /api/v1/broker/rules": {
+		Tag: "Broker", Body: ExecutionRuleRequest{},
+		Summary: "Place orders when one of the caller's strategies decides BUY or SELL",
+	},
+	"PATCH /api/v1/broker/rules/:id": {
+		Tag: "Broker", Body: ExecutionRuleRequest{},
+		Summary: "Change an execution rule; only the fields present are changed",
+	},
+	"DELETE /api/v1/broker/rules/:id": {
+		Tag:     "Broker",
+		Summary: "Delete an execution rule",
+	},
+	"GET /api/v1/broker/orders": {
+		Tag:     "Broker",
+		Summary: "The caller's broker orders, newest first",
+		Query: []openapi.Param{
+			openapi.Query("status", "Only orders in this status, e.g. pending, failed or filled"),
+			openapi.Query("ticker", "Only orders for this ticker"),
+			limitParam, offsetParam,
+		},
+	},
+	"POST /api/v1/broker/kill-switch": {
+		Tag: "Broker", Body: KillSwitchRequest{},
+		Summary: "Stop placing orders for the caller and cancel their open orders",
+	},
+	"DELETE /api/v1/broker/kill-switch": {
+		Tag:     "Broker",
+		Summary: "Release the caller's kill switch",
+	},
+	"POST /api/v1/admin/broker/kill-switch": {
+		Tag: "Admin", Scope: models.ScopeAdmin, Body: KillSwitchRequest{},
+		Summary: "Stop placing orders for every user and cancel every open order",
+	},
+	"DELETE /api/v1/admin/broker/kill-switch": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Release the global kill switch; users' own kill switches stay engaged",
+	},
+
+	// Sharing
+	"POST /api/v1/deepsearch/analysis/:id/share": {
+		Tag: "Sharing", Scope: models.ScopeDeepsearchRead,
+		Summary: "Issue a share link to a stored analysis",
+		Query:   []openapi.Param{sharesParam},
+	},
+	"POST /api/v1/admin/reports/artifacts/:id/share": {
+		Tag: "Sharing", Scope: models.ScopeAdmin,
+		Summary: "Issue a share link to a generated report",
+		Query:   []openapi.Param{sharesParam},
+	},
+	"GET /api/v1/shared/:token": {
+		Tag: "Sharing", Public: true, Produces: "text/html",
+		Summary: "The analysis or report a share link points at",
+		Query:   []openapi.Param{openapi.Query("format", "Rendering (default: html)").OneOf("html", "csv", "pdf")},
+	},
+
+	// Administration
+	"POST /api/v1/admin/ingest/grouped-daily": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Load grouped daily bars for one date",
+		Query:   []openapi.Param{dateParam.Require()},
+	},
+	"POST /api/v1/admin/ingest/tickers": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Start a ticker reference data sync in the background",
+		Query:   []openapi.Param{openapi.Query("details_batch", "Tickers to fetch details for (default: 200)").Int()},
+	},
+	"POST /api/v1/admin/bars/compact": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Run the bar store compaction now",
+		Query:   []openapi.Param{openapi.Query("retention_days", "Delete intraday bars older than this; 0 keeps them (default: BAR_RETENTION_DAYS)").Int()},
+	},
+	"GET /api/v1/admin/retention": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Analysis retention policy and rows archived or purged by this process",
+	},
+	"POST /api/v1/admin/retention/run": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary:     "Run the analysis retention job now",
+		Description: "Archives or purges analyses and deep search requests older than their owner's tier retention window.",
+		Query:       []openapi.Param{openapi.Query("mode", "Override ANALYSIS_RETENTION_MODE").OneOf("archive", "purge")},
+	},
+	"POST /api/v1/admin/footprints/:ticker": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Build and store a ticker's weekly footprint now",
+		Query:   []openapi.Param{openapi.Query("week", "Any date in the week, YYYY-MM-DD (default: the last completed week)")},
+	},
+	"POST /api/v1/admin/blocks/:ticker": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Detect and store a session's block trades now",
+		Query: []openapi.Param{
+			openapi.Query("date", "Session date, YYYY-MM-DD (default: the last completed session)"),
+			openapi.Query("min_notional", "Dollar value a print needs to count as a block").Number(),
+			openapi.Query("timespan", "Bar size unit flow flags are verified at (default: minute)").OneOf("second", "minute", "hour"),
+			openapi.Query("multiplier", "Bar size multiplier (default: 1)").Int(),
+		},
+	},
+	"POST /api/v1/admin/confirmations/:ticker": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Find and store a session's confirmed moves now",
+		Query: []openapi.Param{
+			openapi.Query("date", "Session date, YYYY-MM-DD (default: the last completed session)"),
+			openapi.Query("sources", "Comma-separated sources, N (default: volume,blocks,sweeps,dark_pool)"),
+			openapi.Query("required", "Sources that must agree, K (default: 3)").Int(),
+			openapi.Query("window_minutes", "How close together they must fire (default: 15)").Int(),
+		},
+	},
+	"POST /api/v1/admin/insiders/:ticker": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Read a ticker's new Form 4 filings now",
+		Query:   []openapi.Param{openapi.Query("lookback_days", "Days of filings to read (default: 90, max 1000)").Int()},
+	},
+	"POST /api/v1/admin/ftd": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Load one half-month fails-to-deliver file now",
+		Query:   []openapi.Param{openapi.Query("period", "Month and half, e.g. 202505a (default: the last period before the current one)")},
+	},
+	"GET /api/v1/admin/jobs/failed": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Failed jobs with their error and parameters, newest first",
+		Query:   []openapi.Param{openapi.Query("ticker", "Only jobs for this ticker"), limitParam, offsetParam},
+	},
+	"POST /api/v1/admin/jobs/failed/retry": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Requeue every failed job",
+		Query: []openapi.Param{
+			openapi.Query("ticker", "Only jobs for this ticker"),
+			openapi.Query("since", "Only jobs that failed at or after this time, RFC3339 or YYYY-MM-DD"),
+		},
+	},
+	"POST /api/v1/admin/jobs/failed/:id/retry": {
+		Tag: "Admin", Scope: models.ScopeAdmin, Summary: "Requeue one failed job",
+	},
+	"GET /api/v1/admin/reports": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Generated report artifacts, newest first",
+		Query: []openapi.Param{
+			openapi.Query("kind", "Report kind").OneOf(models.ReportDailyDigest, models.ReportEarningsPreview),
+			openapi.Query("format", "Artifact format").OneOf("html", "csv", "pdf"),
+			limitParam, offsetParam,
+		},
+	},
+	"POST /api/v1/admin/reports/:kind": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Generate a report now",
+		Query:   []openapi.Param{openapi.Query("date", "Market date the report is for, YYYY-MM-DD (default: today)")},
+	},
+	"GET /api/v1/admin/decision-rules": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "The decision table in evaluation order",
+	},
+	"POST /api/v1/admin/decision-rules": {
+		Tag: "Admin", Scope: models.ScopeAdmin, Body: DecisionRuleRequest{},
+		Summary: "Add a decision rule",
+		Query:   []openapi.Param{openapi.Query("seed", "Import the built-in defaults before the first stored rule").Bool()},
+	},
+	"POST /api/v1/admin/decision-rules/evaluate": {
+		Tag: "Admin", Scope: models.ScopeAdmin, Body: map[string]float64{},
+		Summary: "Evaluate the decision table against the inputs in the body",
+	},
+	"PATCH /api/v1/admin/decision-rules/:id": {
+		Tag: "Admin", Scope: models.ScopeAdmin, Body: DecisionRuleRequest{},
+		Summary: "Change a decision rule; only the fields present are changed",
+	},
+	"DELETE /api/v1/admin/decision-rules/:id": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Delete a decision rule; deleting the last restores the defaults",
+	},
+	"GET /api/v1/admin/usage/polygon": {
+		Tag: "Admin", Scope: models.ScopeAdmin,
+		Summary: "Polygon calls summed over a date range",
+		Query: []openapi.Param{
+			openapi.Query("from", "First UTC day, YYYY-MM-DD (default: 6 days before to)"),
+			openapi.Query("to", "Last UTC day, YYYY-MM-DD (default: today)"),
+			openapi.Query("group_by", "Comma-separated dimensions of day, feature, user, endpoint and tier (default: feature)"),
+			openapi.Query("feature", "Only this feature"),
+			openapi.Query("user_id", "Only this user"),
+		},
+	},
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+	"gorm.io/gorm"
+)
+
+// maxPainExpiries is the most expiries one max pain request may ask for
+const maxPainExpiries = 12
+
+// OptionsHandler serves options data from Polygon snapshots
+type OptionsHandler struct {
+	db *gorm.DB
+}
+
+// NewOptionsHandler creates a new options handler
+func NewOptionsHandler(db *gorm.DB) *OptionsHandler {
+	return &OptionsHandler{db: db}
+}
+
+// HandleGetChain returns a ticker's options chain: strikes, expiries, open
+// interest, volume and greeks per contract, with put/call totals
+// Query parameters:
+//   - type: call or put (default: both)
+//   - expiration: Only this expiry, YYYY-MM-DD
+//   - expiration_from, expiration_to: Expiry range, YYYY-MM-DD (default: from today)
+//   - strike_min, strike_max: Strike range
+//   - limit: Maximum number of contracts (default: 250, max: 2500)
+func (h *OptionsHandler) HandleGetChain(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := service.OptionsChainFilter{
+		ExpirationFrom: c.Query("expiration_from"),
+		ExpirationTo:   c.Query("expiration_to"),
+	}
+	switch contractType := strings.ToLower(c.Query("type")); contractType {
+	case "":
+	case "call":
+		filter.ContractType = polygonmodels.ContractCall
+	case "put":
+		filter.ContractType = polygonmodels.ContractPut
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be call or put"})
+		return
+	}
+	if expiration := c.Query("expiration"); expiration != "" {
+		filter.ExpirationFrom, filter.ExpirationTo = expiration, expiration
+	}
+	if filter.ExpirationFrom == "" {
+		filter.ExpirationFrom = time.Now().In(jobs.MarketTimezone).Format("2006-01-02")
+	}
+	for _, date := range []string{filter.ExpirationFrom, filter.ExpirationTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiration format, use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	for param, dst := range map[string]*float64{"strike_min": &filter.StrikeMin, "strike_max": &filter.StrikeMax} {
+		if val := c.Query(param); val != "" {
+			strike, err := strconv.ParseFloat(val, 64)
+			if err != nil || strike <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a positive number"})
+				return
+			}
+			*dst = strike
+		}
+	}
+
+	if filter.StrikeMax > 0 && filter.StrikeMax < filter.StrikeMin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strike_max cannot be below strike_min"})
+		return
+	}
+
+	limit, _ := parsePagination(c, 250, 2500)
+	chain, err := deepsearch.FetchOptionsChain(c.Request.Context(), ticker, filter, limit)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch options chain", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, chain)
+}
+
+// HandleGetMaxPain returns the max pain strike and open interest by strike of
+// a ticker's nearest expiries, and stores them as today's snapshot
+// Query parameters:
+//   - expiries: Number of nearest expiries (default: 4, max: 12)
+func (h *OptionsHandler) HandleGetMaxPain(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiries := jobs.DefaultMaxPainExpiries
+	if val := c.Query("expiries"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 || n > maxPainExpiries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expiries must be between 1 and %d", maxPainExpiries)})
+			return
+		}
+		expiries = n
+	}
+
+	snapshots, err := deepsearch.ComputeMaxPain(c.Request.Context(), ticker, expiries)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch options chain", "details": err.Error()})
+		return
+	}
+	// The response does not depend on the snapshot being stored
+	if err := models.UpsertMaxPain(h.db, snapshots); err != nil {
+		fmt.Printf("[API] failed to store max pain of %s: %v\n", ticker, err)
+	}
+	if snapshots == nil {
+		snapshots = []models.MaxPainSnapshot{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "expirations": snapshots})
+}
+
+// HandleGetMaxPainHistory returns a ticker's stored daily max pain snapshots,
+// ordered by expiration then day, to chart how max pain migrates into
+// expiration
+// Query parameters:
+//   - expiration: Only this expiry, YYYY-MM-DD (optional)
+//   - from, to: Snapshot days, YYYY-MM-DD (optional)
+//   - strikes: true to include open interest by strike (default: false)
+func (h *OptionsHandler) HandleGetMaxPainHistory(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker is required"})
+		return
+	}
+
+	query := h.db.Where("ticker = ?", ticker)
+	for param, condition := range map[string]string{"expiration": "expiration = ?", "from": "day >= ?", "to": "day <= ?"} {
+		val := c.Query(param)
+		if val == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", val); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format, use YYYY-MM-DD", param)})
+			return
+		}
+		query = query.Where(condition, val)
+	}
+	if c.Query("strikes") != "true" {
+		query = query.Omit("strikes")
+	}
+
+	var snapshots []models.MaxPainSnapshot
+	if err := query.Order("expiration, day").Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load max pain history", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "data": snapshots, "count": len(snapshots)})
+}
+
+// HandleGetPutCallHistory returns a ticker's stored put/call ratios, oldest
+// first. MARKET is the market-wide ratio.
+// Query parameters:
+//   - interval: daily or intraday (default: daily)
+//   - from, to: Market days, YYYY-MM-DD (optional)
+//   - extreme: true to return only extreme readings (default: false)
+func (h *OptionsHandler) HandleGetPutCallHistory(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker is required"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", models.PutCallDaily)
+	if interval != models.PutCallDaily && interval != models.PutCallIntraday {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be daily or intraday"})
+		return
+	}
+
+	query := h.db.Where("ticker = ? AND sample_interval = ?", ticker, interval)
+	for param, condition := range map[string]string{"from": "day >= ?", "to": "day <= ?"} {
+		val := c.Query(param)
+		if val == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", val); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format, use YYYY-MM-DD", param)})
+			return
+		}
+		query = query.Where(condition, val)
+	}
+	if c.Query("extreme") == "true" {
+		query = query.Where("extreme <> ''")
+	}
+
+	var ratios []models.PutCallRatio
+	if err := query.Order("timestamp").Find(&ratios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load put/call ratios", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "interval": interval, "data": ratios, "count": len(ratios)})
+}
+
+// HandleGetIVRank reads a ticker's at-the-money implied volatility now,
+// stores it as today's reading and returns it with its IV rank and percentile
+// over the trailing year of stored readings
+// Query parameters:
+//   - history: true to include the year's daily readings (default: false)
+func (h *OptionsHandler) HandleGetIVRank(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	iv, rank, err := deepsearch.RefreshImpliedVolatility(c.Request.Context(), h.db, ticker)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read implied volatility", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{"ticker": ticker, "current": iv, "rank": rank}
+	if c.Query("history") == "true" {
+		var history []models.ImpliedVolatility
+		since := time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
+		if err := h.db.Where("ticker = ? AND day > ?", ticker, since).Order("day").Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load implied volatility history", "details": err.Error()})
+			return
+		}
+		response["history"] = history
+	}
+
+	c.JSON(http.StatusOK, response)
+}
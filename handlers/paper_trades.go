@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PaperTradesHandler serves the caller's paper trades and their P&L
+type PaperTradesHandler struct {
+	db *gorm.DB
+}
+
+// NewPaperTradesHandler creates a new paper trades handler
+func NewPaperTradesHandler(db *gorm.DB) *PaperTradesHandler {
+	return &PaperTradesHandler{db: db}
+}
+
+// PaperPnL sums a user's paper trades in one currency
+type PaperPnL struct {
+	Currency      string  `json:"currency"`
+	OpenTrades    int     `json:"open_trades"`
+	ClosedTrades  int     `json:"closed_trades"`
+	Wins          int     `json:"wins"`
+	WinRate       float64 `json:"win_rate"`
+	RealizedPnL   float64 `gorm:"column:realized_pnl" json:"realized_pnl"`
+	UnrealizedPnL float64 `gorm:"column:unrealized_pnl" json:"unrealized_pnl"`
+	TotalPnL      float64 `gorm:"-" json:"total_pnl"`
+}
+
+// HandleListPaperTrades lists the caller's paper trades, newest first
+// Query parameters:
+//   - status: open or closed (default: both)
+//   - ticker: Only trades in this ticker (optional)
+//   - limit/offset: Pagination (default 100, max 1000)
+func (h *PaperTradesHandler) HandleListPaperTrades(c *gin.Context) {
+	query := h.db.Model(&models.PaperTrade{}).Where("user_id = ?", currentUserID(c))
+	switch status := c.Query("status"); status {
+	case "":
+	case models.PaperTradeOpen, models.PaperTradeClosed:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open or closed"})
+		return
+	}
+	if ticker := c.Query("ticker"); ticker != "" {
+		query = query.Where("ticker = ?", strings.ToUpper(ticker))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper trades", "details": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	trades := []models.PaperTrade{}
+	if err := query.Order("entry_at desc, id desc").Limit(limit).Offset(offset).Find(&trades).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper trades", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": trades,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(trades),
+		},
+	})
+}
+
+// HandleGetPaperPnL returns the caller's realized and unrealized paper P&L
+// by currency. Unrealized P&L is as of each open trade's last mark.
+func (h *PaperTradesHandler) HandleGetPaperPnL(c *gin.Context) {
+	pnl := []PaperPnL{}
+	err := h.db.Raw(`
+		SELECT currency,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS open_trades,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS closed_trades,
+			SUM(CASE WHEN status = ? AND realized_pnl > 0 THEN 1 ELSE 0 END) AS wins,
+			SUM(realized_pnl) AS realized_pnl,
+			SUM(unrealized_pnl) AS unrealized_pnl
+		FROM paper_trades
+		WHERE user_id = ?
+		GROUP BY currency
+		ORDER BY currency`,
+		models.PaperTradeOpen, models.PaperTradeClosed, models.PaperTradeClosed, currentUserID(c)).
+		Scan(&pnl).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper P&L", "details": err.Error()})
+		return
+	}
+
+	for i := range pnl {
+		p := &pnl[i]
+		if p.ClosedTrades > 0 {
+			p.WinRate = float64(p.Wins) / float64(p.ClosedTrades)
+		}
+		p.TotalPnL = p.RealizedPnL + p.UnrealizedPnL
+	}
+	c.JSON(http.StatusOK, gin.H{"pnl": pnl})
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PerformanceHandler serves recorded signal hit rates
+type PerformanceHandler struct {
+	db *gorm.DB
+}
+
+// NewPerformanceHandler creates a new performance handler
+func NewPerformanceHandler(db *gorm.DB) *PerformanceHandler {
+	return &PerformanceHandler{db: db}
+}
+
+// SignalTypePerformance is the next-bar hit rate of one signal kind and direction
+type SignalTypePerformance struct {
+	Kind      string  `json:"kind"`
+	Direction string  `json:"direction"`
+	Analyses  int     `json:"analyses"`
+	Signals   int     `json:"signals"`
+	Wins      int     `json:"wins"`
+	WinRate   float64 `json:"win_rate"`
+	ReturnSum float64 `json:"-"`
+	// AvgReturn is the mean next-bar return, signed so positive favours the signal
+	AvgReturn float64 `json:"avg_return"`
+}
+
+// HandleGetPerformance returns a ticker's signal hit rates across all its
+// analyses, by signal type, most frequent first
+// Query parameters:
+//   - ticker: Ticker symbol (required)
+func (h *PerformanceHandler) HandleGetPerformance(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker is required"})
+		return
+	}
+
+	bySignal := []SignalTypePerformance{}
+	err := h.db.Raw(`
+		SELECT kind, direction, COUNT(DISTINCT analysis_id) AS analyses, SUM(signals) AS signals,
+			SUM(wins) AS wins, SUM(return_sum) AS return_sum
+		FROM signal_performances
+		WHERE ticker = ?
+		GROUP BY kind, direction
+		ORDER BY signals DESC, kind, direction`, ticker).
+		Scan(&bySignal).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signal performance", "details": err.Error()})
+		return
+	}
+
+	var analyses int64
+	if err := h.db.Raw("SELECT COUNT(DISTINCT analysis_id) FROM signal_performances WHERE ticker = ?", ticker).Scan(&analyses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signal performance", "details": err.Error()})
+		return
+	}
+
+	var signals, wins int
+	for i := range bySignal {
+		p := &bySignal[i]
+		if p.Signals > 0 {
+			p.WinRate = float64(p.Wins) / float64(p.Signals)
+			p.AvgReturn = p.ReturnSum / float64(p.Signals)
+		}
+		signals += p.Signals
+		wins += p.Wins
+	}
+	winRate := 0.0
+	if signals > 0 {
+		winRate = float64(wins) / float64(signals)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticker":    ticker,
+		"analyses":  analyses,
+		"signals":   signals,
+		"wins":      wins,
+		"win_rate":  winRate,
+		"by_signal": bySignal,
+	})
+}
+
+// attributionGroups maps the attribution group_by options to their columns
+var attributionGroups = map[string][]string{
+	"kind":     {"kind", "direction"},
+	"ticker":   {"ticker"},
+	"strategy": {"strategy_id"},
+}
+
+// AttributionGroup is the hypothetical P&L of one signal kind, ticker or
+// strategy's signals at a horizon
+type AttributionGroup struct {
+	Kind       string `json:"kind,omitempty"`
+	Direction  string `json:"direction,omitempty"`
+	Ticker     string `json:"ticker,omitempty"`
+	StrategyID *uint  `json:"strategy_id,omitempty"`
+	// Strategy is the strategy's name, "built-in" for the built-in signals
+	Strategy  string  `json:"strategy,omitempty"`
+	Signals   int     `json:"signals"`
+	Wins      int     `json:"wins"`
+	Pending   int     `json:"pending"`
+	WinRate   float64 `json:"win_rate"`
+	ReturnSum float64 `json:"-"`
+	// AvgReturn is the mean return, signed so positive favours the signal
+	AvgReturn float64 `json:"avg_return"`
+	// PnL is ReturnSum x notional: what taking every signal with notional would have made
+	PnL float64 `json:"pnl"`
+}
+
+// HandleGetAttribution attributes the hypothetical P&L of the caller's
+// signals at a horizon to signal kinds, tickers or strategies, largest P&L first
+// Query parameters:
+//   - horizon: A tracked horizon, e.g. 5m, 30m or 1d (default: 30m)
+//   - group_by: kind (default), ticker or strategy
+//   - ticker: Only signals in this ticker (optional)
+//   - strategy_id: Only signals of this strategy, 0 for the built-in signals (optional)
+//   - from, to: Only signals on or between these market days, YYYY-MM-DD (optional)
+//   - notional: The position taken per signal (default: 10000)
+func (h *PerformanceHandler) HandleGetAttribution(c *gin.Context) {
+	horizon := c.DefaultQuery("horizon", "30m")
+	if _, err := deepsearch.ParseHorizon(horizon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "kind")
+	columns, ok := attributionGroups[groupBy]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be kind, ticker or strategy"})
+		return
+	}
+	notional := 10000.0
+	if val := c.Query("notional"); val != "" {
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notional must be a positive number"})
+			return
+		}
+		notional = n
+	}
+
+	query := ownedBy(h.db.Model(&models.SignalReturn{}), ownerID(c)).
+		Where("horizon = ? AND status <> ?", horizon, models.SignalReturnUnavailable)
+	if ticker := c.Query("ticker"); ticker != "" {
+		query = query.Where("ticker = ?", strings.ToUpper(ticker))
+	}
+	if val := c.Query("strategy_id"); val != "" {
+		id, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid strategy_id"})
+			return
+		}
+		query = query.Where("strategy_id = ?", id)
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<"} {
+		if val := c.Query(param); val != "" {
+			day, err := time.ParseInLocation("2006-01-02", val, jobs.MarketTimezone)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format, use YYYY-MM-DD", param)})
+				return
+			}
+			if param == "to" {
+				day = day.AddDate(0, 0, 1)
+			}
+			query = query.Where("entry_time "+op+" ?", day)
+		}
+	}
+
+	groups := []AttributionGroup{}
+	err := query.Select(strings.Join(columns, ", ")+`,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS signals,
+			SUM(CASE WHEN status = ? AND win = ? THEN 1 ELSE 0 END) AS wins,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS pending,
+			SUM(CASE WHEN status = ? THEN signed_return ELSE 0 END) AS return_sum`,
+		models.SignalReturnScored, models.SignalReturnScored, true, models.SignalReturnPending, models.SignalReturnScored).
+		Group(strings.Join(columns, ", ")).
+		Scan(&groups).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signal attribution", "details": err.Error()})
+		return
+	}
+
+	var total AttributionGroup
+	for i := range groups {
+		g := &groups[i]
+		if g.Signals > 0 {
+			g.WinRate = float64(g.Wins) / float64(g.Signals)
+			g.AvgReturn = g.ReturnSum / float64(g.Signals)
+		}
+		g.PnL = g.ReturnSum * notional
+		total.Signals += g.Signals
+		total.Wins += g.Wins
+		total.Pending += g.Pending
+		total.ReturnSum += g.ReturnSum
+	}
+	if total.Signals > 0 {
+		total.WinRate = float64(total.Wins) / float64(total.Signals)
+		total.AvgReturn = total.ReturnSum / float64(total.Signals)
+	}
+	total.PnL = total.ReturnSum * notional
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].PnL > groups[j].PnL })
+
+	if groupBy == "strategy" {
+		if err := h.nameStrategies(groups); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signal attribution", "details": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"horizon":  horizon,
+		"group_by": groupBy,
+		"notional": notional,
+		"total":    total,
+		"groups":   groups,
+	})
+}
+
+// nameStrategies fills in the strategy names of attribution groups
+func (h *PerformanceHandler) nameStrategies(groups []AttributionGroup) error {
+	var ids []uint
+	for _, g := range groups {
+		if g.StrategyID != nil && *g.StrategyID != 0 {
+			ids = append(ids, *g.StrategyID)
+		}
+	}
+	names := map[uint]string{}
+	if len(ids) > 0 {
+		var strategies []models.Strategy
+		if err := h.db.Select("id", "name").Where("id IN ?", ids).Find(&strategies).Error; err != nil {
+			return err
+		}
+		for _, strategy := range strategies {
+			names[strategy.ID] = strategy.Name
+		}
+	}
+	for i := range groups {
+		g := &groups[i]
+		switch {
+		case g.StrategyID == nil || *g.StrategyID == 0:
+			g.Strategy = "built-in"
+		case names[*g.StrategyID] != "":
+			g.Strategy = names[*g.StrategyID]
+		default:
+			g.Strategy = "deleted"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/portfolio"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PortfolioHandler records the current user's transactions and serves the
+// positions they add up to
+type PortfolioHandler struct {
+	db *gorm.DB
+}
+
+// NewPortfolioHandler creates a new portfolio handler
+func NewPortfolioHandler(db *gorm.DB) *PortfolioHandler {
+	return &PortfolioHandler{db: db}
+}
+
+// TransactionRequest is the body of POST /api/v1/portfolio/transactions
+type TransactionRequest struct {
+	Ticker   string  `json:"ticker" binding:"required"`
+	Side     string  `json:"side" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required"`
+	Price    float64 `json:"price" binding:"required"`
+	Fees     float64 `json:"fees"`
+	// Currency defaults to the ticker's quote currency
+	Currency string `json:"currency"`
+	// ExecutedAt defaults to now
+	ExecutedAt *time.Time `json:"executed_at"`
+	Note       string     `json:"note"`
+}
+
+// HandleGetPositions values the current user's open positions at their last
+// trades and totals them per currency
+// Query parameters:
+//   - include_closed: Also list closed positions with their realized P&L (default: false)
+func (h *PortfolioHandler) HandleGetPositions(c *gin.Context) {
+	valuation, err := portfolio.Value(c.Request.Context(), h.db, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch positions", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{"holdings": valuation.Holdings, "summaries": valuation.Summaries}
+	if c.Query("include_closed") == "true" {
+		closed := []models.Position{}
+		if err := h.db.Where("user_id = ? AND quantity = 0", currentUserID(c)).Order("ticker").Find(&closed).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch positions", "details": err.Error()})
+			return
+		}
+		response["closed"] = closed
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleListTransactions lists the current user's transactions, latest
+// executed first
+// Query parameters:
+//   - ticker: Only transactions in this ticker (optional)
+//   - limit/offset: Pagination (default 100, max 1000)
+func (h *PortfolioHandler) HandleListTransactions(c *gin.Context) {
+	query := h.db.Model(&models.Transaction{}).Where("user_id = ?", currentUserID(c))
+	if ticker := c.Query("ticker"); ticker != "" {
+		query = query.Where("ticker = ?", strings.ToUpper(ticker))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions", "details": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	transactions := []models.Transaction{}
+	if err := query.Order("executed_at desc, id desc").Limit(limit).Offset(offset).Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": transactions,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(transactions),
+		},
+	})
+}
+
+// HandleCreateTransaction records a buy or sell and returns it with the
+// position it leaves
+func (h *PortfolioHandler) HandleCreateTransaction(c *gin.Context) {
+	var req TransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	transaction := models.Transaction{
+		UserId:   currentUserID(c),
+		Ticker:   strings.ToUpper(strings.TrimSpace(req.Ticker)),
+		Side:     strings.ToLower(req.Side),
+		Quantity: req.Quantity,
+		Price:    req.Price,
+		Fees:     req.Fees,
+		Currency: strings.ToUpper(req.Currency),
+		Note:     req.Note,
+	}
+	switch {
+	case transaction.Side != models.TransactionBuy && transaction.Side != models.TransactionSell:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be buy or sell"})
+		return
+	case transaction.Quantity <= 0 || transaction.Price <= 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity and price must be positive"})
+		return
+	case transaction.Fees < 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fees cannot be negative"})
+		return
+	}
+	transaction.ExecutedAt = time.Now()
+	if req.ExecutedAt != nil {
+		if req.ExecutedAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "executed_at cannot be in the future"})
+			return
+		}
+		transaction.ExecutedAt = *req.ExecutedAt
+	}
+	if transaction.Currency == "" {
+		transaction.Currency = models.TickerCurrency(h.db, transaction.Ticker).Currency
+	}
+
+	var position *models.Position
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var held models.Position
+		err := tx.Select("currency").Where("user_id = ? AND ticker = ? AND quantity <> 0", transaction.UserId, transaction.Ticker).First(&held).Error
+		if err == nil && held.Currency != transaction.Currency {
+			return badRequest(fmt.Sprintf("the %s position is in %s", transaction.Ticker, held.Currency))
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		position, err = portfolio.Rebuild(c.Request.Context(), tx, transaction.UserId, transaction.Ticker)
+		return err
+	})
+	if err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"transaction": transaction, "position": position})
+}
+
+// HandleDeleteTransaction removes a transaction recorded by mistake and
+// returns the position rebuilt without it, null if none is left
+func (h *PortfolioHandler) HandleDeleteTransaction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction id"})
+		return
+	}
+
+	var position *models.Position
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var transaction models.Transaction
+		if err := tx.Where("user_id = ?", currentUserID(c)).First(&transaction, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &RequestError{Status: http.StatusNotFound, Message: "Transaction not found"}
+			}
+			return err
+		}
+		if err := tx.Delete(&transaction).Error; err != nil {
+			return err
+		}
+		position, err = portfolio.Rebuild(c.Request.Context(), tx, transaction.UserId, transaction.Ticker)
+		return err
+	})
+	if err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted", "position": position})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var validTimeSpans = map[string]bool{
+	"second": true, "minute": true, "hour": true, "day": true,
+	"week": true, "month": true, "quarter": true, "year": true,
+}
+
+// PresetsHandler manages a user's analysis presets
+type PresetsHandler struct {
+	db *gorm.DB
+}
+
+// NewPresetsHandler creates a new presets handler
+func NewPresetsHandler(db *gorm.DB) *PresetsHandler {
+	return &PresetsHandler{db: db}
+}
+
+// PresetConfig is the portable form of an AnalysisPreset
+type PresetConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	TimeSpan    string `json:"timespan"`
+	Multiplier  int    `json:"multiplier"`
+}
+
+// Validate checks preset fields and applies defaults
+func (p *PresetConfig) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("preset name is required")
+	}
+	if p.TimeSpan == "" {
+		p.TimeSpan = "minute"
+	}
+	if !validTimeSpans[p.TimeSpan] {
+		return errors.New("invalid timespan for preset " + p.Name)
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 5
+	}
+	if p.Multiplier < 0 {
+		return errors.New("multiplier must be positive for preset " + p.Name)
+	}
+	return nil
+}
+
+func presetConfigFromModel(p models.AnalysisPreset) PresetConfig {
+	return PresetConfig{
+		Name:        p.Name,
+		Description: p.Description,
+		TimeSpan:    p.TimeSpan,
+		Multiplier:  p.Multiplier,
+	}
+}
+
+// HandleListPresets returns the current user's presets
+func (h *PresetsHandler) HandleListPresets(c *gin.Context) {
+	var presets []models.AnalysisPreset
+	if err := h.db.Where("user_id = ?", currentUserID(c)).Order("name").Find(&presets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"presets": presets})
+}
+
+// HandleSavePreset creates a preset, or updates the existing one with the same name
+func (h *PresetsHandler) HandleSavePreset(c *gin.Context) {
+	var req PresetConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preset, err := upsertPreset(h.db, currentUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preset": preset})
+}
+
+// HandleDeletePreset removes a preset by name
+func (h *PresetsHandler) HandleDeletePreset(c *gin.Context) {
+	result := h.db.Where("user_id = ? AND name = ?", currentUserID(c), c.Param("name")).Delete(&models.AnalysisPreset{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preset not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Preset deleted"})
+}
+
+// upsertPreset inserts or updates a preset keyed on (user_id, name)
+func upsertPreset(db *gorm.DB, userID string, cfg PresetConfig) (*models.AnalysisPreset, error) {
+	preset := models.AnalysisPreset{
+		UserId:      userID,
+		Name:        cfg.Name,
+		Description: cfg.Description,
+		TimeSpan:    cfg.TimeSpan,
+		Multiplier:  cfg.Multiplier,
+	}
+
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "time_span", "multiplier", "updated_at"}),
+	}).Create(&preset).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &preset, nil
+}
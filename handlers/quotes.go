@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+	polygonmodels "github.com/polygon-io/client-go/rest/models"
+)
+
+// maxQuoteTickers is the most tickers one batch quote request may ask for
+const maxQuoteTickers = 100
+
+// Quote is a ticker's last trade and quote with the day's change
+type Quote struct {
+	Ticker        string    `json:"ticker"`
+	Price         float64   `json:"price"`
+	LastTradeSize float64   `json:"last_trade_size"`
+	LastTradeAt   time.Time `json:"last_trade_at"`
+	Bid           float64   `json:"bid"`
+	BidSize       float64   `json:"bid_size"`
+	Ask           float64   `json:"ask"`
+	AskSize       float64   `json:"ask_size"`
+	QuotedAt      time.Time `json:"quoted_at"`
+	DayOpen       float64   `json:"day_open"`
+	DayHigh       float64   `json:"day_high"`
+	DayLow        float64   `json:"day_low"`
+	DayVolume     float64   `json:"day_volume"`
+	PrevClose     float64   `json:"prev_close"`
+	Change        float64   `json:"change"`
+	ChangePct     float64   `json:"change_pct"`
+	// FetchedAt is when the snapshot was read from Polygon; it is at most the
+	// cache TTL old
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// QuotesHandler serves cached last trades and quotes, so frontends can show
+// prices without their own Polygon key
+type QuotesHandler struct {
+	cache *service.QuoteCache
+}
+
+// NewQuotesHandler creates a new quotes handler over the process-wide cache
+func NewQuotesHandler() *QuotesHandler {
+	return &QuotesHandler{cache: service.DefaultQuoteCache()}
+}
+
+// HandleGetQuote returns one ticker's quote
+func (h *QuotesHandler) HandleGetQuote(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker is required"})
+		return
+	}
+
+	quotes, _, ok := h.fetch(c, []string{ticker})
+	if !ok {
+		return
+	}
+	if len(quotes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No quote for %s", ticker)})
+		return
+	}
+
+	c.JSON(http.StatusOK, quotes[0])
+}
+
+// HandleGetQuotes returns the quotes of up to 100 tickers in request order
+// Query parameters:
+//   - tickers: Comma-separated tickers (required)
+func (h *QuotesHandler) HandleGetQuotes(c *gin.Context) {
+	tickers := normalizeTickers(strings.Split(c.Query("tickers"), ","))
+	if len(tickers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tickers is required"})
+		return
+	}
+	if len(tickers) > maxQuoteTickers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tickers cannot exceed %d entries", maxQuoteTickers)})
+		return
+	}
+
+	quotes, missing, ok := h.fetch(c, tickers)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    quotes,
+		"missing": missing,
+		"count":   len(quotes),
+	})
+}
+
+// fetch reads tickers through the cache and returns their quotes in order
+// and the tickers without one. On a Polygon error it responds and reports false.
+func (h *QuotesHandler) fetch(c *gin.Context, tickers []string) ([]Quote, []string, bool) {
+	snapshots, fetchedAt, err := h.cache.Get(c.Request.Context(), tickers)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch quotes", "details": err.Error()})
+		return nil, nil, false
+	}
+
+	quotes := make([]Quote, 0, len(tickers))
+	missing := []string{}
+	for _, ticker := range tickers {
+		snapshot, ok := snapshots[ticker]
+		if !ok {
+			missing = append(missing, ticker)
+			continue
+		}
+		quotes = append(quotes, newQuote(snapshot, fetchedAt[ticker]))
+	}
+
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(h.cache.TTL().Seconds())))
+	return quotes, missing, true
+}
+
+func newQuote(s polygonmodels.TickerSnapshot, fetchedAt time.Time) Quote {
+	return Quote{
+		Ticker:        s.Ticker,
+		Price:         s.LastTrade.Price,
+		LastTradeSize: s.LastTrade.Size,
+		LastTradeAt:   time.Time(s.LastTrade.Timestamp).UTC(),
+		Bid:           s.LastQuote.BidPrice,
+		BidSize:       s.LastQuote.BidSize,
+		Ask:           s.LastQuote.AskPrice,
+		AskSize:       s.LastQuote.AskSize,
+		QuotedAt:      time.Time(s.LastQuote.Timestamp).UTC(),
+		DayOpen:       s.Day.Open,
+		DayHigh:       s.Day.High,
+		DayLow:        s.Day.Low,
+		DayVolume:     s.Day.Volume,
+		PrevClose:     s.PrevDay.Close,
+		Change:        s.TodaysChange,
+		ChangePct:     s.TodaysChangePerc,
+		FetchedAt:     fetchedAt.UTC(),
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReplaySpeed = 60
+	maxReplaySpeed     = 3600
+)
+
+// ReplayHandler replays trading sessions bar by bar
+type ReplayHandler struct {
+	db *gorm.DB
+}
+
+// NewReplayHandler creates a new replay handler
+func NewReplayHandler(db *gorm.DB) *ReplayHandler {
+	return &ReplayHandler{db: db}
+}
+
+// HandleReplay streams a session over Server-Sent Events: a "replay" event
+// describing the session, one "bar" event per bar with the signals emitted on
+// it and the running decision, then "end".
+// Query parameters:
+//   - date: Session date, YYYY-MM-DD (required)
+//   - timespan: Bar size unit, second, minute or hour (default: minute)
+//   - multiplier: Bar size multiplier (default: 5)
+//   - speed: Replay speed as a multiple of real time, or "max" to send every
+//     bar immediately (default: 60, max 3600)
+func (h *ReplayHandler) HandleReplay(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+
+	date, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date is required, use YYYY-MM-DD"})
+		return
+	}
+
+	timeSpan := c.DefaultQuery("timespan", "minute")
+	if timeSpan != "second" && timeSpan != "minute" && timeSpan != "hour" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timespan must be second, minute or hour"})
+		return
+	}
+	multiplier, err := strconv.Atoi(c.DefaultQuery("multiplier", "5"))
+	if err != nil || multiplier <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multiplier must be a positive integer"})
+		return
+	}
+
+	// delay is the pause between bars; zero replays as fast as the client reads
+	var delay time.Duration
+	if speed := c.DefaultQuery("speed", strconv.Itoa(defaultReplaySpeed)); speed != "max" {
+		n, err := strconv.ParseFloat(speed, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "speed must be between 0 and 3600, or max"})
+			return
+		}
+		if delay, err = replayDelay(n, timeSpan, multiplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	replay, err := deepsearch.BuildReplay(c.Request.Context(), h.db, ticker, date, timeSpan, multiplier)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to build replay", "details": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("replay", gin.H{
+		"ticker":     replay.Ticker,
+		"date":       replay.Date,
+		"timespan":   replay.TimeSpan,
+		"multiplier": replay.Multiplier,
+		"source":     replay.Source,
+		"currency":   replay.Currency,
+		"bars":       len(replay.Frames),
+	})
+	c.Writer.Flush()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for i, frame := range replay.Frames {
+		if i > 0 && delay > 0 {
+			timer.Reset(delay)
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-timer.C:
+			}
+		} else if c.Request.Context().Err() != nil {
+			return
+		}
+
+		c.SSEvent("bar", frame)
+		c.Writer.Flush()
+	}
+
+	c.SSEvent("end", gin.H{"bars": len(replay.Frames)})
+	c.Writer.Flush()
+}
+
+// HandleSimulation feeds the bars of several sessions through the signal
+// generator one at a time, as if they were arriving live, and returns every
+// frame at once. The WebSocket stream's replay action sends the same frames
+// paced like a live session.
+// Query parameters:
+//   - from, to: First and last session, YYYY-MM-DD (required, at most 31 days apart)
+//   - timespan: Bar size unit, second, minute or hour (default: minute)
+//   - multiplier: Bar size multiplier (default: 5)
+func (h *ReplayHandler) HandleSimulation(c *gin.Context) {
+	req := SimulationRequest{
+		Ticker:   c.Param("ticker"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		TimeSpan: c.Query("timespan"),
+	}
+	if val := c.Query("multiplier"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multiplier must be a positive integer"})
+			return
+		}
+		req.Multiplier = n
+	}
+
+	sim, err := h.Simulate(c.Request.Context(), req)
+	if err != nil {
+		writeOperationError(c, err)
+		return
+	}
+
+	frames := make([]deepsearch.ReplayFrame, 0, sim.Bars)
+	signals := 0
+	for frame, ok := sim.Next(); ok; frame, ok = sim.Next() {
+		frames = append(frames, frame)
+		signals += len(frame.Signals)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"simulation": sim,
+		"signals":    signals,
+		"frames":     frames,
+	})
+}
+
+// SimulationRequest is a window to simulate; empty fields take their defaults
+type SimulationRequest struct {
+	Ticker string `json:"ticker"`
+	// From and To are the first and last session, YYYY-MM-DD
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TimeSpan   string `json:"timespan"`
+	Multiplier int    `json:"multiplier"`
+}
+
+// Simulate validates req and loads its simulation
+func (h *ReplayHandler) Simulate(ctx context.Context, req SimulationRequest) (*deepsearch.Simulation, error) {
+	ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+	if ticker == "" {
+		return nil, badRequest("Ticker is required")
+	}
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return nil, badRequest("from is required, use YYYY-MM-DD")
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return nil, badRequest("to is required, use YYYY-MM-DD")
+	}
+	if err := deepsearch.ValidateSimulationWindow(from, to); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	timeSpan := req.TimeSpan
+	if timeSpan == "" {
+		timeSpan = "minute"
+	}
+	if timeSpan != "second" && timeSpan != "minute" && timeSpan != "hour" {
+		return nil, badRequest("timespan must be second, minute or hour")
+	}
+	multiplier := req.Multiplier
+	if multiplier == 0 {
+		multiplier = 5
+	}
+	if multiplier < 0 {
+		return nil, badRequest("multiplier must be a positive integer")
+	}
+
+	if err := validateTicker(h.db, ticker); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	sim, err := deepsearch.NewSimulation(ctx, h.db, ticker, from, to, timeSpan, multiplier)
+	if err != nil {
+		return nil, &RequestError{Status: http.StatusUnprocessableEntity, Message: "Failed to build simulation", Err: err}
+	}
+	return sim, nil
+}
+
+// replayDelay is the pause between bars replayed at speed times real time
+func replayDelay(speed float64, timeSpan string, multiplier int) (time.Duration, error) {
+	if speed <= 0 || speed > maxReplaySpeed {
+		return 0, fmt.Errorf("speed must be between 0 and %d, or max", maxReplaySpeed)
+	}
+	return time.Duration(float64(deepsearch.BarDuration(timeSpan, multiplier)) / speed), nil
+}
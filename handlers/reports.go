@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+	"institutionanalyser/reports"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportsHandler lists and generates scheduled report artifacts
+type ReportsHandler struct {
+	db        *gorm.DB
+	generator *reports.Generator
+	store     reports.Store
+}
+
+// NewReportsHandler creates a new reports handler; generator is nil when
+// reports are disabled, and store signs artifact download URLs
+func NewReportsHandler(db *gorm.DB, generator *reports.Generator, store reports.Store) *ReportsHandler {
+	return &ReportsHandler{db: db, generator: generator, store: store}
+}
+
+// HandleListReports lists generated report artifacts, newest first
+// Query parameters:
+//   - kind: daily_digest or weekly_earnings_preview (optional)
+//   - format: html, csv or pdf (optional)
+//   - limit/offset: Pagination (default 50, max 500)
+func (h *ReportsHandler) HandleListReports(c *gin.Context) {
+	query := h.db.Model(&models.ReportArtifact{})
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if format := c.Query("format"); format != "" {
+		query = query.Where("format = ?", format)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 50, 500)
+	var artifacts []models.ReportArtifact
+	if err := query.Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&artifacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.signArtifacts(artifacts)
+	c.JSON(http.StatusOK, gin.H{
+		"data": artifacts,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(artifacts),
+		},
+	})
+}
+
+// HandleGenerateReport generates a report now instead of waiting for its schedule
+// Query parameters:
+//   - date: Market date the report is for, YYYY-MM-DD (default: today; for the
+//     earnings preview, the first day of the week covered)
+func (h *ReportsHandler) HandleGenerateReport(c *gin.Context) {
+	if h.generator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Reports are not enabled (set REPORTS_ENABLED=true)"})
+		return
+	}
+
+	kind := c.Param("kind")
+	if kind != models.ReportDailyDigest && kind != models.ReportEarningsPreview {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be daily_digest or weekly_earnings_preview"})
+		return
+	}
+
+	date := time.Now().In(jobs.MarketTimezone)
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	artifacts, err := h.generator.Run(c.Request.Context(), kind, date)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to generate report", "details": err.Error()})
+		return
+	}
+
+	h.signArtifacts(artifacts)
+	c.JSON(http.StatusCreated, gin.H{"data": artifacts})
+}
+
+// signArtifacts sets a signed download URL on each artifact written to the
+// configured bucket. Artifacts in other storage, or in local storage, get none.
+func (h *ReportsHandler) signArtifacts(artifacts []models.ReportArtifact) {
+	if h.store == nil {
+		return
+	}
+	for i := range artifacts {
+		if artifacts[i].Storage != h.store.Backend() {
+			continue
+		}
+		url, expiresAt, err := h.store.SignedURL(artifacts[i].Key)
+		if err != nil || url == "" {
+			continue
+		}
+		artifacts[i].URL, artifacts[i].URLExpiresAt = url, &expiresAt
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestError is an operation failure the caller can act on, such as an
+// invalid parameter or an unavailable upstream, rather than an internal fault.
+// Status is the HTTP status it is reported with; the gRPC API maps it to a code.
+type RequestError struct {
+	Status  int
+	Message string
+	// Err is the underlying failure, reported as details
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// badRequest returns a 400 RequestError
+func badRequest(message string) *RequestError {
+	return &RequestError{Status: http.StatusBadRequest, Message: message}
+}
+
+// writeOperationError writes err as the response: a RequestError with its
+// status and message, anything else as a 500
+func writeOperationError(c *gin.Context, err error) {
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	body := gin.H{"error": requestErr.Message}
+	if requestErr.Err != nil {
+		body["details"] = requestErr.Err.Error()
+	}
+	c.JSON(requestErr.Status, body)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SandboxHandler re-evaluates stored analyses under other thresholds
+type SandboxHandler struct {
+	db *gorm.DB
+}
+
+// NewSandboxHandler creates a new sandbox handler
+func NewSandboxHandler(db *gorm.DB) *SandboxHandler {
+	return &SandboxHandler{db: db}
+}
+
+// SandboxRequest is the body of POST /api/v1/sandbox/evaluate
+type SandboxRequest struct {
+	AnalysisID uint                       `json:"analysis_id"`
+	Overrides  deepsearch.SignalOverrides `json:"overrides"`
+	Weights    map[string]float64         `json:"weights"`
+}
+
+// HandleEvaluate recomputes one of the caller's stored analyses' signals and decision from its
+// persisted bars with the given threshold overrides and direction weights.
+// Nothing is fetched from Polygon and nothing is stored.
+func (h *SandboxHandler) HandleEvaluate(c *gin.Context) {
+	var req SandboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.AnalysisID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "analysis_id is required"})
+		return
+	}
+
+	var analysis models.TechnicalSignal
+	if err := ownedBy(h.db, ownerID(c)).First(&analysis, req.AnalysisID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := deepsearch.EvaluateSandbox(c.Request.Context(), h.db, &analysis, req.Overrides, req.Weights)
+	if errors.Is(err, deepsearch.ErrBarsNotStored) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bars not stored", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to evaluate analysis", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"institutionanalyser/deepsearch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSectorConstituents caps how many constituents one sector analysis may run
+const maxSectorConstituents = 25
+
+// SectorRequest is the body of POST /api/v1/deepsearch/sector
+type SectorRequest struct {
+	ETF   string `json:"etf"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Constituents replaces the built-in holdings; required for ETFs without them
+	Constituents []string `json:"constituents"`
+	Preset       string   `json:"preset"`
+}
+
+// HandleListSectors returns the built-in sector ETFs and their constituents
+func (deepSearchHandler *DeepSearchHandler) HandleListSectors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"sectors": deepsearch.SectorETFs()})
+}
+
+// HandleAnalyseSector runs the pipeline over a sector ETF and its top
+// constituents for one window and returns a sector verdict with each
+// member's result. Results are computed on the fly and not stored.
+func (deepSearchHandler *DeepSearchHandler) HandleAnalyseSector(c *gin.Context) {
+	var req SectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	etf := strings.ToUpper(strings.TrimSpace(req.ETF))
+	if etf == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "etf is required"})
+		return
+	}
+	sector, known := deepsearch.LookupSectorETF(etf)
+	if !known {
+		sector = deepsearch.SectorETF{ETF: etf}
+	}
+
+	if len(req.Constituents) > 0 {
+		constituents := normalizeTickers(req.Constituents)
+		if len(constituents) > maxSectorConstituents {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("constituents cannot exceed %d tickers", maxSectorConstituents)})
+			return
+		}
+		sector.Constituents = constituents
+	}
+	if len(sector.Constituents) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not a built-in sector ETF, constituents are required", etf)})
+		return
+	}
+
+	window := deepsearch.ComparisonWindow{Label: etf, Start: req.Start, End: req.End}
+	if err := deepsearch.ValidateWindow(window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, ticker := range append([]string{etf}, sector.Constituents...) {
+		if err := validateTicker(deepSearchHandler.db, ticker); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	timeSpan, multiplier, ok := deepSearchHandler.aggregation(c, currentUserID(c), req.Preset)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, deepsearch.AnalyseSector(c.Request.Context(), sector, timeSpan, multiplier, window))
+}
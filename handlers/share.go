@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/middleware"
+	"institutionanalyser/models"
+	"institutionanalyser/reports"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultShareTTLHours = 7 * 24
+	maxShareTTLHours     = 30 * 24
+)
+
+// ShareHandler issues and serves expiring read-only links to single analyses
+// and reports, for viewers without an account
+type ShareHandler struct {
+	db *gorm.DB
+}
+
+// NewShareHandler creates a new share handler
+func NewShareHandler(db *gorm.DB) *ShareHandler {
+	return &ShareHandler{db: db}
+}
+
+// HandleShareAnalysis issues a share link to one of the caller's stored analyses
+// Query parameters:
+//   - expires_in_hours: How long the link works (default: 168, max 720)
+func (h *ShareHandler) HandleShareAnalysis(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis ID"})
+		return
+	}
+	var analysis models.TechnicalSignal
+	if err := ownedBy(h.db, ownerID(c)).Select("id").First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analysis", "details": err.Error()})
+		return
+	}
+	h.issue(c, middleware.ShareAnalysis, analysis.ID)
+}
+
+// HandleShareReport issues a share link to one generated report
+// Query parameters:
+//   - expires_in_hours: How long the link works (default: 168, max 720)
+func (h *ShareHandler) HandleShareReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+	var artifact models.ReportArtifact
+	if err := h.db.First(&artifact, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report", "details": err.Error()})
+		return
+	}
+	h.issue(c, middleware.ShareReport, artifact.ID)
+}
+
+// issue signs a link to the resource and responds with it
+func (h *ShareHandler) issue(c *gin.Context, resource string, id uint) {
+	hours, err := strconv.Atoi(c.DefaultQuery("expires_in_hours", strconv.Itoa(defaultShareTTLHours)))
+	if err != nil || hours <= 0 || hours > maxShareTTLHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_hours must be between 1 and 720"})
+		return
+	}
+
+	token, expiresAt, err := middleware.IssueShareToken(resource, id, time.Duration(hours)*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sharing is not configured", "details": err.Error()})
+		return
+	}
+
+	path := "/api/v1/shared/" + token
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        shareBaseURL(c) + path,
+		"path":       path,
+		"resource":   resource,
+		"id":         id,
+		"expires_at": expiresAt,
+	})
+}
+
+// shareBaseURL is PUBLIC_BASE_URL, or the scheme and host the request came in on
+func shareBaseURL(c *gin.Context) string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// HandleViewShared renders the analysis or report a share link points at.
+// It needs no credentials: the signed token is the only access granted, and
+// it exposes nothing beyond that one resource.
+// Query parameters:
+//   - format: html (default), csv or pdf
+func (h *ShareHandler) HandleViewShared(c *gin.Context) {
+	// Keep the token out of caches, search indexes and outbound referrers
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	c.Header("Referrer-Policy", "no-referrer")
+
+	format := c.DefaultQuery("format", reports.FormatHTML)
+	if format != reports.FormatHTML && format != reports.FormatCSV && format != reports.FormatPDF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html, csv or pdf"})
+		return
+	}
+
+	resource, id, err := middleware.ParseShareToken(c.Param("token"))
+	if errors.Is(err, middleware.ErrShareExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": "This share link has expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	var report *reports.Report
+	switch resource {
+	case middleware.ShareAnalysis:
+		var analysis models.TechnicalSignal
+		if err := h.db.First(&analysis, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The shared analysis no longer exists"})
+			return
+		}
+		report = reports.BuildAnalysis(&analysis)
+	case middleware.ShareReport:
+		var artifact models.ReportArtifact
+		if err := h.db.First(&artifact, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The shared report no longer exists"})
+			return
+		}
+		date, err := time.ParseInLocation("2006-01-02", artifact.PeriodStart, jobs.MarketTimezone)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report", "details": err.Error()})
+			return
+		}
+		if report, err = reports.Build(c.Request.Context(), h.db, artifact.Kind, date); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report", "details": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	body, contentType, err := reports.Render(report, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
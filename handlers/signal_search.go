@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleSearchSignals returns the caller's stored analyses matching a
+// structured filter, newest first, with structured signals
+// Body: deepsearch.SignalFilter, e.g. {"tickers": ["AAPL"], "signal_types": ["Bollinger Breakout"], "min_confidence": 0.6}
+// Query parameters:
+//   - limit: Maximum number of results (default: 100, max: 1000)
+//   - offset: Number of results to skip (default: 0)
+//   - archived: Search archived analyses instead (default: false)
+func (deepSearchHandler *DeepSearchHandler) HandleSearchSignals(c *gin.Context) {
+	var filter deepsearch.SignalFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if err := filter.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	query := filter.Apply(ownedBy(models.ReadReplica(deepSearchHandler.db).Model(&models.TechnicalSignal{}), ownerID(c)))
+	query = withArchived(query, c.Query("archived") == "true")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search analyses", "details": err.Error()})
+		return
+	}
+
+	var signals []models.TechnicalSignal
+	if err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&signals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search analyses", "details": err.Error()})
+		return
+	}
+
+	analyses := make([]AnalysisV2Response, 0, len(signals))
+	for _, s := range signals {
+		analyses = append(analyses, AnalysisV2Response{
+			TechnicalSignal:   s,
+			StructuredSignals: deepsearch.ParseSignals(s.Signals),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": analyses,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(analyses),
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"institutionanalyser/events"
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+)
+
+func TestSQLiteStoredEarnings(t *testing.T) {
+	db, err := models.InitDatabase("sqlite://" + t.TempDir() + "/handlers.db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	defer models.CloseDatabase(db)
+
+	for _, estimate := range []models.EarningsEstimate{
+		{Ticker: "MSFT", ReportDate: "2026-04-30", ProviderUpdated: "v1"},
+		{Ticker: "AAPL", ReportDate: "2026-04-30", ProviderUpdated: "v1"},
+		{Ticker: "AAPL", ReportDate: "2026-04-30", ProviderUpdated: "v2"},
+		{Ticker: "AAPL", ReportDate: "2026-05-01", ProviderUpdated: "v3"},
+	} {
+		if err := db.Create(&estimate).Error; err != nil {
+			t.Fatalf("seed estimate: %v", err)
+		}
+	}
+
+	stored, err := storedEarnings(context.Background(), db, "2026-04-30", "", nil, 10)
+	if err != nil {
+		t.Fatalf("storedEarnings: %v", err)
+	}
+	if len(stored) != 2 || stored[0].Ticker != "AAPL" || stored[0].Updated != "v2" || stored[1].Ticker != "MSFT" {
+		t.Fatalf("stored = %+v, want AAPL (v2) then MSFT", stored)
+	}
+}
+
+func TestSQLiteTriggerNormalizesTicker(t *testing.T) {
+	db, err := models.InitDatabase("sqlite://" + t.TempDir() + "/handlers.db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	defer models.CloseDatabase(db)
+	if err := db.Create(&models.Ticker{Ticker: "AAPL", Name: "Apple Inc.", Active: true}).Error; err != nil {
+		t.Fatalf("seed ticker: %v", err)
+	}
+
+	handler := NewDeepSearchHandler(db, jobs.NewAnalysisQueue(db, events.NewHub(), nil, nil, nil, nil), nil)
+	job, created, err := handler.Trigger(context.Background(), DefaultUserID, TriggerRequest{Ticker: " aapl ", StartDuration: "2026-03-02"})
+	if err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	if !created || job.Ticker != "AAPL" {
+		t.Fatalf("job = %+v, created = %v, want a new AAPL job", job, created)
+	}
+	var request models.DeepSearchRequest
+	if err := db.First(&request).Error; err != nil || request.Ticker != "AAPL" {
+		t.Fatalf("request = %+v (%v), want AAPL", request, err)
+	}
+}
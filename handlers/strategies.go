@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StrategiesHandler manages a user's signal strategies
+type StrategiesHandler struct {
+	db *gorm.DB
+}
+
+// NewStrategiesHandler creates a new strategies handler
+func NewStrategiesHandler(db *gorm.DB) *StrategiesHandler {
+	return &StrategiesHandler{db: db}
+}
+
+// HandleListStrategies returns the current user's strategies and the fields rules can use
+func (h *StrategiesHandler) HandleListStrategies(c *gin.Context) {
+	var strategies []models.Strategy
+	if err := h.db.Where("user_id = ?", currentUserID(c)).Order("name").Find(&strategies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"strategies": strategies, "fields": deepsearch.StrategyFields()})
+}
+
+// HandleGetStrategy returns one strategy by name
+// Query parameters:
+//   - format: json (default) or yaml
+func (h *StrategiesHandler) HandleGetStrategy(c *gin.Context) {
+	var strategy models.Strategy
+	err := h.db.Where("user_id = ? AND name = ?", currentUserID(c), c.Param("name")).First(&strategy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategy not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "yaml" {
+		c.YAML(http.StatusOK, strategy)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"strategy": strategy})
+}
+
+// HandleSaveStrategy creates a strategy, or replaces the existing one with the
+// same name. The body is JSON, or YAML with a YAML content type.
+func (h *StrategiesHandler) HandleSaveStrategy(c *gin.Context) {
+	var strategy models.Strategy
+	bind := binding.JSON
+	if strings.Contains(c.ContentType(), "yaml") {
+		bind = binding.YAML
+	}
+	if err := c.ShouldBindWith(&strategy, bind); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if err := deepsearch.NormalizeStrategy(&strategy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	strategy.ID = 0
+	strategy.UserId = currentUserID(c)
+	err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "include_builtin", "rules", "updated_at"}),
+	}).Create(&strategy).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"strategy": strategy})
+}
+
+// HandleDeleteStrategy removes a strategy by name. Queued analyses using it fail.
+func (h *StrategiesHandler) HandleDeleteStrategy(c *gin.Context) {
+	result := h.db.Where("user_id = ? AND name = ?", currentUserID(c), c.Param("name")).Delete(&models.Strategy{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strategy deleted"})
+}
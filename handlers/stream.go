@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"institutionanalyser/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 60 * time.Second
+)
+
+// StreamMessage is a request sent by a WebSocket client
+type StreamMessage struct {
+	Action  string   `json:"action"` // subscribe, unsubscribe, replay or stop_replay
+	Tickers []string `json:"tickers"`
+	// Replay is the window the replay action simulates
+	Replay *StreamReplay `json:"replay,omitempty"`
+}
+
+// StreamReplay is a simulation streamed over the WebSocket at a multiple of
+// real time
+type StreamReplay struct {
+	SimulationRequest
+	// Speed is the multiple of real time bars are sent at (default: 60, max 3600)
+	Speed float64 `json:"speed"`
+	// MaxSpeed sends every bar as soon as it is computed
+	MaxSpeed bool `json:"max_speed"`
+}
+
+// StreamHandler pushes new analyses and job progress to WebSocket clients,
+// and replays simulations to the client that asks for them
+type StreamHandler struct {
+	hub      *events.Hub
+	replays  *ReplayHandler
+	upgrader websocket.Upgrader
+}
+
+// NewStreamHandler creates a stream handler; WS_ALLOWED_ORIGINS is a comma-separated
+// list of browser origins allowed to connect (default http://localhost:3000)
+func NewStreamHandler(hub *events.Hub, db *gorm.DB) *StreamHandler {
+	allowed := map[string]bool{}
+	origins := os.Getenv("WS_ALLOWED_ORIGINS")
+	if origins == "" {
+		origins = "http://localhost:3000"
+	}
+	for _, o := range strings.Split(origins, ",") {
+		allowed[strings.TrimSpace(o)] = true
+	}
+
+	return &StreamHandler{
+		hub:     hub,
+		replays: NewReplayHandler(db),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || allowed[origin]
+			},
+		},
+	}
+}
+
+// HandleStream upgrades to a WebSocket. Clients send
+// {"action": "subscribe", "tickers": ["AAPL"]} ("*" for all tickers) and receive
+// {"type": "signal"|"job", "ticker": ..., "data": ...} events for their own analyses.
+// {"action": "replay", "replay": {"ticker": "SPY", "from": ..., "to": ..., "speed": 600}}
+// streams a simulation of that window (see ReplayHandler.HandleSimulation) as
+// "replay", "replay_bar" and "replay_end" events, paced at speed times real
+// time; a new replay or {"action": "stop_replay"} stops the current one.
+func (h *StreamHandler) HandleStream(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	sub := h.hub.Subscribe(currentUserID(c))
+	defer h.hub.Unsubscribe(sub)
+
+	if tickers := c.Query("tickers"); tickers != "" {
+		sub.Subscribe(strings.Split(tickers, ","))
+	}
+
+	// Replays run one at a time and stop with the connection
+	replayEvents := make(chan interface{})
+
+	// Reader: apply subscription changes until the client goes away. Replies go
+	// through the writer loop since a connection allows only one writer; done
+	// is closed when the writer loop returns, so the reader never blocks on a
+	// reply nobody will send.
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	replies := make(chan interface{}, 8)
+	reply := func(r interface{}) bool {
+		select {
+		case replies <- r:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	go func() {
+		defer close(closed)
+		stopReplay := context.CancelFunc(func() {})
+		defer func() { stopReplay() }()
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+
+		for {
+			var msg StreamMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Action {
+			case "subscribe":
+				sub.Subscribe(msg.Tickers)
+			case "unsubscribe":
+				sub.Unsubscribe(msg.Tickers)
+			case "replay":
+				if msg.Replay == nil {
+					if !reply(gin.H{"type": "error", "error": "replay is required"}) {
+						return
+					}
+					continue
+				}
+				stopReplay()
+				ctx, cancel := context.WithCancel(c.Request.Context())
+				stopReplay = cancel
+				go h.replay(ctx, *msg.Replay, replayEvents)
+				continue
+			case "stop_replay":
+				stopReplay()
+				if !reply(gin.H{"type": "replay_stopped"}) {
+					return
+				}
+				continue
+			default:
+				if !reply(gin.H{"type": "error", "error": "unknown action: " + msg.Action}) {
+					return
+				}
+				continue
+			}
+			if !reply(gin.H{"type": "subscriptions", "tickers": sub.Tickers()}) {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case reply := <-replies:
+			if h.write(conn, reply) != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok || h.write(conn, event) != nil {
+				return
+			}
+		case event := <-replayEvents:
+			if h.write(conn, event) != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replay loads a simulation and sends its events to out, pausing between bars
+// as the requested speed says, until it ends or ctx is cancelled
+func (h *StreamHandler) replay(ctx context.Context, req StreamReplay, out chan<- interface{}) {
+	send := func(event interface{}) bool {
+		select {
+		case out <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// delay is the pause between bars; zero sends them as fast as the client reads
+	var delay time.Duration
+	if !req.MaxSpeed {
+		speed := req.Speed
+		if speed == 0 {
+			speed = defaultReplaySpeed
+		}
+		timeSpan, multiplier := req.TimeSpan, req.Multiplier
+		if timeSpan == "" {
+			timeSpan = "minute"
+		}
+		if multiplier <= 0 {
+			multiplier = 5
+		}
+		var err error
+		if delay, err = replayDelay(speed, timeSpan, multiplier); err != nil {
+			send(gin.H{"type": "error", "error": err.Error()})
+			return
+		}
+	}
+
+	sim, err := h.replays.Simulate(ctx, req.SimulationRequest)
+	if err != nil {
+		if ctx.Err() == nil {
+			send(gin.H{"type": "error", "error": err.Error()})
+		}
+		return
+	}
+	if !send(events.Event{Type: events.TypeReplay, Ticker: sim.Ticker, Data: sim}) {
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	first := true
+	for frame, ok := sim.Next(); ok; frame, ok = sim.Next() {
+		if !first && delay > 0 {
+			timer.Reset(delay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		}
+		first = false
+		if !send(events.Event{Type: events.TypeReplayBar, Ticker: sim.Ticker, Data: frame}) {
+			return
+		}
+	}
+	send(events.Event{Type: events.TypeReplayEnd, Ticker: sim.Ticker, Data: gin.H{"bars": sim.Bars}})
+}
+
+func (h *StreamHandler) write(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
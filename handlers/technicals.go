@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"institutionanalyser/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// indicatorFetchTimeout bounds how long indicators keep loading after the response was sent
+	indicatorFetchTimeout = 30 * time.Second
+	// summaryRetention is how long a partial summary can be fetched again
+	summaryRetention = 10 * time.Minute
+)
+
+// TechnicalSummary is the structured daily indicator summary for a ticker
+type TechnicalSummary struct {
+	ID         string                       `json:"summary_id"`
+	Ticker     string                       `json:"ticker"`
+	CreatedAt  time.Time                    `json:"created_at"`
+	Complete   bool                         `json:"complete"`
+	Pending    int                          `json:"pending"`
+	Indicators []service.TechnicalIndicator `json:"indicators"`
+	// RemainderURL returns the summary with any since-completed indicators filled in
+	RemainderURL string `json:"remainder_url,omitempty"`
+}
+
+// summaryStore keeps recent summaries so indicators that missed the latency
+// budget can be collected later
+type summaryStore struct {
+	mu        sync.Mutex
+	summaries map[string]*TechnicalSummary
+}
+
+func (st *summaryStore) put(summary *TechnicalSummary) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for id, s := range st.summaries {
+		if time.Since(s.CreatedAt) > summaryRetention {
+			delete(st.summaries, id)
+		}
+	}
+	st.summaries[summary.ID] = summary
+}
+
+func (st *summaryStore) setIndicator(id string, i int, indicator service.TechnicalIndicator) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if s, ok := st.summaries[id]; ok {
+		s.Indicators[i] = indicator
+		s.Pending--
+		s.Complete = s.Pending == 0
+	}
+}
+
+// snapshot returns a copy of a summary that is safe to serialise
+func (st *summaryStore) snapshot(id string) (TechnicalSummary, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.summaries[id]
+	if !ok {
+		return TechnicalSummary{}, false
+	}
+	snap := *s
+	snap.Indicators = append([]service.TechnicalIndicator(nil), s.Indicators...)
+	if snap.Complete {
+		snap.RemainderURL = ""
+	}
+	return snap, true
+}
+
+// TechnicalsHandler serves daily technical indicator summaries
+type TechnicalsHandler struct {
+	summaries *summaryStore
+}
+
+// NewTechnicalsHandler creates a new technicals handler
+func NewTechnicalsHandler() *TechnicalsHandler {
+	return &TechnicalsHandler{summaries: &summaryStore{summaries: map[string]*TechnicalSummary{}}}
+}
+
+// HandleGetSummary returns the latest SMA/EMA/RSI/MACD values for a ticker
+// Query parameters:
+//   - ticker: Stock symbol (required)
+//   - max_latency_ms: Latency budget; indicators not returned in time are marked
+//     "pending" and can be collected from remainder_url (default: wait for all)
+func (h *TechnicalsHandler) HandleGetSummary(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+
+	budget := indicatorFetchTimeout
+	if val := c.Query("max_latency_ms"); val != "" {
+		ms, err := strconv.Atoi(val)
+		if err != nil || ms <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_latency_ms must be a positive integer"})
+			return
+		}
+		if d := time.Duration(ms) * time.Millisecond; d < budget {
+			budget = d
+		}
+	}
+
+	id, err := newSummaryID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	specs := service.SummaryIndicators
+	summary := &TechnicalSummary{
+		ID:           id,
+		Ticker:       ticker,
+		CreatedAt:    time.Now(),
+		Pending:      len(specs),
+		Indicators:   make([]service.TechnicalIndicator, len(specs)),
+		RemainderURL: fmt.Sprintf("/api/v1/technicals/summary/%s", id),
+	}
+	for i, spec := range specs {
+		summary.Indicators[i] = service.PendingIndicator(spec)
+	}
+	h.summaries.put(summary)
+
+	// Fetches are detached from the request so late indicators still land in
+	// the store for the remainder call
+	ctx, cancel := context.WithTimeout(context.Background(), indicatorFetchTimeout)
+	svc := service.NewStockTechnicalService(ticker)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec service.IndicatorSpec) {
+			defer wg.Done()
+			h.summaries.setIndicator(id, i, svc.FetchIndicator(ctx, spec))
+		}(i, spec)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		cancel()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(budget):
+	case <-c.Request.Context().Done():
+	}
+
+	snap, _ := h.summaries.snapshot(id)
+	c.JSON(http.StatusOK, snap)
+}
+
+// HandleGetSummaryRemainder returns a previously requested summary, including
+// indicators that completed after the original response
+func (h *TechnicalsHandler) HandleGetSummaryRemainder(c *gin.Context) {
+	snap, ok := h.summaries.snapshot(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Summary not found or expired"})
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+func newSummaryID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"institutionanalyser/jobs"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TickersHandler serves locally synced ticker reference data
+type TickersHandler struct {
+	db     *gorm.DB
+	syncer *jobs.TickerSyncer
+}
+
+// NewTickersHandler creates a new tickers handler
+func NewTickersHandler(db *gorm.DB, syncer *jobs.TickerSyncer) *TickersHandler {
+	return &TickersHandler{db: db, syncer: syncer}
+}
+
+// HandleListTickers screens the ticker table
+// Query parameters:
+//   - sector: Case-insensitive substring of the SIC sector description
+//   - exchange: Primary exchange MIC (e.g. XNAS, XNYS)
+//   - type: Security type (e.g. CS, ETF)
+//   - locale: Polygon locale (e.g. us, global)
+//   - currency: ISO code prices are normalized to (e.g. USD, GBP, CAD)
+//   - min_market_cap / max_market_cap: Market cap bounds, in currency (default: USD);
+//     only tickers in that currency are compared
+//   - search: Case-insensitive substring of ticker or name
+//   - active: true (default) or false
+//   - limit: Maximum number of results (default: 100, max: 1000)
+//   - offset: Pagination offset (default: 0)
+func (h *TickersHandler) HandleListTickers(c *gin.Context) {
+	query := h.db.Model(&models.Ticker{}).Where("active = ?", c.DefaultQuery("active", "true") != "false")
+
+	if sector := c.Query("sector"); sector != "" {
+		query = query.Where("sector "+models.ILike(h.db)+" ?", "%"+sector+"%")
+	}
+	if exchange := c.Query("exchange"); exchange != "" {
+		query = query.Where("primary_exchange = ?", strings.ToUpper(exchange))
+	}
+	if tickerType := c.Query("type"); tickerType != "" {
+		query = query.Where("type = ?", strings.ToUpper(tickerType))
+	}
+	if locale := c.Query("locale"); locale != "" {
+		query = query.Where("locale = ?", strings.ToLower(locale))
+	}
+	currency := strings.ToUpper(c.Query("currency"))
+	minCap, minErr := strconv.ParseFloat(c.Query("min_market_cap"), 64)
+	maxCap, maxErr := strconv.ParseFloat(c.Query("max_market_cap"), 64)
+	// Market caps are in the listing's currency, so bounds only apply within one
+	if currency == "" && (minErr == nil || maxErr == nil) {
+		currency = models.DefaultCurrency
+	}
+	if currency != "" {
+		// Tickers synced before currencies were normalized only have currency_name
+		query = query.Where("currency = ? OR (currency = '' AND UPPER(currency_name) = ?)", currency, currency)
+	}
+	if minErr == nil {
+		query = query.Where("market_cap >= ?", minCap)
+	}
+	if maxErr == nil {
+		query = query.Where("market_cap <= ?", maxCap)
+	}
+	if search := c.Query("search"); search != "" {
+		ilike := models.ILike(h.db)
+		query = query.Where("ticker "+ilike+" ? OR name "+ilike+" ?", search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := parsePagination(c, 100, 1000)
+	var tickers []models.Ticker
+	if err := query.Order("market_cap desc, ticker").Limit(limit).Offset(offset).Find(&tickers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tickers,
+		"pagination": gin.H{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(tickers),
+		},
+	})
+}
+
+// HandleGetTicker returns reference data for one ticker
+func (h *TickersHandler) HandleGetTicker(c *gin.Context) {
+	var ticker models.Ticker
+	err := h.db.Where("ticker = ?", strings.ToUpper(c.Param("ticker"))).First(&ticker).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticker not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": ticker})
+}
+
+// HandleSyncTickers starts a reference data sync in the background; only one
+// runs at a time
+func (h *TickersHandler) HandleSyncTickers(c *gin.Context) {
+	detailsBatch, err := strconv.Atoi(c.DefaultQuery("details_batch", "200"))
+	if err != nil || detailsBatch < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "details_batch must be a non-negative integer"})
+		return
+	}
+
+	if h.syncer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Ticker sync is not available"})
+		return
+	}
+	if !h.syncer.Sync(detailsBatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A ticker sync is already running"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Ticker sync started"})
+}
+
+// validateTicker rejects tickers that are unknown or inactive in the synced
+// reference table. Before the first sync the table is empty and every ticker passes.
+func validateTicker(db *gorm.DB, symbol string) error {
+	var ticker models.Ticker
+	err := db.Where("ticker = ?", symbol).Limit(1).Find(&ticker).Error
+	if err != nil {
+		return nil
+	}
+	if ticker.ID != 0 {
+		if !ticker.Active {
+			return fmt.Errorf("ticker %s is no longer active", symbol)
+		}
+		return nil
+	}
+
+	var synced bool
+	db.Raw("SELECT EXISTS (SELECT 1 FROM tickers)").Scan(&synced)
+	if synced {
+		return fmt.Errorf("unknown ticker %s", symbol)
+	}
+	return nil
+}
+
+// parsePagination reads limit/offset query parameters with a default and maximum limit
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (int, int) {
+	limit := defaultLimit
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = parsed
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+	}
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed > 0 {
+		offset = parsed
+	}
+	return limit, offset
+}
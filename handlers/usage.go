@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/usage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// usageGroupColumns are the dimensions Polygon usage can be grouped by
+var usageGroupColumns = map[string]string{
+	"day":      "day",
+	"feature":  "feature",
+	"user":     "user_id",
+	"endpoint": "endpoint",
+	"tier":     "tier",
+}
+
+// UsageHandler shows operators how Polygon calls are spent
+type UsageHandler struct {
+	db *gorm.DB
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(db *gorm.DB) *UsageHandler {
+	return &UsageHandler{db: db}
+}
+
+// HandleGetPolygonUsage returns Polygon calls summed over a date range, most
+// calls first. Calls counted but not yet flushed are written first, so the
+// figures are current.
+// Query parameters:
+//   - from, to: UTC days, YYYY-MM-DD (default: the last 7 days including today)
+//   - group_by: Comma-separated dimensions of day, feature, user, endpoint and tier (default: feature)
+//   - feature, user_id: Only this feature or user (optional)
+func (h *UsageHandler) HandleGetPolygonUsage(c *gin.Context) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -6), today
+	for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if val := c.Query(param); val != "" {
+			day, err := time.Parse("2006-01-02", val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format, use YYYY-MM-DD", param)})
+				return
+			}
+			*dst = day
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to cannot be before from"})
+		return
+	}
+
+	groupBy := normalizeGroupBy(c.DefaultQuery("group_by", "feature"))
+	columns := make([]string, 0, len(groupBy))
+	for _, dim := range groupBy {
+		column, ok := usageGroupColumns[dim]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid group_by %q (day, feature, user, endpoint or tier)", dim)})
+			return
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by is required"})
+		return
+	}
+
+	if err := usage.Flush(c.Request.Context(), h.db); err != nil {
+		fmt.Printf("[API] failed to flush Polygon usage: %v\n", err)
+	}
+
+	query := h.db.Model(&models.PolygonUsage{}).Where("day BETWEEN ? AND ?", from, to)
+	if feature := c.Query("feature"); feature != "" {
+		query = query.Where("feature = ?", feature)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// Both aggregates below start from the same filters
+	query = query.Session(&gorm.Session{})
+
+	var rows []map[string]interface{}
+	err := query.
+		Select(strings.Join(columns, ", ") + ", SUM(calls) AS calls").
+		Group(strings.Join(columns, ", ")).
+		Order("calls DESC").
+		Find(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load Polygon usage", "details": err.Error()})
+		return
+	}
+
+	var tiers []struct {
+		Tier  string
+		Calls int64
+	}
+	if err := query.Select("tier, SUM(calls) AS calls").Group("tier").Scan(&tiers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load Polygon usage", "details": err.Error()})
+		return
+	}
+	byTier := gin.H{usage.TierLow: int64(0), usage.TierMedium: int64(0), usage.TierHigh: int64(0)}
+	var total int64
+	for _, t := range tiers {
+		byTier[t.Tier] = t.Calls
+		total += t.Calls
+	}
+
+	for _, row := range rows {
+		if day, ok := row["day"].(time.Time); ok {
+			row["day"] = day.Format("2006-01-02")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format("2006-01-02"),
+		"to":          to.Format("2006-01-02"),
+		"group_by":    groupBy,
+		"total_calls": total,
+		"by_tier":     byTier,
+		"data":        rows,
+	})
+}
+
+// normalizeGroupBy splits, trims and de-duplicates group_by dimensions
+func normalizeGroupBy(raw string) []string {
+	seen := map[string]bool{}
+	var dims []string
+	for _, dim := range strings.Split(raw, ",") {
+		dim = strings.ToLower(strings.TrimSpace(dim))
+		if dim == "" || seen[dim] {
+			continue
+		}
+		seen[dim] = true
+		dims = append(dims, dim)
+	}
+	return dims
+}
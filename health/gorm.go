@@ -0,0 +1,64 @@
+package health
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GormPlugin records the database as reachable or not after every GORM
+// statement. Only connection failures count against it; query errors such as
+// constraint violations mean the database answered.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "health"
+}
+
+// Initialize registers a health callback after each GORM processor
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	register := []error{
+		cb.Create().After("gorm:create").Register("health:after_create", recordStatement),
+		cb.Query().After("gorm:query").Register("health:after_query", recordStatement),
+		cb.Update().After("gorm:update").Register("health:after_update", recordStatement),
+		cb.Delete().After("gorm:delete").Register("health:after_delete", recordStatement),
+		cb.Row().After("gorm:row").Register("health:after_row", recordStatement),
+		cb.Raw().After("gorm:raw").Register("health:after_raw", recordStatement),
+	}
+	return errors.Join(register...)
+}
+
+func recordStatement(db *gorm.DB) {
+	if ConnectionError(db.Error) {
+		RecordFailure(Database, db.Error)
+		return
+	}
+	RecordSuccess(Database)
+}
+
+// ConnectionError reports whether err means the database could not be
+// reached: a broken or refused connection, or a Postgres connection
+// exception, shutdown or resource error
+func ConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		code := pgErr.SQLState()
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53") || strings.HasPrefix(code, "57P")
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package health
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dependency is an external service the API relies on
+type Dependency string
+
+const (
+	Polygon  Dependency = "polygon"
+	Database Dependency = "database"
+)
+
+// Header marks degraded responses with the dependencies they did without
+const Header = "X-Degraded"
+
+// Config decides when a dependency counts as down
+type Config struct {
+	// FailureThreshold is how many failures in a row mark a dependency down
+	FailureThreshold int
+	// RetryAfter is how long a down dependency is skipped before callers try it again
+	RetryAfter time.Duration
+}
+
+// GetConfig reads health settings from environment variables with sensible
+// defaults if not provided
+func GetConfig() Config {
+	config := Config{
+		FailureThreshold: 3,
+		RetryAfter:       30 * time.Second,
+	}
+
+	if val := os.Getenv("HEALTH_FAILURE_THRESHOLD"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.FailureThreshold = n
+		}
+	}
+
+	if val := os.Getenv("HEALTH_RETRY_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.RetryAfter = time.Duration(n) * time.Second
+		}
+	}
+
+	return config
+}
+
+// Status is the observed health of one dependency
+type Status struct {
+	Dependency          Dependency `json:"dependency"`
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+type tracker struct {
+	failures    int
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+}
+
+var (
+	mu       sync.Mutex
+	config   = Config{FailureThreshold: 3, RetryAfter: 30 * time.Second}
+	trackers = map[Dependency]*tracker{Polygon: {}, Database: {}}
+)
+
+// Configure sets when dependencies count as down
+func Configure(cfg Config) {
+	mu.Lock()
+	config = cfg
+	mu.Unlock()
+}
+
+// RecordSuccess notes a call that reached dep; one success marks it healthy again
+func RecordSuccess(dep Dependency) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := trackerFor(dep)
+	t.failures = 0
+	t.lastSuccess = time.Now()
+}
+
+// RecordFailure notes a call that could not reach dep
+func RecordFailure(dep Dependency, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := trackerFor(dep)
+	t.failures++
+	t.lastFailure = time.Now()
+	if err != nil {
+		t.lastError = err.Error()
+	}
+}
+
+// Degraded reports whether dep has failed FailureThreshold times in a row
+// within the last RetryAfter. Callers skip optional work against a degraded
+// dependency and serve stored data instead; once RetryAfter passes they try
+// it again.
+func Degraded(dep Dependency) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := trackerFor(dep)
+	return t.failures >= config.FailureThreshold && time.Since(t.lastFailure) < config.RetryAfter
+}
+
+// Statuses returns the health of every dependency
+func Statuses() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+
+	statuses := make([]Status, 0, len(trackers))
+	for dep, t := range trackers {
+		status := Status{
+			Dependency:          dep,
+			Healthy:             t.failures < config.FailureThreshold,
+			ConsecutiveFailures: t.failures,
+			LastError:           t.lastError,
+		}
+		if !t.lastFailure.IsZero() {
+			at := t.lastFailure
+			status.LastFailure = &at
+		}
+		if !t.lastSuccess.IsZero() {
+			at := t.lastSuccess
+			status.LastSuccess = &at
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Dependency < statuses[j].Dependency })
+	return statuses
+}
+
+func trackerFor(dep Dependency) *tracker {
+	t, ok := trackers[dep]
+	if !ok {
+		t = &tracker{}
+		trackers[dep] = t
+	}
+	return t
+}
+
+// Degradation records what a response did without because a dependency was
+// unavailable. Responses embed it, so degraded ones say so explicitly.
+type Degradation struct {
+	Degraded bool     `json:"degraded"`
+	Reasons  []string `json:"degraded_reasons,omitempty"`
+
+	dependencies []Dependency
+}
+
+// Add notes that the response skipped or replaced something dep provides
+func (d *Degradation) Add(dep Dependency, reason string) {
+	d.Degraded = true
+	d.Reasons = append(d.Reasons, string(dep)+": "+reason)
+	for _, seen := range d.dependencies {
+		if seen == dep {
+			return
+		}
+	}
+	d.dependencies = append(d.dependencies, dep)
+}
+
+// HeaderValue lists the unavailable dependencies for the Header response header
+func (d *Degradation) HeaderValue() string {
+	names := make([]string, 0, len(d.dependencies))
+	for _, dep := range d.dependencies {
+		names = append(names, string(dep))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"time"
+)
+
+// ReadReplica is the optional database replica serving dashboard reads.
+// Statements sent to it are tracked like the primary's; while it is degraded,
+// its reads go to the primary.
+const ReadReplica Dependency = "read_replica"
+
+// Check is the result of actively probing a dependency
+type Check struct {
+	Dependency Dependency             `json:"dependency"`
+	Healthy    bool                   `json:"healthy"`
+	LatencyMs  int64                  `json:"latency_ms"`
+	Error      string                 `json:"error,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Probe runs probe against dep and times it. The probe returns details worth
+// reporting, such as a response status, along with any error.
+func Probe(dep Dependency, probe func() (map[string]interface{}, error)) Check {
+	start := time.Now()
+	details, err := probe()
+	check := Check{
+		Dependency: dep,
+		Healthy:    err == nil,
+		LatencyMs:  time.Since(start).Milliseconds(),
+		Details:    details,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"sort"
+	"sync"
+)
+
+// Warmup steps an instance completes at startup before it reports ready
+const (
+	// WarmupMigrations is the database schema being migrated
+	WarmupMigrations = "migrations"
+	// WarmupAPI is the API's routes, queue and background jobs being set up
+	WarmupAPI = "api"
+	// WarmupCaches is the process-wide caches, such as the trading calendar,
+	// being primed
+	WarmupCaches = "caches"
+)
+
+var (
+	warmupMu      sync.Mutex
+	warmupPending = map[string]bool{WarmupMigrations: true, WarmupAPI: true, WarmupCaches: true}
+)
+
+// WarmedUp marks a warmup step complete
+func WarmedUp(step string) {
+	warmupMu.Lock()
+	delete(warmupPending, step)
+	warmupMu.Unlock()
+}
+
+// WarmupPending returns the warmup steps not yet complete; the instance is
+// not ready until it is empty
+func WarmupPending() []string {
+	warmupMu.Lock()
+	defer warmupMu.Unlock()
+
+	steps := make([]string, 0, len(warmupPending))
+	for step := range warmupPending {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	return steps
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultBarRetentionDays is how long intraday bars are kept; daily and
+// coarser bars are kept indefinitely
+const defaultBarRetentionDays = 365
+
+// CompactionResult counts the bars a compaction removed
+type CompactionResult struct {
+	// Merged are coarse intraday bars dropped because the 1-multiplier bars
+	// they roll up from are stored for the same span
+	Merged int64 `json:"merged"`
+	// Expired are intraday bars older than the retention window
+	Expired int64 `json:"expired"`
+}
+
+// BarRetention returns how long intraday bars are kept; BAR_RETENTION_DAYS
+// overrides the default and 0 keeps them indefinitely
+func BarRetention() time.Duration {
+	days := defaultBarRetentionDays
+	if val := os.Getenv("BAR_RETENTION_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CompactBars keeps the bar store bounded. Analyses and imports of the same
+// ticker at different aggregations overlap; a second, minute or hour bar with
+// a multiplier above 1 is deleted when the same session's 1-multiplier bars
+// cover its whole span, since models.FindBars rolls it up from them on read.
+// Intraday bars older than retention are then deleted (0 keeps them).
+func CompactBars(ctx context.Context, db *gorm.DB, retention time.Duration) (CompactionResult, error) {
+	var result CompactionResult
+	db = db.WithContext(ctx)
+
+	// Matching bars to market sessions needs Postgres's time zone conversion,
+	// so SQLite databases only expire old bars
+	if models.IsSQLite(db) {
+		return result, expireBars(db, retention, &result)
+	}
+
+	merged := db.Exec(`
+		DELETE FROM bars coarse
+		USING (
+			SELECT ticker, time_span, (timestamp AT TIME ZONE ?)::date AS session,
+				MIN(timestamp) AS first_bar, MAX(timestamp) AS last_bar
+			FROM bars
+			WHERE multiplier = 1 AND time_span IN ('second', 'minute', 'hour')
+			GROUP BY 1, 2, 3
+		) base
+		WHERE coarse.multiplier > 1
+			AND coarse.ticker = base.ticker
+			AND coarse.time_span = base.time_span
+			AND (coarse.timestamp AT TIME ZONE ?)::date = base.session
+			AND coarse.timestamp >= base.first_bar
+			AND coarse.timestamp + (coarse.multiplier - 1) * CASE coarse.time_span
+				WHEN 'second' THEN INTERVAL '1 second'
+				WHEN 'minute' THEN INTERVAL '1 minute'
+				ELSE INTERVAL '1 hour' END <= base.last_bar`,
+		MarketTimezone.String(), MarketTimezone.String())
+	if merged.Error != nil {
+		return result, fmt.Errorf("failed to merge overlapping bars: %w", merged.Error)
+	}
+	result.Merged = merged.RowsAffected
+
+	return result, expireBars(db, retention, &result)
+}
+
+// expireBars deletes intraday bars older than retention (0 keeps them)
+func expireBars(db *gorm.DB, retention time.Duration, result *CompactionResult) error {
+	if retention <= 0 {
+		return nil
+	}
+	expired := db.Exec(`DELETE FROM bars WHERE time_span IN ('second', 'minute', 'hour') AND timestamp < ?`,
+		time.Now().Add(-retention))
+	if expired.Error != nil {
+		return fmt.Errorf("failed to expire bars: %w", expired.Error)
+	}
+	result.Expired = expired.RowsAffected
+	return nil
+}
+
+// BarCompactionTask compacts the bar store; BAR_RETENTION_DAYS sets the retention
+func BarCompactionTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		result, err := CompactBars(ctx, db, BarRetention())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[jobs] bar compaction: merged %d, expired %d bars\n", result.Merged, result.Expired)
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// RefreshBlockTrades detects and stores a ticker's block trades for the
+// session on date, verified against its timeSpan/multiplier bars
+func RefreshBlockTrades(ctx context.Context, db *gorm.DB, ticker string, date time.Time, minNotional float64, timeSpan string, multiplier int) (*models.BlockTradeSession, []models.BlockTrade, error) {
+	session, blocks, err := deepsearch.DetectBlockTrades(ctx, db, ticker, date, minNotional, timeSpan, multiplier)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := models.StoreBlockTradeSession(db.WithContext(ctx), session, blocks); err != nil {
+		return nil, nil, fmt.Errorf("failed to store block trades: %w", err)
+	}
+	return session, blocks, nil
+}
+
+// BlockTradeTask stores the day's block trades of the BLOCK_TRADE_TICKERS
+// after the close. Each ticker lists its whole tape, so the job is off until
+// tickers are configured.
+func BlockTradeTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		now := time.Now().In(MarketTimezone)
+		tickers := tickerList("BLOCK_TRADE_TICKERS", "")
+		if len(tickers) == 0 || !service.DefaultTradingCalendar().IsTradingDay(ctx, now) {
+			return nil
+		}
+
+		minNotional := deepsearch.BlockTradeMinNotional()
+		stored := 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, _, err := RefreshBlockTrades(ctx, db, ticker, now, minNotional, "minute", 1); err != nil {
+				fmt.Printf("[jobs] block trades: %s: %v\n", ticker, err)
+				continue
+			}
+			stored++
+		}
+		fmt.Printf("[jobs] block trades: stored %d/%d tickers for %s\n", stored, len(tickers), now.Format("2006-01-02"))
+		return nil
+	}
+}
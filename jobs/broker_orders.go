@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/broker"
+)
+
+// defaultBrokerSyncMinutes is how often open broker orders are synced
+const defaultBrokerSyncMinutes = 1
+
+// BrokerSyncInterval returns how often open broker orders are synced;
+// BROKER_ORDER_SYNC_MINUTES overrides the default and 0 disables syncing
+func BrokerSyncInterval() time.Duration {
+	if val := os.Getenv("BROKER_ORDER_SYNC_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return defaultBrokerSyncMinutes * time.Minute
+}
+
+// BrokerSyncTask records the status and fills of orders still open at their
+// brokers
+func BrokerSyncTask(router *broker.Router) Task {
+	return func(ctx context.Context) error {
+		synced, err := router.SyncOrders(ctx)
+		if err != nil {
+			return err
+		}
+		if synced > 0 {
+			fmt.Printf("[jobs] broker orders: synced %d\n", synced)
+		}
+		return nil
+	}
+}
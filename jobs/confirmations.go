@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// RefreshConfirmedMoves confirms and stores a ticker's institutional moves
+// for the session on date, replacing those stored for it
+func RefreshConfirmedMoves(ctx context.Context, db *gorm.DB, ticker string, date time.Time, config deepsearch.ConfirmationConfig) (*deepsearch.Confirmation, error) {
+	confirmation, err := deepsearch.ConfirmInstitutionalMoves(ctx, db, ticker, date, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.StoreConfirmedMoves(db.WithContext(ctx), ticker, confirmation.Day, confirmation.Moves); err != nil {
+		return nil, fmt.Errorf("failed to store confirmed moves: %w", err)
+	}
+	return confirmation, nil
+}
+
+// ConfirmationTask stores the day's confirmed institutional moves of the
+// CONFIRMATION_TICKERS after the close. Each ticker lists its stock and
+// option tapes, so the job is off until tickers are configured.
+func ConfirmationTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		now := time.Now().In(MarketTimezone)
+		tickers := tickerList("CONFIRMATION_TICKERS", "")
+		if len(tickers) == 0 || !service.DefaultTradingCalendar().IsTradingDay(ctx, now) {
+			return nil
+		}
+
+		config := deepsearch.DefaultConfirmationConfig()
+		if err := config.Normalize(); err != nil {
+			return fmt.Errorf("invalid confirmation settings: %w", err)
+		}
+		stored, moves := 0, 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			confirmation, err := RefreshConfirmedMoves(ctx, db, ticker, now, config)
+			if err != nil {
+				fmt.Printf("[jobs] confirmations: %s: %v\n", ticker, err)
+				continue
+			}
+			stored++
+			moves += len(confirmation.Moves)
+		}
+		fmt.Printf("[jobs] confirmations: stored %d moves of %d/%d tickers for %s\n", moves, stored, len(tickers), now.Format("2006-01-02"))
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordEarningsEstimates stores a snapshot of each announcement's estimates.
+// Versions already seen (same ticker, report date and provider updated time)
+// are skipped, so calling this on every fetch only records actual revisions.
+func RecordEarningsEstimates(db *gorm.DB, earnings []service.EarningsResult) error {
+	if len(earnings) == 0 {
+		return nil
+	}
+
+	snapshots := make([]models.EarningsEstimate, 0, len(earnings))
+	for _, e := range earnings {
+		if e.Ticker == "" || e.Date == "" {
+			continue
+		}
+		snapshots = append(snapshots, models.EarningsEstimate{
+			Ticker:           e.Ticker,
+			ReportDate:       e.Date,
+			ProviderUpdated:  e.Updated,
+			ReportTime:       e.Time,
+			Importance:       e.Importance,
+			EstimatedEPS:     e.EstimatedEPS,
+			EstimatedRevenue: e.EstimatedRevenue,
+			ActualEPS:        e.ActualEPS,
+			ActualRevenue:    e.ActualRevenue,
+		})
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&snapshots, 500).Error
+}
+
+// SnapshotUpcomingEarnings records the estimates of every announcement in the next days calendar days
+func SnapshotUpcomingEarnings(ctx context.Context, db *gorm.DB, days int) (int, error) {
+	svc := service.NewEarningsService()
+	if !svc.Configured() {
+		return 0, fmt.Errorf("POLYGON_API_KEY is not configured")
+	}
+
+	seen := 0
+	today := time.Now().In(MarketTimezone)
+	for i := 0; i <= days; i++ {
+		if ctx.Err() != nil {
+			return seen, ctx.Err()
+		}
+
+		date := today.AddDate(0, 0, i).Format("2006-01-02")
+		earnings, err := svc.FetchEarnings(ctx, date, "", nil, 50000)
+		if err != nil {
+			return seen, fmt.Errorf("failed to fetch earnings for %s: %w", date, err)
+		}
+		if err := RecordEarningsEstimates(db, earnings); err != nil {
+			return seen, err
+		}
+		seen += len(earnings)
+	}
+
+	return seen, nil
+}
+
+// EarningsEstimateTask snapshots upcoming earnings estimates; EARNINGS_ESTIMATE_LOOKAHEAD_DAYS sets the window
+func EarningsEstimateTask(db *gorm.DB) Task {
+	days := 14
+	if val := os.Getenv("EARNINGS_ESTIMATE_LOOKAHEAD_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			days = n
+		}
+	}
+
+	return func(ctx context.Context) error {
+		seen, err := SnapshotUpcomingEarnings(ctx, db, days)
+		fmt.Printf("[jobs] earnings estimate snapshot: %d announcements over %d days\n", seen, days+1)
+		return err
+	}
+}
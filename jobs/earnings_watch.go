@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultEarningsWatchMinutes is how often reporters' flow is sampled
+	defaultEarningsWatchMinutes = 30
+	// defaultEarningsWatchTickers caps the reporters sampled, most important
+	// first, since each sample lists the ticker's whole tape so far
+	defaultEarningsWatchTickers = 25
+	// earningsWatchThreshold is the large trade multiple of the average trade
+	// size, the big money endpoint's default
+	earningsWatchThreshold = 10.0
+	// earningsWatchConcurrency bounds the tickers sampled at once
+	earningsWatchConcurrency = 5
+)
+
+// EarningsWatchInterval returns how often the day's reporters are sampled,
+// 0 when EARNINGS_WATCH_MINUTES disables the watch
+func EarningsWatchInterval() time.Duration {
+	if val := os.Getenv("EARNINGS_WATCH_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return defaultEarningsWatchMinutes * time.Minute
+}
+
+// earningsWatchTickers returns the reporter cap; EARNINGS_WATCH_MAX_TICKERS overrides the default
+func earningsWatchTickers() int {
+	if val := os.Getenv("EARNINGS_WATCH_MAX_TICKERS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultEarningsWatchTickers
+}
+
+// watchedReporters returns the latest estimate of each ticker reporting on
+// day, most important first. Reporters come from the stored estimate
+// snapshots; when none are stored for the day the calendar is fetched and recorded.
+func watchedReporters(ctx context.Context, db *gorm.DB, day string, limit int) ([]models.EarningsEstimate, error) {
+	load := func() ([]models.EarningsEstimate, error) {
+		var estimates []models.EarningsEstimate
+		query := db.WithContext(ctx).Model(&models.EarningsEstimate{}).Where("report_date = ?", day)
+		err := models.LatestPer(query, "ticker", "created_at DESC, id DESC").Order("ticker").Find(&estimates).Error
+		return estimates, err
+	}
+
+	estimates, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if len(estimates) == 0 {
+		earnings, err := service.NewEarningsService().FetchEarnings(ctx, day, "", nil, 50000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch earnings for %s: %w", day, err)
+		}
+		if err := RecordEarningsEstimates(db.WithContext(ctx), earnings); err != nil {
+			return nil, err
+		}
+		if estimates, err = load(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(estimates, func(i, j int) bool { return estimates[i].Importance > estimates[j].Importance })
+	if len(estimates) > limit {
+		estimates = estimates[:limit]
+	}
+	return estimates, nil
+}
+
+// SampleBigMoneyFlow records the large-trade flow of the day's reporters from
+// the open up to at, returning how many were stored. Samples after the close
+// are stored at the close.
+func SampleBigMoneyFlow(ctx context.Context, db *gorm.DB, at time.Time) (int, error) {
+	at = at.In(MarketTimezone)
+	day := at.Format("2006-01-02")
+	reporters, err := watchedReporters(ctx, db, day, earningsWatchTickers())
+	if err != nil {
+		return 0, err
+	}
+
+	closeAt := time.Date(at.Year(), at.Month(), at.Day(), 16, 0, 0, 0, MarketTimezone)
+	stamp := at.Truncate(time.Minute)
+	if stamp.After(closeAt) {
+		stamp = closeAt
+	}
+
+	flow := service.NewTradeFlowService()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []models.BigMoneySample
+	)
+	semaphore := make(chan struct{}, earningsWatchConcurrency)
+	for _, reporter := range reporters {
+		wg.Add(1)
+		go func(reporter models.EarningsEstimate) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			result, err := flow.AnalyzeSession(ctx, reporter.Ticker, at, earningsWatchThreshold)
+			if err != nil {
+				fmt.Printf("[jobs] earnings watch: %s: %v\n", reporter.Ticker, err)
+				return
+			}
+			mu.Lock()
+			samples = append(samples, models.BigMoneySample{
+				Ticker:                reporter.Ticker,
+				ReportDate:            day,
+				Timestamp:             stamp,
+				ReportTime:            reporter.ReportTime,
+				Direction:             result.Direction,
+				NetBigMoneyFlow:       result.NetBigMoneyFlow,
+				BuyerInitiatedVolume:  result.BuyerInitiatedVolume,
+				SellerInitiatedVolume: result.SellerInitiatedVolume,
+				LargeTradesCount:      result.LargeTradesCount,
+				TotalTrades:           result.TotalTrades,
+			})
+			mu.Unlock()
+		}(reporter)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	if err := models.UpsertBigMoneySamples(db.WithContext(ctx), samples); err != nil {
+		return 0, fmt.Errorf("failed to store big money samples: %w", err)
+	}
+	fmt.Printf("[jobs] earnings watch: sampled %d/%d reporters for %s\n", len(samples), len(reporters), day)
+	return len(samples), nil
+}
+
+// EarningsWatchTask samples the big-money flow of the day's reporters on
+// trading days, skipping runs while Polygon is degraded
+func EarningsWatchTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		if !service.DefaultTradingCalendar().IsTradingDay(ctx, now) || health.Degraded(health.Polygon) {
+			return nil
+		}
+		_, err := SampleBigMoneyFlow(ctx, db, now)
+		return err
+	}
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultFootprintTickers caps how many tickers the weekly footprint job
+// covers, since each session of each ticker lists all of its trades
+const defaultFootprintTickers = 50
+
+// footprintTickers returns the job's ticker cap; FOOTPRINT_MAX_TICKERS
+// overrides the default and 0 disables the job
+func footprintTickers() int {
+	if val := os.Getenv("FOOTPRINT_MAX_TICKERS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultFootprintTickers
+}
+
+// RefreshFootprint builds and stores a ticker's footprint for the week containing week
+func RefreshFootprint(ctx context.Context, db *gorm.DB, ticker string, week time.Time) (*models.InstitutionalFootprint, error) {
+	footprint, err := deepsearch.BuildFootprint(ctx, db, ticker, week)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.UpsertFootprint(db.WithContext(ctx), footprint); err != nil {
+		return nil, fmt.Errorf("failed to store footprint: %w", err)
+	}
+	return footprint, nil
+}
+
+// FootprintTask stores the week's institutional footprint of the most analysed
+// tickers once the week is over. The scheduler only runs daily jobs, so it
+// checks the weekday (FOOTPRINT_DAY) itself.
+func FootprintTask(db *gorm.DB, weekday time.Weekday) Task {
+	limit := footprintTickers()
+	return func(ctx context.Context) error {
+		now := time.Now().In(MarketTimezone)
+		if now.Weekday() != weekday || limit == 0 {
+			return nil
+		}
+		week := deepsearch.LastCompletedWeek(now)
+
+		var tickers []string
+		err := db.WithContext(ctx).Model(&models.TechnicalSignal{}).
+			Where("end_date >= ? AND start_date < ?", week, week.AddDate(0, 0, 5)).
+			Group("ticker").
+			Order("COUNT(*) DESC").
+			Limit(limit).
+			Pluck("ticker", &tickers).Error
+		if err != nil {
+			return err
+		}
+
+		stored := 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, err := RefreshFootprint(ctx, db, ticker, week); err != nil {
+				fmt.Printf("[jobs] institutional footprint: %s: %v\n", ticker, err)
+				continue
+			}
+			stored++
+		}
+		fmt.Printf("[jobs] institutional footprint: stored %d/%d tickers for the week of %s\n", stored, len(tickers), week.Format("2006-01-02"))
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// FreshnessConfig is the policy for refreshing stored analyses on read
+type FreshnessConfig struct {
+	// MaxAge is how old an analysis may get during market hours before a read
+	// queues a refresh; zero disables refreshes
+	MaxAge time.Duration
+	// Throttle is the minimum time between refreshes of the same analysis
+	Throttle time.Duration
+}
+
+// GetFreshnessConfig reads the freshness policy from environment variables
+// with sensible defaults if not provided
+func GetFreshnessConfig() FreshnessConfig {
+	config := FreshnessConfig{
+		MaxAge:   15 * time.Minute,
+		Throttle: 5 * time.Minute,
+	}
+
+	if val := os.Getenv("ANALYSIS_MAX_AGE_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			config.MaxAge = time.Duration(n) * time.Minute
+		}
+	}
+
+	if val := os.Getenv("ANALYSIS_REFRESH_THROTTLE_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			config.Throttle = time.Duration(n) * time.Minute
+		}
+	}
+
+	return config
+}
+
+// Freshness tells a reader how current a stored analysis is
+type Freshness struct {
+	AgeSeconds int64 `json:"age_seconds"`
+	Stale      bool  `json:"stale"`
+	// Refreshing is true while a newer analysis of the same window is queued or running
+	Refreshing   bool  `json:"refreshing"`
+	RefreshJobID *uint `json:"refresh_job_id,omitempty"`
+}
+
+// marketOpen reports whether t falls in the regular session (9:30-16:00 ET) of a trading day
+func marketOpen(ctx context.Context, t time.Time) bool {
+	t = t.In(MarketTimezone)
+	if !service.DefaultTradingCalendar().IsTradingDay(ctx, t) {
+		return false
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= 9*60+30 && minutes < 16*60
+}
+
+// RefreshIfStale checks a stored analysis against the freshness policy. An
+// analysis older than MaxAge during market hours is stale, and a refresh of
+// its window up to today is queued for the analysis owner unless one was
+// queued within Throttle. Failures are logged; the caller still serves the
+// stored result.
+func (q *AnalysisQueue) RefreshIfStale(ctx context.Context, analysis *models.TechnicalSignal) Freshness {
+	now := time.Now()
+	freshness := Freshness{AgeSeconds: int64(now.Sub(analysis.CreatedAt).Seconds())}
+	if q == nil || q.freshness.MaxAge == 0 || now.Sub(analysis.CreatedAt) < q.freshness.MaxAge || !marketOpen(ctx, now) {
+		return freshness
+	}
+	freshness.Stale = true
+
+	// A refresh queued recently, whatever its outcome, holds off another one
+	var recent models.AnalysisJob
+	err := q.db.WithContext(ctx).
+		Where("user_id = ? AND ticker = ? AND start_duration = ? AND time_span = ? AND multiplier = ? AND vwap_anchor = ? AND atr_period = ? AND strategy_id = ? AND created_at > ?",
+			analysis.UserId, analysis.Ticker, analysis.PolyStartDuration, analysis.PolyTimeSpan, analysis.PolyMultiplier, analysis.VWAPAnchor, analysis.ATRPeriod, analysis.StrategyID, now.Add(-q.freshness.Throttle)).
+		Order("created_at desc").
+		First(&recent).Error
+	if err == nil {
+		if recent.Status == models.JobStatusPending || recent.Status == models.JobStatusRunning {
+			freshness.Refreshing = true
+			freshness.RefreshJobID = &recent.ID
+		}
+		return freshness
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("[jobs] failed to check refreshes of analysis %d: %v\n", analysis.ID, err)
+		return freshness
+	}
+
+	job := models.AnalysisJob{
+		Ticker:        analysis.Ticker,
+		StartDuration: analysis.PolyStartDuration,
+		EndDuration:   now.Format("2006-01-02"),
+		TimeSpan:      analysis.PolyTimeSpan,
+		Multiplier:    analysis.PolyMultiplier,
+		VWAPAnchor:    analysis.VWAPAnchor,
+		ATRPeriod:     analysis.ATRPeriod,
+		StrategyID:    analysis.StrategyID,
+		UserId:        analysis.UserId,
+	}
+	if _, err := q.Enqueue(ctx, &job, nil); err != nil {
+		fmt.Printf("[jobs] failed to queue refresh of analysis %d: %v\n", analysis.ID, err)
+		return freshness
+	}
+	fmt.Printf("[jobs] analysis %d is %s old, queued refresh job %d\n", analysis.ID, now.Sub(analysis.CreatedAt).Round(time.Second), job.ID)
+
+	freshness.Refreshing = true
+	freshness.RefreshJobID = &job.ID
+	return freshness
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// ftdRecentPeriods is how many half-month fails-to-deliver files each run
+// looks for, enough to catch up after a month of missed runs
+const ftdRecentPeriods = 4
+
+// FTDTask loads the recent fails-to-deliver files the SEC has published
+// since the last run. Files loaded before are skipped, and the task is off
+// until SEC_USER_AGENT is configured.
+func FTDTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		if !service.NewSECService().Configured() {
+			return nil
+		}
+
+		periods := deepsearch.RecentFTDPeriods(time.Now().In(MarketTimezone), ftdRecentPeriods)
+		var loaded []string
+		if err := db.WithContext(ctx).Model(&models.FailToDeliverPeriod{}).Where("period IN ?", periods).Pluck("period", &loaded).Error; err != nil {
+			return err
+		}
+		skip := make(map[string]bool, len(loaded))
+		for _, period := range loaded {
+			skip[period] = true
+		}
+
+		stored := 0
+		for _, period := range periods {
+			if skip[period] {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ingest, err := deepsearch.IngestFailsToDeliver(ctx, db, period)
+			if err != nil {
+				fmt.Printf("[jobs] ftd: %s: %v\n", period, err)
+				continue
+			}
+			if !ingest.Published {
+				continue
+			}
+			stored++
+			fmt.Printf("[jobs] ftd: loaded %s with %d rows for %d tickers\n", period, ingest.Rows, ingest.Tickers)
+		}
+		fmt.Printf("[jobs] ftd: loaded %d new periods\n", stored)
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// IngestGroupedDaily pulls Polygon grouped daily bars for every US stock on a
+// date and upserts them into the bar store. Returns the number of bars stored.
+func IngestGroupedDaily(ctx context.Context, db *gorm.DB, date time.Time) (int, error) {
+	svc := service.NewMarketDataService()
+	aggs, err := svc.GetGroupedDailyBars(ctx, date)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch grouped daily bars for %s: %w", date.Format("2006-01-02"), err)
+	}
+	if len(aggs) == 0 {
+		return 0, nil
+	}
+
+	bars := make([]models.Bar, 0, len(aggs))
+	for _, agg := range aggs {
+		if agg.Ticker == "" {
+			continue
+		}
+		bars = append(bars, models.Bar{
+			Ticker:       agg.Ticker,
+			Timestamp:    time.Time(agg.Timestamp).UTC(),
+			TimeSpan:     "day",
+			Multiplier:   1,
+			Open:         agg.Open,
+			High:         agg.High,
+			Low:          agg.Low,
+			Close:        agg.Close,
+			Volume:       agg.Volume,
+			VWAP:         agg.VWAP,
+			Transactions: agg.Transactions,
+		})
+	}
+
+	if err := models.UpsertBars(db, bars); err != nil {
+		return 0, err
+	}
+
+	return len(bars), nil
+}
+
+// GroupedDailyTask ingests the current market day's grouped bars; scheduled each evening
+func GroupedDailyTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		today := time.Now().In(MarketTimezone)
+		date := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+		n, err := IngestGroupedDaily(ctx, db, date)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[jobs] grouped daily bars: stored %d bars for %s\n", n, date.Format("2006-01-02"))
+		return nil
+	}
+}
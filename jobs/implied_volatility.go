@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ivRecentDays is how far back analysed tickers are recorded when
+	// IV_TICKERS is unset
+	ivRecentDays = 30
+	// ivMaxTickers caps the analysed tickers recorded in one run
+	ivMaxTickers = 200
+)
+
+// ivTickers returns the tickers the daily job records IV for: IV_TICKERS
+// when set ("none" disables the job), else the most recently analysed ones
+func ivTickers(ctx context.Context, db *gorm.DB) ([]string, error) {
+	if getEnvDefault("IV_TICKERS", "") != "" {
+		return tickerList("IV_TICKERS", ""), nil
+	}
+
+	var tickers []string
+	err := db.WithContext(ctx).Model(&models.TechnicalSignal{}).
+		Where("created_at > ?", time.Now().AddDate(0, 0, -ivRecentDays)).
+		Group("ticker").
+		Order("MAX(created_at) DESC").
+		Limit(ivMaxTickers).
+		Pluck("ticker", &tickers).Error
+	return tickers, err
+}
+
+// ImpliedVolatilityTask stores the day's at-the-money implied volatility of
+// each ticker on trading days, building the history IV rank is measured against
+func ImpliedVolatilityTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		if !service.DefaultTradingCalendar().IsTradingDay(ctx, time.Now()) {
+			return nil
+		}
+		tickers, err := ivTickers(ctx, db)
+		if err != nil {
+			return fmt.Errorf("failed to list tickers: %w", err)
+		}
+
+		stored := 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, _, err := deepsearch.RefreshImpliedVolatility(ctx, db, ticker); err != nil {
+				fmt.Printf("[jobs] implied volatility: %s: %v\n", ticker, err)
+				continue
+			}
+			stored++
+		}
+		fmt.Printf("[jobs] implied volatility: stored %d/%d tickers\n", stored, len(tickers))
+		return nil
+	}
+}
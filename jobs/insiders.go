@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// defaultInsiderLookbackDays is how far back Form 4 filings are read
+const defaultInsiderLookbackDays = 90
+
+// InsiderLookbackDays returns how many days of Form 4 filings each run reads,
+// INSIDER_LOOKBACK_DAYS overriding the default. Filings read before are skipped.
+func InsiderLookbackDays() int {
+	if val := os.Getenv("INSIDER_LOOKBACK_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultInsiderLookbackDays
+}
+
+// InsiderTask reads the new Form 4 filings of the INSIDER_TICKERS before the
+// open. It is off until tickers and SEC_USER_AGENT are configured.
+func InsiderTask(db *gorm.DB) Task {
+	return func(ctx context.Context) error {
+		tickers := tickerList("INSIDER_TICKERS", "")
+		if len(tickers) == 0 || !service.NewSECService().Configured() {
+			return nil
+		}
+
+		since := time.Now().In(MarketTimezone).AddDate(0, 0, -InsiderLookbackDays())
+		filings, transactions := 0, 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ingest, err := deepsearch.IngestInsiderFilings(ctx, db, ticker, since)
+			if err != nil {
+				fmt.Printf("[jobs] insiders: %s: %v\n", ticker, err)
+				continue
+			}
+			filings += ingest.Filings
+			transactions += ingest.Transactions
+		}
+		fmt.Printf("[jobs] insiders: read %d new filings with %d buys and sells for %d tickers\n", filings, transactions, len(tickers))
+		return nil
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultMaxPainTickers are snapshotted when MAX_PAIN_TICKERS is unset
+	defaultMaxPainTickers = "SPY,QQQ,IWM"
+	// DefaultMaxPainExpiries is how many of the nearest expiries are snapshotted
+	DefaultMaxPainExpiries = 4
+)
+
+// maxPainTickers returns the tickers the daily job snapshots; MAX_PAIN_TICKERS
+// overrides the default and "none" disables the job
+func maxPainTickers() []string {
+	return tickerList("MAX_PAIN_TICKERS", defaultMaxPainTickers)
+}
+
+// maxPainExpiries returns how many expiries the job snapshots per ticker
+func maxPainExpiries() int {
+	if val := os.Getenv("MAX_PAIN_EXPIRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxPainExpiries
+}
+
+// RefreshMaxPain computes and stores today's max pain of a ticker's nearest expiries
+func RefreshMaxPain(ctx context.Context, db *gorm.DB, ticker string, expiries int) ([]models.MaxPainSnapshot, error) {
+	snapshots, err := deepsearch.ComputeMaxPain(ctx, ticker, expiries)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.UpsertMaxPain(db.WithContext(ctx), snapshots); err != nil {
+		return nil, fmt.Errorf("failed to store max pain: %w", err)
+	}
+	return snapshots, nil
+}
+
+// MaxPainTask stores the day's max pain and open interest by strike of the
+// configured tickers, so its migration into expiration can be charted
+func MaxPainTask(db *gorm.DB) Task {
+	tickers := maxPainTickers()
+	expiries := maxPainExpiries()
+	return func(ctx context.Context) error {
+		stored := 0
+		for _, ticker := range tickers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, err := RefreshMaxPain(ctx, db, ticker, expiries); err != nil {
+				fmt.Printf("[jobs] max pain: %s: %v\n", ticker, err)
+				continue
+			}
+			stored++
+		}
+		fmt.Printf("[jobs] max pain: stored %d/%d tickers\n", stored, len(tickers))
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/paper"
+)
+
+// defaultPaperMarkMinutes is how often open paper trades are marked to market
+const defaultPaperMarkMinutes = 5
+
+// PaperMarkInterval returns how often open paper trades are marked;
+// PAPER_TRADE_MARK_MINUTES overrides the default and 0 disables marking
+func PaperMarkInterval() time.Duration {
+	if val := os.Getenv("PAPER_TRADE_MARK_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return defaultPaperMarkMinutes * time.Minute
+}
+
+// PaperMarkTask marks open paper trades at their last trade and closes those
+// whose stops were hit, skipping runs while Polygon is degraded
+func PaperMarkTask(trader *paper.Engine) Task {
+	return func(ctx context.Context) error {
+		if health.Degraded(health.Polygon) {
+			return nil
+		}
+		marked, closed, err := trader.MarkToMarket(ctx)
+		if err != nil {
+			return err
+		}
+		if closed > 0 {
+			fmt.Printf("[jobs] paper trades: marked %d, %d stopped out\n", marked, closed)
+		}
+		return nil
+	}
+}
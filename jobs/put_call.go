@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultPutCallTickers are recorded when PUT_CALL_TICKERS is unset
+	defaultPutCallTickers = "SPY,QQQ,IWM"
+	// defaultPutCallMarketTickers make up the market-wide ratio when
+	// PUT_CALL_MARKET_TICKERS is unset
+	defaultPutCallMarketTickers = "SPY,SPX"
+	// defaultPutCallIntradayMinutes is the intraday sampling interval
+	defaultPutCallIntradayMinutes = 30
+)
+
+// tickerList splits a comma-separated ticker list from the environment;
+// "none" gives no tickers
+func tickerList(key, fallback string) []string {
+	raw := getEnvDefault(key, fallback)
+	if strings.EqualFold(raw, "none") {
+		return nil
+	}
+	var tickers []string
+	for _, ticker := range strings.Split(raw, ",") {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != "" {
+			tickers = append(tickers, ticker)
+		}
+	}
+	return tickers
+}
+
+// PutCallIntradayInterval returns how often intraday put/call ratios are
+// sampled, 0 when PUT_CALL_INTRADAY_MINUTES disables sampling
+func PutCallIntradayInterval() time.Duration {
+	if val := os.Getenv("PUT_CALL_INTRADAY_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return defaultPutCallIntradayMinutes * time.Minute
+}
+
+// RecordPutCallRatios reads the put/call ratios of the configured tickers and
+// of the market at at, scores them and stores them under interval. Daily
+// readings are stored at the start of the market day, intraday ones at at.
+// The market reading is stored when at least one component could be read.
+func RecordPutCallRatios(ctx context.Context, db *gorm.DB, interval string, at time.Time) ([]models.PutCallRatio, error) {
+	tickers := tickerList("PUT_CALL_TICKERS", defaultPutCallTickers)
+	market := tickerList("PUT_CALL_MARKET_TICKERS", defaultPutCallMarketTickers)
+
+	read := map[string]models.PutCallRatio{}
+	for _, ticker := range append(append([]string{}, tickers...), market...) {
+		if _, ok := read[ticker]; ok {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		ratio, err := deepsearch.ReadPutCallRatio(ctx, ticker, at)
+		if err != nil {
+			fmt.Printf("[jobs] put/call: %s: %v\n", ticker, err)
+			continue
+		}
+		read[ticker] = ratio
+	}
+
+	var ratios []models.PutCallRatio
+	for _, ticker := range tickers {
+		if ratio, ok := read[ticker]; ok {
+			ratios = append(ratios, ratio)
+		}
+	}
+	var components []models.PutCallRatio
+	for _, ticker := range market {
+		if ratio, ok := read[ticker]; ok {
+			components = append(components, ratio)
+		}
+	}
+	if len(components) > 0 {
+		combined := deepsearch.CombinePutCallRatios(models.PutCallMarket, components)
+		combined.Partial = combined.Partial || len(components) < len(market)
+		ratios = append(ratios, combined)
+	}
+
+	for i := range ratios {
+		ratios[i].Interval = interval
+		ratios[i].Timestamp = at.Truncate(time.Minute)
+		if interval == models.PutCallDaily {
+			ratios[i].Timestamp, _ = time.ParseInLocation("2006-01-02", ratios[i].Day, MarketTimezone)
+		}
+		if err := deepsearch.ScorePutCallRatio(ctx, db, &ratios[i]); err != nil {
+			fmt.Printf("[jobs] put/call: failed to score %s: %v\n", ratios[i].Ticker, err)
+		}
+	}
+	if err := models.UpsertPutCallRatios(db.WithContext(ctx), ratios); err != nil {
+		return nil, fmt.Errorf("failed to store put/call ratios: %w", err)
+	}
+	for _, ratio := range ratios {
+		if ratio.Signal != "" {
+			fmt.Printf("[jobs] put/call: %s %s\n", ratio.Ticker, ratio.Signal)
+		}
+	}
+	return ratios, nil
+}
+
+// PutCallTask records put/call ratios under interval on trading days
+func PutCallTask(db *gorm.DB, interval string) Task {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		if !service.DefaultTradingCalendar().IsTradingDay(ctx, now) {
+			return nil
+		}
+		ratios, err := RecordPutCallRatios(ctx, db, interval, now)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[jobs] put/call: stored %d %s readings\n", len(ratios), interval)
+		return nil
+	}
+}
@@ -0,0 +1,417 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"institutionanalyser/alerts"
+	"institutionanalyser/broker"
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/events"
+	"institutionanalyser/models"
+	"institutionanalyser/monitoring"
+	"institutionanalyser/paper"
+	"institutionanalyser/tracing"
+	"institutionanalyser/usage"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"gorm.io/gorm"
+)
+
+const (
+	queuePollInterval = 2 * time.Second
+	// Jobs left running longer than this (e.g. after a crash) are requeued on startup
+	staleJobAfter = 30 * time.Minute
+)
+
+// AnalysisQueue runs deepsearch analyses in background workers. Jobs are
+// persisted in analysis_jobs and claimed with SKIP LOCKED, so pending work
+// survives restarts and multiple replicas can share one queue.
+type AnalysisQueue struct {
+	db        *gorm.DB
+	hub       *events.Hub
+	alerts    *alerts.Dispatcher
+	email     *alerts.EmailNotifier
+	paper     *paper.Engine
+	broker    *broker.Router
+	freshness FreshnessConfig
+	workers   int
+	wake      chan struct{}
+	running   sync.WaitGroup
+}
+
+// NewAnalysisQueue creates a queue that reports progress to hub, checks
+// completed analyses against alert rules, emails their summaries, trades them
+// on paper and routes their orders (email, trader and router may be nil);
+// ANALYSIS_WORKERS sets the worker count (default 4) and GetFreshnessConfig
+// the refresh-on-read policy
+func NewAnalysisQueue(db *gorm.DB, hub *events.Hub, dispatcher *alerts.Dispatcher, email *alerts.EmailNotifier, trader *paper.Engine, router *broker.Router) *AnalysisQueue {
+	workers := 4
+	if val := os.Getenv("ANALYSIS_WORKERS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			workers = n
+		}
+	}
+	return &AnalysisQueue{
+		db:        db,
+		hub:       hub,
+		alerts:    dispatcher,
+		email:     email,
+		paper:     trader,
+		broker:    router,
+		freshness: GetFreshnessConfig(),
+		workers:   workers,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Enqueue persists a pending job and wakes a worker. If the same user already
+// has a pending or running job for the same ticker, window and aggregation,
+// job is overwritten with that job and Enqueue reports false instead of
+// queueing a duplicate. request, if not nil, is the deep search request that
+// triggered the job; it is stored with a new job in the same transaction, and
+// not at all for a duplicate. A transaction-scoped advisory lock on the job key
+// serialises concurrent triggers (e.g. orchestrator retries) across replicas;
+// SQLite serialises writers itself.
+func (q *AnalysisQueue) Enqueue(ctx context.Context, job *models.AnalysisJob, request *models.DeepSearchRequest) (bool, error) {
+	created := false
+	err := q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if !models.IsSQLite(tx) {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", jobLockKey(job)).Error; err != nil {
+				return err
+			}
+		}
+
+		var existing models.AnalysisJob
+		err := tx.Where("user_id = ? AND ticker = ? AND start_duration = ? AND end_duration = ? AND time_span = ? AND multiplier = ? AND vwap_anchor = ? AND atr_period = ? AND strategy_id = ? AND status IN ?",
+			job.UserId, job.Ticker, job.StartDuration, job.EndDuration, job.TimeSpan, job.Multiplier, job.VWAPAnchor, job.ATRPeriod, job.StrategyID,
+			[]string{models.JobStatusPending, models.JobStatusRunning}).
+			Order("created_at").
+			First(&existing).Error
+		if err == nil {
+			*job = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if request != nil {
+			if err := tx.Create(request).Error; err != nil {
+				return err
+			}
+			job.DeepSearchRequestID = request.ID
+		}
+		job.Status = models.JobStatusPending
+		job.TraceParent = tracing.Inject(ctx)
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		q.publishJob(job)
+		q.notify()
+	}
+	return created, nil
+}
+
+// jobLockKey identifies analyses that would produce the same result
+func jobLockKey(job *models.AnalysisJob) string {
+	return fmt.Sprintf("analysis:%s:%s:%s:%s:%s:%d:%s:%d:%d", job.UserId, job.Ticker, job.StartDuration, job.EndDuration, job.TimeSpan, job.Multiplier, job.VWAPAnchor, job.ATRPeriod, job.StrategyID)
+}
+
+// RetryFilter narrows which failed jobs RetryFailed requeues; zero values match everything
+type RetryFilter struct {
+	IDs    []uint
+	Ticker string
+	// Since only matches jobs that failed at or after this time
+	Since time.Time
+}
+
+// RetryFailed moves failed jobs back to pending and wakes the workers. Only
+// the latest failure of each ticker/window/aggregation is retried, and jobs
+// whose analysis is already pending or running again are skipped. It returns
+// the requeued jobs.
+func (q *AnalysisQueue) RetryFailed(ctx context.Context, filter RetryFilter) ([]models.AnalysisJob, error) {
+	conditions := []string{"status = @failed"}
+	args := map[string]interface{}{
+		"failed":  models.JobStatusFailed,
+		"pending": models.JobStatusPending,
+		"active":  []string{models.JobStatusPending, models.JobStatusRunning},
+		"now":     time.Now(),
+	}
+	if len(filter.IDs) > 0 {
+		conditions = append(conditions, "id IN @ids")
+		args["ids"] = filter.IDs
+	}
+	if filter.Ticker != "" {
+		conditions = append(conditions, "ticker = @ticker")
+		args["ticker"] = filter.Ticker
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "finished_at >= @since")
+		args["since"] = filter.Since
+	}
+
+	var retried []models.AnalysisJob
+	err := q.db.WithContext(ctx).Raw(`
+		UPDATE analysis_jobs
+		SET status = @pending, error = '', started_at = NULL, finished_at = NULL, result_id = NULL, updated_at = @now
+		WHERE `+strings.Join(conditions, " AND ")+`
+			-- retry only the latest failure per analysis key
+			AND id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (
+						PARTITION BY user_id, ticker, start_duration, end_duration, time_span, multiplier, vwap_anchor, atr_period, strategy_id
+						ORDER BY created_at DESC, id DESC
+					) AS failure_row
+					FROM analysis_jobs
+					WHERE status = @failed
+				) failures
+				WHERE failure_row = 1
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM analysis_jobs active
+				WHERE active.status IN @active AND active.user_id = analysis_jobs.user_id
+					AND active.ticker = analysis_jobs.ticker AND active.start_duration = analysis_jobs.start_duration
+					AND active.end_duration = analysis_jobs.end_duration AND active.time_span = analysis_jobs.time_span
+					AND active.multiplier = analysis_jobs.multiplier AND active.vwap_anchor = analysis_jobs.vwap_anchor
+					AND active.atr_period = analysis_jobs.atr_period AND active.strategy_id = analysis_jobs.strategy_id
+			)
+		RETURNING *`, args).
+		Scan(&retried).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range retried {
+		q.publishJob(&retried[i])
+	}
+	if len(retried) > 0 {
+		fmt.Printf("[jobs] requeued %d failed analysis jobs\n", len(retried))
+		q.notify()
+	}
+	return retried, nil
+}
+
+func (q *AnalysisQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start requeues stale jobs and launches the workers; they stop when ctx is cancelled
+func (q *AnalysisQueue) Start(ctx context.Context) {
+	requeued := q.db.Model(&models.AnalysisJob{}).
+		Where("status = ? AND started_at < ?", models.JobStatusRunning, time.Now().Add(-staleJobAfter)).
+		Update("status", models.JobStatusPending)
+	if requeued.RowsAffected > 0 {
+		fmt.Printf("[jobs] requeued %d stale analysis jobs\n", requeued.RowsAffected)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.running.Add(1)
+		go func() {
+			defer q.running.Done()
+			q.worker(ctx)
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped after Start's ctx is cancelled.
+// Jobs they were running are cancelled through ctx and returned to pending.
+func (q *AnalysisQueue) Wait() {
+	q.running.Wait()
+}
+
+func (q *AnalysisQueue) worker(ctx context.Context) {
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything claimable before sleeping
+		for {
+			job, err := q.claim(ctx)
+			if err != nil {
+				fmt.Printf("[jobs] failed to claim analysis job: %v\n", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim atomically moves the oldest pending job to running. SQLite has no
+// row locks and needs none, as it runs one write at a time.
+func (q *AnalysisQueue) claim(ctx context.Context) (*models.AnalysisJob, error) {
+	lock := "FOR UPDATE SKIP LOCKED"
+	if models.IsSQLite(q.db) {
+		lock = ""
+	}
+	now := time.Now()
+	var jobs []models.AnalysisJob
+	err := q.db.WithContext(ctx).Raw(`
+		UPDATE analysis_jobs
+		SET status = ?, started_at = ?, updated_at = ?, attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM analysis_jobs
+			WHERE status = ?
+			ORDER BY created_at
+			LIMIT 1
+			`+lock+`
+		)
+		RETURNING *`, models.JobStatusRunning, now, now, models.JobStatusPending).
+		Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (q *AnalysisQueue) run(ctx context.Context, job *models.AnalysisJob) {
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, job.TraceParent), "analysis_job.run",
+		trace.WithAttributes(
+			attribute.Int64("job.id", int64(job.ID)),
+			attribute.String("ticker", job.Ticker),
+			attribute.Int("job.attempts", job.Attempts),
+		))
+	defer span.End()
+	ctx = usage.WithUser(usage.WithFeature(ctx, usage.FeatureDeepsearch), job.UserId)
+
+	fmt.Printf("[jobs] analysis job %d started: %s %s-%s\n", job.ID, job.Ticker, job.StartDuration, job.EndDuration)
+	q.publishJob(job)
+
+	result, chart, err := q.analyse(ctx, job)
+
+	if err != nil && ctx.Err() != nil {
+		// Cancelled by shutdown: hand the job back so the next worker to
+		// start, here or on another replica, runs it again
+		if err := q.db.Model(job).Update("status", models.JobStatusPending).Error; err != nil {
+			fmt.Printf("[jobs] failed to requeue interrupted analysis job %d: %v\n", job.ID, err)
+		}
+		fmt.Printf("[jobs] analysis job %d interrupted by shutdown, requeued\n", job.ID)
+		job.Status = models.JobStatusPending
+		q.publishJob(job)
+		return
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+		fmt.Printf("[jobs] analysis job %d failed: %v\n", job.ID, err)
+		monitoring.CaptureError(err, job.UserId, jobTags(job))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		job.Status = models.JobStatusCompleted
+		job.Error = ""
+		job.ResultID = &result.ID
+		fmt.Printf("[jobs] analysis job %d completed: analysis %d\n", job.ID, result.ID)
+	}
+	updates := map[string]interface{}{
+		"finished_at": job.FinishedAt,
+		"status":      job.Status,
+		"error":       job.Error,
+		"result_id":   job.ResultID,
+	}
+
+	// Record the outcome even if the worker is shutting down
+	if err := q.db.Model(job).Updates(updates).Error; err != nil {
+		fmt.Printf("[jobs] failed to record outcome of analysis job %d: %v\n", job.ID, err)
+	}
+
+	if result != nil {
+		q.hub.Publish(events.Event{
+			Type:   events.TypeSignal,
+			Ticker: result.Ticker,
+			UserID: result.UserId,
+			Data: map[string]interface{}{
+				"analysis":           result,
+				"structured_signals": deepsearch.ParseSignals(result.Signals),
+			},
+		})
+		q.alerts.Notify(context.WithoutCancel(ctx), result)
+		q.email.NotifyCompleted(context.WithoutCancel(ctx), result, chart)
+		if err := q.paper.OnAnalysis(context.WithoutCancel(ctx), result); err != nil {
+			fmt.Printf("[jobs] failed to paper trade analysis %d: %v\n", result.ID, err)
+		}
+		if err := q.broker.OnAnalysis(context.WithoutCancel(ctx), result); err != nil {
+			fmt.Printf("[jobs] failed to route orders for analysis %d: %v\n", result.ID, err)
+		}
+	}
+	q.publishJob(job)
+}
+
+// analyse runs the job's analysis, converting a panic into a job failure so
+// one bad ticker cannot take down the worker. The window's chart is rendered
+// only when summaries are emailed.
+func (q *AnalysisQueue) analyse(ctx context.Context, job *models.AnalysisJob) (result *models.TechnicalSignal, chart []byte, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			monitoring.CapturePanic(recovered, job.UserId, jobTags(job))
+			result, chart, err = nil, nil, fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	svc := deepsearch.NewDeepSearchService(job.StartDuration, job.EndDuration, job.TimeSpan, job.Multiplier, job.Ticker, job.UserId, q.db)
+	svc.SetVWAPAnchor(job.VWAPAnchor)
+	svc.SetATRPeriod(job.ATRPeriod)
+	if job.StrategyID != 0 {
+		strategy, err := deepsearch.LoadStrategy(ctx, q.db, job.StrategyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := svc.SetStrategy(strategy); err != nil {
+			return nil, nil, err
+		}
+	}
+	result, err = svc.AnalyseMain(ctx)
+	if err == nil && q.email.Enabled() {
+		if chart, err = svc.ChartPNG(); err != nil {
+			fmt.Printf("[jobs] failed to render chart for analysis job %d: %v\n", job.ID, err)
+			chart, err = nil, nil
+		}
+	}
+	return result, chart, err
+}
+
+func jobTags(job *models.AnalysisJob) map[string]string {
+	return map[string]string{
+		"job_id": fmt.Sprintf("%d", job.ID),
+		"ticker": job.Ticker,
+		"window": job.StartDuration + ".." + job.EndDuration,
+	}
+}
+
+// publishJob pushes the job's current state to its owner's subscribers
+func (q *AnalysisQueue) publishJob(job *models.AnalysisJob) {
+	q.hub.Publish(events.Event{Type: events.TypeJob, Ticker: job.Ticker, UserID: job.UserId, Data: job})
+}
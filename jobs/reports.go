@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/reports"
+	"institutionanalyser/service"
+)
+
+// DailyDigestTask generates the daily digest after the close of each trading day
+func DailyDigestTask(generator *reports.Generator) Task {
+	return func(ctx context.Context) error {
+		today := time.Now().In(MarketTimezone)
+		if !service.DefaultTradingCalendar().IsTradingDay(ctx, today) {
+			fmt.Printf("[jobs] daily digest: %s is not a trading day, skipping\n", today.Format("2006-01-02"))
+			return nil
+		}
+		_, err := generator.Run(ctx, models.ReportDailyDigest, today)
+		return err
+	}
+}
+
+// EarningsPreviewTask generates the earnings preview for the coming week. The
+// scheduler only runs daily jobs, so it checks the weekday (REPORT_EARNINGS_PREVIEW_DAY) itself.
+func EarningsPreviewTask(generator *reports.Generator, weekday time.Weekday) Task {
+	return func(ctx context.Context) error {
+		today := time.Now().In(MarketTimezone)
+		if today.Weekday() != weekday {
+			return nil
+		}
+		// The preview covers the seven days after the day it is written
+		start := today.AddDate(0, 0, 1)
+		_, err := generator.Run(ctx, models.ReportEarningsPreview, start)
+		return err
+	}
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(value, day.String()) || strings.EqualFold(value, day.String()[:3]) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", value)
+}
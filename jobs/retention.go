@@ -0,0 +1,254 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// Retention modes: archive keeps expired rows out of listings, purge deletes them
+const (
+	RetentionArchive = "archive"
+	RetentionPurge   = "purge"
+)
+
+// RetentionConfig sets how long analyses and deep search requests are kept
+type RetentionConfig struct {
+	// Mode is RetentionArchive or RetentionPurge
+	Mode string `json:"mode"`
+	// DefaultDays applies to users whose tier has no retention of its own and
+	// to system accounts; 0 keeps rows indefinitely
+	DefaultDays int `json:"default_days"`
+	// TierDays overrides DefaultDays per user tier (users.tier); 0 keeps the
+	// tier's rows indefinitely
+	TierDays map[string]int `json:"tier_days"`
+}
+
+// GetRetentionConfig reads the retention policy from ANALYSIS_RETENTION_MODE,
+// ANALYSIS_RETENTION_DAYS and ANALYSIS_RETENTION_TIER_DAYS ("free=90,pro=730")
+func GetRetentionConfig() (RetentionConfig, error) {
+	config := RetentionConfig{
+		Mode:     getEnvDefault("ANALYSIS_RETENTION_MODE", RetentionArchive),
+		TierDays: map[string]int{},
+	}
+	if config.Mode != RetentionArchive && config.Mode != RetentionPurge {
+		return config, fmt.Errorf("invalid ANALYSIS_RETENTION_MODE %q: use archive or purge", config.Mode)
+	}
+	if val := os.Getenv("ANALYSIS_RETENTION_DAYS"); val != "" {
+		days, err := strconv.Atoi(val)
+		if err != nil || days < 0 {
+			return config, fmt.Errorf("invalid ANALYSIS_RETENTION_DAYS %q: must be a non-negative integer", val)
+		}
+		config.DefaultDays = days
+	}
+	for _, entry := range strings.Split(os.Getenv("ANALYSIS_RETENTION_TIER_DAYS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		tier, val, ok := strings.Cut(entry, "=")
+		days, err := strconv.Atoi(strings.TrimSpace(val))
+		if !ok || strings.TrimSpace(tier) == "" || err != nil || days < 0 {
+			return config, fmt.Errorf("invalid ANALYSIS_RETENTION_TIER_DAYS entry %q: use tier=days", entry)
+		}
+		config.TierDays[strings.TrimSpace(tier)] = days
+	}
+	return config, nil
+}
+
+// Enabled reports whether any rows expire
+func (c RetentionConfig) Enabled() bool {
+	if c.DefaultDays > 0 {
+		return true
+	}
+	for _, days := range c.TierDays {
+		if days > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TierRetention counts the rows one tier's retention window expired
+type TierRetention struct {
+	// Tier is the user tier, "" for the default retention
+	Tier     string `json:"tier"`
+	Days     int    `json:"days"`
+	Analyses int64  `json:"analyses"`
+	Requests int64  `json:"requests"`
+}
+
+// RetentionResult counts the rows a retention run archived or purged
+type RetentionResult struct {
+	Mode     string          `json:"mode"`
+	Analyses int64           `json:"analyses"`
+	Requests int64           `json:"requests"`
+	Tiers    []TierRetention `json:"tiers"`
+}
+
+// RetentionStats are the rows archived and purged by this process since it
+// started, and its last run
+type RetentionStats struct {
+	ArchivedAnalyses int64            `json:"archived_analyses"`
+	ArchivedRequests int64            `json:"archived_requests"`
+	PurgedAnalyses   int64            `json:"purged_analyses"`
+	PurgedRequests   int64            `json:"purged_requests"`
+	Runs             int64            `json:"runs"`
+	LastRunAt        *time.Time       `json:"last_run_at,omitempty"`
+	LastRun          *RetentionResult `json:"last_run,omitempty"`
+}
+
+var (
+	retentionMu    sync.Mutex
+	retentionStats RetentionStats
+)
+
+// GetRetentionStats returns the retention counters of this process
+func GetRetentionStats() RetentionStats {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	return retentionStats
+}
+
+func recordRetention(result RetentionResult) {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	if result.Mode == RetentionPurge {
+		retentionStats.PurgedAnalyses += result.Analyses
+		retentionStats.PurgedRequests += result.Requests
+	} else {
+		retentionStats.ArchivedAnalyses += result.Analyses
+		retentionStats.ArchivedRequests += result.Requests
+	}
+	now := time.Now()
+	retentionStats.Runs++
+	retentionStats.LastRunAt = &now
+	retentionStats.LastRun = &result
+}
+
+// ApplyRetention archives or purges the analyses and deep search requests
+// older than their owner's retention window. Users are matched to a tier
+// through users.tier; everyone else, including system accounts such as the
+// orchestrator, gets DefaultDays. Archiving sets an analysis's ArchivedAt and
+// soft-deletes a request; purging deletes both for good, along with the
+// analyses' signal performance rows and stored bars, including rows already
+// archived or soft-deleted.
+func ApplyRetention(ctx context.Context, db *gorm.DB, config RetentionConfig) (RetentionResult, error) {
+	result := RetentionResult{Mode: config.Mode, Tiers: []TierRetention{}}
+	db = db.WithContext(ctx)
+
+	tiers := make([]string, 0, len(config.TierDays))
+	for tier := range config.TierDays {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+
+	expire := func(tier string, days int, owners func(*gorm.DB) *gorm.DB) error {
+		if days == 0 {
+			return nil
+		}
+		counts := TierRetention{Tier: tier, Days: days}
+		cutoff := time.Now().AddDate(0, 0, -days)
+		var err error
+		if counts.Analyses, err = expireAnalyses(db, config.Mode, cutoff, owners); err != nil {
+			return err
+		}
+		if counts.Requests, err = expireRequests(db, config.Mode, cutoff, owners); err != nil {
+			return err
+		}
+		result.Analyses += counts.Analyses
+		result.Requests += counts.Requests
+		result.Tiers = append(result.Tiers, counts)
+		return nil
+	}
+
+	for _, tier := range tiers {
+		members := db.Model(&models.User{}).Select("CAST(id AS TEXT)").Where("tier = ?", tier)
+		err := expire(tier, config.TierDays[tier], func(query *gorm.DB) *gorm.DB {
+			return query.Where("user_id IN (?)", members)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	err := expire("", config.DefaultDays, func(query *gorm.DB) *gorm.DB {
+		if len(tiers) == 0 {
+			return query
+		}
+		return query.Where("user_id NOT IN (?)", db.Model(&models.User{}).Select("CAST(id AS TEXT)").Where("tier IN ?", tiers))
+	})
+	if err != nil {
+		return result, err
+	}
+
+	recordRetention(result)
+	return result, nil
+}
+
+// expireAnalyses archives or purges the analyses of owners created before cutoff
+func expireAnalyses(db *gorm.DB, mode string, cutoff time.Time, owners func(*gorm.DB) *gorm.DB) (int64, error) {
+	if mode == RetentionArchive {
+		archived := owners(db.Model(&models.TechnicalSignal{})).
+			Where("archived_at IS NULL AND created_at < ?", cutoff).
+			Update("archived_at", time.Now())
+		if archived.Error != nil {
+			return 0, fmt.Errorf("failed to archive analyses: %w", archived.Error)
+		}
+		return archived.RowsAffected, nil
+	}
+
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		expired := owners(tx.Unscoped().Model(&models.TechnicalSignal{})).Select("id").Where("created_at < ?", cutoff)
+		for _, rows := range []interface{}{&models.SignalPerformance{}, &models.SignalReturn{}, &models.AnalysisBar{}} {
+			if err := tx.Where("analysis_id IN (?)", expired).Delete(rows).Error; err != nil {
+				return err
+			}
+		}
+		deleted := owners(tx.Unscoped()).Where("created_at < ?", cutoff).Delete(&models.TechnicalSignal{})
+		purged = deleted.RowsAffected
+		return deleted.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge analyses: %w", err)
+	}
+	return purged, nil
+}
+
+// expireRequests soft-deletes (archive) or deletes (purge) the deep search
+// requests of owners created before cutoff
+func expireRequests(db *gorm.DB, mode string, cutoff time.Time, owners func(*gorm.DB) *gorm.DB) (int64, error) {
+	query := db
+	if mode == RetentionPurge {
+		query = db.Unscoped()
+	}
+	deleted := owners(query).Where("created_at < ?", cutoff).Delete(&models.DeepSearchRequest{})
+	if deleted.Error != nil {
+		return 0, fmt.Errorf("failed to %s deep search requests: %w", mode, deleted.Error)
+	}
+	return deleted.RowsAffected, nil
+}
+
+// RetentionTask applies the retention policy from the environment
+func RetentionTask(db *gorm.DB, config RetentionConfig) Task {
+	return func(ctx context.Context) error {
+		result, err := ApplyRetention(ctx, db, config)
+		if err != nil {
+			return err
+		}
+		verb := "archived"
+		if result.Mode == RetentionPurge {
+			verb = "purged"
+		}
+		fmt.Printf("[jobs] retention: %s %d analyses, %d deep search requests\n", verb, result.Analyses, result.Requests)
+		return nil
+	}
+}
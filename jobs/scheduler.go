@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	_ "time/tzdata" // market schedules need America/New_York in minimal containers
+
+	"institutionanalyser/monitoring"
+	"institutionanalyser/usage"
+)
+
+// MarketTimezone is the timezone scheduled jobs are expressed in
+var MarketTimezone = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Task is a unit of scheduled background work
+type Task func(ctx context.Context) error
+
+type dailyTask struct {
+	name         string
+	hour         int
+	minute       int
+	weekdaysOnly bool
+	run          Task
+}
+
+type intervalTask struct {
+	name            string
+	every           time.Duration
+	marketHoursOnly bool
+	run             Task
+}
+
+// Scheduler runs tasks at fixed times of day in the market timezone, or at
+// fixed intervals
+type Scheduler struct {
+	daily    []dailyTask
+	interval []intervalTask
+	running  sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Daily registers a task to run every day at the given "HH:MM" (market time)
+func (s *Scheduler) Daily(name, at string, weekdaysOnly bool, run Task) error {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return fmt.Errorf("invalid schedule time %q for %s: %w", at, name, err)
+	}
+	s.daily = append(s.daily, dailyTask{
+		name:         name,
+		hour:         t.Hour(),
+		minute:       t.Minute(),
+		weekdaysOnly: weekdaysOnly,
+		run:          run,
+	})
+	return nil
+}
+
+// Every registers a task to run at every multiple of interval since
+// midnight, market time; with marketHoursOnly, only during the regular session
+func (s *Scheduler) Every(name string, interval time.Duration, marketHoursOnly bool, run Task) error {
+	if interval < time.Minute {
+		return fmt.Errorf("invalid interval %v for %s: must be at least a minute", interval, name)
+	}
+	s.interval = append(s.interval, intervalTask{
+		name:            name,
+		every:           interval,
+		marketHoursOnly: marketHoursOnly,
+		run:             run,
+	})
+	return nil
+}
+
+// Start launches every registered task; they stop when ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, task := range s.daily {
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			s.runDaily(ctx, task)
+		}()
+	}
+	for _, task := range s.interval {
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			s.runInterval(ctx, task)
+		}()
+	}
+}
+
+// Wait blocks until every task has stopped after Start's ctx is cancelled,
+// including runs in progress
+func (s *Scheduler) Wait() {
+	s.running.Wait()
+}
+
+func (s *Scheduler) runDaily(ctx context.Context, task dailyTask) {
+	for {
+		next := nextRun(time.Now().In(MarketTimezone), task)
+		fmt.Printf("[jobs] %s scheduled for %s\n", task.name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		runLogged(ctx, task.name, task.run)
+	}
+}
+
+func (s *Scheduler) runInterval(ctx context.Context, task intervalTask) {
+	fmt.Printf("[jobs] %s scheduled every %v\n", task.name, task.every)
+	for {
+		// Wall-clock time since midnight, so runs keep their times across DST changes
+		now := time.Now().In(MarketTimezone)
+		elapsed := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+		offset := elapsed.Truncate(task.every) + task.every
+		next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, int(offset/time.Second), 0, MarketTimezone)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if task.marketHoursOnly && !marketOpen(ctx, time.Now()) {
+			continue
+		}
+		runLogged(ctx, task.name, task.run)
+	}
+}
+
+// runLogged runs a task and logs its outcome, reporting failures
+func runLogged(ctx context.Context, name string, run Task) {
+	start := time.Now()
+	if err := runTask(ctx, name, run); err != nil {
+		fmt.Printf("[jobs] %s failed after %v: %v\n", name, time.Since(start), err)
+		monitoring.CaptureError(err, "", map[string]string{"task": name})
+	} else {
+		fmt.Printf("[jobs] %s completed in %v\n", name, time.Since(start))
+	}
+}
+
+// runTask runs a task, converting a panic into an error so the schedule keeps going
+func runTask(ctx context.Context, name string, run Task) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			monitoring.CapturePanic(recovered, "", map[string]string{"task": name})
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return run(usage.WithFeature(ctx, "job:"+name))
+}
+
+// nextRun returns the first scheduled time strictly after now
+func nextRun(now time.Time, task dailyTask) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), task.hour, task.minute, 0, 0, now.Location())
+	for !next.After(now) || (task.weekdaysOnly && isWeekend(next)) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"os"
+
+	"institutionanalyser/broker"
+	"institutionanalyser/models"
+	"institutionanalyser/paper"
+	"institutionanalyser/reports"
+
+	"gorm.io/gorm"
+)
+
+// SetupScheduledJobs registers every recurring background job. Times are
+// market time (America/New_York) and can be overridden from the environment.
+// Report jobs are only registered when a report generator is configured.
+func SetupScheduledJobs(scheduler *Scheduler, db *gorm.DB, generator *reports.Generator) error {
+	if err := scheduler.Daily("grouped-daily-bars", getEnvDefault("GROUPED_DAILY_INGEST_TIME", "18:00"), true, GroupedDailyTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("ticker-sync", getEnvDefault("TICKER_SYNC_TIME", "06:00"), true, TickerSyncTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("earnings-estimates", getEnvDefault("EARNINGS_ESTIMATE_SYNC_TIME", "07:00"), true, EarningsEstimateTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("insiders", getEnvDefault("INSIDER_SYNC_TIME", "07:30"), true, InsiderTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("ftd", getEnvDefault("FTD_SYNC_TIME", "06:30"), true, FTDTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("bar-compaction", getEnvDefault("BAR_COMPACTION_TIME", "03:00"), false, BarCompactionTask(db)); err != nil {
+		return err
+	}
+	retention, err := GetRetentionConfig()
+	if err != nil {
+		return err
+	}
+	if retention.Enabled() {
+		if err := scheduler.Daily("analysis-retention", getEnvDefault("ANALYSIS_RETENTION_TIME", "03:30"), false, RetentionTask(db, retention)); err != nil {
+			return err
+		}
+	}
+	if err := scheduler.Daily("signal-performance-backfill", getEnvDefault("SIGNAL_PERFORMANCE_BACKFILL_TIME", "04:00"), false, SignalPerformanceTask(db)); err != nil {
+		return err
+	}
+	if interval := SignalOutcomeInterval(); interval > 0 {
+		if err := scheduler.Every("signal-outcomes", interval, false, SignalOutcomeTask(db)); err != nil {
+			return err
+		}
+	}
+	if err := scheduler.Daily("max-pain", getEnvDefault("MAX_PAIN_TIME", "09:45"), true, MaxPainTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("implied-volatility", getEnvDefault("IV_SNAPSHOT_TIME", "15:45"), true, ImpliedVolatilityTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("put-call-daily", getEnvDefault("PUT_CALL_DAILY_TIME", "16:15"), true, PutCallTask(db, models.PutCallDaily)); err != nil {
+		return err
+	}
+	if interval := PutCallIntradayInterval(); interval > 0 {
+		if err := scheduler.Every("put-call-intraday", interval, true, PutCallTask(db, models.PutCallIntraday)); err != nil {
+			return err
+		}
+	}
+	// The watch samples every interval during the session and once more after the close
+	if interval := EarningsWatchInterval(); interval > 0 {
+		if err := scheduler.Every("earnings-watch", interval, true, EarningsWatchTask(db)); err != nil {
+			return err
+		}
+		if err := scheduler.Daily("earnings-watch-close", getEnvDefault("EARNINGS_WATCH_CLOSE_TIME", "16:05"), true, EarningsWatchTask(db)); err != nil {
+			return err
+		}
+	}
+	if trader := paper.NewEngine(db, paper.GetConfig()); trader != nil {
+		if interval := PaperMarkInterval(); interval > 0 {
+			if err := scheduler.Every("paper-trade-marks", interval, true, PaperMarkTask(trader)); err != nil {
+				return err
+			}
+		}
+	}
+	router, err := broker.NewRouter(db, broker.GetConfig())
+	if err != nil {
+		return err
+	}
+	if router != nil {
+		if interval := BrokerSyncInterval(); interval > 0 {
+			if err := scheduler.Every("broker-order-sync", interval, false, BrokerSyncTask(router)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scheduler.Daily("block-trades", getEnvDefault("BLOCK_TRADE_TIME", "16:30"), true, BlockTradeTask(db)); err != nil {
+		return err
+	}
+	if err := scheduler.Daily("confirmations", getEnvDefault("CONFIRMATION_TIME", "16:45"), true, ConfirmationTask(db)); err != nil {
+		return err
+	}
+	footprintDay, err := parseWeekday(getEnvDefault("FOOTPRINT_DAY", "Saturday"))
+	if err != nil {
+		return err
+	}
+	if err := scheduler.Daily("institutional-footprint", getEnvDefault("FOOTPRINT_TIME", "08:00"), false, FootprintTask(db, footprintDay)); err != nil {
+		return err
+	}
+
+	if generator == nil {
+		return nil
+	}
+	if err := scheduler.Daily("daily-digest", getEnvDefault("REPORT_DAILY_DIGEST_TIME", "17:00"), true, DailyDigestTask(generator)); err != nil {
+		return err
+	}
+	previewDay, err := parseWeekday(getEnvDefault("REPORT_EARNINGS_PREVIEW_DAY", "Sunday"))
+	if err != nil {
+		return err
+	}
+	if err := scheduler.Daily("weekly-earnings-preview", getEnvDefault("REPORT_EARNINGS_PREVIEW_TIME", "18:00"), false, EarningsPreviewTask(generator, previewDay)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getEnvDefault(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultPerformanceBackfillBatch caps how many unscored analyses one backfill
+// run scores, since analyses whose bars are not stored need Polygon
+const defaultPerformanceBackfillBatch = 500
+
+// performanceBackfillBatch returns the backfill batch size;
+// SIGNAL_PERFORMANCE_BACKFILL_BATCH overrides the default and 0 disables the job
+func performanceBackfillBatch() int {
+	if val := os.Getenv("SIGNAL_PERFORMANCE_BACKFILL_BATCH"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultPerformanceBackfillBatch
+}
+
+// BackfillSignalPerformance scores up to limit analyses whose signal
+// performance has not been recorded, newest first, and returns how many it scored
+func BackfillSignalPerformance(ctx context.Context, db *gorm.DB, limit int) (int, error) {
+	var analyses []models.TechnicalSignal
+	err := db.WithContext(ctx).
+		Where("performance_scored_at IS NULL").
+		Order("id DESC").
+		Limit(limit).
+		Find(&analyses).Error
+	if err != nil {
+		return 0, err
+	}
+
+	scored := 0
+	for i := range analyses {
+		if ctx.Err() != nil {
+			return scored, ctx.Err()
+		}
+		if err := deepsearch.ScoreStoredAnalysis(ctx, db, &analyses[i]); err != nil {
+			fmt.Printf("[jobs] signal performance: analysis %d: %v\n", analyses[i].ID, err)
+			continue
+		}
+		scored++
+	}
+	return scored, nil
+}
+
+// SignalPerformanceTask backfills the signal performance of analyses stored
+// before their runs recorded it, one batch per run
+func SignalPerformanceTask(db *gorm.DB) Task {
+	limit := performanceBackfillBatch()
+	return func(ctx context.Context) error {
+		if limit == 0 {
+			return nil
+		}
+		scored, err := BackfillSignalPerformance(ctx, db, limit)
+		if err != nil {
+			return err
+		}
+		if scored > 0 {
+			fmt.Printf("[jobs] signal performance: scored %d analyses\n", scored)
+		}
+		return nil
+	}
+}
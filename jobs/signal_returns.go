@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultSignalOutcomeMinutes is how often due signal returns are priced
+	defaultSignalOutcomeMinutes = 15
+	// defaultSignalOutcomeBatch caps how many analyses one run prices, since
+	// each horizon of each analysis is one Polygon call
+	defaultSignalOutcomeBatch = 200
+	// signalReturnSettle is how long after a horizon its bar is left to
+	// complete before the return is priced
+	signalReturnSettle = 30 * time.Minute
+)
+
+// SignalOutcomeInterval returns how often due signal returns are priced;
+// SIGNAL_OUTCOME_MINUTES overrides the default and 0 disables pricing
+func SignalOutcomeInterval() time.Duration {
+	if val := os.Getenv("SIGNAL_OUTCOME_MINUTES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return defaultSignalOutcomeMinutes * time.Minute
+}
+
+// signalOutcomeBatch returns how many analyses one run prices;
+// SIGNAL_OUTCOME_BATCH overrides the default
+func signalOutcomeBatch() int {
+	if val := os.Getenv("SIGNAL_OUTCOME_BATCH"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultSignalOutcomeBatch
+}
+
+// ScoreDueSignalReturns prices the pending signal returns whose horizons have
+// passed, for up to limit analyses, and returns how many it scored. Analyses
+// tried least recently go first, so returns that cannot be priced yet do not
+// hold up the rest.
+func ScoreDueSignalReturns(ctx context.Context, db *gorm.DB, limit int) (int, error) {
+	due := time.Now().Add(-signalReturnSettle)
+	var analysisIDs []uint
+	err := db.WithContext(ctx).Model(&models.SignalReturn{}).
+		Where("status = ? AND due_at <= ?", models.SignalReturnPending, due).
+		Group("analysis_id").
+		Order("MIN(updated_at)").
+		Limit(limit).
+		Pluck("analysis_id", &analysisIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	scored := 0
+	for _, id := range analysisIDs {
+		if ctx.Err() != nil {
+			return scored, ctx.Err()
+		}
+
+		var rows []models.SignalReturn
+		if err := db.WithContext(ctx).Where("analysis_id = ? AND status = ? AND due_at <= ?", id, models.SignalReturnPending, due).
+			Order("horizon, signal_index").Find(&rows).Error; err != nil {
+			return scored, err
+		}
+		// Mark the attempt, which moves the analysis to the back of the queue
+		if err := db.WithContext(ctx).Model(&models.SignalReturn{}).Where("analysis_id = ? AND status = ? AND due_at <= ?", id, models.SignalReturnPending, due).
+			Update("updated_at", time.Now()).Error; err != nil {
+			return scored, err
+		}
+
+		var analysis models.TechnicalSignal
+		err := db.WithContext(ctx).First(&analysis, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted analyses are not priced
+			if err := db.WithContext(ctx).Model(&models.SignalReturn{}).Where("analysis_id = ? AND status = ?", id, models.SignalReturnPending).
+				Update("status", models.SignalReturnUnavailable).Error; err != nil {
+				return scored, err
+			}
+			continue
+		}
+		if err != nil {
+			return scored, err
+		}
+
+		byHorizon := map[string][]models.SignalReturn{}
+		var horizons []string
+		for _, row := range rows {
+			if _, ok := byHorizon[row.Horizon]; !ok {
+				horizons = append(horizons, row.Horizon)
+			}
+			byHorizon[row.Horizon] = append(byHorizon[row.Horizon], row)
+		}
+		for _, horizon := range horizons {
+			n, err := deepsearch.ScoreSignalReturns(ctx, db, &analysis, horizon, byHorizon[horizon])
+			if err != nil {
+				fmt.Printf("[jobs] signal outcomes: analysis %d at %s: %v\n", id, horizon, err)
+				continue
+			}
+			scored += n
+		}
+	}
+	return scored, nil
+}
+
+// SignalOutcomeTask prices due signal returns, one batch per run, skipping
+// runs while Polygon is degraded
+func SignalOutcomeTask(db *gorm.DB) Task {
+	limit := signalOutcomeBatch()
+	return func(ctx context.Context) error {
+		if health.Degraded(health.Polygon) {
+			return nil
+		}
+		scored, err := ScoreDueSignalReturns(ctx, db, limit)
+		if err != nil {
+			return err
+		}
+		if scored > 0 {
+			fmt.Printf("[jobs] signal outcomes: scored %d returns\n", scored)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"institutionanalyser/events"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := models.InitDatabase("sqlite://" + t.TempDir() + "/jobs.db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { models.CloseDatabase(db) })
+	return db
+}
+
+func TestSQLiteRetryFailed(t *testing.T) {
+	db := openSQLite(t)
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	jobs := []models.AnalysisJob{
+		{Ticker: "AAPL", Status: models.JobStatusFailed, Error: "timeout"},
+		{Ticker: "AAPL", Status: models.JobStatusFailed, Error: "timeout"},
+		{Ticker: "MSFT", Status: models.JobStatusFailed, Error: "timeout"},
+		{Ticker: "MSFT", Status: models.JobStatusRunning},
+	}
+	for i := range jobs {
+		jobs[i].UserId = "1"
+		jobs[i].StartDuration = "2026-03-02"
+		jobs[i].EndDuration = "2026-03-02"
+		jobs[i].TimeSpan = "minute"
+		jobs[i].Multiplier = 1
+		jobs[i].CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := db.Create(&jobs[i]).Error; err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	queue := NewAnalysisQueue(db, events.NewHub(), nil, nil, nil, nil)
+	retried, err := queue.RetryFailed(context.Background(), RetryFilter{})
+	if err != nil {
+		t.Fatalf("RetryFailed: %v", err)
+	}
+	// MSFT already has a running job and only the newest AAPL failure is retried
+	if len(retried) != 1 || retried[0].ID != jobs[1].ID || retried[0].Status != models.JobStatusPending {
+		t.Fatalf("retried = %+v, want job %d pending", retried, jobs[1].ID)
+	}
+}
+
+func TestSQLiteApplyRetentionByTier(t *testing.T) {
+	db := openSQLite(t)
+	pro := models.User{Email: "pro@example.com", PasswordHash: "x", Tier: "pro"}
+	free := models.User{Email: "free@example.com", PasswordHash: "x"}
+	for _, user := range []*models.User{&pro, &free} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("seed user: %v", err)
+		}
+	}
+	old := time.Now().AddDate(0, 0, -45)
+	for _, user := range []models.User{pro, free} {
+		analysis := models.TechnicalSignal{Ticker: "AAPL", UserId: strconv.FormatUint(uint64(user.ID), 10), StartDate: old, EndDate: old,
+			Signals: models.StringArray{}}
+		analysis.CreatedAt = old
+		if err := db.Create(&analysis).Error; err != nil {
+			t.Fatalf("seed analysis: %v", err)
+		}
+	}
+
+	config := RetentionConfig{Mode: RetentionArchive, DefaultDays: 30, TierDays: map[string]int{"pro": 90}}
+	result, err := ApplyRetention(context.Background(), db, config)
+	if err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	if result.Analyses != 1 {
+		t.Fatalf("archived %d analyses, want only the default tier's", result.Analyses)
+	}
+	var archived models.TechnicalSignal
+	if err := db.Where("archived_at IS NOT NULL").First(&archived).Error; err != nil {
+		t.Fatalf("find archived analysis: %v", err)
+	}
+	if archived.UserId != strconv.FormatUint(uint64(free.ID), 10) {
+		t.Errorf("archived the analysis of user %s, want %d", archived.UserId, free.ID)
+	}
+}
+
+func TestSQLiteCompactBarsExpires(t *testing.T) {
+	db := openSQLite(t)
+	now := time.Now()
+	bars := []models.Bar{
+		{Ticker: "AAPL", TimeSpan: "minute", Multiplier: 1, Timestamp: now.AddDate(0, 0, -40)},
+		{Ticker: "AAPL", TimeSpan: "minute", Multiplier: 1, Timestamp: now.AddDate(0, 0, -1)},
+		{Ticker: "AAPL", TimeSpan: "day", Multiplier: 1, Timestamp: now.AddDate(0, 0, -40)},
+	}
+	if err := db.Create(&bars).Error; err != nil {
+		t.Fatalf("seed bars: %v", err)
+	}
+
+	result, err := CompactBars(context.Background(), db, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("CompactBars: %v", err)
+	}
+	if result.Merged != 0 || result.Expired != 1 {
+		t.Fatalf("result = %+v, want 1 expired intraday bar", result)
+	}
+}
+
+func TestSQLiteWatchedReporters(t *testing.T) {
+	db := openSQLite(t)
+	estimates := []models.EarningsEstimate{
+		{Ticker: "MSFT", ReportDate: "2026-04-30", ProviderUpdated: "v1", Importance: 2},
+		{Ticker: "AAPL", ReportDate: "2026-04-30", ProviderUpdated: "v1", Importance: 1},
+		{Ticker: "AAPL", ReportDate: "2026-04-30", ProviderUpdated: "v2", Importance: 5},
+		{Ticker: "TSLA", ReportDate: "2026-05-01", ProviderUpdated: "v1", Importance: 5},
+	}
+	for i := range estimates {
+		if err := db.Create(&estimates[i]).Error; err != nil {
+			t.Fatalf("seed estimate: %v", err)
+		}
+	}
+
+	reporters, err := watchedReporters(context.Background(), db, "2026-04-30", 10)
+	if err != nil {
+		t.Fatalf("watchedReporters: %v", err)
+	}
+	if len(reporters) != 2 || reporters[0].Ticker != "AAPL" || reporters[0].ProviderUpdated != "v2" || reporters[1].Ticker != "MSFT" {
+		t.Fatalf("reporters = %+v, want AAPL (v2) then MSFT", reporters)
+	}
+}
@@ -0,0 +1,201 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/monitoring"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tickerUpsertBatchSize = 1000
+	tickerDetailsMaxAge   = 30 * 24 * time.Hour
+)
+
+// TickerSyncResult summarises one reference data sync
+type TickerSyncResult struct {
+	Synced      int `json:"synced"`
+	Deactivated int `json:"deactivated"`
+	Enriched    int `json:"enriched"`
+}
+
+// SyncTickers mirrors Polygon's active stock tickers into the tickers table,
+// deactivates tickers no longer listed and refreshes market cap/sector for a
+// batch of tickers whose details are missing or stale
+func SyncTickers(ctx context.Context, db *gorm.DB, detailsBatch int) (TickerSyncResult, error) {
+	var result TickerSyncResult
+	svc := service.NewMarketDataService()
+	syncStart := time.Now()
+
+	batch := make([]models.Ticker, 0, tickerUpsertBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "ticker"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"name", "market", "locale", "primary_exchange", "type", "currency_name", "currency", "cik", "active", "last_synced_at", "updated_at",
+			}),
+		}).Create(&batch).Error
+		result.Synced += len(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	it := svc.ListStockTickers(ctx, true)
+	for it.Next() {
+		t := it.Item()
+		currency, _ := models.NormalizeCurrency(t.CurrencyName)
+		batch = append(batch, models.Ticker{
+			Ticker:          t.Ticker,
+			Name:            t.Name,
+			Market:          t.Market,
+			Locale:          t.Locale,
+			PrimaryExchange: t.PrimaryExchange,
+			Type:            t.Type,
+			CurrencyName:    t.CurrencyName,
+			Currency:        currency,
+			CIK:             t.CIK,
+			Active:          t.Active,
+			LastSyncedAt:    syncStart,
+		})
+		if len(batch) == tickerUpsertBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return result, fmt.Errorf("failed to list tickers: %w", err)
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	// Only deactivate after a complete listing, otherwise a partial failure
+	// would mark most of the market inactive
+	deactivated := db.Model(&models.Ticker{}).
+		Where("active = ? AND last_synced_at < ?", true, syncStart).
+		Update("active", false)
+	if deactivated.Error != nil {
+		return result, deactivated.Error
+	}
+	result.Deactivated = int(deactivated.RowsAffected)
+
+	enriched, err := enrichTickerDetails(ctx, db, svc, detailsBatch)
+	result.Enriched = enriched
+	return result, err
+}
+
+// enrichTickerDetails fetches ticker details for up to limit tickers, oldest first
+func enrichTickerDetails(ctx context.Context, db *gorm.DB, svc *service.MarketDataService, limit int) (int, error) {
+	var stale []models.Ticker
+	err := db.Where("active = ? AND (details_synced_at IS NULL OR details_synced_at < ?)", true, time.Now().Add(-tickerDetailsMaxAge)).
+		Order("details_synced_at NULLS FIRST").
+		Limit(limit).
+		Find(&stale).Error
+	if err != nil {
+		return 0, err
+	}
+
+	enriched := 0
+	for _, t := range stale {
+		if ctx.Err() != nil {
+			return enriched, ctx.Err()
+		}
+
+		details, err := svc.GetTickerDetails(ctx, t.Ticker)
+		now := time.Now()
+		if err != nil {
+			// Mark as attempted so one bad ticker does not block the queue
+			fmt.Printf("[jobs] ticker details for %s failed: %v\n", t.Ticker, err)
+			db.Model(&t).Update("details_synced_at", now)
+			continue
+		}
+
+		err = db.Model(&t).Updates(map[string]interface{}{
+			"market_cap":         details.MarketCap,
+			"sic_code":           details.SICCode,
+			"sector":             details.SICDescription,
+			"shares_outstanding": details.ShareClassSharesOutstanding,
+			"details_synced_at":  now,
+		}).Error
+		if err != nil {
+			return enriched, err
+		}
+		enriched++
+	}
+
+	return enriched, nil
+}
+
+// TickerSyncer runs on-demand ticker syncs in the background, one at a time
+type TickerSyncer struct {
+	db      *gorm.DB
+	ctx     context.Context
+	busy    atomic.Bool
+	running sync.WaitGroup
+}
+
+// NewTickerSyncer creates a syncer; syncs run under the ctx passed to Start
+func NewTickerSyncer(db *gorm.DB) *TickerSyncer {
+	return &TickerSyncer{db: db, ctx: context.Background()}
+}
+
+// Start sets the context syncs run under; they are cancelled with it
+func (s *TickerSyncer) Start(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Sync starts a sync in the background. It returns false without starting
+// one if a sync is already running.
+func (s *TickerSyncer) Sync(detailsBatch int) bool {
+	if !s.busy.CompareAndSwap(false, true) {
+		return false
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer s.busy.Store(false)
+		result, err := SyncTickers(s.ctx, s.db, detailsBatch)
+		if err != nil {
+			fmt.Printf("[jobs] ticker sync failed: %v\n", err)
+			monitoring.CaptureError(err, "", map[string]string{"task": "ticker-sync"})
+			return
+		}
+		fmt.Printf("[jobs] ticker sync: %d synced, %d deactivated, %d enriched\n", result.Synced, result.Deactivated, result.Enriched)
+	}()
+	return true
+}
+
+// Wait blocks until running syncs have stopped
+func (s *TickerSyncer) Wait() {
+	s.running.Wait()
+}
+
+// TickerSyncTask syncs ticker reference data; TICKER_DETAILS_BATCH bounds detail calls per run
+func TickerSyncTask(db *gorm.DB) Task {
+	detailsBatch := 200
+	if val := os.Getenv("TICKER_DETAILS_BATCH"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			detailsBatch = n
+		}
+	}
+
+	return func(ctx context.Context) error {
+		result, err := SyncTickers(ctx, db, detailsBatch)
+		fmt.Printf("[jobs] ticker sync: %d synced, %d deactivated, %d enriched\n", result.Synced, result.Deactivated, result.Enriched)
+		return err
+	}
+}
@@ -1,25 +1,114 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"institutionanalyser/alerts"
+	"institutionanalyser/broker"
+	"institutionanalyser/config"
+	"institutionanalyser/events"
+	"institutionanalyser/grpcapi"
+	"institutionanalyser/handlers"
+	"institutionanalyser/health"
+	"institutionanalyser/jobs"
+	"institutionanalyser/middleware"
 	"institutionanalyser/models"
+	"institutionanalyser/monitoring"
+	"institutionanalyser/paper"
+	"institutionanalyser/reports"
 	"institutionanalyser/routes"
+	"institutionanalyser/service"
+	"institutionanalyser/tracing"
+	"institutionanalyser/usage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	// Load .env file if it exists. The note goes to stderr, so it does not
+	// mix with command output such as analyse --output json.
+	if err := godotenv.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, "Note: .env file not found, using environment variables only")
+	}
+
+	if err := rootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// configureOutbound sets up the shared outbound HTTP client honouring
+// proxy/TLS settings, which Polygon calls go through
+func configureOutbound() {
+	if err := service.ConfigureHTTPClient(config.GetHTTPClientConfig()); err != nil {
+		log.Fatalf("Failed to configure outbound HTTP client: %v", err)
+	}
+	service.ConfigurePolygonClient(config.GetPolygonClientConfig())
+	health.Configure(health.GetConfig())
+}
+
+// serve runs the REST and gRPC APIs, the analysis queue and background jobs
+// until SIGINT or SIGTERM
+func serve() {
 	fmt.Println("Institution Analyser API")
 
-	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("Note: .env file not found, using environment variables only")
+	configureOutbound()
+
+	// Optional error tracking
+	if enabled, err := monitoring.Init(); err != nil {
+		log.Fatalf("Failed to initialize error tracking: %v", err)
+	} else if enabled {
+		fmt.Println("Error tracking enabled")
+		defer monitoring.Flush(2 * time.Second)
+	}
+
+	// Optional tracing, exported over OTLP
+	if enabled, shutdown, err := tracing.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	} else if enabled {
+		fmt.Println("Tracing enabled")
+		defer shutdown(context.Background())
+	}
+
+	// Get port from environment or use default
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
 	}
 
+	// Set Gin mode
+	ginMode := os.Getenv("GIN_MODE")
+	if ginMode == "" {
+		ginMode = "release" // Default to release mode for production
+	}
+	gin.SetMode(ginMode)
+
+	// The server listens from the start, so probes can tell a starting
+	// instance from a dead one; other requests get 503 until the API is set up
+	startup := &startupHandler{}
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: startup,
+	}
+	go func() {
+		fmt.Printf("Starting server on port %s...\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
 	// Get database connection string
 	dbDSN := os.Getenv("DATABASE_URL")
 	if dbDSN == "" {
@@ -31,47 +120,219 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer func() {
-		sqlDB, _ := db.DB()
-		if sqlDB != nil {
-			sqlDB.Close()
-		}
-	}()
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to register database tracing: %v", err)
+	}
+	if err := db.Use(health.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to register database health tracking: %v", err)
+	}
 
 	fmt.Println("Database connection established successfully")
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Optional read replica for dashboard, analytics and export queries
+	if replicaDSN := os.Getenv("DATABASE_REPLICA_URL"); replicaDSN != "" {
+		if err := models.UseReadReplica(db, replicaDSN); err != nil {
+			log.Fatalf("Failed to initialize read replica: %v", err)
+		}
+		fmt.Println("Read replica connection established successfully")
 	}
 
-	// Set Gin mode
-	ginMode := os.Getenv("GIN_MODE")
-	if ginMode == "" {
-		ginMode = "release" // Default to release mode for production
+	// Optional Timescale hypertable for the bars analyses compute indicators on
+	if timescale := models.GetTimescaleConfig(); timescale.Enabled {
+		if err := models.UseTimescale(db, timescale); err != nil {
+			log.Fatalf("Failed to initialize Timescale bar store: %v", err)
+		}
+		fmt.Println("Timescale enhanced bar store ready")
 	}
-	gin.SetMode(ginMode)
+	health.WarmedUp(health.WarmupMigrations)
+
+	// SIGINT or SIGTERM starts a graceful shutdown: the servers stop taking
+	// requests and drain, then background jobs are cancelled and waited for
+	// and the database pools are closed
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Background jobs run until the servers have drained
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Initialize router
-	router := gin.Default()
+	// Polygon calls are metered per feature and user and written every minute
+	usageFlushed := usage.Start(ctx, db)
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "institution-analyser-api",
-		})
-	})
+	emailNotifier := alerts.NewEmailNotifier(db, alerts.GetEmailConfig())
+	if emailNotifier.Enabled() {
+		fmt.Println("Analysis summary emails enabled")
+	}
+
+	// Report storage keeps scheduled reports and uploaded charts and reports
+	reportStore, err := reports.NewStore(reports.GetStorageConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure report storage: %v", err)
+	}
+
+	var reportGenerator *reports.Generator
+	if os.Getenv("REPORTS_ENABLED") == "true" {
+		reportGenerator, err = reports.NewGenerator(db, reportStore, emailNotifier, reports.GetGeneratorConfig())
+		if err != nil {
+			log.Fatalf("Failed to configure reports: %v", err)
+		}
+		fmt.Printf("Scheduled reports enabled, writing to %s storage\n", reportStore.Backend())
+	}
+
+	var scheduler *jobs.Scheduler
+	if os.Getenv("SCHEDULED_JOBS_ENABLED") != "false" {
+		scheduler = jobs.NewScheduler()
+		if err := jobs.SetupScheduledJobs(scheduler, db, reportGenerator); err != nil {
+			log.Fatalf("Failed to configure scheduled jobs: %v", err)
+		}
+		scheduler.Start(ctx)
+	}
+
+	hub := events.NewHub()
+	alertDispatcher := alerts.NewDispatcher(db, alerts.GetDispatcherConfig())
+	alertDispatcher.Start(ctx)
+	// Orders are only routed to brokers when BROKER_ROUTING_ENABLED=true
+	orderRouter, err := broker.NewRouter(db, broker.GetConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure order routing: %v", err)
+	}
+	if orderRouter != nil {
+		fmt.Println("Broker order routing enabled")
+	}
+	analysisQueue := jobs.NewAnalysisQueue(db, hub, alertDispatcher, emailNotifier, paper.NewEngine(db, paper.GetConfig()), orderRouter)
+	analysisQueue.Start(ctx)
+	tickerSyncer := jobs.NewTickerSyncer(db)
+	tickerSyncer.Start(ctx)
+
+	// The gRPC API for internal orchestrators runs beside the REST API
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		grpcServer := grpcapi.NewServer(db, analysisQueue, hub)
+		go func() {
+			fmt.Printf("Starting gRPC server on port %s...\n", grpcPort)
+			if err := grpcServer.Serve(signals, ":"+grpcPort); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	// Initialize router. Request logging is part of the API middleware chain
+	// configured in routes, so only panic recovery is registered globally.
+	router := gin.New()
+	// Client IPs key the rate limiter, so X-Forwarded-For is only believed
+	// from the proxies in TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(middleware.Recovery())
 
-	routes.SetupRoutes(router, db)
+	// Health check endpoint; ?deep=true probes each dependency. Liveness and
+	// readiness probes are for orchestrators such as Kubernetes.
+	healthHandler := handlers.NewHealthHandler(db)
+	router.GET("/health", healthHandler.HandleHealth)
+	router.GET("/livez", healthHandler.HandleLivez)
+	router.GET("/readyz", healthHandler.HandleReadyz)
+
+	routes.SetupRoutes(router, db, analysisQueue, hub, reportGenerator, reportStore, tickerSyncer)
 
 	// Root endpoint
 
-	// Start server
-	fmt.Printf("Starting server on port %s...\n", port)
+	startup.serve(router)
+	health.WarmedUp(health.WarmupAPI)
 	fmt.Printf("API available at http://localhost:%s/api/v1\n", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+
+	// Readiness waits for the caches requests would otherwise fill
+	go func() {
+		service.DefaultTradingCalendar().Prime(ctx)
+		health.WarmedUp(health.WarmupCaches)
+	}()
+
+	<-signals.Done()
+	stopSignals() // a second signal exits immediately
+	timeout := shutdownTimeout()
+	fmt.Printf("Shutting down, draining requests for up to %s...\n", timeout)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), timeout)
+	defer cancelShutdown()
+
+	// WebSocket streams are hijacked, so Shutdown does not wait for them
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("Failed to drain HTTP requests: %v\n", err)
+	}
+
+	// Running analysis jobs are cancelled and returned to the queue
+	cancel()
+	waitFor(shutdownCtx, "analysis queue", analysisQueue.Wait)
+	waitFor(shutdownCtx, "alert dispatcher", alertDispatcher.Wait)
+	waitFor(shutdownCtx, "ticker sync", tickerSyncer.Wait)
+	if scheduler != nil {
+		waitFor(shutdownCtx, "scheduled jobs", scheduler.Wait)
+	}
+	waitFor(shutdownCtx, "usage flush", func() { <-usageFlushed })
+
+	if err := models.CloseDatabase(db); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+	fmt.Println("Shutdown complete")
+}
+
+// shutdownTimeout is how long a graceful shutdown may take, from
+// SHUTDOWN_TIMEOUT_SECONDS. It should stay below the orchestrator's grace
+// period (30s on Kubernetes) so the database pools close before SIGKILL.
+func shutdownTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 25 * time.Second
+}
+
+// trustedProxies returns the comma-separated IPs and CIDRs in
+// TRUSTED_PROXIES, nil to trust no proxy and use the connection's address
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// waitFor runs wait until it returns or ctx expires, whichever is first
+func waitFor(ctx context.Context, name string, wait func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wait()
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		fmt.Printf("Timed out waiting for %s to stop\n", name)
+	}
+}
+
+// startupHandler serves the API once it is set up. Until then it answers
+// liveness probes and returns 503 with the pending warmup steps to
+// everything else, readiness probes included.
+type startupHandler struct {
+	api atomic.Value // http.Handler
+}
+
+func (h *startupHandler) serve(api http.Handler) {
+	h.api.Store(api)
+}
+
+func (h *startupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if api, ok := h.api.Load().(http.Handler); ok {
+		api.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if r.URL.Path == "/livez" {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(gin.H{"status": "alive"})
+		return
 	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(gin.H{"status": "starting", "pending": health.WarmupPending()})
 }
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Gin context keys set by Authenticate
+const (
+	// UserIDKey holds the authenticated user's ID
+	UserIDKey = "user_id"
+	// AuthMethodKey holds how the request was authenticated (one of the AuthMethod constants)
+	AuthMethodKey = "auth_method"
+	// APIKeyScopesKey holds the scopes of the API key used, if any
+	APIKeyScopesKey = "api_key_scopes"
+	// AdminKey is true when the caller may access every user's data
+	AdminKey = "admin"
+)
+
+// Authentication methods recorded under AuthMethodKey
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+	AuthMethodShared = "shared_token"
+)
+
+// Authentication errors
+var (
+	ErrUnauthorized  = errors.New("Unauthorized")
+	ErrInvalidAPIKey = errors.New("Invalid or revoked API key")
+)
+
+// Identity is who a request's credentials resolved to. Anonymous requests
+// have an empty Method and UserID, and act as the orchestrator account.
+type Identity struct {
+	UserID string
+	Method string
+	// Scopes are the API key's scopes when Method is AuthMethodAPIKey
+	Scopes []string
+	// Admin is set for callers not limited to one user's data: the shared
+	// token, API keys with the admin scope and users listed in ADMIN_USER_IDS
+	Admin bool
+}
+
+// HasScope reports whether the identity may use routes requiring scope. Only
+// API keys are limited by scopes.
+func (id Identity) HasScope(scope string) bool {
+	if id.Method != AuthMethodAPIKey {
+		return true
+	}
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator resolves credentials to an Identity; the HTTP middleware and
+// the gRPC interceptors share it. Accepted credentials are JWTs issued by
+// /api/v1/auth/login, API keys (X-API-Key header or an ia_-prefixed bearer
+// token) and the shared API_AUTH_TOKEN for the orchestrator. Requests without
+// credentials pass anonymously unless AUTH_REQUIRED=true or a shared token is
+// configured.
+type Authenticator struct {
+	db          *gorm.DB
+	sharedToken string
+	required    bool
+	// admins are the user IDs in ADMIN_USER_IDS
+	admins map[string]bool
+}
+
+// NewAuthenticator creates an authenticator from the environment
+func NewAuthenticator(db *gorm.DB) *Authenticator {
+	sharedToken := os.Getenv("API_AUTH_TOKEN")
+	admins := map[string]bool{}
+	for _, userID := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if userID = strings.TrimSpace(userID); userID != "" {
+			admins[userID] = true
+		}
+	}
+	return &Authenticator{
+		db:          db,
+		sharedToken: sharedToken,
+		required:    os.Getenv("AUTH_REQUIRED") == "true" || sharedToken != "",
+		admins:      admins,
+	}
+}
+
+// Resolve returns the identity of a bearer token or API key (either may be empty)
+func (a *Authenticator) Resolve(bearer, apiKey string) (Identity, error) {
+	if apiKey == "" && strings.HasPrefix(bearer, APIKeyPrefix) {
+		apiKey = bearer
+	}
+	if apiKey != "" {
+		key, err := lookupAPIKey(a.db, apiKey)
+		if err != nil {
+			return Identity{}, ErrInvalidAPIKey
+		}
+		identity := Identity{UserID: key.UserId, Method: AuthMethodAPIKey, Scopes: []string(key.Scopes)}
+		identity.Admin = identity.HasScope(models.ScopeAdmin)
+		return identity, nil
+	}
+
+	if bearer == "" {
+		if a.required {
+			return Identity{}, ErrUnauthorized
+		}
+		return Identity{}, nil
+	}
+
+	if a.sharedToken != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(a.sharedToken)) == 1 {
+		return Identity{Method: AuthMethodShared, Admin: true}, nil
+	}
+
+	userID, err := parseToken(bearer)
+	if err != nil {
+		return Identity{}, ErrUnauthorized
+	}
+	return Identity{UserID: userID, Method: AuthMethodJWT, Admin: a.admins[userID]}, nil
+}
+
+// Authenticate resolves the request's credentials (see Authenticator) and
+// stores the user's ID under UserIDKey
+func Authenticate(db *gorm.DB) gin.HandlerFunc {
+	authenticator := NewAuthenticator(db)
+
+	return func(c *gin.Context) {
+		bearer, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		// Browsers cannot set headers on WebSocket handshakes
+		if bearer == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			bearer = c.Query("access_token")
+		}
+
+		identity, err := authenticator.Resolve(bearer, c.GetHeader(APIKeyHeader))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		if identity.UserID != "" {
+			c.Set(UserIDKey, identity.UserID)
+		}
+		if identity.Method != "" {
+			c.Set(AuthMethodKey, identity.Method)
+		}
+		if identity.Method == AuthMethodAPIKey {
+			c.Set(APIKeyScopesKey, identity.Scopes)
+		}
+		c.Set(AdminKey, identity.Admin)
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects callers that are not admins (see Identity). It must run
+// after Authenticate; unlike RequireScope it applies to every caller, not just
+// API keys.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(AdminKey) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation describes a route that has a newer replacement
+type Deprecation struct {
+	// Successor is the path of the replacement endpoint, e.g. /api/v2/deepsearch/analysis
+	Successor string
+	// Sunset is the date after which the route may be removed (optional)
+	Sunset time.Time
+}
+
+// Deprecated marks responses from a route as deprecated so clients can
+// migrate before the route is removed. Headers follow RFC 8594 (Sunset) and
+// the IETF Deprecation header draft.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor))
+		}
+		c.Next()
+	}
+}
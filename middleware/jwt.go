@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultTokenTTL = 24 * time.Hour
+
+// jwtSecret returns the HMAC key used to sign access tokens
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET is not configured")
+	}
+	return []byte(secret), nil
+}
+
+// tokenTTL returns how long issued tokens stay valid; JWT_TTL_HOURS overrides the 24h default
+func tokenTTL() time.Duration {
+	if val := os.Getenv("JWT_TTL_HOURS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return time.Duration(n) * time.Hour
+		}
+	}
+	return defaultTokenTTL
+}
+
+// IssueToken signs an access token whose subject is the user ID
+func IssueToken(userID string) (string, time.Time, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(tokenTTL())
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+
+	signed, err := token.SignedString(secret)
+	return signed, expiresAt, err
+}
+
+// parseToken validates an access token and returns its subject
+func parseToken(tokenString string) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// RequestLogger assigns a request ID to every request and logs method, path,
+// status and latency once the request has been served
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+
+		fmt.Printf("[API] %s | %3d | %13v | %15s | %-7s %s\n",
+			requestID,
+			c.Writer.Status(),
+			time.Since(start),
+			c.ClientIP(),
+			c.Request.Method,
+			c.Request.URL.Path,
+		)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
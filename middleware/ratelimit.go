@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig holds per-client rate limiting configuration
+type RateLimitConfig struct {
+	RequestsPerSecond float64
+	Burst             int
+	// Clients idle for longer than this are evicted from the limiter table
+	IdleTTL time.Duration
+}
+
+// GetRateLimitConfig reads rate limit settings from environment variables
+// with sensible defaults if not provided
+func GetRateLimitConfig() RateLimitConfig {
+	config := RateLimitConfig{
+		RequestsPerSecond: 10,
+		Burst:             20,
+		IdleTTL:           10 * time.Minute,
+	}
+
+	if val := os.Getenv("RATE_LIMIT_RPS"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			config.RequestsPerSecond = n
+		}
+	}
+
+	if val := os.Getenv("RATE_LIMIT_BURST"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.Burst = n
+		}
+	}
+
+	return config
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit applies a token bucket per client IP and rejects requests over
+// the limit with 429
+func RateLimit(config RateLimitConfig) gin.HandlerFunc {
+	var mu sync.Mutex
+	clients := make(map[string]*clientLimiter)
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		now := time.Now()
+
+		mu.Lock()
+		if now.Sub(lastSweep) > config.IdleTTL {
+			for k, cl := range clients {
+				if now.Sub(cl.lastSeen) > config.IdleTTL {
+					delete(clients, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		cl, ok := clients[key]
+		if !ok {
+			cl = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(config.RequestsPerSecond), config.Burst)}
+			clients[key] = cl
+		}
+		cl.lastSeen = now
+		allowed := cl.limiter.Allow()
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+
+		c.Next()
+	}
+}
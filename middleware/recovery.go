@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"institutionanalyser/monitoring"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery converts panics into a structured 500 response and reports them,
+// along with any other 5xx response, to error tracking with request context
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				monitoring.CapturePanic(recovered, c.GetString(UserIDKey), requestTags(c))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error":      "Internal server error",
+					"request_id": c.GetString("request_id"),
+				})
+			}
+		}()
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			err := c.Errors.Last()
+			if err != nil {
+				monitoring.CaptureError(err.Err, c.GetString(UserIDKey), requestTags(c))
+			} else {
+				monitoring.CaptureError(fmt.Errorf("%d response from %s %s", c.Writer.Status(), c.Request.Method, c.FullPath()),
+					c.GetString(UserIDKey), requestTags(c))
+			}
+		}
+	}
+}
+
+func requestTags(c *gin.Context) map[string]string {
+	tags := map[string]string{
+		"method": c.Request.Method,
+		"route":  c.FullPath(),
+	}
+	if requestID := c.GetString("request_id"); requestID != "" {
+		tags["request_id"] = requestID
+	}
+	if ticker := c.Query("ticker"); ticker != "" {
+		tags["ticker"] = ticker
+	}
+	return tags
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Resources a share link can point at
+const (
+	ShareAnalysis = "analysis"
+	ShareReport   = "report"
+)
+
+// shareAudience marks share tokens, which are signed with their own key so
+// they can never pass as access tokens
+const shareAudience = "share"
+
+// ErrShareExpired is returned for a share token past its expiry
+var ErrShareExpired = errors.New("share link has expired")
+
+// shareClaims names the shared resource; the subject is its ID
+type shareClaims struct {
+	Resource string `json:"res"`
+	jwt.RegisteredClaims
+}
+
+// shareSecret returns the HMAC key share links are signed with:
+// SHARE_LINK_SECRET, or else a key derived from JWT_SECRET
+func shareSecret() ([]byte, error) {
+	if secret := os.Getenv("SHARE_LINK_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, errors.New("SHARE_LINK_SECRET or JWT_SECRET must be configured to share")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("share-links"))
+	return mac.Sum(nil), nil
+}
+
+// IssueShareToken signs a token granting view-only access to one resource until ttl elapses
+func IssueShareToken(resource string, id uint, ttl time.Duration) (string, time.Time, error) {
+	secret, err := shareSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, shareClaims{
+		Resource: resource,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(id), 10),
+			Audience:  jwt.ClaimStrings{shareAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	signed, err := token.SignedString(secret)
+	return signed, expiresAt, err
+}
+
+// ParseShareToken validates a share token and returns the resource and ID it grants access to
+func ParseShareToken(tokenString string) (string, uint, error) {
+	secret, err := shareSecret()
+	if err != nil {
+		return "", 0, err
+	}
+
+	claims := &shareClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithExpirationRequired(), jwt.WithAudience(shareAudience))
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return "", 0, ErrShareExpired
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || claims.Resource == "" {
+		return "", 0, errors.New("share token names no resource")
+	}
+	return claims.Resource, uint(id), nil
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"strings"
+
+	"institutionanalyser/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeFeatures attributes API routes to features by path prefix below the
+// version, e.g. /deepsearch for /api/v1/deepsearch/trigger. Matched in
+// order, so longer prefixes come first.
+var routeFeatures = []struct {
+	prefix  string
+	feature string
+}{
+	{"/deepsearch/sector", usage.FeatureScanners},
+	{"/market", usage.FeatureScanners},
+	{"/deepsearch", usage.FeatureDeepsearch},
+	{"/decide", usage.FeatureDeepsearch},
+	{"/decisions", usage.FeatureDeepsearch},
+	{"/replay", usage.FeatureDeepsearch},
+	{"/sandbox", usage.FeatureDeepsearch},
+	{"/footprints", usage.FeatureDeepsearch},
+	{"/earnings", usage.FeatureEarnings},
+	{"/technicals", usage.FeatureTechnicals},
+	{"/options", usage.FeatureMarketData},
+	{"/quote", usage.FeatureMarketData},
+	{"/tickers", usage.FeatureMarketData},
+	{"/admin", usage.FeatureAdmin},
+}
+
+// MeterPolygonUsage attributes the Polygon calls a request makes to its
+// route's feature and the authenticated user. It must run after Authenticate.
+func MeterPolygonUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := usage.WithFeature(c.Request.Context(), routeFeature(c.FullPath()))
+		if userID := c.GetString(UserIDKey); userID != "" {
+			ctx = usage.WithUser(ctx, userID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// routeFeature returns the feature of a route pattern such as
+// /api/v1/deepsearch/jobs/:id
+func routeFeature(route string) string {
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		if rest, ok := strings.CutPrefix(route, version); ok {
+			route = rest
+			break
+		}
+	}
+	for _, rf := range routeFeatures {
+		if strings.HasPrefix(route, rf.prefix) {
+			return rf.feature
+		}
+	}
+	return usage.FeatureOther
+}
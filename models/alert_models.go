@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Alert delivery statuses
+const (
+	AlertDeliveryPending   = "pending"
+	AlertDeliveryDelivered = "delivered"
+	AlertDeliveryFailed    = "failed"
+)
+
+// Alert rule types
+const (
+	// AlertTypeMatch fires on every analysis that matches the rule
+	AlertTypeMatch = "match"
+	// AlertTypeDecisionChange fires only when the final decision differs from
+	// the previous analysis of the ticker
+	AlertTypeDecisionChange = "decision_change"
+)
+
+// Notification channel types
+const (
+	ChannelSlack    = "slack"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+)
+
+// AlertRule notifies a user's webhook when a completed analysis matches. A
+// rule matches when every condition it sets holds: the ticker (empty for any),
+// the final decision, and a metric compared against a threshold. Decision
+// change rules additionally require the decision to have flipped.
+type AlertRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserId     string    `gorm:"not null;index" json:"-"`
+	Name       string    `gorm:"not null" json:"name"`
+	Type       string    `gorm:"not null;default:'match'" json:"type"`
+	Ticker     string    `gorm:"default:''" json:"ticker,omitempty"`
+	Decision   string    `gorm:"default:''" json:"decision,omitempty"`
+	Metric     string    `gorm:"default:''" json:"metric,omitempty"`
+	Operator   string    `gorm:"default:''" json:"operator,omitempty"`
+	Threshold  float64   `gorm:"default:0" json:"threshold"`
+	WebhookURL string    `gorm:"default:''" json:"webhook_url,omitempty"`
+	// ChannelID routes deliveries to a notification channel instead of WebhookURL
+	ChannelID *uint `gorm:"index" json:"channel_id,omitempty"`
+	// Secret signs webhook deliveries (HMAC-SHA256); it is only returned when the rule is created
+	Secret  string `gorm:"not null" json:"-"`
+	Enabled bool   `gorm:"not null;default:true" json:"enabled"`
+	// Template is a Go template rendering the webhook JSON body; empty posts the standard payload
+	Template string `gorm:"type:text;default:''" json:"template,omitempty"`
+}
+
+// NotificationChannel is a user's Slack, Discord, Telegram or email destination
+// for alerts. Credentials (webhook URLs, bot tokens) are never returned by the API.
+type NotificationChannel struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserId     string    `gorm:"not null;index" json:"-"`
+	Name       string    `gorm:"not null" json:"name"`
+	Type       string    `gorm:"not null" json:"type"`
+	WebhookURL string    `gorm:"default:''" json:"-"`
+	BotToken   string    `gorm:"default:''" json:"-"`
+	ChatID     string    `gorm:"default:''" json:"chat_id,omitempty"`
+	// EmailTo is the comma-separated recipients of an email channel
+	EmailTo string `gorm:"default:''" json:"email_to,omitempty"`
+	// Template is a Go template rendering the message; empty uses the built-in one-line message
+	Template string `gorm:"type:text;default:''" json:"template,omitempty"`
+}
+
+// Integration posts every completed analysis of its owner to a third-party
+// automation platform (Zapier, Make, n8n) as flat JSON. FieldMapping renames
+// and selects the fields posted; empty posts every field.
+type Integration struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserId     string    `gorm:"not null;index" json:"-"`
+	Name       string    `gorm:"not null" json:"name"`
+	WebhookURL string    `gorm:"not null" json:"webhook_url"`
+	// FieldMapping maps each posted key to a field of the flat schema
+	FieldMapping map[string]string `gorm:"type:jsonb;serializer:json" json:"field_mapping"`
+	// Secret signs deliveries like alert webhooks; it is only returned when the integration is created
+	Secret  string `gorm:"not null" json:"-"`
+	Enabled bool   `gorm:"not null;default:true" json:"enabled"`
+}
+
+// AlertDelivery is one webhook call for a rule match or an integration,
+// retried until delivered or out of attempts
+type AlertDelivery struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// RuleID is 0 for integration deliveries
+	RuleID        uint            `gorm:"not null;index" json:"rule_id"`
+	IntegrationID *uint           `gorm:"index" json:"integration_id,omitempty"`
+	UserId        string          `gorm:"not null;index" json:"-"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string          `gorm:"not null;default:'pending';index:idx_alert_deliveries_due,priority:1" json:"status"`
+	Attempts      int             `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time       `gorm:"index:idx_alert_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastError     string          `gorm:"type:text;default:''" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
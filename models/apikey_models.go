@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeDeepsearchTrigger = "deepsearch:trigger"
+	ScopeDeepsearchRead    = "deepsearch:read"
+	ScopeAdmin             = "admin"
+)
+
+// APIKeyScopes lists every scope a key can be granted
+var APIKeyScopes = []string{ScopeDeepsearchTrigger, ScopeDeepsearchRead, ScopeAdmin}
+
+// APIKey authenticates a machine client (orchestrator, scheduler) as its
+// owning user. Only a SHA-256 hash of the key is stored.
+type APIKey struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserId     string      `gorm:"not null;index"`
+	Name       string      `gorm:"not null"`
+	Prefix     string      `gorm:"not null"`
+	KeyHash    string      `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     StringArray `gorm:"not null;default:'{}'"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// StringArray is a list of strings stored as a text[] column on Postgres and
+// as a JSON array on SQLite (see InitDatabase). It scans either form, so rows
+// read the same on both databases.
+type StringArray []string
+
+// GormDataType names the type for GORM's schema
+func (StringArray) GormDataType() string {
+	return "string_array"
+}
+
+// GormDBDataType is the column type: text[] on Postgres, JSON elsewhere
+func (StringArray) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if IsSQLite(db) {
+		return "json"
+	}
+	return "text[]"
+}
+
+// GormValue writes the array in the database's form
+func (a StringArray) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if IsSQLite(db) {
+		return clause.Expr{SQL: "?", Vars: []interface{}{jsonArray(a, a == nil)}}
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{pq.StringArray(a)}}
+}
+
+// Value writes the array in Postgres form, for use outside GORM
+func (a StringArray) Value() (driver.Value, error) {
+	return pq.StringArray(a).Value()
+}
+
+// Scan reads a Postgres array or a JSON array
+func (a *StringArray) Scan(src interface{}) error {
+	if text, ok := arrayText(src); ok && strings.HasPrefix(text, "[") {
+		return json.Unmarshal([]byte(text), (*[]string)(a))
+	}
+	return (*pq.StringArray)(a).Scan(src)
+}
+
+// Int64Array is a list of integers stored as a bigint[] column on Postgres
+// and as a JSON array on SQLite, like StringArray
+type Int64Array []int64
+
+// GormDataType names the type for GORM's schema
+func (Int64Array) GormDataType() string {
+	return "int64_array"
+}
+
+// GormDBDataType is the column type: bigint[] on Postgres, JSON elsewhere
+func (Int64Array) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if IsSQLite(db) {
+		return "json"
+	}
+	return "bigint[]"
+}
+
+// GormValue writes the array in the database's form
+func (a Int64Array) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if IsSQLite(db) {
+		return clause.Expr{SQL: "?", Vars: []interface{}{jsonArray(a, a == nil)}}
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{pq.Int64Array(a)}}
+}
+
+// Value writes the array in Postgres form, for use outside GORM
+func (a Int64Array) Value() (driver.Value, error) {
+	return pq.Int64Array(a).Value()
+}
+
+// Scan reads a Postgres array or a JSON array
+func (a *Int64Array) Scan(src interface{}) error {
+	if text, ok := arrayText(src); ok && strings.HasPrefix(text, "[") {
+		return json.Unmarshal([]byte(text), (*[]int64)(a))
+	}
+	return (*pq.Int64Array)(a).Scan(src)
+}
+
+// ArrayElements is a FROM item listing the elements of an array column as
+// rows with one column, value: unnest on Postgres, json_each on SQLite
+func ArrayElements(db *gorm.DB, column string) string {
+	if IsSQLite(db) {
+		return "json_each(" + column + ")"
+	}
+	return "unnest(" + column + ") AS elements(value)"
+}
+
+// ArrayContains matches rows whose array column holds every one of values.
+// On Postgres it is @>, served by a GIN index.
+func ArrayContains(db *gorm.DB, column string, values []string) clause.Expr {
+	if !IsSQLite(db) {
+		return clause.Expr{SQL: column + " @> ?", Vars: []interface{}{pq.StringArray(values)}}
+	}
+	if len(values) == 0 {
+		return clause.Expr{SQL: "1 = 1"}
+	}
+	conditions := make([]string, 0, len(values))
+	vars := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each("+column+") WHERE value = ?)")
+		vars = append(vars, value)
+	}
+	return clause.Expr{SQL: "(" + strings.Join(conditions, " AND ") + ")", Vars: vars}
+}
+
+// ArrayLength is the number of elements in an array column, 0 for an empty
+// or NULL array
+func ArrayLength(db *gorm.DB, column string) string {
+	if IsSQLite(db) {
+		return "COALESCE(json_array_length(" + column + "), 0)"
+	}
+	return "COALESCE(array_length(" + column + ", 1), 0)"
+}
+
+// jsonArray encodes an array as JSON text, or as NULL when it is nil, as pq does
+func jsonArray(a interface{}, isNil bool) interface{} {
+	if isNil {
+		return nil
+	}
+	encoded, _ := json.Marshal(a)
+	return string(encoded)
+}
+
+// arrayText returns a scanned array column as text
+func arrayText(src interface{}) (string, bool) {
+	switch v := src.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const barUpsertBatchSize = 1000
+
+// Bar is a stored OHLCV aggregate. Rows are unique per ticker, timestamp and
+// aggregation (timespan + multiplier) so repeated imports upsert in place.
+type Bar struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Ticker       string    `gorm:"not null;uniqueIndex:idx_bars_ticker_ts_span,priority:1"`
+	Timestamp    time.Time `gorm:"not null;uniqueIndex:idx_bars_ticker_ts_span,priority:2;index"`
+	TimeSpan     string    `gorm:"not null;uniqueIndex:idx_bars_ticker_ts_span,priority:3"`
+	Multiplier   int       `gorm:"not null;default:1;uniqueIndex:idx_bars_ticker_ts_span,priority:4"`
+	Open         float64   `gorm:"not null"`
+	High         float64   `gorm:"not null"`
+	Low          float64   `gorm:"not null"`
+	Close        float64   `gorm:"not null"`
+	Volume       float64   `gorm:"not null"`
+	VWAP         float64   `gorm:"default:0"`
+	Transactions int64     `gorm:"default:0"`
+}
+
+// UpsertBars writes bars to the bar store, replacing rows with the same
+// ticker, timestamp and aggregation
+func UpsertBars(db *gorm.DB, bars []Bar) error {
+	if len(bars) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ticker"}, {Name: "timestamp"}, {Name: "time_span"}, {Name: "multiplier"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"open", "high", "low", "close", "volume", "vwap", "transactions", "updated_at",
+		}),
+	}).CreateInBatches(bars, barUpsertBatchSize).Error
+}
+
+// barUnits are the bar timespans a coarser aggregation can be rolled up from
+// the 1-multiplier bars of, mapped to their length
+var barUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+}
+
+// FindBars returns the stored bars of an aggregation in [from, to), oldest
+// first. Second, minute and hour aggregations with a multiplier above 1 are
+// completed from rolled-up 1-multiplier bars, so ranges whose coarse bars were
+// compacted away are still served; a stored bar wins over a rolled-up one.
+func FindBars(db *gorm.DB, ticker, timeSpan string, multiplier int, from, to time.Time) ([]Bar, error) {
+	var bars []Bar
+	err := db.Where("ticker = ? AND time_span = ? AND multiplier = ? AND timestamp >= ? AND timestamp < ?", ticker, timeSpan, multiplier, from, to).
+		Order("timestamp").
+		Find(&bars).Error
+	if err != nil || multiplier <= 1 || barUnits[timeSpan] == 0 {
+		return bars, err
+	}
+
+	var base []Bar
+	err = db.Where("ticker = ? AND time_span = ? AND multiplier = 1 AND timestamp >= ? AND timestamp < ?", ticker, timeSpan, from, to).
+		Order("timestamp").
+		Find(&base).Error
+	if err != nil || len(base) == 0 {
+		return bars, err
+	}
+
+	stored := make(map[time.Time]bool, len(bars))
+	for _, bar := range bars {
+		stored[bar.Timestamp.UTC()] = true
+	}
+	for _, bar := range RollupBars(base, multiplier) {
+		if !stored[bar.Timestamp.UTC()] {
+			bars = append(bars, bar)
+		}
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Timestamp.Before(bars[j].Timestamp) })
+	return bars, nil
+}
+
+// RollupBars aggregates 1-multiplier bars, oldest first, into multiplier-unit
+// bars aligned to the clock as Polygon does. Other bars are ignored.
+func RollupBars(base []Bar, multiplier int) []Bar {
+	var bars []Bar
+	for _, bar := range base {
+		unit := barUnits[bar.TimeSpan]
+		if bar.Multiplier != 1 || unit == 0 {
+			continue
+		}
+		start := bar.Timestamp.Truncate(time.Duration(multiplier) * unit)
+		if n := len(bars); n > 0 && bars[n-1].Timestamp.Equal(start) && bars[n-1].TimeSpan == bar.TimeSpan {
+			last := &bars[n-1]
+			last.High = math.Max(last.High, bar.High)
+			last.Low = math.Min(last.Low, bar.Low)
+			last.Close = bar.Close
+			last.VWAP += bar.VWAP * bar.Volume
+			last.Volume += bar.Volume
+			last.Transactions += bar.Transactions
+			continue
+		}
+		rolled := bar
+		rolled.ID = 0
+		rolled.Timestamp = start
+		rolled.Multiplier = multiplier
+		rolled.VWAP = bar.VWAP * bar.Volume
+		bars = append(bars, rolled)
+	}
+
+	// VWAP was summed weighted by volume above
+	for i := range bars {
+		if bars[i].Volume > 0 {
+			bars[i].VWAP /= bars[i].Volume
+		}
+	}
+	return bars
+}
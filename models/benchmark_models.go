@@ -0,0 +1,25 @@
+package models
+
+// ExecutionBenchmarks compares an analysis window's prices against the VWAP
+// and TWAP execution benchmarks. Differences are in basis points of the
+// benchmark, positive when the price is above it.
+type ExecutionBenchmarks struct {
+	// VWAP is volume-weighted over the window, TWAP the mean OHLC4 price of
+	// its bars, i.e. weighted by time
+	VWAP  float64 `json:"vwap"`
+	TWAP  float64 `json:"twap"`
+	Close float64 `json:"close"`
+
+	CloseVsVWAPBps float64 `json:"close_vs_vwap_bps"`
+	CloseVsTWAPBps float64 `json:"close_vs_twap_bps"`
+
+	// Large prints are bars whose volume z-score reaches the volume spike
+	// threshold. LargePrintVWAP is their combined VWAP; the fields below it
+	// are zero without large prints.
+	LargePrints           int     `json:"large_prints"`
+	LargePrintVolume      float64 `json:"large_print_volume"`
+	LargePrintVolumeShare float64 `json:"large_print_volume_share"`
+	LargePrintVWAP        float64 `json:"large_print_vwap"`
+	LargePrintVsVWAPBps   float64 `json:"large_print_vs_vwap_bps"`
+	LargePrintVsTWAPBps   float64 `json:"large_print_vs_twap_bps"`
+}
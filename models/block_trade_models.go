@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Block trade sides, by the tick rule
+const (
+	BlockSideBuy  = "BUY"
+	BlockSideSell = "SELL"
+)
+
+// blockTradeBatchSize bounds the rows inserted per statement
+const blockTradeBatchSize = 1000
+
+// BlockTrade is one print of a regular session whose notional reached the
+// block threshold
+type BlockTrade struct {
+	ID     uint   `gorm:"primaryKey" json:"-"`
+	Ticker string `gorm:"not null;index:idx_block_trades_ticker_day,priority:1" json:"ticker"`
+	// Day is the market day, YYYY-MM-DD
+	Day       string    `gorm:"not null;index:idx_block_trades_ticker_day,priority:2" json:"day"`
+	Timestamp time.Time `gorm:"not null" json:"timestamp"`
+	Price     float64   `gorm:"not null" json:"price"`
+	Size      float64   `gorm:"not null" json:"size"`
+	Notional  float64   `gorm:"not null" json:"notional"`
+	// Side is BUY or SELL, empty when the tick rule cannot classify the print
+	Side        string `gorm:"default:''" json:"side,omitempty"`
+	OffExchange bool   `gorm:"default:false" json:"off_exchange"`
+}
+
+// BlockTradeSession totals a ticker's block trades over one regular session
+// and checks the InstitutionalFlow bar heuristic against them
+type BlockTradeSession struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_block_trade_sessions_ticker_day,priority:1" json:"ticker"`
+	// Day is the market day, YYYY-MM-DD
+	Day string `gorm:"not null;uniqueIndex:idx_block_trade_sessions_ticker_day,priority:2" json:"day"`
+	// MinNotional is the dollar value a print needed to count as a block
+	MinNotional float64 `gorm:"not null" json:"min_notional"`
+
+	TotalTrades   int     `gorm:"default:0" json:"total_trades"`
+	TotalVolume   float64 `gorm:"default:0" json:"total_volume"`
+	BlockTrades   int     `gorm:"default:0" json:"block_trades"`
+	BlockVolume   float64 `gorm:"default:0" json:"block_volume"`
+	BlockNotional float64 `gorm:"default:0" json:"block_notional"`
+	BuyBlocks     int     `gorm:"default:0" json:"buy_blocks"`
+	SellBlocks    int     `gorm:"default:0" json:"sell_blocks"`
+	BuyVolume     float64 `gorm:"default:0" json:"buy_volume"`
+	SellVolume    float64 `gorm:"default:0" json:"sell_volume"`
+	BuyNotional   float64 `gorm:"default:0" json:"buy_notional"`
+	SellNotional  float64 `gorm:"default:0" json:"sell_notional"`
+	// NetNotional is buyer minus seller initiated block notional
+	NetNotional            float64 `gorm:"default:0" json:"net_notional"`
+	OffExchangeBlockVolume float64 `gorm:"default:0" json:"off_exchange_block_volume"`
+
+	// Verification compares the session's InstitutionalFlow bars with the
+	// bars blocks printed in; null when the bars could not be loaded
+	Verification *FlowVerification `gorm:"type:jsonb;serializer:json" json:"verification"`
+}
+
+// FlowVerification scores the InstitutionalFlow heuristic (volume per
+// transaction above the running 90th percentile) against a session's block trades
+type FlowVerification struct {
+	TimeSpan   string `json:"timespan"`
+	Multiplier int    `json:"multiplier"`
+	Bars       int    `json:"bars"`
+	// FlowBars are flagged by the heuristic, BlockBars hold at least one
+	// block and MatchedBars are both
+	FlowBars    int `json:"flow_bars"`
+	BlockBars   int `json:"block_bars"`
+	MatchedBars int `json:"matched_bars"`
+	// Precision is MatchedBars / FlowBars and Recall MatchedBars / BlockBars
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	// NotionalCaptured is the share of block notional printed in flagged bars
+	NotionalCaptured float64 `json:"notional_captured"`
+}
+
+// StoreBlockTradeSession stores a session and its blocks, replacing what was
+// stored for the ticker's same day
+func StoreBlockTradeSession(db *gorm.DB, session *BlockTradeSession, blocks []BlockTrade) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "ticker"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at", "min_notional", "total_trades", "total_volume", "block_trades", "block_volume", "block_notional",
+				"buy_blocks", "sell_blocks", "buy_volume", "sell_volume", "buy_notional", "sell_notional", "net_notional",
+				"off_exchange_block_volume", "verification",
+			}),
+		}).Create(session).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("ticker = ? AND day = ?", session.Ticker, session.Day).Delete(&BlockTrade{}).Error; err != nil {
+			return err
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(blocks, blockTradeBatchSize).Error
+	})
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+)
+
+// Supported brokers
+const (
+	BrokerAlpaca = "alpaca"
+)
+
+// Broker order statuses set by the service; every other status is the
+// broker's own, e.g. Alpaca's new, partially_filled, filled or canceled
+const (
+	// BrokerOrderPending is an order recorded but not yet accepted by the broker
+	BrokerOrderPending = "pending"
+	// BrokerOrderFailed is an order the broker refused or could not be sent
+	BrokerOrderFailed = "failed"
+)
+
+// BrokerOrderFinal lists the statuses after which an order no longer changes
+var BrokerOrderFinal = []string{BrokerOrderFailed, "filled", "canceled", "expired", "rejected", "replaced"}
+
+// BrokerAccount holds a user's broker credentials, sealed with
+// BROKER_ENCRYPTION_KEY. Neither the key ID nor the secret is ever returned.
+type BrokerAccount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserId    string    `gorm:"not null;uniqueIndex" json:"-"`
+	Broker    string    `gorm:"not null;default:'alpaca'" json:"broker"`
+	// Paper routes orders to the broker's paper trading environment
+	Paper bool `gorm:"not null;default:true" json:"paper"`
+	// KeyIDHint is the last characters of the key ID, to tell keys apart
+	KeyIDHint    string `gorm:"not null;default:''" json:"key_id_hint"`
+	SealedKeyID  string `gorm:"not null" json:"-"`
+	SealedSecret string `gorm:"not null" json:"-"`
+	// AccountNumber is the broker's account number, read when the
+	// credentials were saved
+	AccountNumber string `gorm:"not null;default:''" json:"account_number"`
+}
+
+// ExecutionRule places an order at the user's broker when an analysis run
+// with StrategyID decides BUY or SELL. Orders are market day orders of either
+// Quantity shares or Notional in the account currency.
+type ExecutionRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserId     string    `gorm:"not null;index:idx_execution_rules_user_strategy,priority:1" json:"-"`
+	StrategyID uint      `gorm:"not null;index:idx_execution_rules_user_strategy,priority:2" json:"strategy_id"`
+	// Ticker limits the rule to one ticker; empty trades every ticker
+	Ticker   string  `gorm:"not null;default:''" json:"ticker,omitempty"`
+	Quantity float64 `gorm:"not null;default:0" json:"quantity,omitempty"`
+	Notional float64 `gorm:"not null;default:0" json:"notional,omitempty"`
+	// MinConfidence is the lowest decision confidence that places an order
+	MinConfidence float64 `gorm:"not null;default:0" json:"min_confidence"`
+	// BuyOnly ignores SELL decisions, for accounts that cannot short
+	BuyOnly bool `gorm:"not null;default:false" json:"buy_only"`
+	Enabled bool `gorm:"not null;default:true" json:"enabled"`
+}
+
+// BrokerOrder is an order placed for an execution rule. ClientOrderID is
+// derived from the analysis and rule, so an analysis places each rule's
+// order at most once across retries and replicas.
+type BrokerOrder struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserId        string    `gorm:"not null;index:idx_broker_orders_user_created,priority:1" json:"-"`
+	AccountID     uint      `gorm:"not null" json:"account_id"`
+	RuleID        uint      `gorm:"not null" json:"rule_id"`
+	AnalysisID    uint      `gorm:"not null;index" json:"analysis_id"`
+	Paper         bool      `gorm:"not null" json:"paper"`
+	ClientOrderID string    `gorm:"not null;uniqueIndex" json:"client_order_id"`
+	BrokerOrderID string    `gorm:"not null;default:'';index" json:"broker_order_id,omitempty"`
+	Ticker        string    `gorm:"not null" json:"ticker"`
+	// Side is buy or sell
+	Side     string  `gorm:"not null" json:"side"`
+	Quantity float64 `gorm:"not null;default:0" json:"quantity,omitempty"`
+	Notional float64 `gorm:"not null;default:0" json:"notional,omitempty"`
+	// Status is BrokerOrderPending, BrokerOrderFailed or the broker's status
+	Status         string     `gorm:"not null;index" json:"status"`
+	FilledQuantity float64    `gorm:"not null;default:0" json:"filled_quantity"`
+	FilledAvgPrice float64    `gorm:"not null;default:0" json:"filled_avg_price"`
+	SubmittedAt    *time.Time `json:"submitted_at,omitempty"`
+	FilledAt       *time.Time `json:"filled_at,omitempty"`
+	SyncedAt       *time.Time `json:"synced_at,omitempty"`
+	Error          string     `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+}
+
+// TradingHalt is an engaged kill switch: no orders are placed for UserId, or
+// for anyone when UserId is empty, until it is released
+type TradingHalt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserId    string    `gorm:"not null;uniqueIndex" json:"user_id,omitempty"`
+	Reason    string    `gorm:"not null;default:''" json:"reason,omitempty"`
+	// HaltedBy is the user who engaged the switch
+	HaltedBy string `gorm:"not null;default:''" json:"halted_by"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Independent sources a confirmed institutional move is built from
+const (
+	// ConfirmVolume is a minute bar's volume z-score spike, directed by the bar's body
+	ConfirmVolume = "volume"
+	// ConfirmBlocks is a block trade, directed by the tick rule
+	ConfirmBlocks = "blocks"
+	// ConfirmSweeps is an option sweep, calls bullish and puts bearish
+	ConfirmSweeps = "sweeps"
+	// ConfirmDarkPool is a spike in a minute's off-exchange volume, directed by
+	// the tick rule's net off-exchange volume
+	ConfirmDarkPool = "dark_pool"
+)
+
+// ConfirmationSources lists every source, in the order they are reported
+var ConfirmationSources = []string{ConfirmVolume, ConfirmBlocks, ConfirmSweeps, ConfirmDarkPool}
+
+// ConfirmedMove is a moment of a regular session when at least Required of
+// the OutOf configured sources agreed on a direction within WindowMinutes
+type ConfirmedMove struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Ticker    string    `gorm:"not null;index:idx_confirmed_moves_ticker_day,priority:1" json:"ticker"`
+	// Day is the market day, YYYY-MM-DD
+	Day string `gorm:"not null;index:idx_confirmed_moves_ticker_day,priority:2" json:"day"`
+	// Timestamp is when the last agreeing source fired
+	Timestamp time.Time `gorm:"not null" json:"timestamp"`
+	// Direction is UP or DOWN
+	Direction string `gorm:"not null" json:"direction"`
+	// Sources are the agreeing sources, in ConfirmationSources order
+	Sources       []string `gorm:"type:jsonb;serializer:json" json:"sources"`
+	Required      int      `gorm:"not null" json:"required"`
+	OutOf         int      `gorm:"not null" json:"out_of"`
+	WindowMinutes int      `gorm:"not null" json:"window_minutes"`
+	// Evidence is the latest event of each agreeing source
+	Evidence []ConfirmationEvidence `gorm:"type:jsonb;serializer:json" json:"evidence"`
+}
+
+// ConfirmationEvidence is one source's event behind a confirmed move
+type ConfirmationEvidence struct {
+	Source    string    `json:"source"`
+	Timestamp time.Time `json:"timestamp"`
+	Detail    string    `json:"detail"`
+}
+
+// StoreConfirmedMoves replaces the moves stored for a ticker's day
+func StoreConfirmedMoves(db *gorm.DB, ticker, day string, moves []ConfirmedMove) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ticker = ? AND day = ?", ticker, day).Delete(&ConfirmedMove{}).Error; err != nil {
+			return err
+		}
+		if len(moves) == 0 {
+			return nil
+		}
+		return tx.Create(&moves).Error
+	})
+}
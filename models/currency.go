@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is assumed for tickers without synced reference data
+const DefaultCurrency = "USD"
+
+// minorCurrencies are quote currencies in hundredths of a major currency,
+// such as London listings quoted in pence
+var minorCurrencies = map[string]string{
+	"GBX":       "GBP",
+	"GBP_PENCE": "GBP",
+	"ZAC":       "ZAR",
+	"ILA":       "ILS",
+}
+
+// NormalizeCurrency returns the ISO code of the major currency a quote
+// currency name is in, and the factor turning its prices into that currency:
+// 0.01 for pence-quoted GBX, 1 for GBP. An empty name is DefaultCurrency.
+func NormalizeCurrency(name string) (string, float64) {
+	code := strings.ToUpper(strings.TrimSpace(name))
+	if code == "" {
+		return DefaultCurrency, 1
+	}
+	if major, ok := minorCurrencies[code]; ok {
+		return major, 0.01
+	}
+	return code, 1
+}
+
+// QuoteCurrency is how one ticker's prices are quoted
+type QuoteCurrency struct {
+	// Currency is the major currency prices are normalized to, e.g. GBP
+	Currency string
+	// Scale turns quoted prices into Currency, 1 unless quoted in a minor unit
+	Scale float64
+}
+
+// TickerCurrencies returns the quote currency of each ticker from the synced
+// reference data. Tickers without reference data are DefaultCurrency.
+func TickerCurrencies(db *gorm.DB, tickers []string) (map[string]QuoteCurrency, error) {
+	currencies := make(map[string]QuoteCurrency, len(tickers))
+	for _, ticker := range tickers {
+		currencies[ticker] = QuoteCurrency{Currency: DefaultCurrency, Scale: 1}
+	}
+	if len(tickers) == 0 || db == nil {
+		return currencies, nil
+	}
+
+	var refs []Ticker
+	if err := db.Select("ticker", "currency_name").Where("ticker IN ?", tickers).Find(&refs).Error; err != nil {
+		return currencies, err
+	}
+	for _, ref := range refs {
+		code, scale := NormalizeCurrency(ref.CurrencyName)
+		currencies[ref.Ticker] = QuoteCurrency{Currency: code, Scale: scale}
+	}
+	return currencies, nil
+}
+
+// TickerCurrency is TickerCurrencies for one ticker, DefaultCurrency when the
+// reference data cannot be read
+func TickerCurrency(db *gorm.DB, ticker string) QuoteCurrency {
+	currencies, _ := TickerCurrencies(db, []string{ticker})
+	return currencies[ticker]
+}
@@ -1,12 +1,16 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -57,6 +61,29 @@ func GetConnectionPoolConfig() ConnectionPoolConfig {
 	return config
 }
 
+// sqliteScheme prefixes a DATABASE_URL served by SQLite instead of Postgres
+const sqliteScheme = "sqlite://"
+
+// IsSQLite reports whether db is a SQLite database
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// openDialector picks the driver for dsn. sqlite://path opens a SQLite
+// database file (sqlite://:memory: an in-memory one) for local development
+// and tests without Postgres; any other DSN is Postgres.
+func openDialector(dsn string) gorm.Dialector {
+	path, ok := strings.CutPrefix(dsn, sqliteScheme)
+	if !ok {
+		return postgres.Open(dsn)
+	}
+	// Wait for other connections' locks instead of failing
+	if path != ":memory:" && !strings.Contains(path, "?") {
+		path += "?_busy_timeout=5000&_journal_mode=WAL"
+	}
+	return sqlite.Open(path)
+}
+
 // InitDatabase initializes the database connection with connection pooling
 func InitDatabase(dsn string) (*gorm.DB, error) {
 	if dsn == "" {
@@ -69,7 +96,7 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	}
 
 	// Open database connection
-	db, err := gorm.Open(postgres.Open(dsn), config)
+	db, err := gorm.Open(openDialector(dsn), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -86,6 +113,12 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(poolConfig.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(poolConfig.ConnMaxLifetime)
 	sqlDB.SetConnMaxIdleTime(poolConfig.ConnMaxIdleTime)
+	// Every connection to :memory: would open a database of its own
+	if dsn == sqliteScheme+":memory:" {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
 
 	// Test connection
 	if err := sqlDB.Ping(); err != nil {
@@ -97,7 +130,74 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// CloseDatabase closes the read replica's connection pool, if one is
+// configured, and then the primary's, waiting for queries in progress
+func CloseDatabase(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+
+	var errs []error
+	if plugin, ok := db.Config.Plugins[replicaPlugin{}.Name()].(replicaPlugin); ok {
+		if pool, ok := plugin.pool.(io.Closer); ok {
+			if err := pool.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close read replica: %w", err))
+			}
+		}
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
 func runMigrations(db *gorm.DB) {
 	db.AutoMigrate(&TechnicalSignal{})
 	db.AutoMigrate(&DeepSearchRequest{})
+	db.AutoMigrate(&AnalysisPreset{})
+	db.AutoMigrate(&Bar{})
+	db.AutoMigrate(&Ticker{})
+	db.AutoMigrate(&AnalysisJob{})
+	db.AutoMigrate(&User{})
+	db.AutoMigrate(&APIKey{})
+	db.AutoMigrate(&EarningsEstimate{})
+	db.AutoMigrate(&NotificationChannel{})
+	db.AutoMigrate(&AlertRule{})
+	db.AutoMigrate(&Integration{})
+	db.AutoMigrate(&AlertDelivery{})
+	db.AutoMigrate(&ReportArtifact{})
+	db.AutoMigrate(&DecisionRule{})
+	db.AutoMigrate(&InstitutionalFootprint{})
+	db.AutoMigrate(&Strategy{})
+	db.AutoMigrate(&SignalPerformance{})
+	db.AutoMigrate(&SignalReturn{})
+	db.AutoMigrate(&AnalysisBar{})
+	db.AutoMigrate(&PolygonUsage{})
+	db.AutoMigrate(&MaxPainSnapshot{})
+	db.AutoMigrate(&PutCallRatio{})
+	db.AutoMigrate(&ImpliedVolatility{})
+	db.AutoMigrate(&BlockTradeSession{})
+	db.AutoMigrate(&BlockTrade{})
+	db.AutoMigrate(&BigMoneySample{})
+	db.AutoMigrate(&ConfirmedMove{})
+	db.AutoMigrate(&InsiderFiling{})
+	db.AutoMigrate(&InsiderTransaction{})
+	db.AutoMigrate(&FailToDeliver{})
+	db.AutoMigrate(&FailToDeliverPeriod{})
+	db.AutoMigrate(&PaperTrade{})
+	db.AutoMigrate(&BrokerAccount{})
+	db.AutoMigrate(&ExecutionRule{})
+	db.AutoMigrate(&BrokerOrder{})
+	db.AutoMigrate(&TradingHalt{})
+	db.AutoMigrate(&Transaction{})
+	db.AutoMigrate(&Position{})
+
+	// Tag filters use array containment, which only a GIN index serves
+	if !IsSQLite(db) {
+		db.Exec("CREATE INDEX IF NOT EXISTS idx_technical_signals_tags ON technical_signals USING gin (tags)")
+	}
 }
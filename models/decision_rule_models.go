@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// DecisionRule is one row of the technicals decision table. Enabled rules are
+// evaluated in ascending Priority and the first whose conditions all hold
+// decides; HOLD is the fallback when none do.
+type DecisionRule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `gorm:"not null;uniqueIndex" json:"name"`
+	Priority  int       `gorm:"not null;default:0;index" json:"priority"`
+	Decision  string    `gorm:"not null" json:"decision"`
+	// Reason is reported with the decision, e.g. "Cheap price, oversold, bullish momentum"
+	Reason     string              `gorm:"default:''" json:"reason"`
+	Conditions []DecisionCondition `gorm:"type:jsonb;serializer:json;not null" json:"conditions"`
+	Enabled    bool                `gorm:"not null;default:true" json:"enabled"`
+}
+
+// DecisionCondition compares an input against a constant Value or, when Ref is
+// set, against another input scaled by Factor (1 if zero):
+// {"input": "atr", "operator": ">", "ref": "prev_atr", "factor": 1.5}
+type DecisionCondition struct {
+	Input    string  `json:"input"`
+	Operator string  `json:"operator"`
+	Value    float64 `json:"value,omitempty"`
+	Ref      string  `json:"ref,omitempty"`
+	Factor   float64 `json:"factor,omitempty"`
+}
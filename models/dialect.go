@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ILike is the case-insensitive LIKE operator: ILIKE on Postgres, LIKE on
+// SQLite, whose LIKE already ignores ASCII case
+func ILike(db *gorm.DB) string {
+	if IsSQLite(db) {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// LatestPer keeps the first row of each partition of query in order, as
+// Postgres's DISTINCT ON does, with a window function SQLite also runs.
+// partition and order are SQL lists, e.g. "ticker" and "created_at DESC".
+// The result selects from the subquery "latest"; narrow it with Select and
+// order it as needed. A query marked by ReadReplica stays on the replica.
+func LatestPer(query *gorm.DB, partition, order string) *gorm.DB {
+	ranked := query.Session(&gorm.Session{}).
+		Select(fmt.Sprintf("*, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS latest_row", partition, order))
+	latest := query.Session(&gorm.Session{NewDB: true})
+	if replica, ok := query.Get(replicaKey); ok {
+		latest = latest.Set(replicaKey, replica)
+	}
+	return latest.Table("(?) AS latest", ranked).Where("latest_row = 1")
+}
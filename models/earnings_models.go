@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EarningsEstimate is one observed version of a Benzinga earnings record.
+// A new row is stored whenever the provider's updated timestamp changes, so
+// the rows for a ticker/report date form its estimate revision history.
+type EarningsEstimate struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt        time.Time `json:"observed_at"`
+	Ticker           string    `gorm:"not null;uniqueIndex:idx_earnings_estimate_version,priority:1" json:"ticker"`
+	ReportDate       string    `gorm:"not null;uniqueIndex:idx_earnings_estimate_version,priority:2" json:"report_date"`
+	ProviderUpdated  string    `gorm:"not null;default:'';uniqueIndex:idx_earnings_estimate_version,priority:3" json:"provider_updated"`
+	ReportTime       string    `gorm:"default:''" json:"report_time,omitempty"`
+	Importance       int       `gorm:"default:0" json:"importance"`
+	EstimatedEPS     *float64  `json:"estimated_eps,omitempty"`
+	EstimatedRevenue *float64  `json:"estimated_revenue,omitempty"`
+	ActualEPS        *float64  `json:"actual_eps,omitempty"`
+	ActualRevenue    *float64  `json:"actual_revenue,omitempty"`
+}
+
+// BigMoneySample is one intraday reading of an earnings reporter's large-trade
+// flow on its report date, from the open up to Timestamp. Successive samples
+// show how NetBigMoneyFlow built up into the close.
+type BigMoneySample struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	Ticker     string    `gorm:"not null;uniqueIndex:idx_big_money_samples_key,priority:1" json:"ticker"`
+	ReportDate string    `gorm:"not null;uniqueIndex:idx_big_money_samples_key,priority:2" json:"report_date"`
+	Timestamp  time.Time `gorm:"not null;uniqueIndex:idx_big_money_samples_key,priority:3" json:"timestamp"`
+	// ReportTime is the Benzinga report time, as on the estimate
+	ReportTime            string  `gorm:"default:''" json:"report_time,omitempty"`
+	Direction             string  `gorm:"not null" json:"direction"`
+	NetBigMoneyFlow       float64 `gorm:"default:0" json:"net_big_money_flow"`
+	BuyerInitiatedVolume  float64 `gorm:"default:0" json:"buyer_initiated_volume"`
+	SellerInitiatedVolume float64 `gorm:"default:0" json:"seller_initiated_volume"`
+	LargeTradesCount      int     `gorm:"default:0" json:"large_trades_count"`
+	TotalTrades           int     `gorm:"default:0" json:"total_trades"`
+}
+
+// UpsertBigMoneySamples stores samples, replacing a ticker's sample taken at the same time
+func UpsertBigMoneySamples(db *gorm.DB, samples []BigMoneySample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ticker"}, {Name: "report_date"}, {Name: "timestamp"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"report_time", "direction", "net_big_money_flow", "buyer_initiated_volume", "seller_initiated_volume",
+			"large_trades_count", "total_trades",
+		}),
+	}).Create(&samples).Error
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BarFeatures are a bar's OHLCV with the indicators an analysis computed for
+// it, in the analysis currency
+type BarFeatures struct {
+	Open         float64 `gorm:"not null" json:"open"`
+	High         float64 `gorm:"not null" json:"high"`
+	Low          float64 `gorm:"not null" json:"low"`
+	Close        float64 `gorm:"not null" json:"close"`
+	Volume       float64 `gorm:"not null" json:"volume"`
+	Transactions int64   `gorm:"default:0" json:"transactions"`
+	VWAP         float64 `gorm:"default:0" json:"vwap"`
+
+	CumulativeVWAP      float64    `gorm:"default:0" json:"cumulative_vwap"`
+	AnchoredVWAP        float64    `gorm:"default:0" json:"anchored_vwap"`
+	AnchoredVWAPStart   *time.Time `json:"anchored_vwap_start"`
+	VolumeZScore        float64    `gorm:"default:0" json:"volume_z_score"`
+	ATR                 float64    `gorm:"default:0" json:"atr"`
+	BollingerMiddle     float64    `gorm:"default:0" json:"bollinger_middle"`
+	BollingerUpper      float64    `gorm:"default:0" json:"bollinger_upper"`
+	BollingerLower      float64    `gorm:"default:0" json:"bollinger_lower"`
+	BollingerWidth      float64    `gorm:"default:0" json:"bollinger_width"`
+	BollingerSqueeze    bool       `gorm:"default:false" json:"bollinger_squeeze"`
+	ADX                 float64    `gorm:"default:0" json:"adx"`
+	PlusDI              float64    `gorm:"default:0" json:"plus_di"`
+	MinusDI             float64    `gorm:"default:0" json:"minus_di"`
+	OBV                 float64    `gorm:"default:0" json:"obv"`
+	OBVDivergence       int        `gorm:"default:0" json:"obv_divergence"`
+	SuperTrend          float64    `gorm:"default:0" json:"super_trend"`
+	SuperTrendDirection int        `gorm:"default:0" json:"super_trend_direction"`
+	YearHigh            float64    `gorm:"default:0" json:"year_high"`
+	YearLow             float64    `gorm:"default:0" json:"year_low"`
+
+	Doji              bool        `gorm:"default:false" json:"doji"`
+	BearishEngulfing  bool        `gorm:"default:false" json:"bearish_engulfing"`
+	BullishEngulfing  bool        `gorm:"default:false" json:"bullish_engulfing"`
+	InstitutionalFlow bool        `gorm:"default:false" json:"institutional_flow"`
+	ConfirmedMove     int         `gorm:"default:0" json:"confirmed_move"`
+	ConfirmedSources  StringArray `json:"confirmed_sources"`
+	InsiderBuyers     int         `gorm:"default:0" json:"insider_buyers"`
+	InsiderBuyValue   float64     `gorm:"default:0" json:"insider_buy_value"`
+	FTDShares         int64       `gorm:"default:0" json:"ftd_shares"`
+	FTDZScore         float64     `gorm:"default:0" json:"ftd_z_score"`
+}
+
+// AnalysisBar is one bar of a stored analysis with the features its signals
+// were computed from, warm-up bars before the window included, so the
+// analysis can be audited and re-evaluated as it ran
+type AnalysisBar struct {
+	AnalysisID uint      `gorm:"primaryKey;autoIncrement:false" json:"analysis_id"`
+	Timestamp  time.Time `gorm:"primaryKey" json:"timestamp"`
+	// Warmup marks bars before the analysis window, fetched so indicators
+	// are warmed up at its start
+	Warmup bool `gorm:"not null;default:false" json:"warmup"`
+	BarFeatures
+}
+
+// FindAnalysisBars returns the stored bars of an analysis, oldest first
+func FindAnalysisBars(db *gorm.DB, analysisID uint) ([]AnalysisBar, error) {
+	var bars []AnalysisBar
+	err := db.Where("analysis_id = ?", analysisID).Order("timestamp").Find(&bars).Error
+	return bars, err
+}
+
+// EnhancedBar is a bar as the latest analysis covering it saw it. Rows are
+// keyed by ticker, aggregation and timestamp rather than analysis, so the
+// table only exists with the Timescale bar store, where it is a hypertable
+// partitioned by timestamp (see UseTimescale).
+type EnhancedBar struct {
+	Ticker     string    `gorm:"primaryKey" json:"ticker"`
+	TimeSpan   string    `gorm:"primaryKey" json:"timespan"`
+	Multiplier int       `gorm:"primaryKey" json:"multiplier"`
+	Timestamp  time.Time `gorm:"primaryKey" json:"timestamp"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Currency   string    `gorm:"not null;default:'USD'" json:"currency"`
+	BarFeatures
+}
+
+// UpsertEnhancedBars writes enhanced bars, replacing rows with the same
+// ticker, aggregation and timestamp
+func UpsertEnhancedBars(db *gorm.DB, bars []EnhancedBar) error {
+	if len(bars) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ticker"}, {Name: "time_span"}, {Name: "multiplier"}, {Name: "timestamp"}},
+		UpdateAll: true,
+	}).CreateInBatches(bars, barUpsertBatchSize).Error
+}
+
+// FindEnhancedBars returns the stored enhanced bars of an aggregation in
+// [from, to), oldest first
+func FindEnhancedBars(db *gorm.DB, ticker, timeSpan string, multiplier int, from, to time.Time) ([]EnhancedBar, error) {
+	var bars []EnhancedBar
+	err := db.Where("ticker = ? AND time_span = ? AND multiplier = ? AND timestamp >= ? AND timestamp < ?", ticker, timeSpan, multiplier, from, to).
+		Order("timestamp").
+		Find(&bars).Error
+	return bars, err
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// DecisionExplanation records why an analysis reached its final decision:
+// every signal's vote and weight, and the thresholds behind each signal
+type DecisionExplanation struct {
+	// Method is how the decision was reached; signal_score is the highest
+	// weighted score, signal_vote (older analyses) a majority of signal votes
+	Method     string         `json:"method"`
+	Decision   string         `json:"decision"`
+	Confidence float64        `json:"confidence"`
+	Votes      map[string]int `json:"votes"`
+	// Scores totals the signal scores behind each vote
+	Scores map[string]float64 `json:"scores,omitempty"`
+	// Summary is a one-line justification, e.g. for emails and summaries
+	Summary       string               `json:"summary"`
+	Contributions []SignalContribution `json:"contributions"`
+}
+
+// SignalContribution is one signal's part in a decision
+type SignalContribution struct {
+	Time      time.Time `json:"time"`
+	Signal    string    `json:"signal"`
+	Direction string    `json:"direction"`
+	// Kind is the signal type that sets its weight and confidence; Score is
+	// their product, and Weight the signal's share of the total score
+	Kind           string  `json:"kind,omitempty"`
+	KindWeight     float64 `json:"kind_weight,omitempty"`
+	KindConfidence float64 `json:"kind_confidence,omitempty"`
+	Score          float64 `json:"score,omitempty"`
+	Vote           string  `json:"vote"`
+	Weight         float64 `json:"weight"`
+	// Supports is set when the signal voted for the final decision
+	Supports  bool                `json:"supports"`
+	Crossings []ThresholdCrossing `json:"crossings,omitempty"`
+}
+
+// ThresholdCrossing is a condition that made a signal fire: Metric compared
+// with Threshold, and Margin = Value - Threshold
+type ThresholdCrossing struct {
+	Metric     string  `json:"metric"`
+	Comparison string  `json:"comparison"`
+	Value      float64 `json:"value"`
+	Threshold  float64 `json:"threshold"`
+	Margin     float64 `json:"margin"`
+}
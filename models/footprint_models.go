@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Footprint biases
+const (
+	FootprintAccumulation = "ACCUMULATION"
+	FootprintDistribution = "DISTRIBUTION"
+	FootprintNeutral      = "NEUTRAL"
+)
+
+// InstitutionalFootprint combines a ticker's institutional activity over one
+// market week (Monday to Friday): the institutional flow signals of stored
+// analyses, block trades and the off-exchange (dark pool) share of volume
+type InstitutionalFootprint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_footprint_ticker_week,priority:1" json:"ticker"`
+	// WeekStart is the Monday of the week (YYYY-MM-DD), WeekEnd its Friday
+	WeekStart string `gorm:"not null;uniqueIndex:idx_footprint_ticker_week,priority:2" json:"week_start"`
+	WeekEnd   string `gorm:"not null" json:"week_end"`
+	Sessions  int    `gorm:"not null;default:0" json:"sessions"`
+
+	// Institutional flow signals from analyses of the ticker, deduplicated across overlapping analyses
+	Analyses        int `gorm:"not null;default:0" json:"analyses"`
+	BuyingSignals   int `gorm:"not null;default:0" json:"buying_signals"`
+	SellingSignals  int `gorm:"not null;default:0" json:"selling_signals"`
+	ActivitySignals int `gorm:"not null;default:0" json:"activity_signals"`
+
+	TotalVolume       float64 `gorm:"default:0" json:"total_volume"`
+	BlockTrades       int     `gorm:"default:0" json:"block_trades"`
+	BlockVolume       float64 `gorm:"default:0" json:"block_volume"`
+	BlockBuyVolume    float64 `gorm:"default:0" json:"block_buy_volume"`
+	BlockSellVolume   float64 `gorm:"default:0" json:"block_sell_volume"`
+	NetBlockFlow      float64 `gorm:"default:0" json:"net_block_flow"`
+	OffExchangeVolume float64 `gorm:"default:0" json:"off_exchange_volume"`
+	DarkPoolShare     float64 `gorm:"default:0" json:"dark_pool_share"`
+
+	// Bias is ACCUMULATION, DISTRIBUTION or NEUTRAL; Score (-1 to 1) is the
+	// mean of the signal and block imbalances behind it
+	Bias  string  `gorm:"not null;default:'NEUTRAL'" json:"bias"`
+	Score float64 `gorm:"default:0" json:"score"`
+
+	Days []FootprintDay `gorm:"type:jsonb;serializer:json" json:"days"`
+}
+
+// FootprintDay is one session of a footprint
+type FootprintDay struct {
+	Date              string  `json:"date"`
+	BuyingSignals     int     `json:"buying_signals"`
+	SellingSignals    int     `json:"selling_signals"`
+	TotalVolume       float64 `json:"total_volume"`
+	BlockTrades       int     `json:"block_trades"`
+	BlockVolume       float64 `json:"block_volume"`
+	NetBlockFlow      float64 `json:"net_block_flow"`
+	OffExchangeVolume float64 `json:"off_exchange_volume"`
+	DarkPoolShare     float64 `json:"dark_pool_share"`
+	// Error is set when the session's trades could not be loaded
+	Error string `json:"error,omitempty"`
+}
+
+// UpsertFootprint stores a footprint, replacing the ticker's footprint for the same week
+func UpsertFootprint(db *gorm.DB, footprint *InstitutionalFootprint) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ticker"}, {Name: "week_start"}},
+		DoUpdates: clause.AssignmentColumns(footprintColumns),
+	}).Create(footprint).Error
+}
+
+// footprintColumns are the columns a rebuilt footprint replaces
+var footprintColumns = []string{
+	"updated_at", "week_end", "sessions", "analyses", "buying_signals", "selling_signals", "activity_signals",
+	"total_volume", "block_trades", "block_volume", "block_buy_volume", "block_sell_volume", "net_block_flow",
+	"off_exchange_volume", "dark_pool_share", "bias", "score", "days",
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// failToDeliverBatchSize keeps each insert of a fails-to-deliver file under
+// Postgres's parameter limit
+const failToDeliverBatchSize = 2000
+
+// FailToDeliver is the number of shares of a ticker that failed to deliver
+// as of one settlement date, from the SEC fails-to-deliver data
+type FailToDeliver struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_fails_to_deliver_key,priority:1" json:"ticker"`
+	// SettlementDate is YYYY-MM-DD
+	SettlementDate string `gorm:"not null;uniqueIndex:idx_fails_to_deliver_key,priority:2" json:"settlement_date"`
+	CUSIP          string `gorm:"default:''" json:"cusip"`
+	Quantity       int64  `gorm:"not null" json:"quantity"`
+	// Price is the previous day's close and Value the failed shares at it,
+	// both 0 when the SEC has no price
+	Price float64 `gorm:"default:0" json:"price"`
+	Value float64 `gorm:"default:0" json:"value"`
+}
+
+// FailToDeliverPeriod records a half-month fails-to-deliver file that was
+// loaded, e.g. 202505a, so it is not downloaded again
+type FailToDeliverPeriod struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Period    string    `gorm:"not null;uniqueIndex" json:"period"`
+	Rows      int       `gorm:"default:0" json:"rows"`
+}
+
+// StoreFailsToDeliver writes a period's rows, replacing stored rows with the
+// same ticker and settlement date, and records the period as loaded
+func StoreFailsToDeliver(db *gorm.DB, period string, fails []FailToDeliver) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if len(fails) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "ticker"}, {Name: "settlement_date"}},
+				DoUpdates: clause.AssignmentColumns([]string{"cusip", "quantity", "price", "value"}),
+			}).CreateInBatches(fails, failToDeliverBatchSize).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "period"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rows", "created_at"}),
+		}).Create(&FailToDeliverPeriod{Period: period, Rows: len(fails)}).Error
+	})
+}
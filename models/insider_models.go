@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Insider transaction sides
+const (
+	InsiderBuy  = "BUY"
+	InsiderSell = "SELL"
+)
+
+// InsiderFiling records a Form 4 that was read, so it is not fetched again
+// whether or not it reported an open market buy or sell
+type InsiderFiling struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Accession string    `gorm:"not null;uniqueIndex" json:"accession"`
+	Ticker    string    `gorm:"not null;index" json:"ticker"`
+	// FiledAt is the EDGAR filing date, YYYY-MM-DD
+	FiledAt      string `gorm:"not null" json:"filed_at"`
+	Transactions int    `gorm:"default:0" json:"transactions"`
+}
+
+// InsiderTransaction is an open market purchase (P) or sale (S) reported on
+// an insider's Form 4
+type InsiderTransaction struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Ticker    string    `gorm:"not null;index:idx_insider_transactions_ticker_date,priority:1" json:"ticker"`
+	Accession string    `gorm:"not null;uniqueIndex:idx_insider_transactions_key,priority:1" json:"accession"`
+	// Seq is the transaction's position in its filing
+	Seq int `gorm:"not null;uniqueIndex:idx_insider_transactions_key,priority:2" json:"-"`
+	// FiledAt is when the market learned of the transaction, YYYY-MM-DD
+	FiledAt string `gorm:"not null" json:"filed_at"`
+	// TransactionDate is YYYY-MM-DD
+	TransactionDate string `gorm:"not null;index:idx_insider_transactions_ticker_date,priority:2" json:"transaction_date"`
+	OwnerCIK        string `gorm:"default:''" json:"owner_cik"`
+	OwnerName       string `gorm:"not null" json:"owner_name"`
+	// Relationship lists the insider's roles, e.g. "Director, CEO"
+	Relationship     string  `gorm:"default:''" json:"relationship,omitempty"`
+	Code             string  `gorm:"not null" json:"code"`
+	Side             string  `gorm:"not null" json:"side"`
+	Shares           float64 `gorm:"default:0" json:"shares"`
+	Price            float64 `gorm:"default:0" json:"price"`
+	Value            float64 `gorm:"default:0" json:"value"`
+	SharesOwnedAfter float64 `gorm:"default:0" json:"shares_owned_after"`
+}
+
+// StoreInsiderFiling records a filing with its transactions, keeping what
+// was stored for a filing read before
+func StoreInsiderFiling(db *gorm.DB, filing *InsiderFiling, transactions []InsiderTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(filing).Error; err != nil {
+			return err
+		}
+		if len(transactions) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&transactions).Error
+	})
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImpliedVolatility is one market day's at-the-money implied volatility of a
+// ticker, read from the options expiry nearest 30 days out. Stored daily, it
+// is the history IV rank and percentile are measured against.
+type ImpliedVolatility struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_implied_volatilities_ticker_day,priority:1" json:"ticker"`
+	// Day is the market day, YYYY-MM-DD
+	Day string `gorm:"not null;uniqueIndex:idx_implied_volatilities_ticker_day,priority:2" json:"day"`
+	// IV is the mean implied volatility of the call and put at the strike
+	// nearest the underlying price, annualised (0.25 is 25%)
+	IV              float64 `gorm:"not null" json:"iv"`
+	Expiration      string  `gorm:"not null" json:"expiration"`
+	Strike          float64 `gorm:"default:0" json:"strike"`
+	UnderlyingPrice float64 `gorm:"default:0" json:"underlying_price"`
+}
+
+// IVRank places a day's implied volatility within the trailing year of
+// stored daily readings
+type IVRank struct {
+	Day string  `json:"day"`
+	IV  float64 `json:"iv"`
+	// Rank is where IV sits between the year's low (0) and high (100)
+	Rank float64 `json:"rank"`
+	// Percentile is the share of the year's earlier days with lower IV, 0-100
+	Percentile float64 `json:"percentile"`
+	High       float64 `json:"high"`
+	Low        float64 `json:"low"`
+	// Days is how many daily readings the year holds, the day's included
+	Days int `json:"days"`
+}
+
+// UpsertImpliedVolatility stores a reading, replacing the ticker's reading of the same day
+func UpsertImpliedVolatility(db *gorm.DB, iv *ImpliedVolatility) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ticker"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "iv", "expiration", "strike", "underlying_price"}),
+	}).Create(iv).Error
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Analysis job statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// AnalysisJob tracks an asynchronously executed deepsearch analysis
+type AnalysisJob struct {
+	ID                  uint `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Status              string `gorm:"not null;default:'pending';index"`
+	Ticker              string `gorm:"not null"`
+	StartDuration       string `gorm:"not null"`
+	EndDuration         string `gorm:"not null"`
+	TimeSpan            string `gorm:"not null"`
+	Multiplier          int    `gorm:"not null"`
+	UserId              string `gorm:"not null;index"`
+	DeepSearchRequestID uint
+	Attempts            int    `gorm:"not null;default:0"`
+	Error               string `gorm:"type:text;default:''"`
+	// ResultID is the TechnicalSignal produced by a completed job
+	ResultID   *uint
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	// TraceParent links the job's spans to the request that queued it
+	TraceParent string `gorm:"default:''" json:"-"`
+	// VWAPAnchor is "", "session", "earnings" or an RFC3339 timestamp
+	VWAPAnchor string `gorm:"not null;default:''"`
+	// ATRPeriod is the Wilder ATR period; 0 uses the default of 14
+	ATRPeriod int `gorm:"not null;default:14"`
+	// StrategyID selects a user strategy's signal rules; 0 uses the built-in signals
+	StrategyID uint `gorm:"not null;default:0"`
+}
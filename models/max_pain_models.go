@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxPainSnapshot is one market day's max pain and open interest by strike
+// for one expiry of a ticker's options. Stored daily, the snapshots of an
+// expiry show how max pain migrates into expiration.
+type MaxPainSnapshot struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_max_pain_ticker_expiry_day,priority:1" json:"ticker"`
+	// Expiration and Day are YYYY-MM-DD; Day is the market day the open interest was read
+	Expiration      string  `gorm:"not null;uniqueIndex:idx_max_pain_ticker_expiry_day,priority:2" json:"expiration"`
+	Day             string  `gorm:"not null;uniqueIndex:idx_max_pain_ticker_expiry_day,priority:3" json:"day"`
+	UnderlyingPrice float64 `gorm:"default:0" json:"underlying_price"`
+	// MaxPain is the strike at which the expiry's open options pay their
+	// holders the least
+	MaxPain          float64 `gorm:"not null" json:"max_pain"`
+	CallOpenInterest float64 `gorm:"default:0" json:"call_open_interest"`
+	PutOpenInterest  float64 `gorm:"default:0" json:"put_open_interest"`
+	// PutCallOIRatio is puts over calls, 0 without calls
+	PutCallOIRatio float64              `gorm:"default:0" json:"put_call_oi_ratio"`
+	Strikes        []StrikeOpenInterest `gorm:"type:jsonb;serializer:json" json:"strikes,omitempty"`
+}
+
+// StrikeOpenInterest is the open interest of one strike of an expiry
+type StrikeOpenInterest struct {
+	Strike           float64 `json:"strike"`
+	CallOpenInterest float64 `json:"call_open_interest"`
+	PutOpenInterest  float64 `json:"put_open_interest"`
+	// Payout is what the expiry's open options pay their holders, in dollars,
+	// if the underlying settles at this strike
+	Payout float64 `json:"payout"`
+}
+
+// UpsertMaxPain stores snapshots, replacing a ticker's snapshot of the same
+// expiry and day
+func UpsertMaxPain(db *gorm.DB, snapshots []MaxPainSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ticker"}, {Name: "expiration"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "underlying_price", "max_pain", "call_open_interest", "put_open_interest", "put_call_oi_ratio", "strikes",
+		}),
+	}).Create(&snapshots).Error
+}
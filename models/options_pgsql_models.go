@@ -3,28 +3,89 @@ package models
 import (
 	"time"
 
-	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 type TechnicalSignal struct {
-	ID                uint `gorm:"primaryKey"`
-	CreatedAt         time.Time
+	ID                uint      `gorm:"primaryKey"`
+	CreatedAt         time.Time `gorm:"index:idx_technical_signals_ticker_created_at,priority:2;index:idx_technical_signals_created_at;index:idx_technical_signals_user_ticker_created_at,priority:3"`
 	UpdatedAt         time.Time
 	PolyStartDuration string `gorm:"not null;"`
 	PolyEndDuration   string `gorm:"not null;"`
 	PolyTimeSpan      string `gorm:"not null;"`
 	PolyMultiplier    int    `gorm:"not null;"`
+	// VWAPAnchor is the anchored VWAP anchor the analysis ran with, if any
+	VWAPAnchor string `gorm:"not null;default:''"`
+	// ATRPeriod is the Wilder ATR period the analysis ran with
+	ATRPeriod int `gorm:"not null;default:14"`
+	// StrategyID is the user strategy whose rules produced the signals, 0 for the built-in signals
+	StrategyID uint `gorm:"not null;default:0"`
+	// AlgoVersion is the signal and decision logic version the analysis ran
+	// with; 0 for analyses stored before versioning
+	AlgoVersion int `gorm:"not null;default:0"`
 
 	StartDate    time.Time `gorm:"not null;"`
 	EndDate      time.Time `gorm:"not null;"`
 	Interval     string    `gorm:"not null;"`
 	WindowSize   int       `gorm:"not null;"`
-	Ticker       string    `gorm:"not null;"`
+	Ticker       string    `gorm:"not null;index:idx_technical_signals_ticker_created_at,priority:1;index:idx_technical_signals_user_ticker_created_at,priority:2"`
 	AnalysisType string    `gorm:"not null;"`
 
-	Signals       pq.StringArray `gorm:"type:text[];not null"`
-	FinalDecision string         `gorm:"default ''"`
-	UserId        string         `gorm:"not null"`
+	Signals StringArray `gorm:"not null"`
+	// SignalTimestamps holds each signal's bar time (Unix ms), parallel to Signals
+	SignalTimestamps Int64Array
+	FinalDecision    string  `gorm:"default ''"`
+	Confidence       float64 `gorm:"default:0"`
+	// UserId owns the analysis; reads are scoped to it through the
+	// (user_id, ticker, created_at) index
+	UserId string `gorm:"not null;index:idx_technical_signals_user_ticker_created_at,priority:1"`
+
+	// Window metrics, used by alert rules
+	LastClose       float64 `gorm:"default:0"`
+	MaxVolumeZScore float64 `gorm:"default:0"`
+
+	// TrailingStop is the SuperTrend level at the last bar: an exit for longs
+	// when TrailingStopSide is "long", for shorts when "short"; 0 if unavailable
+	TrailingStop     float64 `gorm:"default:0"`
+	TrailingStopSide string  `gorm:"not null;default:''"`
+	// ATR is the Wilder ATR at the last bar, which sizes paper trade stops;
+	// 0 for analyses stored before it
+	ATR float64 `gorm:"default:0"`
+
+	// Currency is the ISO code of the currency LastClose, TrailingStop and
+	// the signals' prices are in; prices quoted in a minor unit such as GBX
+	// pence are normalized to the major one
+	Currency string `gorm:"not null;default:'USD'"`
+
+	// Benchmarks compares the close and large prints with the window's VWAP
+	// and TWAP; nil for analyses stored before it
+	Benchmarks *ExecutionBenchmarks `gorm:"type:jsonb;serializer:json"`
+
+	// YearRange places the last close within the 52-week range; nil for
+	// analyses stored before it or when daily bars were unavailable
+	YearRange *YearRange `gorm:"type:jsonb;serializer:json"`
+
+	// IVRank is the ticker's implied volatility rank on the last bar's day;
+	// nil without enough stored IV history or for analyses stored before it
+	IVRank *IVRank `gorm:"type:jsonb;serializer:json"`
+
+	// Explanation breaks down the final decision; nil for analyses stored before it
+	Explanation *DecisionExplanation `gorm:"type:jsonb;serializer:json"`
+
+	// PerformanceScoredAt is when the signals' next-bar hits were recorded as
+	// SignalPerformance rows; nil until the run or the backfill job scores them
+	PerformanceScoredAt *time.Time
+
+	// Analyst annotations
+	Tags  StringArray `gorm:"not null;default:'{}'"`
+	Notes string      `gorm:"type:text;default:''"`
+
+	// ArchivedAt is set while the analysis is archived: it is left out of
+	// history listings but still served by ID
+	ArchivedAt *time.Time `gorm:"index"`
+	// DeletedAt soft-deletes the analysis: queries skip it, but the row is
+	// kept for audit and can be restored
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type DeepSearchRequest struct {
@@ -34,5 +95,7 @@ type DeepSearchRequest struct {
 	StartDate string `gorm:"not null;"`
 	EndDate   string `gorm:"not null;"`
 	Ticker    string `gorm:"not null;"`
-	UserId    string `gorm:"not null;"`
+	UserId    string `gorm:"not null;index"`
+	// DeletedAt is set when retention archives the request
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Paper trade sides
+const (
+	PaperSideLong  = "long"
+	PaperSideShort = "short"
+)
+
+// Paper trade statuses
+const (
+	PaperTradeOpen   = "open"
+	PaperTradeClosed = "closed"
+)
+
+// Paper trade exit reasons
+const (
+	// PaperExitStop closes a trade whose price crossed its trailing stop
+	PaperExitStop = "stop"
+	// PaperExitReversal closes a trade when an analysis decides the other way
+	PaperExitReversal = "reversal"
+)
+
+// PaperTrade is a simulated position a user's analysis opened: long on a BUY
+// decision, short on a SELL. A user holds at most one open trade per ticker.
+// The stop trails the best price since entry by StopATRMultiple times the
+// ATR at entry. Prices are in Currency.
+type PaperTrade struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserId    string    `gorm:"not null;index:idx_paper_trades_user_status,priority:1;uniqueIndex:idx_paper_trades_open,priority:1,where:status = 'open'" json:"-"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_paper_trades_open,priority:2" json:"ticker"`
+	Side      string    `gorm:"not null" json:"side"`
+	Status    string    `gorm:"not null;default:'open';index:idx_paper_trades_user_status,priority:2" json:"status"`
+	Currency  string    `gorm:"not null;default:'USD'" json:"currency"`
+	// Quantity is the notional the trade was opened with over the entry
+	// price, so it can be fractional
+	Quantity float64 `gorm:"not null" json:"quantity"`
+
+	// EntryAnalysisID is the analysis whose decision opened the trade, and
+	// EntryAt and EntryPrice its last bar
+	EntryAnalysisID uint      `gorm:"not null;index" json:"entry_analysis_id"`
+	EntryAt         time.Time `gorm:"not null" json:"entry_at"`
+	EntryPrice      float64   `gorm:"not null" json:"entry_price"`
+
+	ATR             float64 `gorm:"not null" json:"atr"`
+	StopATRMultiple float64 `gorm:"not null" json:"stop_atr_multiple"`
+	Stop            float64 `gorm:"not null" json:"stop"`
+	// BestPrice is the highest price marked since entry for longs, the
+	// lowest for shorts
+	BestPrice float64 `gorm:"not null" json:"best_price"`
+
+	// LastPrice is the latest mark, from a later analysis or the quote
+	// cache, and UnrealizedPnL the open trade's profit at it
+	LastPrice     float64   `gorm:"not null" json:"last_price"`
+	MarkedAt      time.Time `gorm:"not null" json:"marked_at"`
+	UnrealizedPnL float64   `gorm:"column:unrealized_pnl;not null;default:0" json:"unrealized_pnl"`
+
+	// Exit fields are set once the trade is closed
+	ExitAnalysisID *uint      `json:"exit_analysis_id,omitempty"`
+	ExitAt         *time.Time `json:"exit_at,omitempty"`
+	ExitPrice      float64    `gorm:"not null;default:0" json:"exit_price,omitempty"`
+	ExitReason     string     `gorm:"not null;default:''" json:"exit_reason,omitempty"`
+	RealizedPnL    float64    `gorm:"column:realized_pnl;not null;default:0" json:"realized_pnl"`
+}
+
+// PnL is the trade's profit if closed at price
+func (t *PaperTrade) PnL(price float64) float64 {
+	if t.Side == PaperSideShort {
+		return (t.EntryPrice - price) * t.Quantity
+	}
+	return (price - t.EntryPrice) * t.Quantity
+}
+
+// StopHit reports whether price is at or beyond the trade's stop
+func (t *PaperTrade) StopHit(price float64) bool {
+	if t.Side == PaperSideShort {
+		return price >= t.Stop
+	}
+	return price <= t.Stop
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// SignalPerformance is how one kind of signal fared in one analysis: how many
+// of its directional signals the next bar confirmed. Rows are kept per
+// analysis, so re-scoring an analysis replaces its rows and per-ticker hit
+// rates are sums across analyses.
+type SignalPerformance struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	AnalysisID uint      `gorm:"not null;uniqueIndex:idx_signal_performance_kind,priority:1" json:"analysis_id"`
+	Ticker     string    `gorm:"not null;index" json:"ticker"`
+	// Kind is the signal type, e.g. "Bollinger Breakout", and Direction its CALL, PUT, UP or DOWN
+	Kind      string `gorm:"not null;uniqueIndex:idx_signal_performance_kind,priority:2" json:"kind"`
+	Direction string `gorm:"not null;uniqueIndex:idx_signal_performance_kind,priority:3" json:"direction"`
+	Signals   int    `gorm:"not null;default:0" json:"signals"`
+	Wins      int    `gorm:"not null;default:0" json:"wins"`
+	// ReturnSum adds up each signal's next-bar return, signed by its direction
+	ReturnSum float64 `gorm:"not null;default:0" json:"return_sum"`
+}
+
+// Signal return statuses
+const (
+	// SignalReturnPending is a return whose horizon has not been priced yet
+	SignalReturnPending = "pending"
+	// SignalReturnScored is a return measured at its horizon
+	SignalReturnScored = "scored"
+	// SignalReturnUnavailable is a return that could not be priced, e.g. the
+	// analysis was deleted or no bar followed the horizon within a week
+	SignalReturnUnavailable = "unavailable"
+)
+
+// SignalReturn is the hypothetical return of one directional signal held for
+// one horizon, e.g. "30m". Rows are created pending when an analysis is stored
+// and scored once bars past the horizon are available.
+type SignalReturn struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	AnalysisID uint      `gorm:"not null;uniqueIndex:idx_signal_returns_signal,priority:1" json:"analysis_id"`
+	// SignalIndex is the signal's position in the analysis's Signals
+	SignalIndex int    `gorm:"not null;uniqueIndex:idx_signal_returns_signal,priority:2" json:"signal_index"`
+	Horizon     string `gorm:"not null;uniqueIndex:idx_signal_returns_signal,priority:3;index:idx_signal_returns_user_horizon,priority:2" json:"horizon"`
+	UserId      string `gorm:"not null;index:idx_signal_returns_user_horizon,priority:1" json:"-"`
+	Ticker      string `gorm:"not null" json:"ticker"`
+	// StrategyID is the strategy the analysis ran with, 0 for the built-in signals
+	StrategyID uint `gorm:"not null;default:0" json:"strategy_id"`
+	// Kind is the signal type, e.g. "Bollinger Breakout", and Direction its CALL, PUT, UP or DOWN
+	Kind       string    `gorm:"not null" json:"kind"`
+	Direction  string    `gorm:"not null" json:"direction"`
+	EntryTime  time.Time `gorm:"not null" json:"entry_time"`
+	EntryPrice float64   `gorm:"not null" json:"entry_price"`
+	// DueAt is when the horizon elapses: EntryTime plus the horizon
+	DueAt  time.Time `gorm:"not null;index:idx_signal_returns_status_due,priority:2" json:"due_at"`
+	Status string    `gorm:"not null;default:'pending';index:idx_signal_returns_status_due,priority:1" json:"status"`
+	// AdjustedEntryPrice restates EntryPrice for splits and large dividends
+	// before the exit
+	AdjustedEntryPrice float64    `gorm:"not null;default:0" json:"adjusted_entry_price,omitempty"`
+	ExitTime           *time.Time `json:"exit_time,omitempty"`
+	ExitPrice          float64    `gorm:"not null;default:0" json:"exit_price,omitempty"`
+	// SignedReturn is the move from entry to exit, signed so positive favours the signal
+	SignedReturn float64 `gorm:"not null;default:0" json:"signed_return"`
+	Win          bool    `gorm:"not null;default:false" json:"win"`
+}
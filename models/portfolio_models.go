@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Portfolio transaction sides
+const (
+	TransactionBuy  = "buy"
+	TransactionSell = "sell"
+)
+
+// Transaction is a trade a user recorded in their portfolio. Selling more
+// than is held opens a short. Price and Fees are in Currency.
+type Transaction struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserId     string    `gorm:"not null;index:idx_transactions_user_ticker,priority:1" json:"-"`
+	Ticker     string    `gorm:"not null;index:idx_transactions_user_ticker,priority:2" json:"ticker"`
+	Side       string    `gorm:"not null" json:"side"`
+	Quantity   float64   `gorm:"not null" json:"quantity"`
+	Price      float64   `gorm:"not null" json:"price"`
+	Fees       float64   `gorm:"not null;default:0" json:"fees"`
+	Currency   string    `gorm:"not null;default:'USD'" json:"currency"`
+	ExecutedAt time.Time `gorm:"not null" json:"executed_at"`
+	Note       string    `gorm:"type:text;not null;default:''" json:"note,omitempty"`
+}
+
+// Position is a user's holding in a ticker, rebuilt from their transactions
+// in execution order at average cost. Quantity is negative for a short, and
+// 0 once the position is closed; closed positions keep their realized P&L.
+type Position struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserId    string    `gorm:"not null;uniqueIndex:idx_positions_user_ticker,priority:1" json:"-"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_positions_user_ticker,priority:2" json:"ticker"`
+	Currency  string    `gorm:"not null;default:'USD'" json:"currency"`
+	Quantity  float64   `gorm:"not null;default:0" json:"quantity"`
+	// AvgCost is the average price of the open quantity, fees included
+	AvgCost     float64 `gorm:"not null;default:0" json:"avg_cost"`
+	RealizedPnL float64 `gorm:"column:realized_pnl;not null;default:0" json:"realized_pnl"`
+	// OpenedAt is when the open quantity was first opened, nil once closed
+	OpenedAt          *time.Time `json:"opened_at,omitempty"`
+	LastTransactionAt time.Time  `gorm:"not null" json:"last_transaction_at"`
+	Transactions      int        `gorm:"not null;default:0" json:"transactions"`
+}
+
+// CostBasis is what the open quantity cost, negative for a short
+func (p *Position) CostBasis() float64 {
+	return p.Quantity * p.AvgCost
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// AnalysisPreset is a named set of deepsearch trigger parameters owned by a user
+type AnalysisPreset struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserId      string `gorm:"not null;uniqueIndex:idx_analysis_presets_user_name"`
+	Name        string `gorm:"not null;uniqueIndex:idx_analysis_presets_user_name"`
+	Description string `gorm:"default:''"`
+	TimeSpan    string `gorm:"not null;default:'minute'"`
+	Multiplier  int    `gorm:"not null;default:5"`
+}
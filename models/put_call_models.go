@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Put/call ratio intervals
+const (
+	PutCallDaily    = "daily"
+	PutCallIntraday = "intraday"
+)
+
+// PutCallMarket is the ticker market-wide put/call ratios are stored under
+const PutCallMarket = "MARKET"
+
+// Extreme put/call readings
+const (
+	PutCallExtremeHigh = "high"
+	PutCallExtremeLow  = "low"
+)
+
+// PutCallRatio is a ticker's option volume and open interest by side at one
+// point of a market day: the close for daily readings, a sample time for
+// intraday ones. Volumes are the day's so far.
+type PutCallRatio struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Ticker    string    `gorm:"not null;uniqueIndex:idx_put_call_ratios_key,priority:1" json:"ticker"`
+	// Interval is daily or intraday; INTERVAL is a Postgres keyword, hence the column name
+	Interval string `gorm:"column:sample_interval;not null;uniqueIndex:idx_put_call_ratios_key,priority:2" json:"interval"`
+	// Timestamp is the sample time, or the start of the market day for daily readings
+	Timestamp time.Time `gorm:"not null;uniqueIndex:idx_put_call_ratios_key,priority:3" json:"timestamp"`
+	// Day is the market day, YYYY-MM-DD
+	Day        string  `gorm:"not null;index" json:"day"`
+	CallVolume float64 `gorm:"default:0" json:"call_volume"`
+	PutVolume  float64 `gorm:"default:0" json:"put_volume"`
+	// VolumeRatio and OIRatio are puts over calls, 0 without calls
+	VolumeRatio      float64 `gorm:"default:0" json:"volume_ratio"`
+	CallOpenInterest float64 `gorm:"default:0" json:"call_open_interest"`
+	PutOpenInterest  float64 `gorm:"default:0" json:"put_open_interest"`
+	OIRatio          float64 `gorm:"default:0" json:"oi_ratio"`
+	// ZScore is VolumeRatio against the ticker's previous daily ratios, 0
+	// until there are enough of them
+	ZScore float64 `gorm:"default:0" json:"zscore"`
+	// Extreme is high or low when ZScore passes the extreme threshold
+	Extreme string `gorm:"default:''" json:"extreme,omitempty"`
+	// Signal describes an extreme reading, empty otherwise
+	Signal string `gorm:"default:''" json:"signal,omitempty"`
+	// Partial is set when the chain was cut short or, for the market, a
+	// component could not be read
+	Partial bool `gorm:"default:false" json:"partial"`
+}
+
+// UpsertPutCallRatios stores readings, replacing a ticker's reading of the
+// same interval and time
+func UpsertPutCallRatios(db *gorm.DB, ratios []PutCallRatio) error {
+	if len(ratios) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ticker"}, {Name: "sample_interval"}, {Name: "timestamp"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "day", "call_volume", "put_volume", "volume_ratio", "call_open_interest", "put_open_interest",
+			"oi_ratio", "z_score", "extreme", "signal", "partial",
+		}),
+	}).Create(&ratios).Error
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"institutionanalyser/health"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// replicaKey marks statements that may be served by the read replica
+	replicaKey = "replica:read"
+	// replicaPrimaryKey holds the primary's pool on statements sent to the replica
+	replicaPrimaryKey = "replica:primary"
+)
+
+// ReadReplica returns db with its queries routed to the read replica, for
+// dashboard, analytics and export reads that can tolerate replication lag.
+// Writes and transactions still use the primary, and without a replica
+// configured every statement does. Queries the replica cannot serve for a
+// connection failure are retried on the primary, and while the replica is
+// degraded (see health.Degraded) they go to the primary directly.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	if db == nil {
+		return nil
+	}
+	return db.Set(replicaKey, true).Session(&gorm.Session{})
+}
+
+// replicaPlugin sends queries marked by ReadReplica to the replica's connection pool
+type replicaPlugin struct {
+	pool gorm.ConnPool
+}
+
+func (replicaPlugin) Name() string {
+	return "replica"
+}
+
+// Initialize registers the routing callbacks ahead of the query and row
+// processors, and the fallback ones right after them, before the database
+// health callbacks see the result
+func (p replicaPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("replica:query", p.route); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Before("health:after_query").Register("replica:query_fallback", fallback(callbacks.Query)); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("replica:row", p.routeRows); err != nil {
+		return err
+	}
+	return cb.Row().After("gorm:row").Before("health:after_row").Register("replica:row_fallback", fallback(func(db *gorm.DB) {
+		db.Statement.Settings.Store("rows", true)
+		callbacks.RowQuery(db)
+	}))
+}
+
+func (p replicaPlugin) route(db *gorm.DB) {
+	if marked, ok := db.Get(replicaKey); !ok || marked != true {
+		return
+	}
+	// Reads inside a transaction must see its writes
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if health.Degraded(health.ReadReplica) {
+		return
+	}
+	db.InstanceSet(replicaPrimaryKey, db.Statement.ConnPool)
+	db.Statement.ConnPool = p.pool
+}
+
+// routeRows routes Rows and Scan calls. Row defers its error to the caller's
+// Scan, where it could not be retried on the primary, so it stays there.
+func (p replicaPlugin) routeRows(db *gorm.DB) {
+	if rows, ok := db.Get("rows"); ok && rows == true {
+		p.route(db)
+	}
+}
+
+// fallback returns a callback that records how a replica statement went and,
+// if the replica could not be reached, runs it again on the primary with run
+func fallback(run func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		primary, routed := db.InstanceGet(replicaPrimaryKey)
+		if !routed {
+			return
+		}
+		if !health.ConnectionError(db.Error) {
+			health.RecordSuccess(health.ReadReplica)
+			return
+		}
+		health.RecordFailure(health.ReadReplica, db.Error)
+		fmt.Printf("[database] read replica unavailable, retrying on the primary: %v\n", db.Error)
+		db.Error = nil
+		db.Statement.ConnPool = primary.(gorm.ConnPool)
+		run(db)
+	}
+}
+
+// UseReadReplica connects to the replica at dsn, with the primary's pool
+// settings, and routes ReadReplica queries on db to it
+func UseReadReplica(db *gorm.DB, dsn string) error {
+	replica, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return fmt.Errorf("failed to connect to read replica: %w", err)
+	}
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get read replica instance: %w", err)
+	}
+
+	poolConfig := GetConnectionPoolConfig()
+	sqlDB.SetMaxIdleConns(poolConfig.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(poolConfig.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(poolConfig.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(poolConfig.ConnMaxIdleTime)
+
+	if err := sqlDB.Ping(); err != nil {
+		return fmt.Errorf("failed to ping read replica: %w", err)
+	}
+	return db.Use(replicaPlugin{pool: sqlDB})
+}
+
+// PingReadReplica checks the read replica's connection, reporting false if
+// no replica is configured
+func PingReadReplica(ctx context.Context, db *gorm.DB) (bool, error) {
+	plugin, ok := db.Config.Plugins[replicaPlugin{}.Name()].(replicaPlugin)
+	if !ok {
+		return false, nil
+	}
+	pinger, ok := plugin.pool.(interface{ PingContext(context.Context) error })
+	if !ok {
+		return true, nil
+	}
+	return true, pinger.PingContext(ctx)
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"institutionanalyser/health"
+
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T, name string, tickers ...string) *gorm.DB {
+	t.Helper()
+	db, err := InitDatabase("sqlite://" + t.TempDir() + "/" + name + ".db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { CloseDatabase(db) })
+	for _, ticker := range tickers {
+		if err := db.Create(&Ticker{Ticker: ticker, Active: true}).Error; err != nil {
+			t.Fatalf("seed ticker: %v", err)
+		}
+	}
+	return db
+}
+
+// servedBy returns the tickers a query found, telling which database served it
+func servedBy(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var tickers []string
+	if err := db.Model(&Ticker{}).Order("ticker").Pluck("ticker", &tickers).Error; err != nil {
+		t.Fatalf("query tickers: %v", err)
+	}
+	return tickers
+}
+
+func TestReadReplicaRouting(t *testing.T) {
+	db := openSQLite(t, "primary", "PRIMARY")
+	replica, err := openSQLite(t, "replica", "REPLICA").DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(replicaPlugin{pool: replica}); err != nil {
+		t.Fatalf("register replica plugin: %v", err)
+	}
+
+	if got := servedBy(t, db); len(got) != 1 || got[0] != "PRIMARY" {
+		t.Errorf("unmarked query served %v, want the primary", got)
+	}
+	if got := servedBy(t, ReadReplica(db)); len(got) != 1 || got[0] != "REPLICA" {
+		t.Errorf("ReadReplica query served %v, want the replica", got)
+	}
+
+	rows, err := ReadReplica(db).Model(&Ticker{}).Select("ticker").Rows()
+	if err != nil {
+		t.Fatalf("Rows: %v", err)
+	}
+	var ticker string
+	for rows.Next() {
+		rows.Scan(&ticker)
+	}
+	rows.Close()
+	if ticker != "REPLICA" {
+		t.Errorf("ReadReplica rows served %s, want the replica", ticker)
+	}
+
+	err = ReadReplica(db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Ticker{Ticker: "WRITTEN", Active: true}).Error; err != nil {
+			return err
+		}
+		if got := servedBy(t, tx); len(got) != 2 {
+			t.Errorf("query in a transaction served %v, want the primary with its write", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+}
+
+// unreachablePool fails every query as a dropped replica connection does
+type unreachablePool struct {
+	gorm.ConnPool
+	queries int
+}
+
+func (p *unreachablePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.queries++
+	return nil, driver.ErrBadConn
+}
+
+func TestReadReplicaFallback(t *testing.T) {
+	health.Configure(health.Config{FailureThreshold: 2, RetryAfter: time.Minute})
+	t.Cleanup(func() {
+		health.Configure(health.GetConfig())
+		health.RecordSuccess(health.ReadReplica)
+	})
+
+	db := openSQLite(t, "primary", "PRIMARY")
+	replica := &unreachablePool{}
+	if err := db.Use(replicaPlugin{pool: replica}); err != nil {
+		t.Fatalf("register replica plugin: %v", err)
+	}
+
+	// Failed replica queries are retried on the primary until the replica
+	// is degraded, and then sent to the primary directly
+	for i := 0; i < 3; i++ {
+		if got := servedBy(t, ReadReplica(db)); len(got) != 1 || got[0] != "PRIMARY" {
+			t.Fatalf("query %d served %v, want the primary", i, got)
+		}
+	}
+	if replica.queries != 2 {
+		t.Errorf("replica was queried %d times, want 2 before it was degraded", replica.queries)
+	}
+	if !health.Degraded(health.ReadReplica) {
+		t.Error("replica not degraded after failures")
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Report kinds
+const (
+	ReportDailyDigest     = "daily_digest"
+	ReportEarningsPreview = "weekly_earnings_preview"
+	// ReportAnalysis is one stored analysis, rendered for share links and never stored
+	ReportAnalysis = "analysis"
+)
+
+// ReportArtifact is one rendered report file written to report storage
+type ReportArtifact struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Kind      string    `gorm:"not null;index" json:"kind"`
+	// PeriodStart and PeriodEnd are the market dates the report covers (YYYY-MM-DD)
+	PeriodStart string `gorm:"not null" json:"period_start"`
+	PeriodEnd   string `gorm:"not null" json:"period_end"`
+	Format      string `gorm:"not null" json:"format"`
+	ContentType string `gorm:"not null" json:"content_type"`
+	Size        int    `gorm:"not null" json:"size"`
+	// Storage is local, s3 or gcs; Location is the file path or bucket URL
+	Storage  string `gorm:"not null" json:"storage"`
+	Key      string `gorm:"not null" json:"key"`
+	Location string `gorm:"not null" json:"location"`
+	// Emailed is set when the report was also mailed
+	Emailed bool `gorm:"not null;default:false" json:"emailed"`
+
+	// URL is a signed download URL, set in API responses for artifacts in
+	// the current bucket and never stored
+	URL          string     `gorm:"-" json:"url,omitempty"`
+	URLExpiresAt *time.Time `gorm:"-" json:"url_expires_at,omitempty"`
+}
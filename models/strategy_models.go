@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Strategy is a user's named set of signal rules, selectable when triggering
+// an analysis in place of (or alongside) the built-in signals
+type Strategy struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserId      string    `gorm:"not null;uniqueIndex:idx_strategies_user_name" json:"-"`
+	Name        string    `gorm:"not null;uniqueIndex:idx_strategies_user_name" json:"name"`
+	Description string    `gorm:"default:''" json:"description,omitempty"`
+	// IncludeBuiltin keeps the built-in signals alongside the strategy's own
+	IncludeBuiltin bool           `gorm:"not null;default:false" json:"include_builtin"`
+	Rules          []StrategyRule `gorm:"type:jsonb;serializer:json;not null" json:"rules"`
+}
+
+// StrategyRule emits a Signal on the bar its When expression starts to hold,
+// e.g. {"name": "vwap_flow", "when": "volume_zscore > 2 AND close > cumulative_vwap", "signal": "CALL"}
+type StrategyRule struct {
+	Name   string `json:"name"`
+	When   string `json:"when"`
+	Signal string `json:"signal"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Ticker is locally synced reference data for a listed security
+type Ticker struct {
+	ID              uint `gorm:"primaryKey"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Ticker          string `gorm:"not null;uniqueIndex"`
+	Name            string `gorm:"default:''"`
+	Market          string `gorm:"default:''"`
+	Locale          string `gorm:"default:''"`
+	PrimaryExchange string `gorm:"default:'';index"`
+	Type            string `gorm:"default:''"`
+	CurrencyName    string `gorm:"default:''"`
+	// Currency is the ISO code prices are normalized to, e.g. GBP for a
+	// listing quoted in GBX pence (see NormalizeCurrency)
+	Currency          string  `gorm:"default:'';index"`
+	CIK               string  `gorm:"default:''"`
+	Active            bool    `gorm:"not null;default:true;index"`
+	MarketCap         float64 `gorm:"default:0"`
+	SICCode           string  `gorm:"default:''"`
+	Sector            string  `gorm:"default:'';index"` // Polygon sic_description
+	SharesOutstanding int64   `gorm:"default:0"`
+
+	// LastSyncedAt is set by every reference list sync that sees the ticker
+	LastSyncedAt time.Time
+	// DetailsSyncedAt is set when market cap/sector were refreshed from ticker details
+	DetailsSyncedAt *time.Time
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// TimescaleConfig configures the Timescale enhanced bar store
+type TimescaleConfig struct {
+	Enabled bool
+	// ChunkDays is the time range of one hypertable chunk
+	ChunkDays int
+	// CompressAfterDays is the age at which chunks are compressed; 0 leaves
+	// them uncompressed
+	CompressAfterDays int
+}
+
+// GetTimescaleConfig reads the Timescale settings from environment variables
+// with sensible defaults if not provided
+func GetTimescaleConfig() TimescaleConfig {
+	config := TimescaleConfig{
+		Enabled:           os.Getenv("TIMESCALE_ENABLED") == "true",
+		ChunkDays:         7,
+		CompressAfterDays: 30,
+	}
+
+	if val := os.Getenv("TIMESCALE_CHUNK_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.ChunkDays = n
+		}
+	}
+
+	if val := os.Getenv("TIMESCALE_COMPRESS_AFTER_DAYS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			config.CompressAfterDays = n
+		}
+	}
+
+	return config
+}
+
+// UseTimescale creates the enhanced bar store as a Timescale hypertable
+// partitioned by timestamp, creating the extension if needed, and applies
+// the chunk interval and compression policy of config. Compressed chunks are
+// segmented by ticker and aggregation, so reads of one series stay cheap;
+// settings changes apply to chunks created or compressed from then on.
+func UseTimescale(db *gorm.DB, config TimescaleConfig) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS timescaledb").Error; err != nil {
+		return fmt.Errorf("failed to create timescaledb extension: %w", err)
+	}
+	if err := db.AutoMigrate(&EnhancedBar{}); err != nil {
+		return fmt.Errorf("failed to migrate enhanced bars: %w", err)
+	}
+
+	err := db.Exec("SELECT create_hypertable('enhanced_bars', 'timestamp', chunk_time_interval => make_interval(days => ?::int), if_not_exists => TRUE, migrate_data => TRUE)", config.ChunkDays).Error
+	if err != nil {
+		return fmt.Errorf("failed to create enhanced bars hypertable: %w", err)
+	}
+	if err := db.Exec("SELECT set_chunk_time_interval('enhanced_bars', make_interval(days => ?::int))", config.ChunkDays).Error; err != nil {
+		return fmt.Errorf("failed to set enhanced bars chunk interval: %w", err)
+	}
+
+	if err := db.Exec("SELECT remove_compression_policy('enhanced_bars', if_exists => TRUE)").Error; err != nil {
+		return fmt.Errorf("failed to remove enhanced bars compression policy: %w", err)
+	}
+	if config.CompressAfterDays == 0 {
+		return nil
+	}
+
+	// Compression settings cannot change while chunks are compressed, so they
+	// are only set once
+	var compressed bool
+	err = db.Raw("SELECT compression_enabled FROM timescaledb_information.hypertables WHERE hypertable_name = 'enhanced_bars'").
+		Scan(&compressed).Error
+	if err != nil {
+		return fmt.Errorf("failed to read enhanced bars compression: %w", err)
+	}
+	if !compressed {
+		err = db.Exec("ALTER TABLE enhanced_bars SET (timescaledb.compress, timescaledb.compress_segmentby = 'ticker, time_span, multiplier', timescaledb.compress_orderby = 'timestamp DESC')").Error
+		if err != nil {
+			return fmt.Errorf("failed to enable enhanced bars compression: %w", err)
+		}
+	}
+	if err := db.Exec("SELECT add_compression_policy('enhanced_bars', make_interval(days => ?::int))", config.CompressAfterDays).Error; err != nil {
+		return fmt.Errorf("failed to add enhanced bars compression policy: %w", err)
+	}
+	return nil
+}
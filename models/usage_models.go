@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PolygonUsage counts one day's Polygon calls (UTC) of a feature, user and
+// endpoint class. Retried attempts count as calls, since each uses quota.
+type PolygonUsage struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Day       time.Time `gorm:"type:date;not null;uniqueIndex:idx_polygon_usages_key,priority:1" json:"day"`
+	Feature   string    `gorm:"not null;uniqueIndex:idx_polygon_usages_key,priority:2" json:"feature"`
+	// UserId is empty for calls made outside a user's request, e.g. scheduled jobs
+	UserId   string `gorm:"not null;default:'';uniqueIndex:idx_polygon_usages_key,priority:3" json:"user_id"`
+	Endpoint string `gorm:"not null;uniqueIndex:idx_polygon_usages_key,priority:4" json:"endpoint"`
+	// Tier is the endpoint's estimated cost tier: low, medium or high
+	Tier  string `gorm:"not null" json:"tier"`
+	Calls int64  `gorm:"not null;default:0" json:"calls"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// User is an account that owns analyses, requests and presets. Rows elsewhere
+// reference it by the string form of ID in their UserId column.
+type User struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Email        string `gorm:"not null;uniqueIndex"`
+	Name         string `gorm:"default:''"`
+	PasswordHash string `gorm:"not null" json:"-"`
+	// Tier selects the user's data retention window (see
+	// ANALYSIS_RETENTION_TIER_DAYS); "" uses the default
+	Tier string `gorm:"not null;default:'';index"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// YearRange places an analysis's last close within its 52-week range, taken
+// from daily bars
+type YearRange struct {
+	High     float64 `json:"high"`
+	HighDate string  `json:"high_date"`
+	Low      float64 `json:"low"`
+	LowDate  string  `json:"low_date"`
+	Close    float64 `json:"close"`
+	// DistanceFromHighPct is how far the close is below the high in percent
+	// (0 or less), DistanceFromLowPct how far it is above the low (0 or more)
+	DistanceFromHighPct float64 `json:"distance_from_high_pct"`
+	DistanceFromLowPct  float64 `json:"distance_from_low_pct"`
+	// DaysSinceHigh and DaysSinceLow are calendar days since each level was
+	// set, 0 when set on the last bar's day
+	DaysSinceHigh int `json:"days_since_high"`
+	DaysSinceLow  int `json:"days_since_low"`
+	// Position is the close within the range, 0 at the low and 1 at the high
+	Position float64 `json:"position"`
+}
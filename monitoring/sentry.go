@@ -0,0 +1,75 @@
+package monitoring
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/service"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Init configures Sentry (or a Sentry-compatible service) from SENTRY_DSN.
+// When the DSN is not set error tracking is disabled and the capture
+// functions only log.
+func Init() (bool, error) {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		return false, nil
+	}
+
+	sampleRate := 1.0
+	if val := os.Getenv("SENTRY_SAMPLE_RATE"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 && n <= 1 {
+			sampleRate = n
+		}
+	}
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              dsn,
+		Environment:      os.Getenv("SENTRY_ENVIRONMENT"),
+		Release:          os.Getenv("SENTRY_RELEASE"),
+		SampleRate:       sampleRate,
+		AttachStacktrace: true,
+		HTTPClient:       service.HTTPClient(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Flush waits for queued events to be sent, e.g. before shutdown
+func Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
+
+// CaptureError reports err with the user and tags (ticker, job_id, request_id, ...) it relates to
+func CaptureError(err error, userID string, tags map[string]string) {
+	if err == nil {
+		return
+	}
+	sentry.WithScope(func(scope *sentry.Scope) {
+		configureScope(scope, userID, tags)
+		sentry.CaptureException(err)
+	})
+}
+
+// CapturePanic reports a recovered panic value with its stack trace
+func CapturePanic(recovered interface{}, userID string, tags map[string]string) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		configureScope(scope, userID, tags)
+		scope.SetLevel(sentry.LevelFatal)
+		sentry.CurrentHub().Recover(recovered)
+	})
+	fmt.Printf("[monitoring] recovered panic: %v %v\n", recovered, tags)
+}
+
+func configureScope(scope *sentry.Scope, userID string, tags map[string]string) {
+	if userID != "" {
+		scope.SetUser(sentry.User{ID: userID})
+	}
+	scope.SetTags(tags)
+}
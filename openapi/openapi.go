@@ -0,0 +1,418 @@
+// Package openapi builds an OpenAPI 3 document from the routes registered on
+// the router and hand-maintained descriptions of their operations
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Security scheme names
+const (
+	BearerAuth = "bearerAuth"
+	APIKeyAuth = "apiKeyAuth"
+)
+
+// Document is an OpenAPI 3.0 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds a path's operations by lower-case method
+type PathItem map[string]*Operation
+
+// Operation is one method on one path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	// Scope is the API key scope the operation requires
+	Scope string `json:"x-required-scope,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is an operation's request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema of one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response is one response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Schema is the subset of JSON Schema the document uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Components holds the schemas and security schemes operations refer to
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is how a client authenticates
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Route describes the operation registered at one method and path. The path
+// parameters come from the router.
+type Route struct {
+	// ID replaces the operation ID derived from the handler name
+	ID          string
+	Summary     string
+	Description string
+	Tag         string
+	// Scope is the API key scope the route requires, if any
+	Scope string
+	Query []Param
+	// Body is a value of the request body's type, its schema derived from the
+	// type's JSON encoding; nil for routes without a body
+	Body any
+	// Produces is the success response's content type (default: application/json)
+	Produces string
+	// Public routes need no credentials
+	Public     bool
+	Deprecated bool
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+	Enum        []string
+}
+
+// Query returns an optional string query parameter
+func Query(name, description string) Param {
+	return Param{Name: name, Description: description, Type: "string"}
+}
+
+// Int returns the parameter as an integer
+func (p Param) Int() Param {
+	p.Type = "integer"
+	return p
+}
+
+// Number returns the parameter as a number
+func (p Param) Number() Param {
+	p.Type = "number"
+	return p
+}
+
+// Bool returns the parameter as a boolean
+func (p Param) Bool() Param {
+	p.Type = "boolean"
+	return p
+}
+
+// OneOf restricts the parameter to values
+func (p Param) OneOf(values ...string) Param {
+	p.Enum = values
+	return p
+}
+
+// Require returns the parameter as required
+func (p Param) Require() Param {
+	p.Required = true
+	return p
+}
+
+// Build returns the document for routes, described by routes' "METHOD /path"
+// entries in described. Routes without a description are still listed, so the
+// document never omits an endpoint.
+func Build(info Info, routes gin.RoutesInfo, described map[string]Route) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"error":   {Type: "string"},
+						"details": {Type: "string"},
+					},
+					Required: []string{"error"},
+				},
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Token from /api/v1/auth/login"},
+				APIKeyAuth: {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "Key from /api/v1/apikeys, limited to its scopes"},
+			},
+		},
+	}
+
+	// Routes are sorted so operation ID collisions resolve the same way every build
+	sorted := append(gin.RoutesInfo(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	ids := map[string]int{}
+	for _, info := range sorted {
+		route := described[info.Method+" "+info.Path]
+		path, pathParams := convertPath(info.Path)
+
+		id := route.ID
+		if id == "" {
+			id = operationID(info.Handler)
+		}
+		if ids[id]++; ids[id] > 1 {
+			id = fmt.Sprintf("%s%d", id, ids[id])
+		}
+
+		op := &Operation{
+			OperationID: id,
+			Summary:     route.Summary,
+			Description: route.Description,
+			Parameters:  append(pathParams, queryParams(route.Query)...),
+			Responses:   responses(route),
+			Deprecated:  route.Deprecated,
+			Scope:       route.Scope,
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		if !route.Public {
+			op.Security = []map[string][]string{{BearerAuth: {}}, {APIKeyAuth: {}}}
+		}
+		if route.Body != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: SchemaOf(route.Body)}},
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(info.Method)] = op
+	}
+	return doc
+}
+
+// convertPath rewrites gin's :name and *name parameters to OpenAPI's {name}
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "string"}
+		if name == "id" {
+			schema = &Schema{Type: "integer"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func queryParams(query []Param) []Parameter {
+	params := make([]Parameter, 0, len(query))
+	for _, p := range query {
+		params = append(params, Parameter{
+			Name:        p.Name,
+			In:          "query",
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      &Schema{Type: p.Type, Enum: p.Enum},
+		})
+	}
+	return params
+}
+
+// responses returns the success response and the error response every
+// handler writes
+func responses(route Route) map[string]Response {
+	produces := route.Produces
+	if produces == "" {
+		produces = "application/json"
+	}
+	success := &Schema{Type: "object"}
+	if produces != "application/json" {
+		success = &Schema{Type: "string"}
+	}
+	return map[string]Response{
+		"200": {Description: "Success", Content: map[string]MediaType{produces: {Schema: success}}},
+		"default": {
+			Description: "Error",
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		},
+	}
+}
+
+// operationID derives an operation ID from a handler name such as
+// institutionanalyser/handlers.(*DeepSearchHandler).HandleGetHistory-fm
+func operationID(handler string) string {
+	name := handler[strings.LastIndex(handler, ".")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.TrimPrefix(name, "Handle")
+	if name == "" {
+		return "operation"
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf returns the schema of v's JSON encoding. Fields follow their json
+// tags, and binding:"required" fields are marked required.
+func SchemaOf(v any) *Schema {
+	return schemaOf(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		// A type containing itself is cut off at its second occurrence
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(schema, t, seen)
+		return schema
+	}
+	return &Schema{}
+}
+
+// addFields adds t's JSON fields to schema, flattening embedded structs as
+// encoding/json does
+func addFields(schema *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(schema, embedded, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = schemaOf(field.Type, seen)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// UIPage returns the Swagger UI page for the document at specURL, loading
+// Swagger UI from cdn
+func UIPage(title, specURL, cdn string) []byte {
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>%[1]s</title>
+  <link rel="stylesheet" href="%[3]s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%[3]s/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: %[2]q, dom_id: "#swagger-ui", persistAuthorization: true});
+  </script>
+</body>
+</html>
+`, title, specURL, strings.TrimSuffix(cdn, "/")))
+}
@@ -0,0 +1,289 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var marketTimezone = loadMarketTimezone()
+
+func loadMarketTimezone() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Config holds paper trading settings
+type Config struct {
+	Enabled bool
+	// Notional is the size of each trade in the analysis currency
+	Notional float64
+	// StopATRMultiple is how many ATRs the stop trails the best price by
+	StopATRMultiple float64
+}
+
+// GetConfig reads paper trading settings from environment variables with
+// sensible defaults if not provided. Paper trading is on unless
+// PAPER_TRADING_ENABLED=false.
+func GetConfig() Config {
+	config := Config{
+		Enabled:         os.Getenv("PAPER_TRADING_ENABLED") != "false",
+		Notional:        10000,
+		StopATRMultiple: 2,
+	}
+
+	if val := os.Getenv("PAPER_TRADE_NOTIONAL"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			config.Notional = n
+		}
+	}
+
+	if val := os.Getenv("PAPER_STOP_ATR_MULTIPLE"); val != "" {
+		if n, err := strconv.ParseFloat(val, 64); err == nil && n > 0 {
+			config.StopATRMultiple = n
+		}
+	}
+
+	return config
+}
+
+// Engine trades each user's completed analyses on paper: a BUY decision opens
+// a long, a SELL a short, and the opposite decision or a hit trailing stop
+// closes it. Trades are marked with the last close of later analyses and with
+// quotes (see MarkToMarket). A nil engine does nothing.
+type Engine struct {
+	db     *gorm.DB
+	config Config
+}
+
+// NewEngine returns nil when paper trading is disabled
+func NewEngine(db *gorm.DB, config Config) *Engine {
+	if !config.Enabled {
+		return nil
+	}
+	return &Engine{db: db, config: config}
+}
+
+// OnAnalysis applies a completed analysis to its owner's paper trade in the
+// ticker. Analyses of windows that ended before the previous trading session
+// are not traded, since their prices are history; nor are analyses older than
+// the trade's last mark.
+func (e *Engine) OnAnalysis(ctx context.Context, analysis *models.TechnicalSignal) error {
+	if e == nil || analysis == nil || analysis.LastClose <= 0 {
+		return nil
+	}
+	if !e.current(ctx, analysis.EndDate) {
+		return nil
+	}
+	side := decisionSide(analysis.FinalDecision)
+
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var trade models.PaperTrade
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND ticker = ? AND status = ?", analysis.UserId, analysis.Ticker, models.PaperTradeOpen).
+			First(&trade).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			if !analysis.EndDate.After(trade.MarkedAt) {
+				return nil
+			}
+			e.mark(&trade, analysis.LastClose, analysis.EndDate, &analysis.ID)
+			stopped := trade.Status == models.PaperTradeClosed
+			if !stopped && side != "" && side != trade.Side {
+				closeTrade(&trade, analysis.LastClose, analysis.EndDate, models.PaperExitReversal, &analysis.ID)
+			}
+			if err := tx.Save(&trade).Error; err != nil {
+				return err
+			}
+			// A trade stopped out at this close is not reopened on the same side
+			if trade.Status == models.PaperTradeOpen || (stopped && side == trade.Side) {
+				return nil
+			}
+		}
+
+		if side == "" {
+			return nil
+		}
+		if analysis.ATR <= 0 {
+			fmt.Printf("[paper] analysis %d has no ATR, not opening a %s trade in %s\n", analysis.ID, side, analysis.Ticker)
+			return nil
+		}
+		return tx.Create(e.open(analysis, side)).Error
+	})
+}
+
+// MarkToMarket marks every open trade at its ticker's last trade from the
+// quote cache and closes those whose stop was hit. It returns how many trades
+// it marked and closed.
+func (e *Engine) MarkToMarket(ctx context.Context) (int, int, error) {
+	if e == nil {
+		return 0, 0, nil
+	}
+
+	var tickers []string
+	if err := e.db.WithContext(ctx).Model(&models.PaperTrade{}).
+		Where("status = ?", models.PaperTradeOpen).
+		Distinct().Pluck("ticker", &tickers).Error; err != nil {
+		return 0, 0, err
+	}
+	if len(tickers) == 0 {
+		return 0, 0, nil
+	}
+
+	snapshots, _, err := service.DefaultQuoteCache().Get(ctx, tickers)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+	currencies, err := models.TickerCurrencies(e.db.WithContext(ctx), tickers)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	marked, closed := 0, 0
+	for _, ticker := range tickers {
+		snapshot, ok := snapshots[ticker]
+		if !ok || snapshot.LastTrade.Price <= 0 {
+			continue
+		}
+		price := snapshot.LastTrade.Price * currencies[ticker].Scale
+		at := time.Time(snapshot.LastTrade.Timestamp)
+		if at.IsZero() {
+			at = time.Now()
+		}
+
+		var trades []models.PaperTrade
+		err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("ticker = ? AND status = ? AND marked_at < ?", ticker, models.PaperTradeOpen, at).
+				Find(&trades).Error
+			if err != nil {
+				return err
+			}
+			for i := range trades {
+				e.mark(&trades[i], price, at, nil)
+				if err := tx.Save(&trades[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return marked, closed, err
+		}
+		for _, trade := range trades {
+			marked++
+			if trade.Status == models.PaperTradeClosed {
+				closed++
+			}
+		}
+	}
+	return marked, closed, nil
+}
+
+// current reports whether a window ending at end is recent enough to trade:
+// its last bar is in the current or previous trading session
+func (e *Engine) current(ctx context.Context, end time.Time) bool {
+	now := time.Now().In(marketTimezone)
+	previous := service.DefaultTradingCalendar().PreviousTradingDay(ctx, now)
+	sessionStart := time.Date(previous.Year(), previous.Month(), previous.Day(), 0, 0, 0, 0, marketTimezone)
+	return !end.Before(sessionStart)
+}
+
+// open builds the trade an analysis opens on side, with the stop StopATRMultiple
+// ATRs from the entry
+func (e *Engine) open(analysis *models.TechnicalSignal, side string) *models.PaperTrade {
+	price := analysis.LastClose
+	distance := e.config.StopATRMultiple * analysis.ATR
+	stop := price - distance
+	if side == models.PaperSideShort {
+		stop = price + distance
+	}
+	currency := analysis.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	return &models.PaperTrade{
+		UserId:          analysis.UserId,
+		Ticker:          analysis.Ticker,
+		Side:            side,
+		Status:          models.PaperTradeOpen,
+		Currency:        currency,
+		Quantity:        e.config.Notional / price,
+		EntryAnalysisID: analysis.ID,
+		EntryAt:         analysis.EndDate,
+		EntryPrice:      price,
+		ATR:             analysis.ATR,
+		StopATRMultiple: e.config.StopATRMultiple,
+		Stop:            stop,
+		BestPrice:       price,
+		LastPrice:       price,
+		MarkedAt:        analysis.EndDate,
+	}
+}
+
+// mark values an open trade at price, closing it if the stop was hit and
+// otherwise trailing the stop behind the best price so far
+func (e *Engine) mark(trade *models.PaperTrade, price float64, at time.Time, analysisID *uint) {
+	trade.LastPrice = price
+	trade.MarkedAt = at
+	if trade.StopHit(price) {
+		closeTrade(trade, price, at, models.PaperExitStop, analysisID)
+		return
+	}
+
+	distance := trade.StopATRMultiple * trade.ATR
+	if trade.Side == models.PaperSideShort {
+		if price < trade.BestPrice {
+			trade.BestPrice = price
+		}
+		if stop := trade.BestPrice + distance; stop < trade.Stop {
+			trade.Stop = stop
+		}
+	} else {
+		if price > trade.BestPrice {
+			trade.BestPrice = price
+		}
+		if stop := trade.BestPrice - distance; stop > trade.Stop {
+			trade.Stop = stop
+		}
+	}
+	trade.UnrealizedPnL = trade.PnL(price)
+}
+
+// closeTrade exits a trade at price. Stops are filled at the price that
+// crossed them, so a gap past the stop costs more than the stop distance.
+func closeTrade(trade *models.PaperTrade, price float64, at time.Time, reason string, analysisID *uint) {
+	trade.Status = models.PaperTradeClosed
+	trade.ExitAt = &at
+	trade.ExitPrice = price
+	trade.ExitReason = reason
+	trade.ExitAnalysisID = analysisID
+	trade.RealizedPnL = trade.PnL(price)
+	trade.UnrealizedPnL = 0
+}
+
+// decisionSide is the side a final decision trades, or "" for HOLD and STRADDLE
+func decisionSide(decision string) string {
+	switch decision {
+	case "BUY":
+		return models.PaperSideLong
+	case "SELL":
+		return models.PaperSideShort
+	}
+	return ""
+}
@@ -0,0 +1,280 @@
+// Package parquet writes flat tables as Apache Parquet files that pandas,
+// pyarrow and DuckDB read directly. A file is one row group of PLAIN-encoded,
+// uncompressed columns, one data page per column; nested and repeated
+// columns are not supported.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// magic starts and ends every Parquet file
+const magic = "PAR1"
+
+// Physical types
+const (
+	typeBoolean   int32 = 0
+	typeInt32     int32 = 1
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+)
+
+// Converted types, for readers predating logical types
+const (
+	convertedUTF8            int32 = 0
+	convertedTimestampMillis int32 = 9
+)
+
+const (
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	pageTypeData      int32 = 0
+	codecUncompressed int32 = 0
+	formatVersion     int32 = 1
+	createdBy               = "institutionanalyser"
+)
+
+// Column is a named column of values of one type
+type Column struct {
+	name      string
+	physical  int32
+	converted *int32
+	logical   tstruct
+	rows      int
+	// defined marks the non-null rows of an optional column; nil for a required one
+	defined []bool
+	// data holds the non-null values, PLAIN-encoded
+	data []byte
+}
+
+// Int32Column returns a column of 32-bit integers
+func Int32Column(name string, values []int32) Column {
+	data := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(v))
+	}
+	return Column{name: name, physical: typeInt32, rows: len(values), data: data}
+}
+
+// Int64Column returns a column of 64-bit integers
+func Int64Column(name string, values []int64) Column {
+	data := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(v))
+	}
+	return Column{name: name, physical: typeInt64, rows: len(values), data: data}
+}
+
+// DoubleColumn returns a column of 64-bit floats
+func DoubleColumn(name string, values []float64) Column {
+	data := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return Column{name: name, physical: typeDouble, rows: len(values), data: data}
+}
+
+// BoolColumn returns a column of booleans
+func BoolColumn(name string, values []bool) Column {
+	data := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			data[i/8] |= 1 << (i % 8)
+		}
+	}
+	return Column{name: name, physical: typeBoolean, rows: len(values), data: data}
+}
+
+// StringColumn returns a column of UTF-8 strings
+func StringColumn(name string, values []string) Column {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(v)))
+		data = append(data, v...)
+	}
+	converted := convertedUTF8
+	return Column{
+		name:      name,
+		physical:  typeByteArray,
+		converted: &converted,
+		logical:   tstruct{{1, tstruct{}}},
+		rows:      len(values),
+		data:      data,
+	}
+}
+
+// TimestampColumn returns a column of UTC timestamps in milliseconds. Zero
+// times are written as nulls.
+func TimestampColumn(name string, values []time.Time) Column {
+	var data []byte
+	defined := make([]bool, len(values))
+	for i, v := range values {
+		if v.IsZero() {
+			continue
+		}
+		defined[i] = true
+		data = binary.LittleEndian.AppendUint64(data, uint64(v.UnixMilli()))
+	}
+	converted := convertedTimestampMillis
+	// TIMESTAMP(isAdjustedToUTC=true, unit=MILLIS)
+	logical := tstruct{{8, tstruct{{1, true}, {2, tstruct{{1, tstruct{}}}}}}}
+	return Column{
+		name:      name,
+		physical:  typeInt64,
+		converted: &converted,
+		logical:   logical,
+		rows:      len(values),
+		defined:   defined,
+		data:      data,
+	}
+}
+
+// File is a table to write; every column must have the same number of rows
+type File struct {
+	Columns []Column
+	// Metadata is stored as the file's key-value metadata
+	Metadata map[string]string
+}
+
+// WriteTo writes the file in the Parquet format
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if len(f.Columns) == 0 {
+		return 0, errors.New("parquet: a file needs at least one column")
+	}
+	rows := f.Columns[0].rows
+	for _, c := range f.Columns {
+		if c.rows != rows {
+			return 0, fmt.Errorf("parquet: column %s has %d rows, want %d", c.name, c.rows, rows)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+
+	schema := []interface{}{tstruct{{4, "schema"}, {5, int32(len(f.Columns))}}}
+	chunks := make([]interface{}, 0, len(f.Columns))
+	var totalSize int64
+	for _, c := range f.Columns {
+		offset := int64(buf.Len())
+		page := c.page()
+		header := encodeStruct(tstruct{
+			{1, pageTypeData},
+			{2, int32(len(page))},
+			{3, int32(len(page))},
+			{5, tstruct{
+				{1, int32(c.rows)},
+				{2, encodingPlain},
+				{3, encodingRLE},
+				{4, encodingRLE},
+			}},
+		})
+		buf.Write(header)
+		buf.Write(page)
+		size := int64(len(header) + len(page))
+		totalSize += size
+
+		repetition := repetitionRequired
+		if c.defined != nil {
+			repetition = repetitionOptional
+		}
+		element := tstruct{{1, c.physical}, {3, repetition}, {4, c.name}}
+		if c.converted != nil {
+			element = append(element, tfield{6, *c.converted})
+		}
+		if c.logical != nil {
+			element = append(element, tfield{10, c.logical})
+		}
+		schema = append(schema, element)
+
+		chunks = append(chunks, tstruct{
+			{2, offset},
+			{3, tstruct{
+				{1, c.physical},
+				{2, tlist{elem: typeI32, items: []interface{}{encodingPlain, encodingRLE}}},
+				{3, tlist{elem: typeBinary, items: []interface{}{c.name}}},
+				{4, codecUncompressed},
+				{5, int64(c.rows)},
+				{6, size},
+				{7, size},
+				{9, offset},
+			}},
+		})
+	}
+
+	var metadata interface{}
+	if len(f.Metadata) > 0 {
+		keys := make([]string, 0, len(f.Metadata))
+		for key := range f.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, tstruct{{1, key}, {2, f.Metadata[key]}})
+		}
+		metadata = tlist{elem: typeStruct, items: pairs}
+	}
+
+	footer := encodeStruct(tstruct{
+		{1, formatVersion},
+		{2, tlist{elem: typeStruct, items: schema}},
+		{3, int64(rows)},
+		{4, tlist{elem: typeStruct, items: []interface{}{tstruct{
+			{1, tlist{elem: typeStruct, items: chunks}},
+			{2, totalSize},
+			{3, int64(rows)},
+		}}}},
+		{5, metadata},
+		{6, createdBy},
+	})
+	buf.Write(footer)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	buf.WriteString(magic)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// page returns the column's data page body: definition levels for an
+// optional column, then the values
+func (c Column) page() []byte {
+	if c.defined == nil {
+		return c.data
+	}
+	levels := rleLevels(c.defined)
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	return append(page, c.data...)
+}
+
+// rleLevels encodes definition levels of bit width 1 as RLE runs of the
+// RLE/bit-packing hybrid encoding
+func rleLevels(defined []bool) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(defined); {
+		run := 1
+		for i+run < len(defined) && defined[i+run] == defined[i] {
+			run++
+		}
+		writeVarint(&buf, uint64(run)<<1)
+		if defined[i] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		i += run
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,113 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Parquet metadata is Thrift, in the compact protocol. The writer builds it
+// from these values: int32, int64, bool, string, tstruct and tlist.
+
+// tfield is a struct field; nil values are left out
+type tfield struct {
+	id    int16
+	value interface{}
+}
+
+type tstruct []tfield
+
+type tlist struct {
+	elem  byte
+	items []interface{}
+}
+
+// Compact protocol type IDs
+const (
+	typeTrue   byte = 1
+	typeFalse  byte = 2
+	typeI32    byte = 5
+	typeI64    byte = 6
+	typeBinary byte = 8
+	typeList   byte = 9
+	typeStruct byte = 12
+)
+
+func compactType(v interface{}) byte {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return typeTrue
+		}
+		return typeFalse
+	case int32:
+		return typeI32
+	case int64:
+		return typeI64
+	case string:
+		return typeBinary
+	case tlist:
+		return typeList
+	}
+	return typeStruct
+}
+
+// encodeStruct returns s in the compact protocol
+func encodeStruct(s tstruct) []byte {
+	var buf bytes.Buffer
+	writeStruct(&buf, s)
+	return buf.Bytes()
+}
+
+func writeStruct(buf *bytes.Buffer, s tstruct) {
+	var last int16
+	for _, f := range s {
+		if f.value == nil {
+			continue
+		}
+		typ := compactType(f.value)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			buf.WriteByte(byte(delta)<<4 | typ)
+		} else {
+			buf.WriteByte(typ)
+			writeVarint(buf, zigzag(int64(f.id)))
+		}
+		last = f.id
+		writeValue(buf, f.value)
+	}
+	buf.WriteByte(0)
+}
+
+// writeValue writes a value after its field header; a bool field's value is
+// in its header
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case int32:
+		writeVarint(buf, zigzag(int64(v)))
+	case int64:
+		writeVarint(buf, zigzag(v))
+	case string:
+		writeVarint(buf, uint64(len(v)))
+		buf.WriteString(v)
+	case tstruct:
+		writeStruct(buf, v)
+	case tlist:
+		if len(v.items) < 15 {
+			buf.WriteByte(byte(len(v.items))<<4 | v.elem)
+		} else {
+			buf.WriteByte(0xf0 | v.elem)
+			writeVarint(buf, uint64(len(v.items)))
+		}
+		for _, item := range v.items {
+			writeValue(buf, item)
+		}
+	}
+}
+
+func writeVarint(buf *bytes.Buffer, n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func zigzag(n int64) uint64 {
+	return uint64(n<<1) ^ uint64(n>>63)
+}
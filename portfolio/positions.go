@@ -0,0 +1,98 @@
+package portfolio
+
+import (
+	"context"
+	"math"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// quantityEpsilon treats smaller remaining quantities as closed, so
+// fractional shares summing back to zero do not leave dust positions
+const quantityEpsilon = 1e-9
+
+// Rebuild recomputes userID's position in ticker from their transactions and
+// stores it. It returns nil, and removes the position, when no transactions
+// are left. Call it inside the transaction that changed the ledger.
+func Rebuild(ctx context.Context, tx *gorm.DB, userID, ticker string) (*models.Position, error) {
+	var transactions []models.Transaction
+	if err := tx.WithContext(ctx).Where("user_id = ? AND ticker = ?", userID, ticker).
+		Order("executed_at, id").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		err := tx.WithContext(ctx).Where("user_id = ? AND ticker = ?", userID, ticker).Delete(&models.Position{}).Error
+		return nil, err
+	}
+
+	position := Replay(transactions)
+	position.UserId = userID
+	position.Ticker = ticker
+	err := tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "ticker"}},
+		DoUpdates: clause.AssignmentColumns([]string{"currency", "quantity", "avg_cost", "realized_pnl", "opened_at", "last_transaction_at", "transactions", "updated_at"}),
+	}).Create(position).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.WithContext(ctx).Where("user_id = ? AND ticker = ?", userID, ticker).First(position).Error; err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
+// Replay applies transactions, in order, to an empty position at average
+// cost. Fees raise the cost of what a transaction opens and lower the
+// proceeds of what it closes.
+func Replay(transactions []models.Transaction) *models.Position {
+	position := &models.Position{Currency: models.DefaultCurrency}
+	for _, t := range transactions {
+		if t.Quantity <= 0 {
+			continue
+		}
+		direction := 1.0
+		price := t.Price + t.Fees/t.Quantity
+		if t.Side == models.TransactionSell {
+			direction = -1
+			price = t.Price - t.Fees/t.Quantity
+		}
+
+		remaining := t.Quantity
+		if position.Quantity*direction < 0 {
+			// Close against the open quantity first
+			closed := math.Min(remaining, math.Abs(position.Quantity))
+			position.RealizedPnL += closed * (price - position.AvgCost) * -direction
+			position.Quantity += closed * direction
+			remaining -= closed
+			if math.Abs(position.Quantity) < quantityEpsilon {
+				position.Quantity = 0
+				position.AvgCost = 0
+				position.OpenedAt = nil
+			}
+		}
+		if remaining > quantityEpsilon {
+			held := math.Abs(position.Quantity)
+			position.AvgCost = (held*position.AvgCost + remaining*price) / (held + remaining)
+			position.Quantity += remaining * direction
+			if position.OpenedAt == nil {
+				at := t.ExecutedAt
+				position.OpenedAt = &at
+			}
+		}
+
+		position.Currency = t.Currency
+		position.LastTransactionAt = t.ExecutedAt
+		position.Transactions++
+	}
+	return position
+}
+
+// OpenPositions returns userID's positions that are not closed, by ticker
+func OpenPositions(ctx context.Context, db *gorm.DB, userID string) ([]models.Position, error) {
+	positions := []models.Position{}
+	err := db.WithContext(ctx).Where("user_id = ? AND quantity <> 0", userID).Order("ticker").Find(&positions).Error
+	return positions, err
+}
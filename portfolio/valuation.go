@@ -0,0 +1,251 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"institutionanalyser/health"
+	"institutionanalyser/models"
+	"institutionanalyser/service"
+
+	"gorm.io/gorm"
+)
+
+// Holding is an open position valued at its ticker's last trade
+type Holding struct {
+	models.Position
+	// Price is the last trade from the quote cache, or the average cost when
+	// no quote was available (Priced is then false)
+	Price    float64    `json:"price"`
+	Priced   bool       `json:"priced"`
+	PricedAt *time.Time `json:"priced_at,omitempty"`
+	// MarketValue is quantity x price, negative for a short
+	MarketValue   float64 `json:"market_value"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	// Weight is the holding's share of its currency's gross market value
+	Weight float64 `json:"weight"`
+}
+
+// Summary totals the holdings in one currency
+type Summary struct {
+	Currency  string  `json:"currency"`
+	Positions int     `json:"positions"`
+	Long      float64 `json:"long_value"`
+	Short     float64 `json:"short_value"`
+	Gross     float64 `json:"gross_value"`
+	Net       float64 `json:"net_value"`
+	// NetExposure is the net over the gross value, from -1 (all short) to 1
+	// (all long)
+	NetExposure   float64 `json:"net_exposure"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+}
+
+// Valuation is a user's open positions valued together
+type Valuation struct {
+	Holdings  []Holding `json:"holdings"`
+	Summaries []Summary `json:"summaries"`
+}
+
+// Summary returns the totals in currency, zero when nothing is held in it
+func (v *Valuation) Summary(currency string) Summary {
+	for _, s := range v.Summaries {
+		if s.Currency == currency {
+			return s
+		}
+	}
+	return Summary{Currency: currency}
+}
+
+// Holding returns the holding in ticker, nil if it is not held
+func (v *Valuation) Holding(ticker string) *Holding {
+	for i := range v.Holdings {
+		if v.Holdings[i].Ticker == ticker {
+			return &v.Holdings[i]
+		}
+	}
+	return nil
+}
+
+// Value values userID's open positions at their last trades from the quote
+// cache. Positions are valued at average cost when quotes are unavailable,
+// and without fetching them while Polygon is degraded.
+func Value(ctx context.Context, db *gorm.DB, userID string) (*Valuation, error) {
+	positions, err := OpenPositions(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	valuation := &Valuation{Holdings: make([]Holding, 0, len(positions)), Summaries: []Summary{}}
+	if len(positions) == 0 {
+		return valuation, nil
+	}
+
+	tickers := make([]string, len(positions))
+	for i, p := range positions {
+		tickers[i] = p.Ticker
+	}
+	currencies, err := models.TickerCurrencies(db.WithContext(ctx), tickers)
+	if err != nil {
+		return nil, err
+	}
+	quotes := lastTrades(ctx, tickers)
+
+	summaries := map[string]*Summary{}
+	for _, p := range positions {
+		holding := Holding{Position: p, Price: p.AvgCost}
+		if quote, ok := quotes[p.Ticker]; ok {
+			holding.Price = quote.price * currencies[p.Ticker].Scale
+			holding.Priced = true
+			if !quote.at.IsZero() {
+				holding.PricedAt = &quote.at
+			}
+			holding.UnrealizedPnL = p.Quantity * (holding.Price - p.AvgCost)
+		}
+		holding.MarketValue = p.Quantity * holding.Price
+		valuation.Holdings = append(valuation.Holdings, holding)
+
+		s, ok := summaries[p.Currency]
+		if !ok {
+			s = &Summary{Currency: p.Currency}
+			summaries[p.Currency] = s
+		}
+		s.Positions++
+		if holding.MarketValue > 0 {
+			s.Long += holding.MarketValue
+		} else {
+			s.Short += -holding.MarketValue
+		}
+		s.UnrealizedPnL += holding.UnrealizedPnL
+	}
+
+	for _, s := range summaries {
+		s.Gross = s.Long + s.Short
+		s.Net = s.Long - s.Short
+		if s.Gross > 0 {
+			s.NetExposure = s.Net / s.Gross
+		}
+		valuation.Summaries = append(valuation.Summaries, *s)
+	}
+	sort.Slice(valuation.Summaries, func(i, j int) bool {
+		return valuation.Summaries[i].Currency < valuation.Summaries[j].Currency
+	})
+	for i := range valuation.Holdings {
+		h := &valuation.Holdings[i]
+		if gross := summaries[h.Currency].Gross; gross > 0 {
+			h.Weight = math.Abs(h.MarketValue) / gross
+		}
+	}
+	return valuation, nil
+}
+
+// lastTrade is a ticker's last trade in its quoted unit
+type lastTrade struct {
+	price float64
+	at    time.Time
+}
+
+// lastTrades reads tickers' last trades from the quote cache. It returns none
+// while Polygon is degraded or when the quotes cannot be fetched.
+func lastTrades(ctx context.Context, tickers []string) map[string]lastTrade {
+	trades := map[string]lastTrade{}
+	if health.Degraded(health.Polygon) {
+		return trades
+	}
+	snapshots, _, err := service.DefaultQuoteCache().Get(ctx, tickers)
+	if err != nil {
+		fmt.Printf("[portfolio] failed to fetch quotes, valuing at cost: %v\n", err)
+		return trades
+	}
+	for ticker, snapshot := range snapshots {
+		if snapshot.LastTrade.Price > 0 {
+			trades[ticker] = lastTrade{price: snapshot.LastTrade.Price, at: time.Time(snapshot.LastTrade.Timestamp)}
+		}
+	}
+	return trades
+}
+
+// Alignments of a decision with the position it is about
+const (
+	// AlignmentAligned is a BUY on a long or a SELL on a short
+	AlignmentAligned = "aligned"
+	// AlignmentOpposed is a BUY on a short or a SELL on a long
+	AlignmentOpposed = "opposed"
+	// AlignmentNew is a BUY or SELL on a ticker that is not held
+	AlignmentNew = "new"
+	// AlignmentNeutral is a HOLD or STRADDLE
+	AlignmentNeutral = "neutral"
+)
+
+// DecisionContext places an analysis's decision in the user's portfolio
+type DecisionContext struct {
+	// Position is the ticker's holding; nil when it is not held
+	Position *Holding `json:"position"`
+	// Side is long, short or flat
+	Side      string `json:"side"`
+	Alignment string `json:"alignment"`
+	// Action reads the decision against the position: open_long, open_short,
+	// add, reduce, cover, hold or none
+	Action string `json:"action"`
+	// Portfolio totals the holdings in the position's currency, or the
+	// analysis's when the ticker is not held
+	Portfolio Summary `json:"portfolio"`
+}
+
+// Context places analysis in userID's portfolio. It returns nil when the user
+// holds nothing, so callers can leave it out for users who do not track a
+// portfolio.
+func Context(ctx context.Context, db *gorm.DB, userID string, analysis *models.TechnicalSignal) (*DecisionContext, error) {
+	valuation, err := Value(ctx, db, userID)
+	if err != nil || len(valuation.Holdings) == 0 {
+		return nil, err
+	}
+
+	dc := &DecisionContext{Side: "flat", Position: valuation.Holding(analysis.Ticker)}
+	currency := analysis.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	held := 0.0
+	if dc.Position != nil {
+		currency = dc.Position.Currency
+		held = math.Copysign(1, dc.Position.Quantity)
+		dc.Side = "long"
+		if held < 0 {
+			dc.Side = "short"
+		}
+	}
+	dc.Portfolio = valuation.Summary(currency)
+
+	direction := 0.0
+	switch analysis.FinalDecision {
+	case "BUY":
+		direction = 1
+	case "SELL":
+		direction = -1
+	}
+	dc.Alignment, dc.Action = classify(direction, held)
+	return dc, nil
+}
+
+// classify reads a decision's direction (+1 BUY, -1 SELL, 0 otherwise)
+// against a position's (+1 long, -1 short, 0 flat)
+func classify(direction, held float64) (string, string) {
+	switch {
+	case direction == 0 && held == 0:
+		return AlignmentNeutral, "none"
+	case direction == 0:
+		return AlignmentNeutral, "hold"
+	case held == 0 && direction > 0:
+		return AlignmentNew, "open_long"
+	case held == 0:
+		return AlignmentNew, "open_short"
+	case direction == held:
+		return AlignmentAligned, "add"
+	case held > 0:
+		return AlignmentOpposed, "reduce"
+	default:
+		return AlignmentOpposed, "cover"
+	}
+}
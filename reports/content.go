@@ -0,0 +1,352 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"institutionanalyser/deepsearch"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// Report is the rendered-format-independent content of one report: a titled
+// table with a few summary lines above it
+type Report struct {
+	Kind        string
+	Title       string
+	PeriodStart string
+	PeriodEnd   string
+	Summary     []string
+	// Sections are further tables shown between the summary and the main
+	// table in HTML and PDF; CSV has the main table only
+	Sections []Section
+	// Chart is a PNG drawn above the summary in PDF, nil for none
+	Chart   []byte
+	Columns []string
+	Rows    [][]string
+}
+
+// Section is a table under a heading
+type Section struct {
+	Heading string
+	Columns []string
+	Rows    [][]string
+}
+
+// earningsPreviewDays is how far ahead the weekly earnings preview looks
+const earningsPreviewDays = 7
+
+// Build assembles the report of kind for the market date
+func Build(ctx context.Context, db *gorm.DB, kind string, date time.Time) (*Report, error) {
+	switch kind {
+	case models.ReportDailyDigest:
+		return buildDailyDigest(ctx, db, date)
+	case models.ReportEarningsPreview:
+		return buildEarningsPreview(ctx, db, date)
+	}
+	return nil, fmt.Errorf("unknown report kind %q (%s or %s)", kind, models.ReportDailyDigest, models.ReportEarningsPreview)
+}
+
+type digestRow struct {
+	ID            uint
+	Ticker        string
+	FinalDecision string
+	Confidence    float64
+	LastClose     float64
+	Currency      string
+	SignalCount   int
+	UserId        string
+	CreatedAt     time.Time
+}
+
+// buildDailyDigest lists the latest analysis of each ticker run during the
+// market day, with the decision mix
+func buildDailyDigest(ctx context.Context, db *gorm.DB, date time.Time) (*Report, error) {
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var rows []digestRow
+	err := db.WithContext(ctx).Raw(`
+		SELECT id, ticker, final_decision, confidence, last_close, currency, signal_count, user_id, created_at
+		FROM (
+			SELECT id, ticker, final_decision, confidence, last_close, currency,
+				`+models.ArrayLength(db, "signals")+` AS signal_count, user_id, created_at,
+				ROW_NUMBER() OVER (PARTITION BY ticker ORDER BY created_at DESC) AS latest_row
+			FROM technical_signals
+			WHERE created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		) latest
+		WHERE latest_row = 1
+		ORDER BY ticker`, dayStart, dayEnd).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	day := dayStart.Format("2006-01-02")
+	report := &Report{
+		Kind:        models.ReportDailyDigest,
+		Title:       "Daily digest " + day,
+		PeriodStart: day,
+		PeriodEnd:   day,
+		Columns:     []string{"Ticker", "Decision", "Confidence", "Last close", "Currency", "Signals", "Analysis", "Analysed at"},
+	}
+
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.FinalDecision]++
+		report.Rows = append(report.Rows, []string{
+			row.Ticker,
+			row.FinalDecision,
+			strconv.FormatFloat(row.Confidence, 'f', 2, 64),
+			strconv.FormatFloat(row.LastClose, 'f', 2, 64),
+			row.Currency,
+			strconv.Itoa(row.SignalCount),
+			strconv.FormatUint(uint64(row.ID), 10),
+			row.CreatedAt.In(date.Location()).Format("15:04"),
+		})
+	}
+	report.Summary = []string{
+		fmt.Sprintf("%d tickers analysed", len(rows)),
+		fmt.Sprintf("BUY %d, SELL %d, STRADDLE %d, HOLD %d", counts["BUY"], counts["SELL"], counts["STRADDLE"], counts["HOLD"]),
+	}
+	return report, nil
+}
+
+// buildEarningsPreview lists announcements in the week after date with their
+// latest estimates and how the EPS estimate moved since it was first seen
+func buildEarningsPreview(ctx context.Context, db *gorm.DB, date time.Time) (*Report, error) {
+	from := date.Format("2006-01-02")
+	to := date.AddDate(0, 0, earningsPreviewDays-1).Format("2006-01-02")
+
+	var estimates []models.EarningsEstimate
+	err := db.WithContext(ctx).
+		Where("report_date >= ? AND report_date <= ?", from, to).
+		Order("report_date, ticker, created_at").
+		Find(&estimates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Kind:        models.ReportEarningsPreview,
+		Title:       fmt.Sprintf("Earnings preview %s to %s", from, to),
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Columns:     []string{"Date", "Time", "Ticker", "Importance", "EPS estimate", "EPS revision", "Revenue estimate"},
+	}
+
+	// Versions are ordered oldest first, so the first of a group is the first
+	// seen and the last is the current estimate
+	for i := 0; i < len(estimates); {
+		j := i
+		for j+1 < len(estimates) && estimates[j+1].Ticker == estimates[i].Ticker && estimates[j+1].ReportDate == estimates[i].ReportDate {
+			j++
+		}
+		first, latest := estimates[i], estimates[j]
+
+		revision := ""
+		if first.EstimatedEPS != nil && latest.EstimatedEPS != nil && i != j {
+			revision = strconv.FormatFloat(*latest.EstimatedEPS-*first.EstimatedEPS, 'f', 2, 64)
+		}
+		report.Rows = append(report.Rows, []string{
+			latest.ReportDate,
+			latest.ReportTime,
+			latest.Ticker,
+			strconv.Itoa(latest.Importance),
+			formatOptional(latest.EstimatedEPS, 2),
+			revision,
+			formatOptional(latest.EstimatedRevenue, 0),
+		})
+		i = j + 1
+	}
+	report.Summary = []string{fmt.Sprintf("%d announcements", len(report.Rows))}
+	return report, nil
+}
+
+// BuildAnalysis lays out one stored analysis for viewing on its own: the
+// decision and window, then its signals. Tags, notes and the owner are left out.
+func BuildAnalysis(analysis *models.TechnicalSignal) *Report {
+	start, end := analysis.StartDate.Format("2006-01-02"), analysis.EndDate.Format("2006-01-02")
+	report := &Report{
+		Kind:        models.ReportAnalysis,
+		Title:       fmt.Sprintf("%s analysis %s to %s", analysis.Ticker, start, end),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Columns:     []string{"Time", "Direction", "Signal", "Close"},
+	}
+	report.Summary = []string{
+		fmt.Sprintf("Decision %s at %.0f%% confidence", analysis.FinalDecision, analysis.Confidence*100),
+		fmt.Sprintf("%d %s bars, last close %.2f %s", analysis.PolyMultiplier, analysis.PolyTimeSpan, analysis.LastClose, analysis.Currency),
+		fmt.Sprintf("Analysed %s", analysis.CreatedAt.UTC().Format("2006-01-02 15:04 UTC")),
+	}
+
+	for _, signal := range deepsearch.ParseSignals(analysis.Signals) {
+		close := ""
+		if signal.Close != 0 {
+			close = strconv.FormatFloat(signal.Close, 'f', 2, 64)
+		}
+		report.Rows = append(report.Rows, []string{signal.Time, signal.Direction, signal.Description, close})
+	}
+	return report
+}
+
+// maxReportContributions caps the signal contributions listed in a ticker report
+const maxReportContributions = 10
+
+// BuildTickerReport lays out one stored analysis for readers who do not use
+// the API: BuildAnalysis's decision and signals, with a price/VWAP chart and
+// the indicators at the last bar from its stored bars, and the
+// decision's rationale. Without stored bars the chart and indicators are left
+// out and the summary says so.
+func BuildTickerReport(ctx context.Context, db *gorm.DB, analysis *models.TechnicalSignal) (*Report, error) {
+	report := BuildAnalysis(analysis)
+	report.Title = fmt.Sprintf("%s report %s to %s", analysis.Ticker, report.PeriodStart, report.PeriodEnd)
+	if analysis.Explanation != nil && analysis.Explanation.Summary != "" {
+		report.Summary = slices.Insert(report.Summary, 1, "Rationale: "+analysis.Explanation.Summary)
+	}
+
+	bars, _, err := deepsearch.AnalysisBars(ctx, db, analysis, false)
+	switch {
+	case errors.Is(err, deepsearch.ErrBarsNotStored):
+		report.Summary = append(report.Summary, "Chart and indicators unavailable: the bars of this analysis are not stored")
+	case err != nil:
+		return nil, err
+	case len(bars) > 0:
+		// A window too short to chart goes without one
+		report.Chart, _ = deepsearch.AnalysisChartPNG(analysis, bars, deepsearch.ChartOptions{})
+		report.Sections = append(report.Sections, indicatorSection(analysis, bars[len(bars)-1]))
+	}
+
+	if explanation := analysis.Explanation; explanation != nil {
+		report.Sections = append(report.Sections, rationaleSections(explanation)...)
+	}
+	return report, nil
+}
+
+// indicatorSection tabulates the indicators at bar, each with a plain-language reading
+func indicatorSection(analysis *models.TechnicalSignal, bar deepsearch.EnhancedBar) Section {
+	price := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	side := func(v float64, name string) string {
+		if bar.Close >= v {
+			return "Close above " + name
+		}
+		return "Close below " + name
+	}
+
+	section := Section{
+		Heading: "Indicators at " + bar.Timestamp.UTC().Format("2006-01-02 15:04 UTC"),
+		Columns: []string{"Indicator", "Value", "Reading"},
+		Rows: [][]string{
+			{"Close", price(bar.Close), analysis.Currency},
+			{"VWAP", price(bar.CumulativeVWAP), side(bar.CumulativeVWAP, "VWAP")},
+		},
+	}
+	if bar.AnchoredVWAP != 0 {
+		section.Rows = append(section.Rows, []string{"Anchored VWAP", price(bar.AnchoredVWAP), side(bar.AnchoredVWAP, "anchored VWAP")})
+	}
+	if bar.ATR != 0 {
+		section.Rows = append(section.Rows, []string{
+			fmt.Sprintf("ATR (%d)", analysis.ATRPeriod), price(bar.ATR),
+			fmt.Sprintf("%.1f%% of close per bar", bar.ATR/bar.Close*100),
+		})
+	}
+	if bar.ADX != 0 {
+		control := "Sellers in control"
+		if bar.PlusDI >= bar.MinusDI {
+			control = "Buyers in control"
+		}
+		market := "Trending market"
+		if deepsearch.BarRegime(bar) == deepsearch.RegimeChoppy {
+			market = "Choppy market, no clear trend"
+		}
+		section.Rows = append(section.Rows,
+			[]string{"ADX", fmt.Sprintf("%.1f", bar.ADX), market},
+			[]string{"+DI / -DI", fmt.Sprintf("%.1f / %.1f", bar.PlusDI, bar.MinusDI), control},
+		)
+	}
+	if bar.BollingerMiddle != 0 {
+		reading := "Close within the bands"
+		switch {
+		case bar.BollingerSqueeze:
+			reading = "Squeeze: volatility at its narrowest"
+		case bar.Close > bar.BollingerUpper:
+			reading = "Close above the upper band"
+		case bar.Close < bar.BollingerLower:
+			reading = "Close below the lower band"
+		}
+		section.Rows = append(section.Rows, []string{
+			"Bollinger bands", fmt.Sprintf("%s / %s / %s", price(bar.BollingerLower), price(bar.BollingerMiddle), price(bar.BollingerUpper)), reading,
+		})
+	}
+	if bar.SuperTrendDirection != 0 {
+		trend := "Downtrend"
+		if bar.SuperTrendDirection > 0 {
+			trend = "Uptrend"
+		}
+		section.Rows = append(section.Rows, []string{"SuperTrend", price(bar.SuperTrend), trend})
+	}
+	obv := ""
+	switch {
+	case bar.OBVDivergence > 0:
+		obv = "Bullish divergence (accumulation)"
+	case bar.OBVDivergence < 0:
+		obv = "Bearish divergence (distribution)"
+	}
+	section.Rows = append(section.Rows,
+		[]string{"OBV", strconv.FormatFloat(bar.OBV, 'f', 0, 64), obv},
+		[]string{"Volume z-score", fmt.Sprintf("%.2f", bar.VolumeZScore), ""},
+	)
+	return section
+}
+
+// rationaleSections tabulates the votes behind a decision and the signals
+// that weighed most in it
+func rationaleSections(explanation *models.DecisionExplanation) []Section {
+	votes := Section{Heading: "Decision rationale", Columns: []string{"Vote", "Signals", "Score"}}
+	directions := make([]string, 0, len(explanation.Votes))
+	for direction := range explanation.Votes {
+		directions = append(directions, direction)
+	}
+	sort.Strings(directions)
+	for _, direction := range directions {
+		score := ""
+		if explanation.Scores != nil {
+			score = fmt.Sprintf("%.2f", explanation.Scores[direction])
+		}
+		votes.Rows = append(votes.Rows, []string{direction, strconv.Itoa(explanation.Votes[direction]), score})
+	}
+
+	contributions := append([]models.SignalContribution{}, explanation.Contributions...)
+	sort.SliceStable(contributions, func(i, j int) bool { return contributions[i].Weight > contributions[j].Weight })
+	if len(contributions) > maxReportContributions {
+		contributions = contributions[:maxReportContributions]
+	}
+	top := Section{Heading: "Top contributing signals", Columns: []string{"Time", "Signal", "Vote", "Weight", "Supports decision"}}
+	for _, contribution := range contributions {
+		supports := "no"
+		if contribution.Supports {
+			supports = "yes"
+		}
+		top.Rows = append(top.Rows, []string{
+			contribution.Time.UTC().Format("2006-01-02 15:04"),
+			contribution.Signal,
+			contribution.Vote,
+			fmt.Sprintf("%.0f%%", contribution.Weight*100),
+			supports,
+		})
+	}
+	return []Section{votes, top}
+}
+
+func formatOptional(v *float64, precision int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', precision, 64)
+}
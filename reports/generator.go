@@ -0,0 +1,127 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"institutionanalyser/alerts"
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+)
+
+// GeneratorConfig holds which formats reports are rendered in and who they are mailed to
+type GeneratorConfig struct {
+	Formats []string
+	// EmailTo receives every report with its files attached; empty disables report emails
+	EmailTo []string
+}
+
+// GetGeneratorConfig reads report settings from environment variables with
+// sensible defaults if not provided
+func GetGeneratorConfig() GeneratorConfig {
+	config := GeneratorConfig{}
+	for _, format := range splitList(os.Getenv("REPORT_FORMATS")) {
+		config.Formats = append(config.Formats, strings.ToLower(format))
+	}
+	if len(config.Formats) == 0 {
+		config.Formats = []string{FormatHTML, FormatCSV}
+	}
+	config.EmailTo = splitList(os.Getenv("REPORT_EMAIL_TO"))
+	return config
+}
+
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Generator builds scheduled reports, writes each format to report storage,
+// records the artifacts and mails them
+type Generator struct {
+	db     *gorm.DB
+	store  Store
+	email  *alerts.EmailNotifier
+	config GeneratorConfig
+}
+
+func NewGenerator(db *gorm.DB, store Store, email *alerts.EmailNotifier, config GeneratorConfig) (*Generator, error) {
+	for _, format := range config.Formats {
+		if _, ok := contentTypes[format]; !ok {
+			return nil, fmt.Errorf("invalid REPORT_FORMATS entry %q (html, csv or pdf)", format)
+		}
+	}
+	return &Generator{db: db, store: store, email: email, config: config}, nil
+}
+
+// Run generates the report of kind for the market date and returns its artifacts
+func (g *Generator) Run(ctx context.Context, kind string, date time.Time) ([]models.ReportArtifact, error) {
+	report, err := Build(ctx, g.db, kind, date)
+	if err != nil {
+		return nil, err
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	artifacts := make([]models.ReportArtifact, 0, len(g.config.Formats))
+	attachments := make([]alerts.Attachment, 0, len(g.config.Formats))
+	for _, format := range g.config.Formats {
+		body, contentType, err := Render(report, format)
+		if err != nil {
+			return artifacts, err
+		}
+
+		name := fmt.Sprintf("%s_%s_%s.%s", kind, report.PeriodStart, stamp, format)
+		key := path.Join(kind, report.PeriodStart, name)
+		location, err := g.store.Put(ctx, key, contentType, body)
+		if err != nil {
+			return artifacts, fmt.Errorf("failed to store %s: %w", key, err)
+		}
+
+		artifacts = append(artifacts, models.ReportArtifact{
+			Kind:        kind,
+			PeriodStart: report.PeriodStart,
+			PeriodEnd:   report.PeriodEnd,
+			Format:      format,
+			ContentType: contentType,
+			Size:        len(body),
+			Storage:     g.store.Backend(),
+			Key:         key,
+			Location:    location,
+		})
+		attachments = append(attachments, alerts.Attachment{Name: name, ContentType: contentType, Data: body})
+	}
+
+	emailed := false
+	if g.email.Enabled() && len(g.config.EmailTo) > 0 {
+		email := alerts.Email{
+			To:          g.config.EmailTo,
+			Subject:     report.Title,
+			Text:        strings.Join(report.Summary, "\n") + "\n\nThe full report is attached.\n",
+			Attachments: attachments,
+		}
+		if err := g.email.Send(email); err != nil {
+			fmt.Printf("[reports] failed to email %s: %v\n", report.Title, err)
+		} else {
+			emailed = true
+		}
+	}
+
+	for i := range artifacts {
+		artifacts[i].Emailed = emailed
+	}
+	if err := g.db.WithContext(ctx).Create(&artifacts).Error; err != nil {
+		return artifacts, err
+	}
+
+	fmt.Printf("[reports] %s: %d rows, %d files to %s\n", report.Title, len(report.Rows), len(artifacts), g.store.Backend())
+	return artifacts, nil
+}
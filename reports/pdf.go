@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// PDF page layout, in points (US Letter)
+const (
+	pdfPageWidth    = 612
+	pdfPageHeight   = 792
+	pdfMargin       = 50
+	pdfFontSize     = 9
+	pdfLineHeight   = 12
+	pdfTitleSize    = 14
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin - 2*pdfLineHeight) / pdfLineHeight
+)
+
+// pdfImage is a chart converted to a Flate-compressed RGB image, drawn at
+// the top of the first page across the text width
+type pdfImage struct {
+	width, height int
+	data          []byte
+	// drawWidth and drawHeight are its size on the page, in points
+	drawWidth, drawHeight float64
+}
+
+// newPDFImage decodes a PNG, flattening transparency onto white, and scales
+// it to the text width but no more than half the page height
+func newPDFImage(pngData []byte) (*pdfImage, error) {
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		return nil, fmt.Errorf("decoding chart: %w", err)
+	}
+	bounds := img.Bounds()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	row := make([]byte, 3*bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			i := 3 * (x - bounds.Min.X)
+			row[i], row[i+1], row[i+2] = byte((r+0xffff-a)>>8), byte((g+0xffff-a)>>8), byte((b+0xffff-a)>>8)
+		}
+		zw.Write(row)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	image := &pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: compressed.Bytes()}
+	image.drawWidth = pdfPageWidth - 2*pdfMargin
+	image.drawHeight = image.drawWidth * float64(image.height) / float64(image.width)
+	if maxHeight := float64(pdfPageHeight-2*pdfMargin) / 2; image.drawHeight > maxHeight {
+		image.drawWidth *= maxHeight / image.drawHeight
+		image.drawHeight = maxHeight
+	}
+	return image, nil
+}
+
+// lines returns how many text lines the image takes up, with a blank line below
+func (image *pdfImage) lines() int {
+	return int(math.Ceil(image.drawHeight/pdfLineHeight)) + 1
+}
+
+// renderPDF lays out a title, an optional chart and monospaced text lines
+// over as many pages as needed. Reports are tabular text, so a minimal
+// hand-written PDF with the built-in Courier font is enough and needs no
+// dependency.
+func renderPDF(title string, chart *pdfImage, lines []string) []byte {
+	var pages [][]string
+	capacity := pdfLinesPerPage
+	if chart != nil {
+		capacity -= chart.lines()
+	}
+	for len(pages) == 0 || len(lines) > 0 {
+		n := min(capacity, len(lines))
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+		capacity = pdfLinesPerPage
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 font, 4 bold font, 5 the chart if
+	// any, then a page and its content stream for each page
+	first := 5
+	resources := "/Font << /F1 3 0 R /F2 4 0 R >>"
+	if chart != nil {
+		first = 6
+		resources += " /XObject << /Im1 5 0 R >>"
+	}
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", first+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>",
+	)
+	if chart != nil {
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+			chart.width, chart.height, len(chart.data), chart.data))
+	}
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		y := pdfPageHeight - pdfMargin
+		fmt.Fprintf(&content, "BT /F2 %d Tf %d %d Td (%s) Tj ET\n", pdfTitleSize, pdfMargin, y, pdfEscape(title))
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (Page %d of %d) Tj ET\n", pdfFontSize, pdfPageWidth-pdfMargin-80, y, i+1, len(pages))
+		y -= 2 * pdfLineHeight
+		if i == 0 && chart != nil {
+			top := float64(y + pdfFontSize)
+			fmt.Fprintf(&content, "q %.2f 0 0 %.2f %d %.2f cm /Im1 Do Q\n", chart.drawWidth, chart.drawHeight, pdfMargin, top-chart.drawHeight)
+			y -= chart.lines() * pdfLineHeight
+		}
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, y)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+		}
+		content.WriteString("ET\n")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << %s >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, resources, first+1+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape escapes a string literal and drops characters the standard fonts
+// cannot show
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
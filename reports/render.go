@@ -0,0 +1,109 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Report formats
+const (
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+	FormatPDF  = "pdf"
+)
+
+var contentTypes = map[string]string{
+	FormatHTML: "text/html; charset=utf-8",
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatPDF:  "application/pdf",
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif">
+<h2>{{.Title}}</h2>
+{{range .Summary}}<p>{{.}}</p>
+{{end}}{{range .Sections}}<h3>{{.Heading}}</h3>
+{{template "table" .}}{{end}}{{template "table" .}}</body>
+</html>
+{{define "table"}}<table border="1" cellpadding="4" cellspacing="0" style="border-collapse: collapse">
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}`))
+
+// Render returns the report in format and its content type
+func Render(report *Report, format string) ([]byte, string, error) {
+	switch format {
+	case FormatHTML:
+		var buf bytes.Buffer
+		if err := reportTemplate.Execute(&buf, report); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), contentTypes[format], nil
+	case FormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(report.Columns)
+		w.WriteAll(report.Rows)
+		if err := w.Error(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), contentTypes[format], nil
+	case FormatPDF:
+		var chart *pdfImage
+		if report.Chart != nil {
+			var err error
+			if chart, err = newPDFImage(report.Chart); err != nil {
+				return nil, "", err
+			}
+		}
+		return renderPDF(report.Title, chart, textLines(report)), contentTypes[format], nil
+	}
+	return nil, "", fmt.Errorf("unknown report format %q (html, csv or pdf)", format)
+}
+
+// textLines lays the report out as fixed-width text for the PDF
+func textLines(report *Report) []string {
+	lines := append([]string{}, report.Summary...)
+	for _, section := range report.Sections {
+		lines = append(lines, "", section.Heading)
+		lines = append(lines, tableLines(section.Columns, section.Rows)...)
+	}
+	lines = append(lines, "")
+	return append(lines, tableLines(report.Columns, report.Rows)...)
+}
+
+// tableLines lays a table out as fixed-width text, a line per row under a
+// header line
+func tableLines(columns []string, rows [][]string) []string {
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = len(column)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	format := func(cells []string) string {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		return strings.TrimRight(strings.Join(parts, "  "), " ")
+	}
+
+	lines := []string{format(columns)}
+	for _, row := range rows {
+		lines = append(lines, format(row))
+	}
+	return lines
+}
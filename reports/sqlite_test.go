@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"institutionanalyser/models"
+)
+
+func TestSQLiteDailyDigest(t *testing.T) {
+	db, err := models.InitDatabase("sqlite://" + t.TempDir() + "/reports.db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	defer models.CloseDatabase(db)
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	for i, analysis := range []models.TechnicalSignal{
+		{Ticker: "AAPL", FinalDecision: "SELL", Signals: models.StringArray{"10:05 PUT: RSI Overbought at 181.20"}},
+		{Ticker: "AAPL", FinalDecision: "BUY", Signals: models.StringArray{"10:30 CALL: Bollinger Breakout at 182.10", "11:00 UP: Volume Spike at 183.00"}},
+		{Ticker: "MSFT", FinalDecision: "HOLD", Signals: models.StringArray{}},
+	} {
+		analysis.CreatedAt = day.Add(time.Duration(15*60+i) * time.Minute)
+		analysis.StartDate = day
+		analysis.EndDate = day
+		if err := db.Create(&analysis).Error; err != nil {
+			t.Fatalf("seed analysis: %v", err)
+		}
+	}
+
+	report, err := buildDailyDigest(context.Background(), db, day)
+	if err != nil {
+		t.Fatalf("buildDailyDigest: %v", err)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("rows = %v, want the latest AAPL and MSFT analyses", report.Rows)
+	}
+	// Ticker, decision and signal count
+	if got := report.Rows[0]; got[0] != "AAPL" || got[1] != "BUY" || got[5] != "2" {
+		t.Errorf("AAPL row = %v, want BUY with 2 signals", got)
+	}
+	if got := report.Rows[1]; got[0] != "MSFT" || got[5] != "0" {
+		t.Errorf("MSFT row = %v, want 0 signals", got)
+	}
+}
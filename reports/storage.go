@@ -0,0 +1,304 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/service"
+)
+
+// Storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageGCS   = "gcs"
+)
+
+// gcsEndpoint is Cloud Storage's S3-compatible XML API, used with HMAC keys
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// Signed URL lifetimes; SigV4 presigned URLs last at most seven days
+const (
+	defaultSignedURLTTL = time.Hour
+	maxSignedURLTTL     = 7 * 24 * time.Hour
+)
+
+// StorageConfig holds where report artifacts are written
+type StorageConfig struct {
+	Backend string
+	// Prefix is prepended to every object key
+	Prefix string
+	// LocalDir is the directory for the local backend
+	LocalDir string
+	Bucket   string
+	Region   string
+	// Endpoint overrides the S3 endpoint (MinIO, R2, ...); buckets are then addressed path-style
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SignedURLTTL is how long signed download URLs stay valid
+	SignedURLTTL time.Duration
+}
+
+// GetStorageConfig reads report storage settings from environment variables
+// with sensible defaults if not provided
+func GetStorageConfig() StorageConfig {
+	config := StorageConfig{
+		Backend:         strings.ToLower(os.Getenv("REPORT_STORAGE")),
+		Prefix:          strings.Trim(os.Getenv("REPORT_PREFIX"), "/"),
+		LocalDir:        os.Getenv("REPORT_LOCAL_DIR"),
+		Bucket:          os.Getenv("REPORT_BUCKET"),
+		Region:          os.Getenv("REPORT_S3_REGION"),
+		Endpoint:        strings.TrimSuffix(os.Getenv("REPORT_S3_ENDPOINT"), "/"),
+		AccessKeyID:     os.Getenv("REPORT_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("REPORT_SECRET_ACCESS_KEY"),
+	}
+	if config.Backend == "" {
+		config.Backend = StorageLocal
+	}
+	if config.LocalDir == "" {
+		config.LocalDir = "data/reports"
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.SignedURLTTL = defaultSignedURLTTL
+	if ttl, err := time.ParseDuration(os.Getenv("REPORT_SIGNED_URL_TTL")); err == nil && ttl > 0 {
+		config.SignedURLTTL = min(ttl, maxSignedURLTTL)
+	}
+	if config.Backend == StorageGCS {
+		config.Endpoint = gcsEndpoint
+		config.Region = "auto"
+	}
+	return config
+}
+
+// Store writes report artifacts and rendered charts
+type Store interface {
+	// Put writes body under key (after REPORT_PREFIX) and returns where it can be found
+	Put(ctx context.Context, key, contentType string, body []byte) (location string, err error)
+	// SignedURL returns a URL that downloads the object at key without
+	// credentials until expiresAt; url is empty when the backend cannot issue one
+	SignedURL(key string) (url string, expiresAt time.Time, err error)
+	Backend() string
+}
+
+// StoredObject is an object written to storage, as returned by the API
+type StoredObject struct {
+	Storage  string `json:"storage"`
+	Key      string `json:"key"`
+	Location string `json:"location"`
+	// URL is a signed download URL, empty for local storage
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Size      int        `json:"size"`
+}
+
+// Upload writes body to store under key and signs a download URL for it
+func Upload(ctx context.Context, store Store, key, contentType string, body []byte) (*StoredObject, error) {
+	location, err := store.Put(ctx, key, contentType, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	object := &StoredObject{Storage: store.Backend(), Key: key, Location: location, Size: len(body)}
+	url, expiresAt, err := store.SignedURL(key)
+	if err != nil {
+		return nil, err
+	}
+	if url != "" {
+		object.URL, object.ExpiresAt = url, &expiresAt
+	}
+	return object, nil
+}
+
+// NewStore returns the configured backend
+func NewStore(config StorageConfig) (Store, error) {
+	switch config.Backend {
+	case StorageLocal:
+		return localStore{dir: config.LocalDir, prefix: config.Prefix}, nil
+	case StorageS3, StorageGCS:
+		if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+			return nil, fmt.Errorf("%s report storage needs REPORT_BUCKET, REPORT_ACCESS_KEY_ID and REPORT_SECRET_ACCESS_KEY", config.Backend)
+		}
+		return &s3Store{config: config}, nil
+	}
+	return nil, fmt.Errorf("invalid REPORT_STORAGE %q (local, s3 or gcs)", config.Backend)
+}
+
+type localStore struct {
+	dir    string
+	prefix string
+}
+
+func (s localStore) Backend() string { return StorageLocal }
+
+func (s localStore) SignedURL(string) (string, time.Time, error) { return "", time.Time{}, nil }
+
+func (s localStore) Put(_ context.Context, key, _ string, body []byte) (string, error) {
+	file := filepath.Join(s.dir, filepath.FromSlash(path.Join(s.prefix, key)))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, body, 0o644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// s3Store uploads with SigV4-signed PUTs, which S3, Cloud Storage (HMAC keys)
+// and S3-compatible stores all accept
+type s3Store struct {
+	config StorageConfig
+}
+
+func (s *s3Store) Backend() string { return s.config.Backend }
+
+func (s *s3Store) Put(ctx context.Context, key, contentType string, body []byte) (string, error) {
+	key = path.Join(s.config.Prefix, key)
+	target, host, canonicalPath := s.objectURL(key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, host, canonicalPath, body, time.Now().UTC())
+
+	resp, err := service.HTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s upload of %s returned %d: %s", s.config.Backend, key, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	scheme := "s3://"
+	if s.config.Backend == StorageGCS {
+		scheme = "gs://"
+	}
+	return scheme + s.config.Bucket + "/" + key, nil
+}
+
+// SignedURL presigns a GET with SigV4 query parameters, which S3 and Cloud
+// Storage's XML API both accept
+func (s *s3Store) SignedURL(key string) (string, time.Time, error) {
+	now := time.Now().UTC()
+	target, host, canonicalPath := s.objectURL(path.Join(s.config.Prefix, key))
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := path.Join(date, s.config.Region, "s3", "aws4_request")
+
+	// Parameters in the sorted order of the canonical query string
+	query := strings.Join([]string{
+		"X-Amz-Algorithm=AWS4-HMAC-SHA256",
+		"X-Amz-Credential=" + escapeQuery(s.config.AccessKeyID+"/"+scope),
+		"X-Amz-Date=" + amzDate,
+		"X-Amz-Expires=" + strconv.Itoa(int(s.config.SignedURLTTL.Seconds())),
+		"X-Amz-SignedHeaders=host",
+	}, "&")
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, canonicalPath, query, "host:" + host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	return target + "?" + query + "&X-Amz-Signature=" + s.signature(date, stringToSign), now.Add(s.config.SignedURLTTL), nil
+}
+
+// objectURL addresses the object virtual-hosted style on AWS and path style
+// on a custom endpoint
+func (s *s3Store) objectURL(key string) (target, host, canonicalPath string) {
+	escapedKey := escapePath(key)
+	if s.config.Endpoint == "" {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", s.config.Bucket, s.config.Region)
+		canonicalPath = "/" + escapedKey
+		return "https://" + host + canonicalPath, host, canonicalPath
+	}
+
+	scheme, rest, found := strings.Cut(s.config.Endpoint, "://")
+	if !found {
+		scheme, rest = "https", s.config.Endpoint
+	}
+	host = rest
+	canonicalPath = "/" + escapePath(s.config.Bucket) + "/" + escapedKey
+	return scheme + "://" + host + canonicalPath, host, canonicalPath
+}
+
+// sign adds AWS Signature Version 4 headers for an unsigned-query PUT
+func (s *s3Store) sign(req *http.Request, host, canonicalPath string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Host = host
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), host, payloadHash, amzDate)
+	canonicalRequest := strings.Join([]string{
+		http.MethodPut, canonicalPath, "", canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := path.Join(date, s.config.Region, "s3", "aws4_request")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, s.signature(date, stringToSign)))
+}
+
+// signature signs stringToSign with the SigV4 key for date
+func (s *s3Store) signature(date, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapeQuery URI-encodes a query parameter value as SigV4 requires
+func escapeQuery(value string) string {
+	return strings.ReplaceAll(escapePath(value), "/", "%2F")
+}
+
+// escapePath URI-encodes an object key as SigV4 requires: everything except
+// unreserved characters and the path separator
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -1,31 +1,246 @@
 package routes
 
 import (
+	"institutionanalyser/events"
 	"institutionanalyser/handlers"
+	"institutionanalyser/jobs"
+	"institutionanalyser/middleware"
+	"institutionanalyser/models"
+	"institutionanalyser/reports"
+	"institutionanalyser/tracing"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB) {
+func SetupRoutes(router *gin.Engine, db *gorm.DB, queue *jobs.AnalysisQueue, hub *events.Hub, generator *reports.Generator, store reports.Store, syncer *jobs.TickerSyncer) {
 	// CORS configuration
 	router.Use(cors.New(cors.Config{
 		AllowOrigins: []string{
 			"http://localhost:3000",
 		},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.APIKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", "Deprecation", "Sunset", "Link", middleware.RequestIDHeader, handlers.NextCursorHeader, handlers.HasMoreHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600, // 12 hours
 	}))
 
-	deepSearchHandler := handlers.NewDeepSearchHandler(db)
-	earningsBigMoneyHandler := handlers.NewEarningsBigMoneyHandler()
+	// Dashboard, analytics and export reads go to the read replica when one is configured
+	readDB := models.ReadReplica(db)
 
-	router.GET("/api/v1/deepsearch/analysis", deepSearchHandler.HandleGetAnalysis)
-	router.POST("/api/v1/deepsearch/trigger", deepSearchHandler.HandleTriggerAnalysis)
-	router.GET("/api/v1/earnings/bigmoney", earningsBigMoneyHandler.GetEarningsWithBigMoney)
+	deepSearchHandler := handlers.NewDeepSearchHandler(db, queue, store)
+	earningsBigMoneyHandler := handlers.NewEarningsBigMoneyHandler(db)
+	earningsHandler := handlers.NewEarningsHandler(db)
+	decisionsHandler := handlers.NewDecisionsHandler(db)
+	annotationsHandler := handlers.NewAnnotationsHandler(db)
+	archiveHandler := handlers.NewArchiveHandler(db)
+	presetsHandler := handlers.NewPresetsHandler(db)
+	configTransferHandler := handlers.NewConfigTransferHandler(db)
+	ingestHandler := handlers.NewIngestHandler(db)
+	tickersHandler := handlers.NewTickersHandler(db, syncer)
+	authHandler := handlers.NewAuthHandler(db)
+	apiKeysHandler := handlers.NewAPIKeysHandler(db)
+	technicalsHandler := handlers.NewTechnicalsHandler()
+	streamHandler := handlers.NewStreamHandler(hub, db)
+	jobsAdminHandler := handlers.NewJobsAdminHandler(db, queue)
+	alertsHandler := handlers.NewAlertsHandler(db)
+	integrationsHandler := handlers.NewIntegrationsHandler(db)
+	replayHandler := handlers.NewReplayHandler(db)
+	enhancedBarsHandler := handlers.NewEnhancedBarsHandler(db)
+	sandboxHandler := handlers.NewSandboxHandler(db)
+	reportsHandler := handlers.NewReportsHandler(db, generator, store)
+	decisionRulesHandler := handlers.NewDecisionRulesHandler(db)
+	footprintsHandler := handlers.NewFootprintsHandler(db)
+	blockTradesHandler := handlers.NewBlockTradesHandler(db)
+	confirmationsHandler := handlers.NewConfirmationsHandler(db)
+	insidersHandler := handlers.NewInsidersHandler(db)
+	ftdHandler := handlers.NewFTDHandler(db)
+	shareHandler := handlers.NewShareHandler(db)
+	performanceHandler := handlers.NewPerformanceHandler(readDB)
+	strategiesHandler := handlers.NewStrategiesHandler(db)
+	paperTradesHandler := handlers.NewPaperTradesHandler(db)
+	brokerHandler := handlers.NewBrokerHandler(db)
+	portfolioHandler := handlers.NewPortfolioHandler(db)
+	exportHandler := handlers.NewExportHandler(readDB)
+	marketHandler := handlers.NewMarketHandler(readDB)
+	analyticsHandler := handlers.NewAnalyticsHandler(readDB)
+	optionsHandler := handlers.NewOptionsHandler(db)
+	quotesHandler := handlers.NewQuotesHandler()
+	usageHandler := handlers.NewUsageHandler(db)
+	graphQLHandler := handlers.NewGraphQLHandler(readDB)
 
+	// Shared middleware chain for every API version
+	api := router.Group("/api",
+		otelgin.Middleware(tracing.ServiceName),
+		middleware.RequestLogger(),
+		middleware.RateLimit(middleware.GetRateLimitConfig()),
+	)
+
+	// Signup and login issue tokens, so they sit outside Authenticate
+	authRoutes := api.Group("/v1/auth")
+	{
+		authRoutes.POST("/signup", authHandler.HandleSignup)
+		authRoutes.POST("/login", authHandler.HandleLogin)
+	}
+
+	// Share links carry their own signed token, so they sit outside Authenticate
+	api.GET("/v1/shared/:token", shareHandler.HandleViewShared)
+
+	authenticated := api.Group("", middleware.Authenticate(db), middleware.MeterPolygonUsage())
+
+	v1 := authenticated.Group("/v1")
+	{
+		v1.GET("/auth/me", authHandler.HandleMe)
+		v1.GET("/apikeys", apiKeysHandler.HandleListAPIKeys)
+		v1.POST("/apikeys", apiKeysHandler.HandleCreateAPIKey)
+		v1.DELETE("/apikeys/:id", apiKeysHandler.HandleRevokeAPIKey)
+		v1.GET("/deepsearch/analysis",
+			middleware.Deprecated(middleware.Deprecation{Successor: "/api/v2/deepsearch/analysis"}),
+			middleware.RequireScope(models.ScopeDeepsearchRead),
+			deepSearchHandler.HandleGetAnalysis)
+		v1.POST("/deepsearch/trigger", middleware.RequireScope(models.ScopeDeepsearchTrigger), deepSearchHandler.HandleTriggerAnalysis)
+		v1.POST("/deepsearch/compare", middleware.RequireScope(models.ScopeDeepsearchTrigger), deepSearchHandler.HandleCompareWindows)
+		v1.POST("/deepsearch/sector", middleware.RequireScope(models.ScopeDeepsearchTrigger), deepSearchHandler.HandleAnalyseSector)
+		v1.GET("/deepsearch/sectors", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleListSectors)
+		v1.POST("/deepsearch/technical-decision", middleware.RequireScope(models.ScopeDeepsearchTrigger), deepSearchHandler.HandleTechnicalDecision)
+		v1.GET("/deepsearch/history", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetHistory)
+		v1.GET("/deepsearch/jobs/:id", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetJob)
+		v1.GET("/deepsearch/chart", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetChart)
+		v1.GET("/deepsearch/chart/series", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetChartSeries)
+		v1.GET("/deepsearch/signal-weights", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetSignalWeights)
+		v1.GET("/deepsearch/analysis/:id/outcomes", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetOutcomes)
+		v1.GET("/deepsearch/analysis/:id/bars", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetAnalysisBars)
+		v1.GET("/deepsearch/analysis/:id/features", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetAnalysisFeatures)
+		v1.GET("/deepsearch/analysis/:id/report", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetAnalysisReport)
+		v1.POST("/deepsearch/analysis/:id/share", middleware.RequireScope(models.ScopeDeepsearchRead), shareHandler.HandleShareAnalysis)
+		v1.GET("/earnings/bigmoney", earningsBigMoneyHandler.GetEarningsWithBigMoney)
+		v1.GET("/earnings/bigmoney/watch", earningsBigMoneyHandler.HandleGetBigMoneyWatch)
+		v1.GET("/earnings/revisions", earningsHandler.HandleGetEstimateRevisions)
+		v1.GET("/earnings/review", earningsHandler.HandleGetEarningsReview)
+		v1.POST("/decisions/latest", decisionsHandler.HandleLatestDecisions)
+		v1.POST("/decisions/exposure", decisionsHandler.HandleExposure)
+		v1.GET("/decide/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), decisionsHandler.HandleQuickDecision)
+		v1.PATCH("/deepsearch/analysis/:id/annotations", annotationsHandler.HandleUpdateAnnotations)
+		v1.GET("/deepsearch/analyses/tagged", annotationsHandler.HandleListByTag)
+		v1.GET("/tags", annotationsHandler.HandleListTags)
+		v1.POST("/deepsearch/analysis/:id/archive", archiveHandler.HandleArchiveAnalysis)
+		v1.POST("/deepsearch/analysis/:id/restore", archiveHandler.HandleRestoreAnalysis)
+		v1.DELETE("/deepsearch/analysis/:id", archiveHandler.HandleDeleteAnalysis)
+		v1.POST("/deepsearch/history/archive", archiveHandler.HandleArchiveHistory)
+		v1.GET("/presets", presetsHandler.HandleListPresets)
+		v1.POST("/presets", presetsHandler.HandleSavePreset)
+		v1.DELETE("/presets/:name", presetsHandler.HandleDeletePreset)
+		v1.GET("/strategies", strategiesHandler.HandleListStrategies)
+		v1.GET("/strategies/:name", strategiesHandler.HandleGetStrategy)
+		v1.POST("/strategies", strategiesHandler.HandleSaveStrategy)
+		v1.DELETE("/strategies/:name", strategiesHandler.HandleDeleteStrategy)
+		v1.GET("/config/export", configTransferHandler.HandleExport)
+		v1.POST("/config/import", configTransferHandler.HandleImport)
+		v1.GET("/tickers", tickersHandler.HandleListTickers)
+		v1.GET("/tickers/:ticker", tickersHandler.HandleGetTicker)
+		v1.GET("/technicals/summary", technicalsHandler.HandleGetSummary)
+		v1.GET("/technicals/summary/:id", technicalsHandler.HandleGetSummaryRemainder)
+		v1.GET("/ws", middleware.RequireScope(models.ScopeDeepsearchRead), streamHandler.HandleStream)
+		v1.GET("/replay/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), replayHandler.HandleReplay)
+		v1.GET("/replay/:ticker/simulation", middleware.RequireScope(models.ScopeDeepsearchRead), replayHandler.HandleSimulation)
+		v1.GET("/bars/enhanced/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), enhancedBarsHandler.HandleGetEnhancedBars)
+		v1.POST("/sandbox/evaluate", middleware.RequireScope(models.ScopeDeepsearchRead), sandboxHandler.HandleEvaluate)
+		v1.GET("/footprints/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), footprintsHandler.HandleGetFootprints)
+		v1.GET("/blocks/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), blockTradesHandler.HandleGetBlockTrades)
+		v1.GET("/confirmations/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), confirmationsHandler.HandleGetConfirmedMoves)
+		v1.GET("/insiders/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), insidersHandler.HandleGetInsiders)
+		v1.GET("/ftd/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), ftdHandler.HandleGetFailsToDeliver)
+		v1.GET("/performance", middleware.RequireScope(models.ScopeDeepsearchRead), performanceHandler.HandleGetPerformance)
+		v1.GET("/performance/attribution", middleware.RequireScope(models.ScopeDeepsearchRead), performanceHandler.HandleGetAttribution)
+		v1.GET("/paper/trades", middleware.RequireScope(models.ScopeDeepsearchRead), paperTradesHandler.HandleListPaperTrades)
+		v1.GET("/paper/pnl", middleware.RequireScope(models.ScopeDeepsearchRead), paperTradesHandler.HandleGetPaperPnL)
+		v1.POST("/signals/search", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleSearchSignals)
+		v1.POST("/graphql", middleware.RequireScope(models.ScopeDeepsearchRead), graphQLHandler.HandleQuery)
+		v1.GET("/graphql", middleware.RequireScope(models.ScopeDeepsearchRead), graphQLHandler.HandleQuery)
+		v1.GET("/graphql/schema", graphQLHandler.HandleSchema)
+		v1.GET("/export/decisions", middleware.RequireScope(models.ScopeDeepsearchRead), exportHandler.HandleExportDecisions)
+		v1.GET("/market/breadth", middleware.RequireScope(models.ScopeDeepsearchRead), marketHandler.HandleGetBreadth)
+		v1.GET("/analytics/heatmap", middleware.RequireScope(models.ScopeDeepsearchRead), analyticsHandler.HandleGetHeatmap)
+		v1.GET("/options/chain/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), optionsHandler.HandleGetChain)
+		v1.GET("/options/max-pain/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), optionsHandler.HandleGetMaxPain)
+		v1.GET("/options/max-pain/:ticker/history", middleware.RequireScope(models.ScopeDeepsearchRead), optionsHandler.HandleGetMaxPainHistory)
+		v1.GET("/options/iv-rank/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), optionsHandler.HandleGetIVRank)
+		v1.GET("/options/put-call/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), optionsHandler.HandleGetPutCallHistory)
+		v1.GET("/quote/:ticker", middleware.RequireScope(models.ScopeDeepsearchRead), quotesHandler.HandleGetQuote)
+		v1.GET("/quotes", middleware.RequireScope(models.ScopeDeepsearchRead), quotesHandler.HandleGetQuotes)
+		v1.GET("/alerts", alertsHandler.HandleListAlertRules)
+		v1.POST("/alerts", alertsHandler.HandleCreateAlertRule)
+		v1.PATCH("/alerts/:id", alertsHandler.HandleUpdateAlertRule)
+		v1.DELETE("/alerts/:id", alertsHandler.HandleDeleteAlertRule)
+		v1.GET("/alerts/:id/deliveries", alertsHandler.HandleListAlertDeliveries)
+		v1.GET("/alerts/channels", alertsHandler.HandleListNotificationChannels)
+		v1.POST("/alerts/channels", alertsHandler.HandleCreateNotificationChannel)
+		v1.PATCH("/alerts/channels/:id", alertsHandler.HandleUpdateNotificationChannel)
+		v1.DELETE("/alerts/channels/:id", alertsHandler.HandleDeleteNotificationChannel)
+		v1.POST("/alerts/templates/preview", alertsHandler.HandlePreviewTemplate)
+		v1.POST("/alerts/channels/:id/test", alertsHandler.HandleTestNotificationChannel)
+		v1.GET("/portfolio/positions", portfolioHandler.HandleGetPositions)
+		v1.GET("/portfolio/transactions", portfolioHandler.HandleListTransactions)
+		v1.POST("/portfolio/transactions", portfolioHandler.HandleCreateTransaction)
+		v1.DELETE("/portfolio/transactions/:id", portfolioHandler.HandleDeleteTransaction)
+		v1.GET("/broker/account", brokerHandler.HandleGetBrokerAccount)
+		v1.PUT("/broker/account", brokerHandler.HandleSaveBrokerAccount)
+		v1.DELETE("/broker/account", brokerHandler.HandleDeleteBrokerAccount)
+		v1.GET("/broker/rules", brokerHandler.HandleListExecutionRules)
+		v1.POST("/broker/rules", brokerHandler.HandleCreateExecutionRule)
+		v1.PATCH("/broker/rules/:id", brokerHandler.HandleUpdateExecutionRule)
+		v1.DELETE("/broker/rules/:id", brokerHandler.HandleDeleteExecutionRule)
+		v1.GET("/broker/orders", brokerHandler.HandleListBrokerOrders)
+		v1.POST("/broker/kill-switch", brokerHandler.HandleEngageKillSwitch)
+		v1.DELETE("/broker/kill-switch", brokerHandler.HandleReleaseKillSwitch)
+		v1.GET("/integrations", integrationsHandler.HandleListIntegrations)
+		v1.POST("/integrations", integrationsHandler.HandleCreateIntegration)
+		v1.GET("/integrations/fields", integrationsHandler.HandleListIntegrationFields)
+		v1.PATCH("/integrations/:id", integrationsHandler.HandleUpdateIntegration)
+		v1.DELETE("/integrations/:id", integrationsHandler.HandleDeleteIntegration)
+		v1.POST("/integrations/:id/test", integrationsHandler.HandleTestIntegration)
+		v1.GET("/integrations/:id/deliveries", integrationsHandler.HandleListIntegrationDeliveries)
+	}
+
+	admin := v1.Group("/admin", middleware.RequireScope(models.ScopeAdmin), middleware.RequireAdmin())
+	{
+		admin.POST("/ingest/grouped-daily", ingestHandler.HandleIngestGroupedDaily)
+		admin.POST("/ingest/tickers", tickersHandler.HandleSyncTickers)
+		admin.POST("/bars/compact", ingestHandler.HandleCompactBars)
+		admin.GET("/retention", ingestHandler.HandleGetRetention)
+		admin.POST("/retention/run", ingestHandler.HandleRunRetention)
+		admin.POST("/footprints/:ticker", footprintsHandler.HandleBuildFootprint)
+		admin.POST("/blocks/:ticker", blockTradesHandler.HandleDetectBlockTrades)
+		admin.POST("/confirmations/:ticker", confirmationsHandler.HandleConfirmMoves)
+		admin.POST("/insiders/:ticker", insidersHandler.HandleIngestInsiders)
+		admin.POST("/ftd", ftdHandler.HandleIngestFailsToDeliver)
+		admin.GET("/jobs/failed", jobsAdminHandler.HandleListFailedJobs)
+		admin.POST("/jobs/failed/retry", jobsAdminHandler.HandleRetryAllFailedJobs)
+		admin.POST("/jobs/failed/:id/retry", jobsAdminHandler.HandleRetryFailedJob)
+		admin.GET("/reports", reportsHandler.HandleListReports)
+		admin.POST("/reports/:kind", reportsHandler.HandleGenerateReport)
+		admin.POST("/reports/artifacts/:id/share", shareHandler.HandleShareReport)
+		admin.GET("/decision-rules", decisionRulesHandler.HandleListDecisionRules)
+		admin.POST("/decision-rules", decisionRulesHandler.HandleCreateDecisionRule)
+		admin.POST("/decision-rules/evaluate", decisionRulesHandler.HandleEvaluateDecisionRules)
+		admin.PATCH("/decision-rules/:id", decisionRulesHandler.HandleUpdateDecisionRule)
+		admin.DELETE("/decision-rules/:id", decisionRulesHandler.HandleDeleteDecisionRule)
+		admin.GET("/usage/polygon", usageHandler.HandleGetPolygonUsage)
+		admin.POST("/broker/kill-switch", brokerHandler.HandleEngageGlobalKillSwitch)
+		admin.DELETE("/broker/kill-switch", brokerHandler.HandleReleaseGlobalKillSwitch)
+	}
+
+	v2 := authenticated.Group("/v2")
+	{
+		v2.GET("/deepsearch/analysis", middleware.RequireScope(models.ScopeDeepsearchRead), deepSearchHandler.HandleGetAnalysisV2)
+	}
+
+	// The OpenAPI document describes every route registered on the router,
+	// so these are registered last; they need no credentials
+	docsHandler := handlers.NewDocsHandler(router)
+	router.GET("/openapi.json", docsHandler.HandleSpec)
+	router.GET("/docs", docsHandler.HandleUI)
 }
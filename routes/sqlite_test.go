@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"institutionanalyser/events"
+	"institutionanalyser/handlers"
+	"institutionanalyser/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newSQLiteRouter serves the API from a fresh SQLite database, as
+// DATABASE_URL=sqlite://... does
+func newSQLiteRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := models.InitDatabase("sqlite://" + t.TempDir() + "/api.db")
+	if err != nil {
+		t.Fatalf("open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { models.CloseDatabase(db) })
+
+	router := gin.New()
+	SetupRoutes(router, db, nil, events.NewHub(), nil, nil, nil)
+	return router, db
+}
+
+func seedAnalyses(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	day := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	analyses := []models.TechnicalSignal{
+		{Ticker: "AAPL", FinalDecision: "SELL", Confidence: 0.4, Tags: models.StringArray{"earnings"},
+			Signals: models.StringArray{"10:05 PUT: RSI Overbought at 181.20"}},
+		{Ticker: "AAPL", FinalDecision: "BUY", Confidence: 0.8, Tags: models.StringArray{"earnings", "swing"},
+			Signals: models.StringArray{"10:30 CALL: Bollinger Breakout at 182.10", "11:00 UP: Volume Spike at 183.00"}},
+		{Ticker: "MSFT", FinalDecision: "HOLD", Confidence: 0.5, Tags: models.StringArray{"swing"},
+			Signals: models.StringArray{}},
+	}
+	for i := range analyses {
+		analyses[i].UserId = handlers.DefaultUserID
+		analyses[i].PolyStartDuration = "2026-03-02"
+		analyses[i].PolyEndDuration = "2026-03-02"
+		analyses[i].StartDate = day
+		analyses[i].EndDate = day
+		analyses[i].LastClose = 180
+		analyses[i].CreatedAt = day.Add(time.Duration(i) * time.Minute)
+		if err := db.Create(&analyses[i]).Error; err != nil {
+			t.Fatalf("seed analysis: %v", err)
+		}
+	}
+}
+
+func serve(t *testing.T, router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, want int, v interface{}) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d: %s", w.Code, want, w.Body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", w.Body.String(), err)
+		}
+	}
+}
+
+func TestSQLiteSignalSearch(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+
+	tests := []struct {
+		name    string
+		filter  map[string]interface{}
+		tickers []string
+	}{
+		{"signal type", map[string]interface{}{"signal_types": []string{"Bollinger Breakout"}}, []string{"AAPL"}},
+		{"one tag", map[string]interface{}{"tags": []string{"swing"}}, []string{"MSFT", "AAPL"}},
+		{"every tag", map[string]interface{}{"tags": []string{"swing", "earnings"}}, []string{"AAPL"}},
+		{"no match", map[string]interface{}{"signal_types": []string{"MACD Cross"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				Data []struct{ Ticker string }
+			}
+			decode(t, serve(t, router, http.MethodPost, "/api/v1/signals/search", tt.filter), http.StatusOK, &resp)
+			var tickers []string
+			for _, a := range resp.Data {
+				tickers = append(tickers, a.Ticker)
+			}
+			if len(tickers) != len(tt.tickers) {
+				t.Fatalf("tickers = %v, want %v", tickers, tt.tickers)
+			}
+			for i := range tickers {
+				if tickers[i] != tt.tickers[i] {
+					t.Fatalf("tickers = %v, want %v", tickers, tt.tickers)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteTagCounts(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+
+	var resp struct {
+		Tags []handlers.TagCount
+	}
+	decode(t, serve(t, router, http.MethodGet, "/api/v1/tags", nil), http.StatusOK, &resp)
+	want := []handlers.TagCount{{Tag: "earnings", Count: 2}, {Tag: "swing", Count: 2}}
+	if len(resp.Tags) != len(want) || resp.Tags[0] != want[0] || resp.Tags[1] != want[1] {
+		t.Fatalf("tags = %+v, want %+v", resp.Tags, want)
+	}
+}
+
+func TestSQLiteLatestDecisions(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+
+	var resp struct {
+		Decisions []handlers.LatestDecision
+		Missing   []string
+	}
+	body := handlers.LatestDecisionsRequest{Tickers: []string{"aapl", "MSFT", "TSLA"}}
+	decode(t, serve(t, router, http.MethodPost, "/api/v1/decisions/latest", body), http.StatusOK, &resp)
+	if len(resp.Decisions) != 2 {
+		t.Fatalf("decisions = %+v, want AAPL and MSFT", resp.Decisions)
+	}
+	if got := resp.Decisions[0]; got.Ticker != "AAPL" || got.FinalDecision != "BUY" {
+		t.Errorf("AAPL decision = %+v, want the newest analysis (BUY)", got)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "TSLA" {
+		t.Errorf("missing = %v, want [TSLA]", resp.Missing)
+	}
+
+	body.Tag = "swing"
+	body.Tickers = []string{"AAPL"}
+	decode(t, serve(t, router, http.MethodPost, "/api/v1/decisions/latest", body), http.StatusOK, &resp)
+	if len(resp.Decisions) != 1 || resp.Decisions[0].FinalDecision != "BUY" {
+		t.Fatalf("tagged decisions = %+v, want AAPL BUY", resp.Decisions)
+	}
+}
+
+func TestSQLiteTickerSearch(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	for _, ticker := range []models.Ticker{
+		{Ticker: "AAPL", Name: "Apple Inc.", Active: true, Sector: "Technology"},
+		{Ticker: "AAL", Name: "American Airlines Group", Active: true, Sector: "Industrials"},
+	} {
+		if err := db.Create(&ticker).Error; err != nil {
+			t.Fatalf("seed ticker: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"search=aa", 2},
+		{"search=apple", 1},
+		{"sector=technology", 1},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Data []models.Ticker
+		}
+		decode(t, serve(t, router, http.MethodGet, "/api/v1/tickers?"+tt.query, nil), http.StatusOK, &resp)
+		if len(resp.Data) != tt.want {
+			t.Errorf("%s: %d tickers, want %d", tt.query, len(resp.Data), tt.want)
+		}
+	}
+}
+
+func TestSQLiteExportDecisions(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+
+	w := serve(t, router, http.MethodGet, "/api/v1/export/decisions?since=2026-03-01", nil)
+	decode(t, w, http.StatusOK, nil)
+	var rows []handlers.ExportedDecision
+	for dec := json.NewDecoder(w.Body); dec.More(); {
+		var row handlers.ExportedDecision
+		if err := dec.Decode(&row); err != nil {
+			t.Fatalf("decode row: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != 3 || rows[1].SignalCount != 2 || rows[2].SignalCount != 0 {
+		t.Fatalf("rows = %+v, want 3 with signal counts 1, 2, 0", rows)
+	}
+}
+
+func TestSQLiteGraphQLEarnings(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+	for i, updated := range []string{"v1", "v2"} {
+		estimate := models.EarningsEstimate{Ticker: "AAPL", ReportDate: "2026-04-30", ProviderUpdated: updated, Importance: i + 3}
+		if err := db.Create(&estimate).Error; err != nil {
+			t.Fatalf("seed estimate: %v", err)
+		}
+	}
+
+	query := `{ technicalSignals(filter: {tickers: ["AAPL"]}) { ticker earnings { providerUpdated revisions { providerUpdated } } } }`
+	var resp struct {
+		Data struct {
+			TechnicalSignals []struct {
+				Earnings []struct {
+					ProviderUpdated string
+					Revisions       []struct{ ProviderUpdated string }
+				}
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	decode(t, serve(t, router, http.MethodPost, "/api/v1/graphql", map[string]string{"query": query}), http.StatusOK, &resp)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %+v", resp.Errors)
+	}
+	if len(resp.Data.TechnicalSignals) != 2 {
+		t.Fatalf("analyses = %+v, want 2", resp.Data.TechnicalSignals)
+	}
+	for _, analysis := range resp.Data.TechnicalSignals {
+		if len(analysis.Earnings) != 1 || analysis.Earnings[0].ProviderUpdated != "v2" || len(analysis.Earnings[0].Revisions) != 2 {
+			t.Errorf("earnings = %+v, want the latest estimate (v2) with 2 revisions", analysis.Earnings)
+		}
+	}
+}
+
+func TestSQLiteHeatmapNotImplemented(t *testing.T) {
+	router, _ := newSQLiteRouter(t)
+	w := serve(t, router, http.MethodGet, "/api/v1/analytics/heatmap?from=2026-03-02&to=2026-03-06", nil)
+	decode(t, w, http.StatusNotImplemented, nil)
+}
+
+func TestSQLiteMarketBreadth(t *testing.T) {
+	router, db := newSQLiteRouter(t)
+	seedAnalyses(t, db)
+
+	var breadth struct {
+		Tickers int
+	}
+	decode(t, serve(t, router, http.MethodGet, "/api/v1/market/breadth?date=2026-03-02", nil), http.StatusOK, &breadth)
+	if breadth.Tickers != 2 {
+		t.Fatalf("tickers = %d, want AAPL and MSFT", breadth.Tickers)
+	}
+}
+
+func TestBrokerRequiresUserToken(t *testing.T) {
+	router, _ := newSQLiteRouter(t)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/broker/account"},
+		{http.MethodPut, "/api/v1/broker/account"},
+		{http.MethodPost, "/api/v1/broker/rules"},
+		{http.MethodDelete, "/api/v1/broker/kill-switch"},
+	} {
+		if w := serve(t, router, route.method, route.path, map[string]string{}); w.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s %s = %d, want 401", route.method, route.path, w.Code)
+		}
+	}
+}
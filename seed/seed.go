@@ -0,0 +1,184 @@
+// Package seed loads a bundled sample dataset so new deployments and demos
+// have populated endpoints before a Polygon key is configured. The minute bars
+// are generated sample data, not real market prices.
+package seed
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed data
+var data embed.FS
+
+// Tickers are the tickers the sample dataset has minute bars for
+var Tickers = []string{"SPY", "AAPL"}
+
+// Summary counts what a load wrote
+type Summary struct {
+	Tickers  int `json:"tickers"`
+	Bars     int `json:"bars"`
+	Earnings int `json:"earnings"`
+}
+
+// tickerFixture is a reference data row in data/tickers.json
+type tickerFixture struct {
+	Ticker            string  `json:"ticker"`
+	Name              string  `json:"name"`
+	Market            string  `json:"market"`
+	Locale            string  `json:"locale"`
+	PrimaryExchange   string  `json:"primary_exchange"`
+	Type              string  `json:"type"`
+	CurrencyName      string  `json:"currency_name"`
+	CIK               string  `json:"cik"`
+	MarketCap         float64 `json:"market_cap"`
+	SICCode           string  `json:"sic_code"`
+	Sector            string  `json:"sector"`
+	SharesOutstanding int64   `json:"shares_outstanding"`
+}
+
+// Load writes the sample tickers, minute bars and earnings estimates. It is
+// safe to run repeatedly: bars are upserted and rows already present are kept.
+func Load(ctx context.Context, db *gorm.DB) (Summary, error) {
+	var summary Summary
+	db = db.WithContext(ctx)
+
+	tickers, err := loadTickers()
+	if err != nil {
+		return summary, err
+	}
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "ticker"}}, DoNothing: true}).
+		Create(&tickers).Error; err != nil {
+		return summary, fmt.Errorf("failed to store tickers: %w", err)
+	}
+	summary.Tickers = len(tickers)
+
+	for _, ticker := range Tickers {
+		bars, err := loadBars(ticker)
+		if err != nil {
+			return summary, err
+		}
+		if err := models.UpsertBars(db, bars); err != nil {
+			return summary, fmt.Errorf("failed to store %s bars: %w", ticker, err)
+		}
+		summary.Bars += len(bars)
+	}
+
+	var estimates []models.EarningsEstimate
+	if err := readJSON("data/earnings.json", &estimates); err != nil {
+		return summary, err
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&estimates).Error; err != nil {
+		return summary, fmt.Errorf("failed to store earnings estimates: %w", err)
+	}
+	summary.Earnings = len(estimates)
+
+	return summary, nil
+}
+
+func loadTickers() ([]models.Ticker, error) {
+	var fixtures []tickerFixture
+	if err := readJSON("data/tickers.json", &fixtures); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tickers := make([]models.Ticker, 0, len(fixtures))
+	for _, f := range fixtures {
+		currency, _ := models.NormalizeCurrency(f.CurrencyName)
+		tickers = append(tickers, models.Ticker{
+			Ticker:            f.Ticker,
+			Name:              f.Name,
+			Market:            f.Market,
+			Locale:            f.Locale,
+			PrimaryExchange:   f.PrimaryExchange,
+			Type:              f.Type,
+			CurrencyName:      f.CurrencyName,
+			Currency:          currency,
+			CIK:               f.CIK,
+			Active:            true,
+			MarketCap:         f.MarketCap,
+			SICCode:           f.SICCode,
+			Sector:            f.Sector,
+			SharesOutstanding: f.SharesOutstanding,
+			LastSyncedAt:      now,
+		})
+	}
+	return tickers, nil
+}
+
+// loadBars reads data/<ticker>_minute.csv.gz: a header, then one
+// timestamp (Unix ms),open,high,low,close,volume,vwap,transactions row per bar
+func loadBars(ticker string) ([]models.Bar, error) {
+	name := fmt.Sprintf("data/%s_minute.csv.gz", ticker)
+	file, err := data.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer gz.Close()
+
+	var bars []models.Bar
+	scanner := bufio.NewScanner(gz)
+	for line := 0; scanner.Scan(); line++ {
+		if line == 0 {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("%s line %d: expected 8 fields, got %d", name, line+1, len(fields))
+		}
+
+		var values [8]float64
+		for i, field := range fields {
+			if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", name, line+1, err)
+			}
+		}
+		bars = append(bars, models.Bar{
+			Ticker:       ticker,
+			Timestamp:    time.UnixMilli(int64(values[0])).UTC(),
+			TimeSpan:     "minute",
+			Multiplier:   1,
+			Open:         values[1],
+			High:         values[2],
+			Low:          values[3],
+			Close:        values[4],
+			Volume:       values[5],
+			VWAP:         values[6],
+			Transactions: int64(values[7]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return bars, nil
+}
+
+func readJSON(name string, v interface{}) error {
+	raw, err := data.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
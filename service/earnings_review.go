@@ -0,0 +1,176 @@
+package service
+
+import (
+	"math"
+)
+
+// Component weights of the post-earnings score. Guidance is only weighted in
+// when the company issued any; otherwise EPS and revenue share the score.
+const (
+	epsSurpriseWeight     = 0.5
+	revenueSurpriseWeight = 0.3
+	guidanceWeight        = 0.2
+	// surpriseScale is the surprise (percent) that maps to a full +/-1 component
+	surpriseScale = 10.0
+)
+
+// Guidance directions
+const (
+	GuidanceRaised     = "RAISED"
+	GuidanceLowered    = "LOWERED"
+	GuidanceMaintained = "MAINTAINED"
+	GuidanceAbove      = "ABOVE_CONSENSUS"
+	GuidanceBelow      = "BELOW_CONSENSUS"
+)
+
+// EarningsReview scores a reported quarter on EPS surprise, revenue surprise and guidance
+type EarningsReview struct {
+	Ticker             string          `json:"ticker"`
+	Date               string          `json:"date"`
+	EPSSurprisePct     *float64        `json:"eps_surprise_pct,omitempty"`
+	RevenueSurprisePct *float64        `json:"revenue_surprise_pct,omitempty"`
+	Guidance           *GuidanceResult `json:"guidance,omitempty"`
+	GuidanceDirection  string          `json:"guidance_direction,omitempty"`
+	GuidanceChangePct  *float64        `json:"guidance_change_pct,omitempty"`
+	// Score is in [-1, 1]; positive means a better than expected report
+	Score   float64 `json:"score"`
+	Verdict string  `json:"verdict"` // "BEAT", "MISS", "MIXED", "INLINE", "NOT_REPORTED"
+}
+
+// ReviewEarnings scores a reported announcement. guidance may be nil.
+func ReviewEarnings(earning EarningsResult, guidance *GuidanceResult) EarningsReview {
+	review := EarningsReview{
+		Ticker:   earning.Ticker,
+		Date:     earning.Date,
+		Guidance: guidance,
+	}
+
+	review.EPSSurprisePct = surprisePct(earning.ActualEPS, earning.EstimatedEPS, earning.EPSSurprisePercent)
+	review.RevenueSurprisePct = surprisePct(earning.ActualRevenue, earning.EstimatedRevenue, earning.RevenueSurprisePercent)
+	if guidance != nil {
+		review.GuidanceDirection, review.GuidanceChangePct = guidanceChange(guidance)
+	}
+
+	if review.EPSSurprisePct == nil && review.RevenueSurprisePct == nil {
+		review.Verdict = "NOT_REPORTED"
+		return review
+	}
+
+	var score, weight float64
+	if review.EPSSurprisePct != nil {
+		score += epsSurpriseWeight * component(*review.EPSSurprisePct)
+		weight += epsSurpriseWeight
+	}
+	if review.RevenueSurprisePct != nil {
+		score += revenueSurpriseWeight * component(*review.RevenueSurprisePct)
+		weight += revenueSurpriseWeight
+	}
+	if review.GuidanceChangePct != nil {
+		score += guidanceWeight * component(*review.GuidanceChangePct)
+		weight += guidanceWeight
+	}
+	review.Score = score / weight
+	review.Verdict = verdict(review)
+
+	return review
+}
+
+// surprisePct prefers the provider's surprise percentage and otherwise derives it from actual and estimate
+func surprisePct(actual, estimate, reported *float64) *float64 {
+	if actual == nil {
+		return nil
+	}
+	if reported != nil {
+		return reported
+	}
+	if estimate == nil || *estimate == 0 {
+		return nil
+	}
+	pct := (*actual - *estimate) / math.Abs(*estimate) * 100
+	return &pct
+}
+
+// guidanceChange compares the guidance midpoint with the previous guidance,
+// or with consensus when the company did not guide before. EPS guidance is
+// used when available, revenue guidance otherwise.
+func guidanceChange(g *GuidanceResult) (string, *float64) {
+	ranges := [][5]*float64{
+		{g.MinEPSGuidance, g.MaxEPSGuidance, g.PreviousMinEPSGuidance, g.PreviousMaxEPSGuidance, g.EstimatedEPSGuidance},
+		{g.MinRevenueGuidance, g.MaxRevenueGuidance, g.PreviousMinRevenueGuidance, g.PreviousMaxRevenueGuidance, g.EstimatedRevenueGuidance},
+	}
+
+	for _, r := range ranges {
+		current := midpoint(r[0], r[1])
+		if current == nil {
+			continue
+		}
+
+		if previous := midpoint(r[2], r[3]); previous != nil && *previous != 0 {
+			pct := (*current - *previous) / math.Abs(*previous) * 100
+			switch {
+			case pct > 0:
+				return GuidanceRaised, &pct
+			case pct < 0:
+				return GuidanceLowered, &pct
+			default:
+				return GuidanceMaintained, &pct
+			}
+		}
+
+		if consensus := r[4]; consensus != nil && *consensus != 0 {
+			pct := (*current - *consensus) / math.Abs(*consensus) * 100
+			if pct >= 0 {
+				return GuidanceAbove, &pct
+			}
+			return GuidanceBelow, &pct
+		}
+	}
+
+	return "", nil
+}
+
+func midpoint(low, high *float64) *float64 {
+	switch {
+	case low != nil && high != nil:
+		mid := (*low + *high) / 2
+		return &mid
+	case low != nil:
+		return low
+	default:
+		return high
+	}
+}
+
+// component maps a percentage onto [-1, 1]
+func component(pct float64) float64 {
+	return math.Max(-1, math.Min(1, pct/surpriseScale))
+}
+
+// verdict is BEAT/MISS when every reported component agrees, MIXED when they
+// disagree and INLINE when the overall score is negligible
+func verdict(review EarningsReview) string {
+	if math.Abs(review.Score) < 0.05 {
+		return "INLINE"
+	}
+
+	positive, negative := 0, 0
+	for _, pct := range []*float64{review.EPSSurprisePct, review.RevenueSurprisePct, review.GuidanceChangePct} {
+		if pct == nil {
+			continue
+		}
+		if *pct > 0 {
+			positive++
+		} else if *pct < 0 {
+			negative++
+		}
+	}
+
+	switch {
+	case negative == 0:
+		return "BEAT"
+	case positive == 0:
+		return "MISS"
+	default:
+		return "MIXED"
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// EarningsResult represents a single Benzinga earnings announcement
+type EarningsResult struct {
+	Ticker           string   `json:"ticker"`
+	Date             string   `json:"date"`
+	ActualEPS        *float64 `json:"actual_eps,omitempty"`
+	ActualRevenue    *float64 `json:"actual_revenue,omitempty"`
+	EstimatedEPS     *float64 `json:"estimated_eps,omitempty"`
+	EstimatedRevenue *float64 `json:"estimated_revenue,omitempty"`
+	// Surprise percentages as computed by Benzinga, when reported
+	EPSSurprisePercent     *float64 `json:"eps_surprise_percent,omitempty"`
+	RevenueSurprisePercent *float64 `json:"revenue_surprise_percent,omitempty"`
+	FiscalPeriod           string   `json:"fiscal_period,omitempty"`
+	FiscalYear             int      `json:"fiscal_year,omitempty"`
+	Importance             int      `json:"importance"`
+	Time                   string   `json:"time,omitempty"`
+	// Updated is when Benzinga last changed the record (e.g. an estimate revision)
+	Updated string `json:"updated,omitempty"`
+}
+
+// PolygonEarningsResponse represents the response from Polygon's Benzinga earnings API
+type PolygonEarningsResponse struct {
+	Status    string           `json:"status"`
+	RequestID string           `json:"request_id"`
+	Count     int              `json:"count"`
+	Results   []EarningsResult `json:"results"`
+}
+
+// GuidanceResult is a company's EPS/revenue guidance as reported by Benzinga
+type GuidanceResult struct {
+	Ticker                     string   `json:"ticker"`
+	Date                       string   `json:"date"`
+	FiscalPeriod               string   `json:"fiscal_period,omitempty"`
+	FiscalYear                 int      `json:"fiscal_year,omitempty"`
+	MinEPSGuidance             *float64 `json:"min_eps_guidance,omitempty"`
+	MaxEPSGuidance             *float64 `json:"max_eps_guidance,omitempty"`
+	PreviousMinEPSGuidance     *float64 `json:"previous_min_eps_guidance,omitempty"`
+	PreviousMaxEPSGuidance     *float64 `json:"previous_max_eps_guidance,omitempty"`
+	EstimatedEPSGuidance       *float64 `json:"estimated_eps_guidance,omitempty"`
+	MinRevenueGuidance         *float64 `json:"min_revenue_guidance,omitempty"`
+	MaxRevenueGuidance         *float64 `json:"max_revenue_guidance,omitempty"`
+	PreviousMinRevenueGuidance *float64 `json:"previous_min_revenue_guidance,omitempty"`
+	PreviousMaxRevenueGuidance *float64 `json:"previous_max_revenue_guidance,omitempty"`
+	EstimatedRevenueGuidance   *float64 `json:"estimated_revenue_guidance,omitempty"`
+	Updated                    string   `json:"last_updated,omitempty"`
+}
+
+// PolygonGuidanceResponse represents the response from Polygon's Benzinga guidance API
+type PolygonGuidanceResponse struct {
+	Status  string           `json:"status"`
+	Results []GuidanceResult `json:"results"`
+}
+
+// EarningsService fetches the Benzinga earnings calendar through Polygon
+type EarningsService struct {
+	apiKey  string
+	baseURL string
+}
+
+func NewEarningsService() *EarningsService {
+	return &EarningsService{apiKey: os.Getenv("POLYGON_API_KEY"), baseURL: PolygonBaseURL()}
+}
+
+// Configured reports whether a Polygon API key is set
+func (s *EarningsService) Configured() bool {
+	return s.apiKey != ""
+}
+
+// FetchEarnings returns the announcements for one date, optionally filtered by ticker and importance
+func (s *EarningsService) FetchEarnings(ctx context.Context, date, ticker string, importance *int, limit int) ([]EarningsResult, error) {
+	query := url.Values{}
+	query.Set("date", date)
+	query.Set("limit", strconv.Itoa(limit))
+	if ticker != "" {
+		query.Set("ticker", ticker)
+	}
+	if importance != nil {
+		query.Set("importance", strconv.Itoa(*importance))
+	}
+
+	var polygonResp PolygonEarningsResponse
+	if err := s.get(ctx, "/benzinga/v1/earnings", query, &polygonResp); err != nil {
+		return nil, err
+	}
+
+	if polygonResp.Status != "OK" {
+		return nil, fmt.Errorf("Polygon API returned non-OK status: %s", polygonResp.Status)
+	}
+
+	return polygonResp.Results, nil
+}
+
+// FetchGuidance returns the guidance issued on one date, optionally for a single ticker
+func (s *EarningsService) FetchGuidance(ctx context.Context, date, ticker string, limit int) ([]GuidanceResult, error) {
+	query := url.Values{}
+	query.Set("date", date)
+	query.Set("limit", strconv.Itoa(limit))
+	if ticker != "" {
+		query.Set("ticker", ticker)
+	}
+
+	var polygonResp PolygonGuidanceResponse
+	if err := s.get(ctx, "/benzinga/v1/guidance", query, &polygonResp); err != nil {
+		return nil, err
+	}
+
+	if polygonResp.Status != "OK" {
+		return nil, fmt.Errorf("Polygon API returned non-OK status: %s", polygonResp.Status)
+	}
+
+	return polygonResp.Results, nil
+}
+
+// get makes an authenticated GET to a Benzinga endpoint and decodes the JSON body into out
+func (s *EarningsService) get(ctx context.Context, path string, query url.Values, out any) error {
+	query.Set("apiKey", s.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := PolygonHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to Polygon API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Polygon API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"institutionanalyser/config"
+
+	polygon "github.com/polygon-io/client-go/rest"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var (
+	httpClientMu     sync.Mutex
+	httpClient       *http.Client
+	publicHTTPClient *http.Client
+)
+
+// ConfigureHTTPClient builds the shared outbound client from cfg. It is called
+// once at startup; callers that run before it get a client built from the
+// environment on first use.
+func ConfigureHTTPClient(cfg config.HTTPClientConfig) error {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	public, err := newPublicHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	httpClientMu.Lock()
+	httpClient = client
+	publicHTTPClient = public
+	httpClientMu.Unlock()
+	return nil
+}
+
+// HTTPClient returns the shared client used for all outbound calls
+func HTTPClient() *http.Client {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+
+	if httpClient == nil {
+		client, err := newHTTPClient(config.GetHTTPClientConfig())
+		if err != nil {
+			fmt.Printf("[http] invalid outbound HTTP configuration, using defaults: %v\n", err)
+			client = &http.Client{Transport: http.DefaultTransport}
+		}
+		httpClient = client
+	}
+	return httpClient
+}
+
+// PublicHTTPClient returns a client configured like HTTPClient for URLs that
+// users supply, such as alert webhooks. It refuses to connect to loopback,
+// private, link-local and unspecified addresses. The check runs on the
+// address actually dialed, so a host that resolved to a public address when
+// the URL was saved cannot be re-pointed at internal services later (DNS
+// rebinding). Connections to the egress proxy are exempt, since the proxy
+// dials the target itself.
+func PublicHTTPClient() *http.Client {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+
+	if publicHTTPClient == nil {
+		client, err := newPublicHTTPClient(config.GetHTTPClientConfig())
+		if err != nil {
+			fmt.Printf("[http] invalid outbound HTTP configuration, using defaults: %v\n", err)
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			refusePrivateDials(transport)
+			client = &http.Client{Transport: transport}
+		}
+		publicHTTPClient = client
+	}
+	return publicHTTPClient
+}
+
+// newPolygonClient returns a Polygon client that shares the rate limited
+// Polygon transport. The polygon client sets its own timeout on the
+// *http.Client it is given, so each gets its own wrapper around the transport.
+// Its built-in retries are disabled because the transport already retries.
+func newPolygonClient(apiKey string) *polygon.Client {
+	c := polygon.NewWithClient(apiKey, &http.Client{Transport: PolygonHTTPClient().Transport})
+	c.HTTP.SetRetryCount(0)
+	c.HTTP.SetBaseURL(PolygonBaseURL())
+	return c
+}
+
+func newHTTPClient(cfg config.HTTPClientConfig) (*http.Client, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Outbound calls become client spans of the caller's trace
+	return &http.Client{Transport: otelhttp.NewTransport(transport), Timeout: cfg.Timeout}, nil
+}
+
+func newPublicHTTPClient(cfg config.HTTPClientConfig) (*http.Client, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	refusePrivateDials(transport)
+	return &http.Client{Transport: otelhttp.NewTransport(transport), Timeout: cfg.Timeout}, nil
+}
+
+// refusePrivateDials makes transport refuse connections to non-public
+// addresses, except to the proxies it is configured with
+func refusePrivateDials(transport *http.Transport) {
+	proxies := map[string]bool{}
+	if transport.Proxy != nil {
+		for _, scheme := range []string{"http", "https"} {
+			proxy, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: "example.com"}})
+			if err == nil && proxy != nil {
+				proxies[proxyAddress(proxy)] = true
+			}
+		}
+	}
+
+	direct := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	public := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivateAddress}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if proxies[address] {
+			return direct.DialContext(ctx, network, address)
+		}
+		return public.DialContext(ctx, network, address)
+	}
+}
+
+// proxyAddress is the host:port a transport dials for proxy
+func proxyAddress(proxy *url.URL) string {
+	if port := proxy.Port(); port != "" {
+		return net.JoinHostPort(proxy.Hostname(), port)
+	}
+	ports := map[string]string{"http": "80", "https": "443", "socks5": "1080"}
+	return net.JoinHostPort(proxy.Hostname(), ports[proxy.Scheme])
+}
+
+// refusePrivateAddress is a net.Dialer Control that fails connections to
+// loopback, private, link-local and unspecified addresses
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
+
+// newTransport builds an outbound transport from cfg
+func newTransport(cfg config.HTTPClientConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EGRESS_PROXY_URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         cfg.MinTLSVersion,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CABundlePath != "" {
+		pem, err := os.ReadFile(cfg.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS_CA_BUNDLE: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("TLS_CA_BUNDLE %s contains no certificates", cfg.CABundlePath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRefusePrivateDials(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	refusePrivateDials(transport)
+	_, err := (&http.Client{Transport: transport}).Get(target.URL)
+	if err == nil || !strings.Contains(err.Error(), "refusing to connect to non-public address 127.0.0.1") {
+		t.Fatalf("Get(%s) error = %v, want a refused private address", target.URL, err)
+	}
+}
+
+func TestRefusePrivateDialsAllowsProxy(t *testing.T) {
+	// The proxy resolves and dials the target itself
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	refusePrivateDials(transport)
+	resp, err := (&http.Client{Transport: transport}).Get("http://hooks.example.com/alert")
+	if err != nil {
+		t.Fatalf("Get through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want the proxy's 204", resp.StatusCode)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/polygon-io/client-go/rest/iter"
+	"github.com/polygon-io/client-go/rest/models"
+)
+
+// MarketDataService fetches market-wide (not per-ticker) data from Polygon
+type MarketDataService struct {
+	apiKey string
+}
+
+func NewMarketDataService() *MarketDataService {
+	return &MarketDataService{apiKey: os.Getenv("POLYGON_API_KEY")}
+}
+
+// GetGroupedDailyBars returns the daily bar of every US stock for a date
+func (s *MarketDataService) GetGroupedDailyBars(ctx context.Context, date time.Time) ([]models.Agg, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.GetGroupedDailyAggsParams{
+		Locale:     models.US,
+		MarketType: models.Stocks,
+		Date:       models.Date(date),
+	}.WithAdjusted(true)
+
+	res, err := c.GetGroupedDailyAggs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Results, nil
+}
+
+// GetTickerSnapshots returns the current snapshot of each ticker in one call.
+// Tickers Polygon has no snapshot for are missing from the result.
+func (s *MarketDataService) GetTickerSnapshots(ctx context.Context, tickers []string) ([]models.TickerSnapshot, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.GetAllTickersSnapshotParams{
+		Locale:     models.US,
+		MarketType: models.Stocks,
+	}.WithTickers(strings.Join(tickers, ","))
+
+	res, err := c.GetAllTickersSnapshot(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Tickers, nil
+}
+
+// ListStockTickers streams Polygon's reference tickers for the stocks market
+func (s *MarketDataService) ListStockTickers(ctx context.Context, active bool) *iter.Iter[models.Ticker] {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListTickersParams{}.
+		WithMarket(models.AssetStocks).
+		WithActive(active).
+		WithLimit(1000)
+
+	return c.ListTickers(ctx, params)
+}
+
+// GetTickerDetails returns full reference details (market cap, SIC sector) for a ticker
+func (s *MarketDataService) GetTickerDetails(ctx context.Context, ticker string) (*models.Ticker, error) {
+	c := newPolygonClient(s.apiKey)
+
+	res, err := c.GetTickerDetails(ctx, &models.GetTickerDetailsParams{Ticker: ticker})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Results, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/polygon-io/client-go/rest/models"
+)
+
+// optionsChainPageSize is the largest page Polygon serves for chain snapshots
+const optionsChainPageSize = 250
+
+// OptionsChainFilter narrows an options chain snapshot. Zero values are unset.
+type OptionsChainFilter struct {
+	ContractType   models.ContractType
+	ExpirationFrom string // YYYY-MM-DD, inclusive
+	ExpirationTo   string // YYYY-MM-DD, inclusive
+	StrikeMin      float64
+	StrikeMax      float64
+}
+
+// GetOptionsChain returns the snapshot of the ticker's option contracts that
+// match filter, at most max of them ordered by expiration. It reports whether
+// more contracts matched.
+func (s *StockTechnicalService) GetOptionsChain(ctx context.Context, filter OptionsChainFilter, max int) ([]models.OptionContractSnapshot, bool, error) {
+	c := newPolygonClient(s.apiKey)
+
+	limit := optionsChainPageSize
+	sort := models.Sort("expiration_date")
+	order := models.Asc
+	params := &models.ListOptionsChainParams{
+		UnderlyingAsset: s.ticker,
+		Limit:           &limit,
+		Sort:            &sort,
+		Order:           &order,
+	}
+	if filter.ContractType != "" {
+		params.ContractType = &filter.ContractType
+	}
+	if filter.ExpirationFrom != "" {
+		from, err := time.Parse("2006-01-02", filter.ExpirationFrom)
+		if err != nil {
+			return nil, false, err
+		}
+		date := models.Date(from)
+		params.ExpirationDateGTE = &date
+	}
+	if filter.ExpirationTo != "" {
+		to, err := time.Parse("2006-01-02", filter.ExpirationTo)
+		if err != nil {
+			return nil, false, err
+		}
+		date := models.Date(to)
+		params.ExpirationDateLTE = &date
+	}
+	if filter.StrikeMin > 0 {
+		params.StrikePriceGTE = &filter.StrikeMin
+	}
+	if filter.StrikeMax > 0 {
+		params.StrikePriceLTE = &filter.StrikeMax
+	}
+
+	var contracts []models.OptionContractSnapshot
+	it := c.ListOptionsChainSnapshot(ctx, params)
+	for it.Next() {
+		if len(contracts) == max {
+			return contracts, true, nil
+		}
+		contracts = append(contracts, it.Item())
+	}
+	return contracts, false, it.Err()
+}
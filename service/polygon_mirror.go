@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mirrorTransport sends Polygon requests to a read-through mirror (a shared
+// cache or proxy) instead of the origin, falling back to the origin when the
+// mirror is unreachable or returns a 5xx
+type mirrorTransport struct {
+	next   http.RoundTripper
+	origin *url.URL
+	mirror *url.URL
+}
+
+func newMirrorTransport(next http.RoundTripper, originURL, mirrorURL string) (*mirrorTransport, error) {
+	origin, err := url.Parse(originURL)
+	if err != nil || origin.Host == "" {
+		return nil, fmt.Errorf("invalid Polygon base URL %q", originURL)
+	}
+	mirror, err := url.Parse(mirrorURL)
+	if err != nil || mirror.Host == "" || (mirror.Scheme != "http" && mirror.Scheme != "https") {
+		return nil, fmt.Errorf("invalid mirror URL %q", mirrorURL)
+	}
+	return &mirrorTransport{next: next, origin: origin, mirror: mirror}, nil
+}
+
+func (t *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Only origin requests are mirrored, and only if they can be sent twice
+	if req.URL.Host != t.origin.Host || (req.Body != nil && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+
+	mirrored := req.Clone(req.Context())
+	mirrored.URL.Scheme = t.mirror.Scheme
+	mirrored.URL.Host = t.mirror.Host
+	mirrored.URL.Path = strings.TrimSuffix(t.mirror.Path, "/") + req.URL.Path
+	mirrored.URL.RawPath = ""
+	mirrored.Host = ""
+	if req.Body != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		mirrored.Body = body
+	}
+
+	resp, err := t.next.RoundTrip(mirrored)
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		return resp, nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+
+	if resp != nil {
+		fmt.Printf("[http] polygon mirror returned %d for %s, falling back to %s\n", resp.StatusCode, req.URL.Path, t.origin.Host)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	} else {
+		fmt.Printf("[http] polygon mirror failed for %s: %v, falling back to %s\n", req.URL.Path, err, t.origin.Host)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"institutionanalyser/config"
+	"institutionanalyser/health"
+	"institutionanalyser/usage"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	polygonClientMu sync.Mutex
+	polygonClient   *http.Client
+	polygonBaseURL  string
+)
+
+// ConfigurePolygonClient sets the rate limit and retry policy for Polygon
+// calls. It must run after ConfigureHTTPClient, whose transport it wraps.
+func ConfigurePolygonClient(cfg config.PolygonClientConfig) {
+	client := newPolygonHTTPClient(cfg)
+
+	polygonClientMu.Lock()
+	polygonClient = client
+	polygonBaseURL = cfg.BaseURL
+	polygonClientMu.Unlock()
+}
+
+// PolygonBaseURL returns the Polygon REST endpoint requests are built
+// against; a configured mirror is applied by the Polygon client's transport
+func PolygonBaseURL() string {
+	polygonClientMu.Lock()
+	defer polygonClientMu.Unlock()
+
+	if polygonBaseURL == "" {
+		polygonBaseURL = config.GetPolygonClientConfig().BaseURL
+	}
+	return polygonBaseURL
+}
+
+// PolygonHTTPClient returns the client for Polygon calls. All callers share one
+// token bucket, and throttled or failed requests are retried with backoff.
+func PolygonHTTPClient() *http.Client {
+	polygonClientMu.Lock()
+	defer polygonClientMu.Unlock()
+
+	if polygonClient == nil {
+		polygonClient = newPolygonHTTPClient(config.GetPolygonClientConfig())
+	}
+	return polygonClient
+}
+
+func newPolygonHTTPClient(cfg config.PolygonClientConfig) *http.Client {
+	shared := HTTPClient()
+
+	next := shared.Transport
+	if cfg.MirrorURL != "" {
+		mirror, err := newMirrorTransport(next, cfg.BaseURL, cfg.MirrorURL)
+		if err != nil {
+			fmt.Printf("[http] ignoring POLYGON_MIRROR_URL: %v\n", err)
+		} else {
+			next = mirror
+		}
+	}
+
+	limit := rate.Inf
+	if cfg.RequestsPerSecond > 0 {
+		limit = rate.Limit(cfg.RequestsPerSecond)
+	}
+
+	return &http.Client{
+		Transport: &polygonTransport{
+			next:    next,
+			limiter: rate.NewLimiter(limit, cfg.Burst),
+			cfg:     cfg,
+		},
+		Timeout: shared.Timeout,
+	}
+}
+
+// polygonTransport waits for a token before every attempt and retries 429s,
+// 5xx gateway errors and network failures with exponential backoff
+type polygonTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+	cfg     config.PolygonClientConfig
+}
+
+func (t *polygonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		// Every attempt uses quota, so retries are metered too
+		usage.Record(ctx, req.URL.Path)
+		resp, err := t.next.RoundTrip(attemptReq)
+		if !t.shouldRetry(req, resp, err) || attempt >= t.cfg.MaxRetries {
+			recordPolygonHealth(resp, err)
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			fmt.Printf("[http] polygon %s returned %d, retrying in %s (attempt %d/%d)\n", req.URL.Path, resp.StatusCode, delay, attempt+1, t.cfg.MaxRetries)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			fmt.Printf("[http] polygon %s failed: %v, retrying in %s (attempt %d/%d)\n", req.URL.Path, err, delay, attempt+1, t.cfg.MaxRetries)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *polygonTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	// Requests whose body cannot be replayed are sent once
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// recordPolygonHealth counts a request's final outcome towards Polygon's
+// health. Network failures and 429s or 5xx left after retries count against
+// it; cancelled requests do not count at all.
+func recordPolygonHealth(resp *http.Response, err error) {
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+	case err != nil:
+		health.RecordFailure(health.Polygon, err)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		health.RecordFailure(health.Polygon, fmt.Errorf("polygon returned status %d", resp.StatusCode))
+	default:
+		health.RecordSuccess(health.Polygon)
+	}
+}
+
+// backoff honours Retry-After when present, otherwise doubles the base delay
+// per attempt with jitter, capped at the configured maximum
+func (t *polygonTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			return min(retryAfter, t.cfg.RetryMaxDelay)
+		}
+	}
+
+	delay := t.cfg.RetryBaseDelay << attempt
+	if delay <= 0 || delay > t.cfg.RetryMaxDelay {
+		delay = t.cfg.RetryMaxDelay
+	}
+	// Spread retries between half and the full delay so concurrent callers do not retry in lockstep
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// PingPolygon makes one market status request to Polygon, without the rate
+// limiter or retries so a health check reports what it sees right away, and
+// returns the response status. The call counts towards Polygon's health and
+// usage like any other.
+func PingPolygon(ctx context.Context) (int, error) {
+	apiKey := os.Getenv("POLYGON_API_KEY")
+	if apiKey == "" {
+		return 0, fmt.Errorf("POLYGON_API_KEY is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, PolygonBaseURL()+"/v1/marketstatus/now?apiKey="+url.QueryEscape(apiKey), nil)
+	if err != nil {
+		return 0, err
+	}
+	usage.Record(ctx, req.URL.Path)
+	resp, err := HTTPClient().Do(req)
+	recordPolygonHealth(resp, err)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return resp.StatusCode, fmt.Errorf("polygon rate limit or plan quota exhausted")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp.StatusCode, fmt.Errorf("polygon rejected the API key (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return resp.StatusCode, fmt.Errorf("polygon returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/polygon-io/client-go/rest/models"
+)
+
+// defaultQuoteTTL is how long a ticker snapshot is served from memory
+const defaultQuoteTTL = 5 * time.Second
+
+// QuoteCache serves ticker snapshots from memory for a short TTL, so many
+// clients polling the same tickers cost one Polygon call per TTL. Tickers
+// that miss the cache are fetched together in a single snapshot call.
+type QuoteCache struct {
+	market *MarketDataService
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedQuote
+}
+
+type cachedQuote struct {
+	snapshot  models.TickerSnapshot
+	fetchedAt time.Time
+}
+
+var (
+	defaultQuoteCacheOnce sync.Once
+	defaultQuoteCache     *QuoteCache
+)
+
+// DefaultQuoteCache returns the process-wide quote cache
+func DefaultQuoteCache() *QuoteCache {
+	defaultQuoteCacheOnce.Do(func() {
+		defaultQuoteCache = NewQuoteCache()
+	})
+	return defaultQuoteCache
+}
+
+// NewQuoteCache creates a quote cache whose TTL is QUOTE_CACHE_TTL_SECONDS
+// (default 5 seconds)
+func NewQuoteCache() *QuoteCache {
+	ttl := defaultQuoteTTL
+	if val := os.Getenv("QUOTE_CACHE_TTL_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			ttl = time.Duration(n) * time.Second
+		}
+	}
+	return &QuoteCache{
+		market:  NewMarketDataService(),
+		ttl:     ttl,
+		entries: make(map[string]cachedQuote),
+	}
+}
+
+// TTL is how long a snapshot stays fresh
+func (q *QuoteCache) TTL() time.Duration {
+	return q.ttl
+}
+
+// Get returns the snapshots of tickers keyed by ticker and when each was
+// fetched. Tickers Polygon has no snapshot for are missing from the result.
+func (q *QuoteCache) Get(ctx context.Context, tickers []string) (map[string]models.TickerSnapshot, map[string]time.Time, error) {
+	snapshots := make(map[string]models.TickerSnapshot, len(tickers))
+	fetchedAt := make(map[string]time.Time, len(tickers))
+
+	var missing []string
+	q.mu.Lock()
+	now := time.Now()
+	for ticker, entry := range q.entries {
+		if now.Sub(entry.fetchedAt) >= q.ttl {
+			delete(q.entries, ticker)
+		}
+	}
+	for _, ticker := range tickers {
+		if entry, ok := q.entries[ticker]; ok {
+			snapshots[ticker] = entry.snapshot
+			fetchedAt[ticker] = entry.fetchedAt
+			continue
+		}
+		missing = append(missing, ticker)
+	}
+	q.mu.Unlock()
+
+	if len(missing) == 0 {
+		return snapshots, fetchedAt, nil
+	}
+
+	// Fetched outside the lock so cached tickers are not held up by Polygon
+	fetched, err := q.market.GetTickerSnapshots(ctx, missing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now = time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, snapshot := range fetched {
+		q.entries[snapshot.Ticker] = cachedQuote{snapshot: snapshot, fetchedAt: now}
+		snapshots[snapshot.Ticker] = snapshot
+		fetchedAt[snapshot.Ticker] = now
+	}
+	return snapshots, fetchedAt, nil
+}
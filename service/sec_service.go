@@ -0,0 +1,365 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secDataURL     = "https://data.sec.gov"
+	secArchivesURL = "https://www.sec.gov"
+	// secRequestInterval keeps requests under EDGAR's fair access limit of 10 per second
+	secRequestInterval = 120 * time.Millisecond
+)
+
+var (
+	// ErrSECNotFound is returned for documents EDGAR does not have, such as a
+	// fails-to-deliver file that is not published yet
+	ErrSECNotFound = errors.New("not found on EDGAR")
+
+	secRateMu      sync.Mutex
+	secLastRequest time.Time
+
+	secCIKsMu sync.Mutex
+	secCIKs   map[string]string
+)
+
+// Form4Filing is one Form 4 in a company's EDGAR filing index
+type Form4Filing struct {
+	Accession string
+	FiledAt   string // YYYY-MM-DD
+	// Document is the filing's XML document name
+	Document string
+}
+
+// Form4Transaction is one non-derivative transaction reported on a Form 4
+type Form4Transaction struct {
+	OwnerCIK     string
+	OwnerName    string
+	Relationship string
+	Date         string // YYYY-MM-DD
+	// Code is the SEC transaction code, e.g. P for an open market purchase
+	// and S for an open market sale
+	Code             string
+	Shares           float64
+	Price            float64
+	Acquired         bool
+	SharesOwnedAfter float64
+}
+
+// SECService reads insider filings and fails-to-deliver data from SEC EDGAR. EDGAR requires every
+// client to identify itself, so nothing is requested without SEC_USER_AGENT.
+type SECService struct {
+	userAgent string
+}
+
+func NewSECService() *SECService {
+	return &SECService{userAgent: os.Getenv("SEC_USER_AGENT")}
+}
+
+// Configured reports whether SEC_USER_AGENT is set
+func (s *SECService) Configured() bool {
+	return s.userAgent != ""
+}
+
+// TickerCIK returns the zero-padded CIK of a ticker from EDGAR's ticker map,
+// loaded once per process
+func (s *SECService) TickerCIK(ctx context.Context, ticker string) (string, error) {
+	secCIKsMu.Lock()
+	defer secCIKsMu.Unlock()
+
+	if secCIKs == nil {
+		var entries map[string]struct {
+			CIK    int    `json:"cik_str"`
+			Ticker string `json:"ticker"`
+		}
+		body, err := s.get(ctx, secArchivesURL+"/files/company_tickers.json")
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return "", fmt.Errorf("failed to parse EDGAR ticker map: %w", err)
+		}
+		ciks := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			ciks[strings.ToUpper(entry.Ticker)] = fmt.Sprintf("%010d", entry.CIK)
+		}
+		secCIKs = ciks
+	}
+
+	cik, ok := secCIKs[strings.ToUpper(ticker)]
+	if !ok {
+		return "", fmt.Errorf("no CIK for %s in EDGAR", ticker)
+	}
+	return cik, nil
+}
+
+// RecentForm4Filings lists the company's Form 4 filings filed on or after
+// since, newest first, from the recent filings of its EDGAR index
+func (s *SECService) RecentForm4Filings(ctx context.Context, cik, since string) ([]Form4Filing, error) {
+	cik, err := padCIK(cik)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.get(ctx, secDataURL+"/submissions/CIK"+cik+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var submissions struct {
+		Filings struct {
+			Recent struct {
+				AccessionNumber []string `json:"accessionNumber"`
+				FilingDate      []string `json:"filingDate"`
+				Form            []string `json:"form"`
+				PrimaryDocument []string `json:"primaryDocument"`
+			} `json:"recent"`
+		} `json:"filings"`
+	}
+	if err := json.Unmarshal(body, &submissions); err != nil {
+		return nil, fmt.Errorf("failed to parse EDGAR submissions: %w", err)
+	}
+
+	recent := submissions.Filings.Recent
+	var filings []Form4Filing
+	for i, form := range recent.Form {
+		if form != "4" || i >= len(recent.AccessionNumber) || i >= len(recent.FilingDate) || i >= len(recent.PrimaryDocument) {
+			continue
+		}
+		if recent.FilingDate[i] < since {
+			continue
+		}
+		// The primary document is the XSL rendering; the raw XML sits beside it
+		document := recent.PrimaryDocument[i]
+		if _, raw, found := strings.Cut(document, "/"); found {
+			document = raw
+		}
+		filings = append(filings, Form4Filing{
+			Accession: recent.AccessionNumber[i],
+			FiledAt:   recent.FilingDate[i],
+			Document:  document,
+		})
+	}
+	return filings, nil
+}
+
+// form4Value is an EDGAR <value> wrapper
+type form4Value struct {
+	Value string `xml:"value"`
+}
+
+// form4Document is the part of a Form 4's XML that is read
+type form4Document struct {
+	Owners []struct {
+		CIK          string `xml:"reportingOwnerId>rptOwnerCik"`
+		Name         string `xml:"reportingOwnerId>rptOwnerName"`
+		Relationship struct {
+			IsDirector        string `xml:"isDirector"`
+			IsOfficer         string `xml:"isOfficer"`
+			OfficerTitle      string `xml:"officerTitle"`
+			IsTenPercentOwner string `xml:"isTenPercentOwner"`
+		} `xml:"reportingOwnerRelationship"`
+	} `xml:"reportingOwner"`
+	Transactions []struct {
+		Date             form4Value `xml:"transactionDate"`
+		Code             string     `xml:"transactionCoding>transactionCode"`
+		Shares           form4Value `xml:"transactionAmounts>transactionShares"`
+		Price            form4Value `xml:"transactionAmounts>transactionPricePerShare"`
+		AcquiredDisposed form4Value `xml:"transactionAmounts>transactionAcquiredDisposedCode"`
+		OwnedAfter       form4Value `xml:"postTransactionAmounts>sharesOwnedFollowingTransaction"`
+	} `xml:"nonDerivativeTable>nonDerivativeTransaction"`
+}
+
+// Form4Transactions reads the non-derivative transactions of one filing.
+// Joint filings are attributed to their first reporting owner.
+func (s *SECService) Form4Transactions(ctx context.Context, cik string, filing Form4Filing) ([]Form4Transaction, error) {
+	cik, err := padCIK(cik)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := strings.TrimLeft(cik, "0")
+	accession := strings.ReplaceAll(filing.Accession, "-", "")
+	body, err := s.get(ctx, fmt.Sprintf("%s/Archives/edgar/data/%s/%s/%s", secArchivesURL, trimmed, accession, filing.Document))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc form4Document
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse Form 4 %s: %w", filing.Accession, err)
+	}
+	if len(doc.Owners) == 0 {
+		return nil, fmt.Errorf("Form 4 %s names no reporting owner", filing.Accession)
+	}
+
+	owner := doc.Owners[0]
+	var roles []string
+	if form4Flag(owner.Relationship.IsDirector) {
+		roles = append(roles, "Director")
+	}
+	if form4Flag(owner.Relationship.IsOfficer) {
+		title := strings.TrimSpace(owner.Relationship.OfficerTitle)
+		if title == "" {
+			title = "Officer"
+		}
+		roles = append(roles, title)
+	}
+	if form4Flag(owner.Relationship.IsTenPercentOwner) {
+		roles = append(roles, "10% Owner")
+	}
+
+	transactions := make([]Form4Transaction, 0, len(doc.Transactions))
+	for _, t := range doc.Transactions {
+		transactions = append(transactions, Form4Transaction{
+			OwnerCIK:         strings.TrimSpace(owner.CIK),
+			OwnerName:        strings.TrimSpace(owner.Name),
+			Relationship:     strings.Join(roles, ", "),
+			Date:             strings.TrimSpace(t.Date.Value),
+			Code:             strings.TrimSpace(t.Code),
+			Shares:           form4Number(t.Shares.Value),
+			Price:            form4Number(t.Price.Value),
+			Acquired:         strings.TrimSpace(t.AcquiredDisposed.Value) == "A",
+			SharesOwnedAfter: form4Number(t.OwnedAfter.Value),
+		})
+	}
+	return transactions, nil
+}
+
+// form4Flag reads an EDGAR boolean, written as 1/0 or true/false
+func form4Flag(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "1" || strings.EqualFold(value, "true")
+}
+
+// form4Number reads an EDGAR number, 0 when it is missing
+func form4Number(value string) float64 {
+	n, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return n
+}
+
+// FailToDeliver is one row of the SEC fails-to-deliver data: the shares of a
+// security that failed to deliver as of a settlement date
+type FailToDeliver struct {
+	SettlementDate string // YYYY-MM-DD
+	CUSIP          string
+	Symbol         string
+	Quantity       int64
+	Description    string
+	// Price is the previous day's close, 0 when the SEC has none
+	Price float64
+}
+
+// FailsToDeliver reads one half-month file of the SEC fails-to-deliver data.
+// period is the file's month and half, e.g. 202505a for May 1-15 and 202505b
+// for the rest of May. ErrSECNotFound means the file is not published yet.
+func (s *SECService) FailsToDeliver(ctx context.Context, period string) ([]FailToDeliver, error) {
+	body, err := s.get(ctx, secArchivesURL+"/files/data/fails-deliver-data/cnsfails"+period+".zip")
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fails-to-deliver file %s: %w", period, err)
+	}
+	if len(archive.File) == 0 {
+		return nil, fmt.Errorf("fails-to-deliver file %s is empty", period)
+	}
+	file, err := archive.File[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fails-to-deliver file %s: %w", period, err)
+	}
+	defer file.Close()
+
+	// Rows are SETTLEMENT DATE|CUSIP|SYMBOL|QUANTITY (FAILS)|DESCRIPTION|PRICE
+	// after a header, followed by trailer lines
+	var fails []FailToDeliver
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) < 6 {
+			continue
+		}
+		settled, err := time.Parse("20060102", strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue // the header
+		}
+		quantity, err := strconv.ParseInt(strings.TrimSpace(fields[3]), 10, 64)
+		if err != nil {
+			continue
+		}
+		fails = append(fails, FailToDeliver{
+			SettlementDate: settled.Format("2006-01-02"),
+			CUSIP:          strings.TrimSpace(fields[1]),
+			Symbol:         strings.ToUpper(strings.TrimSpace(fields[2])),
+			Quantity:       quantity,
+			Description:    strings.TrimSpace(fields[4]),
+			Price:          form4Number(fields[5]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fails-to-deliver file %s: %w", period, err)
+	}
+	return fails, nil
+}
+
+// padCIK returns cik zero-padded to the 10 digits EDGAR indexes use
+func padCIK(cik string) (string, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(cik), 10, 64)
+	if err != nil || n == 0 {
+		return "", fmt.Errorf("invalid CIK %q", cik)
+	}
+	return fmt.Sprintf("%010d", n), nil
+}
+
+// get fetches an EDGAR URL, spacing requests to respect its rate limit
+func (s *SECService) get(ctx context.Context, url string) ([]byte, error) {
+	if !s.Configured() {
+		return nil, errors.New("SEC_USER_AGENT must be set to read EDGAR")
+	}
+
+	secRateMu.Lock()
+	if wait := secRequestInterval - time.Since(secLastRequest); wait > 0 {
+		time.Sleep(wait)
+	}
+	secLastRequest = time.Now()
+	secRateMu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request to EDGAR: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrSECNotFound, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("EDGAR returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
@@ -10,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	polygon "github.com/polygon-io/client-go/rest"
 	"github.com/polygon-io/client-go/rest/iter"
 	"github.com/polygon-io/client-go/rest/models"
 )
@@ -203,30 +202,34 @@ Here is a summary of the current technical indicator data across multiple timefr
 }
 
 func (s *StockTechnicalService) FetchSMA(window int) (*TechnicalResponse, error) {
-	return s.fetchTechnical("sma", map[string]string{"window": fmt.Sprintf("%d", window)})
+	return s.fetchTechnical(context.Background(), "sma", map[string]string{"window": fmt.Sprintf("%d", window)})
 }
 
 func (s *StockTechnicalService) FetchEMA(window int) (*TechnicalResponse, error) {
-	return s.fetchTechnical("ema", map[string]string{"window": fmt.Sprintf("%d", window)})
+	return s.fetchTechnical(context.Background(), "ema", map[string]string{"window": fmt.Sprintf("%d", window)})
 }
 
 func (s *StockTechnicalService) FetchRSI(window int) (*TechnicalResponse, error) {
-	return s.fetchTechnical("rsi", map[string]string{"window": fmt.Sprintf("%d", window)})
+	return s.fetchTechnical(context.Background(), "rsi", map[string]string{"window": fmt.Sprintf("%d", window)})
 }
 
 func (s *StockTechnicalService) FetchMACD(shortWindow, longWindow, signalWindow int) (*MACDResponse, error) {
+	return s.fetchMACDWindows(context.Background(), shortWindow, longWindow, signalWindow)
+}
+
+func (s *StockTechnicalService) fetchMACDWindows(ctx context.Context, shortWindow, longWindow, signalWindow int) (*MACDResponse, error) {
 	params := map[string]string{
 		"short_window":  fmt.Sprintf("%d", shortWindow),
 		"long_window":   fmt.Sprintf("%d", longWindow),
 		"signal_window": fmt.Sprintf("%d", signalWindow),
 	}
 	url := fmt.Sprintf("https://api.polygon.io/v1/indicators/macd/%s", s.ticker)
-	return s.fetchMACD(url, params)
+	return s.fetchMACD(ctx, url, params)
 }
 
 func (s *StockTechnicalService) GetTickerDetailsFromPolygon() (*models.GetTickerDetailsResponse, error) {
 
-	c := polygon.New(s.apiKey)
+	c := newPolygonClient(s.apiKey)
 
 	params := models.GetTickerDetailsParams{
 		Ticker: s.ticker,
@@ -242,7 +245,7 @@ func (s *StockTechnicalService) GetTickerDetailsFromPolygon() (*models.GetTicker
 }
 
 func (s *StockTechnicalService) GetTickeSnapshotPolygon() (*models.GetTickerSnapshotResponse, error) {
-	c := polygon.New(s.apiKey)
+	c := newPolygonClient(s.apiKey)
 
 	params := models.GetTickerSnapshotParams{
 		Ticker:     s.ticker,
@@ -259,8 +262,24 @@ func (s *StockTechnicalService) GetTickeSnapshotPolygon() (*models.GetTickerSnap
 
 }
 
+// GetTickerSnapshot returns the current day, last trade and previous day snapshot of the ticker
+func (s *StockTechnicalService) GetTickerSnapshot(ctx context.Context) (*models.TickerSnapshot, error) {
+	c := newPolygonClient(s.apiKey)
+
+	res, err := c.GetTickerSnapshot(ctx, &models.GetTickerSnapshotParams{
+		Ticker:     s.ticker,
+		Locale:     "us",
+		MarketType: "stocks",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Snapshot, nil
+}
+
 func (s *StockTechnicalService) GetSimilarTickers() (*models.GetTickerRelatedCompaniesResponse, error) {
-	c := polygon.New(s.apiKey)
+	c := newPolygonClient(s.apiKey)
 
 	params := models.GetTickerRelatedCompaniesParams{
 		Ticker: s.ticker,
@@ -274,9 +293,15 @@ func (s *StockTechnicalService) GetSimilarTickers() (*models.GetTickerRelatedCom
 	return res, nil
 }
 
-func (s *StockTechnicalService) GetPolygonAggregate(timeSpan, startDate, endDate string, multiplier int) (*iter.Iter[models.Agg], error) {
+func (s *StockTechnicalService) GetPolygonAggregate(ctx context.Context, timeSpan, startDate, endDate string, multiplier int) (*iter.Iter[models.Agg], error) {
+	return s.GetPolygonAggregateAdjusted(ctx, timeSpan, startDate, endDate, multiplier, true)
+}
+
+// GetPolygonAggregateAdjusted is GetPolygonAggregate with control over split adjustment.
+// Unadjusted bars are needed when comparing against prices recorded before a split.
+func (s *StockTechnicalService) GetPolygonAggregateAdjusted(ctx context.Context, timeSpan, startDate, endDate string, multiplier int, adjusted bool) (*iter.Iter[models.Agg], error) {
 
-	c := polygon.New(s.apiKey)
+	c := newPolygonClient(s.apiKey)
 
 	from, err := time.Parse("2006-01-02", startDate)
 	if err != nil {
@@ -294,18 +319,37 @@ func (s *StockTechnicalService) GetPolygonAggregate(timeSpan, startDate, endDate
 		From:       models.Millis(from),
 		To:         models.Millis(to),
 	}.
-		WithAdjusted(true).
+		WithAdjusted(adjusted).
 		WithOrder(models.Order("asc")).
 		WithLimit(120)
 
-	iter := c.ListAggs(context.Background(), params)
+	iter := c.ListAggs(ctx, params)
 
 	return iter, nil
 
 }
 
+// GetPolygonAggregateBetween returns adjusted bars between two instants, e.g.
+// today's partial session up to now
+func (s *StockTechnicalService) GetPolygonAggregateBetween(ctx context.Context, timeSpan string, multiplier int, from, to time.Time) *iter.Iter[models.Agg] {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListAggsParams{
+		Ticker:     s.ticker,
+		Multiplier: multiplier,
+		Timespan:   models.Timespan(timeSpan),
+		From:       models.Millis(from),
+		To:         models.Millis(to),
+	}.
+		WithAdjusted(true).
+		WithOrder(models.Order("asc")).
+		WithLimit(5000)
+
+	return c.ListAggs(ctx, params)
+}
+
 func (s *StockTechnicalService) GetPolygonNewsForTicker() (string, *iter.Iter[models.TickerNews]) {
-	c := polygon.New(s.apiKey)
+	c := newPolygonClient(s.apiKey)
 
 	params := models.ListTickerNewsParams{
 		TickerEQ: &s.ticker,
@@ -333,6 +377,42 @@ func (s *StockTechnicalService) GetPolygonNewsForTicker() (string, *iter.Iter[mo
 	return sb.String(), iter
 }
 
+// GetSplits returns the ticker's stock splits executed between from and to (inclusive)
+func (s *StockTechnicalService) GetSplits(ctx context.Context, from, to time.Time) ([]models.Split, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListSplitsParams{}.
+		WithTicker(models.EQ, s.ticker).
+		WithExecutionDate(models.GTE, models.Date(from)).
+		WithExecutionDate(models.LTE, models.Date(to)).
+		WithLimit(1000)
+
+	var splits []models.Split
+	it := c.ListSplits(ctx, params)
+	for it.Next() {
+		splits = append(splits, it.Item())
+	}
+	return splits, it.Err()
+}
+
+// GetDividends returns the ticker's cash dividends going ex between from and to (inclusive)
+func (s *StockTechnicalService) GetDividends(ctx context.Context, from, to time.Time) ([]models.Dividend, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListDividendsParams{}.
+		WithTicker(models.EQ, s.ticker).
+		WithExDividendDate(models.GTE, models.Date(from)).
+		WithExDividendDate(models.LTE, models.Date(to)).
+		WithLimit(1000)
+
+	var dividends []models.Dividend
+	it := c.ListDividends(ctx, params)
+	for it.Next() {
+		dividends = append(dividends, it.Item())
+	}
+	return dividends, it.Err()
+}
+
 func ptr(s string) *string {
 	return &s
 }
@@ -341,8 +421,8 @@ func ptrInt(i int) *int {
 	return &i
 }
 
-func (s *StockTechnicalService) fetchTechnical(indicator string, extraParams map[string]string) (*TechnicalResponse, error) {
-	baseURL := fmt.Sprintf("https://api.polygon.io/v1/indicators/%s/%s", indicator, s.ticker)
+func (s *StockTechnicalService) fetchTechnical(ctx context.Context, indicator string, extraParams map[string]string) (*TechnicalResponse, error) {
+	baseURL := fmt.Sprintf("%s/v1/indicators/%s/%s", PolygonBaseURL(), indicator, s.ticker)
 	u, _ := url.Parse(baseURL)
 	q := u.Query()
 	q.Set("timespan", "day")
@@ -355,7 +435,11 @@ func (s *StockTechnicalService) fetchTechnical(indicator string, extraParams map
 	}
 	u.RawQuery = q.Encode()
 
-	resp, err := http.Get(u.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := PolygonHTTPClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -372,7 +456,7 @@ func (s *StockTechnicalService) fetchTechnical(indicator string, extraParams map
 	return &data, nil
 }
 
-func (s *StockTechnicalService) fetchMACD(apiURL string, params map[string]string) (*MACDResponse, error) {
+func (s *StockTechnicalService) fetchMACD(ctx context.Context, apiURL string, params map[string]string) (*MACDResponse, error) {
 	u, _ := url.Parse(apiURL)
 	q := u.Query()
 	q.Set("timespan", "day")
@@ -385,7 +469,11 @@ func (s *StockTechnicalService) fetchMACD(apiURL string, params map[string]strin
 	}
 	u.RawQuery = q.Encode()
 
-	resp, err := http.Get(u.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := PolygonHTTPClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
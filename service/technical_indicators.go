@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// Indicator fetch statuses
+const (
+	IndicatorStatusOK      = "ok"
+	IndicatorStatusPending = "pending"
+	IndicatorStatusError   = "error"
+)
+
+// IndicatorSpec identifies one daily indicator in the technical summary
+type IndicatorSpec struct {
+	Name string `json:"name"` // e.g. sma_20, macd_12_26_9
+	Kind string `json:"kind"` // sma, ema, rsi or macd
+	// Window is used by sma/ema/rsi; MACD uses the short/long/signal windows
+	Window       int `json:"window,omitempty"`
+	ShortWindow  int `json:"short_window,omitempty"`
+	LongWindow   int `json:"long_window,omitempty"`
+	SignalWindow int `json:"signal_window,omitempty"`
+}
+
+// SummaryIndicators are the indicators reported by FetchTechnicalSummary
+var SummaryIndicators = []IndicatorSpec{
+	{Name: "sma_20", Kind: "sma", Window: 20},
+	{Name: "sma_50", Kind: "sma", Window: 50},
+	{Name: "sma_200", Kind: "sma", Window: 200},
+	{Name: "ema_20", Kind: "ema", Window: 20},
+	{Name: "ema_50", Kind: "ema", Window: 50},
+	{Name: "ema_200", Kind: "ema", Window: 200},
+	{Name: "rsi_5", Kind: "rsi", Window: 5},
+	{Name: "rsi_14", Kind: "rsi", Window: 14},
+	{Name: "rsi_50", Kind: "rsi", Window: 50},
+	{Name: "macd_6_13_5", Kind: "macd", ShortWindow: 6, LongWindow: 13, SignalWindow: 5},
+	{Name: "macd_12_26_9", Kind: "macd", ShortWindow: 12, LongWindow: 26, SignalWindow: 9},
+	{Name: "macd_26_52_9", Kind: "macd", ShortWindow: 26, LongWindow: 52, SignalWindow: 9},
+}
+
+// TechnicalIndicator is the structured latest value of one indicator
+type TechnicalIndicator struct {
+	IndicatorSpec
+	Status    string   `json:"status"`
+	Value     *float64 `json:"value,omitempty"`
+	Signal    *float64 `json:"signal,omitempty"`
+	Histogram *float64 `json:"histogram,omitempty"`
+	Trend     string   `json:"trend,omitempty"`
+	// RSIStatus is overbought, oversold or neutral for RSI indicators
+	RSIStatus string `json:"rsi_status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PendingIndicator returns the placeholder for an indicator that has not returned yet
+func PendingIndicator(spec IndicatorSpec) TechnicalIndicator {
+	return TechnicalIndicator{IndicatorSpec: spec, Status: IndicatorStatusPending}
+}
+
+// FetchIndicator fetches one indicator and reduces it to its latest value and trend
+func (s *StockTechnicalService) FetchIndicator(ctx context.Context, spec IndicatorSpec) TechnicalIndicator {
+	result := TechnicalIndicator{IndicatorSpec: spec, Status: IndicatorStatusOK}
+
+	if spec.Kind == "macd" {
+		resp, err := s.fetchMACDWindows(ctx, spec.ShortWindow, spec.LongWindow, spec.SignalWindow)
+		if err != nil {
+			result.Status, result.Error = IndicatorStatusError, err.Error()
+			return result
+		}
+		if resp.Status == "OK" && len(resp.Results.Values) > 0 {
+			latest := resp.Results.Values[0]
+			result.Value, result.Signal, result.Histogram = &latest.Value, &latest.Signal, &latest.Histogram
+		}
+		result.Trend = getMACDTrend(resp)
+		return result
+	}
+
+	switch spec.Kind {
+	case "sma", "ema", "rsi":
+	default:
+		result.Status, result.Error = IndicatorStatusError, fmt.Sprintf("unknown indicator kind %q", spec.Kind)
+		return result
+	}
+
+	resp, err := s.fetchTechnical(ctx, spec.Kind, map[string]string{"window": fmt.Sprintf("%d", spec.Window)})
+	if err != nil {
+		result.Status, result.Error = IndicatorStatusError, err.Error()
+		return result
+	}
+	if resp.Status == "OK" && len(resp.Results.Values) > 0 {
+		latest := resp.Results.Values[0].Value
+		result.Value = &latest
+		if spec.Kind == "rsi" {
+			switch {
+			case latest > 70:
+				result.RSIStatus = "overbought"
+			case latest < 30:
+				result.RSIStatus = "oversold"
+			default:
+				result.RSIStatus = "neutral"
+			}
+		}
+	}
+	result.Trend = getTrend(resp)
+	return result
+}
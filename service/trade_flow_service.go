@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/polygon-io/client-go/rest/models"
+)
+
+// Big money directions
+const (
+	DirectionBuyingPressure  = "BUYING_PRESSURE"
+	DirectionSellingPressure = "SELLING_PRESSURE"
+	DirectionNeutral         = "NEUTRAL"
+)
+
+const (
+	// flowImbalanceThreshold is the share of large-trade volume one side must
+	// lead by before the day is called buying or selling pressure
+	flowImbalanceThreshold = 0.1
+	// preOpenQuoteWindow is how far before the open quotes are read, so trades
+	// at the open have a prevailing quote
+	preOpenQuoteWindow = time.Minute
+	// A block trade is at least blockTradeMinShares shares or blockTradeMinNotional dollars
+	blockTradeMinShares   = 10000
+	blockTradeMinNotional = 200000
+	// finraADF is Polygon's exchange id for FINRA's Alternative Display Facility,
+	// where off-exchange (dark pool and internalised) prints are reported
+	finraADF = 4
+	// sweepWindow is how close together the prints of one sweep land
+	sweepWindow = 50 * time.Millisecond
+	// optionMultiplier is the shares one option contract covers
+	optionMultiplier = 100
+)
+
+// TradeFlowResult summarises large-trade flow for one ticker and session
+type TradeFlowResult struct {
+	Ticker                string    `json:"ticker"`
+	StartTime             time.Time `json:"start_time"`
+	EndTime               time.Time `json:"end_time"`
+	TotalTrades           int       `json:"total_trades"`
+	AvgTradeSize          float64   `json:"avg_trade_size"`
+	LargeTradeThreshold   float64   `json:"large_trade_threshold"`
+	LargeTradeMinSize     float64   `json:"large_trade_min_size"`
+	LargeTradesCount      int       `json:"large_trades_count"`
+	NetBigMoneyFlow       float64   `json:"net_big_money_flow"`
+	BuyerInitiatedVolume  float64   `json:"buyer_initiated_volume"`
+	SellerInitiatedVolume float64   `json:"seller_initiated_volume"`
+	Direction             string    `json:"direction"`
+}
+
+// TradeFlowService classifies large trades as buyer or seller initiated from Polygon tick data
+type TradeFlowService struct {
+	apiKey string
+}
+
+func NewTradeFlowService() *TradeFlowService {
+	return &TradeFlowService{apiKey: os.Getenv("POLYGON_API_KEY")}
+}
+
+type tick struct {
+	timestamp time.Time
+	price     float64
+	size      float64
+	// offExchange is set for prints reported through a trade reporting facility
+	offExchange bool
+	exchange    int
+}
+
+// SessionPrints summarises one regular session's block trades and off-exchange volume
+type SessionPrints struct {
+	Date              string  `json:"date"`
+	TotalVolume       float64 `json:"total_volume"`
+	OffExchangeVolume float64 `json:"off_exchange_volume"`
+	BlockTrades       int     `json:"block_trades"`
+	BlockVolume       float64 `json:"block_volume"`
+	// BlockBuyVolume and BlockSellVolume are blocks classified by the tick rule
+	BlockBuyVolume  float64 `json:"block_buy_volume"`
+	BlockSellVolume float64 `json:"block_sell_volume"`
+	// NetBlockFlow is buyer minus seller initiated block notional
+	NetBlockFlow float64 `json:"net_block_flow"`
+}
+
+// AnalyzeSession classifies the large trades of a ticker's regular session on
+// date. A trade is large when its size is at least largeThreshold times the
+// session's average trade size. Each large trade is compared with the NBBO
+// midpoint prevailing when it printed (above = buyer initiated, below = seller
+// initiated); trades at the midpoint fall back to the tick rule.
+func (s *TradeFlowService) AnalyzeSession(ctx context.Context, ticker string, date time.Time, largeThreshold float64) (*TradeFlowResult, error) {
+	open, close, err := regularSession(date)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TradeFlowResult{
+		Ticker:              ticker,
+		StartTime:           open,
+		EndTime:             close,
+		LargeTradeThreshold: largeThreshold,
+		Direction:           DirectionNeutral,
+	}
+
+	trades, err := s.listTrades(ctx, ticker, open, close)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalTrades = len(trades)
+	if len(trades) == 0 {
+		return result, nil
+	}
+
+	var totalSize float64
+	for _, t := range trades {
+		totalSize += t.size
+	}
+	result.AvgTradeSize = totalSize / float64(len(trades))
+	result.LargeTradeMinSize = result.AvgTradeSize * largeThreshold
+
+	var large []int
+	for i, t := range trades {
+		if t.size >= result.LargeTradeMinSize {
+			large = append(large, i)
+		}
+	}
+	result.LargeTradesCount = len(large)
+
+	sides := s.classifyTrades(ctx, ticker, open, trades, large)
+	for n, i := range large {
+		t := trades[i]
+		switch sides[n] {
+		case 1:
+			result.BuyerInitiatedVolume += t.size
+			result.NetBigMoneyFlow += t.size * t.price
+		case -1:
+			result.SellerInitiatedVolume += t.size
+			result.NetBigMoneyFlow -= t.size * t.price
+		}
+	}
+
+	classified := result.BuyerInitiatedVolume + result.SellerInitiatedVolume
+	if classified > 0 {
+		imbalance := (result.BuyerInitiatedVolume - result.SellerInitiatedVolume) / classified
+		if imbalance > flowImbalanceThreshold {
+			result.Direction = DirectionBuyingPressure
+		} else if imbalance < -flowImbalanceThreshold {
+			result.Direction = DirectionSellingPressure
+		}
+	}
+
+	return result, nil
+}
+
+// SessionPrints totals a ticker's block trades and off-exchange prints over
+// its regular session on date. Blocks are classified with the tick rule rather
+// than NBBO lookups, since liquid tickers print thousands of them a day.
+func (s *TradeFlowService) SessionPrints(ctx context.Context, ticker string, date time.Time) (*SessionPrints, error) {
+	open, close, err := regularSession(date)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := s.listTrades(ctx, ticker, open, close)
+	if err != nil {
+		return nil, err
+	}
+
+	prints := &SessionPrints{Date: open.Format("2006-01-02")}
+	for i, t := range trades {
+		prints.TotalVolume += t.size
+		if t.offExchange {
+			prints.OffExchangeVolume += t.size
+		}
+		if t.size < blockTradeMinShares && t.size*t.price < blockTradeMinNotional {
+			continue
+		}
+		prints.BlockTrades++
+		prints.BlockVolume += t.size
+		switch tickRule(trades, i) {
+		case 1:
+			prints.BlockBuyVolume += t.size
+			prints.NetBlockFlow += t.size * t.price
+		case -1:
+			prints.BlockSellVolume += t.size
+			prints.NetBlockFlow -= t.size * t.price
+		}
+	}
+	return prints, nil
+}
+
+// BlockPrint is one block trade of a session. Side is +1 when the tick rule
+// calls it buyer initiated, -1 seller initiated and 0 when it cannot tell.
+type BlockPrint struct {
+	Timestamp   time.Time
+	Price       float64
+	Size        float64
+	Side        int
+	OffExchange bool
+}
+
+// TapeMinute totals one minute of a session's tape. OffExchangeNet is the
+// tick rule's buyer minus seller initiated off-exchange volume.
+type TapeMinute struct {
+	Start             time.Time
+	Volume            float64
+	OffExchangeVolume float64
+	OffExchangeNet    float64
+}
+
+// SessionBlocks is a session's tape reduced to its block trades, with the
+// volume of each minute that printed
+type SessionBlocks struct {
+	Date        string
+	TotalTrades int
+	TotalVolume float64
+	Blocks      []BlockPrint
+	Minutes     []TapeMinute
+}
+
+// SessionBlocks lists a ticker's prints over its regular session on date
+// whose notional is at least minNotional, oldest first, classified with the
+// tick rule like SessionPrints
+func (s *TradeFlowService) SessionBlocks(ctx context.Context, ticker string, date time.Time, minNotional float64) (*SessionBlocks, error) {
+	open, close, err := regularSession(date)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := s.listTrades(ctx, ticker, open, close)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &SessionBlocks{Date: open.Format("2006-01-02"), TotalTrades: len(trades)}
+	for i, t := range trades {
+		session.TotalVolume += t.size
+
+		start := t.timestamp.Truncate(time.Minute)
+		if n := len(session.Minutes); n == 0 || !session.Minutes[n-1].Start.Equal(start) {
+			session.Minutes = append(session.Minutes, TapeMinute{Start: start})
+		}
+		minute := &session.Minutes[len(session.Minutes)-1]
+		minute.Volume += t.size
+		if t.offExchange {
+			minute.OffExchangeVolume += t.size
+			minute.OffExchangeNet += float64(tickRule(trades, i)) * t.size
+		}
+
+		if t.size*t.price < minNotional {
+			continue
+		}
+		session.Blocks = append(session.Blocks, BlockPrint{
+			Timestamp:   t.timestamp,
+			Price:       t.price,
+			Size:        t.size,
+			Side:        tickRule(trades, i),
+			OffExchange: t.offExchange,
+		})
+	}
+	return session, nil
+}
+
+// OptionSweep is a burst of prints of one option contract routed to several
+// exchanges at once, the footprint of an order sweeping the book
+type OptionSweep struct {
+	Contract  string
+	Timestamp time.Time
+	Prints    int
+	Exchanges int
+	Size      float64
+	// Premium is the dollar value paid, price x size x 100
+	Premium float64
+}
+
+// ContractSweeps lists the sweeps of an option contract over the regular
+// session on date whose premium is at least minPremium, oldest first. Prints
+// within sweepWindow of the burst's first print belong to it, and a burst
+// counts as a sweep when it reached at least two exchanges.
+func (s *TradeFlowService) ContractSweeps(ctx context.Context, contract string, date time.Time, minPremium float64) ([]OptionSweep, error) {
+	open, close, err := regularSession(date)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := s.listTrades(ctx, contract, open, close)
+	if err != nil {
+		return nil, err
+	}
+
+	var sweeps []OptionSweep
+	for i := 0; i < len(trades); {
+		burst := OptionSweep{Contract: contract, Timestamp: trades[i].timestamp}
+		exchanges := map[int]bool{}
+		j := i
+		for ; j < len(trades) && trades[j].timestamp.Sub(burst.Timestamp) <= sweepWindow; j++ {
+			burst.Prints++
+			burst.Size += trades[j].size
+			burst.Premium += trades[j].price * trades[j].size * optionMultiplier
+			exchanges[trades[j].exchange] = true
+		}
+		burst.Exchanges = len(exchanges)
+		if burst.Exchanges >= 2 && burst.Premium >= minPremium {
+			sweeps = append(sweeps, burst)
+		}
+		i = j
+	}
+	return sweeps, nil
+}
+
+// regularSession returns the 09:30-16:00 New York session on date
+func regularSession(date time.Time) (time.Time, time.Time, error) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	open := time.Date(date.Year(), date.Month(), date.Day(), 9, 30, 0, 0, loc)
+	close := time.Date(date.Year(), date.Month(), date.Day(), 16, 0, 0, 0, loc)
+	return open, close, nil
+}
+
+// listTrades returns the trades printed between from and to, oldest first
+func (s *TradeFlowService) listTrades(ctx context.Context, ticker string, from, to time.Time) ([]tick, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListTradesParams{Ticker: ticker}.
+		WithTimestamp(models.GTE, models.Nanos(from)).
+		WithTimestamp(models.LT, models.Nanos(to)).
+		WithSort(models.Timestamp).
+		WithOrder(models.Asc).
+		WithLimit(50000)
+
+	var trades []tick
+	it := c.ListTrades(ctx, params)
+	for it.Next() {
+		t := it.Item()
+		if t.Size <= 0 || t.Price <= 0 {
+			continue
+		}
+		trades = append(trades, tick{
+			timestamp:   time.Time(t.SipTimestamp),
+			price:       t.Price,
+			size:        t.Size,
+			offExchange: t.Exchange == finraADF || t.TrfID != 0,
+			exchange:    t.Exchange,
+		})
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trades for %s: %w", ticker, err)
+	}
+	return trades, nil
+}
+
+// classifyTrades returns +1 (buyer initiated), -1 (seller initiated) or 0
+// (unclassified) for each trade index in large. If the session's quotes cannot
+// be read, every trade is classified with the tick rule.
+func (s *TradeFlowService) classifyTrades(ctx context.Context, ticker string, open time.Time, trades []tick, large []int) []int {
+	sides := make([]int, len(large))
+	if len(large) == 0 {
+		return sides
+	}
+
+	mids, err := s.prevailingMidpoints(ctx, ticker, open.Add(-preOpenQuoteWindow), trades, large)
+	if err != nil {
+		fmt.Printf("[trade flow] %v; classifying %s with the tick rule\n", err, ticker)
+	}
+	for n, i := range large {
+		trade := trades[i]
+		if mids != nil && mids[n] > 0 {
+			if trade.price > mids[n] {
+				sides[n] = 1
+				continue
+			}
+			if trade.price < mids[n] {
+				sides[n] = -1
+				continue
+			}
+		}
+		sides[n] = tickRule(trades, i)
+	}
+	return sides
+}
+
+// prevailingMidpoints returns the NBBO midpoint of the last quote at or before
+// each trade index in large, or 0 where there is none or it is crossed. The
+// quotes from from to the last large trade are read once, in time order, and
+// walked alongside the trades, which are sorted by time.
+func (s *TradeFlowService) prevailingMidpoints(ctx context.Context, ticker string, from time.Time, trades []tick, large []int) ([]float64, error) {
+	c := newPolygonClient(s.apiKey)
+
+	params := models.ListQuotesParams{Ticker: ticker}.
+		WithTimestamp(models.GTE, models.Nanos(from)).
+		WithTimestamp(models.LTE, models.Nanos(trades[large[len(large)-1]].timestamp)).
+		WithSort(models.Timestamp).
+		WithOrder(models.Asc).
+		WithLimit(50000)
+
+	mids := make([]float64, len(large))
+	var last models.Quote
+	n := 0
+	it := c.ListQuotes(ctx, params)
+	for n < len(large) && it.Next() {
+		q := it.Item()
+		at := time.Time(q.SipTimestamp)
+		for n < len(large) && trades[large[n]].timestamp.Before(at) {
+			mids[n] = quoteMidpoint(last)
+			n++
+		}
+		last = q
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list quotes for %s: %w", ticker, err)
+	}
+	for ; n < len(large); n++ {
+		mids[n] = quoteMidpoint(last)
+	}
+	return mids, nil
+}
+
+// quoteMidpoint returns a quote's bid/ask midpoint, or 0 if it is empty or crossed
+func quoteMidpoint(q models.Quote) float64 {
+	if q.BidPrice <= 0 || q.AskPrice <= 0 || q.AskPrice < q.BidPrice {
+		return 0
+	}
+	return (q.BidPrice + q.AskPrice) / 2
+}
+
+// tickRule classifies a trade by the last different price before it: an
+// uptick is buyer initiated, a downtick seller initiated
+func tickRule(trades []tick, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if trades[j].price < trades[i].price {
+			return 1
+		}
+		if trades[j].price > trades[i].price {
+			return -1
+		}
+	}
+	return 0
+}
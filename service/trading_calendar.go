@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// holidayRefreshInterval is how often Polygon's upcoming holiday list is re-read
+const holidayRefreshInterval = 24 * time.Hour
+
+// specialClosures are unscheduled full-day NYSE closures that the holiday
+// rules cannot derive
+var specialClosures = map[string]string{
+	"2012-10-29": "Hurricane Sandy",
+	"2012-10-30": "Hurricane Sandy",
+	"2018-12-05": "National Day of Mourning (George H.W. Bush)",
+	"2025-01-09": "National Day of Mourning (Jimmy Carter)",
+}
+
+// TradingCalendar resolves US equity trading days. Regular NYSE holidays are
+// computed from their rules, and closures announced by Polygon's upcoming
+// market holidays endpoint are merged in when an API key is configured.
+type TradingCalendar struct {
+	apiKey string
+
+	mu          sync.Mutex
+	announced   map[string]string
+	refreshedAt time.Time
+}
+
+var (
+	defaultCalendarOnce sync.Once
+	defaultCalendar     *TradingCalendar
+)
+
+// DefaultTradingCalendar returns the process-wide calendar, so announced
+// closures are fetched once per refresh interval rather than per request
+func DefaultTradingCalendar() *TradingCalendar {
+	defaultCalendarOnce.Do(func() {
+		defaultCalendar = NewTradingCalendar()
+	})
+	return defaultCalendar
+}
+
+func NewTradingCalendar() *TradingCalendar {
+	return &TradingCalendar{apiKey: os.Getenv("POLYGON_API_KEY")}
+}
+
+// Holiday reports whether the exchange is closed all day on date's calendar
+// day (weekends excluded) and the holiday's name
+func (c *TradingCalendar) Holiday(ctx context.Context, date time.Time) (string, bool) {
+	key := date.Format("2006-01-02")
+	if name, ok := specialClosures[key]; ok {
+		return name, true
+	}
+	if name, ok := nyseHolidays(date.Year())[key]; ok {
+		return name, true
+	}
+	name, ok := c.announcedClosures(ctx)[key]
+	return name, ok
+}
+
+// IsTradingDay reports whether date is a weekday on which the exchange is open
+func (c *TradingCalendar) IsTradingDay(ctx context.Context, date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	_, closed := c.Holiday(ctx, date)
+	return !closed
+}
+
+// PreviousTradingDay returns the last trading day strictly before date
+func (c *TradingCalendar) PreviousTradingDay(ctx context.Context, date time.Time) time.Time {
+	prev := date.AddDate(0, 0, -1)
+	for !c.IsTradingDay(ctx, prev) {
+		prev = prev.AddDate(0, 0, -1)
+	}
+	return prev
+}
+
+// OnOrBeforeTradingDay returns date if it is a trading day, else the previous one
+func (c *TradingCalendar) OnOrBeforeTradingDay(ctx context.Context, date time.Time) time.Time {
+	if c.IsTradingDay(ctx, date) {
+		return date
+	}
+	return c.PreviousTradingDay(ctx, date)
+}
+
+// Prime fetches Polygon's announced closures ahead of the first request
+// that needs them
+func (c *TradingCalendar) Prime(ctx context.Context) {
+	c.announcedClosures(ctx)
+}
+
+// announcedClosures returns Polygon's upcoming full-day closures, refreshed
+// at most once per interval. Failures are logged and the rules apply alone.
+func (c *TradingCalendar) announcedClosures(ctx context.Context) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.apiKey == "" || time.Since(c.refreshedAt) < holidayRefreshInterval {
+		return c.announced
+	}
+	c.refreshedAt = time.Now()
+
+	res, err := newPolygonClient(c.apiKey).GetMarketHolidays(ctx)
+	if err != nil {
+		fmt.Printf("[calendar] failed to fetch upcoming market holidays: %v\n", err)
+		return c.announced
+	}
+
+	announced := make(map[string]string)
+	for _, h := range *res {
+		if h.Status != "closed" || (h.Exchange != "NYSE" && h.Exchange != "NASDAQ") {
+			continue
+		}
+		announced[time.Time(h.Date).Format("2006-01-02")] = h.Name
+	}
+	c.announced = announced
+	return announced
+}
+
+// nyseHolidays returns the regular full-day NYSE holidays of a year, keyed by
+// observed date
+func nyseHolidays(year int) map[string]string {
+	holidays := make(map[string]string)
+	add := func(date time.Time, name string) {
+		holidays[date.Format("2006-01-02")] = name
+	}
+
+	// New Year's Day falling on a Saturday is not observed on the Friday before
+	if newYear := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); newYear.Weekday() != time.Saturday {
+		add(observed(newYear), "New Year's Day")
+	}
+	add(nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King Jr. Day")
+	add(nthWeekday(year, time.February, time.Monday, 3), "Washington's Birthday")
+	add(easter(year).AddDate(0, 0, -2), "Good Friday")
+	add(lastWeekday(year, time.May, time.Monday), "Memorial Day")
+	if year >= 2022 {
+		add(observed(time.Date(year, time.June, 19, 0, 0, 0, 0, time.UTC)), "Juneteenth")
+	}
+	add(observed(time.Date(year, time.July, 4, 0, 0, 0, 0, time.UTC)), "Independence Day")
+	add(nthWeekday(year, time.September, time.Monday, 1), "Labor Day")
+	add(nthWeekday(year, time.November, time.Thursday, 4), "Thanksgiving Day")
+	add(observed(time.Date(year, time.December, 25, 0, 0, 0, 0, time.UTC)), "Christmas Day")
+
+	return holidays
+}
+
+// observed moves a Saturday holiday to Friday and a Sunday holiday to Monday
+func observed(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easter returns Easter Sunday (Gregorian, anonymous algorithm)
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,200 @@
+// Package usage meters Polygon calls by feature and user, so operators can
+// see what uses the plan's quota. Calls are counted in memory and added to
+// daily rows by Start's flush loop.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"institutionanalyser/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// flushInterval is how often counted calls are written to the database
+const flushInterval = time.Minute
+
+// Features calls are attributed to. Scheduled jobs use "job:<task name>".
+const (
+	FeatureDeepsearch = "deepsearch"
+	FeatureEarnings   = "earnings"
+	FeatureTechnicals = "technicals"
+	FeatureScanners   = "scanners"
+	FeatureMarketData = "market_data"
+	FeatureAdmin      = "admin"
+	FeatureHealth     = "health"
+	FeatureCLI        = "cli"
+	// FeatureOther covers calls made without a feature in their context
+	FeatureOther = "other"
+)
+
+// Estimated cost tiers of Polygon endpoints
+const (
+	TierLow    = "low"
+	TierMedium = "medium"
+	TierHigh   = "high"
+)
+
+// endpointClass groups Polygon paths by what they return and what they
+// roughly cost against the plan
+type endpointClass struct {
+	prefix   string
+	endpoint string
+	tier     string
+}
+
+// endpointClasses are matched in order, so longer prefixes come first
+var endpointClasses = []endpointClass{
+	{"/v3/snapshot/options", "options_snapshot", TierHigh},
+	{"/v2/snapshot", "snapshot", TierMedium},
+	{"/v3/snapshot", "snapshot", TierMedium},
+	{"/v2/aggs/grouped", "grouped_daily", TierMedium},
+	{"/v2/aggs", "aggregates", TierLow},
+	{"/v1/indicators", "indicators", TierMedium},
+	{"/v3/trades", "trades", TierHigh},
+	{"/v3/quotes", "quotes", TierHigh},
+	{"/benzinga", "benzinga", TierHigh},
+	{"/v2/reference/news", "news", TierLow},
+	{"/v3/reference", "reference", TierLow},
+	{"/v1/marketstatus", "reference", TierLow},
+}
+
+// Classify returns the endpoint class and estimated cost tier of a Polygon path
+func Classify(path string) (endpoint, tier string) {
+	for _, class := range endpointClasses {
+		if strings.HasPrefix(path, class.prefix) {
+			return class.endpoint, class.tier
+		}
+	}
+	return "other", TierLow
+}
+
+type contextKey int
+
+const (
+	featureKey contextKey = iota
+	userKey
+)
+
+// WithFeature attributes the Polygon calls made with ctx to a feature
+func WithFeature(ctx context.Context, feature string) context.Context {
+	return context.WithValue(ctx, featureKey, feature)
+}
+
+// WithUser attributes the Polygon calls made with ctx to a user
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey, userID)
+}
+
+// Attribution returns the feature and user ctx attributes calls to
+func Attribution(ctx context.Context) (feature, userID string) {
+	feature, _ = ctx.Value(featureKey).(string)
+	if feature == "" {
+		feature = FeatureOther
+	}
+	userID, _ = ctx.Value(userKey).(string)
+	return feature, userID
+}
+
+type counterKey struct {
+	day      string
+	feature  string
+	userID   string
+	endpoint string
+	tier     string
+}
+
+var (
+	mu     sync.Mutex
+	counts = map[counterKey]int64{}
+)
+
+// Record counts one Polygon call to path, attributed through ctx
+func Record(ctx context.Context, path string) {
+	feature, userID := Attribution(ctx)
+	endpoint, tier := Classify(path)
+	key := counterKey{
+		day:      time.Now().UTC().Format("2006-01-02"),
+		feature:  feature,
+		userID:   userID,
+		endpoint: endpoint,
+		tier:     tier,
+	}
+
+	mu.Lock()
+	counts[key]++
+	mu.Unlock()
+}
+
+// Flush adds the calls counted since the last flush to their daily rows.
+// Counts that fail to write are kept for the next flush.
+func Flush(ctx context.Context, db *gorm.DB) error {
+	mu.Lock()
+	pending := counts
+	counts = map[counterKey]int64{}
+	mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]models.PolygonUsage, 0, len(pending))
+	for key, calls := range pending {
+		day, _ := time.Parse("2006-01-02", key.day)
+		rows = append(rows, models.PolygonUsage{
+			Day:      day,
+			Feature:  key.feature,
+			UserId:   key.userID,
+			Endpoint: key.endpoint,
+			Tier:     key.tier,
+			Calls:    calls,
+		})
+	}
+
+	err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "feature"}, {Name: "user_id"}, {Name: "endpoint"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"calls":      gorm.Expr("polygon_usages.calls + EXCLUDED.calls"),
+			"updated_at": gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(&rows).Error
+	if err != nil {
+		mu.Lock()
+		for key, calls := range pending {
+			counts[key] += calls
+		}
+		mu.Unlock()
+	}
+	return err
+}
+
+// Start flushes counted calls every minute until ctx is cancelled, then once
+// more; done is closed after that final flush
+func Start(ctx context.Context, db *gorm.DB) (done <-chan struct{}) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := Flush(context.WithoutCancel(ctx), db); err != nil {
+					fmt.Printf("[usage] failed to flush Polygon usage: %v\n", err)
+				}
+				return
+			case <-ticker.C:
+				if err := Flush(ctx, db); err != nil {
+					fmt.Printf("[usage] failed to flush Polygon usage: %v\n", err)
+				}
+			}
+		}
+	}()
+	return stopped
+}